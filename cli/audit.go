@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+)
+
+const defaultAuditLog = "/var/lib/cni/terway/audit.log"
+
+func init() {
+	registerCommand("audit", "show openapi calls mutating enis and ips recorded in audit log of terway", runAudit)
+}
+
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	file := fs.String("file", defaultAuditLog, "audit log of terway daemon or controller")
+	since := fs.Duration("since", 0, "only calls started within the duration, eg: 1h, 0 for all")
+	action := fs.String("action", "", "only calls of the openapi action, eg: CreateNetworkInterface")
+	resource := fs.String("resource", "", "only calls on the resource, eg: eni id")
+	failed := fs.Bool("failed", false, "only failed calls")
+	output := fs.String("o", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	records, err := aliyun.ReadAuditLog(*file)
+	if err != nil {
+		return err
+	}
+
+	var matched []aliyun.AuditRecord
+	for _, record := range records {
+		if *since > 0 && time.Since(record.Time) > *since {
+			continue
+		}
+		if (*action != "" && record.Action != *action) ||
+			(*resource != "" && record.Resource != *resource) ||
+			(*failed && record.Error == "") {
+			continue
+		}
+		matched = append(matched, record)
+	}
+
+	switch *output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		for _, record := range matched {
+			if err = enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tACTION\tRESOURCE\tDETAIL\tREQUEST ID\tLATENCY\tERROR")
+		for _, record := range matched {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%dms\t%s\n", record.Time.Format(time.RFC3339), record.Action,
+				orDash(record.Resource), orDash(record.Detail), orDash(record.RequestID), record.LatencyMs, orDash(record.Error))
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format: %s", *output)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
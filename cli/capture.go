@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"google.golang.org/grpc"
+)
+
+const defaultSocket = "/var/run/eni/eni.socket"
+
+func init() {
+	registerCommand("capture", "capture packets of pod by terway daemon into pcap file", runCapture)
+}
+
+func runCapture(args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocket, "grpc socket of terway daemon")
+	namespace := fs.String("namespace", "default", "namespace of the pod")
+	filter := fs.String("filter", "", "tcpdump filter expression of packets, eg: \"tcp port 80\"")
+	duration := fs.Duration("duration", 30*time.Second, "duration of the capture, up to 5m")
+	count := fs.Int("count", 0, "stop after count packets captured, 0 for no limit")
+	snapLen := fs.Int("snaplen", 0, "bytes captured of each packet, 0 for default")
+	output := fs.String("o", "", "output pcap file, \"-\" for stdout, default <namespace>_<pod>.pcap")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: terway-cli capture [flags] <pod>")
+	}
+	pod := fs.Arg(0)
+
+	conn, err := grpc.Dial(*socket, grpc.WithInsecure(), grpc.WithDialer(
+		func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}))
+	if err != nil {
+		return fmt.Errorf("error dial terway daemon: %v", err)
+	}
+	defer conn.Close()
+
+	// daemon stops the capture on duration, extra time for flush of the data
+	ctx, cancel := context.WithTimeout(context.Background(), *duration+30*time.Second)
+	defer cancel()
+	stream, err := rpc.NewTerwayBackendClient(conn).CapturePod(ctx, &rpc.CaptureRequest{
+		K8SPodName:      pod,
+		K8SPodNamespace: *namespace,
+		Filter:          *filter,
+		DurationSeconds: int32(*duration / time.Second),
+		MaxPackets:      int32(*count),
+		SnapLen:         int32(*snapLen),
+	})
+	if err != nil {
+		return fmt.Errorf("error start capture: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output == "" {
+		*output = fmt.Sprintf("%s_%s.pcap", *namespace, pod)
+	}
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var size int
+	for {
+		reply, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("capture failed: %v", err)
+		}
+		if _, err = w.Write(reply.GetData()); err != nil {
+			return fmt.Errorf("error write capture: %v", err)
+		}
+		size += len(reply.GetData())
+	}
+	fmt.Fprintf(os.Stderr, "%d bytes captured of pod %s/%s to %s\n", size, *namespace, pod, *output)
+	return nil
+}
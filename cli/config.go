@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/daemon"
+)
+
+const defaultConfigPath = "/etc/eni/eni.json"
+
+func init() {
+	registerCommand("config check", "validate terway config with cloud resources before deploy", runConfigCheck)
+}
+
+func runConfigCheck(args []string) error {
+	fs := flag.NewFlagSet("config check", flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "path of terway config file")
+	daemonMode := fs.String("daemon-mode", "VPC", "terway network mode, overridden by daemon_mode of profile")
+	profile := fs.String("profile", "", "check cloud resources of the config profile instead of the global config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := daemon.CheckConfig(*configPath, *daemonMode, *profile); err != nil {
+		return fmt.Errorf("config check failed: %v", err)
+	}
+	if *profile != "" {
+		fmt.Printf("config %s is valid with profile %s\n", *configPath, *profile)
+		return nil
+	}
+	fmt.Printf("config %s is valid for mode %s\n", *configPath, *daemonMode)
+	return nil
+}
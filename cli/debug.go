@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand("debug dump", "collect goroutines, heap, pools and recent openapi calls of daemon into tarball", runDebugDump)
+}
+
+func runDebugDump(args []string) error {
+	fs := flag.NewFlagSet("debug dump", flag.ExitOnError)
+	debugSocket := fs.String("debug-socket", defaultDebugSocket, "debug socket of terway daemon")
+	output := fs.String("o", fmt.Sprintf("terway-dump-%s.tar.gz", time.Now().Format("20060102150405")), "output file of the dump")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, endpoint := debugClient(*debugSocket)
+	resp, err := client.Get(endpoint + "/debug/dump")
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("debug dump not enabled, restart terway daemon with --enable-pprof")
+	default:
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("dump failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err = io.Copy(f, resp.Body); err != nil {
+		return fmt.Errorf("error write dump to %s: %v", *output, err)
+	}
+	fmt.Printf("dump written to %s\n", *output)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func init() {
+	registerCommand("defrag", "plan or apply freeing enis of only idle ips by moving the ips to other enis", runDefrag)
+}
+
+type defragMove struct {
+	ENI   string `json:"eni"`
+	IPs   int    `json:"ips"`
+	Error string `json:"error"`
+}
+
+type defragReport struct {
+	Moves   []defragMove `json:"moves"`
+	Applied bool         `json:"applied"`
+}
+
+func runDefrag(args []string) error {
+	fs := flag.NewFlagSet("defrag", flag.ExitOnError)
+	debugSocket := fs.String("debug-socket", defaultDebugSocket, "debug socket of terway daemon")
+	apply := fs.Bool("apply", false, "move the ips and free the enis, only plan if false")
+	maxENIs := fs.Int("max-enis", 1, "enis freed at most")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, endpoint := debugClient(*debugSocket)
+	url := fmt.Sprintf("%s/debug/defrag?max_enis=%d", endpoint, *maxENIs)
+	var (
+		resp *http.Response
+		err  error
+	)
+	if *apply {
+		resp, err = client.Post(url, "", nil)
+	} else {
+		resp, err = client.Get(url)
+	}
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("defrag failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	var report defragReport
+	if err = json.Unmarshal(body, &report); err != nil {
+		return fmt.Errorf("error parse defrag report: %v", err)
+	}
+	if len(report.Moves) == 0 {
+		fmt.Println("no eni to free, enis with only idle ips or room on other enis not found")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ENI\tIPS\tRESULT")
+	failed := 0
+	for _, move := range report.Moves {
+		result := "planned"
+		if report.Applied {
+			result = "freed"
+		}
+		if move.Error != "" {
+			result = move.Error
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", move.ENI, move.IPs, result)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d enis failed to free", failed, len(report.Moves))
+	}
+	return nil
+}
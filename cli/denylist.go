@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	registerCommand("denylist show", "show ips denied to allocate by terway daemon", runDenyListShow)
+	registerCommand("denylist add", "deny ips to allocate to pods, eg: blocked by security incident", runDenyListAdd)
+	registerCommand("denylist remove", "allow ips denied before to allocate again", runDenyListRemove)
+}
+
+func runDenyListShow(args []string) error {
+	fs := flag.NewFlagSet("denylist show", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocket, "grpc socket of terway daemon")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	return callDenyList(*socket, func(ctx context.Context, client rpc.TerwayBackendClient) (*rpc.IPDenyListReply, error) {
+		return client.GetIPDenyList(ctx, &rpc.GetIPDenyListRequest{})
+	})
+}
+
+func runDenyListAdd(args []string) error {
+	fs := flag.NewFlagSet("denylist add", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocket, "grpc socket of terway daemon")
+	reason := fs.String("reason", "", "reason of the ips denied, eg: incident id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: terway-cli denylist add [flags] <ip>...")
+	}
+	return callDenyList(*socket, func(ctx context.Context, client rpc.TerwayBackendClient) (*rpc.IPDenyListReply, error) {
+		return client.UpdateIPDenyList(ctx, &rpc.UpdateIPDenyListRequest{Deny: fs.Args(), Reason: *reason})
+	})
+}
+
+func runDenyListRemove(args []string) error {
+	fs := flag.NewFlagSet("denylist remove", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocket, "grpc socket of terway daemon")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: terway-cli denylist remove [flags] <ip>...")
+	}
+	return callDenyList(*socket, func(ctx context.Context, client rpc.TerwayBackendClient) (*rpc.IPDenyListReply, error) {
+		return client.UpdateIPDenyList(ctx, &rpc.UpdateIPDenyListRequest{Allow: fs.Args()})
+	})
+}
+
+// callDenyList call rpc of ip deny list on daemon and print the deny list replied
+func callDenyList(socket string, call func(ctx context.Context, client rpc.TerwayBackendClient) (*rpc.IPDenyListReply, error)) error {
+	conn, err := grpc.Dial(socket, grpc.WithInsecure(), grpc.WithDialer(
+		func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}))
+	if err != nil {
+		return fmt.Errorf("error dial terway daemon: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reply, err := call(ctx, rpc.NewTerwayBackendClient(conn))
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "IP\tDENIED AT\tREASON")
+	for _, ip := range reply.IPs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", ip.IP, time.Unix(ip.DeniedAt, 0).Format(time.RFC3339), ip.Reason)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	registerCommand("drain", "release resources of pods and return pooled resources to cloud before node terminated", runDrain)
+}
+
+func runDrain(args []string) error {
+	fs := flag.NewFlagSet("drain", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocket, "grpc socket of terway daemon")
+	force := fs.Bool("force", false, "drain even if node not cordoned")
+	timeout := fs.Duration("timeout", 10*time.Minute, "timeout of drain")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conn, err := grpc.Dial(*socket, grpc.WithInsecure(), grpc.WithDialer(
+		func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}))
+	if err != nil {
+		return fmt.Errorf("error dial terway daemon: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	stream, err := rpc.NewTerwayBackendClient(conn).Drain(ctx, &rpc.DrainRequest{Force: *force})
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+	for {
+		progress, err := stream.Recv()
+		if err == io.EOF {
+			fmt.Println("drained")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error drain node: %v", err)
+		}
+		line := fmt.Sprintf("[%s %d/%d] %s", progress.Phase, progress.Done, progress.Total, progress.Message)
+		if progress.Error != "" {
+			line += " error: " + progress.Error
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AliyunContainerService/terway/pkg/feature"
+)
+
+func init() {
+	registerCommand("features", "show feature gates of terway daemon enabled on node", runFeatures)
+}
+
+func runFeatures(args []string) error {
+	fs := flag.NewFlagSet("features", flag.ExitOnError)
+	debugSocket := fs.String("debug-socket", defaultDebugSocket, "debug socket of terway daemon")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, endpoint := debugClient(*debugSocket)
+	resp, err := client.Get(endpoint + "/debug/features")
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get feature gates failed: %s", strings.TrimSpace(string(body)))
+	}
+	var states []feature.State
+	if err = json.Unmarshal(body, &states); err != nil {
+		return fmt.Errorf("error parse feature gates: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FEATURE\tSTAGE\tDEFAULT\tENABLED\tREASON")
+	for _, state := range states {
+		reason := state.Reason
+		if reason == "" {
+			reason = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%v\t%v\t%s\n", state.Name, state.Stage, state.Default, state.Enabled, reason)
+	}
+	return w.Flush()
+}
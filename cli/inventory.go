@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	registerCommand("inventory", "show resources in pools of terway daemon with state, age and owner pod", runInventory)
+}
+
+func runInventory(args []string) error {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocket, "grpc socket of terway daemon")
+	watch := fs.Bool("watch", false, "stream allocations, releases and garbage collections after listing, until interrupted")
+	eventTypes := fs.String("event-types", "", "comma separated types of events to watch: Allocate, Release or GC, all if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: terway-cli inventory [flags] [ip, resource id or namespace/pod]")
+	}
+
+	conn, err := grpc.Dial(*socket, grpc.WithInsecure(), grpc.WithDialer(
+		func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}))
+	if err != nil {
+		return fmt.Errorf("error dial terway daemon: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reply, err := rpc.NewTerwayBackendClient(conn).GetResourceInventory(ctx, &rpc.GetResourceInventoryRequest{Filter: fs.Arg(0)})
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+
+	now := time.Now()
+	age := func(unix int64) string {
+		if unix == 0 {
+			return "-"
+		}
+		return now.Sub(time.Unix(unix, 0)).Truncate(time.Second).String()
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tID\tIP\tSTATE\tPOD\tAGE\tLAST USED\tPINNED")
+	for _, res := range reply.Resources {
+		pod := "-"
+		if res.PodName != "" {
+			pod = res.PodNamespace + "/" + res.PodName
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%v\n", res.Type, res.ID, res.IP, res.State, pod,
+			age(res.Created), age(res.LastUsed), res.Pinned)
+	}
+	if err = w.Flush(); err != nil || !*watch {
+		return err
+	}
+
+	var types []string
+	if *eventTypes != "" {
+		types = strings.Split(*eventTypes, ",")
+	}
+	stream, err := rpc.NewTerwayBackendClient(conn).WatchEvents(context.Background(),
+		&rpc.WatchEventsRequest{Types: types, Filter: fs.Arg(0)})
+	if err != nil {
+		return fmt.Errorf("error watch events of terway daemon: %v", err)
+	}
+	fmt.Println()
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("error receive events of terway daemon: %v", err)
+		}
+		line := fmt.Sprintf("%s %-8s %s/%s sandbox %s", time.Unix(event.Timestamp, 0).Format(time.RFC3339), event.Type,
+			event.PodNamespace, event.PodName, event.SandboxID)
+		if event.ResourceID != "" {
+			line += fmt.Sprintf(" %s %s", event.ResourceType, event.ResourceID)
+		}
+		if event.Error != "" {
+			line += " error: " + event.Error
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command is a subcommand of terway-cli
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{}
+
+func registerCommand(name, usage string, run func(args []string) error) {
+	commands[name] = command{usage: usage, run: run}
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: terway-cli <command> [flags]\n\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	flag.Usage = printUsage
+	flag.Parse()
+	args := flag.Args()
+
+	// command name may have sub command, eg: "config check"
+	for i := len(args); i > 0; i-- {
+		cmd, ok := commands[strings.Join(args[:i], " ")]
+		if !ok {
+			continue
+		}
+		if err := cmd.run(args[i:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printUsage()
+	os.Exit(1)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func init() {
+	registerCommand("migrate", "plan or import ips of pods set up by flannel or calico for migrating to terway", runMigrate)
+}
+
+type migrateEntry struct {
+	IP        string `json:"ip"`
+	SandboxID string `json:"sandboxID"`
+	Pod       string `json:"pod"`
+	Result    string `json:"result"`
+	Reason    string `json:"reason"`
+}
+
+type migrateReport struct {
+	From    string         `json:"from"`
+	Entries []migrateEntry `json:"entries"`
+	Applied bool           `json:"applied"`
+}
+
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	debugSocket := fs.String("debug-socket", defaultDebugSocket, "debug socket of terway daemon")
+	from := fs.String("from", "flannel", "cni migrated from, flannel or calico")
+	dir := fs.String("dir", "", "dir of host-local ipam of the cni, default of the cni if empty")
+	apply := fs.Bool("apply", false, "import the ips, only plan if false")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, endpoint := debugClient(*debugSocket)
+	query := url.Values{"from": {*from}}
+	if *dir != "" {
+		query.Set("dir", *dir)
+	}
+	reqURL := fmt.Sprintf("%s/debug/migrate?%s", endpoint, query.Encode())
+	var (
+		resp *http.Response
+		err  error
+	)
+	if *apply {
+		resp, err = client.Post(reqURL, "", nil)
+	} else {
+		resp, err = client.Get(reqURL)
+	}
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("migrate failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	var report migrateReport
+	if err = json.Unmarshal(body, &report); err != nil {
+		return fmt.Errorf("error parse migrate report: %v", err)
+	}
+	if len(report.Entries) == 0 {
+		fmt.Printf("no ip of pods set up by %s found\n", report.From)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "IP\tPOD\tSANDBOX\tRESULT\tREASON")
+	failed := 0
+	for _, entry := range report.Entries {
+		if entry.Result == "failed" {
+			failed++
+		}
+		sandbox := entry.SandboxID
+		if len(sandbox) > 12 {
+			sandbox = sandbox[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", entry.IP, entry.Pod, sandbox, entry.Result, entry.Reason)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d ips failed to import", failed, len(report.Entries))
+	}
+	return nil
+}
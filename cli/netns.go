@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func init() {
+	registerCommand("netns leaks", "show netns files without any process left by container runtimes and their host veths", runNetnsLeaks)
+}
+
+type netnsLeak struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"modTime"`
+	Veths   []string  `json:"veths"`
+}
+
+type netnsLeakReport struct {
+	Leaks   []netnsLeak `json:"leaks"`
+	Cleaned bool        `json:"cleaned"`
+}
+
+func runNetnsLeaks(args []string) error {
+	fs := flag.NewFlagSet("netns leaks", flag.ExitOnError)
+	debugSocket := fs.String("debug-socket", defaultDebugSocket, "debug socket of terway daemon")
+	clean := fs.Bool("clean", false, "remove the leaked netns files and their host veths")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, endpoint := debugClient(*debugSocket)
+	var (
+		resp *http.Response
+		err  error
+	)
+	if *clean {
+		resp, err = client.Post(endpoint+"/debug/netns", "", nil)
+	} else {
+		resp, err = client.Get(endpoint + "/debug/netns")
+	}
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("scan netns leaks failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	var report netnsLeakReport
+	if err = json.Unmarshal(body, &report); err != nil {
+		return fmt.Errorf("error parse netns leaks: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NETNS\tAGE\tHOST VETHS")
+	for _, leak := range report.Leaks {
+		veths := "-"
+		if len(leak.Veths) != 0 {
+			veths = strings.Join(leak.Veths, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", leak.Path, time.Since(leak.ModTime).Truncate(time.Second), veths)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if report.Cleaned {
+		fmt.Printf("%d leaked netns removed\n", len(report.Leaks))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+const defaultDebugSocket = "unix:///var/run/eni/eni_debug.socket"
+
+func init() {
+	registerCommand("policy explain", "show which policy rule won for pod network settings", runPolicyExplain)
+}
+
+type policyDecision struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// debugClient http client to terway daemon debug server
+func debugClient(debugSocket string) (*http.Client, string) {
+	if !strings.HasPrefix(debugSocket, "unix://") {
+		return http.DefaultClient, "http://" + debugSocket
+	}
+	socketPath := strings.TrimPrefix(debugSocket, "unix://")
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}, "http://terway"
+}
+
+func runPolicyExplain(args []string) error {
+	fs := flag.NewFlagSet("policy explain", flag.ExitOnError)
+	debugSocket := fs.String("debug-socket", defaultDebugSocket, "debug socket of terway daemon")
+	namespace := fs.String("namespace", "default", "namespace of pod")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: terway-cli policy explain [-namespace ns] <pod>")
+	}
+
+	client, endpoint := debugClient(*debugSocket)
+	query := url.Values{"namespace": {*namespace}, "name": {fs.Arg(0)}}
+	resp, err := client.Get(endpoint + "/debug/explain?" + query.Encode())
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("explain failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	var decisions []policyDecision
+	if err = json.Unmarshal(body, &decisions); err != nil {
+		return fmt.Errorf("error parse explain result: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, d := range decisions {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Key, d.Value, d.Source)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func init() {
+	registerCommand("pool recommend", "recommend min and max idle of pools by acquires of pools on nodes", runPoolRecommend)
+}
+
+// poolGroupKey pool of resource type on nodes of group
+type poolGroupKey struct {
+	group   string
+	resType string
+}
+
+// poolGroupRecommendation recommendation of pools of nodes in group, the max of recommendations of nodes
+type poolGroupRecommendation struct {
+	nodes    int
+	acquires int
+	hits     int
+	rec      crd.PoolRecommendation
+}
+
+func (g *poolGroupRecommendation) add(rec *crd.PoolRecommendation) {
+	g.nodes++
+	g.acquires += rec.Acquires
+	g.hits += rec.Acquires * rec.HitRatePercent / 100
+	if rec.MinIdle > g.rec.MinIdle {
+		g.rec.MinIdle = rec.MinIdle
+	}
+	if rec.MaxIdle > g.rec.MaxIdle {
+		g.rec.MaxIdle = rec.MaxIdle
+	}
+	if rec.AvgWaitMs > g.rec.AvgWaitMs {
+		g.rec.AvgWaitMs = rec.AvgWaitMs
+	}
+	g.rec.CurrentMinIdle, g.rec.CurrentMaxIdle = rec.CurrentMinIdle, rec.CurrentMaxIdle
+}
+
+// groupRecommendations group recommendations of pools on nodes by group of node
+func groupRecommendations(states []crd.NodeNetworkState, groupOf func(node string) string) map[poolGroupKey]*poolGroupRecommendation {
+	groups := make(map[poolGroupKey]*poolGroupRecommendation)
+	for _, state := range states {
+		if state.Status.Summary == nil {
+			continue
+		}
+		for resType, pool := range state.Status.Summary.Pools {
+			if pool.Recommendation == nil {
+				continue
+			}
+			key := poolGroupKey{group: groupOf(state.Name), resType: resType}
+			if groups[key] == nil {
+				groups[key] = &poolGroupRecommendation{}
+			}
+			groups[key].add(pool.Recommendation)
+		}
+	}
+	return groups
+}
+
+func runPoolRecommend(args []string) error {
+	fs := flag.NewFlagSet("pool recommend", flag.ExitOnError)
+	kubeconfig := fs.String("kubeconfig", "", "path to kubeconfig file")
+	master := fs.String("master", "", "address of the kubernetes api server")
+	label := fs.String("label", "", "label of nodes grouping pools, eg: nodepool id, per node if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	restConfig, err := clientcmd.BuildConfigFromFlags(*master, *kubeconfig)
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	states, err := crd.NewClient(client.Discovery().RESTClient()).List()
+	if err != nil {
+		return fmt.Errorf("error list node network states: %v", err)
+	}
+	groupOf := func(node string) string { return node }
+	if *label != "" {
+		nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("error list nodes: %v", err)
+		}
+		groups := make(map[string]string, len(nodes.Items))
+		for _, node := range nodes.Items {
+			groups[node.Name] = node.Labels[*label]
+		}
+		groupOf = func(node string) string {
+			if group := groups[node]; group != "" {
+				return group
+			}
+			return "<none>"
+		}
+	}
+
+	groups := groupRecommendations(states, groupOf)
+	keys := make([]poolGroupKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].group != keys[j].group {
+			return keys[i].group < keys[j].group
+		}
+		return keys[i].resType < keys[j].resType
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tTYPE\tNODES\tACQUIRES\tHIT RATE\tMAX AVG WAIT\tMIN IDLE\tMAX IDLE\tRECOMMENDED MIN IDLE\tRECOMMENDED MAX IDLE")
+	for _, key := range keys {
+		g := groups[key]
+		hitRate := "-"
+		if g.acquires != 0 {
+			hitRate = fmt.Sprintf("%d%%", g.hits*100/g.acquires)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%dms\t%d\t%d\t%d\t%d\n", key.group, key.resType, g.nodes, g.acquires, hitRate,
+			g.rec.AvgWaitMs, g.rec.CurrentMinIdle, g.rec.CurrentMaxIdle, g.rec.MinIdle, g.rec.MaxIdle)
+	}
+	return w.Flush()
+}
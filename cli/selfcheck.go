@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func init() {
+	registerCommand("selfcheck", "show report of self check of terway daemon on start with hints to fix", runSelfCheck)
+}
+
+// selfCheckReport report of self check served by daemon at /readyz
+type selfCheckReport struct {
+	Time    time.Time `json:"time"`
+	Ready   bool      `json:"ready"`
+	Policy  string    `json:"policy"`
+	Results []struct {
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Hint    string `json:"hint"`
+	} `json:"results"`
+}
+
+func runSelfCheck(args []string) error {
+	fs := flag.NewFlagSet("selfcheck", flag.ExitOnError)
+	debugSocket := fs.String("debug-socket", defaultDebugSocket, "debug socket of terway daemon")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, endpoint := debugClient(*debugSocket)
+	resp, err := client.Get(endpoint + "/readyz")
+	if err != nil {
+		return fmt.Errorf("error request terway daemon, run terway-cli config check if daemon not started: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return fmt.Errorf("self check failed: %s", strings.TrimSpace(string(body)))
+	}
+	report := &selfCheckReport{}
+	if err = json.Unmarshal(body, report); err != nil {
+		return fmt.Errorf("error parse self check report: %v", err)
+	}
+
+	fmt.Printf("checked at %s, policy %s\n", report.Time.Format(time.RFC3339), report.Policy)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tMESSAGE\tHINT")
+	for _, result := range report.Results {
+		hint := result.Hint
+		if hint == "" {
+			hint = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Name, result.Status, result.Message, hint)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if !report.Ready {
+		return fmt.Errorf("terway daemon not ready by self check")
+	}
+	return nil
+}
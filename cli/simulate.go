@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"google.golang.org/grpc"
+)
+
+func init() {
+	registerCommand("simulate", "check whether pod could be allocated network on node now, nothing allocated", runSimulate)
+}
+
+func runSimulate(args []string) error {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	socket := fs.String("socket", defaultSocket, "grpc socket of terway daemon")
+	podNetworkType := fs.String("pod-network-type", "", "VPCIP, VPCENI or ENIMultiIP, by policy of pod if empty")
+	priorityClass := fs.String("priority-class", "", "priority class of pod not created yet")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: terway-cli simulate [flags] <namespace/pod or namespace>")
+	}
+	parts := strings.SplitN(fs.Arg(0), "/", 2)
+	req := &rpc.SimulateAllocationRequest{
+		K8SPodNamespace:   parts[0],
+		PodNetworkType:    *podNetworkType,
+		PriorityClassName: *priorityClass,
+	}
+	if len(parts) == 2 {
+		req.K8SPodName = parts[1]
+	}
+
+	conn, err := grpc.Dial(*socket, grpc.WithInsecure(), grpc.WithDialer(
+		func(addr string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout("unix", addr, timeout)
+		}))
+	if err != nil {
+		return fmt.Errorf("error dial terway daemon: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	reply, err := rpc.NewTerwayBackendClient(conn).SimulateAllocation(ctx, req)
+	if err != nil {
+		return fmt.Errorf("error request terway daemon: %v", err)
+	}
+	fmt.Printf("pod network type: %s, resource type: %s, idle: %d, free: %d, need create: %v\n",
+		reply.PodNetworkType, reply.ResourceType, reply.Idle, reply.Free, reply.NeedCreate)
+	if !reply.Allocatable {
+		return fmt.Errorf("not allocatable: %s", reply.Reason)
+	}
+	fmt.Println("allocatable")
+	return nil
+}
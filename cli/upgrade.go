@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AliyunContainerService/terway/daemon"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/version"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// cni config of terway, the config list rendered by daemon if cni_conf configured
+const (
+	terwayCNIConf     = "10-terway.conf"
+	terwayCNIConfList = "10-terway.conflist"
+)
+
+func init() {
+	registerCommand("upgrade preflight", "check all nodes are ready for upgrading terway to this version", runUpgradePreflight)
+}
+
+// nodePreflight the go/no-go result of node
+type nodePreflight struct {
+	node    string
+	reasons []string
+}
+
+func (n *nodePreflight) fail(format string, args ...interface{}) {
+	n.reasons = append(n.reasons, fmt.Sprintf(format, args...))
+}
+
+// preflightNode check the node against the target datapath of upgrade
+func preflightNode(node string, kernelRelease string, state *crd.NodeNetworkState, daemonMode, eniIPVirtualType string) *nodePreflight {
+	result := &nodePreflight{node: node}
+	if state == nil {
+		result.fail("no network state published, terway daemon not running or too old")
+		return result
+	}
+	if daemonMode == "" {
+		daemonMode = state.Spec.DaemonMode
+	}
+
+	if err := daemon.CheckDatapathKernel(daemonMode, eniIPVirtualType, kernelRelease); err != nil {
+		result.fail("%v", err)
+	}
+
+	facts := state.Status.Node
+	if facts == nil {
+		// daemon before facts published writes the first schema
+		facts = &crd.NodeFacts{DBSchemaVersion: version.MinDBSchemaVersion}
+	}
+	if facts.DBSchemaVersion < version.MinDBSchemaVersion || facts.DBSchemaVersion > version.DBSchemaVersion {
+		result.fail("db schema %d not supported, supported: %d-%d",
+			facts.DBSchemaVersion, version.MinDBSchemaVersion, version.DBSchemaVersion)
+	}
+	if len(facts.CNIConfFiles) != 0 && facts.CNIConfFiles[0] != terwayCNIConf && facts.CNIConfFiles[0] != terwayCNIConfList {
+		result.fail("cni config %s takes precedence over %s", facts.CNIConfFiles[0], terwayCNIConf)
+	}
+	return result
+}
+
+func runUpgradePreflight(args []string) error {
+	fs := flag.NewFlagSet("upgrade preflight", flag.ExitOnError)
+	kubeconfig := fs.String("kubeconfig", "", "path to kubeconfig file")
+	master := fs.String("master", "", "address of the kubernetes api server")
+	daemonMode := fs.String("daemon-mode", "", "daemon mode after upgrade, keep current mode of node if empty")
+	eniIPVirtualType := fs.String("eniip-virtual-type", "", "eniip virtual type after upgrade, eg: IPVlan")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	restConfig, err := clientcmd.BuildConfigFromFlags(*master, *kubeconfig)
+	if err != nil {
+		return err
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+	crdClient := crd.NewClient(client.Discovery().RESTClient())
+
+	nodes, err := client.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error list nodes: %v", err)
+	}
+	states := make(map[string]*crd.NodeNetworkState)
+	stateList, err := crdClient.List()
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error list node network states: %v", err)
+	}
+	for i := range stateList {
+		states[stateList[i].Name] = &stateList[i]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tRESULT\tREASONS")
+	noGo := 0
+	for _, node := range nodes.Items {
+		result := preflightNode(node.Name, node.Status.NodeInfo.KernelVersion, states[node.Name], *daemonMode, *eniIPVirtualType)
+		status := "go"
+		if len(result.reasons) != 0 {
+			status = "no-go"
+			noGo++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.node, status, strings.Join(result.reasons, "; "))
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if noGo != 0 {
+		return fmt.Errorf("%d of %d nodes not ready for upgrade", noGo, len(nodes.Items))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/pkg/defaults"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// stateChecker compare checksum of node states published by daemons with cloud view,
+// flag the diverged nodes for targeted reconciliation
+type stateChecker struct {
+	client      crd.Client
+	ecs         aliyun.ECS
+	stableGrace time.Duration
+}
+
+// exclusiveENIs macs of enis in daemon resources as a whole, the others are ids of eni ips as mac.ip
+func exclusiveENIs(resources []string) map[string]bool {
+	macs := make(map[string]bool)
+	for _, id := range resources {
+		if !strings.Contains(id, ".") {
+			macs[id] = true
+		}
+	}
+	return macs
+}
+
+// cloudResources resource ids of node view from cloud, same as daemon pool, enis of exclusive pool
+// in hybrid mode by the resources of daemon
+func cloudResources(daemonMode string, enis []*aliyun.InstanceENI, resources []string) []string {
+	var ids []string
+	var exclusive map[string]bool
+	if daemonMode == defaults.ModeHybrid {
+		exclusive = exclusiveENIs(resources)
+	}
+	for _, eni := range enis {
+		res := &types.ENI{MAC: eni.MAC}
+		// vpc ips of dual mode not cloud resources
+		shared := daemonMode == defaults.ModeENIMultiIP || daemonMode == defaults.ModeDual ||
+			(daemonMode == defaults.ModeHybrid && !exclusive[eni.MAC])
+		if !shared {
+			ids = append(ids, res.GetResourceID())
+			continue
+		}
+		for _, ip := range eni.IPs {
+			ids = append(ids, (&types.ENIIP{Eni: res, SecAddress: ip}).GetResourceID())
+		}
+	}
+	return ids
+}
+
+func (c *stateChecker) check() error {
+	states, err := c.client.List()
+	if err != nil {
+		return errors.Wrapf(err, "error list node network states")
+	}
+	if len(states) == 0 {
+		return nil
+	}
+	// one region scan instead of describe per node
+	enis, err := c.ecs.ListInstanceENIs()
+	if err != nil {
+		return errors.Wrapf(err, "error list instance enis")
+	}
+	instanceENIs := make(map[string][]*aliyun.InstanceENI)
+	for _, eni := range enis {
+		instanceENIs[eni.InstanceID] = append(instanceENIs[eni.InstanceID], eni)
+	}
+
+	for i := range states {
+		state := &states[i]
+		cloud := cloudResources(state.Spec.DaemonMode, instanceENIs[state.Spec.InstanceID], state.Status.Resources)
+		cloudChecksum := crd.Checksum(cloud)
+
+		diverged := state.Status.Diverged
+		if cloudChecksum == state.Status.Checksum {
+			diverged = false
+		} else if time.Since(state.Status.UpdateTime.Time) > c.stableGrace {
+			// daemon state is stable but still differ from cloud
+			diverged = true
+		}
+		if diverged == state.Status.Diverged && cloudChecksum == state.Status.CloudChecksum {
+			continue
+		}
+
+		state.Status.CloudChecksum = cloudChecksum
+		state.Status.Diverged = diverged
+		state.Status.DivergedReason = ""
+		if diverged {
+			state.Status.DivergedReason = crd.Diff(state.Status.Resources, cloud)
+			log.Warnf("node %s state diverged from cloud: %s", state.Name, state.Status.DivergedReason)
+		}
+		state.Status.CheckTime = metav1.Now()
+		if _, err = c.client.Update(state); err != nil {
+			log.Errorf("error update node network state %s: %v", state.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/defaults"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudResources(t *testing.T) {
+	enis := []*aliyun.InstanceENI{
+		{MAC: "00:16:3e:00:00:01", IPs: []net.IP{net.ParseIP("192.168.0.1"), net.ParseIP("192.168.0.2")}},
+		{MAC: "00:16:3e:00:00:02", IPs: []net.IP{net.ParseIP("192.168.0.3")}},
+	}
+	eniIPs := []string{"00:16:3e:00:00:01.192.168.0.1", "00:16:3e:00:00:01.192.168.0.2", "00:16:3e:00:00:02.192.168.0.3"}
+
+	assert.Equal(t, []string{"00:16:3e:00:00:01", "00:16:3e:00:00:02"}, cloudResources(defaults.ModeENIOnly, enis, nil))
+	assert.Equal(t, eniIPs, cloudResources(defaults.ModeENIMultiIP, enis, nil))
+	assert.Equal(t, eniIPs, cloudResources(defaults.ModeDual, enis, nil))
+
+	// hybrid: exclusive eni as a whole, ips of shared eni
+	resources := []string{"00:16:3e:00:00:01.192.168.0.1", "00:16:3e:00:00:01.192.168.0.2", "00:16:3e:00:00:02"}
+	assert.Equal(t, resources, cloudResources(defaults.ModeHybrid, enis, resources))
+	// hybrid without exclusive eni in daemon view
+	assert.Equal(t, eniIPs, cloudResources(defaults.ModeHybrid, enis, eniIPs[:2]))
+}
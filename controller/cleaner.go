@@ -0,0 +1,163 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const eniStatusAvailable = "Available"
+
+// eniCleaner delete terway enis orphaned by deleted nodes, which node-local gc can never release,
+// only enis in vswitches or security group of cluster and tagged with id of cluster considered as
+// vpc may be shared by clusters
+type eniCleaner struct {
+	ecs       aliyun.ECS
+	k8sClient kubernetes.Interface
+	states    crd.Client
+	// grace an eni kept orphaned before deleted, cover enis creating or attaching by daemons
+	grace         time.Duration
+	vSwitches     map[string]bool
+	securityGroup string
+	// clusterID id of cluster tagged on enis by daemons
+	clusterID string
+	// orphanSince the time eni first found orphaned
+	orphanSince map[string]time.Time
+}
+
+// instanceIDOfNode return ecs instance id from provider id of node, eg: "cn-hangzhou.i-xxx"
+func instanceIDOfNode(node *corev1.Node) string {
+	providerID := node.Spec.ProviderID
+	return providerID[strings.LastIndex(providerID, ".")+1:]
+}
+
+// liveInstances return ecs instance ids of nodes in cluster
+func (c *eniCleaner) liveInstances() (map[string]bool, error) {
+	nodes, err := c.k8sClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list nodes")
+	}
+	states, err := c.states.List()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list node network states")
+	}
+	live := make(map[string]bool)
+	nodeNames := make(map[string]bool)
+	for i := range nodes.Items {
+		nodeNames[nodes.Items[i].Name] = true
+		if id := instanceIDOfNode(&nodes.Items[i]); id != "" {
+			live[id] = true
+		}
+	}
+	for _, state := range states {
+		if nodeNames[state.Name] && state.Spec.InstanceID != "" {
+			live[state.Spec.InstanceID] = true
+		}
+	}
+	return live, nil
+}
+
+// inCluster whether eni in vswitches and security group of cluster
+func (c *eniCleaner) inCluster(eni *aliyun.InstanceENI) bool {
+	if len(c.vSwitches) > 0 && !c.vSwitches[eni.VSwitchID] {
+		return false
+	}
+	if c.securityGroup == "" {
+		return true
+	}
+	for _, sg := range eni.SecurityGroups {
+		if sg == c.securityGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// orphaned whether eni not attached to instance of live nodes, enis left detached of live instance
+// kept for daemon to adopt on reinstall
+func (c *eniCleaner) orphaned(eni *aliyun.InstanceENI, live map[string]bool) bool {
+	if !c.inCluster(eni) {
+		return false
+	}
+	if eni.InstanceID != "" {
+		return !live[eni.InstanceID]
+	}
+	return eni.Owner == "" || !live[eni.Owner]
+}
+
+func (c *eniCleaner) cleanup() error {
+	live, err := c.liveInstances()
+	if err != nil {
+		return err
+	}
+	// never treat all enis as orphaned on empty view of cluster
+	if len(live) == 0 {
+		return errors.New("no node found in cluster, skip cleanup of orphaned enis")
+	}
+	return c.cleanupENIs(live, time.Now())
+}
+
+// ownedByCluster whether eni tagged with id of cluster, enis of other clusters in same vswitches or
+// security group never deleted
+func (c *eniCleaner) ownedByCluster(eniID string) (bool, error) {
+	tags, err := c.ecs.GetENITags(eniID)
+	if err != nil {
+		return false, err
+	}
+	return tags[aliyun.TagKeyClusterID] == c.clusterID, nil
+}
+
+// cleanupENIs delete enis orphaned longer than grace, not attached to instances of live nodes
+func (c *eniCleaner) cleanupENIs(live map[string]bool, now time.Time) error {
+	enis, err := c.ecs.ListTerwayENIs()
+	if err != nil {
+		return errors.Wrapf(err, "error list terway enis")
+	}
+
+	orphanSince := make(map[string]time.Time)
+	for _, eni := range enis {
+		if !c.orphaned(eni, live) {
+			continue
+		}
+		since, ok := c.orphanSince[eni.ID]
+		if !ok {
+			since = now
+		}
+		if now.Sub(since) < c.grace {
+			orphanSince[eni.ID] = since
+			continue
+		}
+
+		owned, err := c.ownedByCluster(eni.ID)
+		if err != nil {
+			log.Errorf("error get tags of orphaned eni %s: %v", eni.ID, err)
+			orphanSince[eni.ID] = since
+			continue
+		}
+		if !owned {
+			log.Debugf("eni %s of instance %q not tagged with cluster %s, skip", eni.ID, eni.InstanceID, c.clusterID)
+			continue
+		}
+
+		if eni.Status == eniStatusAvailable {
+			err = c.ecs.DeleteENI(eni.ID)
+		} else {
+			err = c.ecs.FreeENI(eni.ID, eni.InstanceID)
+		}
+		if err != nil {
+			log.Errorf("error delete orphaned eni %s of instance %q: %v", eni.ID, eni.InstanceID, err)
+			orphanSince[eni.ID] = since
+			continue
+		}
+		log.Infof("deleted eni %s orphaned since %v, instance %q not in cluster", eni.ID, since, eni.InstanceID)
+	}
+	c.orphanSince = orphanSince
+	return nil
+}
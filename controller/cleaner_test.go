@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestInstanceIDOfNode(t *testing.T) {
+	assert.Equal(t, "i-xxx", instanceIDOfNode(&corev1.Node{Spec: corev1.NodeSpec{ProviderID: "cn-hangzhou.i-xxx"}}))
+	assert.Equal(t, "", instanceIDOfNode(&corev1.Node{}))
+}
+
+func TestENICleanerInCluster(t *testing.T) {
+	c := &eniCleaner{vSwitches: map[string]bool{"vsw-1": true}, securityGroup: "sg-1"}
+	assert.True(t, c.inCluster(&aliyun.InstanceENI{VSwitchID: "vsw-1", SecurityGroups: []string{"sg-0", "sg-1"}}))
+	assert.False(t, c.inCluster(&aliyun.InstanceENI{VSwitchID: "vsw-2", SecurityGroups: []string{"sg-1"}}))
+	assert.False(t, c.inCluster(&aliyun.InstanceENI{VSwitchID: "vsw-1", SecurityGroups: []string{"sg-2"}}))
+
+	c = &eniCleaner{securityGroup: "sg-1"}
+	assert.True(t, c.inCluster(&aliyun.InstanceENI{VSwitchID: "vsw-2", SecurityGroups: []string{"sg-1"}}))
+}
+
+func TestENICleanerOrphaned(t *testing.T) {
+	c := &eniCleaner{securityGroup: "sg-1"}
+	live := map[string]bool{"i-1": true}
+	sg := []string{"sg-1"}
+	assert.False(t, c.orphaned(&aliyun.InstanceENI{InstanceID: "i-1", SecurityGroups: sg}, live))
+	assert.True(t, c.orphaned(&aliyun.InstanceENI{InstanceID: "i-2", SecurityGroups: sg}, live))
+	assert.False(t, c.orphaned(&aliyun.InstanceENI{InstanceID: "i-2", SecurityGroups: []string{"sg-2"}}, live))
+	// detached enis of live instance kept for adoption
+	assert.False(t, c.orphaned(&aliyun.InstanceENI{Owner: "i-1", Status: eniStatusAvailable, SecurityGroups: sg}, live))
+	assert.True(t, c.orphaned(&aliyun.InstanceENI{Owner: "i-2", Status: eniStatusAvailable, SecurityGroups: sg}, live))
+	assert.True(t, c.orphaned(&aliyun.InstanceENI{Status: eniStatusAvailable, SecurityGroups: sg}, live))
+}
+
+type cleanerECS struct {
+	aliyun.ECS
+	enis    []*aliyun.InstanceENI
+	tags    map[string]map[string]string
+	deleted []string
+}
+
+func (e *cleanerECS) ListTerwayENIs() ([]*aliyun.InstanceENI, error) {
+	return e.enis, nil
+}
+
+func (e *cleanerECS) GetENITags(eniID string) (map[string]string, error) {
+	return e.tags[eniID], nil
+}
+
+func (e *cleanerECS) DeleteENI(eniID string) error {
+	e.deleted = append(e.deleted, eniID)
+	return nil
+}
+
+func (e *cleanerECS) FreeENI(eniID string, instanceID string) error {
+	e.deleted = append(e.deleted, eniID)
+	return nil
+}
+
+func TestENICleanerForeignCluster(t *testing.T) {
+	sg := []string{"sg-1"}
+	ecs := &cleanerECS{
+		enis: []*aliyun.InstanceENI{
+			{ID: "eni-own", InstanceID: "i-2", SecurityGroups: sg},
+			// eni of other cluster in the same security group
+			{ID: "eni-foreign", InstanceID: "i-3", SecurityGroups: sg},
+			{ID: "eni-untagged", Status: eniStatusAvailable, SecurityGroups: sg},
+			{ID: "eni-live", InstanceID: "i-1", SecurityGroups: sg},
+		},
+		tags: map[string]map[string]string{
+			"eni-own":     {aliyun.TagKeyClusterID: "c-1"},
+			"eni-foreign": {aliyun.TagKeyClusterID: "c-2"},
+			"eni-live":    {aliyun.TagKeyClusterID: "c-1"},
+		},
+	}
+	c := &eniCleaner{ecs: ecs, securityGroup: "sg-1", clusterID: "c-1", grace: time.Minute,
+		orphanSince: make(map[string]time.Time)}
+	live := map[string]bool{"i-1": true}
+
+	now := time.Now()
+	assert.NoError(t, c.cleanupENIs(live, now))
+	assert.Empty(t, ecs.deleted)
+	assert.NoError(t, c.cleanupENIs(live, now.Add(time.Minute)))
+	assert.Equal(t, []string{"eni-own"}, ecs.deleted)
+}
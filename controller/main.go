@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const defaultConfigPath = "/etc/eni/eni.json"
+
+var (
+	gitVer      string
+	logLevel    string
+	kubeconfig  string
+	master      string
+	configPath  string
+	checkPeriod time.Duration
+	stableGrace time.Duration
+
+	cleanupOrphanENIs bool
+	orphanENIGrace    time.Duration
+	auditLog          string
+
+	configureRoutes bool
+	clusterCIDR     string
+	routeTables     string
+	repairRoutes    bool
+
+	aggregateIPUsage bool
+	nodePoolLabel    string
+	metricsListen    string
+)
+
+func init() {
+	flag.StringVar(&logLevel, "log-level", "info", "terway controller log level")
+	flag.StringVar(&master, "master", "", "The address of the Kubernetes API server (overrides any value in kubeconfig).")
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
+	flag.StringVar(&configPath, "config", defaultConfigPath, "terway config file with openapi credential")
+	flag.DurationVar(&checkPeriod, "check-period", time.Minute, "period to compare node state with cloud")
+	flag.DurationVar(&stableGrace, "stable-grace", 2*time.Minute, "node state unchanged time before it can be flagged diverged")
+	flag.BoolVar(&cleanupOrphanENIs, "cleanup-orphan-enis", false, "delete terway enis orphaned by nodes deleted from cluster, requires cluster_id, and vswitches or security_group in config")
+	flag.DurationVar(&orphanENIGrace, "orphan-eni-grace", 10*time.Minute, "time an eni kept orphaned before deleted")
+	flag.StringVar(&auditLog, "audit-log", "", "file recording openapi calls deleting orphaned enis, disabled if empty")
+	flag.BoolVar(&configureRoutes, "configure-routes", false, "create routes of pod cidrs of nodes in vpc route tables for VPC mode, requires cluster-cidr")
+	flag.StringVar(&clusterCIDR, "cluster-cidr", "", "cidr of pods in cluster, route entries in it not of any node deleted")
+	flag.StringVar(&routeTables, "route-tables", "", "comma separated ids of route tables routes created in, system route table of vpc if empty")
+	flag.BoolVar(&repairRoutes, "repair-route-conflicts", true, "replace route entries of pod cidrs of nodes to other instances in cluster-cidr, only reported by events if false")
+	flag.BoolVar(&aggregateIPUsage, "aggregate-ip-usage", true, "aggregate ip usage of cluster by vswitch and nodepool into cluster network state and metrics")
+	flag.StringVar(&nodePoolLabel, "nodepool-label", "alibabacloud.com/nodepool-id", "label of nodes naming their nodepool in ip usage")
+	flag.StringVar(&metricsListen, "metrics-listen", ":9190", "address of prometheus metrics of controller, disabled if empty")
+}
+
+func main() {
+	flag.Parse()
+	level, err := log.ParseLevel(logLevel)
+	if err != nil {
+		log.Fatalf("error set log level: %s: %v", logLevel, err)
+	}
+	log.SetLevel(level)
+	log.Infof("Starting terway controller of version: %s", gitVer)
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		log.Fatalf("failed read file %s: %v", configPath, err)
+	}
+	config := &types.Configure{}
+	if err = json.Unmarshal(data, config); err != nil {
+		log.Fatalf("failed parse config: %v", err)
+	}
+
+	ecs, err := aliyun.NewECS(config.AccessID, config.AccessSecret, "")
+	if err != nil {
+		log.Fatalf("error init ecs client: %v", err)
+	}
+	ecs, err = ecs.WithENIAccount(aliyun.NewENIAccount(config))
+	if err != nil {
+		log.Fatalf("error init ecs client of eni account: %v", err)
+	}
+	if auditLog != "" {
+		if err = aliyun.EnableAuditLog(auditLog, aliyun.DefaultAuditLogMaxSize); err != nil {
+			log.Fatalf("error enable audit log: %v", err)
+		}
+	}
+
+	k8sRestConfig, err := clientcmd.BuildConfigFromFlags(master, kubeconfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	k8sClient, err := kubernetes.NewForConfig(k8sRestConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	stateClient := crd.NewClient(k8sClient.Discovery().RESTClient())
+	c := &stateChecker{
+		client:      stateClient,
+		ecs:         ecs,
+		stableGrace: stableGrace,
+	}
+
+	var cleaner *eniCleaner
+	if cleanupOrphanENIs {
+		cleaner = &eniCleaner{
+			ecs:           ecs.WithPriority(aliyun.PriorityBackground),
+			k8sClient:     k8sClient,
+			states:        stateClient,
+			grace:         orphanENIGrace,
+			vSwitches:     make(map[string]bool),
+			securityGroup: config.SecurityGroup,
+			clusterID:     config.ClusterID,
+			orphanSince:   make(map[string]time.Time),
+		}
+		for _, vSwitches := range config.VSwitches {
+			for _, vSwitch := range vSwitches {
+				cleaner.vSwitches[vSwitch] = true
+			}
+		}
+		if len(cleaner.vSwitches) == 0 && cleaner.securityGroup == "" {
+			log.Fatalf("cleanup of orphaned enis requires vswitches or security_group in config to scope enis of cluster")
+		}
+		if cleaner.clusterID == "" {
+			log.Fatalf("cleanup of orphaned enis requires cluster_id in config, only enis tagged with it deleted")
+		}
+	}
+
+	var router *routeController
+	if configureRoutes {
+		router = &routeController{
+			ecs:             ecs.WithPriority(aliyun.PriorityBackground),
+			k8sClient:       k8sClient,
+			repairConflicts: repairRoutes,
+			quotaExceeded:   make(map[string]bool),
+			conflicts:       make(map[string]bool),
+		}
+		if _, router.clusterCIDR, err = net.ParseCIDR(clusterCIDR); err != nil {
+			log.Fatalf("configure routes requires valid cluster-cidr of pods: %q", clusterCIDR)
+		}
+		if routeTables != "" {
+			router.routeTables = strings.Split(routeTables, ",")
+		} else {
+			vpcID, err := aliyun.GetLocalVPC()
+			if err != nil {
+				log.Fatalf("error get vpc of controller: %v", err)
+			}
+			if router.routeTables, err = ecs.GetVPCRouteTables(vpcID); err != nil {
+				log.Fatalf("error get route tables of vpc %s: %v", vpcID, err)
+			}
+		}
+		log.Infof("configure routes of pod cidrs in %s to route tables %v", router.clusterCIDR, router.routeTables)
+	}
+
+	var aggregator *usageAggregator
+	if aggregateIPUsage {
+		aggregator = &usageAggregator{
+			ecs:           ecs.WithPriority(aliyun.PriorityBackground),
+			k8sClient:     k8sClient,
+			states:        stateClient,
+			cluster:       crd.NewClusterClient(k8sClient.Discovery().RESTClient()),
+			nodePoolLabel: nodePoolLabel,
+		}
+		if aggregator.vpcID, err = aliyun.GetLocalVPC(); err != nil {
+			log.Fatalf("error get vpc of controller: %v", err)
+		}
+	}
+
+	if metricsListen != "" {
+		metric.RegisterClusterPrometheus()
+		http.Handle("/metrics", promhttp.Handler())
+		go func() {
+			log.Fatal(http.ListenAndServe(metricsListen, nil))
+		}()
+	}
+
+	for {
+		if err = c.check(); err != nil {
+			log.Errorf("error check node network states: %v", err)
+		}
+		if cleaner != nil {
+			if err = cleaner.cleanup(); err != nil {
+				log.Errorf("error cleanup orphaned enis: %v", err)
+			}
+		}
+		if router != nil {
+			if err = router.sync(); err != nil {
+				log.Errorf("error sync routes of nodes: %v", err)
+			}
+		}
+		if aggregator != nil {
+			if err = aggregator.aggregate(); err != nil {
+				log.Errorf("error aggregate ip usage of cluster: %v", err)
+			}
+		}
+		time.Sleep(checkPeriod)
+	}
+}
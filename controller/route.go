@@ -0,0 +1,300 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// reasons of NetworkUnavailable condition of node, same as route controller of cloud-controller-manager
+const (
+	routeReasonCreated       = "RouteCreated"
+	routeReasonNotCreated    = "NoRouteCreated"
+	routeReasonQuotaExceeded = "RouteQuotaExceeded"
+	routeReasonConflict      = "RouteConflict"
+)
+
+// routeController program pod cidrs of nodes into route tables of vpc in VPC mode, in place of the route
+// controller of cloud-controller-manager, entries in cluster cidr not of any node deleted
+type routeController struct {
+	ecs         aliyun.ECS
+	k8sClient   kubernetes.Interface
+	routeTables []string
+	clusterCIDR *net.IPNet
+	// repairConflicts replace entries of pod cidrs of nodes to other instances, only reported if false
+	repairConflicts bool
+	// quotaExceeded route tables failed with quota exceeded on last sync, warned once
+	quotaExceeded map[string]bool
+	// conflicts found on last sync, event recorded once
+	conflicts map[string]bool
+}
+
+// nodeRoute pod cidr of node routed to its instance
+type nodeRoute struct {
+	node       string
+	cidr       *net.IPNet
+	instanceID string
+}
+
+// nodeRoutes return routes of nodes with pod cidr and instance in cluster cidr
+func nodeRoutes(nodes []corev1.Node, clusterCIDR *net.IPNet) []nodeRoute {
+	var routes []nodeRoute
+	for i := range nodes {
+		node := &nodes[i]
+		instanceID := instanceIDOfNode(node)
+		if node.Spec.PodCIDR == "" || instanceID == "" {
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(node.Spec.PodCIDR)
+		if err != nil || !clusterCIDR.Contains(cidr.IP) {
+			log.Warnf("pod cidr %q of node %s not in cluster cidr %s, skip route", node.Spec.PodCIDR, node.Name, clusterCIDR)
+			continue
+		}
+		routes = append(routes, nodeRoute{node: node.Name, cidr: cidr, instanceID: instanceID})
+	}
+	return routes
+}
+
+// managedEntry whether entry managed by controller, to an instance and in cluster cidr
+func managedEntry(entry *aliyun.RouteEntry, clusterCIDR *net.IPNet) bool {
+	ones, _ := entry.CIDR.Mask.Size()
+	clusterOnes, _ := clusterCIDR.Mask.Size()
+	return entry.NextHopType == aliyun.RouteNextHopInstance && clusterCIDR.Contains(entry.CIDR.IP) && ones > clusterOnes
+}
+
+// routeChanges return entries of route table to delete, managed but not of nodes, and routes of
+// nodes to create
+func routeChanges(entries []*aliyun.RouteEntry, routes []nodeRoute, clusterCIDR *net.IPNet) ([]*aliyun.RouteEntry, []nodeRoute) {
+	wanted := make(map[string]string, len(routes))
+	for _, route := range routes {
+		wanted[route.cidr.String()] = route.instanceID
+	}
+	existing := make(map[string]bool)
+	var stale []*aliyun.RouteEntry
+	for _, entry := range entries {
+		if !managedEntry(entry, clusterCIDR) {
+			continue
+		}
+		if wanted[entry.CIDR.String()] == entry.InstanceID {
+			existing[entry.CIDR.String()] = true
+			continue
+		}
+		stale = append(stale, entry)
+	}
+	var missing []nodeRoute
+	for _, route := range routes {
+		if !existing[route.cidr.String()] {
+			missing = append(missing, route)
+		}
+	}
+	return stale, missing
+}
+
+// routeConflict entry of route table hijacking pod cidr of node, eg: left by the instance of a replaced node
+// with the cidr reused
+type routeConflict struct {
+	route nodeRoute
+	entry *aliyun.RouteEntry
+	// repairable entry managed by controller, replaced by route of node
+	repairable bool
+}
+
+func (c *routeConflict) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", c.entry.RouteTableID, c.route.node, c.entry.CIDR, c.entry.NextHopID)
+}
+
+func (c *routeConflict) Error() string {
+	return fmt.Sprintf("entry of %s to %s %s in %s conflicts with pod cidr %s of node %s on instance %s",
+		c.entry.CIDR, c.entry.NextHopType, c.entry.NextHopID, c.entry.RouteTableID, c.route.cidr, c.route.node, c.route.instanceID)
+}
+
+// routeConflicts return entries of pod cidrs of nodes to other next hops, or more specific in the pod cidrs,
+// which take precedence over the route of node
+func routeConflicts(entries []*aliyun.RouteEntry, routes []nodeRoute, clusterCIDR *net.IPNet) []routeConflict {
+	var conflicts []routeConflict
+	for _, route := range routes {
+		routeOnes, _ := route.cidr.Mask.Size()
+		for _, entry := range entries {
+			if entry.CIDR.String() == route.cidr.String() && entry.NextHopType == aliyun.RouteNextHopInstance &&
+				entry.InstanceID == route.instanceID {
+				continue
+			}
+			if ones, _ := entry.CIDR.Mask.Size(); ones < routeOnes || !route.cidr.Contains(entry.CIDR.IP) {
+				continue
+			}
+			conflicts = append(conflicts, routeConflict{route: route, entry: entry, repairable: managedEntry(entry, clusterCIDR)})
+		}
+	}
+	return conflicts
+}
+
+func (c *routeController) sync() error {
+	nodes, err := c.k8sClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "error list nodes")
+	}
+	routes := nodeRoutes(nodes.Items, c.clusterCIDR)
+	// reason of node not routed in any route table
+	failed := make(map[string]error)
+	conflicts := make(map[string]bool)
+	defer func() { c.conflicts = conflicts }()
+	for _, table := range c.routeTables {
+		entries, err := c.ecs.ListRouteEntries(table)
+		if err != nil {
+			return errors.Wrapf(err, "error list route entries of %s", table)
+		}
+		// entries kept and nodes not routed for conflicts not repaired
+		kept := make(map[*aliyun.RouteEntry]bool)
+		blocked := make(map[string]error)
+		tableConflicts := routeConflicts(entries, routes, c.clusterCIDR)
+		for i := range tableConflicts {
+			conflict := &tableConflicts[i]
+			repair := conflict.repairable && c.repairConflicts
+			if !repair {
+				kept[conflict.entry] = true
+				blocked[conflict.route.node] = conflict
+			}
+			c.reportConflict(conflict, repair, conflicts)
+		}
+		stale, missing := routeChanges(entries, routes, c.clusterCIDR)
+		// deleted first, freeing quota of table for the missing routes
+		for _, entry := range stale {
+			if kept[entry] {
+				continue
+			}
+			if err = c.ecs.DeleteRouteEntry(table, entry.CIDR, entry.InstanceID); err != nil {
+				log.Errorf("error delete stale route of %s to instance %s in %s: %v", entry.CIDR, entry.InstanceID, table, err)
+				continue
+			}
+			log.Infof("deleted route of %s to instance %s in %s, not of any node", entry.CIDR, entry.InstanceID, table)
+		}
+		quotaExceeded := false
+		for _, route := range missing {
+			if blocked[route.node] != nil {
+				failed[route.node] = blocked[route.node]
+				continue
+			}
+			if quotaExceeded {
+				failed[route.node] = err
+				continue
+			}
+			if err = c.ecs.CreateRouteEntry(table, route.cidr, route.instanceID); err != nil {
+				failed[route.node] = err
+				// later creates in table fail the same, not retried until next sync
+				if quotaExceeded = aliyun.IsQuotaExceeded(err); quotaExceeded {
+					if !c.quotaExceeded[table] {
+						log.Errorf("route entries of %s exceed quota, %d nodes not routed: %v", table, len(missing), err)
+					}
+					continue
+				}
+				log.Errorf("error create route of %s to node %s in %s: %v", route.cidr, route.node, table, err)
+				continue
+			}
+			log.Infof("created route of %s to node %s in %s", route.cidr, route.node, table)
+		}
+		c.quotaExceeded[table] = quotaExceeded
+	}
+
+	routed := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		routed[route.node] = true
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if !routed[node.Name] {
+			continue
+		}
+		if err = c.updateNetworkCondition(node, failed[node.Name]); err != nil {
+			log.Errorf("error update network condition of node %s: %v", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// updateNetworkCondition set NetworkUnavailable condition of node by error of its routes, updated only on change
+func (c *routeController) updateNetworkCondition(node *corev1.Node, routeErr error) error {
+	condition := corev1.NodeCondition{
+		Type:    corev1.NodeNetworkUnavailable,
+		Status:  corev1.ConditionFalse,
+		Reason:  routeReasonCreated,
+		Message: "terway controller created route",
+	}
+	if routeErr != nil {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = routeReasonNotCreated
+		if aliyun.IsQuotaExceeded(routeErr) {
+			condition.Reason = routeReasonQuotaExceeded
+		} else if _, ok := routeErr.(*routeConflict); ok {
+			condition.Reason = routeReasonConflict
+		}
+		condition.Message = fmt.Sprintf("terway controller failed to create route: %v", routeErr)
+	}
+	index := -1
+	for i, existing := range node.Status.Conditions {
+		if existing.Type != corev1.NodeNetworkUnavailable {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason {
+			return nil
+		}
+		index = i
+	}
+	now := metav1.Now()
+	condition.LastTransitionTime = now
+	condition.LastHeartbeatTime = now
+	if index < 0 {
+		node.Status.Conditions = append(node.Status.Conditions, condition)
+	} else {
+		node.Status.Conditions[index] = condition
+	}
+	_, err := c.k8sClient.CoreV1().Nodes().UpdateStatus(node)
+	return err
+}
+
+// reportConflict log conflict and record event of node, only the first time found
+func (c *routeController) reportConflict(conflict *routeConflict, repair bool, conflicts map[string]bool) {
+	key := conflict.key()
+	conflicts[key] = true
+	if c.conflicts[key] {
+		return
+	}
+	message := conflict.Error() + ", not repaired"
+	if repair {
+		message = conflict.Error() + ", replaced by route of node"
+	} else if conflict.repairable {
+		message += " as repair of conflicts disabled"
+	} else {
+		message += " as entry not managed by terway"
+	}
+	log.Warn(message)
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: conflict.route.node + ".",
+			Namespace:    metav1.NamespaceDefault,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "Node",
+			APIVersion: "v1",
+			Name:       conflict.route.node,
+			UID:        k8stypes.UID(conflict.route.node),
+		},
+		Type:    corev1.EventTypeWarning,
+		Reason:  routeReasonConflict,
+		Message: message,
+		Source:  corev1.EventSource{Component: "terway-controller"},
+		Count:   1,
+	}
+	event.FirstTimestamp = metav1.Now()
+	event.LastTimestamp = event.FirstTimestamp
+	if _, err := c.k8sClient.CoreV1().Events(metav1.NamespaceDefault).Create(event); err != nil {
+		log.Errorf("error record route conflict event of node %s: %v", conflict.route.node, err)
+	}
+}
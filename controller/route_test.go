@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeRoutes(t *testing.T) {
+	_, clusterCIDR, _ := net.ParseCIDR("172.20.0.0/16")
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Spec: corev1.NodeSpec{PodCIDR: "172.20.1.0/24", ProviderID: "cn-hangzhou.i-1"}},
+		// not allocated pod cidr yet
+		{ObjectMeta: metav1.ObjectMeta{Name: "node2"}, Spec: corev1.NodeSpec{ProviderID: "cn-hangzhou.i-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node3"}, Spec: corev1.NodeSpec{PodCIDR: "10.0.0.0/24", ProviderID: "cn-hangzhou.i-3"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node4"}, Spec: corev1.NodeSpec{PodCIDR: "172.20.4.0/24"}},
+	}
+	routes := nodeRoutes(nodes, clusterCIDR)
+	assert.Len(t, routes, 1)
+	assert.Equal(t, "node1", routes[0].node)
+	assert.Equal(t, "172.20.1.0/24", routes[0].cidr.String())
+	assert.Equal(t, "i-1", routes[0].instanceID)
+}
+
+func TestRouteChanges(t *testing.T) {
+	cidr := func(s string) *net.IPNet {
+		_, n, _ := net.ParseCIDR(s)
+		return n
+	}
+	clusterCIDR := cidr("172.20.0.0/16")
+	routes := []nodeRoute{
+		{node: "node1", cidr: cidr("172.20.1.0/24"), instanceID: "i-1"},
+		{node: "node2", cidr: cidr("172.20.2.0/24"), instanceID: "i-2"},
+		{node: "node3", cidr: cidr("172.20.3.0/24"), instanceID: "i-3"},
+	}
+	instance := func(s, instanceID string) *aliyun.RouteEntry {
+		return &aliyun.RouteEntry{RouteTableID: "vtb-1", CIDR: cidr(s), NextHopType: aliyun.RouteNextHopInstance,
+			NextHopID: instanceID, InstanceID: instanceID}
+	}
+	entries := []*aliyun.RouteEntry{
+		instance("172.20.1.0/24", "i-1"),
+		// pod cidr of node reassigned to another instance
+		instance("172.20.2.0/24", "i-old"),
+		// node deleted
+		instance("172.20.9.0/24", "i-9"),
+		// not managed by controller
+		instance("192.168.0.0/24", "i-9"),
+		instance("172.20.0.0/16", "i-9"),
+		{RouteTableID: "vtb-1", CIDR: cidr("172.20.3.0/25"), NextHopType: "HaVip", NextHopID: "havip-1"},
+	}
+	stale, missing := routeChanges(entries, routes, clusterCIDR)
+	assert.Len(t, stale, 2)
+	assert.Equal(t, "i-old", stale[0].InstanceID)
+	assert.Equal(t, "172.20.9.0/24", stale[1].CIDR.String())
+	assert.Len(t, missing, 2)
+	assert.Equal(t, "node2", missing[0].node)
+	assert.Equal(t, "node3", missing[1].node)
+
+	conflicts := routeConflicts(entries, routes, clusterCIDR)
+	assert.Len(t, conflicts, 2)
+	// entry of reused pod cidr left by replaced instance repaired
+	assert.Equal(t, "node2", conflicts[0].route.node)
+	assert.Equal(t, "i-old", conflicts[0].entry.InstanceID)
+	assert.True(t, conflicts[0].repairable)
+	// more specific entry not managed by controller only reported, the less specific one not a conflict
+	assert.Equal(t, "node3", conflicts[1].route.node)
+	assert.Equal(t, "havip-1", conflicts[1].entry.NextHopID)
+	assert.False(t, conflicts[1].repairable)
+}
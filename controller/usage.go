@@ -0,0 +1,163 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodePoolNone nodepool of nodes without nodepool label
+const nodePoolNone = "<none>"
+
+// usageAggregator aggregate node network states into ip usage of cluster by vswitch and nodepool, published
+// in the cluster network state and metrics
+type usageAggregator struct {
+	ecs       aliyun.ECS
+	k8sClient kubernetes.Interface
+	states    crd.Client
+	cluster   crd.ClusterClient
+	vpcID     string
+	// nodePoolLabel label of nodes naming their nodepool
+	nodePoolLabel string
+}
+
+// aggregateUsage return ip usage of nodes with network state, vswitches of enis of the nodes only
+func aggregateUsage(states []crd.NodeNetworkState, nodes []corev1.Node, enis []*aliyun.InstanceENI,
+	vSwitches []*aliyun.VSwitch, nodePoolLabel string) crd.ClusterNetworkStateStatus {
+	nodePoolOf := make(map[string]string)
+	for _, node := range nodes {
+		nodePoolOf[node.Name] = node.Labels[nodePoolLabel]
+	}
+	status := crd.ClusterNetworkStateStatus{}
+	instances := make(map[string]bool)
+	nodePools := make(map[string]*crd.NodePoolUsage)
+	for _, state := range states {
+		instances[state.Spec.InstanceID] = true
+		name := nodePoolOf[state.Name]
+		if name == "" {
+			name = nodePoolNone
+		}
+		usage, ok := nodePools[name]
+		if !ok {
+			usage = &crd.NodePoolUsage{Name: name, Pools: make(map[string]crd.PoolStats)}
+			nodePools[name] = usage
+		}
+		usage.Nodes++
+		status.Nodes++
+		if state.Status.Summary == nil {
+			continue
+		}
+		usage.Pods += state.Status.Summary.PodCount
+		status.Pods += state.Status.Summary.PodCount
+		for resType, stats := range state.Status.Summary.Pools {
+			sum := usage.Pools[resType]
+			sum.Capacity += stats.Capacity
+			sum.Inuse += stats.Inuse
+			sum.Idle += stats.Idle
+			sum.Quarantine += stats.Quarantine
+			usage.Pools[resType] = sum
+		}
+	}
+	for _, usage := range nodePools {
+		status.NodePools = append(status.NodePools, *usage)
+	}
+	sort.Slice(status.NodePools, func(i, j int) bool {
+		return status.NodePools[i].Name < status.NodePools[j].Name
+	})
+
+	vSwitchUsage := make(map[string]*crd.VSwitchUsage)
+	for _, eni := range enis {
+		if !instances[eni.InstanceID] {
+			continue
+		}
+		usage, ok := vSwitchUsage[eni.VSwitchID]
+		if !ok {
+			usage = &crd.VSwitchUsage{ID: eni.VSwitchID}
+			vSwitchUsage[eni.VSwitchID] = usage
+		}
+		usage.ENIs++
+		usage.UsedIPs += len(eni.IPs)
+	}
+	for _, vsw := range vSwitches {
+		usage, ok := vSwitchUsage[vsw.ID]
+		if !ok {
+			continue
+		}
+		usage.Zone = vsw.Zone
+		usage.AvailableIPs = vsw.AvailableIPs
+		if vsw.CIDR != nil {
+			usage.CIDR = vsw.CIDR.String()
+		}
+	}
+	for _, usage := range vSwitchUsage {
+		status.VSwitches = append(status.VSwitches, *usage)
+	}
+	sort.Slice(status.VSwitches, func(i, j int) bool {
+		return status.VSwitches[i].ID < status.VSwitches[j].ID
+	})
+	return status
+}
+
+// aggregate publish ip usage of cluster in cluster network state and metrics
+func (a *usageAggregator) aggregate() error {
+	states, err := a.states.List()
+	if err != nil {
+		return errors.Wrapf(err, "error list node network states")
+	}
+	nodes, err := a.k8sClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "error list nodes")
+	}
+	enis, err := a.ecs.ListInstanceENIs()
+	if err != nil {
+		return errors.Wrapf(err, "error list instance enis")
+	}
+	vSwitches, err := a.ecs.ListVSwitches(a.vpcID, "")
+	if err != nil {
+		return errors.Wrapf(err, "error list vswitches of vpc %s", a.vpcID)
+	}
+	status := aggregateUsage(states, nodes.Items, enis, vSwitches, a.nodePoolLabel)
+	status.UpdateTime = metav1.NewTime(time.Now())
+	observeUsage(&status)
+
+	state, err := a.cluster.Get(crd.ClusterNetworkStateName)
+	if apierrors.IsNotFound(err) {
+		state = &crd.ClusterNetworkState{ObjectMeta: metav1.ObjectMeta{Name: crd.ClusterNetworkStateName}, Status: status}
+		_, err = a.cluster.Create(state)
+		return errors.Wrapf(err, "error create cluster network state")
+	}
+	if err != nil {
+		return errors.Wrapf(err, "error get cluster network state")
+	}
+	state.Status = status
+	_, err = a.cluster.Update(state)
+	return errors.Wrapf(err, "error update cluster network state")
+}
+
+// observeUsage set metrics of ip usage, those of vswitches and nodepools gone dropped
+func observeUsage(status *crd.ClusterNetworkStateStatus) {
+	metric.ClusterVSwitchIPs.Reset()
+	for _, vsw := range status.VSwitches {
+		metric.ClusterVSwitchIPs.WithLabelValues(vsw.ID, vsw.Zone, "used").Set(float64(vsw.UsedIPs))
+		metric.ClusterVSwitchIPs.WithLabelValues(vsw.ID, vsw.Zone, "available").Set(float64(vsw.AvailableIPs))
+	}
+	metric.ClusterNodePoolIPs.Reset()
+	for _, pool := range status.NodePools {
+		for resType, stats := range pool.Pools {
+			metric.ClusterNodePoolIPs.WithLabelValues(pool.Name, resType, "capacity").Set(float64(stats.Capacity))
+			metric.ClusterNodePoolIPs.WithLabelValues(pool.Name, resType, "inuse").Set(float64(stats.Inuse))
+			metric.ClusterNodePoolIPs.WithLabelValues(pool.Name, resType, "idle").Set(float64(stats.Idle))
+		}
+	}
+	log.Debugf("ip usage of %d nodes in %d nodepools and %d vswitches aggregated", status.Nodes,
+		len(status.NodePools), len(status.VSwitches))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAggregateUsage(t *testing.T) {
+	label := "alibabacloud.com/nodepool-id"
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{label: "np-1"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{label: "np-1"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}},
+	}
+	summary := func(pods, capacity, inuse, idle int) *crd.NodeNetworkSummary {
+		return &crd.NodeNetworkSummary{PodCount: pods, Pools: map[string]crd.PoolStats{
+			"eniIp": {Capacity: capacity, Inuse: inuse, Idle: idle}}}
+	}
+	states := []crd.NodeNetworkState{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: crd.NodeNetworkStateSpec{InstanceID: "i-1"},
+			Status: crd.NodeNetworkStateStatus{Summary: summary(3, 20, 3, 2)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}, Spec: crd.NodeNetworkStateSpec{InstanceID: "i-2"},
+			Status: crd.NodeNetworkStateStatus{Summary: summary(1, 20, 1, 4)}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}, Spec: crd.NodeNetworkStateSpec{InstanceID: "i-3"}},
+	}
+	ips := func(n int) []net.IP {
+		return make([]net.IP, n)
+	}
+	enis := []*aliyun.InstanceENI{
+		{InstanceID: "i-1", VSwitchID: "vsw-1", IPs: ips(1)},
+		{InstanceID: "i-1", VSwitchID: "vsw-2", IPs: ips(5)},
+		{InstanceID: "i-2", VSwitchID: "vsw-2", IPs: ips(6)},
+		// instance not in cluster
+		{InstanceID: "i-9", VSwitchID: "vsw-3", IPs: ips(2)},
+	}
+	_, cidr, _ := net.ParseCIDR("10.0.1.0/24")
+	vSwitches := []*aliyun.VSwitch{
+		{ID: "vsw-2", Zone: "cn-hangzhou-a", CIDR: cidr, AvailableIPs: 200},
+		{ID: "vsw-3", Zone: "cn-hangzhou-a", AvailableIPs: 100},
+	}
+
+	status := aggregateUsage(states, nodes, enis, vSwitches, label)
+	assert.Equal(t, 3, status.Nodes)
+	assert.Equal(t, 4, status.Pods)
+	assert.Equal(t, []crd.NodePoolUsage{
+		{Name: nodePoolNone, Nodes: 1, Pools: map[string]crd.PoolStats{}},
+		{Name: "np-1", Nodes: 2, Pods: 4, Pools: map[string]crd.PoolStats{"eniIp": {Capacity: 40, Inuse: 4, Idle: 6}}},
+	}, status.NodePools)
+	assert.Equal(t, []crd.VSwitchUsage{
+		{ID: "vsw-1", ENIs: 1, UsedIPs: 1},
+		{ID: "vsw-2", Zone: "cn-hangzhou-a", CIDR: "10.0.1.0/24", ENIs: 2, UsedIPs: 11, AvailableIPs: 200},
+	}, status.VSwitches)
+}
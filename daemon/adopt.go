@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const eniStatusAvailable = "Available"
+
+// adoptENIs attach enis created by terway for the instance but left detached, eg: os of node reimaged
+// and terway reinstalled, so pools take them back on init instead of allocating a fresh set,
+// return count of enis adopted
+func adoptENIs(ecs aliyun.ECS, instanceID string) (int, error) {
+	enis, err := ecs.ListOwnedENIs(instanceID)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error list enis created for instance %s", instanceID)
+	}
+	var detached []*aliyun.InstanceENI
+	for _, eni := range enis {
+		if eni.Status == eniStatusAvailable {
+			detached = append(detached, eni)
+		}
+	}
+	if len(detached) == 0 {
+		return 0, nil
+	}
+
+	attached, err := ecs.GetAttachedENIs(instanceID, true)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error get attached enis of instance %s", instanceID)
+	}
+	maxENI, err := ecs.GetInstanceMaxENI(instanceID)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error get max eni of instance %s", instanceID)
+	}
+	adopted := 0
+	for _, eni := range detached {
+		if len(attached)+adopted >= maxENI {
+			log.Warnf("no eni slot of instance %s to adopt eni %s, %d of %d attached", instanceID, eni.ID, len(attached)+adopted, maxENI)
+			break
+		}
+		if _, err = ecs.AttachENI(eni.ID, instanceID); err != nil {
+			log.Warnf("error adopt eni %s left detached: %v", eni.ID, err)
+			continue
+		}
+		adopted++
+		log.Infof("adopted eni %s of instance %s left detached", eni.ID, instanceID)
+	}
+	return adopted, nil
+}
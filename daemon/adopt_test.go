@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type adoptECS struct {
+	aliyun.ECS
+	owned    []*aliyun.InstanceENI
+	attached []*types.ENI
+	maxENI   int
+}
+
+func (e *adoptECS) ListOwnedENIs(instanceID string) ([]*aliyun.InstanceENI, error) {
+	return e.owned, nil
+}
+
+func (e *adoptECS) GetAttachedENIs(instanceID string, containsMainENI bool) ([]*types.ENI, error) {
+	return e.attached, nil
+}
+
+func (e *adoptECS) GetInstanceMaxENI(instanceID string) (int, error) {
+	return e.maxENI, nil
+}
+
+func (e *adoptECS) AttachENI(eniID, instanceID string) (*types.ENI, error) {
+	eni := &types.ENI{ID: eniID}
+	e.attached = append(e.attached, eni)
+	return eni, nil
+}
+
+func TestAdoptENIs(t *testing.T) {
+	ecs := &adoptECS{
+		owned: []*aliyun.InstanceENI{
+			{ID: "eni-1", InstanceID: "i-1", Status: "InUse"},
+			{ID: "eni-2", Status: eniStatusAvailable},
+			{ID: "eni-3", Status: eniStatusAvailable},
+		},
+		attached: []*types.ENI{{ID: "eni-0"}, {ID: "eni-1"}},
+		maxENI:   3,
+	}
+	adopted, err := adoptENIs(ecs, "i-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, adopted, "adopt no more than eni slots of instance")
+	assert.Equal(t, "eni-2", ecs.attached[2].ID)
+}
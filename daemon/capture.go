@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tcpdumpBin = "tcpdump"
+	// defaultCaptureDuration duration of capture if not specified by request
+	defaultCaptureDuration = 30 * time.Second
+	// maxCaptureDuration capture is time-boxed to avoid forgotten captures
+	maxCaptureDuration    = 5 * time.Minute
+	defaultCaptureSnapLen = 262144
+	captureChunkSize      = 32 * 1024
+)
+
+// captureDuration validate the duration of capture request
+func captureDuration(seconds int32) (time.Duration, error) {
+	duration := time.Duration(seconds) * time.Second
+	switch {
+	case seconds == 0:
+		return defaultCaptureDuration, nil
+	case seconds < 0:
+		return 0, status.Errorf(codes.InvalidArgument, "invalid capture duration %ds", seconds)
+	case duration > maxCaptureDuration:
+		return 0, status.Errorf(codes.InvalidArgument, "capture duration %v exceeds max %v", duration, maxCaptureDuration)
+	}
+	return duration, nil
+}
+
+// tcpdumpArgs build tcpdump arguments writing pcap to stdout, podFilter selects packets of pod
+// on shared interface and is combined with filter of user
+func tcpdumpArgs(iface, podFilter string, r *rpc.CaptureRequest) []string {
+	snapLen := int(r.SnapLen)
+	if snapLen <= 0 {
+		snapLen = defaultCaptureSnapLen
+	}
+	args := []string{"-i", iface, "-U", "-w", "-", "-s", strconv.Itoa(snapLen)}
+	if r.MaxPackets > 0 {
+		args = append(args, "-c", strconv.Itoa(int(r.MaxPackets)))
+	}
+	filter := strings.TrimSpace(r.Filter)
+	switch {
+	case podFilter != "" && filter != "":
+		filter = fmt.Sprintf("(%s) and (%s)", podFilter, filter)
+	case podFilter != "":
+		filter = podFilter
+	}
+	if filter != "" {
+		args = append(args, filter)
+	}
+	return args
+}
+
+// captureTarget find the host interface carrying traffic of pod, and the filter selecting the pod
+// if the interface is shared with other pods
+func (networkService *networkService) captureTarget(namespace, name string) (string, string, error) {
+	res, err := networkService.getPodResource(&podInfo{Namespace: namespace, Name: name})
+	if err != nil {
+		return "", "", status.Errorf(codes.Internal, "error get resources of pod %s/%s: %v", namespace, name, err)
+	}
+	if len(res.Resources) == 0 {
+		return "", "", status.Errorf(codes.NotFound, "pod %s/%s has no network resource on this node", namespace, name)
+	}
+
+	hostVeth := hostVethOfPod(name, namespace)
+	if _, err = netlink.LinkByName(hostVeth); err == nil {
+		return hostVeth, "", nil
+	}
+	// ipvlan datapath has no host veth, capture on the parent eni of pod ip
+	for _, item := range res.Resources {
+		if item.Type != types.ResourceTypeENIIP {
+			continue
+		}
+		// id of eni ip: <mac>.<ip>
+		sep := strings.Index(item.ID, ".")
+		if sep < 0 {
+			continue
+		}
+		iface, err := link.GetDeviceName(item.ID[:sep])
+		if err != nil {
+			return "", "", status.Errorf(codes.NotFound, "error find eni of pod %s/%s: %v", namespace, name, err)
+		}
+		return iface, "host " + item.ID[sep+1:], nil
+	}
+	return "", "", status.Errorf(codes.FailedPrecondition, "no host interface of pod %s/%s to capture on", namespace, name)
+}
+
+// CapturePod capture packets of pod by tcpdump on host interface of pod, stream pcap data back
+// until duration or max packets reached, or the client gone
+func (networkService *networkService) CapturePod(r *rpc.CaptureRequest, stream rpc.TerwayBackend_CapturePodServer) error {
+	log.Infof("CapturePod request: %+v", r)
+	duration, err := captureDuration(r.DurationSeconds)
+	if err != nil {
+		return err
+	}
+	iface, podFilter, err := networkService.captureTarget(r.K8SPodNamespace, r.K8SPodName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(stream.Context(), duration)
+	defer cancel()
+	cmd := exec.Command(tcpdumpBin, tcpdumpArgs(iface, podFilter, r)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return status.Errorf(codes.Internal, "error pipe output of %s: %v", tcpdumpBin, err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Start(); err != nil {
+		return status.Errorf(codes.Unavailable, "error start %s: %v", tcpdumpBin, err)
+	}
+	log.Infof("capture packets of pod %s/%s on %s for %v", r.K8SPodNamespace, r.K8SPodName, iface, duration)
+
+	// interrupt tcpdump rather than kill, so it flushes the pcap data captured
+	exited := make(chan struct{})
+	defer close(exited)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = cmd.Process.Signal(os.Interrupt)
+		case <-exited:
+		}
+	}()
+
+	var sendErr error
+	buf := make([]byte, captureChunkSize)
+	for {
+		n, err := stdout.Read(buf)
+		if n > 0 && sendErr == nil {
+			if sendErr = stream.Send(&rpc.CaptureReply{Data: buf[:n]}); sendErr != nil {
+				cancel()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err = cmd.Wait(); err != nil && ctx.Err() == nil {
+		return status.Errorf(codes.Internal, "%s exited: %v: %s", tcpdumpBin, err, strings.TrimSpace(stderr.String()))
+	}
+	return sendErr
+}
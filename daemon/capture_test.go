@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCaptureDuration(t *testing.T) {
+	d, err := captureDuration(0)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultCaptureDuration, d)
+
+	d, err = captureDuration(10)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, d)
+
+	_, err = captureDuration(-1)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = captureDuration(int32(maxCaptureDuration/time.Second) + 1)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestTcpdumpArgs(t *testing.T) {
+	assert.Equal(t, []string{"-i", "cali123", "-U", "-w", "-", "-s", "262144"},
+		tcpdumpArgs("cali123", "", &rpc.CaptureRequest{}))
+	assert.Equal(t, []string{"-i", "eth1", "-U", "-w", "-", "-s", "128", "-c", "10", "(host 192.168.0.2) and (tcp port 80)"},
+		tcpdumpArgs("eth1", "host 192.168.0.2", &rpc.CaptureRequest{Filter: " tcp port 80 ", MaxPackets: 10, SnapLen: 128}))
+	assert.Equal(t, []string{"-i", "eth1", "-U", "-w", "-", "-s", "262144", "host 192.168.0.2"},
+		tcpdumpArgs("eth1", "host 192.168.0.2", &rpc.CaptureRequest{}))
+}
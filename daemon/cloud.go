@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// cloud backends managing network resources of daemon
+const (
+	cloudBackendAliyun = "aliyun"
+	cloudBackendFake   = "fake"
+)
+
+// CloudConfig configuration of cloud backend of daemon
+type CloudConfig struct {
+	// Backend "aliyun", or "fake" simulating the cloud in memory for development and e2e tests
+	Backend string
+	// FakeConfigFile config of the fake cloud, defaults used if empty
+	FakeConfigFile string
+	// AuditLog file recording openapi calls mutating cloud resources, disabled if empty
+	AuditLog string
+}
+
+// newECS return ECS of cloud backend with openapi calls limited by config
+func newECS(cloud *CloudConfig, config *types.Configure) (aliyun.ECS, error) {
+	limiter := aliyun.NewRateLimiter(config.OpenAPIQPS, config.OpenAPIBurst)
+	switch cloud.Backend {
+	case "", cloudBackendAliyun:
+		regionID, err := aliyun.GetLocalRegion()
+		if err != nil {
+			return nil, errors.Wrapf(err, "error get region-id")
+		}
+		if cloud.AuditLog != "" {
+			if err = aliyun.EnableAuditLog(cloud.AuditLog, aliyun.DefaultAuditLogMaxSize); err != nil {
+				return nil, err
+			}
+		}
+		ecs, err := aliyun.NewRateLimitedECS(config.AccessID, config.AccessSecret, regionID, limiter)
+		if err != nil {
+			return nil, err
+		}
+		return ecs.WithENIAccount(aliyun.NewENIAccount(config))
+	case cloudBackendFake:
+		fakeConfig, err := aliyun.LoadFakeConfig(cloud.FakeConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Warnf("using fake cloud backend, network resources are simulated")
+		return aliyun.NewFakeECS(fakeConfig, limiter)
+	default:
+		return nil, fmt.Errorf("unsupported cloud backend: %s", cloud.Backend)
+	}
+}
@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	cniConfListPath = "/etc/cni/net.d/10-terway.conflist"
+	// defaultCNIVersion cniVersion of config list if neither configured nor in 10-terway.conf
+	defaultCNIVersion = "0.3.0"
+	// cniConfResyncPeriod period of checking installed cni config list against the rendered one
+	cniConfResyncPeriod = time.Minute
+
+	// range of mtu of pod interfaces supported by cni binary
+	minCNIMTU = 576
+	maxCNIMTU = 8500
+)
+
+// cniConfListVersions spec versions supporting config list
+var cniConfListVersions = []string{"0.3.0", "0.3.1"}
+
+// defaultCNIPluginConf config of terway plugin if 10-terway.conf not provided
+var defaultCNIPluginConf = []byte(`{"name": "terway", "type": "terway"}`)
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCNIConf check cni version and chained plugins of cni config list
+func validateCNIConf(cfg *types.CNIConf) []error {
+	var errs []error
+	if cfg.CNIVersion != "" && !containsString(cniConfListVersions, cfg.CNIVersion) {
+		errs = append(errs, errors.Errorf("cni_version %s not supported, one of %v expected", cfg.CNIVersion, cniConfListVersions))
+	}
+	if cfg.MTU != 0 && (cfg.MTU < minCNIMTU || cfg.MTU > maxCNIMTU) {
+		errs = append(errs, errors.Errorf("mtu %d of cni_conf out of range [%d, %d]", cfg.MTU, minCNIMTU, maxCNIMTU))
+	}
+	for i, raw := range cfg.ChainedPlugins {
+		plugin := struct {
+			Type string `json:"type"`
+		}{}
+		if err := json.Unmarshal(raw, &plugin); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid chained plugin %d of cni_conf", i))
+			continue
+		}
+		if plugin.Type == "" || plugin.Type == "terway" {
+			errs = append(errs, errors.Errorf("invalid type %q of chained plugin %d of cni_conf", plugin.Type, i))
+		}
+	}
+	return errs
+}
+
+// renderCNIConfList render config list of terway plugin in 10-terway.conf followed by chained plugins,
+// keys sorted for comparing with the installed one
+func renderCNIConfList(pluginConf []byte, cfg *types.CNIConf) ([]byte, error) {
+	plugin := make(map[string]interface{})
+	if err := json.Unmarshal(pluginConf, &plugin); err != nil {
+		return nil, errors.Wrapf(err, "error parse cni config of terway plugin")
+	}
+	cniVersion := cfg.CNIVersion
+	if cniVersion == "" {
+		cniVersion, _ = plugin["cniVersion"].(string)
+	}
+	if cniVersion == "" {
+		cniVersion = defaultCNIVersion
+	}
+	name, _ := plugin["name"].(string)
+	if name == "" {
+		name = "terway"
+	}
+	delete(plugin, "cniVersion")
+	delete(plugin, "name")
+	if cfg.MTU > 0 {
+		plugin["mtu"] = cfg.MTU
+	}
+
+	plugins := []interface{}{plugin}
+	for _, raw := range cfg.ChainedPlugins {
+		chained := make(map[string]interface{})
+		if err := json.Unmarshal(raw, &chained); err != nil {
+			return nil, errors.Wrapf(err, "error parse chained plugin")
+		}
+		plugins = append(plugins, chained)
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"cniVersion": cniVersion,
+		"name":       name,
+		"plugins":    plugins,
+	}, "", "  ")
+}
+
+// cniConfInstaller install rendered cni config list and repair it on drift, eg: overwritten by other agents
+type cniConfInstaller struct {
+	path string
+	data []byte
+	// legacyPath config of terway plugin taking precedence over the config list, removed
+	legacyPath string
+}
+
+// install write config list if missing or changed, return whether written
+func (c *cniConfInstaller) install() (bool, error) {
+	if err := os.Remove(c.legacyPath); err != nil && !os.IsNotExist(err) {
+		return false, errors.Wrapf(err, "error remove cni config %s", c.legacyPath)
+	}
+	installed, err := ioutil.ReadFile(c.path)
+	if err == nil && bytes.Equal(installed, c.data) {
+		return false, nil
+	}
+	if err = writeFileAtomic(c.path, c.data, 0644); err != nil {
+		return false, errors.Wrapf(err, "error install cni config list %s", c.path)
+	}
+	return true, nil
+}
+
+// start periodically repair installed config list
+func (c *cniConfInstaller) start() {
+	go func() {
+		for {
+			time.Sleep(cniConfResyncPeriod)
+			repaired, err := c.install()
+			if err != nil {
+				log.Warnf("error repair cni config list: %v", err)
+				continue
+			}
+			if repaired {
+				log.Warnf("cni config list %s drifted from rendered, repaired", c.path)
+				metric.CNIConfRepaired.Inc()
+			}
+		}
+	}()
+}
+
+// setupCNIConfList render and install cni config list, checked supported by cni binary, eg: cni version and
+// chained plugins installed
+func setupCNIConfList(pluginConf []byte, cfg *types.CNIConf, supportedVersions []string) error {
+	if pluginConf == nil {
+		pluginConf = defaultCNIPluginConf
+	}
+	data, err := renderCNIConfList(pluginConf, cfg)
+	if err != nil {
+		return err
+	}
+	conf := struct {
+		CNIVersion string `json:"cniVersion"`
+		Plugins    []struct {
+			Type string `json:"type"`
+		} `json:"plugins"`
+	}{}
+	if err = json.Unmarshal(data, &conf); err != nil {
+		return err
+	}
+	// binary before versions report supports 0.3.0 only
+	if len(supportedVersions) == 0 {
+		supportedVersions = []string{defaultCNIVersion}
+	}
+	if !containsString(supportedVersions, conf.CNIVersion) {
+		return errors.Errorf("cni version %s not supported by cni binary %s, supported: %v", conf.CNIVersion, cniBinPath, supportedVersions)
+	}
+	for _, plugin := range conf.Plugins[1:] {
+		if _, err = os.Stat(filepath.Join(filepath.Dir(cniBinPath), plugin.Type)); err != nil {
+			return errors.Wrapf(err, "binary of chained plugin %s not installed", plugin.Type)
+		}
+	}
+
+	installer := &cniConfInstaller{path: cniConfListPath, data: data, legacyPath: cniConfPath}
+	if _, err = installer.install(); err != nil {
+		return err
+	}
+	installer.start()
+	log.Infof("cni config list %s installed with %d chained plugins", cniConfListPath, len(cfg.ChainedPlugins))
+	return nil
+}
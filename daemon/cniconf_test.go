@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderCNIConfList(t *testing.T) {
+	cfg := &types.CNIConf{
+		MTU:            1500,
+		ChainedPlugins: []json.RawMessage{json.RawMessage(`{"type": "portmap", "capabilities": {"portMappings": true}}`)},
+	}
+	assert.Empty(t, validateCNIConf(cfg))
+	data, err := renderCNIConfList([]byte(`{"cniVersion": "0.3.0", "name": "terway", "type": "terway", "eniip_virtual_type": "IPVlan"}`), cfg)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"cniVersion": "0.3.0",
+		"name": "terway",
+		"plugins": [
+			{"type": "terway", "eniip_virtual_type": "IPVlan", "mtu": 1500},
+			{"type": "portmap", "capabilities": {"portMappings": true}}
+		]
+	}`, string(data))
+
+	cfg.CNIVersion = "0.3.1"
+	data, err = renderCNIConfList(defaultCNIPluginConf, cfg)
+	assert.NoError(t, err)
+	again, _ := renderCNIConfList(defaultCNIPluginConf, cfg)
+	assert.Equal(t, data, again)
+
+	assert.Len(t, validateCNIConf(&types.CNIConf{
+		CNIVersion:     "1.0.0",
+		ChainedPlugins: []json.RawMessage{json.RawMessage(`{"type": "terway"}`), json.RawMessage(`{}`)},
+	}), 3)
+}
+
+func TestCNIConfInstaller(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cniconf")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	installer := &cniConfInstaller{
+		path:       filepath.Join(dir, "10-terway.conflist"),
+		data:       []byte(`{"name": "terway"}`),
+		legacyPath: filepath.Join(dir, "10-terway.conf"),
+	}
+	assert.NoError(t, ioutil.WriteFile(installer.legacyPath, []byte(`{}`), 0644))
+	written, err := installer.install()
+	assert.NoError(t, err)
+	assert.True(t, written)
+	_, err = os.Stat(installer.legacyPath)
+	assert.True(t, os.IsNotExist(err))
+
+	written, err = installer.install()
+	assert.NoError(t, err)
+	assert.False(t, written)
+
+	// drifted by other agent
+	assert.NoError(t, ioutil.WriteFile(installer.path, []byte(`{}`), 0644))
+	written, err = installer.install()
+	assert.NoError(t, err)
+	assert.True(t, written)
+	data, _ := ioutil.ReadFile(installer.path)
+	assert.Equal(t, installer.data, data)
+}
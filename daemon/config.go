@@ -0,0 +1,312 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/AliyunContainerService/terway/pkg/defaults"
+	"github.com/AliyunContainerService/terway/pkg/feature"
+	"github.com/AliyunContainerService/terway/pkg/netfilter"
+	"github.com/AliyunContainerService/terway/pkg/tracing"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// getConfigFromPath read and parse terway config file
+func getConfigFromPath(configFilePath string) (*types.Configure, error) {
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed read file %s: %v", configFilePath, err)
+	}
+
+	config := &types.Configure{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed parse config: %v", err)
+	}
+	return config, nil
+}
+
+// validateConfig static validation of config without cloud resources
+func validateConfig(cfg *types.Configure) error {
+	var errs []error
+	if cfg.MinPoolSize < 0 || cfg.MaxPoolSize < 0 {
+		errs = append(errs, fmt.Errorf("pool size must not be negative: min %d, max %d", cfg.MinPoolSize, cfg.MaxPoolSize))
+	}
+	if cfg.MaxPoolSize < cfg.MinPoolSize {
+		errs = append(errs, fmt.Errorf("max_pool_size %d less than min_pool_size %d", cfg.MaxPoolSize, cfg.MinPoolSize))
+	}
+	if cfg.EniCapRatio < 0 || cfg.EniCapRatio > 1 {
+		errs = append(errs, fmt.Errorf("eni_cap_ratio %v out of range (0, 1]", cfg.EniCapRatio))
+	}
+	if cfg.ServiceCIDR != "" {
+		if _, _, err := net.ParseCIDR(cfg.ServiceCIDR); err != nil {
+			errs = append(errs, errors.Wrapf(err, "error parse service cidr: %s", cfg.ServiceCIDR))
+		}
+	}
+	if (cfg.AccessID == "") != (cfg.AccessSecret == "") {
+		errs = append(errs, fmt.Errorf("access_key and access_secret must be set together"))
+	}
+	switch cfg.LinkLocalAccess {
+	case "", types.LinkLocalAccessAllow, types.LinkLocalAccessBlock, types.LinkLocalAccessMasquerade:
+	default:
+		errs = append(errs, fmt.Errorf("unsupported link_local_access: %s", cfg.LinkLocalAccess))
+	}
+	if cfg.OpenAPIQPS < 0 || cfg.OpenAPIBurst < 0 {
+		errs = append(errs, fmt.Errorf("open_api_qps %v and open_api_burst %d must not be negative", cfg.OpenAPIQPS, cfg.OpenAPIBurst))
+	}
+	for resType, policy := range cfg.ReleasePolicy {
+		if resType != types.ResourceTypeENI && resType != types.ResourceTypeENIIP {
+			errs = append(errs, fmt.Errorf("release policy not supported for resource type: %s", resType))
+		}
+		switch policy {
+		case types.ReleasePolicyPool, types.ReleasePolicyCloud, types.ReleasePolicyQuarantine:
+		default:
+			errs = append(errs, fmt.Errorf("unsupported release policy %s of %s", policy, resType))
+		}
+	}
+	for resType, limit := range cfg.NamespaceResourceLimits {
+		if resType != types.ResourceTypeENI && resType != types.ResourceTypeENIIP {
+			errs = append(errs, fmt.Errorf("namespace resource limit not supported for resource type: %s", resType))
+		}
+		if limit < 0 {
+			errs = append(errs, fmt.Errorf("namespace resource limit %d of %s must not be negative", limit, resType))
+		}
+	}
+	if err := annotation.ValidateSysctls(cfg.PodSysctls); err != nil {
+		errs = append(errs, errors.Wrapf(err, "invalid pod_sysctls"))
+	}
+	if cfg.InterfaceTuning != nil {
+		if err := annotation.ValidateInterfaceTuning(cfg.InterfaceTuning); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid interface_tuning"))
+		}
+	}
+	if cfg.ReservedSlots < 0 {
+		errs = append(errs, fmt.Errorf("reserved_slots %d must not be negative", cfg.ReservedSlots))
+	}
+	if cfg.ReservedSlots > 0 && len(cfg.CriticalNamespaces) == 0 && len(cfg.CriticalPriorityClasses) == 0 {
+		errs = append(errs, fmt.Errorf("reserved_slots requires critical_namespaces or critical_priority_classes"))
+	}
+	if cfg.PoolDrainRate < 0 {
+		errs = append(errs, fmt.Errorf("pool_drain_rate %d must not be negative", cfg.PoolDrainRate))
+	}
+	if cfg.PoolParallelCreates < 0 {
+		errs = append(errs, fmt.Errorf("pool_parallel_creates %d must not be negative", cfg.PoolParallelCreates))
+	}
+	if cfg.ENIHealthCheckSeconds < 0 {
+		errs = append(errs, fmt.Errorf("eni_health_check_seconds %d must not be negative", cfg.ENIHealthCheckSeconds))
+	}
+	if cfg.HybridExclusiveENIs < 0 {
+		errs = append(errs, fmt.Errorf("hybrid_exclusive_enis %d must not be negative", cfg.HybridExclusiveENIs))
+	}
+	if cfg.CloudReconcileSeconds < 0 {
+		errs = append(errs, fmt.Errorf("cloud_reconcile_seconds %d must not be negative", cfg.CloudReconcileSeconds))
+	}
+	if cfg.ReleaseDelayMaxSeconds < 0 {
+		errs = append(errs, fmt.Errorf("release_delay_max_seconds %d must not be negative", cfg.ReleaseDelayMaxSeconds))
+	}
+	if cfg.ENIDefragSeconds < 0 {
+		errs = append(errs, fmt.Errorf("eni_defrag_seconds %d must not be negative", cfg.ENIDefragSeconds))
+	}
+	if cfg.MaxInflightRequests < 0 {
+		errs = append(errs, fmt.Errorf("max_inflight_requests %d must not be negative", cfg.MaxInflightRequests))
+	}
+	if cfg.MaxQueuedRequests < 0 {
+		errs = append(errs, fmt.Errorf("max_queued_requests %d must not be negative", cfg.MaxQueuedRequests))
+	}
+	if cfg.QuarantineSeconds < 0 {
+		errs = append(errs, fmt.Errorf("quarantine_seconds %d must not be negative", cfg.QuarantineSeconds))
+	}
+	if cfg.IPChunkSize < 0 || cfg.IPChunkSize > maxIPBacklog {
+		errs = append(errs, fmt.Errorf("ip_chunk_size %d out of range [0, %d]", cfg.IPChunkSize, maxIPBacklog))
+	}
+	errs = append(errs, validateEgressNAT(cfg.EgressNAT)...)
+	switch corev1.NodeConditionType(cfg.ExhaustionCondition) {
+	case corev1.NodeReady, corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure:
+		errs = append(errs, fmt.Errorf("exhaustion_condition %s owned by kubelet", cfg.ExhaustionCondition))
+	}
+	if err := feature.Validate(cfg.FeatureGates); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.CNIConf != nil {
+		errs = append(errs, validateCNIConf(cfg.CNIConf)...)
+	}
+	switch cfg.NetfilterBackend {
+	case "", netfilter.BackendLegacy, netfilter.BackendNFT:
+	default:
+		errs = append(errs, fmt.Errorf("invalid netfilter_backend: %s, %s or %s expected", cfg.NetfilterBackend, netfilter.BackendLegacy, netfilter.BackendNFT))
+	}
+	if cfg.ENIMaxIPs < 0 {
+		errs = append(errs, fmt.Errorf("eni_max_ips %d must not be negative", cfg.ENIMaxIPs))
+	}
+	if cfg.GCTimeout != "" {
+		if timeout, err := time.ParseDuration(cfg.GCTimeout); err != nil || timeout <= 0 {
+			errs = append(errs, fmt.Errorf("invalid gc_timeout: %s", cfg.GCTimeout))
+		}
+	}
+	switch cfg.SelfCheck {
+	case "", selfCheckPolicyWarn, selfCheckPolicyStrict:
+	default:
+		errs = append(errs, fmt.Errorf("invalid self_check: %s, %s or %s expected", cfg.SelfCheck, selfCheckPolicyWarn, selfCheckPolicyStrict))
+	}
+	switch cfg.VPCIPAM {
+	case "", vpcIPAMHostLocal, vpcIPAMCRD:
+	default:
+		errs = append(errs, fmt.Errorf("invalid vpc_ipam: %s, %s or %s expected", cfg.VPCIPAM, vpcIPAMHostLocal, vpcIPAMCRD))
+	}
+	if cfg.GCProtectionWindow != "" {
+		if window, err := time.ParseDuration(cfg.GCProtectionWindow); err != nil || window < 0 {
+			errs = append(errs, fmt.Errorf("invalid gc_protection_window: %s", cfg.GCProtectionWindow))
+		}
+	}
+	if cfg.AllocationBudget != "" {
+		if budget, err := time.ParseDuration(cfg.AllocationBudget); err != nil || budget <= 0 {
+			errs = append(errs, fmt.Errorf("invalid allocation_budget: %s", cfg.AllocationBudget))
+		}
+	}
+	if cfg.SlowAllocationThreshold != "" {
+		if threshold, err := time.ParseDuration(cfg.SlowAllocationThreshold); err != nil || threshold <= 0 {
+			errs = append(errs, fmt.Errorf("invalid slow_allocation_threshold: %s", cfg.SlowAllocationThreshold))
+		}
+	}
+	if cfg.TracingEndpoint != "" {
+		if err := tracing.ValidateEndpoint(cfg.TracingEndpoint); err != nil {
+			errs = append(errs, fmt.Errorf("invalid tracing_endpoint: %v", err))
+		}
+	}
+	for _, podNetworkType := range cfg.ConntrackCleanup {
+		switch podNetworkType {
+		case podNetworkTypeVPCIP, podNetworkTypeVPCENI, podNetworkTypeENIMultiIP:
+		default:
+			errs = append(errs, fmt.Errorf("unsupported pod network type of conntrack_cleanup: %s", podNetworkType))
+		}
+	}
+	for key := range cfg.ENITags {
+		if err := validateTagKey(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for zone, vSwitches := range cfg.VSwitches {
+		if len(vSwitches) == 0 {
+			errs = append(errs, fmt.Errorf("no vswitch configured for zone %s", zone))
+		}
+	}
+	for zone, vSwitches := range cfg.OverflowVSwitches {
+		if len(vSwitches) == 0 {
+			errs = append(errs, fmt.Errorf("no overflow vswitch configured for zone %s", zone))
+		}
+	}
+	if cfg.VSwitchAutoCreate != nil {
+		if err := validateVSwitchAutoCreate(cfg.VSwitchAutoCreate); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid vswitch_auto_create"))
+		}
+	}
+	names := make(map[string]bool)
+	for i := range cfg.Profiles {
+		profile := &cfg.Profiles[i]
+		if profile.Name == "" || names[profile.Name] {
+			errs = append(errs, fmt.Errorf("name of profile %d missing or duplicated: %q", i, profile.Name))
+		}
+		names[profile.Name] = true
+		if profile.DaemonMode != "" && !daemonModeSupported(profile.DaemonMode) {
+			errs = append(errs, fmt.Errorf("unsupported daemon_mode %s of profile %s", profile.DaemonMode, profile.Name))
+		}
+		merged, err := applyProfile(cfg, profile)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err = validateConfig(merged); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid config of profile %s", profile.Name))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// checkPoolConfig validate pool config against instance limits, the cloud resources checked by self check
+func checkPoolConfig(poolConfig *types.PoolConfig, daemonMode string, ecs aliyun.ECS) error {
+	var errs []error
+
+	var (
+		capacity int
+		err      error
+	)
+	switch daemonMode {
+	case daemonModeENIMultiIP, daemonModeHybrid, daemonModeDual:
+		capacity, _, err = eniIPCapacity(ecs, poolConfig)
+	case daemonModeVPC, daemonModeENIOnly:
+		capacity, err = ecs.GetInstanceMaxENI(poolConfig.InstanceID)
+		capacity = int(float64(capacity)*poolConfig.EniCapRatio) + poolConfig.EniCapShift - 1
+	}
+	if err != nil {
+		errs = append(errs, err)
+	} else if poolConfig.MinPoolSize > capacity {
+		errs = append(errs, fmt.Errorf("min_pool_size %d bigger than instance capacity %d", poolConfig.MinPoolSize, capacity))
+	} else if poolConfig.ReservedSlots >= capacity {
+		errs = append(errs, fmt.Errorf("reserved_slots %d not less than instance capacity %d", poolConfig.ReservedSlots, capacity))
+	} else if poolConfig.MaxPoolSize > capacity {
+		log.Warnf("max_pool_size %d bigger than instance capacity %d, will be set to capacity", poolConfig.MaxPoolSize, capacity)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// CheckConfig validate config file for daemon mode, used by pre-flight check on node
+func CheckConfig(configFilePath, daemonMode, profileName string) error {
+	config, err := getConfigFromPath(configFilePath)
+	if err != nil {
+		return err
+	}
+	if err = validateConfig(config); err != nil {
+		return err
+	}
+	if profileName != "" {
+		var profile *types.ConfigProfile
+		for i := range config.Profiles {
+			if config.Profiles[i].Name == profileName {
+				profile = &config.Profiles[i]
+			}
+		}
+		if profile == nil {
+			return fmt.Errorf("profile %s not found in config", profileName)
+		}
+		if profile.DaemonMode != "" {
+			daemonMode = profile.DaemonMode
+		}
+		if config, err = applyProfile(config, profile); err != nil {
+			return err
+		}
+	}
+	if !daemonModeSupported(daemonMode) {
+		return fmt.Errorf("unsupport daemon mode: %s", daemonMode)
+	}
+	if err = defaults.SetDefault(config); err != nil {
+		return err
+	}
+
+	regionID, err := aliyun.GetLocalRegion()
+	if err != nil {
+		return errors.Wrapf(err, "error get region-id")
+	}
+	ecs, err := aliyun.NewECS(config.AccessID, config.AccessSecret, regionID)
+	if err != nil {
+		return errors.Wrapf(err, "error init ecs client")
+	}
+	poolConfig, err := getPoolConfig(config, ecs)
+	if err != nil {
+		return errors.Wrapf(err, "error get pool config")
+	}
+	report := newSelfChecker(poolConfig, daemonMode, ecs).run(config.SelfCheck)
+	report.log()
+	if err = report.err(); err != nil {
+		return err
+	}
+	return checkPoolConfig(poolConfig, daemonMode, ecs)
+}
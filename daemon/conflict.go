@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/arp"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+)
+
+const (
+	arpProbeTimeout  = 500 * time.Millisecond
+	maxConflictRetry = 3
+)
+
+func linkByMAC(mac string) (string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", errors.Wrapf(err, "error list interfaces")
+	}
+	for _, iface := range interfaces {
+		if strings.EqualFold(iface.HardwareAddr.String(), mac) {
+			return iface.Name, nil
+		}
+	}
+	return "", errors.Errorf("interface with mac %s not found", mac)
+}
+
+// detectIPConflict arp probe ip on eni, the ip is conflict if any device other than eni and
+// gateway (vpc answer arp by gateway) replied
+func detectIPConflict(eni *types.ENI, ip net.IP) (bool, error) {
+	link, err := linkByMAC(eni.MAC)
+	if err != nil {
+		return false, err
+	}
+
+	ignore := map[string]bool{strings.ToLower(eni.MAC): true}
+	if eni.Gateway != nil {
+		gwMACs, err := arp.Probe(link, eni.Gateway, arpProbeTimeout)
+		if err != nil {
+			return false, errors.Wrapf(err, "error probe gateway %s", eni.Gateway)
+		}
+		for _, mac := range gwMACs {
+			ignore[mac.String()] = true
+		}
+	}
+
+	macs, err := arp.Probe(link, ip, arpProbeTimeout)
+	if err != nil {
+		return false, errors.Wrapf(err, "error probe ip %s", ip)
+	}
+	for _, mac := range macs {
+		if !ignore[mac.String()] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"net"
+	"strings"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/vishvananda/netlink"
+)
+
+// podIPConntrackFilter match conntrack flows of pod ip in either direction
+type podIPConntrackFilter net.IP
+
+// MatchConntrackFlow match flows with pod ip as source or destination of original or reply direction
+func (f podIPConntrackFilter) MatchConntrackFlow(flow *netlink.ConntrackFlow) bool {
+	ip := net.IP(f)
+	return ip.Equal(flow.Forward.SrcIP) || ip.Equal(flow.Forward.DstIP) ||
+		ip.Equal(flow.Reverse.SrcIP) || ip.Equal(flow.Reverse.DstIP)
+}
+
+// flushConntrack delete conntrack entries of ip on host, return count of entries deleted
+func flushConntrack(ip net.IP) (uint, error) {
+	family := netlink.InetFamily(netlink.FAMILY_V4)
+	if ip.To4() == nil {
+		family = netlink.InetFamily(netlink.FAMILY_V6)
+	}
+	return netlink.ConntrackDeleteFilter(netlink.ConntrackTable, family, podIPConntrackFilter(ip))
+}
+
+// countConntrack count conntrack entries of ips on host by ip, the table of each family dumped once
+func countConntrack(ips []net.IP) (map[string]int, error) {
+	counts := make(map[string]int)
+	families := make(map[netlink.InetFamily]bool)
+	for _, ip := range ips {
+		counts[ip.String()] = 0
+		if ip.To4() == nil {
+			families[netlink.InetFamily(netlink.FAMILY_V6)] = true
+		} else {
+			families[netlink.InetFamily(netlink.FAMILY_V4)] = true
+		}
+	}
+	for family := range families {
+		flows, err := netlink.ConntrackTableList(netlink.ConntrackTable, family)
+		if err != nil {
+			return nil, err
+		}
+		for _, flow := range flows {
+			// count each flow once per ip, eg: hairpin flows of pod to itself
+			matched := make(map[string]bool, 4)
+			for _, ip := range []net.IP{flow.Forward.SrcIP, flow.Forward.DstIP, flow.Reverse.SrcIP, flow.Reverse.DstIP} {
+				if ip == nil {
+					continue
+				}
+				key := ip.String()
+				if _, ok := counts[key]; ok && !matched[key] {
+					matched[key] = true
+					counts[key]++
+				}
+			}
+		}
+	}
+	return counts, nil
+}
+
+// releasedPodIPs ips of pod released with resources, eni ips from resources and ip in pod status
+func releasedPodIPs(res PodResources, pod *podInfo) []net.IP {
+	var ips []net.IP
+	add := func(s string) {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return
+		}
+		for _, exist := range ips {
+			if exist.Equal(ip) {
+				return
+			}
+		}
+		ips = append(ips, ip)
+	}
+	for _, item := range res.Resources {
+		// id of eni ip: <mac>.<ip>
+		if sep := strings.Index(item.ID, "."); item.Type == types.ResourceTypeENIIP && sep >= 0 {
+			add(item.ID[sep+1:])
+		}
+	}
+	add(pod.PodIP)
+	return ips
+}
@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+)
+
+func TestPodIPConntrackFilter(t *testing.T) {
+	filter := podIPConntrackFilter(net.ParseIP("192.168.0.2"))
+	flow := func(src, dst, replySrc, replyDst string) *netlink.ConntrackFlow {
+		f := &netlink.ConntrackFlow{}
+		f.Forward.SrcIP, f.Forward.DstIP = net.ParseIP(src), net.ParseIP(dst)
+		f.Reverse.SrcIP, f.Reverse.DstIP = net.ParseIP(replySrc), net.ParseIP(replyDst)
+		return f
+	}
+	// pod as client
+	assert.True(t, filter.MatchConntrackFlow(flow("192.168.0.2", "10.0.0.1", "10.0.0.1", "192.168.0.2")))
+	// service dnat to pod
+	assert.True(t, filter.MatchConntrackFlow(flow("192.168.0.3", "172.21.0.10", "192.168.0.2", "192.168.0.3")))
+	assert.False(t, filter.MatchConntrackFlow(flow("192.168.0.3", "10.0.0.1", "10.0.0.1", "192.168.0.3")))
+}
+
+func TestReleasedPodIPs(t *testing.T) {
+	res := PodResources{Resources: []ResourceItem{
+		{Type: types.ResourceTypeENIIP, ID: "00:16:3e:00:00:01.192.168.0.2"},
+		{Type: types.ResourceTypeENI, ID: "00:16:3e:00:00:02"},
+	}}
+	ips := releasedPodIPs(res, &podInfo{PodIP: "192.168.0.2"})
+	assert.Equal(t, []net.IP{net.ParseIP("192.168.0.2")}, ips)
+
+	ips = releasedPodIPs(PodResources{}, &podInfo{PodIP: "192.168.0.5"})
+	assert.Equal(t, []net.IP{net.ParseIP("192.168.0.5")}, ips)
+	assert.Empty(t, releasedPodIPs(PodResources{}, &podInfo{}))
+}
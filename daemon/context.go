@@ -10,6 +10,10 @@ type networkContext struct {
 	resources  []ResourceItem
 	pod        *podInfo
 	k8sService Kubernetes
+	// sandboxID infra container of pod the request for
+	sandboxID string
+	// avoid resources not to share eni with, eg: ips of other pods of the owner
+	avoid []string
 }
 
 func (networkContext *networkContext) Log() *logrus.Entry {
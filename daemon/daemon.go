@@ -3,29 +3,39 @@ package daemon
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net"
-	"os"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/pkg/defaults"
 	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/netfilter"
 	"github.com/AliyunContainerService/terway/pkg/pool"
 	"github.com/AliyunContainerService/terway/pkg/storage"
 	"github.com/AliyunContainerService/terway/rpc"
 	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/version"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
 	daemonModeVPC        = "VPC"
 	daemonModeENIMultiIP = "ENIMultiIP"
 	daemonModeENIOnly    = "ENIOnly"
+	// daemonModeHybrid pods share eni ips, or use exclusive eni by policy key network mode
+	daemonModeHybrid = "Hybrid"
+	// daemonModeDual pods share eni ips, or use vpc ips of node cidr by policy key network mode
+	daemonModeDual = "Dual"
 
 	gcPeriod = 5 * time.Minute
 
@@ -41,6 +51,47 @@ type networkService struct {
 	eniIPResMgr ResourceManager
 	//networkResourceMgr ResourceManager
 	mgrForResource map[string]ResourceManager
+	gc             *gcRunner
+	// netnsLeaks scanner of netns leaked by crashed runtimes, collected in gc
+	netnsLeaks *netnsLeakScanner
+	// gcProtection resources of sandboxes allocated within the window not reclaimed by gc, their pods may be
+	// not in the cache of local pods yet
+	gcProtection time.Duration
+	// allocationBudget end-to-end deadline of each allocation, ahead of the timeout of cni binary
+	allocationBudget time.Duration
+	// limitLock guard the checks of namespace limits and limitReservations
+	limitLock sync.Mutex
+	// limitReservations namespace/resource type to pods allocating in it, counted in namespace limits till
+	// allocations recorded or failed
+	limitReservations map[string]map[string]bool
+	// hostPort host port rules of pods with exclusive eni, nil if mode without exclusive eni
+	hostPort *hostPortManager
+	// routeTables policy route tables of enis in eni multi ip mode
+	routeTables *routeTableAllocator
+	// conntrackCleanup pod network types flushing conntrack entries of pod ip on release
+	conntrackCleanup map[string]bool
+	// allocTimings per-phase latency of allocations and slow allocation logging
+	allocTimings *allocTimings
+	// delayedReleases releases of pods delayed till grace period elapsed and conntrack drained
+	delayedReleases *delayedReleases
+	// recentErrors errors of allocations and releases published in node network state
+	recentErrors *recentErrors
+	// ipDenyList ips denied to allocate, kept in quarantine of pools
+	ipDenyList *ipDenyList
+	// allocFlights allocations in flight by sandbox, concurrent requests of sandbox share one
+	allocFlights *allocFlights
+	// slotReserve slots of pools reserved for critical pods, nil if none reserved
+	slotReserve *slotReserve
+	// eips eips associated to pods with eni or eni ip
+	eips *eipManager
+	// poolStats acquires of eni and eni ip pools for recommending min and max idle
+	poolStats *poolStats
+	// events allocations, releases and garbage collections streamed to watchers
+	events *resourceEvents
+	// selfCheck report of self check on start, served at /readyz
+	selfCheck *selfCheckReport
+	// exhaustion condition of node set while pools exhausted, nil if disabled
+	exhaustion *exhaustionMonitor
 	sync.RWMutex
 }
 
@@ -48,22 +99,119 @@ func (networkService *networkService) getResourceManagerForRes(resType string) R
 	return networkService.mgrForResource[resType]
 }
 
-//return resource relation in db, or return nil.
+// podResourceKey key of resource relation in db by pod uid and sandbox id, so relations of sandboxes of pods
+// recreated with same name kept apart, namespace/name for relations recorded without them
+func podResourceKey(res *PodResources) string {
+	if res.PodInfo.UID == "" && res.SandboxID == "" {
+		return podInfoKey(res.PodInfo.Namespace, res.PodInfo.Name)
+	}
+	return res.PodInfo.UID + "/" + res.SandboxID
+}
+
+// return resource relation of pod in db, the one of same pod uid and allocated latest if several sandboxes
+// of pods of the name, or return empty.
 func (networkService *networkService) getPodResource(info *podInfo) (PodResources, error) {
-	obj, err := networkService.resourceDB.Get(podInfoKey(info.Namespace, info.Name))
-	if err == nil {
-		return obj.(PodResources), nil
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		return PodResources{}, err
+	}
+	var ret PodResources
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil || resRelate.PodInfo.Namespace != info.Namespace || resRelate.PodInfo.Name != info.Name {
+			continue
+		}
+		if ret.PodInfo == nil || preferredRelation(&resRelate, &ret, info.UID) {
+			ret = resRelate
+		}
+	}
+	return ret, nil
+}
+
+// preferredRelation whether relation a preferred to b for pod of uid, the one of same uid, then allocated later
+func preferredRelation(a, b *PodResources, uid string) bool {
+	if (a.PodInfo.UID == uid) != (b.PodInfo.UID == uid) {
+		return a.PodInfo.UID == uid
 	}
-	if err == storage.ErrNotFound {
-		return PodResources{}, nil
+	return a.AllocatedAt > b.AllocatedAt
+}
+
+// getSandboxResource return resource relation of sandbox of pod in db, or the relation of pod if not found,
+// eg: relations recorded without sandbox id
+func (networkService *networkService) getSandboxResource(info *podInfo, sandboxID string) (PodResources, error) {
+	if sandboxID != "" {
+		resRelateList, err := networkService.resourceDB.List()
+		if err != nil {
+			return PodResources{}, err
+		}
+		for _, resRelateObj := range resRelateList {
+			resRelate := resRelateObj.(PodResources)
+			if resRelate.PodInfo != nil && resRelate.PodInfo.Namespace == info.Namespace &&
+				resRelate.PodInfo.Name == info.Name && resRelate.SandboxID == sandboxID {
+				return resRelate, nil
+			}
+		}
 	}
+	return networkService.getPodResource(info)
+}
 
-	return PodResources{}, err
+// putPodResource record resource relation of sandbox allocated, the relation of previous sandbox dropped if
+// its resources taken over, eg: transferred or reused by ip of sticky pod
+func (networkService *networkService) putPodResource(oldRes, newRes *PodResources) error {
+	if err := networkService.resourceDB.Put(podResourceKey(newRes), *newRes); err != nil {
+		return err
+	}
+	if oldRes.PodInfo == nil || podResourceKey(oldRes) == podResourceKey(newRes) {
+		return nil
+	}
+	for _, res := range oldRes.Resources {
+		for _, taken := range newRes.Resources {
+			if res == taken {
+				return networkService.deletePodResource(oldRes)
+			}
+		}
+	}
+	return nil
 }
 
-func (networkService *networkService) deletePodResource(info *podInfo) error {
-	key := podInfoKey(info.Namespace, info.Name)
-	return networkService.resourceDB.Delete(key)
+func (networkService *networkService) deletePodResource(res *PodResources) error {
+	return networkService.resourceDB.Delete(podResourceKey(res))
+}
+
+// migrateResourceKeys move relations recorded by namespace/name to keys of pod uid and sandbox id
+func migrateResourceKeys(db storage.Storage) error {
+	resRelateList, err := db.List()
+	if err != nil {
+		return err
+	}
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil {
+			continue
+		}
+		legacyKey := podInfoKey(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name)
+		if podResourceKey(&resRelate) == legacyKey {
+			continue
+		}
+		obj, err := db.Get(legacyKey)
+		if err == storage.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// the record of legacy key may be another sandbox of pod with the name
+		if legacy := obj.(PodResources); legacy.SandboxID != resRelate.SandboxID || legacy.PodInfo.UID != resRelate.PodInfo.UID {
+			continue
+		}
+		if err = db.Put(podResourceKey(&resRelate), resRelate); err != nil {
+			return err
+		}
+		if err = db.Delete(legacyKey); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (networkService *networkService) allocateVeth(ctx *networkContext, old *PodResources) (*types.Veth, error) {
@@ -79,6 +227,9 @@ func (networkService *networkService) allocateVeth(ctx *networkContext, old *Pod
 		}
 	}
 
+	if res := networkService.transferResource(ctx, networkService.vethResMgr, old, oldVethID); res != nil {
+		return res.(*types.Veth), nil
+	}
 	res, err := networkService.vethResMgr.Allocate(ctx, oldVethID)
 	if err != nil {
 		return nil, err
@@ -99,6 +250,9 @@ func (networkService *networkService) allocateENI(ctx *networkContext, old *PodR
 		}
 	}
 
+	if res := networkService.transferResource(ctx, networkService.eniResMgr, old, oldENIID); res != nil {
+		return res.(*types.ENI), nil
+	}
 	res, err := networkService.eniResMgr.Allocate(ctx, oldENIID)
 	if err != nil {
 		return nil, err
@@ -119,6 +273,12 @@ func (networkService *networkService) allocateENIMultiIP(ctx *networkContext, ol
 		}
 	}
 
+	if res := networkService.transferResource(ctx, networkService.eniIPResMgr, old, oldVethID); res != nil {
+		return res.(*types.ENIIP), nil
+	}
+	if ctx.pod.ENISpread {
+		ctx.avoid = networkService.ownerResources(ctx.pod, types.ResourceTypeENIIP)
+	}
 	res, err := networkService.eniIPResMgr.Allocate(ctx, oldVethID)
 	if err != nil {
 		return nil, err
@@ -126,7 +286,57 @@ func (networkService *networkService) allocateENIMultiIP(ctx *networkContext, ol
 	return res.(*types.ENIIP), nil
 }
 
+// transferResource hand resource of previous sandbox of pod over to the sandbox of request without release,
+// so ip of pod kept across in-place restart, nil if not transferable or failed and allocate instead
+func (networkService *networkService) transferResource(ctx *networkContext, mgr ResourceManager, old *PodResources, resID string) types.NetworkResource {
+	if resID == "" || !old.transferableTo(ctx.pod, ctx.sandboxID) {
+		return nil
+	}
+	res, err := mgr.Transfer(ctx, old.SandboxID, resID)
+	if err != nil {
+		ctx.Log().Warnf("error transfer %s of previous sandbox %s, allocate instead: %v", resID, old.SandboxID, err)
+		return nil
+	}
+	ctx.Log().Infof("transferred %s from previous sandbox %s to sandbox %s", resID, old.SandboxID, ctx.sandboxID)
+	return res
+}
+
+// ownerResources return resources of type allocated to other pods of the owner of pod
+func (networkService *networkService) ownerResources(pod *podInfo, resType string) []string {
+	if pod.Owner == "" {
+		return nil
+	}
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		log.Warnf("error list resource db for pods of %s: %v", pod.Owner, err)
+		return nil
+	}
+	var ids []string
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil || resRelate.PodInfo.Owner != pod.Owner || resRelate.PodInfo.Namespace != pod.Namespace ||
+			resRelate.PodInfo.Name == pod.Name {
+			continue
+		}
+		for _, res := range resRelate.GetResourceItemByType(resType) {
+			ids = append(ids, res.ID)
+		}
+	}
+	return ids
+}
+
 func (networkService *networkService) AllocIP(grpcContext context.Context, r *rpc.AllocIPRequest) (*rpc.AllocIPReply, error) {
+	key := sandboxKey(r.K8SPodNamespace, r.K8SPodName, r.K8SPodInfraContainerId)
+	reply, shared, err := networkService.allocFlights.do(grpcContext, key, func() (*rpc.AllocIPReply, error) {
+		return networkService.allocIP(grpcContext, r)
+	})
+	if shared {
+		log.Infof("alloc ip request of sandbox %s collapsed onto allocation in flight, err: %v", key, err)
+	}
+	return reply, err
+}
+
+func (networkService *networkService) allocIP(grpcContext context.Context, r *rpc.AllocIPRequest) (*rpc.AllocIPReply, error) {
 	log.Infof("alloc ip request: %+v", r)
 	networkService.RLock()
 	defer networkService.RUnlock()
@@ -134,8 +344,12 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 		start = time.Now()
 		err   error
 	)
+	grpcContext, span := startSpan(grpcContext, "AllocIP", r.K8SPodNamespace, r.K8SPodName, r.K8SPodInfraContainerId)
 	defer func() {
 		metric.RPCLatency.WithLabelValues("AllocIP", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+		networkService.recentErrors.record("AllocIP", podInfoKey(r.K8SPodNamespace, r.K8SPodName), err)
+		networkService.exhaustion.observe(err)
+		span.End(err)
 	}()
 
 	// 0. Get pod Info
@@ -145,18 +359,33 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 	}
 
 	// 1. Init Context
+	// resources in creation after budget exceeded handed to pools, and allocated ones rolled back below,
+	// instead of left in limbo by cni binary timed out
+	grpcContext, cancel := context.WithTimeout(grpcContext, networkService.allocationBudget)
+	defer cancel()
+	timing := networkService.allocTimings.begin(podinfo.PodNetworkType)
 	networkContext := &networkContext{
-		Context:    grpcContext,
+		Context:    pool.WithPhaseObserver(grpcContext, timing.observe),
 		resources:  []ResourceItem{},
 		pod:        podinfo,
 		k8sService: networkService.k8s,
+		sandboxID:  r.K8SPodInfraContainerId,
 	}
 	allocIPReply := &rpc.AllocIPReply{}
+	defer func() {
+		networkService.allocTimings.finish(podInfoKey(podinfo.Namespace, podinfo.Name), timing, err == nil)
+		if resType := podResourceType(podinfo.PodNetworkType); err == nil && resType != types.ResourceTypeVeth {
+			networkService.poolStats.observeAllocation(resType, timing)
+		}
+	}()
 
 	defer func() {
 		// roll back allocated resource when error
 		if err != nil {
 			networkContext.Log().Errorf("alloc result with error, %+v", err)
+			if grpcContext.Err() == context.DeadlineExceeded {
+				networkContext.Log().Warnf("allocation exceeded budget %s, roll back allocated resources", networkService.allocationBudget)
+			}
 			for _, res := range networkContext.resources {
 				mgr := networkService.getResourceManagerForRes(res.Type)
 				if mgr == nil {
@@ -165,8 +394,14 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 				}
 				mgr.Release(networkContext, res.ID)
 			}
+			networkService.publishPodEvents(eventTypeAllocate,
+				&PodResources{PodInfo: podinfo, SandboxID: r.K8SPodInfraContainerId}, err)
 		} else {
 			networkContext.Log().Infof("alloc result: %+v", allocIPReply)
+			if networkService.events.watched() {
+				newRes, _ := networkService.getPodResource(podinfo)
+				networkService.publishPodEvents(eventTypeAllocate, &newRes, nil)
+			}
 		}
 	}()
 
@@ -180,16 +415,37 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 		return nil, fmt.Errorf("unexpect pod network type allocate, maybe daemon mode changed: %+v", podinfo.PodNetworkType)
 	}
 
+	resType := podResourceType(podinfo.PodNetworkType)
+	networkService.releaseOtherTypes(networkContext, &oldRes, resType)
+	if limit, ok := podinfo.NamespaceLimits[resType]; ok {
+		var done func()
+		if done, err = networkService.reserveNamespaceLimit(podinfo, resType, limit); err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+	// pods taking back resources allocated before need no more slot
+	if mgr := networkService.getResourceManagerForRes(resType); mgr != nil && resType != types.ResourceTypeVeth &&
+		len(oldRes.GetResourceItemByType(resType)) == 0 {
+		var done func()
+		if done, err = networkService.slotReserve.acquire(podinfo, resType, mgr.Stats()); err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+
 	// 3. Allocate network resource for pod
 	switch podinfo.PodNetworkType {
 	case podNetworkTypeENIMultiIP:
 		var eniMultiIP *types.ENIIP
 		eniMultiIP, err = networkService.allocateENIMultiIP(networkContext, &oldRes)
 		if err != nil {
-			return nil, fmt.Errorf("error get allocated eniip ip for: %+v, result: %+v", podinfo, err)
+			return nil, errors.Wrapf(err, "error get allocated eniip ip for: %+v", podinfo)
 		}
 		newRes := PodResources{
-			PodInfo: podinfo,
+			PodInfo:     podinfo,
+			SandboxID:   r.K8SPodInfraContainerId,
+			AllocatedAt: time.Now().Unix(),
 			Resources: []ResourceItem{
 				{
 					ID:   eniMultiIP.GetResourceID(),
@@ -198,11 +454,22 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 			},
 		}
 
-		err = networkService.resourceDB.Put(podInfoKey(podinfo.Namespace, podinfo.Name), newRes)
+		err = networkService.eips.bind(podInfoKey(podinfo.Namespace, podinfo.Name), podinfo.EIP, eniMultiIP.Eni.ID, eniMultiIP.SecAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error bind eip of pod")
+		}
+		err = networkService.putPodResource(&oldRes, &newRes)
 		if err != nil {
+			networkService.unbindEIPOnRollback(networkContext, podinfo)
 			return nil, errors.Wrapf(err, "error put resource into store")
 		}
 
+		// table id 0 let cni binary use the legacy table id
+		routeTableID, tableErr := networkService.routeTables.allocate(eniMultiIP.Eni.MAC)
+		if tableErr != nil {
+			networkContext.Log().Warnf("error allocate route table for eni %s, use legacy table: %v", eniMultiIP.Eni.MAC, tableErr)
+		}
+
 		allocIPReply.IPType = rpc.IPType_TypeENIMultiIP
 		allocIPReply.Success = true
 		allocIPReply.NetworkInfo = &rpc.AllocIPReply_ENIMultiIP{
@@ -214,10 +481,14 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 					Gateway:         eniMultiIP.Eni.Gateway.String(),
 					DeviceNumber:    eniMultiIP.Eni.DeviceNumber,
 					PrimaryIPv4Addr: eniMultiIP.Eni.Address.IP.String(),
+					RouteTableID:    int32(routeTableID),
 				},
 				PodConfig: &rpc.Pod{
-					Ingress: podinfo.TcIngress,
-					Egress:  podinfo.TcEgress,
+					Ingress:         podinfo.TcIngress,
+					Egress:          podinfo.TcEgress,
+					LinkLocalAccess: podinfo.LinkLocalAccess,
+					Routes:          rpcRoutes(podinfo.Routes),
+					Sysctls:         podinfo.Sysctls,
 				},
 			},
 		}
@@ -225,10 +496,12 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 		var vpcEni *types.ENI
 		vpcEni, err = networkService.allocateENI(networkContext, &oldRes)
 		if err != nil {
-			return nil, fmt.Errorf("error get allocated vpc ENI ip for: %+v, result: %+v", podinfo, err)
+			return nil, errors.Wrapf(err, "error get allocated vpc ENI ip for: %+v", podinfo)
 		}
 		newRes := PodResources{
-			PodInfo: podinfo,
+			PodInfo:     podinfo,
+			SandboxID:   r.K8SPodInfraContainerId,
+			AllocatedAt: time.Now().Unix(),
 			Resources: []ResourceItem{
 				{
 					ID:   vpcEni.GetResourceID(),
@@ -237,8 +510,19 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 			},
 		}
 
-		err = networkService.resourceDB.Put(podInfoKey(podinfo.Namespace, podinfo.Name), newRes)
+		err = networkService.hostPort.setup(podInfoKey(podinfo.Namespace, podinfo.Name), vpcEni.Address.IP, podinfo.HostPorts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error setup host ports of pod")
+		}
+		err = networkService.eips.bind(podInfoKey(podinfo.Namespace, podinfo.Name), podinfo.EIP, vpcEni.ID, vpcEni.Address.IP)
+		if err != nil {
+			networkService.teardownHostPortsOnRollback(networkContext, podinfo)
+			return nil, errors.Wrapf(err, "error bind eip of pod")
+		}
+		err = networkService.putPodResource(&oldRes, &newRes)
 		if err != nil {
+			networkService.unbindEIPOnRollback(networkContext, podinfo)
+			networkService.teardownHostPortsOnRollback(networkContext, podinfo)
 			return nil, errors.Wrapf(err, "error put resource into store")
 		}
 		allocIPReply.IPType = rpc.IPType_TypeVPCENI
@@ -254,8 +538,12 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 					PrimaryIPv4Addr: vpcEni.Address.IP.String(),
 				},
 				PodConfig: &rpc.Pod{
-					Ingress: podinfo.TcIngress,
-					Egress:  podinfo.TcEgress,
+					Ingress:         podinfo.TcIngress,
+					Egress:          podinfo.TcEgress,
+					LinkLocalAccess: podinfo.LinkLocalAccess,
+					Routes:          rpcRoutes(podinfo.Routes),
+					Sysctls:         podinfo.Sysctls,
+					InterfaceTuning: rpcInterfaceTuning(podinfo.InterfaceTuning),
 				},
 				ServiceCidr: networkService.k8s.GetServiceCidr().String(),
 			},
@@ -264,10 +552,12 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 		var vpcVeth *types.Veth
 		vpcVeth, err = networkService.allocateVeth(networkContext, &oldRes)
 		if err != nil {
-			return nil, fmt.Errorf("error get allocated vpc ip for: %+v, result: %+v", podinfo, err)
+			return nil, errors.Wrapf(err, "error get allocated vpc ip for: %+v", podinfo)
 		}
 		newRes := PodResources{
-			PodInfo: podinfo,
+			PodInfo:     podinfo,
+			SandboxID:   r.K8SPodInfraContainerId,
+			AllocatedAt: time.Now().Unix(),
 			Resources: []ResourceItem{
 				{
 					ID:   vpcVeth.GetResourceID(),
@@ -276,19 +566,27 @@ func (networkService *networkService) AllocIP(grpcContext context.Context, r *rp
 			},
 		}
 
-		err = networkService.resourceDB.Put(podInfoKey(podinfo.Namespace, podinfo.Name), newRes)
+		err = networkService.putPodResource(&oldRes, &newRes)
 		if err != nil {
 			return nil, errors.Wrapf(err, "error put resource into store")
 		}
+		if podinfo.EIP != nil {
+			networkContext.Log().Warnf("eip not supported for pod with vpc ip sharing node eni, ignored")
+		}
 		allocIPReply.IPType = rpc.IPType_TypeVPCIP
 		allocIPReply.Success = true
 		allocIPReply.NetworkInfo = &rpc.AllocIPReply_VpcIp{
 			VpcIp: &rpc.VPCIP{
 				PodConfig: &rpc.Pod{
-					Ingress: podinfo.TcIngress,
-					Egress:  podinfo.TcEgress,
+					Ingress:         podinfo.TcIngress,
+					Egress:          podinfo.TcEgress,
+					LinkLocalAccess: podinfo.LinkLocalAccess,
+					Routes:          rpcRoutes(podinfo.Routes),
+					Sysctls:         podinfo.Sysctls,
 				},
 				NodeCidr: networkService.k8s.GetNodeCidr().String(),
+				PodIP:    vpcVeth.PodIP.String(),
+				Gateway:  vpcVeth.Gateway.String(),
 			},
 		}
 
@@ -314,8 +612,11 @@ func (networkService *networkService) ReleaseIP(grpcContext context.Context, r *
 		start = time.Now()
 		err   error
 	)
+	grpcContext, span := startSpan(grpcContext, "ReleaseIP", r.K8SPodNamespace, r.K8SPodName, r.K8SPodInfraContainerId)
 	defer func() {
 		metric.RPCLatency.WithLabelValues("ReleaseIP", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+		networkService.recentErrors.record("ReleaseIP", podInfoKey(r.K8SPodNamespace, r.K8SPodName), err)
+		span.End(err)
 	}()
 
 	// 0. Get pod Info
@@ -330,6 +631,7 @@ func (networkService *networkService) ReleaseIP(grpcContext context.Context, r *
 		resources:  []ResourceItem{},
 		pod:        podinfo,
 		k8sService: networkService.k8s,
+		sandboxID:  r.K8SPodInfraContainerId,
 	}
 	releaseReply := &rpc.ReleaseIPReply{
 		Success: true,
@@ -340,18 +642,49 @@ func (networkService *networkService) ReleaseIP(grpcContext context.Context, r *
 		} else {
 			networkContext.Log().Infof("release result: %+v", releaseReply)
 		}
+		if len(networkContext.resources) > 0 {
+			networkService.publishPodEvents(eventTypeRelease, &PodResources{PodInfo: podinfo,
+				SandboxID: networkContext.sandboxID, Resources: networkContext.resources}, err)
+		}
 	}()
 
-	oldRes, err := networkService.getPodResource(podinfo)
+	oldRes, err := networkService.getSandboxResource(podinfo, r.K8SPodInfraContainerId)
 	if err != nil {
 		return nil, err
 	}
 
+	// pod recreated with same name, the resources belong to new sandbox
+	if !oldRes.ownedBy(r.K8SPodInfraContainerId) {
+		networkContext.Log().Warnf("resources of pod bound to sandbox %s, skip release for sandbox %s",
+			oldRes.SandboxID, r.K8SPodInfraContainerId)
+		return releaseReply, nil
+	}
+	if oldRes.SandboxID != "" {
+		networkContext.sandboxID = oldRes.SandboxID
+	}
+
 	if !networkService.verifyPodNetworkType(podinfo.PodNetworkType) {
 		networkContext.Log().Warnf("unexpect pod network type release, maybe daemon mode changed: %+v", podinfo.PodNetworkType)
 		return releaseReply, nil
 	}
 
+	if err = networkService.hostPort.teardown(podInfoKey(podinfo.Namespace, podinfo.Name)); err != nil {
+		return nil, errors.Wrapf(err, "error teardown host ports of pod")
+	}
+	// unassociated before ip released, ip may be reused by other pods after released
+	if err = networkService.eips.unbind(podInfoKey(podinfo.Namespace, podinfo.Name)); err != nil {
+		return nil, errors.Wrapf(err, "error unbind eip of pod")
+	}
+	if err = teardownMirror(podinfo.Namespace, podinfo.Name); err != nil {
+		networkContext.Log().Warnf("error teardown traffic mirror of pod: %v", err)
+	}
+
+	delay := networkService.delayedReleases.shouldDelay(podinfo)
+	if !delay {
+		networkService.flushPodConntrack(networkContext, oldRes, podinfo)
+	}
+
+	var delayed []ResourceItem
 	for _, res := range oldRes.Resources {
 		//record old resource for pod
 		networkContext.resources = append(networkContext.resources, res)
@@ -360,11 +693,22 @@ func (networkService *networkService) ReleaseIP(grpcContext context.Context, r *
 			networkContext.Log().Warnf("error cleanup allocated network resource %s, %s: %v", res.ID, res.Type, err)
 			continue
 		}
-		if err = mgr.Release(networkContext, res.ID); err != nil && err != pool.ErrInvalidState {
+		// held inuse till released by delayed release
+		if delay && delayReleasable(res.Type) {
+			delayed = append(delayed, res)
+		} else if err = mgr.Release(networkContext, res.ID); err != nil && err != pool.ErrInvalidState {
 			return nil, errors.Wrapf(err, "error release request network resource for: %+v", r)
 		}
-
-		if err = networkService.deletePodResource(podinfo); err != nil {
+	}
+	// persisted before the relation deleted, so the resources kept inuse across restarts of daemon
+	if len(delayed) > 0 {
+		if err = networkService.delayedReleases.add(podinfo, networkContext.sandboxID, oldRes, delayed, time.Now()); err != nil {
+			return nil, errors.Wrapf(err, "error delay release of resources for: %+v", r)
+		}
+		networkContext.Log().Infof("release of %d resources delayed till grace period of pod elapsed and conntrack drained", len(delayed))
+	}
+	if len(oldRes.Resources) > 0 {
+		if err = networkService.deletePodResource(&oldRes); err != nil {
 			return nil, errors.Wrapf(err, "error delete resource from db: %+v", r)
 		}
 	}
@@ -377,6 +721,23 @@ func (networkService *networkService) ReleaseIP(grpcContext context.Context, r *
 	return releaseReply, nil
 }
 
+// flushPodConntrack flush conntrack entries of ips of pod if configured, before resources released as ip may be
+// reused by other pods after released
+func (networkService *networkService) flushPodConntrack(networkContext *networkContext, res PodResources, podinfo *podInfo) {
+	if !networkService.conntrackCleanup[podinfo.PodNetworkType] {
+		return
+	}
+	for _, ip := range releasedPodIPs(res, podinfo) {
+		flushed, err := flushConntrack(ip)
+		if err != nil {
+			networkContext.Log().Warnf("error flush conntrack entries of %s: %v", ip, err)
+			continue
+		}
+		networkContext.Log().Infof("%d conntrack entries of %s flushed", flushed, ip)
+		metric.ConntrackFlushed.WithLabelValues(podinfo.PodNetworkType).Add(float64(flushed))
+	}
+}
+
 func (networkService *networkService) GetIPInfo(ctx context.Context, r *rpc.GetInfoRequest) (*rpc.GetInfoReply, error) {
 	log.Infof("GetIPInfo request: %+v", r)
 	// 0. Get pod Info
@@ -399,14 +760,26 @@ func (networkService *networkService) GetIPInfo(ctx context.Context, r *rpc.GetI
 		networkContext.Log().Infof("getIpInfo result: %+v", getIPInfoResult)
 	}()
 
+	// use the pod info recorded on allocation of the sandbox, pod may be recreated with same name
+	oldRes, err := networkService.getSandboxResource(podinfo, r.K8SPodInfraContainerId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error get pod resources from db for pod %+v", podinfo)
+	}
+	if oldRes.PodInfo != nil && oldRes.SandboxID != "" && oldRes.SandboxID == r.K8SPodInfraContainerId {
+		podinfo = oldRes.PodInfo
+	}
+
 	// 2. return network info for pod
 	switch podinfo.PodNetworkType {
 	case podNetworkTypeENIMultiIP:
 		getIPInfoResult = &rpc.GetInfoReply{
 			IPType: rpc.IPType_TypeENIMultiIP,
 			PodConfig: &rpc.Pod{
-				Ingress: podinfo.TcIngress,
-				Egress:  podinfo.TcEgress,
+				Ingress:         podinfo.TcIngress,
+				Egress:          podinfo.TcEgress,
+				LinkLocalAccess: podinfo.LinkLocalAccess,
+				Routes:          rpcRoutes(podinfo.Routes),
+				Sysctls:         podinfo.Sysctls,
 			},
 		}
 		return getIPInfoResult, nil
@@ -414,24 +787,157 @@ func (networkService *networkService) GetIPInfo(ctx context.Context, r *rpc.GetI
 		getIPInfoResult = &rpc.GetInfoReply{
 			IPType: rpc.IPType_TypeVPCIP,
 			PodConfig: &rpc.Pod{
-				Ingress: podinfo.TcIngress,
-				Egress:  podinfo.TcEgress,
+				Ingress:         podinfo.TcIngress,
+				Egress:          podinfo.TcEgress,
+				LinkLocalAccess: podinfo.LinkLocalAccess,
+				Routes:          rpcRoutes(podinfo.Routes),
+				Sysctls:         podinfo.Sysctls,
 			},
-			NodeCidr: networkService.k8s.GetNodeCidr().String(),
+			NodeCidr:   networkService.k8s.GetNodeCidr().String(),
+			DaemonIPAM: true,
 		}
 		return getIPInfoResult, nil
 	case podNetworkTypeVPCENI:
 		getIPInfoResult = &rpc.GetInfoReply{
 			IPType: rpc.IPType_TypeVPCENI,
 			PodConfig: &rpc.Pod{
-				Ingress: podinfo.TcIngress,
-				Egress:  podinfo.TcEgress,
+				Ingress:         podinfo.TcIngress,
+				Egress:          podinfo.TcEgress,
+				LinkLocalAccess: podinfo.LinkLocalAccess,
+				Routes:          rpcRoutes(podinfo.Routes),
+				Sysctls:         podinfo.Sysctls,
+				InterfaceTuning: rpcInterfaceTuning(podinfo.InterfaceTuning),
 			},
 		}
 		return getIPInfoResult, nil
 	default:
-		return getIPInfoResult, errors.Errorf("unknown or unsupport network type for: %v", r)
+		return getIPInfoResult, fmt.Errorf("unknown or unsupport network type for: %v", r)
+	}
+}
+
+// Handshake negotiate api version and features with cni binary
+func (networkService *networkService) Handshake(ctx context.Context, r *rpc.HandshakeRequest) (*rpc.HandshakeReply, error) {
+	log.Debugf("handshake request: %+v", r)
+	if err := version.CheckAPIVersion(r.APIVersion); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "cni binary incompatible with daemon: %v", err)
+	}
+	return &rpc.HandshakeReply{
+		APIVersion: version.APIVersion,
+		Features:   version.NegotiateFeatures(version.Features, r.Features),
+	}, nil
+}
+
+// ReportSetup record duration of pod network setup reported by cni binary
+func (networkService *networkService) ReportSetup(ctx context.Context, r *rpc.ReportSetupRequest) (*rpc.ReportSetupReply, error) {
+	log.Debugf("report setup request: %+v", r)
+	if r.SetupMicroseconds < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid setup duration: %d", r.SetupMicroseconds)
+	}
+	networkService.allocTimings.reportSetup(podInfoKey(r.K8SPodNamespace, r.K8SPodName),
+		time.Duration(r.SetupMicroseconds)*time.Microsecond)
+	// host veth of pod set up by cni binary already, mirror ensured by reconcile if failed
+	podinfo, err := networkService.k8s.GetPod(r.K8SPodNamespace, r.K8SPodName)
+	if err != nil {
+		log.Warnf("error get pod %s/%s for traffic mirror: %v", r.K8SPodNamespace, r.K8SPodName, err)
+	} else if err = ensureMirror(podinfo); err != nil {
+		log.Warnf("error set up traffic mirror of pod %s/%s: %v", r.K8SPodNamespace, r.K8SPodName, err)
+	}
+	return &rpc.ReportSetupReply{}, nil
+}
+
+func (networkService *networkService) UpdateIPDenyList(ctx context.Context, r *rpc.UpdateIPDenyListRequest) (*rpc.IPDenyListReply, error) {
+	log.Infof("update ip deny list request: %+v", r)
+	if err := networkService.ipDenyList.update(r.Deny, r.Allow, r.Reason); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "error update ip deny list: %v", err)
+	}
+	return ipDenyListReply(networkService.ipDenyList.list()), nil
+}
+
+func (networkService *networkService) GetIPDenyList(ctx context.Context, r *rpc.GetIPDenyListRequest) (*rpc.IPDenyListReply, error) {
+	return ipDenyListReply(networkService.ipDenyList.list()), nil
+}
+
+func ipDenyListReply(ips []deniedIP) *rpc.IPDenyListReply {
+	reply := &rpc.IPDenyListReply{}
+	for _, ip := range ips {
+		reply.IPs = append(reply.IPs, &rpc.DeniedIP{
+			IP:       ip.IP,
+			Reason:   ip.Reason,
+			DeniedAt: ip.DeniedAt.Unix(),
+		})
+	}
+	return reply
+}
+
+// podResourceType the type of resource allocated for pod network type
+func podResourceType(podNetworkType string) string {
+	switch podNetworkType {
+	case podNetworkTypeENIMultiIP:
+		return types.ResourceTypeENIIP
+	case podNetworkTypeVPCENI:
+		return types.ResourceTypeENI
+	default:
+		return types.ResourceTypeVeth
+	}
+}
+
+// checkNamespaceLimit check resources of type consumed and reserved by other pods in namespace of pod below limit
+func (networkService *networkService) checkNamespaceLimit(podinfo *podInfo, resType string, limit int) error {
+	networkService.limitLock.Lock()
+	defer networkService.limitLock.Unlock()
+	return networkService.checkNamespaceLimitLocked(podinfo, resType, limit)
+}
+
+func (networkService *networkService) checkNamespaceLimitLocked(podinfo *podInfo, resType string, limit int) error {
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		return errors.Wrapf(err, "error list resource db for namespace limit")
+	}
+	used := 0
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil || resRelate.PodInfo.Namespace != podinfo.Namespace || resRelate.PodInfo.Name == podinfo.Name {
+			continue
+		}
+		used += len(resRelate.GetResourceItemByType(resType))
+	}
+	for name := range networkService.limitReservations[podinfo.Namespace+"/"+resType] {
+		if name != podinfo.Name {
+			used++
+		}
+	}
+	if used >= limit {
+		return fmt.Errorf("namespace %s has used %d of %d %s resources allowed on node %s, pod %s not allocated",
+			podinfo.Namespace, used, limit, resType, networkService.k8s.GetNodeName(), podinfo.Name)
+	}
+	return nil
+}
+
+// reserveNamespaceLimit check namespace limit and reserve a resource of type for pod, counted in the limit
+// till done called after the allocation recorded or failed
+func (networkService *networkService) reserveNamespaceLimit(podinfo *podInfo, resType string, limit int) (done func(), err error) {
+	networkService.limitLock.Lock()
+	defer networkService.limitLock.Unlock()
+	if err = networkService.checkNamespaceLimitLocked(podinfo, resType, limit); err != nil {
+		return nil, err
 	}
+
+	key := podinfo.Namespace + "/" + resType
+	if networkService.limitReservations == nil {
+		networkService.limitReservations = make(map[string]map[string]bool)
+	}
+	if networkService.limitReservations[key] == nil {
+		networkService.limitReservations[key] = make(map[string]bool)
+	}
+	networkService.limitReservations[key][podinfo.Name] = true
+	return func() {
+		networkService.limitLock.Lock()
+		defer networkService.limitLock.Unlock()
+		delete(networkService.limitReservations[key], podinfo.Name)
+		if len(networkService.limitReservations[key]) == 0 {
+			delete(networkService.limitReservations, key)
+		}
+	}, nil
 }
 
 func (networkService *networkService) verifyPodNetworkType(podNetworkMode string) bool {
@@ -440,7 +946,13 @@ func (networkService *networkService) verifyPodNetworkType(podNetworkMode string
 		// eni-multi-ip
 		(networkService.daemonMode == daemonModeENIMultiIP && podNetworkMode == podNetworkTypeENIMultiIP) ||
 		// eni-only
-		(networkService.daemonMode == daemonModeENIOnly && podNetworkMode == podNetworkTypeVPCENI)
+		(networkService.daemonMode == daemonModeENIOnly && podNetworkMode == podNetworkTypeVPCENI) ||
+		// hybrid
+		(networkService.daemonMode == daemonModeHybrid &&
+			(podNetworkMode == podNetworkTypeENIMultiIP || podNetworkMode == podNetworkTypeVPCENI)) ||
+		// dual
+		(networkService.daemonMode == daemonModeDual &&
+			(podNetworkMode == podNetworkTypeENIMultiIP || podNetworkMode == podNetworkTypeVPCIP))
 }
 
 func (networkService *networkService) startGarbageCollectionLoop() {
@@ -456,17 +968,26 @@ func (networkService *networkService) startGarbageCollectionLoop() {
 				networkService.Unlock()
 				continue
 			}
-			podKeyMap := make(map[string]bool)
+			podKeyMap := make(map[string]string)
+			hostPortPods := make(map[string]bool)
 
 			for _, pod := range pods {
-				podKeyMap[podInfoKey(pod.Namespace, pod.Name)] = true
+				podKeyMap[podInfoKey(pod.Namespace, pod.Name)] = pod.UID
+				if pod.PodNetworkType == podNetworkTypeVPCENI && len(pod.HostPorts) > 0 {
+					hostPortPods[podInfoKey(pod.Namespace, pod.Name)] = true
+				}
 			}
-
 			var (
 				inUseSet         = make(map[string]map[string]interface{})
 				expireSet        = make(map[string]map[string]interface{})
 				relateExpireList = make([]string, 0)
+				expired          []PodResources
 			)
+			// managers collected even without resources of pods, eg: ips of vpc ip pods all gone in dual mode
+			for resType := range networkService.mgrForResource {
+				inUseSet[resType] = make(map[string]interface{})
+				expireSet[resType] = make(map[string]interface{})
+			}
 
 			resRelateList, err := networkService.resourceDB.List()
 			if err != nil {
@@ -474,12 +995,23 @@ func (networkService *networkService) startGarbageCollectionLoop() {
 				networkService.Unlock()
 				continue
 			}
+			allocating := networkService.allocFlights.pods()
 
 			for _, resRelateObj := range resRelateList {
 				resRelate := resRelateObj.(PodResources)
-				_, podExist := podKeyMap[podInfoKey(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name)]
+				uid, podExist := podKeyMap[podInfoKey(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name)]
+				// pod recreated with same name, relation of the old one expired
+				if podExist && resRelate.PodInfo.UID != "" && uid != resRelate.PodInfo.UID {
+					podExist = false
+				}
+				if !podExist && networkService.gcProtected(resRelate, allocating) {
+					log.Debugf("resources of pod %s/%s allocated recently or in flight, skip gc",
+						resRelate.PodInfo.Namespace, resRelate.PodInfo.Name)
+					podExist = true
+				}
 				if !podExist {
-					relateExpireList = append(relateExpireList, podInfoKey(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name))
+					relateExpireList = append(relateExpireList, podResourceKey(&resRelate))
+					expired = append(expired, resRelate)
 				}
 				for _, res := range resRelate.Resources {
 					if _, ok := inUseSet[res.Type]; !ok {
@@ -497,96 +1029,180 @@ func (networkService *networkService) startGarbageCollectionLoop() {
 					}
 				}
 			}
-			gcDone := true
-			for mgrType := range inUseSet {
-				mgr, ok := networkService.mgrForResource[mgrType]
-				if ok {
-					err = mgr.GarbageCollection(inUseSet[mgrType], expireSet[mgrType])
-					if err != nil {
-						log.Warnf("error do garbage collection for %+v, inuse: %v, expire: %v, err: %v", mgrType, inUseSet, expireSet, err)
-						gcDone = false
-					}
+			collectors := resourceCollectors(networkService.mgrForResource, inUseSet, expireSet)
+			collectors[gcCollectorHostPort] = func() error {
+				return networkService.hostPort.gc(hostPortPods)
+			}
+			collectors[gcCollectorNetns] = networkService.netnsLeaks.collect
+			errs := networkService.gc.run(collectors)
+			resourcesCollected := true
+			for name, err := range errs {
+				log.Warnf("error do garbage collection: %v", err)
+				if _, ok := networkService.mgrForResource[name]; ok {
+					resourcesCollected = false
 				}
 			}
-			if gcDone {
+			// relations expired only if resources of them collected
+			if resourcesCollected {
 				for _, relate := range relateExpireList {
 					err = networkService.resourceDB.Delete(relate)
 					if err != nil {
 						log.Warnf("error delete resource db relation: %v", err)
 					}
 				}
+				for i := range expired {
+					networkService.publishPodEvents(eventTypeGC, &expired[i], nil)
+				}
 			}
 			networkService.Unlock()
 		}
 	}()
 }
 
-func newNetworkService(configFilePath, kubeconfig, master, daemonMode string) (rpc.TerwayBackendServer, error) {
-	log.Debugf("start network service with: %s, %s", configFilePath, daemonMode)
-	netSrv := &networkService{}
-	if daemonMode == daemonModeENIMultiIP || daemonMode == daemonModeVPC || daemonMode == daemonModeENIOnly {
-		netSrv.daemonMode = daemonMode
-	} else {
-		return nil, fmt.Errorf("unsupport daemon mode")
+// teardownHostPortsOnRollback teardown host ports of pod set up by allocation failed
+func (networkService *networkService) teardownHostPortsOnRollback(ctx *networkContext, podinfo *podInfo) {
+	if err := networkService.hostPort.teardown(podInfoKey(podinfo.Namespace, podinfo.Name)); err != nil {
+		ctx.Log().Errorf("error teardown host ports of pod on rollback: %v", err)
 	}
+}
 
-	config := &types.Configure{}
+// unbindEIPOnRollback unbind eip of pod bound by allocation failed
+func (networkService *networkService) unbindEIPOnRollback(ctx *networkContext, podinfo *podInfo) {
+	if err := networkService.eips.unbind(podInfoKey(podinfo.Namespace, podinfo.Name)); err != nil {
+		ctx.Log().Errorf("error unbind eip of pod on rollback: %v", err)
+	}
+}
 
-	f, err := os.Open(configFilePath)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed open config file")
+// releaseOtherTypes release resources of previous sandbox of pod not of resType, eg: network mode of pod
+// changed in dual mode, dropped from the relation of pod and never collected otherwise
+func (networkService *networkService) releaseOtherTypes(ctx *networkContext, oldRes *PodResources, resType string) {
+	if oldRes.PodInfo == nil || oldRes.SandboxID == ctx.sandboxID {
+		return
 	}
+	for _, res := range oldRes.Resources {
+		if res.Type == resType {
+			continue
+		}
+		mgr := networkService.getResourceManagerForRes(res.Type)
+		if mgr == nil {
+			continue
+		}
+		// released as of the previous sandbox, not the ips of the sandbox allocating
+		staleContext := &networkContext{
+			Context:    ctx.Context,
+			pod:        oldRes.PodInfo,
+			k8sService: ctx.k8sService,
+			sandboxID:  oldRes.SandboxID,
+		}
+		err := mgr.Release(staleContext, res.ID)
+		networkService.publishPodEvents(eventTypeRelease, &PodResources{PodInfo: oldRes.PodInfo,
+			SandboxID: oldRes.SandboxID, Resources: []ResourceItem{res}}, err)
+		if err != nil {
+			ctx.Log().Warnf("error release %s %s of previous sandbox %s: %v", res.Type, res.ID, oldRes.SandboxID, err)
+			continue
+		}
+		ctx.Log().Infof("released %s %s of previous sandbox %s, pod network type changed to %s",
+			res.Type, res.ID, oldRes.SandboxID, ctx.pod.PodNetworkType)
+	}
+}
 
-	data, err := ioutil.ReadAll(f)
-	if err != nil {
-		return nil, fmt.Errorf("failed read file %s: %v", configFilePath, err)
+// gcProtected whether resources of pod not found on node protected from gc: allocated within the
+// protection window or allocation of the pod in flight, the pod may be created but not in cache yet
+func (networkService *networkService) gcProtected(resRelate PodResources, allocating map[string]bool) bool {
+	if allocating[podInfoKey(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name)] {
+		return true
 	}
+	return resRelate.AllocatedAt != 0 && time.Since(time.Unix(resRelate.AllocatedAt, 0)) < networkService.gcProtection
+}
 
-	if err := json.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed parse config: %v", err)
+// localPodKeys return keys of pods on node
+func (networkService *networkService) localPodKeys() (map[string]bool, error) {
+	pods, err := networkService.k8s.GetLocalPods()
+	if err != nil {
+		return nil, err
 	}
+	keys := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		keys[podInfoKey(pod.Namespace, pod.Name)] = true
+	}
+	return keys, nil
+}
 
-	log.Infof("got config: %+v from: %+v", config, configFilePath)
+func newNetworkService(config *types.Configure, k8sClient kubernetes.Interface, daemonMode string, cloudConfig *CloudConfig) (*networkService, error) {
+	log.Debugf("start network service with: %s", daemonMode)
+	netSrv := &networkService{}
+	if !daemonModeSupported(daemonMode) {
+		return nil, fmt.Errorf("unsupport daemon mode: %s", daemonMode)
+	}
+	netSrv.daemonMode = daemonMode
 
 	if err := validateConfig(config); err != nil {
 		return nil, err
 	}
 
-	if err := setDefault(config); err != nil {
+	if err := defaults.SetDefault(config); err != nil {
 		return nil, err
 	}
 
-	regionID, err := aliyun.GetLocalRegion()
+	ecs, err := newECS(cloudConfig, config)
 	if err != nil {
-		return nil, errors.Wrapf(err, "error get region-id")
+		return nil, errors.Wrapf(err, "error init ecs client")
 	}
 
-	ecs, err := aliyun.NewECS(config.AccessID, config.AccessSecret, regionID)
-	if err != nil {
-		return nil, errors.Wrapf(err, "error get region-id")
+	var ipnet *net.IPNet
+	if config.ServiceCIDR != "" {
+		_, ipnet, err = net.ParseCIDR(config.ServiceCIDR)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parse service cidr: %s", config.ServiceCIDR)
+		}
 	}
 
-	k8sRestConfig, err := clientcmd.BuildConfigFromFlags(master, kubeconfig)
+	// get pool config
+	poolConfig, err := getPoolConfig(config, ecs)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "error get pool config")
 	}
-	k8sClient, err := kubernetes.NewForConfig(k8sRestConfig)
-	if err != nil {
+	log.Infof("init pool config: %+v", poolConfig)
+
+	netSrv.selfCheck = newSelfChecker(poolConfig, daemonMode, ecs).run(config.SelfCheck)
+	netSrv.selfCheck.log()
+	if err = netSrv.selfCheck.err(); err != nil {
 		return nil, err
 	}
+	if err = checkPoolConfig(poolConfig, daemonMode, ecs); err != nil {
+		return nil, errors.Wrapf(err, "error validate config")
+	}
 
-	var ipnet *net.IPNet
-	if config.ServiceCIDR != "" {
-		_, ipnet, err = net.ParseCIDR(config.ServiceCIDR)
+	// enis of instance attached before pools init are taken back by pools
+	if _, err = adoptENIs(ecs, poolConfig.InstanceID); err != nil {
+		log.Warnf("error adopt enis left detached, fresh enis allocated instead: %v", err)
+	}
+
+	clusterPolicy := map[string]string{
+		policyKeyLinkLocal: config.LinkLocalAccess,
+	}
+	for resType, limit := range config.NamespaceResourceLimits {
+		clusterPolicy[limitPolicyKeys[resType]] = strconv.Itoa(limit)
+	}
+	if len(config.PodSysctls) != 0 {
+		sysctls, err := json.Marshal(config.PodSysctls)
 		if err != nil {
-			return nil, errors.Wrapf(err, "error parse service cidr: %s", config.ServiceCIDR)
+			return nil, err
 		}
+		clusterPolicy[policyKeyPodSysctls] = string(sysctls)
 	}
-
-	netSrv.k8s, err = newK8S(k8sClient, ipnet, daemonMode)
+	if config.InterfaceTuning != nil {
+		tuning, err := json.Marshal(config.InterfaceTuning)
+		if err != nil {
+			return nil, err
+		}
+		clusterPolicy[policyKeyInterfaceTuning] = string(tuning)
+	}
+	netSrv.k8s, err = newK8S(k8sClient, ipnet, daemonMode, clusterPolicy)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error init k8s service")
 	}
+	http.DefaultServeMux.Handle("/debug/explain", explainHandler(netSrv.k8s))
 
 	netSrv.resourceDB, err = storage.NewDiskStorage(
 		resDBName, resDBPath, json.Marshal, func(bytes []byte) (interface{}, error) {
@@ -600,7 +1216,19 @@ func newNetworkService(configFilePath, kubeconfig, master, daemonMode string) (r
 	if err != nil {
 		return nil, errors.Wrapf(err, "error init resource manager storage")
 	}
-	localResource := make(map[string][]string)
+	if err = migrateResourceKeys(netSrv.resourceDB); err != nil {
+		return nil, errors.Wrapf(err, "error migrate keys of resource relation db")
+	}
+	delayedReleaseStore, err := newDelayedReleaseStorage()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init delayed release storage")
+	}
+	netSrv.delayedReleases, err = newDelayedReleases(time.Duration(config.ReleaseDelayMaxSeconds)*time.Second, delayedReleaseStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error load delayed releases")
+	}
+	// resources of delayed releases before restart kept inuse till released
+	localResource := netSrv.delayedReleases.heldResources()
 	resObjList, err := netSrv.resourceDB.List()
 	if err != nil {
 		return nil, errors.Wrapf(err, "error list resource relation db")
@@ -615,96 +1243,182 @@ func newNetworkService(configFilePath, kubeconfig, master, daemonMode string) (r
 		}
 	}
 
-	// get pool config
-	poolConfig, err := getPoolConfig(config, ecs)
+	poolConfig.ENITags = eniTags(config, netSrv.k8s.GetNodeName())
+	netSrv.slotReserve = newSlotReserve(config)
+	if config.ThrottleCoordination {
+		throttle := newThrottleCoordinator(crd.NewThrottleClient(k8sClient.Discovery().RESTClient()),
+			string(poolConfig.Region), netSrv.k8s.GetNodeName())
+		throttle.start()
+		poolConfig.Throttle = throttle.level
+	}
+
+	ipDenyListStore, err := newIPDenyListStorage()
 	if err != nil {
-		return nil, errors.Wrapf(err, "error get pool config")
+		return nil, errors.Wrapf(err, "error init ip deny list storage")
 	}
-	log.Infof("init pool config: %+v", poolConfig)
+	netSrv.ipDenyList, err = newIPDenyList(ipDenyListStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error load ip deny list")
+	}
+	poolConfig.DeniedResource = netSrv.ipDenyList.denied
 
-	switch daemonMode {
-	case daemonModeVPC:
-		//init ENI
-		netSrv.eniResMgr, err = newENIResourceManager(poolConfig, ecs, localResource[types.ResourceTypeENI])
-		if err != nil {
-			return nil, errors.Wrapf(err, "error init ENI resource manager")
-		}
+	netSrv.gcProtection, err = time.ParseDuration(config.GCProtectionWindow)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parse gc protection window")
+	}
+	netSrv.allocationBudget, err = time.ParseDuration(config.AllocationBudget)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parse allocation budget")
+	}
+	netSrv.mgrForResource, err = resourceManagerFactories[daemonMode](&ResourceManagerEnv{
+		Config:         config,
+		PoolConfig:     poolConfig,
+		ECS:            ecs,
+		LocalResources: localResource,
+		KubeClient:     k8sClient,
+		netSrv:         netSrv,
+	})
+	if err != nil {
+		return nil, err
+	}
+	netSrv.vethResMgr = netSrv.mgrForResource[types.ResourceTypeVeth]
+	netSrv.eniResMgr = netSrv.mgrForResource[types.ResourceTypeENI]
+	netSrv.eniIPResMgr = netSrv.mgrForResource[types.ResourceTypeENIIP]
 
-		netSrv.vethResMgr, err = newVPCResourceManager()
+	// rules of daemon and cni binary programmed in backend of kube-proxy, or shadowed by rules of the other
+	backend, err := netfilter.Setup(config.NetfilterBackend)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error setup netfilter backend")
+	}
+	log.Infof("netfilter backend of rules: %s", backend)
+
+	if daemonMode == daemonModeVPC || daemonMode == daemonModeENIOnly || daemonMode == daemonModeHybrid {
+		netSrv.hostPort, err = newHostPortManager()
 		if err != nil {
-			return nil, errors.Wrapf(err, "error init vpc resource manager")
+			return nil, errors.Wrapf(err, "error init host port manager")
 		}
+	}
 
-		netSrv.mgrForResource = map[string]ResourceManager{
-			types.ResourceTypeENI:  netSrv.eniResMgr,
-			types.ResourceTypeVeth: netSrv.vethResMgr,
-		}
+	if err = netSrv.setupServiceShortcut(config.ServiceLocalShortcut, k8sClient); err != nil {
+		return nil, err
+	}
+	if err = setupEgressNAT(config.EgressNAT); err != nil {
+		return nil, err
+	}
 
-	case daemonModeENIMultiIP:
-		//init ENI multi ip
-		netSrv.eniIPResMgr, err = newENIIPResourceManager(poolConfig, ecs, localResource[types.ResourceTypeENIIP])
-		if err != nil {
-			return nil, errors.Wrapf(err, "error init ENI ip resource manager")
-		}
-		netSrv.mgrForResource = map[string]ResourceManager{
-			types.ResourceTypeENIIP: netSrv.eniIPResMgr,
-		}
-	case daemonModeENIOnly:
-		//init eni
-		netSrv.eniResMgr, err = newENIResourceManager(poolConfig, ecs, localResource[types.ResourceTypeENI])
-		if err != nil {
-			return nil, errors.Wrapf(err, "error init eni resource manager")
-		}
-		netSrv.mgrForResource = map[string]ResourceManager{
-			types.ResourceTypeENI: netSrv.eniResMgr,
-		}
-	default:
-		panic("unsupported daemon mode" + daemonMode)
+	slowThreshold, err := time.ParseDuration(config.SlowAllocationThreshold)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parse slow allocation threshold")
 	}
+	eipStore, err := newEIPStorage()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init eip storage")
+	}
+	netSrv.eips = &eipManager{ecs: ecs, store: eipStore}
 
-	//start gc loop
-	netSrv.startGarbageCollectionLoop()
+	netSrv.allocTimings = newAllocTimings(slowThreshold)
+	netSrv.poolStats = newPoolStats(poolConfig.MinPoolSize, poolConfig.MaxPoolSize)
+	netSrv.recentErrors = &recentErrors{}
+	netSrv.events = newResourceEvents()
+	netSrv.allocFlights = newAllocFlights()
 
-	return netSrv, nil
-}
+	netSrv.conntrackCleanup = make(map[string]bool)
+	for _, podNetworkType := range config.ConntrackCleanup {
+		netSrv.conntrackCleanup[podNetworkType] = true
+	}
+	netSrv.startDelayedRelease()
 
-//setup default value
-func setDefault(cfg *types.Configure) error {
-	if cfg.EniCapRatio == 0 {
-		cfg.EniCapRatio = 1
+	// host side network of pods rebuilt before serving requests
+	if err = netSrv.restoreHostNetwork(); err != nil {
+		log.Warnf("error restore host network of pods: %v", err)
 	}
 
-	if cfg.HotPlug == "" {
-		cfg.HotPlug = "true"
+	//start gc loop
+	gcTimeout, err := time.ParseDuration(config.GCTimeout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parse gc timeout")
+	}
+	netSrv.gc = newGCRunner(gcTimeout)
+	netSrv.netnsLeaks = newNetnsLeakScanner(config.NetnsLeakCleanup)
+	netSrv.startGarbageCollectionLoop()
+	netSrv.startReleasePending()
+	netSrv.eips.start(netSrv.localPodKeys)
+	netSrv.startMirrorReconcile()
+	newKubeProxyChecker(daemonMode).start()
+	if config.CloudReconcileSeconds > 0 {
+		netSrv.startReconcile(time.Duration(config.CloudReconcileSeconds) * time.Second)
+	}
+	if config.ENIDefragSeconds > 0 {
+		netSrv.startDefrag(time.Duration(config.ENIDefragSeconds) * time.Second)
 	}
 
-	if cfg.HotPlug == conditionFalse || cfg.HotPlug == "0" {
-		cfg.HotPlug = conditionFalse
+	if config.PrewarmPendingPods {
+		netSrv.startPrewarm()
 	}
 
-	return nil
-}
+	if config.ExhaustionCondition != "" {
+		netSrv.exhaustion = &exhaustionMonitor{
+			client:        k8sClient,
+			nodeName:      netSrv.k8s.GetNodeName(),
+			conditionType: corev1.NodeConditionType(config.ExhaustionCondition),
+			getStats:      netSrv.poolsStats,
+		}
+		netSrv.exhaustion.start()
+	}
 
-func validateConfig(cfg *types.Configure) error {
-	return nil
+	//publish allocation state for controller
+	publisher := &statePublisher{
+		client:       crd.NewClient(k8sClient.Discovery().RESTClient()),
+		nodeName:     netSrv.k8s.GetNodeName(),
+		instanceID:   poolConfig.InstanceID,
+		daemonMode:   daemonMode,
+		getResources: netSrv.getResourceIDs,
+		getSummary:   netSrv.networkSummary,
+	}
+	publisher.start()
+
+	if poolConfig.ENITags != nil {
+		tagger := &eniTagger{
+			ecs:          ecs.WithPriority(aliyun.PriorityBackground),
+			instanceID:   poolConfig.InstanceID,
+			tags:         poolConfig.ENITags,
+			podNamespace: config.TagPodNamespace,
+			managedENIs:  netSrv.managedENIs,
+		}
+		tagger.start()
+	}
+
+	return netSrv, nil
 }
 
 func getPoolConfig(cfg *types.Configure, ecs aliyun.ECS) (*types.PoolConfig, error) {
 	poolConfig := &types.PoolConfig{
-		MaxPoolSize:   cfg.MaxPoolSize,
-		MinPoolSize:   cfg.MinPoolSize,
-		AccessID:      cfg.AccessID,
-		AccessSecret:  cfg.AccessSecret,
-		HotPlug:       cfg.HotPlug == "true",
-		EniCapRatio:   cfg.EniCapRatio,
-		EniCapShift:   cfg.EniCapShift,
-		SecurityGroup: cfg.SecurityGroup,
+		MaxPoolSize:            cfg.MaxPoolSize,
+		MinPoolSize:            cfg.MinPoolSize,
+		AccessID:               cfg.AccessID,
+		AccessSecret:           cfg.AccessSecret,
+		HotPlug:                cfg.HotPlug == "true",
+		EniCapRatio:            cfg.EniCapRatio,
+		EniCapShift:            cfg.EniCapShift,
+		SecurityGroup:          cfg.SecurityGroup,
+		VSwitchSelectionPolicy: cfg.VSwitchSelectionPolicy,
+		VSwitchAutoCreate:      cfg.VSwitchAutoCreate,
+		IPConflictDetection:    cfg.IPConflictDetection,
+		ReleasePolicy:          cfg.ReleasePolicy,
+		QuarantinePeriod:       time.Duration(cfg.QuarantineSeconds) * time.Second,
+		IPChunkSize:            cfg.IPChunkSize,
+		ENIMaxIPs:              cfg.ENIMaxIPs,
+		PrefixDelegation:       cfg.ENIPrefixDelegation,
+		DrainRate:              cfg.PoolDrainRate,
+		ParallelCreates:        cfg.PoolParallelCreates,
+		ReservedSlots:          cfg.ReservedSlots,
 	}
 
 	zone, err := aliyun.GetLocalZone()
 	if err != nil {
 		return nil, err
 	}
+	poolConfig.Zone = zone
 	if cfg.VSwitches != nil {
 		zoneVswitchs, ok := cfg.VSwitches[zone]
 		if ok && len(zoneVswitchs) > 0 {
@@ -718,6 +1432,7 @@ func getPoolConfig(cfg *types.Configure, ecs aliyun.ECS) (*types.PoolConfig, err
 		}
 		poolConfig.VSwitch = []string{vSwitch}
 	}
+	poolConfig.OverflowVSwitch = cfg.OverflowVSwitches[zone]
 
 	if poolConfig.Region, err = aliyun.GetLocalRegion(); err != nil {
 		return nil, err
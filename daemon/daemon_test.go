@@ -0,0 +1,65 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPodResourceRelations(t *testing.T) {
+	networkService := &networkService{resourceDB: storage.NewMemoryStorage()}
+	oldPod := &podInfo{Namespace: "default", Name: "web", UID: "uid-1"}
+	old := &PodResources{PodInfo: oldPod, SandboxID: "sandbox-1", AllocatedAt: 1,
+		Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: "eni-1.10.0.0.1"}}}
+	assert.NoError(t, networkService.putPodResource(&PodResources{}, old))
+
+	// pod recreated with same name, relation of old sandbox kept for its release
+	newPod := &podInfo{Namespace: "default", Name: "web", UID: "uid-2"}
+	recreated := &PodResources{PodInfo: newPod, SandboxID: "sandbox-2", AllocatedAt: 2,
+		Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: "eni-1.10.0.0.2"}}}
+	assert.NoError(t, networkService.putPodResource(old, recreated))
+	res, err := networkService.getPodResource(oldPod)
+	assert.NoError(t, err)
+	assert.Equal(t, "sandbox-1", res.SandboxID)
+	res, err = networkService.getPodResource(newPod)
+	assert.NoError(t, err)
+	assert.Equal(t, "sandbox-2", res.SandboxID)
+	res, err = networkService.getSandboxResource(newPod, "sandbox-1")
+	assert.NoError(t, err)
+	assert.Equal(t, *old, res)
+
+	// release of old sandbox leaves the new one
+	assert.NoError(t, networkService.deletePodResource(old))
+	res, err = networkService.getSandboxResource(oldPod, "sandbox-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "sandbox-2", res.SandboxID)
+	assert.False(t, res.ownedBy("sandbox-1"))
+
+	// relation of previous sandbox dropped once resources taken over
+	restarted := &PodResources{PodInfo: newPod, SandboxID: "sandbox-3", AllocatedAt: 3, Resources: recreated.Resources}
+	assert.NoError(t, networkService.putPodResource(recreated, restarted))
+	list, err := networkService.resourceDB.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{*restarted}, list)
+}
+
+func TestMigrateResourceKeys(t *testing.T) {
+	db := storage.NewMemoryStorage()
+	pod := &podInfo{Namespace: "default", Name: "web", UID: "uid-1"}
+	legacy := PodResources{PodInfo: pod, SandboxID: "sandbox-1"}
+	assert.NoError(t, db.Put(podInfoKey("default", "web"), legacy))
+	unknown := PodResources{PodInfo: &podInfo{Namespace: "default", Name: "db"}}
+	assert.NoError(t, db.Put(podInfoKey("default", "db"), unknown))
+
+	assert.NoError(t, migrateResourceKeys(db))
+	_, err := db.Get(podInfoKey("default", "web"))
+	assert.Equal(t, storage.ErrNotFound, err)
+	obj, err := db.Get("uid-1/sandbox-1")
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, obj)
+	obj, err = db.Get(podInfoKey("default", "db"))
+	assert.NoError(t, err)
+	assert.Equal(t, unknown, obj)
+}
@@ -0,0 +1,228 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/AliyunContainerService/terway/pkg/defaults"
+	"github.com/AliyunContainerService/terway/pkg/kernel"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/version"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	cniBinPath        = "/opt/cni/bin/terway"
+	cniConfSrcPath    = "/etc/eni/10-terway.conf"
+	cniConfPath       = "/etc/cni/net.d/10-terway.conf"
+	datapathStatePath = "/var/lib/cni/terway/datapath.json"
+
+	eniIPVirtualTypeIPVlan = "IPVlan"
+)
+
+// datapath the daemon mode and cni datapath config applied on node
+type datapath struct {
+	DaemonMode       string `json:"daemon_mode"`
+	ENIIPVirtualType string `json:"eniip_virtual_type"`
+}
+
+func (d *datapath) String() string {
+	if d.ENIIPVirtualType == "" {
+		return d.DaemonMode
+	}
+	return fmt.Sprintf("%s/%s", d.DaemonMode, d.ENIIPVirtualType)
+}
+
+// requiredCapabilities the cni binary capabilities required by datapath
+func (d *datapath) requiredCapabilities() []string {
+	switch d.DaemonMode {
+	case daemonModeENIMultiIP:
+		if d.ENIIPVirtualType == eniIPVirtualTypeIPVlan {
+			return []string{version.CapabilityIPVlan}
+		}
+		return []string{version.CapabilityVeth}
+	case daemonModeVPC:
+		return []string{version.CapabilityVeth, version.CapabilityRawNIC}
+	case daemonModeENIOnly:
+		return []string{version.CapabilityRawNIC}
+	case daemonModeHybrid:
+		if d.ENIIPVirtualType == eniIPVirtualTypeIPVlan {
+			return []string{version.CapabilityIPVlan, version.CapabilityRawNIC}
+		}
+		return []string{version.CapabilityVeth, version.CapabilityRawNIC}
+	case daemonModeDual:
+		return []string{version.CapabilityVeth}
+	}
+	return nil
+}
+
+// checkCompatible check datapath supported by cni binary and kernel of node
+func (d *datapath) checkCompatible(capabilities []string) error {
+	// pods with vpc ip routed through host by veth only
+	if d.DaemonMode == daemonModeDual && d.ENIIPVirtualType == eniIPVirtualTypeIPVlan {
+		return errors.Errorf("%s datapath not supported, eni ips of %s mode shared by veth only", d, daemonModeDual)
+	}
+	supported := make(map[string]bool)
+	for _, c := range capabilities {
+		supported[c] = true
+	}
+	for _, required := range d.requiredCapabilities() {
+		if !supported[required] {
+			return errors.Errorf("cni binary %s not support %s, capabilities: %v", cniBinPath, required, capabilities)
+		}
+	}
+
+	if required := d.requiredKernel(); required != nil {
+		if err := kernel.CheckVersion(required.Major, required.Minor); err != nil {
+			return errors.Wrapf(err, "kernel not support %s datapath", d)
+		}
+	}
+	return nil
+}
+
+// requiredKernel the minimal kernel version required by datapath, nil if no requirement
+func (d *datapath) requiredKernel() *kernel.Version {
+	if (d.DaemonMode == daemonModeENIMultiIP || d.DaemonMode == daemonModeHybrid) && d.ENIIPVirtualType == eniIPVirtualTypeIPVlan {
+		return &kernel.Version{Major: defaults.IPVlanKernelMajor, Minor: defaults.IPVlanKernelMinor}
+	}
+	return nil
+}
+
+// CheckDatapathKernel check kernel release of node supports the datapath, used by upgrade preflight
+func CheckDatapathKernel(daemonMode, eniIPVirtualType, release string) error {
+	d := &datapath{DaemonMode: daemonMode, ENIIPVirtualType: eniIPVirtualType}
+	required := d.requiredKernel()
+	if required == nil {
+		return nil
+	}
+	ver, err := kernel.ParseVersion(release)
+	if err != nil {
+		return err
+	}
+	if ver.LessThan(*required) {
+		return errors.Errorf("kernel version %s older than %d.%d required by %s datapath", release, required.Major, required.Minor, d)
+	}
+	return nil
+}
+
+// getCNIPluginInfo get spec versions and datapath capabilities of the cni binary by CNI VERSION command
+func getCNIPluginInfo(binPath string) (*version.PluginInfo, error) {
+	cmd := exec.Command(binPath)
+	cmd.Env = []string{"CNI_COMMAND=VERSION"}
+	cmd.Stdin = bytes.NewBuffer(nil)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error get version of cni binary %s", binPath)
+	}
+	info := &version.PluginInfo{}
+	if err = json.Unmarshal(out, info); err != nil {
+		return nil, errors.Wrapf(err, "error parse version of cni binary %s: %s", binPath, string(out))
+	}
+	// binary before capabilities report
+	if len(info.Capabilities) == 0 {
+		info.Capabilities = version.LegacyCapabilities
+	}
+	return info, nil
+}
+
+func loadDatapath(path string) (*datapath, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dp := &datapath{}
+	if err = json.Unmarshal(data, dp); err != nil {
+		return nil, errors.Wrapf(err, "error parse datapath state %s", path)
+	}
+	return dp, nil
+}
+
+// writeFileAtomic write file by rename temp file to avoid partially written file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ensureDatapath check requested datapath compatible with cni binary and kernel, then
+// install cni config and record it. when incompatible, keep previous datapath active
+// and return its daemon mode.
+func ensureDatapath(daemonMode string, cniConfig *types.CNIConf) (string, error) {
+	requested := &datapath{DaemonMode: daemonMode}
+
+	cniConf, err := ioutil.ReadFile(cniConfSrcPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, "error read cni config %s", cniConfSrcPath)
+	}
+	if err == nil {
+		if err = json.Unmarshal(cniConf, requested); err != nil {
+			return "", errors.Wrapf(err, "error parse cni config %s", cniConfSrcPath)
+		}
+		requested.DaemonMode = daemonMode
+	}
+
+	info, err := getCNIPluginInfo(cniBinPath)
+	if err != nil {
+		log.Warnf("error get capabilities of cni binary, assume legacy: %v", err)
+		info = &version.PluginInfo{Capabilities: version.LegacyCapabilities}
+	}
+	capabilities := info.Capabilities
+
+	if err = requested.checkCompatible(capabilities); err != nil {
+		previous, loadErr := loadDatapath(datapathStatePath)
+		if loadErr != nil {
+			return "", errors.Wrapf(err, "refuse to enable datapath %s, and no previous datapath to keep", requested)
+		}
+		if prevErr := previous.checkCompatible(capabilities); prevErr != nil {
+			return "", errors.Wrapf(err, "refuse to enable datapath %s, previous datapath %s also incompatible: %v", requested, previous, prevErr)
+		}
+		log.Errorf("refuse to switch datapath from %s to %s, keep previous datapath active: %v", previous, requested, err)
+		metric.DatapathIncompatible.WithLabelValues(requested.String(), previous.String()).Set(1)
+		return previous.DaemonMode, nil
+	}
+
+	if cniConfig != nil {
+		if err = setupCNIConfList(cniConf, cniConfig, info.SupportedVersions); err != nil {
+			return "", err
+		}
+	} else if cniConf != nil {
+		if err = writeFileAtomic(cniConfPath, cniConf, 0644); err != nil {
+			return "", errors.Wrapf(err, "error install cni config %s", cniConfPath)
+		}
+		if err = os.Remove(cniConfListPath); err != nil && !os.IsNotExist(err) {
+			log.Warnf("error remove cni config list %s: %v", cniConfListPath, err)
+		}
+	}
+	data, err := json.Marshal(requested)
+	if err != nil {
+		return "", err
+	}
+	if err = writeFileAtomic(datapathStatePath, data, 0600); err != nil {
+		return "", errors.Wrapf(err, "error record datapath state %s", datapathStatePath)
+	}
+	log.Infof("datapath %s enabled, cni capabilities: %v", requested, capabilities)
+	return daemonMode, nil
+}
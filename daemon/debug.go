@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	log "github.com/sirupsen/logrus"
+)
+
+// poolState state of resource pool in debug dump
+type poolState struct {
+	Stats     pool.Stats `json:"stats"`
+	Resources []string   `json:"resources"`
+}
+
+// registerPprof register pprof handlers on debug server
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
+
+// poolStates return state of resource pools by resource type
+func (networkService *networkService) poolStates() map[string]poolState {
+	networkService.RLock()
+	defer networkService.RUnlock()
+	states := make(map[string]poolState, len(networkService.mgrForResource))
+	for resType, mgr := range networkService.mgrForResource {
+		resources := mgr.GetResourceIDs()
+		sort.Strings(resources)
+		states[resType] = poolState{Stats: mgr.Stats(), Resources: resources}
+	}
+	return states
+}
+
+// errDebugActionsDisabled error of debug endpoints changing state of daemon requested without enabled
+const errDebugActionsDisabled = "debug actions not enabled, restart terway daemon with --enable-debug-actions"
+
+// dumpHandler debug handler collect goroutines, heap, pools and recent openapi calls into tarball: /debug/dump
+func dumpHandler(networkService *networkService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=terway-dump-%s.tar.gz", time.Now().Format("20060102150405")))
+		if err := writeDump(w, networkService); err != nil {
+			log.Errorf("error write debug dump: %v", err)
+		}
+	})
+}
+
+func writeDump(w io.Writer, networkService *networkService) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	now := time.Now()
+	addFile := func(name string, write func(buf *bytes.Buffer) error) error {
+		buf := &bytes.Buffer{}
+		if err := write(buf); err != nil {
+			return fmt.Errorf("error collect %s: %v", name, err)
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(buf.Len()), ModTime: now}); err != nil {
+			return err
+		}
+		_, err := tw.Write(buf.Bytes())
+		return err
+	}
+
+	if err := addFile("goroutines.txt", func(buf *bytes.Buffer) error {
+		return runtimepprof.Lookup("goroutine").WriteTo(buf, 2)
+	}); err != nil {
+		return err
+	}
+	if err := addFile("heap.pprof", func(buf *bytes.Buffer) error {
+		return runtimepprof.Lookup("heap").WriteTo(buf, 0)
+	}); err != nil {
+		return err
+	}
+	if err := addFile("pools.json", func(buf *bytes.Buffer) error {
+		return json.NewEncoder(buf).Encode(networkService.poolStates())
+	}); err != nil {
+		return err
+	}
+	if eniResMgr, ok := networkService.eniResMgr.(*eniResourceManager); ok {
+		if err := addFile("devices.json", func(buf *bytes.Buffer) error {
+			return json.NewEncoder(buf).Encode(eniResMgr.deviceBindings())
+		}); err != nil {
+			return err
+		}
+	}
+	if err := addFile("openapi.json", func(buf *bytes.Buffer) error {
+		return json.NewEncoder(buf).Encode(aliyun.RecentCalls())
+	}); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
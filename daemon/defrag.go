@@ -0,0 +1,254 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defragMinIdle ips idle shorter are not moved, eg: released by pod restarting
+	defragMinIdle = 5 * time.Minute
+	// defragMaxENIs enis freed by one round of periodic defragmentation at most
+	defragMaxENIs = 1
+)
+
+// defragENI eni of eni ip pool as candidate of defragmentation
+type defragENI struct {
+	id string
+	// ips of eni in pool
+	ips []types.NetworkResource
+	// movable ips of eni all idle long enough, not pinned, none carved from prefix or allocating
+	movable bool
+	// room ips could be assigned to eni
+	room int
+}
+
+// DefragMove idle ips of eni moved to other enis to free the eni
+type DefragMove struct {
+	ENI   string `json:"eni"`
+	IPs   int    `json:"ips"`
+	Error string `json:"error,omitempty"`
+}
+
+// DefragReport enis planned or freed by defragmentation
+type DefragReport struct {
+	Moves   []DefragMove `json:"moves"`
+	Applied bool         `json:"applied"`
+}
+
+// planDefrag choose enis of fewest ips to free, at most max, as long as the enis left have room for their ips
+func planDefrag(enis []*defragENI, max int) []*defragENI {
+	var (
+		candidates []*defragENI
+		room       int
+	)
+	for _, eni := range enis {
+		room += eni.room
+		if eni.movable && len(eni.ips) > 0 {
+			candidates = append(candidates, eni)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].ips) < len(candidates[j].ips)
+	})
+	var plan []*defragENI
+	for _, eni := range candidates {
+		if len(plan) >= max {
+			break
+		}
+		// room of eni freed no more usable
+		if room-eni.room < len(eni.ips) {
+			continue
+		}
+		room -= eni.room + len(eni.ips)
+		plan = append(plan, eni)
+	}
+	return plan
+}
+
+// defragENIs enis of factory with their ips in pool, ips of enis not in pool, eg: inuse by pods, skipped
+func (f *eniIPFactory) defragENIs(inventory []pool.InventoryItem, now time.Time) []*defragENI {
+	items := make(map[string][]pool.InventoryItem)
+	for _, item := range inventory {
+		ip, ok := item.Resource.(*types.ENIIP)
+		if !ok {
+			continue
+		}
+		items[ip.Eni.ID] = append(items[ip.Eni.ID], item)
+	}
+
+	f.RLock()
+	defer f.RUnlock()
+	var enis []*defragENI
+	for _, eni := range f.enis {
+		eni.lock.Lock()
+		candidate := &defragENI{
+			id:      eni.ID,
+			movable: !eni.detached && eni.pending == 0 && len(eni.carved) == 0 && len(items[eni.ID]) == len(eni.ips),
+		}
+		if !eni.detached && f.eniHealth.healthy(eni.MAC) {
+			candidate.room = eni.roomLocked()
+		}
+		eni.lock.Unlock()
+		for _, item := range items[eni.ID] {
+			candidate.ips = append(candidate.ips, item.Resource)
+			last := item.LastUsed
+			if last.IsZero() {
+				last = item.Created
+			}
+			if item.State != pool.StateIdle || item.Pinned || now.Sub(last) < defragMinIdle {
+				candidate.movable = false
+			}
+		}
+		enis = append(enis, candidate)
+	}
+	return enis
+}
+
+// assignOnExisting assign count ips on enis with room, never creating eni
+func (f *eniIPFactory) assignOnExisting(count int) ([]types.NetworkResource, error) {
+	var resources []types.NetworkResource
+	for len(resources) < count {
+		submitted, err := f.submit(count-len(resources), aliyun.PriorityBackground)
+		if err != nil {
+			return resources, err
+		}
+		for i := 0; i < submitted; i++ {
+			ip, err := f.popResult()
+			if err != nil {
+				return resources, err
+			}
+			resources = append(resources, ip)
+		}
+	}
+	return resources, nil
+}
+
+// moveIPs take idle ips of eni out of pool and unassign them, eni released with its primary ip, then assign
+// the same count of ips on other enis into pool. ips of eni taken by pods meanwhile abort the move
+func (m *eniIPResourceManager) moveIPs(eni *defragENI) error {
+	var evicted []types.NetworkResource
+	for _, ip := range eni.ips {
+		if err := m.pool.Evict(ip.GetResourceID()); err != nil {
+			m.adopt(evicted)
+			return errors.Wrapf(err, "ip %s of eni %s no more idle", ip.GetResourceID(), eni.id)
+		}
+		evicted = append(evicted, ip)
+	}
+	failed, err := m.factory.DisposeBatch(evicted)
+	if err != nil {
+		m.adopt(failed)
+		return errors.Wrapf(err, "error free eni %s, %d ips kept", eni.id, len(failed))
+	}
+
+	created, err := m.factory.assignOnExisting(len(evicted))
+	m.adopt(created)
+	if err != nil {
+		return errors.Wrapf(err, "eni %s freed, %d of %d ips assigned on other enis", eni.id, len(created), len(evicted))
+	}
+	return nil
+}
+
+// adopt add resources back to pool, disposed if pool full
+func (m *eniIPResourceManager) adopt(resources []types.NetworkResource) {
+	for _, res := range resources {
+		if err := m.pool.Adopt(res); err != nil {
+			log.Warnf("error add %s back to pool, dispose it: %v", res.GetResourceID(), err)
+			if err = m.factory.Dispose(res); err != nil {
+				log.Errorf("error dispose %s: %v", res.GetResourceID(), err)
+			}
+		}
+	}
+}
+
+// defrag free at most max enis of only idle ips by moving the ips to other enis with room, eg: for exclusive
+// eni pods or detaching enis, plan only if not apply
+func (m *eniIPResourceManager) defrag(max int, apply bool) *DefragReport {
+	enis := m.factory.defragENIs(m.pool.Inventory(), time.Now())
+	report := &DefragReport{Applied: apply}
+	for _, eni := range planDefrag(enis, max) {
+		move := DefragMove{ENI: eni.id, IPs: len(eni.ips)}
+		if apply {
+			if err := m.moveIPs(eni); err != nil {
+				log.Warnf("error defrag eni: %v", err)
+				move.Error = err.Error()
+			} else {
+				log.Infof("%d idle ips of eni %s moved to other enis, eni freed", len(eni.ips), eni.id)
+				metric.ENIDefragFreed.Inc()
+			}
+		}
+		report.Moves = append(report.Moves, move)
+	}
+	return report
+}
+
+// defragmenter resource managers able to move idle resources off enis
+type defragmenter interface {
+	defrag(max int, apply bool) *DefragReport
+}
+
+// defrag defragment pools of network service, drain and gc excluded
+func (networkService *networkService) defrag(max int, apply bool) *DefragReport {
+	networkService.RLock()
+	defer networkService.RUnlock()
+	report := &DefragReport{Applied: apply}
+	for _, mgr := range networkService.mgrForResource {
+		if d, ok := mgr.(defragmenter); ok {
+			report.Moves = append(report.Moves, d.defrag(max, apply).Moves...)
+		}
+	}
+	return report
+}
+
+// startDefrag free enis of only idle ips in period
+func (networkService *networkService) startDefrag(period time.Duration) {
+	go func() {
+		for {
+			time.Sleep(period)
+			networkService.defrag(defragMaxENIs, true)
+		}
+	}()
+}
+
+// defragHandler debug handler of defragmentation: GET /debug/defrag to plan, POST /debug/defrag to apply if allowApply,
+// max_enis of query enis freed at most, 1 by default
+func defragHandler(networkService *networkService, allowApply bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var apply bool
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if !allowApply {
+				http.Error(w, errDebugActionsDisabled, http.StatusForbidden)
+				return
+			}
+			apply = true
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		max := defragMaxENIs
+		if value := r.URL.Query().Get("max_enis"); value != "" {
+			var err error
+			if max, err = strconv.Atoi(value); err != nil || max <= 0 {
+				http.Error(w, fmt.Sprintf("invalid max_enis %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(networkService.defrag(max, apply)); err != nil {
+			log.Errorf("error write defrag report: %v", err)
+		}
+	})
+}
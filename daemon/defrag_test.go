@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanDefrag(t *testing.T) {
+	ips := func(n int) []types.NetworkResource {
+		return make([]types.NetworkResource, n)
+	}
+	enis := []*defragENI{
+		{id: "eni-full", ips: ips(8), room: 2},
+		{id: "eni-3", ips: ips(3), movable: true, room: 7},
+		{id: "eni-1", ips: ips(1), movable: true, room: 9},
+		{id: "eni-inuse", ips: ips(2), room: 1},
+	}
+	plan := planDefrag(enis, 1)
+	assert.Len(t, plan, 1)
+	assert.Equal(t, "eni-1", plan[0].id)
+
+	// room of eni-3 left for ips of eni-1 only
+	plan = planDefrag(enis, 2)
+	assert.Len(t, plan, 1)
+
+	enis[3].room = 4
+	plan = planDefrag(enis, 2)
+	assert.Len(t, plan, 2)
+	assert.Equal(t, "eni-3", plan[1].id)
+
+	// no room on other enis
+	assert.Empty(t, planDefrag([]*defragENI{{id: "eni-1", ips: ips(2), movable: true, room: 8}}, 1))
+}
+
+func TestDefragENIs(t *testing.T) {
+	now := time.Now()
+	newENI := func(id string, addresses ...string) *ENI {
+		eni := &ENI{ENI: &types.ENI{ID: id, MAC: id, MaxIPs: 10}, carved: make(map[string]bool)}
+		for _, address := range addresses {
+			eni.ips = append(eni.ips, &ENIIP{ENIIP: &types.ENIIP{Eni: eni.ENI, SecAddress: net.ParseIP(address)}})
+		}
+		return eni
+	}
+	f := &eniIPFactory{enis: []*ENI{
+		newENI("eni-idle", "10.0.0.1", "10.0.0.2"),
+		newENI("eni-inuse", "10.0.1.1", "10.0.1.2"),
+		newENI("eni-fresh", "10.0.2.1"),
+	}}
+	var inventory []pool.InventoryItem
+	for _, eni := range f.enis {
+		for _, ip := range eni.ips {
+			item := pool.InventoryItem{ID: ip.GetResourceID(), State: pool.StateIdle, Created: now.Add(-time.Hour), Resource: ip.ENIIP}
+			if ip.SecAddress.String() == "10.0.1.2" {
+				item.State = pool.StateInuse
+			}
+			if eni.ID == "eni-fresh" {
+				item.LastUsed = now.Add(-time.Minute)
+			}
+			inventory = append(inventory, item)
+		}
+	}
+
+	enis := f.defragENIs(inventory, now)
+	assert.Len(t, enis, 3)
+	assert.True(t, enis[0].movable)
+	assert.Len(t, enis[0].ips, 2)
+	assert.Equal(t, 8, enis[0].room)
+	assert.False(t, enis[1].movable)
+	assert.False(t, enis[2].movable)
+}
@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const (
+	// delayedReleaseCheckPeriod period of checking delayed releases ready
+	delayedReleaseCheckPeriod = 5 * time.Second
+
+	delayedReleaseDBPath = "/var/lib/cni/terway/DelayedRelease.db"
+	delayedReleaseDBName = "delayed_release"
+)
+
+// delayedRelease resources of pod held inuse until termination grace period of pod elapsed and conntrack
+// entries of its ips drained, so peers of long-lived connections are not reset by other pods taking the ips
+type delayedRelease struct {
+	Pod       *podInfo       `json:"pod"`
+	SandboxID string         `json:"sandbox_id"`
+	Resources []ResourceItem `json:"resources"`
+	// Res relation of pod released, for ips of it
+	Res PodResources `json:"res"`
+	// GraceDeadline time termination grace period elapses
+	GraceDeadline time.Time `json:"grace_deadline"`
+	// Deadline released regardless of conntrack entries
+	Deadline time.Time `json:"deadline"`
+}
+
+// delayedReleases releases of pods delayed, by pod key, persisted across restarts of daemon so the resources
+// held are kept inuse in pools till released
+type delayedReleases struct {
+	// max delay of releases, releases not delayed if zero
+	max time.Duration
+	// countConntrack count conntrack entries of ips by ip
+	countConntrack func(ips []net.IP) (map[string]int, error)
+	store          storage.Storage
+
+	lock    sync.Mutex
+	pending map[string]*delayedRelease
+}
+
+func newDelayedReleaseStorage() (storage.Storage, error) {
+	return storage.NewDiskStorage(delayedReleaseDBName, delayedReleaseDBPath, json.Marshal, func(bytes []byte) (interface{}, error) {
+		release := delayedRelease{}
+		if err := json.Unmarshal(bytes, &release); err != nil {
+			return nil, errors.Wrapf(err, "error unmarshal delayed release")
+		}
+		return release, nil
+	})
+}
+
+// newDelayedReleases return delayed releases with the pending ones of store, which are released at their
+// deadlines even if delay disabled since
+func newDelayedReleases(max time.Duration, store storage.Storage) (*delayedReleases, error) {
+	d := &delayedReleases{max: max, countConntrack: countConntrack, store: store, pending: make(map[string]*delayedRelease)}
+	records, err := store.List()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list delayed releases")
+	}
+	for _, obj := range records {
+		release := obj.(delayedRelease)
+		if release.Pod == nil {
+			continue
+		}
+		d.pending[delayedReleaseKey(release.Pod, release.SandboxID)] = &release
+	}
+	return d, nil
+}
+
+func delayedReleaseKey(pod *podInfo, sandboxID string) string {
+	return podInfoKey(pod.Namespace, pod.Name) + "/" + sandboxID
+}
+
+// heldResources resources held by pending releases by resource type, inuse in pools on start of daemon
+func (d *delayedReleases) heldResources() map[string][]string {
+	held := make(map[string][]string)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, release := range d.pending {
+		for _, res := range release.Resources {
+			held[res.Type] = append(held[res.Type], res.ID)
+		}
+	}
+	return held
+}
+
+// delayReleasable resource types held by delayed release, ips of pods taken from cloud
+func delayReleasable(resType string) bool {
+	return resType == types.ResourceTypeENIIP || resType == types.ResourceTypeENI
+}
+
+// shouldDelay whether release of pod delayed, pods with sticky ip take the ip back and never delayed
+func (d *delayedReleases) shouldDelay(pod *podInfo) bool {
+	return d != nil && d.max > 0 && pod.IPStickTime == 0
+}
+
+// add delay release of resources of pod, persisted before the relation of pod deleted
+func (d *delayedReleases) add(pod *podInfo, sandboxID string, res PodResources, resources []ResourceItem, now time.Time) error {
+	release := &delayedRelease{
+		Pod:           pod,
+		SandboxID:     sandboxID,
+		Resources:     resources,
+		Res:           res,
+		GraceDeadline: now,
+		Deadline:      now.Add(d.max),
+	}
+	if pod.DeletionDeadline.After(now) {
+		release.GraceDeadline = pod.DeletionDeadline
+	}
+	key := delayedReleaseKey(pod, sandboxID)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if err := d.store.Put(key, *release); err != nil {
+		return errors.Wrapf(err, "error persist delayed release")
+	}
+	d.pending[key] = release
+	return nil
+}
+
+// draining whether release waiting for conntrack entries of ips of pod drained at now
+func (release *delayedRelease) draining(now time.Time) bool {
+	return now.Before(release.Deadline) && !now.Before(release.GraceDeadline)
+}
+
+// ready whether delayed release ready at now, conntrack entries of ips of pod checked after grace period by
+// counts of entries by ip, ips not counted are taken as not drained
+func (release *delayedRelease) ready(now time.Time, counts map[string]int) bool {
+	if !now.Before(release.Deadline) {
+		return true
+	}
+	if now.Before(release.GraceDeadline) {
+		return false
+	}
+	for _, ip := range releasedPodIPs(release.Res, release.Pod) {
+		if count, ok := counts[ip.String()]; !ok || count > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// take remove releases ready at now, or all if all. conntrack entries of ips of all releases draining are
+// counted in one dump out of lock. releases failed to remove from store are kept till next take
+func (d *delayedReleases) take(now time.Time, all bool) []*delayedRelease {
+	if d == nil {
+		return nil
+	}
+	var counts map[string]int
+	if !all {
+		var ips []net.IP
+		d.lock.Lock()
+		for _, release := range d.pending {
+			if release.draining(now) {
+				ips = append(ips, releasedPodIPs(release.Res, release.Pod)...)
+			}
+		}
+		d.lock.Unlock()
+		if len(ips) > 0 {
+			var err error
+			if counts, err = d.countConntrack(ips); err != nil {
+				log.Warnf("error count conntrack entries of %d ips, wait till delay deadline: %v", len(ips), err)
+			}
+		}
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var ret []*delayedRelease
+	for key, release := range d.pending {
+		if all || release.ready(now, counts) {
+			if err := d.store.Delete(key); err != nil {
+				log.Warnf("error delete delayed release %s from db: %v", key, err)
+				continue
+			}
+			ret = append(ret, release)
+			delete(d.pending, key)
+		}
+	}
+	return ret
+}
+
+// releaseDelayed release resources of delayed releases to pools
+func (networkService *networkService) releaseDelayed(releases []*delayedRelease) {
+	for _, release := range releases {
+		networkContext := &networkContext{
+			Context:    context.Background(),
+			resources:  release.Resources,
+			pod:        release.Pod,
+			k8sService: networkService.k8s,
+			sandboxID:  release.SandboxID,
+		}
+		networkService.flushPodConntrack(networkContext, release.Res, release.Pod)
+		for _, res := range release.Resources {
+			mgr := networkService.getResourceManagerForRes(res.Type)
+			if mgr == nil {
+				continue
+			}
+			if err := mgr.Release(networkContext, res.ID); err != nil && err != pool.ErrInvalidState {
+				networkContext.Log().Errorf("error release %s %s delayed: %v", res.Type, res.ID, err)
+			}
+		}
+		networkContext.Log().Infof("delayed release of %d resources done", len(release.Resources))
+	}
+}
+
+// startDelayedRelease release delayed releases once ready
+func (networkService *networkService) startDelayedRelease() {
+	go func() {
+		for {
+			time.Sleep(delayedReleaseCheckPeriod)
+			networkService.RLock()
+			networkService.releaseDelayed(networkService.delayedReleases.take(time.Now(), false))
+			networkService.RUnlock()
+		}
+	}()
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelayedReleases(t *testing.T) {
+	now := time.Now()
+	entries := map[string]int{"10.0.0.1": 3}
+	store := storage.NewMemoryStorage()
+	d, err := newDelayedReleases(time.Hour, store)
+	assert.NoError(t, err)
+	dumps := 0
+	d.countConntrack = func(ips []net.IP) (map[string]int, error) {
+		dumps++
+		counts := make(map[string]int)
+		for _, ip := range ips {
+			counts[ip.String()] = entries[ip.String()]
+		}
+		return counts, nil
+	}
+	assert.True(t, d.shouldDelay(&podInfo{}))
+	assert.False(t, d.shouldDelay(&podInfo{IPStickTime: time.Minute}))
+	assert.False(t, (*delayedReleases)(nil).shouldDelay(&podInfo{}))
+	assert.False(t, (&delayedReleases{}).shouldDelay(&podInfo{}))
+	assert.True(t, delayReleasable(types.ResourceTypeENIIP))
+	assert.False(t, delayReleasable(types.ResourceTypeVeth))
+
+	pod := &podInfo{Namespace: "default", Name: "web", DeletionDeadline: now.Add(30 * time.Second)}
+	res := PodResources{Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: "00:16:3e:00:00:01.10.0.0.1"}}}
+	assert.NoError(t, d.add(pod, "sandbox", res, res.Resources, now))
+
+	// pending releases restored with their deadlines and resources held
+	restored, err := newDelayedReleases(0, store)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{types.ResourceTypeENIIP: {"00:16:3e:00:00:01.10.0.0.1"}}, restored.heldResources())
+	assert.False(t, restored.shouldDelay(&podInfo{}))
+	restored.countConntrack = d.countConntrack
+	assert.Empty(t, restored.take(now.Add(10*time.Second), false))
+	assert.Len(t, restored.take(now.Add(time.Hour), false), 1)
+	dumps = 0
+
+	// in grace period
+	assert.Empty(t, d.take(now.Add(10*time.Second), false))
+	assert.Equal(t, 0, dumps)
+	// conntrack entries not drained
+	assert.Empty(t, d.take(now.Add(time.Minute), false))
+	entries["10.0.0.1"] = 0
+	released := d.take(now.Add(time.Minute), false)
+	assert.Len(t, released, 1)
+	assert.Equal(t, "sandbox", released[0].SandboxID)
+	// removed from store once taken
+	records, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	// released at max delay regardless of conntrack, ips of all releases counted in one dump
+	entries["10.0.0.1"] = 3
+	assert.NoError(t, d.add(&podInfo{Namespace: "default", Name: "web"}, "sandbox", res, res.Resources, now))
+	other := PodResources{Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: "00:16:3e:00:00:01.10.0.0.2"}}}
+	assert.NoError(t, d.add(&podInfo{Namespace: "default", Name: "web-1"}, "sandbox", other, other.Resources, now))
+	dumps = 0
+	released = d.take(now.Add(time.Minute), false)
+	assert.Equal(t, 1, dumps)
+	assert.Len(t, released, 1)
+	assert.Equal(t, "web-1", released[0].Pod.Name)
+	assert.Len(t, d.take(now.Add(time.Hour), false), 1)
+
+	assert.NoError(t, d.add(pod, "sandbox", res, res.Resources, now))
+	assert.Len(t, d.take(now, true), 1)
+	assert.Nil(t, (*delayedReleases)(nil).take(now, true))
+}
@@ -0,0 +1,155 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ipDenyListDBPath = "/var/lib/cni/terway/IPDenyList.db"
+	ipDenyListDBName = "ip_deny_list"
+)
+
+// deniedIP ip must not be allocated to pods, eg: blocked by security incident
+type deniedIP struct {
+	IP       string    `json:"ip"`
+	Reason   string    `json:"reason"`
+	DeniedAt time.Time `json:"denied_at"`
+}
+
+// ipDenyList ips denied to allocate, persisted across restarts of daemon
+type ipDenyList struct {
+	lock  sync.RWMutex
+	store storage.Storage
+	ips   map[string]deniedIP
+}
+
+func newIPDenyListStorage() (storage.Storage, error) {
+	return storage.NewDiskStorage(ipDenyListDBName, ipDenyListDBPath, json.Marshal, func(bytes []byte) (interface{}, error) {
+		record := deniedIP{}
+		if err := json.Unmarshal(bytes, &record); err != nil {
+			return nil, errors.Wrapf(err, "error unmarshal denied ip")
+		}
+		return record, nil
+	})
+}
+
+func newIPDenyList(store storage.Storage) (*ipDenyList, error) {
+	l := &ipDenyList{
+		store: store,
+		ips:   make(map[string]deniedIP),
+	}
+	records, err := store.List()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list ip deny list")
+	}
+	for _, obj := range records {
+		record := obj.(deniedIP)
+		l.ips[record.IP] = record
+	}
+	return l, nil
+}
+
+// normalizeIP return ip in canonical form
+func normalizeIP(ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", fmt.Errorf("invalid ip: %q", ip)
+	}
+	return parsed.String(), nil
+}
+
+// denied return whether ip of resource denied to allocate
+func (l *ipDenyList) denied(res types.NetworkResource) bool {
+	if l == nil {
+		return false
+	}
+	var ip net.IP
+	switch r := res.(type) {
+	case *types.ENIIP:
+		ip = r.SecAddress
+	case *types.ENI:
+		ip = r.Address.IP
+	}
+	if ip == nil {
+		return false
+	}
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	_, ok := l.ips[ip.String()]
+	return ok
+}
+
+// update deny ips with reason and allow ips denied before
+func (l *ipDenyList) update(deny, allow []string, reason string) error {
+	if l == nil {
+		return fmt.Errorf("ip deny list not enabled")
+	}
+	var denyIPs, allowIPs []string
+	for _, ip := range deny {
+		normalized, err := normalizeIP(ip)
+		if err != nil {
+			return err
+		}
+		denyIPs = append(denyIPs, normalized)
+	}
+	for _, ip := range allow {
+		normalized, err := normalizeIP(ip)
+		if err != nil {
+			return err
+		}
+		allowIPs = append(allowIPs, normalized)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now().Truncate(time.Second)
+	for _, ip := range denyIPs {
+		if _, ok := l.ips[ip]; ok {
+			continue
+		}
+		record := deniedIP{IP: ip, Reason: reason, DeniedAt: now}
+		if err := l.store.Put(ip, record); err != nil {
+			return errors.Wrapf(err, "error persist denied ip %s", ip)
+		}
+		l.ips[ip] = record
+		log.Infof("deny ip %s to allocate: %s", ip, reason)
+	}
+	for _, ip := range allowIPs {
+		if _, ok := l.ips[ip]; !ok {
+			continue
+		}
+		if err := l.store.Delete(ip); err != nil {
+			return errors.Wrapf(err, "error delete denied ip %s", ip)
+		}
+		delete(l.ips, ip)
+		log.Infof("allow ip %s to allocate", ip)
+	}
+	return nil
+}
+
+// list denied ips sorted by ip
+func (l *ipDenyList) list() []deniedIP {
+	if l == nil {
+		return nil
+	}
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	ips := make([]deniedIP, 0, len(l.ips))
+	for _, record := range l.ips {
+		ips = append(ips, record)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		return ips[i].IP < ips[j].IP
+	})
+	return ips
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPDenyList(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	l, err := newIPDenyList(store)
+	assert.NoError(t, err)
+
+	eni := &types.ENI{MAC: "mac-1", Address: net.IPNet{IP: net.ParseIP("192.168.0.10")}}
+	eniIP := &types.ENIIP{Eni: eni, SecAddress: net.ParseIP("192.168.0.11")}
+	assert.False(t, l.denied(eniIP))
+
+	assert.Error(t, l.update([]string{"192.168.0.x"}, nil, ""))
+	assert.NoError(t, l.update([]string{"192.168.0.11", "192.168.0.10"}, nil, "incident-1"))
+	assert.True(t, l.denied(eniIP))
+	assert.True(t, l.denied(eni))
+	assert.False(t, l.denied(&types.Veth{HostVeth: "cali1"}))
+
+	// deny list persisted
+	l, err = newIPDenyList(store)
+	assert.NoError(t, err)
+	ips := l.list()
+	assert.Len(t, ips, 2)
+	assert.Equal(t, "192.168.0.10", ips[0].IP)
+	assert.Equal(t, "incident-1", ips[1].Reason)
+
+	assert.NoError(t, l.update(nil, []string{"192.168.0.11"}, ""))
+	assert.False(t, l.denied(eniIP))
+	assert.Len(t, l.list(), 1)
+
+	var disabled *ipDenyList
+	assert.False(t, disabled.denied(eni))
+	assert.Error(t, disabled.update([]string{"192.168.0.11"}, nil, ""))
+}
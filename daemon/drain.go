@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// phases of drain reported in progress
+const (
+	drainPhaseRelease = "release"
+	drainPhaseDispose = "dispose"
+)
+
+// Drain release resources of all pods on node and dispose resources of pools back to cloud, enis detached and
+// deleted, for node to be terminated, eg: scaled down by autoscaler, the node must be cordoned unless forced
+func (networkService *networkService) Drain(r *rpc.DrainRequest, stream rpc.TerwayBackend_DrainServer) error {
+	log.Infof("Drain request: %+v", r)
+	nodeName := networkService.k8s.GetNodeName()
+	if !r.Force {
+		cordoned, err := networkService.k8s.NodeCordoned()
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "error check node %s cordoned: %v", nodeName, err)
+		}
+		if !cordoned {
+			return status.Errorf(codes.FailedPrecondition, "node %s not cordoned, cordon it first or drain with force", nodeName)
+		}
+	}
+
+	// allocations and gc blocked until drained
+	networkService.Lock()
+	defer networkService.Unlock()
+
+	failed := 0
+	send := func(progress *rpc.DrainProgress, err error) error {
+		if err != nil {
+			failed++
+			progress.Error = err.Error()
+			log.Warnf("error drain %s %s: %v", progress.Phase, progress.Message, err)
+		}
+		return stream.Send(progress)
+	}
+
+	// eips unassociated before enis of them deleted
+	if err := networkService.eips.drain(); err != nil {
+		if err = send(&rpc.DrainProgress{Phase: drainPhaseRelease, Message: "eips of pods"}, err); err != nil {
+			return err
+		}
+	}
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		return status.Errorf(codes.Internal, "error list resource db: %v", err)
+	}
+	for i, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		progress := &rpc.DrainProgress{
+			Phase: drainPhaseRelease,
+			Done:  int32(i + 1),
+			Total: int32(len(resRelateList)),
+		}
+		if resRelate.PodInfo != nil {
+			progress.Message = podInfoKey(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name)
+		}
+		if err = send(progress, networkService.drainPod(stream.Context(), &resRelate)); err != nil {
+			return err
+		}
+	}
+
+	// resources of pods deleted held by delayed release disposed as well
+	networkService.releaseDelayed(networkService.delayedReleases.take(time.Now(), true))
+
+	resTypes := make([]string, 0, len(networkService.mgrForResource))
+	for resType := range networkService.mgrForResource {
+		resTypes = append(resTypes, resType)
+	}
+	sort.Strings(resTypes)
+	for i, resType := range resTypes {
+		disposed, drainErr := networkService.mgrForResource[resType].Drain()
+		progress := &rpc.DrainProgress{
+			Phase:   drainPhaseDispose,
+			Message: fmt.Sprintf("%d %s disposed", disposed, resType),
+			Done:    int32(i + 1),
+			Total:   int32(len(resTypes)),
+		}
+		if err = send(progress, drainErr); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return status.Errorf(codes.Internal, "%d steps of drain failed on node %s, drain again to retry", failed, nodeName)
+	}
+	log.Infof("node %s drained", nodeName)
+	return nil
+}
+
+// drainPod release resources of pod to pools and drop its relation
+func (networkService *networkService) drainPod(ctx context.Context, res *PodResources) error {
+	networkContext := &networkContext{
+		Context:    ctx,
+		resources:  res.Resources,
+		pod:        res.PodInfo,
+		k8sService: networkService.k8s,
+		sandboxID:  res.SandboxID,
+	}
+	for _, item := range res.Resources {
+		mgr := networkService.getResourceManagerForRes(item.Type)
+		if mgr == nil {
+			continue
+		}
+		if err := mgr.Release(networkContext, item.ID); err != nil && err != pool.ErrInvalidState {
+			return errors.Wrapf(err, "error release %s %s", item.Type, item.ID)
+		}
+	}
+	if res.PodInfo != nil {
+		if err := networkService.deletePodResource(res); err != nil {
+			return errors.Wrapf(err, "error delete resource from db")
+		}
+	}
+	networkService.publishPodEvents(eventTypeRelease, res, nil)
+	return nil
+}
@@ -0,0 +1,197 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/netfilter"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// egress nat of pod traffic by destination: traffic to no snat cidrs accepted in nat table so skipping
+// masquerade rules after, eg: of ip-masq-agent, and traffic to snat cidrs snat to node
+const (
+	egressNATChain        = "TERWAY-EGRESS-NAT"
+	egressNATComment      = "terway egress nat"
+	egressNATResyncPeriod = 30 * time.Second
+)
+
+// validateEgressNAT return errors of cidrs and ips in egress nat config
+func validateEgressNAT(cfg *types.EgressNAT) []error {
+	if cfg == nil {
+		return nil
+	}
+	var errs []error
+	parseCIDRs := func(field string, cidrs []string) {
+		for _, cidr := range cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				errs = append(errs, errors.Wrapf(err, "error parse %s of egress_nat: %s", field, cidr))
+			}
+		}
+	}
+	parseCIDRs("pod_cidrs", cfg.PodCIDRs)
+	parseCIDRs("no_snat", cfg.NoSNAT)
+	for _, rule := range cfg.SNAT {
+		parseCIDRs("snat destination", []string{rule.Destination})
+		if rule.ToSource != "" && net.ParseIP(rule.ToSource).To4() == nil {
+			errs = append(errs, fmt.Errorf("invalid to_source of egress_nat snat: %s", rule.ToSource))
+		}
+	}
+	if len(cfg.SNAT) > 0 && len(cfg.PodCIDRs) == 0 {
+		errs = append(errs, fmt.Errorf("pod_cidrs of egress_nat required by snat rules"))
+	}
+	return errs
+}
+
+// egressNATRules return rules of egress nat chain, no snat rules ahead of snat rules, by each pod cidr
+func egressNATRules(cfg *types.EgressNAT) [][]string {
+	if cfg == nil {
+		return nil
+	}
+	sources := cfg.PodCIDRs
+	if len(sources) == 0 {
+		sources = []string{""}
+	}
+	var rules [][]string
+	for _, dst := range cfg.NoSNAT {
+		for _, src := range sources {
+			var rule []string
+			if src != "" {
+				rule = append(rule, "-s", src)
+			}
+			rules = append(rules, append(rule, "-d", dst, "-m", "comment", "--comment", "no snat", "-j", "ACCEPT"))
+		}
+	}
+	for _, snat := range cfg.SNAT {
+		for _, src := range cfg.PodCIDRs {
+			rule := []string{"-s", src, "-d", snat.Destination, "-m", "comment", "--comment", "snat"}
+			if snat.ToSource == "" {
+				rule = append(rule, "-j", "MASQUERADE")
+			} else {
+				rule = append(rule, "-j", "SNAT", "--to-source", snat.ToSource)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// egressNAT keep egress nat chain and its jump rule as configured, restored if clobbered on node
+type egressNAT struct {
+	ipt   netfilter.IPTables
+	rules [][]string
+}
+
+func newEgressNAT(cfg *types.EgressNAT) (*egressNAT, error) {
+	ipt, err := netfilter.New()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init iptables")
+	}
+	return &egressNAT{ipt: ipt, rules: egressNATRules(cfg)}, nil
+}
+
+// inSync return whether chain has exactly the rules configured
+func (e *egressNAT) inSync() (bool, error) {
+	// chain definition listed ahead of its rules, error if chain not exists
+	listed, err := e.ipt.List("nat", egressNATChain)
+	if err != nil || len(listed) != len(e.rules)+1 {
+		return false, nil
+	}
+	for _, rule := range e.rules {
+		exists, err := e.ipt.Exists("nat", egressNATChain, rule...)
+		if err != nil {
+			return false, errors.Wrapf(err, "error check rule of %s", egressNATChain)
+		}
+		if !exists {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// sync rewrite egress nat chain if rules changed on node, and insert its jump rule ahead of POSTROUTING if missing
+func (e *egressNAT) sync() error {
+	synced, err := e.inSync()
+	if err != nil {
+		return err
+	}
+	if !synced {
+		// ClearChain create the chain if not exists
+		if err = e.ipt.ClearChain("nat", egressNATChain); err != nil {
+			return errors.Wrapf(err, "error clear chain %s", egressNATChain)
+		}
+		for _, rule := range e.rules {
+			if err = e.ipt.Append("nat", egressNATChain, rule...); err != nil {
+				return errors.Wrapf(err, "error add rule of egress nat")
+			}
+		}
+		log.Infof("%d rules of egress nat synced", len(e.rules))
+	}
+	jump := []string{"-m", "comment", "--comment", egressNATComment, "-j", egressNATChain}
+	exists, err := e.ipt.Exists("nat", "POSTROUTING", jump...)
+	if err != nil {
+		return errors.Wrapf(err, "error check jump rule to %s", egressNATChain)
+	}
+	if !exists {
+		if err = e.ipt.Insert("nat", "POSTROUTING", 1, jump...); err != nil {
+			return errors.Wrapf(err, "error add jump rule to %s", egressNATChain)
+		}
+		log.Infof("jump rule to %s restored", egressNATChain)
+	}
+	return nil
+}
+
+// start periodically restore rules of egress nat
+func (e *egressNAT) start() {
+	go func() {
+		for {
+			time.Sleep(egressNATResyncPeriod)
+			if err := e.sync(); err != nil {
+				log.Warnf("error sync egress nat: %v", err)
+			}
+		}
+	}()
+}
+
+// removeEgressNAT remove egress nat chain and its jump rule left by previous run with egress nat configured
+func removeEgressNAT() error {
+	ipt, err := netfilter.New()
+	if err != nil {
+		return err
+	}
+	jump := []string{"-m", "comment", "--comment", egressNATComment, "-j", egressNATChain}
+	exists, err := ipt.Exists("nat", "POSTROUTING", jump...)
+	if err != nil || !exists {
+		return err
+	}
+	if err = ipt.Delete("nat", "POSTROUTING", jump...); err != nil {
+		return errors.Wrapf(err, "error delete jump rule to %s", egressNATChain)
+	}
+	if err = ipt.ClearChain("nat", egressNATChain); err != nil {
+		return errors.Wrapf(err, "error clear chain %s", egressNATChain)
+	}
+	return errors.Wrapf(ipt.DeleteChain("nat", egressNATChain), "error delete chain %s", egressNATChain)
+}
+
+// setupEgressNAT program egress nat rules if configured and keep them reconciled, or remove the rules left
+// by previous run
+func setupEgressNAT(cfg *types.EgressNAT) error {
+	if len(egressNATRules(cfg)) == 0 {
+		if err := removeEgressNAT(); err != nil {
+			log.Warnf("error remove egress nat: %v", err)
+		}
+		return nil
+	}
+	e, err := newEgressNAT(cfg)
+	if err != nil {
+		return errors.Wrapf(err, "error init egress nat")
+	}
+	if err = e.sync(); err != nil {
+		return errors.Wrapf(err, "error setup egress nat")
+	}
+	e.start()
+	return nil
+}
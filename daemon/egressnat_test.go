@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEgressNATRules(t *testing.T) {
+	cfg := &types.EgressNAT{
+		PodCIDRs: []string{"172.16.0.0/16"},
+		NoSNAT:   []string{"192.168.0.0/16"},
+		SNAT: []types.SNATRule{
+			{Destination: "10.0.0.0/8", ToSource: "172.17.0.10"},
+			{Destination: "0.0.0.0/0"},
+		},
+	}
+	assert.Empty(t, validateEgressNAT(cfg))
+	rules := egressNATRules(cfg)
+	assert.Equal(t, [][]string{
+		{"-s", "172.16.0.0/16", "-d", "192.168.0.0/16", "-m", "comment", "--comment", "no snat", "-j", "ACCEPT"},
+		{"-s", "172.16.0.0/16", "-d", "10.0.0.0/8", "-m", "comment", "--comment", "snat", "-j", "SNAT", "--to-source", "172.17.0.10"},
+		{"-s", "172.16.0.0/16", "-d", "0.0.0.0/0", "-m", "comment", "--comment", "snat", "-j", "MASQUERADE"},
+	}, rules)
+
+	// exemptions of any source without pod cidrs
+	assert.Equal(t, [][]string{{"-d", "192.168.0.0/16", "-m", "comment", "--comment", "no snat", "-j", "ACCEPT"}},
+		egressNATRules(&types.EgressNAT{NoSNAT: []string{"192.168.0.0/16"}}))
+	assert.Empty(t, egressNATRules(nil))
+
+	assert.Len(t, validateEgressNAT(&types.EgressNAT{
+		NoSNAT: []string{"192.168.0.0"},
+		SNAT:   []types.SNATRule{{Destination: "0.0.0.0/0", ToSource: "node"}},
+	}), 3)
+}
@@ -0,0 +1,236 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	eipDBPath = "/var/lib/cni/terway/EIP.db"
+	eipDBName = "eip"
+	// eipReleasePeriod period to release eips retained after pods deleted
+	eipReleasePeriod = time.Minute
+)
+
+// podEIPRecord eip bound to pod, persisted until released or unbound
+type podEIPRecord struct {
+	PodKey string `json:"pod_key"`
+	ID     string `json:"id"`
+	// Address public address of eip
+	Address string `json:"address"`
+	// Allocated eip allocated by terway, released after pod deleted
+	Allocated     bool `json:"allocated"`
+	RetainSeconds int  `json:"retain_seconds,omitempty"`
+	// ENIID and PrivateIP the eip associated to, empty if not associated
+	ENIID     string `json:"eni_id,omitempty"`
+	PrivateIP string `json:"private_ip,omitempty"`
+	// ReleaseAt time the eip retained released if pod not recreated
+	ReleaseAt time.Time `json:"release_at,omitempty"`
+}
+
+// eipManager associate eips to ips of pods with eni or eni ip, allocated and released by terway if not by user
+type eipManager struct {
+	lock  sync.Mutex
+	ecs   aliyun.ECS
+	store storage.Storage
+}
+
+func newEIPStorage() (storage.Storage, error) {
+	return storage.NewDiskStorage(eipDBName, eipDBPath, json.Marshal, func(bytes []byte) (interface{}, error) {
+		record := podEIPRecord{}
+		if err := json.Unmarshal(bytes, &record); err != nil {
+			return nil, errors.Wrapf(err, "error unmarshal eip record")
+		}
+		return record, nil
+	})
+}
+
+func (m *eipManager) get(podKey string) (*podEIPRecord, error) {
+	obj, err := m.store.Get(podKey)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error get eip of pod %s", podKey)
+	}
+	record := obj.(podEIPRecord)
+	return &record, nil
+}
+
+func (m *eipManager) put(record *podEIPRecord) error {
+	return errors.Wrapf(m.store.Put(record.PodKey, *record), "error persist eip of pod %s", record.PodKey)
+}
+
+// bind associate eip of pod to its private ip on eni, nothing done if pod without eip
+func (m *eipManager) bind(podKey string, eip *annotation.EIP, eniID string, privateIP net.IP) error {
+	if m == nil || eip == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	record, err := m.get(podKey)
+	if err != nil {
+		return err
+	}
+	// eip of pod changed by user
+	if record != nil && (eip.ID != "" && eip.ID != record.ID || eip.ID == "" && !record.Allocated) {
+		if err = m.unbindLocked(record, 0); err != nil {
+			return err
+		}
+		record = nil
+	}
+	if record != nil && record.ENIID == eniID && record.PrivateIP == privateIP.String() {
+		return nil
+	}
+	if record != nil && record.ENIID != "" {
+		if err = m.ecs.UnassociateEIP(record.ID, record.ENIID, net.ParseIP(record.PrivateIP)); err != nil {
+			return err
+		}
+	}
+
+	if record == nil {
+		record = &podEIPRecord{PodKey: podKey, ID: eip.ID}
+		if eip.ID == "" {
+			allocated, err := m.ecs.AllocateEIP(eip.Bandwidth)
+			if err != nil {
+				return err
+			}
+			record.ID, record.Address, record.Allocated = allocated.ID, allocated.Address.String(), true
+			log.Infof("allocate eip %s %s for pod %s", record.ID, record.Address, podKey)
+		} else {
+			existing, err := m.ecs.GetEIP(eip.ID)
+			if err != nil {
+				return err
+			}
+			record.Address = existing.Address.String()
+		}
+	}
+	record.RetainSeconds = int(eip.Retain / time.Second)
+	record.ENIID, record.PrivateIP, record.ReleaseAt = "", "", time.Time{}
+	// persisted before associated, eip allocated not leaked if daemon restarted
+	if err = m.put(record); err != nil {
+		return err
+	}
+	if err = m.ecs.AssociateEIP(record.ID, eniID, privateIP); err != nil {
+		// released now or on retention expired, the pod may never be back
+		if unbindErr := m.unbindLocked(record, time.Duration(record.RetainSeconds)*time.Second); unbindErr != nil {
+			log.Warnf("error unbind eip %s of pod %s failed to associate: %v", record.ID, podKey, unbindErr)
+		}
+		return err
+	}
+	record.ENIID, record.PrivateIP = eniID, privateIP.String()
+	if err = m.put(record); err != nil {
+		return err
+	}
+	log.Infof("associate eip %s %s to %s of eni %s for pod %s", record.ID, record.Address, privateIP, eniID, podKey)
+	return nil
+}
+
+// unbind unassociate eip of pod, the eip allocated by terway released after retention
+func (m *eipManager) unbind(podKey string) error {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	record, err := m.get(podKey)
+	if err != nil || record == nil {
+		return err
+	}
+	return m.unbindLocked(record, time.Duration(record.RetainSeconds)*time.Second)
+}
+
+func (m *eipManager) unbindLocked(record *podEIPRecord, retain time.Duration) error {
+	if record.ENIID != "" {
+		if err := m.ecs.UnassociateEIP(record.ID, record.ENIID, net.ParseIP(record.PrivateIP)); err != nil {
+			return err
+		}
+		log.Infof("unassociate eip %s from eni %s of pod %s", record.ID, record.ENIID, record.PodKey)
+		record.ENIID, record.PrivateIP = "", ""
+	}
+	if record.Allocated && retain > 0 {
+		if record.ReleaseAt.IsZero() {
+			record.ReleaseAt = time.Now().Add(retain)
+		}
+		return m.put(record)
+	}
+	if record.Allocated {
+		if err := m.ecs.ReleaseEIP(record.ID); err != nil {
+			return err
+		}
+		log.Infof("release eip %s of pod %s", record.ID, record.PodKey)
+	}
+	return errors.Wrapf(m.store.Delete(record.PodKey), "error delete eip of pod %s", record.PodKey)
+}
+
+// drain unbind eips of all pods, the eips allocated by terway released without retention, eg: node to be terminated
+func (m *eipManager) drain() error {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	records, err := m.store.List()
+	if err != nil {
+		return errors.Wrapf(err, "error list eips of pods")
+	}
+	for _, obj := range records {
+		record := obj.(podEIPRecord)
+		if err = m.unbindLocked(&record, 0); err != nil {
+			return errors.Wrapf(err, "error release eip %s of pod %s", record.ID, record.PodKey)
+		}
+	}
+	return nil
+}
+
+// gc unbind eips of pods not on node, and release eips retained expired
+func (m *eipManager) gc(pods map[string]bool, now time.Time) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	records, err := m.store.List()
+	if err != nil {
+		log.Warnf("error list eips of pods: %v", err)
+		return
+	}
+	for _, obj := range records {
+		record := obj.(podEIPRecord)
+		retain := time.Duration(record.RetainSeconds) * time.Second
+		switch {
+		case record.ENIID != "" && !pods[record.PodKey]:
+		case !record.ReleaseAt.IsZero() && now.After(record.ReleaseAt):
+			retain = 0
+		default:
+			continue
+		}
+		if err = m.unbindLocked(&record, retain); err != nil {
+			log.Warnf("error gc eip %s of pod %s: %v", record.ID, record.PodKey, err)
+		}
+	}
+}
+
+// start release eips retained after expired in period
+func (m *eipManager) start(localPods func() (map[string]bool, error)) {
+	go func() {
+		for {
+			time.Sleep(eipReleasePeriod)
+			pods, err := localPods()
+			if err != nil {
+				log.Warnf("error get local pods for gc of eips: %v", err)
+				continue
+			}
+			m.gc(pods, time.Now())
+		}
+	}()
+}
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEIPManager(t *testing.T) {
+	ecs, err := aliyun.NewFakeECS(&aliyun.FakeConfig{}, nil)
+	assert.NoError(t, err)
+	eni, err := ecs.AllocateENI("vsw-fake", "sg-fake", "i-fake")
+	assert.NoError(t, err)
+	ips, err := ecs.AssignNIPsForENI(eni.ID, 2)
+	assert.NoError(t, err)
+	m := &eipManager{ecs: ecs, store: storage.NewMemoryStorage()}
+
+	// pod without eip
+	assert.NoError(t, m.bind("default/plain", nil, eni.ID, ips[0]))
+
+	retained := &annotation.EIP{Bandwidth: annotation.DefaultEIPBandwidth, Retain: time.Minute}
+	assert.NoError(t, m.bind("default/web-0", retained, eni.ID, ips[0]))
+	record, err := m.get("default/web-0")
+	assert.NoError(t, err)
+	eip, err := ecs.GetEIP(record.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ips[0].String(), eip.PrivateIP.String())
+
+	// retained after pod deleted, associated again to pod recreated with another ip
+	assert.NoError(t, m.unbind("default/web-0"))
+	assert.NoError(t, m.bind("default/web-0", retained, eni.ID, ips[1]))
+	eip, err = ecs.GetEIP(record.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ips[1].String(), eip.PrivateIP.String())
+
+	// unassociated when pod not on node, released after retention expired
+	m.gc(map[string]bool{}, time.Now())
+	eip, err = ecs.GetEIP(record.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, eip.ENIID)
+	m.gc(map[string]bool{}, time.Now().Add(2*time.Minute))
+	_, err = ecs.GetEIP(record.ID)
+	assert.Error(t, err)
+	record, err = m.get("default/web-0")
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	// eip of user associated and unassociated, not released
+	user, err := ecs.AllocateEIP(10)
+	assert.NoError(t, err)
+	assert.NoError(t, m.bind("default/web-1", &annotation.EIP{ID: user.ID}, eni.ID, ips[0]))
+	assert.NoError(t, m.unbind("default/web-1"))
+	eip, err = ecs.GetEIP(user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, eip.ENIID)
+}
@@ -2,9 +2,13 @@ package daemon
 
 import (
 	"fmt"
+	"net"
+	"strings"
 	"sync"
 
 	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/fault"
+	"github.com/AliyunContainerService/terway/pkg/metric"
 	"github.com/AliyunContainerService/terway/pkg/pool"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/pkg/errors"
@@ -21,6 +25,14 @@ type eniIPFactory struct {
 	enis         []*ENI
 	eniOperChan  chan struct{}
 	ipResultChan chan *ENIIP
+	// prefixDelegation carve ips from prefixes of eni
+	prefixDelegation bool
+	// routeTables route table ids of enis, reclaimed on eni released
+	routeTables *routeTableAllocator
+	// eniHealth health of enis, no ips allocated on failed enis, nil if not checked
+	eniHealth *eniHealthMonitor
+	// maxIPsPerENI private ips of each eni used at most, the max of instance type if 0
+	maxIPsPerENI int
 	sync.RWMutex
 }
 
@@ -34,82 +46,118 @@ type ENIIP struct {
 type ENI struct {
 	lock sync.Mutex
 	*types.ENI
-	ips       []*ENIIP
-	pending   int
-	ipBacklog chan struct{}
+	ips     []*ENIIP
+	pending int
+	// ipBacklog priorities of ip allocations submitted
+	ipBacklog chan aliyun.Priority
 	ecs       aliyun.ECS
-	done      chan struct{}
+	// backgroundECS for allocations of idle ips, eg: prewarm of pool
+	backgroundECS aliyun.ECS
+	done          chan struct{}
+
+	prefixDelegation bool
+	prefixes         []*net.IPNet
+	// carved ips of prefixes, allocated or pending
+	carved map[string]bool
+	// detached eni gone from instance not by terway, no more ips allocated on it, evicted by reconcile
+	detached bool
 }
 
-// eni ip allocator
+// eni ip allocator, allocations batched in the highest priority of them
 func (e *ENI) allocateWorker(resultChan chan<- *ENIIP) {
 	for {
 		toAllocate := 0
+		var priority aliyun.Priority
 		select {
 		case <-e.done:
 			return
-		case <-e.ipBacklog:
+		case priority = <-e.ipBacklog:
 			toAllocate = 1
 		}
 
 	popAll:
 		for {
 			select {
-			case <-e.ipBacklog:
+			case p := <-e.ipBacklog:
 				toAllocate++
+				if p < priority {
+					priority = p
+				}
 			default:
 				break popAll
 			}
 		}
 		logrus.Debugf("allocate %v ips for eni", toAllocate)
-		ips, err := e.ecs.AssignNIPsForENI(e.ENI.ID, toAllocate)
+		ecs := e.ecs
+		if priority == aliyun.PriorityBackground && e.backgroundECS != nil {
+			ecs = e.backgroundECS
+		}
+		ips, err := e.assignIPs(ecs, toAllocate)
 		logrus.Debugf("allocated ips for eni: %v, %v", e.ENI, ips)
-		if err != nil {
-			for i := 0; i < toAllocate; i++ {
-				resultChan <- &ENIIP{
-					ENIIP: nil,
-					err:   errors.Errorf("error assign ip for ENI: %v", err),
-				}
+		for _, ip := range ips {
+			resultChan <- &ENIIP{
+				ENIIP: &types.ENIIP{
+					Eni:        e.ENI,
+					SecAddress: ip,
+				},
+				err: nil,
 			}
-		} else {
-			for _, ip := range ips {
-				resultChan <- &ENIIP{
-					ENIIP: &types.ENIIP{
-						Eni:        e.ENI,
-						SecAddress: ip,
-					},
-					err: nil,
-				}
+		}
+		if aliyun.IsENIDetachedExternally(err) {
+			logrus.Warnf("eni %s detached externally, no more ips allocated on it: %v", e.ENI.ID, err)
+			e.lock.Lock()
+			e.detached = true
+			e.lock.Unlock()
+		}
+		if err == nil && len(ips) < toAllocate {
+			err = fmt.Errorf("%d ips assigned less than %d", len(ips), toAllocate)
+		}
+		for i := len(ips); i < toAllocate; i++ {
+			resultChan <- &ENIIP{
+				ENIIP: nil,
+				err:   errors.Wrapf(err, "error assign ip for ENI"),
 			}
 		}
 	}
 }
 
-func (f *eniIPFactory) submit() error {
+// submit allocation of at most count ips in priority to one exist eni, return count of ips submitted
+func (f *eniIPFactory) submit(count int, priority aliyun.Priority) (int, error) {
 	f.Lock()
 	defer f.Unlock()
 	for _, eni := range f.enis {
 		logrus.Debugf("check exist eni's ip: %+v", eni)
+		if !f.eniHealth.healthy(eni.MAC) {
+			continue
+		}
 		eni.lock.Lock()
-		ipCount := eni.pending + len(eni.ips)
+		room := eni.roomLocked()
+		detached := eni.detached
 		eni.lock.Unlock()
-		if ipCount < eni.MaxIPs {
+		if detached {
+			continue
+		}
+		submitted := 0
+	submit:
+		for ; submitted < count && submitted < room; submitted++ {
 			select {
-			case eni.ipBacklog <- struct{}{}:
+			case eni.ipBacklog <- priority:
 			default:
-				continue
+				break submit
 			}
-			eni.pending++
-			return nil
+		}
+		if submitted > 0 {
+			eni.pending += submitted
+			return submitted, nil
 		}
 	}
-	return errors.Errorf("trigger ENIIP throttle, max operating concurrent: %v", maxIPBacklog)
+	return 0, fmt.Errorf("trigger ENIIP throttle, max operating concurrent: %v", maxIPBacklog)
 }
 
 func (f *eniIPFactory) popResult() (ip *types.ENIIP, err error) {
 	result := <-f.ipResultChan
 	if result.ENIIP == nil || result.err != nil {
-		return nil, errors.Errorf("error allocate ip from eni: %v", result.err)
+		return nil, errors.Wrapf(result.err, "error allocate ip from eni")
 	}
 	f.Lock()
 	defer f.Unlock()
@@ -122,11 +170,34 @@ func (f *eniIPFactory) popResult() (ip *types.ENIIP, err error) {
 			return result.ENIIP, nil
 		}
 	}
-	return nil, errors.Errorf("unexpected eni ip allocated: %v", result)
+	return nil, fmt.Errorf("unexpected eni ip allocated: %v", result)
+
+}
+
+func (f *eniIPFactory) Create() (types.NetworkResource, error) {
+	return f.create(aliyun.PriorityCritical)
+}
+
+// backgroundENIIPFactory view of eni ip factory creating ips in background priority of openapi calls
+type backgroundENIIPFactory struct {
+	*eniIPFactory
+}
+
+func (f backgroundENIIPFactory) Create() (types.NetworkResource, error) {
+	return f.create(aliyun.PriorityBackground)
+}
 
+func (f backgroundENIIPFactory) CreateBatch(count int) ([]types.NetworkResource, error) {
+	return f.createBatch(count, aliyun.PriorityBackground)
 }
 
-func (f *eniIPFactory) Create() (ip types.NetworkResource, err error) {
+// Background return view of factory creating ips in background, for idle ips of pool
+func (f *eniIPFactory) Background() pool.ObjectFactory {
+	return backgroundENIIPFactory{f}
+}
+
+// create allocate ip on exist eni or on new eni in priority
+func (f *eniIPFactory) create(priority aliyun.Priority) (ip types.NetworkResource, err error) {
 	defer func() {
 		if ip == nil {
 			logrus.Debugf("create result: %v, error: %v", ip, err != nil)
@@ -135,7 +206,10 @@ func (f *eniIPFactory) Create() (ip types.NetworkResource, err error) {
 		}
 	}()
 
-	err = f.submit()
+	if err = fault.Inject(fault.Point(types.ResourceTypeENIIP, fault.OpCreate)); err != nil {
+		return nil, err
+	}
+	_, err = f.submit(1, priority)
 	if err == nil {
 		ip, err = f.popResult()
 		return
@@ -145,9 +219,13 @@ func (f *eniIPFactory) Create() (ip types.NetworkResource, err error) {
 	select {
 	case f.eniOperChan <- struct{}{}:
 	default:
-		return nil, errors.Errorf("trigger ENI throttle, max operating concurrent: %v", maxEniOperating)
+		return nil, fmt.Errorf("trigger ENI throttle, max operating concurrent: %v", maxEniOperating)
+	}
+	ecs := f.eniFactory.ecs
+	if priority == aliyun.PriorityBackground {
+		ecs = f.eniFactory.backgroundECS
 	}
-	rawEni, err := f.eniFactory.Create()
+	rawEni, err := f.eniFactory.create(ecs)
 	<-f.eniOperChan
 	if err != nil {
 		return nil, err
@@ -155,15 +233,10 @@ func (f *eniIPFactory) Create() (ip types.NetworkResource, err error) {
 
 	eniObj, ok := rawEni.(*types.ENI)
 	if !ok {
-		return nil, errors.Errorf("error get type ENI from factory, got: %v", rawEni)
+		return nil, fmt.Errorf("error get type ENI from factory, got: %v", rawEni)
 	}
 
-	eni := &ENI{
-		ENI:       eniObj,
-		ecs:       f.eniFactory.ecs,
-		ipBacklog: make(chan struct{}, maxIPBacklog),
-		done:      make(chan struct{}, 1),
-	}
+	eni := f.newENI(eniObj)
 
 	mainENIIP := &types.ENIIP{
 		Eni:        eni.ENI,
@@ -182,10 +255,165 @@ func (f *eniIPFactory) Create() (ip types.NetworkResource, err error) {
 	return mainENIIP, nil
 }
 
+// newENI return eni to allocate ips, ips of eni capped by max ips per eni
+func (f *eniIPFactory) newENI(eni *types.ENI) *ENI {
+	if f.maxIPsPerENI > 0 && eni.MaxIPs > f.maxIPsPerENI {
+		eni.MaxIPs = f.maxIPsPerENI
+	}
+	return &ENI{
+		ENI:              eni,
+		ips:              []*ENIIP{},
+		ecs:              f.eniFactory.ecs,
+		backgroundECS:    f.eniFactory.backgroundECS,
+		ipBacklog:        make(chan aliyun.Priority, maxIPBacklog),
+		done:             make(chan struct{}, 1),
+		prefixDelegation: f.prefixDelegation,
+		carved:           make(map[string]bool),
+	}
+}
+
+// findENI return eni of factory by id, nil if not found
+func (f *eniIPFactory) findENI(eniID string) *ENI {
+	f.RLock()
+	defer f.RUnlock()
+	for _, e := range f.enis {
+		if e.ID == eniID {
+			return e
+		}
+	}
+	return nil
+}
+
+// CreateBatch allocate ips in chunk on exist eni by one openapi call, or create eni if no room
+func (f *eniIPFactory) CreateBatch(count int) ([]types.NetworkResource, error) {
+	return f.createBatch(count, aliyun.PriorityCritical)
+}
+
+// createBatch allocate ips in chunk in priority
+func (f *eniIPFactory) createBatch(count int, priority aliyun.Priority) ([]types.NetworkResource, error) {
+	if err := fault.Inject(fault.Point(types.ResourceTypeENIIP, fault.OpCreate)); err != nil {
+		return nil, err
+	}
+	submitted, err := f.submit(count, priority)
+	if err != nil {
+		logrus.Debugf("allocate chunk from exist eni error: %v, creating eni", err)
+		ip, err := f.create(priority)
+		if err != nil {
+			return nil, err
+		}
+		return []types.NetworkResource{ip}, nil
+	}
+
+	var resources []types.NetworkResource
+	for i := 0; i < submitted; i++ {
+		ip, popErr := f.popResult()
+		if popErr != nil {
+			err = popErr
+			continue
+		}
+		resources = append(resources, ip)
+	}
+	if len(resources) == 0 {
+		return nil, err
+	}
+	logrus.Debugf("create chunk result: %d of %d ips", len(resources), count)
+	return resources, nil
+}
+
+// DisposeBatch unassign secondary ips in chunk of each eni by one openapi call
+func (f *eniIPFactory) DisposeBatch(resources []types.NetworkResource) ([]types.NetworkResource, error) {
+	var (
+		eniIDs []string
+		// ips disposed one by one after secondary ips: primary ips and ips carved from prefix
+		single []*types.ENIIP
+		failed []types.NetworkResource
+		err    error
+	)
+	if err = fault.Inject(fault.Point(types.ResourceTypeENIIP, fault.OpDispose)); err != nil {
+		return resources, err
+	}
+	secondary := make(map[string][]*types.ENIIP)
+	for _, res := range resources {
+		ip := res.(*types.ENIIP)
+		if ip.Eni.Address.IP.Equal(ip.SecAddress) || f.isCarved(ip) {
+			single = append(single, ip)
+			continue
+		}
+		if _, ok := secondary[ip.Eni.ID]; !ok {
+			eniIDs = append(eniIDs, ip.Eni.ID)
+		}
+		secondary[ip.Eni.ID] = append(secondary[ip.Eni.ID], ip)
+	}
+
+	for _, eniID := range eniIDs {
+		if unAssignErr := f.unAssignIPs(eniID, secondary[eniID]); unAssignErr != nil {
+			for _, ip := range secondary[eniID] {
+				failed = append(failed, ip)
+			}
+			err = unAssignErr
+		}
+	}
+	// primary ip disposed by releasing eni, only if all secondary ips released
+	for _, ip := range single {
+		if disposeErr := f.Dispose(ip); disposeErr != nil {
+			failed = append(failed, ip)
+			err = disposeErr
+		}
+	}
+	return failed, err
+}
+
+// isCarved return whether ip carved from prefix of eni
+func (f *eniIPFactory) isCarved(ip *types.ENIIP) bool {
+	eni := f.findENI(ip.Eni.ID)
+	if eni == nil {
+		return false
+	}
+	eni.lock.Lock()
+	defer eni.lock.Unlock()
+	return eni.prefixOfLocked(ip.SecAddress) != nil
+}
+
+// unAssignIPs unassign secondary ips of eni and remove them from eni
+func (f *eniIPFactory) unAssignIPs(eniID string, ips []*types.ENIIP) error {
+	eni := f.findENI(eniID)
+	if eni == nil {
+		return fmt.Errorf("invalid resource to dispose, eni %s not found", eniID)
+	}
+
+	addresses := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		addresses = append(addresses, ip.SecAddress)
+	}
+	if err := f.eniFactory.backgroundECS.UnAssignIPsForENI(eniID, addresses); err != nil {
+		return fmt.Errorf("error unassign eniips, %v", err)
+	}
+	for _, ip := range ips {
+		eni.removeIP(ip.SecAddress)
+	}
+	return nil
+}
+
+// removeIP remove ip from ips of eni
+func (e *ENI) removeIP(address net.IP) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for i, ip := range e.ips {
+		if ip.SecAddress.Equal(address) {
+			e.ips[len(e.ips)-1], e.ips[i] = e.ips[i], e.ips[len(e.ips)-1]
+			e.ips = e.ips[:len(e.ips)-1]
+			return
+		}
+	}
+}
+
 func (f *eniIPFactory) Dispose(res types.NetworkResource) (err error) {
 	defer func() {
 		logrus.Debugf("dispose result: %v, error: %v", res.GetResourceID(), err != nil)
 	}()
+	if err = fault.Inject(fault.Point(types.ResourceTypeENIIP, fault.OpDispose)); err != nil {
+		return err
+	}
 	ip := res.(*types.ENIIP)
 	var (
 		eni   *ENI
@@ -209,13 +437,23 @@ func (f *eniIPFactory) Dispose(res types.NetworkResource) (err error) {
 		return fmt.Errorf("invalid resource to dispose")
 	}
 
-	ips, err := f.eniFactory.ecs.GetENIIPs(ip.Eni.ID)
+	if f.isCarved(ip) {
+		return f.releaseCarvedIP(eni, ip.SecAddress)
+	}
+
+	ips, err := f.eniFactory.backgroundECS.GetENIIPs(ip.Eni.ID)
 
 	if err != nil {
 		return fmt.Errorf("error get ENI ips for: %v", ip)
 	}
 
 	if len(ips) == 1 {
+		eni.lock.Lock()
+		carved := len(eni.carved)
+		eni.lock.Unlock()
+		if carved > 0 {
+			return fmt.Errorf("ENI has %d ips carved from prefixes, can not be released", carved)
+		}
 		f.eniOperChan <- struct{}{}
 		// only remain ENI main ip address, release the ENI interface
 		err = f.eniFactory.Dispose(ip.Eni)
@@ -233,6 +471,9 @@ func (f *eniIPFactory) Dispose(res types.NetworkResource) (err error) {
 			}
 		}
 		f.Unlock()
+		if err = f.routeTables.release(ip.Eni.MAC); err != nil {
+			logrus.Warnf("error release route table of eni %s: %v", ip.Eni.MAC, err)
+		}
 		return nil
 	}
 
@@ -241,31 +482,148 @@ func (f *eniIPFactory) Dispose(res types.NetworkResource) (err error) {
 		return fmt.Errorf("ip to be release is primary ip of ENI")
 	}
 
-	err = f.eniFactory.ecs.UnAssignIPForENI(ip.Eni.ID, ip.SecAddress)
+	err = f.eniFactory.backgroundECS.UnAssignIPForENI(ip.Eni.ID, ip.SecAddress)
 	if err != nil {
 		return fmt.Errorf("error unassign eniip, %v", err)
 	}
-	eni.lock.Lock()
-	for i, e := range eni.ips {
-		if e.SecAddress.Equal(eniip.SecAddress) {
-			eni.ips[len(eni.ips)-1], eni.ips[i] = eni.ips[i], eni.ips[len(eni.ips)-1]
-			eni.ips = eni.ips[:len(eni.ips)-1]
+	eni.removeIP(eniip.SecAddress)
+	return nil
+}
+
+type eniIPResourceManager struct {
+	pool              pool.ObjectPool
+	factory           *eniIPFactory
+	conflictDetection bool
+	releasePolicy     string
+	reconciler        *poolReconciler
+}
+
+func (m *eniIPResourceManager) poolReconciler() *poolReconciler {
+	return m.reconciler
+}
+
+// cloudView return ips of enis attached to instance in cloud, ips of enis known or created by terway adoptable,
+// ips of prefixes are present but carved on allocation instead of adopted
+func (f *eniIPFactory) cloudView() (*cloudView, error) {
+	ecs := f.eniFactory.backgroundECS
+	enis, err := f.eniFactory.attachedENIs(ecs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error get attached enis")
+	}
+	owned, err := ownedENIs(ecs, f.eniFactory.instanceID)
+	if err != nil {
+		return nil, err
+	}
+	view := newCloudView()
+	for _, eni := range enis {
+		view.enis[eni.ID] = true
+		ips, err := ecs.GetENIIPs(eni.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error get ips of eni %s", eni.ID)
+		}
+		adoptable := owned[eni.ID] || f.findENI(eni.ID) != nil
+		for _, ip := range ips {
+			eniIP := &types.ENIIP{Eni: eni, SecAddress: ip}
+			view.present[eniIP.GetResourceID()] = true
+			if adoptable {
+				view.adoptable = append(view.adoptable, eniIP)
+			}
+		}
+		if !f.prefixDelegation {
+			continue
+		}
+		prefixes, err := ecs.GetENIPrefixes(eni.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error get prefixes of eni %s", eni.ID)
+		}
+		for _, prefix := range prefixes {
+			for _, ip := range prefixIPs(prefix) {
+				view.present[(&types.ENIIP{Eni: eni, SecAddress: ip}).GetResourceID()] = true
+			}
+		}
+	}
+	return view, nil
+}
+
+// adopt track ip found in cloud in its eni, eni of the ip tracked as well if unknown
+func (f *eniIPFactory) adopt(res types.NetworkResource) {
+	ip := res.(*types.ENIIP)
+	f.Lock()
+	defer f.Unlock()
+	var eni *ENI
+	for _, e := range f.enis {
+		if e.ID == ip.Eni.ID {
+			eni = e
 			break
 		}
 	}
+	if eni == nil {
+		eni = f.newENI(ip.Eni)
+		f.enis = append(f.enis, eni)
+		go eni.allocateWorker(f.ipResultChan)
+		logrus.Warnf("adopted eni %s found attached in cloud", ip.Eni.ID)
+	}
+	ip.Eni = eni.ENI
+	eni.lock.Lock()
+	eni.ips = append(eni.ips, &ENIIP{ENIIP: ip})
 	eni.lock.Unlock()
-	return nil
 }
 
-type eniIPResourceManager struct {
-	pool pool.ObjectPool
+// evict untrack ip vanished in cloud, its eni untracked as well once detached and no ips left
+func (f *eniIPFactory) evict(res types.NetworkResource, view *cloudView) {
+	ip := res.(*types.ENIIP)
+	eni := f.findENI(ip.Eni.ID)
+	if eni == nil {
+		return
+	}
+	eni.removeIP(ip.SecAddress)
+	eni.lock.Lock()
+	delete(eni.carved, ip.SecAddress.String())
+	left := len(eni.ips)
+	eni.lock.Unlock()
+	if left > 0 || view.enis[eni.ID] {
+		return
+	}
+	f.Lock()
+	for i, e := range f.enis {
+		if e == eni {
+			close(eni.done)
+			f.enis = append(f.enis[:i], f.enis[i+1:]...)
+			break
+		}
+	}
+	f.Unlock()
+	if err := f.routeTables.release(eni.MAC); err != nil {
+		logrus.Warnf("error release route table of eni %s: %v", eni.MAC, err)
+	}
+	logrus.Warnf("eni %s vanished in cloud untracked", eni.ID)
 }
 
-func newENIIPResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, allocatedResources []string) (ResourceManager, error) {
+// eniIPCapacity return capacity of eni ips of instance and private ips of each eni used at most, capacity
+// shrunk by eni max ips of config below the max of instance type, 0 of ips per eni if not capped
+func eniIPCapacity(ecs aliyun.ECS, poolConfig *types.PoolConfig) (int, int, error) {
+	capacity, err := ecs.GetInstanceMaxPrivateIP(poolConfig.InstanceID)
+	if err != nil || poolConfig.ENIMaxIPs <= 0 {
+		return capacity, 0, err
+	}
+	maxIPs, err := ecs.GetENIMaxIP(poolConfig.InstanceID, "")
+	if err != nil {
+		return 0, 0, err
+	}
+	if poolConfig.ENIMaxIPs >= maxIPs {
+		return capacity, 0, nil
+	}
+	capped := capacity / maxIPs * poolConfig.ENIMaxIPs
+	logrus.Infof("ips of each eni capped to %d of max %d, capacity of eni ips %d", poolConfig.ENIMaxIPs, maxIPs, capped)
+	return capped, poolConfig.ENIMaxIPs, nil
+}
+
+func newENIIPResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, allocatedResources []string, routeTables *routeTableAllocator, eniHealth *eniHealthMonitor, partition *eniPartition) (ResourceManager, error) {
 	eniFactory, err := newENIFactory(poolConfig, ecs)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error get ENI factory for eniip factory")
 	}
+	eniFactory.partition = partition
 
 	factory := &eniIPFactory{
 		eniFactory:   eniFactory,
@@ -273,11 +631,19 @@ func newENIIPResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, alloc
 		eniOperChan:  make(chan struct{}, maxEniOperating),
 		ipResultChan: make(chan *ENIIP, maxIPBacklog),
 	}
+	factory.prefixDelegation = poolConfig.PrefixDelegation
+	factory.routeTables = routeTables
+	factory.eniHealth = eniHealth
 
-	capacity, err := ecs.GetInstanceMaxPrivateIP(poolConfig.InstanceID)
+	capacity, maxIPsPerENI, err := eniIPCapacity(ecs, poolConfig)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error get eniip max capacity for eniip factory")
 	}
+	factory.maxIPsPerENI = maxIPsPerENI
+	capacity, err = partition.sharedIPCapacity(ecs, poolConfig.InstanceID, capacity)
+	if err != nil {
+		return nil, err
+	}
 
 	if poolConfig.MaxPoolSize > capacity {
 		logrus.Infof("max pool size bigger than node capacity, set max pool size to capacity")
@@ -291,7 +657,7 @@ func newENIIPResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, alloc
 		Capacity: capacity,
 		Initializer: func(holder pool.ResourceHolder) error {
 			// not use main ENI for ENI multiple ip allocate
-			enis, err := ecs.GetAttachedENIs(poolConfig.InstanceID, false)
+			enis, err := eniFactory.attachedENIs(ecs)
 			if err != nil {
 				return errors.Wrapf(err, "error get attach ENI on pool init")
 			}
@@ -299,20 +665,20 @@ func newENIIPResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, alloc
 			for _, allocated := range allocatedResources {
 				stubMap[allocated] = true
 			}
+			attached := make(map[string]bool)
+			for _, eni := range enis {
+				attached[eni.MAC] = true
+			}
+			if err = routeTables.reconcile(attached); err != nil {
+				return errors.Wrapf(err, "error reclaim route tables of detached ENI on pool init")
+			}
 
 			for _, eni := range enis {
 				ips, err := ecs.GetENIIPs(eni.ID)
 				if err != nil {
 					return errors.Wrapf(err, "error get ENI's ip on pool init")
 				}
-				poolENI := &ENI{
-					lock:      sync.Mutex{},
-					ENI:       eni,
-					ips:       []*ENIIP{},
-					ecs:       ecs,
-					ipBacklog: make(chan struct{}, maxIPBacklog),
-					done:      make(chan struct{}, 1),
-				}
+				poolENI := factory.newENI(eni)
 				factory.enis = append(factory.enis, poolENI)
 				for _, ip := range ips {
 					eniIP := &types.ENIIP{
@@ -330,30 +696,118 @@ func newENIIPResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, alloc
 						holder.AddInuse(eniIP)
 					}
 				}
+				if factory.prefixDelegation {
+					poolENI.prefixes, err = ecs.GetENIPrefixes(eni.ID)
+					if err != nil {
+						return errors.Wrapf(err, "error get ENI's prefixes on pool init")
+					}
+					// free ips of prefixes carved again on allocation
+					for _, prefix := range poolENI.prefixes {
+						for _, ip := range prefixIPs(prefix) {
+							eniIP := &types.ENIIP{
+								Eni:        eni,
+								SecAddress: ip,
+							}
+							if !stubMap[eniIP.GetResourceID()] {
+								continue
+							}
+							poolENI.carved[ip.String()] = true
+							poolENI.ips = append(poolENI.ips, &ENIIP{
+								ENIIP: eniIP,
+							})
+							holder.AddInuse(eniIP)
+						}
+					}
+				}
 				logrus.Debugf("init factory's exist ENI: %+v", poolENI)
 				go poolENI.allocateWorker(factory.ipResultChan)
 			}
 			return nil
 		},
+		QuarantinePeriod: poolConfig.QuarantinePeriod,
+		ChunkSize:        poolConfig.IPChunkSize,
+		Denied:           poolConfig.DeniedResource,
+		DrainRate:        poolConfig.DrainRate,
+		ParallelCreates:  poolConfig.ParallelCreates,
+		Throttle:         poolConfig.Throttle,
 	}
 	pool, err := pool.NewSimpleObjectPool(poolCfg)
 	if err != nil {
 		return nil, err
 	}
 	return &eniIPResourceManager{
-		pool: pool,
+		pool:              pool,
+		factory:           factory,
+		conflictDetection: poolConfig.IPConflictDetection,
+		releasePolicy:     poolConfig.ReleasePolicy[types.ResourceTypeENIIP],
+		reconciler: &poolReconciler{
+			resType:   types.ResourceTypeENIIP,
+			pool:      pool,
+			listCloud: factory.cloudView,
+			adopt:     factory.adopt,
+			evict:     factory.evict,
+		},
 	}, nil
 }
 
+// eniSpreadScore score ips by how few ips to avoid on the same eni, for spreading pods across enis
+func eniSpreadScore(avoid []string) func(res types.NetworkResource) int {
+	// id of eni ip is mac of eni and the ip joined by dot
+	onENI := make(map[string]int)
+	for _, id := range avoid {
+		if i := strings.Index(id, "."); i > 0 {
+			onENI[id[:i]]++
+		}
+	}
+	return func(res types.NetworkResource) int {
+		eniIP, ok := res.(*types.ENIIP)
+		if !ok || eniIP.Eni == nil {
+			return 0
+		}
+		return -onENI[eniIP.Eni.MAC]
+	}
+}
+
 func (m *eniIPResourceManager) Allocate(ctx *networkContext, prefer string) (types.NetworkResource, error) {
-	return m.pool.Acquire(ctx, prefer)
+	hints := pool.AcquireHints{Prefer: prefer}
+	if len(ctx.avoid) > 0 {
+		hints.Score = eniSpreadScore(ctx.avoid)
+	}
+	for i := 0; ; i++ {
+		res, err := m.pool.AcquireWithHints(ctx, hints)
+		if err != nil || !m.conflictDetection {
+			return res, err
+		}
+		eniIP := res.(*types.ENIIP)
+		conflict, err := detectIPConflict(eniIP.Eni, eniIP.SecAddress)
+		if err != nil {
+			// do not block allocation when probe failed
+			ctx.Log().Warnf("error detect ip conflict for %s: %v", eniIP.GetResourceID(), err)
+			return res, nil
+		}
+		if !conflict {
+			return res, nil
+		}
+
+		metric.ResourceConflict.WithLabelValues(types.ResourceTypeENIIP).Inc()
+		ctx.Log().Errorf("ip %s conflict with other device, dispose it", eniIP.GetResourceID())
+		if err = m.pool.Dispose(eniIP.GetResourceID()); err != nil {
+			ctx.Log().Warnf("error dispose conflict ip %s: %v", eniIP.GetResourceID(), err)
+		}
+		if i+1 >= maxConflictRetry {
+			return nil, fmt.Errorf("ip conflict detected for %d times", maxConflictRetry)
+		}
+		hints.Prefer = ""
+	}
+}
+
+// Transfer keep ip of pod in pool as inuse
+func (m *eniIPResourceManager) Transfer(ctx *networkContext, fromSandbox, resID string) (types.NetworkResource, error) {
+	return m.pool.Transfer(resID)
 }
 
 func (m *eniIPResourceManager) Release(context *networkContext, resID string) error {
-	if context != nil && context.pod != nil {
-		return m.pool.ReleaseWithReverse(resID, context.pod.IPStickTime)
-	}
-	return m.pool.Release(resID)
+	return releaseToPool(m.pool, m.releasePolicy, context, resID)
 }
 
 func (m *eniIPResourceManager) GarbageCollection(inUseSet map[string]interface{}, expireResSet map[string]interface{}) error {
@@ -367,3 +821,23 @@ func (m *eniIPResourceManager) GarbageCollection(inUseSet map[string]interface{}
 	}
 	return nil
 }
+
+func (m *eniIPResourceManager) GetResourceIDs() []string {
+	return m.pool.GetResourceIDs()
+}
+
+func (m *eniIPResourceManager) Stats() pool.Stats {
+	return m.pool.Stats()
+}
+
+func (m *eniIPResourceManager) Inventory() []pool.InventoryItem {
+	return m.pool.Inventory()
+}
+
+func (m *eniIPResourceManager) Prewarm(count int) {
+	m.pool.Prewarm(count)
+}
+
+func (m *eniIPResourceManager) Drain() (int, error) {
+	return m.pool.Drain()
+}
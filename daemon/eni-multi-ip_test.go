@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestENISpreadScore(t *testing.T) {
+	eni1 := &types.ENI{MAC: "00:16:3e:00:00:01"}
+	eni2 := &types.ENI{MAC: "00:16:3e:00:00:02"}
+	ip := func(eni *types.ENI, addr string) *types.ENIIP {
+		return &types.ENIIP{Eni: eni, SecAddress: net.ParseIP(addr)}
+	}
+
+	score := eniSpreadScore([]string{
+		ip(eni1, "192.168.0.2").GetResourceID(),
+		ip(eni1, "192.168.0.3").GetResourceID(),
+		ip(eni2, "192.168.1.2").GetResourceID(),
+	})
+	assert.Equal(t, -2, score(ip(eni1, "192.168.0.4")))
+	assert.Equal(t, -1, score(ip(eni2, "192.168.1.3")))
+	assert.Equal(t, 0, score(ip(&types.ENI{MAC: "00:16:3e:00:00:03"}, "192.168.2.2")))
+}
+
+func TestENIMaxIPs(t *testing.T) {
+	ecs, err := aliyun.NewFakeECS(&aliyun.FakeConfig{InstanceID: "i-1", MaxENI: 4, MaxIPPerENI: 10}, nil)
+	assert.NoError(t, err)
+	capacity, maxIPs, err := eniIPCapacity(ecs, &types.PoolConfig{InstanceID: "i-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 30, capacity)
+	assert.Equal(t, 0, maxIPs)
+
+	// headroom of 4 ips left on each eni
+	capacity, maxIPs, err = eniIPCapacity(ecs, &types.PoolConfig{InstanceID: "i-1", ENIMaxIPs: 6})
+	assert.NoError(t, err)
+	assert.Equal(t, 18, capacity)
+	assert.Equal(t, 6, maxIPs)
+	f := &eniIPFactory{eniFactory: &eniFactory{ecs: ecs}, maxIPsPerENI: maxIPs}
+	assert.Equal(t, 6, f.newENI(&types.ENI{ID: "eni-1", MaxIPs: 10}).MaxIPs)
+	assert.Equal(t, 4, f.newENI(&types.ENI{ID: "eni-2", MaxIPs: 4}).MaxIPs)
+
+	// not capped above the max of instance type
+	capacity, maxIPs, err = eniIPCapacity(ecs, &types.PoolConfig{InstanceID: "i-1", ENIMaxIPs: 20})
+	assert.NoError(t, err)
+	assert.Equal(t, 30, capacity)
+	assert.Equal(t, 0, maxIPs)
+}
@@ -0,0 +1,159 @@
+package daemon
+
+import (
+	"net"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/sirupsen/logrus"
+)
+
+// prefix delegation: an ipv4 prefix assigned to eni by one openapi call takes one ip slot of eni,
+// pod ips are carved from prefixes locally without openapi call
+
+// defaultPrefixSize ips of a prefix not assigned yet, vpc assigns /28 prefix
+const defaultPrefixSize = 16
+
+// prefixIPs return all ips of prefix
+func prefixIPs(prefix *net.IPNet) []net.IP {
+	var ips []net.IP
+	for ip := prefix.IP.Mask(prefix.Mask); prefix.Contains(ip); ip = nextIP(ip) {
+		ips = append(ips, ip)
+	}
+	return ips
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// prefixOfLocked return prefix of eni the ip carved from, nil if ip not carved
+func (e *ENI) prefixOfLocked(ip net.IP) *net.IPNet {
+	if !e.carved[ip.String()] {
+		return nil
+	}
+	for _, prefix := range e.prefixes {
+		if prefix.Contains(ip) {
+			return prefix
+		}
+	}
+	return nil
+}
+
+// roomLocked count of ips can still be allocated on eni
+func (e *ENI) roomLocked() int {
+	if !e.prefixDelegation {
+		return e.MaxIPs - len(e.ips) - e.pending
+	}
+	slots := e.MaxIPs - len(e.prefixes)
+	free := -len(e.carved)
+	for _, prefix := range e.prefixes {
+		ones, bits := prefix.Mask.Size()
+		free += 1 << uint(bits-ones)
+	}
+	for _, ip := range e.ips {
+		if !e.carved[ip.SecAddress.String()] {
+			slots--
+		}
+	}
+	return free + slots*defaultPrefixSize - e.pending
+}
+
+// assignIPs assign ips to eni by ecs, carved from prefixes on prefix delegation
+func (e *ENI) assignIPs(ecs aliyun.ECS, count int) ([]net.IP, error) {
+	e.lock.Lock()
+	prefixDelegation := e.prefixDelegation
+	e.lock.Unlock()
+	if !prefixDelegation {
+		return ecs.AssignNIPsForENI(e.ENI.ID, count)
+	}
+
+	ips, err := e.carveIPs(ecs, count)
+	if aliyun.IsPrefixUnsupported(err) {
+		logrus.Warnf("prefix not supported on eni %s, fallback to secondary ips: %v", e.ENI.ID, err)
+		e.lock.Lock()
+		e.prefixDelegation = false
+		e.lock.Unlock()
+		var secondary []net.IP
+		secondary, err = ecs.AssignNIPsForENI(e.ENI.ID, count-len(ips))
+		ips = append(ips, secondary...)
+	}
+	return ips, err
+}
+
+// carveIPs carve ips from free addresses of prefixes, assign new prefix if not enough
+func (e *ENI) carveIPs(ecs aliyun.ECS, count int) ([]net.IP, error) {
+	var ips []net.IP
+	for {
+		e.lock.Lock()
+		for _, prefix := range e.prefixes {
+			for _, ip := range prefixIPs(prefix) {
+				if len(ips) >= count {
+					break
+				}
+				if !e.carved[ip.String()] {
+					e.carved[ip.String()] = true
+					ips = append(ips, ip)
+				}
+			}
+		}
+		e.lock.Unlock()
+		if len(ips) >= count {
+			return ips, nil
+		}
+
+		prefix, err := ecs.AssignPrefixForENI(e.ENI.ID)
+		if err != nil {
+			return ips, err
+		}
+		logrus.Infof("assigned prefix %s to eni %s", prefix, e.ENI.ID)
+		e.lock.Lock()
+		e.prefixes = append(e.prefixes, prefix)
+		e.lock.Unlock()
+	}
+}
+
+// releaseCarvedIP return ip to free addresses of prefix, prefix unassigned if no ip carved from it
+func (f *eniIPFactory) releaseCarvedIP(eni *ENI, ip net.IP) error {
+	eni.removeIP(ip)
+	eni.lock.Lock()
+	prefix := eni.prefixOfLocked(ip)
+	delete(eni.carved, ip.String())
+	if prefix == nil {
+		eni.lock.Unlock()
+		return nil
+	}
+	for _, addr := range prefixIPs(prefix) {
+		if eni.carved[addr.String()] {
+			eni.lock.Unlock()
+			return nil
+		}
+	}
+	// not carve from prefix being unassigned
+	eni.removePrefixLocked(prefix)
+	eni.lock.Unlock()
+
+	if err := f.eniFactory.backgroundECS.UnAssignPrefixForENI(eni.ENI.ID, prefix); err != nil {
+		logrus.Warnf("error unassign prefix %s of eni %s, keep it for reuse: %v", prefix, eni.ENI.ID, err)
+		eni.lock.Lock()
+		eni.prefixes = append(eni.prefixes, prefix)
+		eni.lock.Unlock()
+	}
+	return nil
+}
+
+func (e *ENI) removePrefixLocked(prefix *net.IPNet) {
+	for i, p := range e.prefixes {
+		if p.String() == prefix.String() {
+			e.prefixes = append(e.prefixes[:i], e.prefixes[i+1:]...)
+			return
+		}
+	}
+}
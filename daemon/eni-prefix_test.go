@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixIPs(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("192.168.0.240/28")
+	ips := prefixIPs(prefix)
+	assert.Len(t, ips, 16)
+	assert.Equal(t, "192.168.0.240", ips[0].String())
+	assert.Equal(t, "192.168.0.255", ips[15].String())
+}
+
+func TestRoomWithPrefix(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("192.168.0.16/28")
+	eni := &ENI{
+		ENI:              &types.ENI{MaxIPs: 3},
+		prefixDelegation: true,
+		prefixes:         []*net.IPNet{prefix},
+		carved:           map[string]bool{"192.168.0.16": true},
+	}
+	eni.ips = []*ENIIP{
+		{ENIIP: &types.ENIIP{SecAddress: net.ParseIP("192.168.0.2")}},
+		{ENIIP: &types.ENIIP{SecAddress: net.ParseIP("192.168.0.16")}},
+	}
+	// 15 free ips of prefix, one slot for new prefix
+	assert.Equal(t, 15+defaultPrefixSize, eni.roomLocked())
+
+	eni.prefixDelegation = false
+	assert.Equal(t, 1, eni.roomLocked())
+}
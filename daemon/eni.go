@@ -1,24 +1,37 @@
 package daemon
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/AliyunContainerService/terway/deviceplugin"
+	"github.com/AliyunContainerService/terway/pkg/defaults"
+	"github.com/AliyunContainerService/terway/pkg/fault"
 	"github.com/AliyunContainerService/terway/pkg/pool"
 	"github.com/AliyunContainerService/terway/types"
 	//"github.com/AliyunContainerService/terway/pkg/storage"
 	"github.com/AliyunContainerService/terway/pkg/aliyun"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 type eniResourceManager struct {
-	pool pool.ObjectPool
-	ecs  aliyun.ECS
+	pool          pool.ObjectPool
+	ecs           aliyun.ECS
+	releasePolicy string
+	// devicePlugin advertise eni devices for scheduling, correlates device allocations with enis of pods
+	devicePlugin *deviceplugin.EniDevicePlugin
+	reconciler   *poolReconciler
 }
 
-func newENIResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, allocatedResource []string) (ResourceManager, error) {
+func newENIResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, allocatedResource []string, partition *eniPartition) (ResourceManager, error) {
 	factory, err := newENIFactory(poolConfig, ecs)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error create ENI factory")
 	}
+	factory.partition = partition
+	factory.exclusive = true
 
 	capacity, err := ecs.GetInstanceMaxENI(poolConfig.InstanceID)
 	if err != nil {
@@ -26,6 +39,9 @@ func newENIResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, allocat
 	}
 
 	capacity = int(float64(capacity)*poolConfig.EniCapRatio) + poolConfig.EniCapShift - 1
+	if partition != nil && partition.exclusiveENIs < capacity {
+		capacity = partition.exclusiveENIs
+	}
 	if poolConfig.MaxPoolSize > capacity {
 		poolConfig.MaxPoolSize = capacity
 	}
@@ -35,7 +51,7 @@ func newENIResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, allocat
 		Capacity: capacity,
 		Factory:  factory,
 		Initializer: func(holder pool.ResourceHolder) error {
-			enis, err := ecs.GetAttachedENIs(poolConfig.InstanceID, false)
+			enis, err := factory.attachedENIs(ecs)
 			if err != nil {
 				return errors.Wrapf(err, "error get attach ENI on pool init")
 			}
@@ -52,6 +68,11 @@ func newENIResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, allocat
 			}
 			return nil
 		},
+		QuarantinePeriod: poolConfig.QuarantinePeriod,
+		Denied:           poolConfig.DeniedResource,
+		DrainRate:        poolConfig.DrainRate,
+		ParallelCreates:  poolConfig.ParallelCreates,
+		Throttle:         poolConfig.Throttle,
 	}
 
 	//init deviceplugin for ENI
@@ -66,20 +87,51 @@ func newENIResourceManager(poolConfig *types.PoolConfig, ecs aliyun.ECS, allocat
 		return nil, err
 	}
 	return &eniResourceManager{
-		pool: pool,
-		ecs:  ecs,
+		pool:          pool,
+		ecs:           ecs,
+		releasePolicy: poolConfig.ReleasePolicy[types.ResourceTypeENI],
+		devicePlugin:  dp,
+		reconciler: &poolReconciler{
+			resType:   types.ResourceTypeENI,
+			pool:      pool,
+			listCloud: factory.cloudView,
+		},
 	}, nil
 }
 
+func (m *eniResourceManager) poolReconciler() *poolReconciler {
+	return m.reconciler
+}
+
 func (m *eniResourceManager) Allocate(ctx *networkContext, prefer string) (types.NetworkResource, error) {
-	return m.pool.Acquire(ctx, prefer)
+	res, err := m.pool.Acquire(ctx, prefer)
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := m.devicePlugin.Bind(podInfoKey(ctx.pod.Namespace, ctx.pod.Name), res.GetResourceID()); ok {
+		ctx.Log().Infof("eni %s bound to device %s", res.GetResourceID(), id)
+	} else {
+		ctx.Log().Warnf("no %s device allocated for pod, eni %s takes a free device away", deviceplugin.DefaultResourceName, res.GetResourceID())
+	}
+	return res, nil
+}
+
+// Transfer keep eni of pod in pool as inuse, the device bound to pod unchanged
+func (m *eniResourceManager) Transfer(ctx *networkContext, fromSandbox, resID string) (types.NetworkResource, error) {
+	return m.pool.Transfer(resID)
 }
 
 func (m *eniResourceManager) Release(context *networkContext, resID string) error {
-	if context != nil && context.pod != nil {
-		return m.pool.ReleaseWithReverse(resID, context.pod.IPStickTime)
+	err := releaseToPool(m.pool, m.releasePolicy, context, resID)
+	if err == nil || err == pool.ErrInvalidState {
+		m.devicePlugin.Unbind(resID)
 	}
-	return m.pool.Release(resID)
+	return err
+}
+
+// deviceBindings eni devices bound to enis of pods
+func (m *eniResourceManager) deviceBindings() []deviceplugin.Binding {
+	return m.devicePlugin.Bindings()
 }
 
 func (m *eniResourceManager) GarbageCollection(inUseSet map[string]interface{}, expireResSet map[string]interface{}) error {
@@ -94,11 +146,45 @@ func (m *eniResourceManager) GarbageCollection(inUseSet map[string]interface{},
 	return nil
 }
 
+func (m *eniResourceManager) GetResourceIDs() []string {
+	return m.pool.GetResourceIDs()
+}
+
+func (m *eniResourceManager) Stats() pool.Stats {
+	return m.pool.Stats()
+}
+
+func (m *eniResourceManager) Inventory() []pool.InventoryItem {
+	return m.pool.Inventory()
+}
+
+func (m *eniResourceManager) Prewarm(count int) {
+	m.pool.Prewarm(count)
+}
+
+func (m *eniResourceManager) Drain() (int, error) {
+	return m.pool.Drain()
+}
+
 type eniFactory struct {
-	switches      []string
+	switches []string
+	// overflowSwitches tried once switches run out of ips
+	overflowSwitches []string
+	// autoCreator create vswitches once all switches run out of ips, nil if disabled
+	autoCreator *vSwitchAutoCreator
+	// freeIPs free ips of switches for selection policy most free, nil if ordered
+	freeIPs       *vSwitchFreeIPs
 	securityGroup string
 	instanceID    string
 	ecs           aliyun.ECS
+	// backgroundECS for releasing resources, yield openapi quota to allocation for pods
+	backgroundECS aliyun.ECS
+	// tags of enis created, nil to not tag
+	tags map[string]string
+	// partition enis of instance shared with pool of the other network mode in hybrid mode, nil if not hybrid
+	partition *eniPartition
+	// exclusive whether enis created for pods exclusively, recorded in partition
+	exclusive bool
 }
 
 func newENIFactory(poolConfig *types.PoolConfig, ecs aliyun.ECS) (*eniFactory, error) {
@@ -109,20 +195,200 @@ func newENIFactory(poolConfig *types.PoolConfig, ecs aliyun.ECS) (*eniFactory, e
 		}
 		poolConfig.SecurityGroup = securityGroup
 	}
+
+	switches := zoneVSwitches(ecs, poolConfig.VSwitch, poolConfig.Zone)
+	if len(switches) == 0 {
+		return nil, fmt.Errorf("no vswitch available in zone %s: %v", poolConfig.Zone, poolConfig.VSwitch)
+	}
+	autoCreator, err := newVSwitchAutoCreator(poolConfig, ecs)
+	if err != nil {
+		return nil, err
+	}
+
+	var freeIPs *vSwitchFreeIPs
+	if poolConfig.VSwitchSelectionPolicy == defaults.VSwitchSelectionMostFree {
+		freeIPs = newVSwitchFreeIPs(ecs.WithPriority(aliyun.PriorityBackground), vSwitchFreeIPsTTL)
+	}
 	return &eniFactory{
-		switches:      poolConfig.VSwitch,
-		securityGroup: poolConfig.SecurityGroup,
-		instanceID:    poolConfig.InstanceID,
-		ecs:           ecs,
+		switches:         switches,
+		overflowSwitches: zoneVSwitches(ecs, poolConfig.OverflowVSwitch, poolConfig.Zone),
+		autoCreator:      autoCreator,
+		freeIPs:          freeIPs,
+		securityGroup:    poolConfig.SecurityGroup,
+		instanceID:       poolConfig.InstanceID,
+		ecs:              ecs,
+		backgroundECS:    ecs.WithPriority(aliyun.PriorityBackground),
+		tags:             poolConfig.ENITags,
 	}, nil
 }
 
+// zoneVSwitches filter out vswitches not in the zone of node
+func zoneVSwitches(ecs aliyun.ECS, vSwitches []string, zone string) []string {
+	var switches []string
+	for _, vSwitch := range vSwitches {
+		vSwitchZone, _, err := ecs.DescribeVSwitch(vSwitch)
+		if err != nil {
+			log.Warnf("error describe vswitch %s, keep it: %v", vSwitch, err)
+			switches = append(switches, vSwitch)
+			continue
+		}
+		if zone != "" && vSwitchZone != zone {
+			log.Warnf("vswitch %s in zone %s, not the zone %s of node, ignore it", vSwitch, vSwitchZone, zone)
+			continue
+		}
+		switches = append(switches, vSwitch)
+	}
+	return switches
+}
+
+// cloudView return enis attached to instance in cloud, the ones created by terway adoptable
+func (f *eniFactory) cloudView() (*cloudView, error) {
+	enis, err := f.attachedENIs(f.backgroundECS)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error get attached enis")
+	}
+	owned, err := ownedENIs(f.backgroundECS, f.instanceID)
+	if err != nil {
+		return nil, err
+	}
+	view := newCloudView()
+	for _, eni := range enis {
+		view.present[eni.GetResourceID()] = true
+		view.enis[eni.ID] = true
+		if owned[eni.ID] {
+			view.adoptable = append(view.adoptable, eni)
+		}
+	}
+	return view, nil
+}
+
+// attachedENIs return enis attached to instance except main eni, only the ones of the pool if partitioned
+func (f *eniFactory) attachedENIs(ecs aliyun.ECS) ([]*types.ENI, error) {
+	enis, err := ecs.GetAttachedENIs(f.instanceID, false)
+	if err != nil {
+		return nil, err
+	}
+	return f.partition.filter(enis, f.exclusive), nil
+}
+
+// candidateVSwitches return vswitches in order to try by selection policy
+func (f *eniFactory) candidateVSwitches() []string {
+	if f.freeIPs == nil || len(f.switches) < 2 {
+		return f.switches
+	}
+	now := time.Now()
+	freeIPs := make(map[string]int)
+	for _, vSwitch := range f.switches {
+		count, err := f.freeIPs.get(vSwitch, now)
+		if err != nil {
+			log.Warnf("error describe vswitch %s for free ips: %v", vSwitch, err)
+			continue
+		}
+		freeIPs[vSwitch] = count
+	}
+	switches := append([]string{}, f.switches...)
+	sort.SliceStable(switches, func(i, j int) bool {
+		return freeIPs[switches[i]] > freeIPs[switches[j]]
+	})
+	return switches
+}
+
 func (f *eniFactory) Create() (types.NetworkResource, error) {
-	//TODO support multi vswitch
-	return f.ecs.AllocateENI(f.switches[0], f.securityGroup, f.instanceID)
+	return f.create(f.ecs)
+}
+
+// backgroundENIFactory view of eni factory creating enis in background priority of openapi calls
+type backgroundENIFactory struct {
+	*eniFactory
+}
+
+func (f backgroundENIFactory) Create() (types.NetworkResource, error) {
+	return f.create(f.backgroundECS)
+}
+
+// Background return view of factory creating enis in background, for idle enis of pool
+func (f *eniFactory) Background() pool.ObjectFactory {
+	return backgroundENIFactory{f}
+}
+
+// create allocate eni by ecs of the priority
+func (f *eniFactory) create(ecs aliyun.ECS) (types.NetworkResource, error) {
+	err := fault.Inject(fault.Point(types.ResourceTypeENI, fault.OpCreate))
+	if err != nil {
+		return nil, err
+	}
+	eni, err := f.allocateENI(ecs, f.candidateVSwitches())
+	if aliyun.IsIPNotEnough(err) && len(f.overflowSwitches) > 0 {
+		log.Warnf("vswitches configured have no available ip, fallback to overflow vswitches %v", f.overflowSwitches)
+		eni, err = f.allocateENI(ecs, f.overflowSwitches)
+	}
+	if aliyun.IsIPNotEnough(err) && f.autoCreator != nil {
+		switches, createErr := f.autoCreator.vSwitches()
+		if createErr != nil {
+			return nil, errors.Wrapf(err, "no vswitch has available ip, error auto create vswitch: %v", createErr)
+		}
+		eni, err = f.allocateENI(ecs, switches)
+	}
+	if aliyun.IsIPNotEnough(err) {
+		return nil, errors.Wrapf(err, "no vswitch has available ip")
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.tagENI(eni)
+	return eni, nil
+}
+
+// allocateENI allocate eni by ecs in the first vswitch of switches with available ip
+func (f *eniFactory) allocateENI(ecs aliyun.ECS, switches []string) (*types.ENI, error) {
+	var err error
+	for _, vSwitch := range switches {
+		var eni *types.ENI
+		if err = fault.Inject(fault.Point(types.ResourceTypeENI, fault.OpAttach)); err == nil {
+			eni, err = ecs.AllocateENI(vSwitch, f.securityGroup, f.instanceID)
+		}
+		if err == nil && f.exclusive && f.partition != nil {
+			if err = f.partition.add(eni.MAC); err != nil {
+				if freeErr := f.backgroundECS.FreeENI(eni.ID, f.instanceID); freeErr != nil {
+					log.Warnf("error free eni %s failed to partition: %v", eni.ID, freeErr)
+				}
+				return nil, err
+			}
+		}
+		if err == nil {
+			return eni, nil
+		}
+		if !aliyun.IsIPNotEnough(err) {
+			return nil, err
+		}
+		if f.freeIPs != nil {
+			f.freeIPs.exhausted(vSwitch, time.Now())
+		}
+		log.Warnf("vswitch %s has no available ip, fallback to next: %v", vSwitch, err)
+	}
+	return nil, err
+}
+
+// tagENI tag eni created, failure left for reconcile of tags
+func (f *eniFactory) tagENI(eni *types.ENI) {
+	if f.tags == nil {
+		return
+	}
+	if err := f.backgroundECS.TagENI(eni.ID, f.tags); err != nil {
+		log.Warnf("error tag eni %s, retry on reconcile: %v", eni.ID, err)
+	}
 }
 
 func (f *eniFactory) Dispose(resource types.NetworkResource) error {
 	eni := resource.(*types.ENI)
-	return f.ecs.FreeENI(eni.ID, f.instanceID)
+	if err := fault.Inject(fault.Point(types.ResourceTypeENI, fault.OpDispose)); err != nil {
+		return err
+	}
+	if err := f.backgroundECS.FreeENI(eni.ID, f.instanceID); err != nil {
+		return err
+	}
+	if err := f.partition.remove(eni.MAC); err != nil {
+		log.Warnf("error remove eni %s freed from partition: %v", eni.ID, err)
+	}
+	return nil
 }
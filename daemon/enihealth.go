@@ -0,0 +1,149 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// eniStatusInUse status of eni attached to instance in cloud
+	eniStatusInUse = "InUse"
+	// podAnnotationENIUnhealthy reason of eni used by pod failed, set by daemon until the eni recovered
+	podAnnotationENIUnhealthy = "k8s.aliyun.com/eni-unhealthy"
+)
+
+// eniHealthMonitor check link of enis on host and status of enis in cloud periodically in eni multi ip mode,
+// ips of failed enis are not allocated and pods using them are marked until the eni recovered
+type eniHealthMonitor struct {
+	ecs        aliyun.ECS
+	instanceID string
+	period     time.Duration
+	// managedENIs return macs of enis in pools
+	managedENIs func() map[string]string
+	// checkLink check link of eni mac exists, up and with carrier on host
+	checkLink func(mac string) error
+	// onChange called with mac of eni newly failed and the reason, or recovered with empty reason
+	onChange func(mac, reason string)
+
+	lock sync.RWMutex
+	// failed mac of failed eni to reason
+	failed map[string]string
+}
+
+// healthy return whether eni of mac passed the last health check
+func (m *eniHealthMonitor) healthy(mac string) bool {
+	if m == nil {
+		return true
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	_, failed := m.failed[mac]
+	return !failed
+}
+
+// denied return whether resource is ip of failed eni
+func (m *eniHealthMonitor) denied(res types.NetworkResource) bool {
+	ip, ok := res.(*types.ENIIP)
+	return ok && ip.Eni != nil && !m.healthy(ip.Eni.MAC)
+}
+
+// check return macs of failed enis in pools to reason
+func (m *eniHealthMonitor) check() (map[string]string, error) {
+	enis, err := m.ecs.ListOwnedENIs(m.instanceID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list enis of instance")
+	}
+	failed := make(map[string]string)
+	managed := m.managedENIs()
+	for mac := range managed {
+		if err = m.checkLink(mac); err != nil {
+			failed[mac] = err.Error()
+		}
+	}
+	for _, eni := range enis {
+		if _, ok := managed[eni.MAC]; !ok {
+			continue
+		}
+		if eni.Status != eniStatusInUse || eni.InstanceID != m.instanceID {
+			failed[eni.MAC] = fmt.Sprintf("eni %s in status %s of instance %q in cloud", eni.ID, eni.Status, eni.InstanceID)
+		}
+	}
+	return failed, nil
+}
+
+// update record failed enis of check, onChange called for enis newly failed or recovered
+func (m *eniHealthMonitor) update(failed map[string]string) {
+	m.lock.Lock()
+	previous := m.failed
+	m.failed = failed
+	m.lock.Unlock()
+	metric.ENIUnhealthy.Set(float64(len(failed)))
+
+	var changed []string
+	for mac := range failed {
+		if _, ok := previous[mac]; !ok {
+			changed = append(changed, mac)
+		}
+	}
+	for mac := range previous {
+		if _, ok := failed[mac]; !ok {
+			changed = append(changed, mac)
+		}
+	}
+	sort.Strings(changed)
+	for _, mac := range changed {
+		if reason, ok := failed[mac]; ok {
+			log.Errorf("eni %s failed health check, ips not allocated until recovered: %s", mac, reason)
+		} else {
+			log.Infof("eni %s recovered", mac)
+		}
+		m.onChange(mac, failed[mac])
+	}
+}
+
+func (m *eniHealthMonitor) start() {
+	go func() {
+		for {
+			time.Sleep(m.period)
+			failed, err := m.check()
+			if err != nil {
+				log.Warnf("error check health of enis: %v", err)
+				continue
+			}
+			m.update(failed)
+		}
+	}()
+}
+
+// markENIPods mark pods using ips of eni with reason the eni failed, or unmark pods if reason empty
+func (networkService *networkService) markENIPods(mac, reason string) {
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		log.Warnf("error list resource db for pods of eni %s: %v", mac, err)
+		return
+	}
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil {
+			continue
+		}
+		for _, res := range resRelate.GetResourceItemByType(types.ResourceTypeENIIP) {
+			if !strings.HasPrefix(res.ID, mac+".") {
+				continue
+			}
+			if err = networkService.k8s.MarkPodENIHealth(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name, reason); err != nil {
+				log.Warnf("error mark pod %s/%s of eni %s: %v", resRelate.PodInfo.Namespace, resRelate.PodInfo.Name, mac, err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type healthECS struct {
+	aliyun.ECS
+	owned []*aliyun.InstanceENI
+}
+
+func (e *healthECS) ListOwnedENIs(instanceID string) ([]*aliyun.InstanceENI, error) {
+	return e.owned, nil
+}
+
+func TestENIHealthMonitor(t *testing.T) {
+	var disabled *eniHealthMonitor
+	assert.True(t, disabled.healthy("mac-1"))
+
+	ecs := &healthECS{owned: []*aliyun.InstanceENI{
+		{ID: "eni-1", InstanceID: "i-1", MAC: "mac-1", Status: eniStatusInUse},
+		{ID: "eni-2", InstanceID: "i-1", MAC: "mac-2", Status: eniStatusInUse},
+		{ID: "eni-3", MAC: "mac-3", Status: "Available"},
+	}}
+	linkDown := map[string]bool{}
+	changes := map[string]string{}
+	m := &eniHealthMonitor{
+		ecs:        ecs,
+		instanceID: "i-1",
+		managedENIs: func() map[string]string {
+			return map[string]string{"mac-1": "", "mac-2": ""}
+		},
+		checkLink: func(mac string) error {
+			if linkDown[mac] {
+				return fmt.Errorf("link of %s is down", mac)
+			}
+			return nil
+		},
+		onChange: func(mac, reason string) {
+			changes[mac] = reason
+		},
+	}
+
+	failed, err := m.check()
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+
+	// eni detached in cloud and link down on host
+	ecs.owned[1].Status = "Available"
+	ecs.owned[1].InstanceID = ""
+	linkDown["mac-1"] = true
+	failed, err = m.check()
+	assert.NoError(t, err)
+	m.update(failed)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, "link of mac-1 is down", changes["mac-1"])
+	assert.False(t, m.healthy("mac-2"))
+	assert.True(t, m.denied(&types.ENIIP{Eni: &types.ENI{MAC: "mac-1"}}))
+	assert.False(t, m.denied(&types.ENI{MAC: "mac-1"}))
+
+	// recovered eni reported once
+	changes = map[string]string{}
+	linkDown["mac-1"] = false
+	failed, err = m.check()
+	assert.NoError(t, err)
+	m.update(failed)
+	assert.Equal(t, map[string]string{"mac-1": ""}, changes)
+	assert.True(t, m.healthy("mac-1"))
+}
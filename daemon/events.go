@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// types of resource events
+const (
+	eventTypeAllocate = "Allocate"
+	eventTypeRelease  = "Release"
+	eventTypeGC       = "GC"
+)
+
+// watchBufferSize events buffered for watcher, the watcher not keeping up is closed rather than missing events
+const watchBufferSize = 256
+
+// resourceEvents broadcast allocations, releases and garbage collections of daemon to watchers
+type resourceEvents struct {
+	lock     sync.Mutex
+	watchers map[*eventWatcher]bool
+}
+
+type eventWatcher struct {
+	types  map[string]bool
+	filter string
+	ch     chan *rpc.ResourceEvent
+	// overflowed closed for events not consumed in time
+	overflowed bool
+}
+
+func newResourceEvents() *resourceEvents {
+	return &resourceEvents{watchers: make(map[*eventWatcher]bool)}
+}
+
+// watch add watcher of events of types matching filter, all types if empty
+func (e *resourceEvents) watch(types []string, filter string) *eventWatcher {
+	w := &eventWatcher{
+		types:  make(map[string]bool, len(types)),
+		filter: filter,
+		ch:     make(chan *rpc.ResourceEvent, watchBufferSize),
+	}
+	for _, t := range types {
+		w.types[t] = true
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.watchers[w] = true
+	return w
+}
+
+// stop remove watcher, its channel closed
+func (e *resourceEvents) stop(w *eventWatcher) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.watchers[w] {
+		delete(e.watchers, w)
+		close(w.ch)
+	}
+}
+
+// watched whether any watcher, events not built if none
+func (e *resourceEvents) watched() bool {
+	if e == nil {
+		return false
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return len(e.watchers) > 0
+}
+
+// publish send events to watchers matching, never blocks
+func (e *resourceEvents) publish(events ...*rpc.ResourceEvent) {
+	if e == nil {
+		return
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for w := range e.watchers {
+		for _, event := range events {
+			if !w.match(event) {
+				continue
+			}
+			select {
+			case w.ch <- event:
+			default:
+				w.overflowed = true
+				delete(e.watchers, w)
+				close(w.ch)
+			}
+			if w.overflowed {
+				break
+			}
+		}
+	}
+}
+
+func (w *eventWatcher) match(event *rpc.ResourceEvent) bool {
+	if len(w.types) > 0 && !w.types[event.Type] {
+		return false
+	}
+	return w.filter == "" || strings.Contains(event.ResourceID, w.filter) ||
+		strings.Contains(podInfoKey(event.PodNamespace, event.PodName), w.filter)
+}
+
+// podEvents events of resources of pod, one event without resource if none, eg: allocation failed
+func podEvents(eventType string, res *PodResources, err error) []*rpc.ResourceEvent {
+	base := rpc.ResourceEvent{
+		Type:      eventType,
+		Timestamp: time.Now().Unix(),
+		SandboxID: res.SandboxID,
+	}
+	if res.PodInfo != nil {
+		base.PodNamespace, base.PodName = res.PodInfo.Namespace, res.PodInfo.Name
+	}
+	if err != nil {
+		base.Error = err.Error()
+	}
+	if len(res.Resources) == 0 {
+		return []*rpc.ResourceEvent{&base}
+	}
+	events := make([]*rpc.ResourceEvent, 0, len(res.Resources))
+	for _, item := range res.Resources {
+		event := base
+		event.ResourceType, event.ResourceID = item.Type, item.ID
+		events = append(events, &event)
+	}
+	return events
+}
+
+// publishPodEvents publish events of resources of pod if watched
+func (networkService *networkService) publishPodEvents(eventType string, res *PodResources, err error) {
+	if networkService.events.watched() {
+		networkService.events.publish(podEvents(eventType, res, err)...)
+	}
+}
+
+// WatchEvents stream events of allocations, releases and garbage collections of daemon until the client gone
+func (networkService *networkService) WatchEvents(r *rpc.WatchEventsRequest, stream rpc.TerwayBackend_WatchEventsServer) error {
+	log.Infof("WatchEvents request: %+v", r)
+	w := networkService.events.watch(r.Types, r.Filter)
+	defer networkService.events.stop(w)
+	for {
+		select {
+		case event, ok := <-w.ch:
+			if !ok {
+				return status.Errorf(codes.ResourceExhausted, "watcher fell behind over %d events", watchBufferSize)
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceEvents(t *testing.T) {
+	var none *resourceEvents
+	assert.False(t, none.watched())
+	none.publish(&rpc.ResourceEvent{Type: eventTypeAllocate})
+
+	events := newResourceEvents()
+	all := events.watch(nil, "")
+	releases := events.watch([]string{eventTypeRelease}, "default/pod-1")
+	assert.True(t, events.watched())
+
+	res := &PodResources{
+		PodInfo:   &podInfo{Namespace: "default", Name: "pod-1"},
+		SandboxID: "sandbox-1",
+		Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: "mac-1.10.0.0.2"}},
+	}
+	events.publish(podEvents(eventTypeAllocate, res, nil)...)
+	events.publish(podEvents(eventTypeRelease, res, nil)...)
+	// failed allocation without resources
+	events.publish(podEvents(eventTypeAllocate, &PodResources{PodInfo: &podInfo{Namespace: "default", Name: "pod-2"}},
+		errors.New("no ip"))...)
+
+	assert.Len(t, all.ch, 3)
+	event := <-all.ch
+	assert.Equal(t, eventTypeAllocate, event.Type)
+	assert.Equal(t, "mac-1.10.0.0.2", event.ResourceID)
+	assert.Equal(t, "sandbox-1", event.SandboxID)
+	<-all.ch
+	event = <-all.ch
+	assert.Equal(t, "pod-2", event.PodName)
+	assert.Equal(t, "no ip", event.Error)
+	assert.Len(t, releases.ch, 1)
+
+	// watcher not keeping up closed
+	for i := 0; i <= watchBufferSize; i++ {
+		events.publish(podEvents(eventTypeRelease, res, nil)...)
+	}
+	assert.True(t, releases.overflowed)
+	assert.True(t, all.overflowed)
+	assert.False(t, events.watched())
+	events.stop(all)
+}
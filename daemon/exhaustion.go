@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/errcode"
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	exhaustionCheckPeriod = 30 * time.Second
+	// exhaustionErrorWindow allocations failed by resources exhausted within the window keep node exhausted,
+	// eg: vswitches out of ips before pools at capacity
+	exhaustionErrorWindow = 5 * time.Minute
+
+	reasonNetworkExhausted = "NetworkResourcesExhausted"
+	reasonNetworkAvailable = "NetworkResourcesAvailable"
+)
+
+// exhaustionMonitor set condition of node while network resources of pools on node exhausted, cleared once
+// resources available again, so scheduler and autoscaler react to exhaustion, eg: NetworkUnavailable tainted
+type exhaustionMonitor struct {
+	client        kubernetes.Interface
+	nodeName      string
+	conditionType corev1.NodeConditionType
+	// getStats stats of pools by resource type
+	getStats func() map[string]pool.Stats
+
+	lock sync.Mutex
+	// lastExhausted time of last allocation failed by resources exhausted
+	lastExhausted time.Time
+	// exhausted status of condition set on node, nil before set
+	exhausted *bool
+}
+
+// observe record allocation failed by resources exhausted
+func (m *exhaustionMonitor) observe(err error) {
+	if m == nil || err == nil {
+		return
+	}
+	switch errorCode(err) {
+	case errcode.QuotaExceeded, errcode.VSwitchExhausted:
+		m.lock.Lock()
+		m.lastExhausted = time.Now()
+		m.lock.Unlock()
+	}
+}
+
+// networkExhausted return whether no pool can serve pods, neither idle resources nor room to create, or
+// allocations failed by resources exhausted recently, with message of pools
+func networkExhausted(stats map[string]pool.Stats, recentlyFailed bool) (bool, string) {
+	var (
+		exhausted = false
+		pools     []string
+	)
+	for resType, s := range stats {
+		// not pooled, eg: veth
+		if s.Capacity == 0 {
+			continue
+		}
+		pools = append(pools, fmt.Sprintf("%s inuse %d/%d, idle %d", resType, s.Inuse+s.Quarantine, s.Capacity, s.Idle))
+		if s.Idle > 0 || (s.Inuse+s.Quarantine < s.Capacity && !recentlyFailed) {
+			return false, ""
+		}
+		exhausted = true
+	}
+	sort.Strings(pools)
+	return exhausted, strings.Join(pools, "; ")
+}
+
+// poolsStats return stats of pools of network service by resource type
+func (networkService *networkService) poolsStats() map[string]pool.Stats {
+	networkService.RLock()
+	defer networkService.RUnlock()
+	stats := make(map[string]pool.Stats, len(networkService.mgrForResource))
+	for resType, mgr := range networkService.mgrForResource {
+		stats[resType] = mgr.Stats()
+	}
+	return stats
+}
+
+// setNodeCondition set condition of type in conditions, transition time kept if status unchanged
+func setNodeCondition(conditions []corev1.NodeCondition, condition corev1.NodeCondition) []corev1.NodeCondition {
+	for i := range conditions {
+		if conditions[i].Type != condition.Type {
+			continue
+		}
+		if conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = conditions[i].LastTransitionTime
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	return append(conditions, condition)
+}
+
+// sync set condition of node if exhaustion changed
+func (m *exhaustionMonitor) sync() error {
+	m.lock.Lock()
+	recentlyFailed := time.Since(m.lastExhausted) < exhaustionErrorWindow
+	m.lock.Unlock()
+	exhausted, message := networkExhausted(m.getStats(), recentlyFailed)
+	if m.exhausted != nil && *m.exhausted == exhausted {
+		return nil
+	}
+
+	now := metav1.Now()
+	condition := corev1.NodeCondition{
+		Type:               m.conditionType,
+		Status:             corev1.ConditionFalse,
+		Reason:             reasonNetworkAvailable,
+		Message:            "network resources of terway available",
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+	}
+	if exhausted {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = reasonNetworkExhausted
+		condition.Message = "network resources of terway exhausted: " + message
+	}
+	node, err := m.client.CoreV1().Nodes().Get(m.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "error get node %s", m.nodeName)
+	}
+	node.Status.Conditions = setNodeCondition(node.Status.Conditions, condition)
+	if _, err = m.client.CoreV1().Nodes().UpdateStatus(node); err != nil {
+		return errors.Wrapf(err, "error update condition %s of node %s", m.conditionType, m.nodeName)
+	}
+	if exhausted {
+		log.Warnf("network resources exhausted, condition %s of node set: %s", m.conditionType, message)
+	} else {
+		log.Infof("network resources available, condition %s of node cleared", m.conditionType)
+	}
+	m.exhausted = &exhausted
+	return nil
+}
+
+// start periodically sync condition of node
+func (m *exhaustionMonitor) start() {
+	go func() {
+		for {
+			if err := m.sync(); err != nil {
+				log.Warnf("error sync exhaustion condition of node: %v", err)
+			}
+			time.Sleep(exhaustionCheckPeriod)
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNetworkExhausted(t *testing.T) {
+	exhausted, _ := networkExhausted(map[string]pool.Stats{"veth": {}}, true)
+	assert.False(t, exhausted)
+
+	exhausted, message := networkExhausted(map[string]pool.Stats{"eniIp": {Capacity: 30, Inuse: 28, Quarantine: 2}}, false)
+	assert.True(t, exhausted)
+	assert.Equal(t, "eniIp inuse 30/30, idle 0", message)
+
+	// room to create unless vswitches exhausted recently
+	exhausted, _ = networkExhausted(map[string]pool.Stats{"eniIp": {Capacity: 30, Inuse: 10}}, false)
+	assert.False(t, exhausted)
+	exhausted, _ = networkExhausted(map[string]pool.Stats{"eniIp": {Capacity: 30, Inuse: 10}}, true)
+	assert.True(t, exhausted)
+
+	// pods of either pool still served
+	exhausted, _ = networkExhausted(map[string]pool.Stats{
+		"eni":   {Capacity: 2, Inuse: 2},
+		"eniIp": {Capacity: 20, Inuse: 19, Idle: 1},
+	}, false)
+	assert.False(t, exhausted)
+}
+
+func TestSetNodeCondition(t *testing.T) {
+	before := metav1.NewTime(time.Now().Add(-time.Hour))
+	conditions := []corev1.NodeCondition{
+		{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+		{Type: corev1.NodeNetworkUnavailable, Status: corev1.ConditionFalse, LastTransitionTime: before},
+	}
+	now := metav1.Now()
+	conditions = setNodeCondition(conditions, corev1.NodeCondition{
+		Type: corev1.NodeNetworkUnavailable, Status: corev1.ConditionFalse, Reason: reasonNetworkAvailable, LastTransitionTime: now})
+	assert.Len(t, conditions, 2)
+	assert.Equal(t, before, conditions[1].LastTransitionTime)
+	assert.Equal(t, reasonNetworkAvailable, conditions[1].Reason)
+
+	conditions = setNodeCondition(conditions, corev1.NodeCondition{
+		Type: corev1.NodeNetworkUnavailable, Status: corev1.ConditionTrue, LastTransitionTime: now})
+	assert.Equal(t, now, conditions[1].LastTransitionTime)
+
+	conditions = setNodeCondition(conditions, corev1.NodeCondition{Type: "TerwayNetworkExhausted", Status: corev1.ConditionTrue})
+	assert.Len(t, conditions, 3)
+}
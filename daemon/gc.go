@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	gcCollectorHostPort = "hostport"
+	gcCollectorNetns    = "netns"
+)
+
+// gcRunner run independent garbage collectors concurrently under deadline, eg: of resource managers,
+// host port chains and leaked netns,
+// a collector exceeding the deadline keeps running in background and is skipped in
+// next cycles until it finished, so it never delays other collectors
+type gcRunner struct {
+	timeout time.Duration
+	lock    sync.Mutex
+	running map[string]bool
+}
+
+func newGCRunner(timeout time.Duration) *gcRunner {
+	return &gcRunner{
+		timeout: timeout,
+		running: make(map[string]bool),
+	}
+}
+
+type gcResult struct {
+	name string
+	err  error
+}
+
+// tryStart mark collector running, false if last run not finished
+func (r *gcRunner) tryStart(name string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.running[name] {
+		return false
+	}
+	r.running[name] = true
+	return true
+}
+
+func (r *gcRunner) finish(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.running, name)
+}
+
+// run collectors by name, return errors by name of collectors failed or not finished in deadline
+func (r *gcRunner) run(collectors map[string]func() error) map[string]error {
+	errs := make(map[string]error)
+	results := make(chan gcResult, len(collectors))
+	pending := make(map[string]bool)
+	for name, collect := range collectors {
+		if !r.tryStart(name) {
+			errs[name] = fmt.Errorf("garbage collection of %s from last cycle not finished", name)
+			continue
+		}
+		pending[name] = true
+		go func(name string, collect func() error) {
+			defer r.finish(name)
+			start := time.Now()
+			err := collect()
+			log.Debugf("garbage collection of %s done in %v, err: %v", name, time.Since(start), err)
+			results <- gcResult{name: name, err: err}
+		}(name, collect)
+	}
+
+	deadline := time.NewTimer(r.timeout)
+	defer deadline.Stop()
+	for len(pending) > 0 {
+		select {
+		case result := <-results:
+			delete(pending, result.name)
+			if result.err != nil {
+				errs[result.name] = fmt.Errorf("error do garbage collection for %s: %v", result.name, result.err)
+			}
+		case <-deadline.C:
+			for name := range pending {
+				errs[name] = fmt.Errorf("garbage collection of %s not finished in %v", name, r.timeout)
+			}
+			return errs
+		}
+	}
+	return errs
+}
+
+// resourceCollectors collectors of resource managers with the resources in use and expired of their types
+func resourceCollectors(mgrs map[string]ResourceManager, inUseSet, expireSet map[string]map[string]interface{}) map[string]func() error {
+	collectors := make(map[string]func() error)
+	for resType := range inUseSet {
+		mgr, ok := mgrs[resType]
+		if !ok {
+			continue
+		}
+		inUse, expire := inUseSet[resType], expireSet[resType]
+		collectors[resType] = func() error {
+			return errors.Wrapf(mgr.GarbageCollection(inUse, expire), "inuse: %v, expire: %v", inUse, expire)
+		}
+	}
+	return collectors
+}
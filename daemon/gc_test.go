@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type gcResourceManager struct {
+	ResourceManager
+	gc func() error
+}
+
+func (m *gcResourceManager) GarbageCollection(inUseResList map[string]interface{}, expireResList map[string]interface{}) error {
+	return m.gc()
+}
+
+func TestGCRunner(t *testing.T) {
+	block := make(chan struct{})
+	collected := make(chan string, 2)
+	mgrs := map[string]ResourceManager{
+		"slow": &gcResourceManager{gc: func() error {
+			<-block
+			return nil
+		}},
+		"fast": &gcResourceManager{gc: func() error {
+			collected <- "fast"
+			return nil
+		}},
+		"broken": &gcResourceManager{gc: func() error {
+			return fmt.Errorf("broken")
+		}},
+	}
+	sets := map[string]map[string]interface{}{"slow": {}, "fast": {}, "broken": {}}
+	collectors := resourceCollectors(mgrs, sets, sets)
+	collectors[gcCollectorNetns] = func() error {
+		collected <- gcCollectorNetns
+		return nil
+	}
+
+	runner := newGCRunner(50 * time.Millisecond)
+	errs := runner.run(collectors)
+	assert.Len(t, errs, 2)
+	assert.Contains(t, errs["slow"].Error(), "slow not finished")
+	assert.Contains(t, errs["broken"].Error(), "broken")
+	assert.ElementsMatch(t, []string{"fast", gcCollectorNetns}, []string{<-collected, <-collected})
+
+	// slow collector still running, skipped without blocking the others
+	errs = runner.run(collectors)
+	assert.Contains(t, errs["slow"].Error(), "slow from last cycle not finished")
+	assert.ElementsMatch(t, []string{"fast", gcCollectorNetns}, []string{<-collected, <-collected})
+
+	close(block)
+	for runner.tryStart("slow") == false {
+		time.Sleep(time.Millisecond)
+	}
+	runner.finish("slow")
+	delete(collectors, "broken")
+	assert.Empty(t, runner.run(collectors))
+	assert.False(t, strings.Contains(fmt.Sprint(runner.running), "slow"))
+	<-collected
+	<-collected
+}
+
+func TestGCProtected(t *testing.T) {
+	networkService := &networkService{gcProtection: time.Minute}
+	f := newAllocFlights()
+	f.calls[sandboxKey("default", "allocating", "sandbox-1")] = &allocCall{done: make(chan struct{})}
+	allocating := f.pods()
+	assert.Equal(t, map[string]bool{"default/allocating": true}, allocating)
+
+	relate := func(name string, allocatedAt time.Time) PodResources {
+		res := PodResources{PodInfo: &podInfo{Namespace: "default", Name: name}}
+		if !allocatedAt.IsZero() {
+			res.AllocatedAt = allocatedAt.Unix()
+		}
+		return res
+	}
+	assert.True(t, networkService.gcProtected(relate("allocating", time.Time{}), allocating))
+	assert.True(t, networkService.gcProtected(relate("new", time.Now().Add(-10*time.Second)), allocating))
+	assert.False(t, networkService.gcProtected(relate("old", time.Now().Add(-time.Hour)), allocating))
+	// relations recorded before allocation time tracked
+	assert.False(t, networkService.gcProtected(relate("legacy", time.Time{}), allocating))
+}
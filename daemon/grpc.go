@@ -0,0 +1,192 @@
+package daemon
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/errcode"
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/pkg/tracing"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCConfig configuration of daemon grpc endpoints
+type GRPCConfig struct {
+	// SocketMode file mode of the unix socket
+	SocketMode os.FileMode
+	// SocketGroup group name or gid owning the unix socket, keep unchanged if empty
+	SocketGroup string
+	// TLSListen tcp address of mTLS listener serving read-only rpc, disabled if empty
+	TLSListen       string
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+}
+
+// readOnlyMethods rpc methods allowed on network listener
+var readOnlyMethods = map[string]bool{
+	"/rpc.TerwayBackend/GetIPInfo":            true,
+	"/rpc.TerwayBackend/Handshake":            true,
+	"/rpc.TerwayBackend/GetIPDenyList":        true,
+	"/rpc.TerwayBackend/GetResourceInventory": true,
+	"/rpc.TerwayBackend/WatchEvents":          true,
+	"/rpc.TerwayBackend/SimulateAllocation":   true,
+}
+
+// listenSecureSocket listen unix socket on temporary path, and rename it to socketFilePath after
+// mode and group set, so that the socket never exposed with permissions looser than configured
+func listenSecureSocket(socketFilePath string, cfg *GRPCConfig) (net.Listener, error) {
+	tmpPath := socketFilePath + ".tmp"
+	if err := syscall.Unlink(tmpPath); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	mask := syscall.Umask(0777)
+	l, err := net.Listen("unix", tmpPath)
+	syscall.Umask(mask)
+	if err != nil {
+		return nil, fmt.Errorf("error listen at %s: %v", tmpPath, err)
+	}
+	// path of listener changed by rename, avoid unlink on close
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err = secureSocket(tmpPath, cfg); err != nil {
+		l.Close()
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if err = os.Rename(tmpPath, socketFilePath); err != nil {
+		l.Close()
+		os.Remove(tmpPath)
+		return nil, errors.Wrapf(err, "error rename socket %s to %s", tmpPath, socketFilePath)
+	}
+	return l, nil
+}
+
+// secureSocket set file mode and group of unix socket
+func secureSocket(socketFilePath string, cfg *GRPCConfig) error {
+	if err := os.Chmod(socketFilePath, cfg.SocketMode); err != nil {
+		return errors.Wrapf(err, "error set mode of socket %s", socketFilePath)
+	}
+	if cfg.SocketGroup == "" {
+		return nil
+	}
+	gid, err := strconv.Atoi(cfg.SocketGroup)
+	if err != nil {
+		group, err := user.LookupGroup(cfg.SocketGroup)
+		if err != nil {
+			return errors.Wrapf(err, "error lookup socket group %s", cfg.SocketGroup)
+		}
+		if gid, err = strconv.Atoi(group.Gid); err != nil {
+			return errors.Wrapf(err, "invalid gid of group %s", cfg.SocketGroup)
+		}
+	}
+	return errors.Wrapf(os.Chown(socketFilePath, -1, gid), "error set group of socket %s", socketFilePath)
+}
+
+// readOnlyInterceptor reject rpc methods changing state of daemon
+func readOnlyInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !readOnlyMethods[info.FullMethod] {
+		return nil, status.Errorf(codes.PermissionDenied, "method %s not allowed on network listener", info.FullMethod)
+	}
+	return handler(ctx, req)
+}
+
+// readOnlyStreamInterceptor reject stream rpc methods not read-only, eg: packet capture of pods
+func readOnlyStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !readOnlyMethods[info.FullMethod] {
+		return status.Errorf(codes.PermissionDenied, "method %s not allowed on network listener", info.FullMethod)
+	}
+	return handler(srv, ss)
+}
+
+// errorCode classify error of rpc into terway error code by the errors it wraps
+func errorCode(err error) errcode.Code {
+	switch {
+	case errcode.Is(err, pool.ErrContextDone) || errcode.Is(err, context.DeadlineExceeded) || errcode.Is(err, context.Canceled):
+		return errcode.Timeout
+	case errcode.Is(err, pool.ErrNoAvailableResource) || aliyun.IsQuotaExceeded(err):
+		return errcode.QuotaExceeded
+	case aliyun.IsIPNotEnough(err):
+		return errcode.VSwitchExhausted
+	case aliyun.IsCredentialInvalid(err) || aliyun.IsForbidden(err):
+		return errcode.CredentialInvalid
+	}
+	return errcode.Unknown
+}
+
+// errorCodeInterceptor convert errors of rpc into grpc status with terway error code,
+// so cni binary can tell retryable failures from fatal ones
+func errorCodeInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return resp, err
+	}
+	return resp, errcode.Status(errorCode(err), err)
+}
+
+// traceparentHeader grpc metadata of w3c trace context of caller
+const traceparentHeader = "traceparent"
+
+// startSpan start span of rpc for pod sandbox, child of trace context of caller in grpc metadata if any
+func startSpan(ctx context.Context, method, namespace, name, sandbox string) (context.Context, *tracing.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(traceparentHeader); len(values) != 0 {
+			ctx = tracing.ContextWithTraceparent(ctx, values[0])
+		}
+	}
+	ctx, span := tracing.Start(ctx, "terway/"+method, tracing.KindServer)
+	span.SetAttributes("k8s.namespace.name", namespace, "k8s.pod.name", name, "terway.sandbox_id", sandbox)
+	return ctx, span
+}
+
+// newTLSServer listen tcp with mTLS, serve read-only rpc of network service
+func newTLSServer(cfg *GRPCConfig, networkService rpc.TerwayBackendServer) (*grpc.Server, net.Listener, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error load grpc tls certificate")
+	}
+	caData, err := ioutil.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error read grpc tls client ca")
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caData) {
+		return nil, nil, fmt.Errorf("no certificate found in grpc tls client ca %s", cfg.TLSClientCAFile)
+	}
+
+	l, err := net.Listen("tcp", cfg.TLSListen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listen at %s: %v", cfg.TLSListen, err)
+	}
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+			MinVersion:   tls.VersionTLS12,
+		})),
+		grpc.UnaryInterceptor(readOnlyInterceptor),
+		grpc.StreamInterceptor(readOnlyStreamInterceptor),
+	)
+	rpc.RegisterTerwayBackendServer(server, networkService)
+	log.Infof("serve read-only grpc with mTLS at %s", cfg.TLSListen)
+	return server, l, nil
+}
@@ -0,0 +1,122 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/errcode"
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/denverdino/aliyungo/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadOnlyInterceptor(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	resp, err := readOnlyInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/rpc.TerwayBackend/GetIPInfo"}, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	_, err = readOnlyInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/rpc.TerwayBackend/AllocIP"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestReadOnlyStreamInterceptor(t *testing.T) {
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	}
+	err := readOnlyStreamInterceptor(nil, nil, &grpc.StreamServerInfo{FullMethod: "/rpc.TerwayBackend/CapturePod"}, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestErrorCodeInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/rpc.TerwayBackend/AllocIP"}
+	call := func(err error) error {
+		_, err = errorCodeInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+		return err
+	}
+
+	assert.NoError(t, call(nil))
+	err := call(errors.Wrapf(&common.Error{ErrorResponse: common.ErrorResponse{Code: "InvalidVSwitchId.IpNotEnough"}}, "error create eni"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, errcode.VSwitchExhausted, errcode.FromError(errors.Wrap(err, "add cmd")))
+	err = call(errors.Wrapf(pool.ErrNoAvailableResource, "error get allocated eniip ip"))
+	assert.Equal(t, errcode.QuotaExceeded, errcode.FromError(err))
+	err = call(errors.Wrapf(&common.Error{ErrorResponse: common.ErrorResponse{Code: "InvalidAccessKeyId.NotFound"}}, "error create eni"))
+	assert.Equal(t, errcode.CredentialInvalid, errcode.FromError(err))
+	assert.False(t, errcode.FromError(err).Retryable())
+	// wrapped twice
+	err = call(errors.Wrapf(errors.Wrap(pool.ErrNoAvailableResource, "error acquire"), "error get allocated eniip ip"))
+	assert.Equal(t, errcode.QuotaExceeded, errcode.FromError(err))
+	err = call(errors.Wrapf(pool.ErrContextDone, "error get allocated eniip ip"))
+	assert.Equal(t, errcode.Timeout, errcode.FromError(err))
+	assert.True(t, errcode.FromError(err).Retryable())
+
+	// status kept as is
+	err = call(status.Errorf(codes.InvalidArgument, "invalid"))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, errcode.Unknown, errcode.FromError(err))
+}
+
+func TestInflightLimiter(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/rpc.TerwayBackend/AllocIP"}
+	limiter := newInflightLimiter(1, 1)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	go limiter.interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(started)
+		<-block
+		return nil, nil
+	})
+	<-started
+
+	// queued until deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := limiter.interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Equal(t, errcode.Overloaded, errcode.FromError(err))
+	assert.True(t, errcode.FromError(err).Retryable())
+
+	// queue full
+	queued := make(chan error)
+	go func() {
+		_, err := limiter.interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+		queued <- err
+	}()
+	for len(limiter.admitted) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = limiter.interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// methods not limited
+	resp, err := limiter.interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/rpc.TerwayBackend/Handshake"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+
+	close(block)
+	assert.NoError(t, <-queued)
+
+	var unlimited *inflightLimiter
+	_, err = unlimited.interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+}
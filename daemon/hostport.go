@@ -0,0 +1,238 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AliyunContainerService/terway/pkg/netfilter"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// host port of pods with exclusive eni, the portmap plugin not applies as the eni moved into pod netns:
+// dnat node ports to eni ip, and masquerade so replies routed back through node
+const (
+	hostPortChain     = "TERWAY-HOSTPORTS"
+	hostPortMasqChain = "TERWAY-HOSTPORTS-MASQ"
+	// per pod chains, suffixed by hash of pod key
+	hostPortPodChainPrefix     = "TERWAY-HP-"
+	hostPortPodMasqChainPrefix = "TERWAY-HPM-"
+)
+
+// hostPort mapping of node port to container port of pod
+type hostPort struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	HostPort      int32  `json:"host_port"`
+	ContainerPort int32  `json:"container_port"`
+	Protocol      string `json:"protocol"`
+}
+
+// podHostPorts return host ports of containers in pod
+func podHostPorts(pod *corev1.Pod) []hostPort {
+	var ports []hostPort
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.HostPort == 0 {
+				continue
+			}
+			protocol := strings.ToLower(string(p.Protocol))
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			ports = append(ports, hostPort{
+				HostIP:        p.HostIP,
+				HostPort:      p.HostPort,
+				ContainerPort: p.ContainerPort,
+				Protocol:      protocol,
+			})
+		}
+	}
+	return ports
+}
+
+// hostPortChains return dnat and masquerade chain of pod
+func hostPortChains(podKey string) (string, string) {
+	hash := sha256.Sum256([]byte(podKey))
+	suffix := strings.ToUpper(hex.EncodeToString(hash[:])[:16])
+	return hostPortPodChainPrefix + suffix, hostPortPodMasqChainPrefix + suffix
+}
+
+// hostPortManager manage host port rules of pods with exclusive eni
+type hostPortManager struct {
+	lock sync.Mutex
+	ipt  netfilter.IPTables
+}
+
+// newHostPortManager ensure entry chains of host port rules
+func newHostPortManager() (*hostPortManager, error) {
+	ipt, err := netfilter.New()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init iptables")
+	}
+	m := &hostPortManager{ipt: ipt}
+	entries := []struct {
+		chain, target string
+		spec          []string
+	}{
+		{"PREROUTING", hostPortChain, []string{"-m", "addrtype", "--dst-type", "LOCAL"}},
+		{"OUTPUT", hostPortChain, []string{"-m", "addrtype", "--dst-type", "LOCAL"}},
+		{"POSTROUTING", hostPortMasqChain, nil},
+	}
+	for _, entry := range entries {
+		if err = m.ensureChain(entry.target); err != nil {
+			return nil, err
+		}
+		spec := append(entry.spec, "-m", "comment", "--comment", "terway hostport", "-j", entry.target)
+		if err = ipt.AppendUnique("nat", entry.chain, spec...); err != nil {
+			return nil, errors.Wrapf(err, "error add jump rule to %s", entry.target)
+		}
+	}
+	return m, nil
+}
+
+func (m *hostPortManager) ensureChain(chain string) error {
+	chains, err := m.ipt.ListChains("nat")
+	if err != nil {
+		return errors.Wrapf(err, "error list nat chains")
+	}
+	for _, c := range chains {
+		if c == chain {
+			return nil
+		}
+	}
+	return errors.Wrapf(m.ipt.NewChain("nat", chain), "error create chain %s", chain)
+}
+
+// setup replace host port rules of pod to dnat to ip, nil manager does nothing
+func (m *hostPortManager) setup(podKey string, ip net.IP, ports []hostPort) error {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if len(ports) == 0 {
+		return m.teardownLocked(podKey)
+	}
+
+	dnatChain, masqChain := hostPortChains(podKey)
+	for _, chain := range []string{dnatChain, masqChain} {
+		// create or flush
+		if err := m.ipt.ClearChain("nat", chain); err != nil {
+			return errors.Wrapf(err, "error clear chain %s", chain)
+		}
+	}
+	for _, p := range ports {
+		hostPortStr := strconv.Itoa(int(p.HostPort))
+		containerPortStr := strconv.Itoa(int(p.ContainerPort))
+		dnat := []string{"-p", p.Protocol, "--dport", hostPortStr}
+		if p.HostIP != "" && p.HostIP != "0.0.0.0" {
+			dnat = append([]string{"-d", p.HostIP}, dnat...)
+		}
+		dnat = append(dnat, "-m", "comment", "--comment", podKey,
+			"-j", "DNAT", "--to-destination", net.JoinHostPort(ip.String(), containerPortStr))
+		if err := m.ipt.Append("nat", dnatChain, dnat...); err != nil {
+			return errors.Wrapf(err, "error add dnat rule of host port %s/%d for %s", p.Protocol, p.HostPort, podKey)
+		}
+		masq := []string{"-p", p.Protocol, "-d", ip.String(), "--dport", containerPortStr,
+			"-m", "conntrack", "--ctstate", "DNAT", "--ctorigdstport", hostPortStr,
+			"-m", "comment", "--comment", podKey, "-j", "MASQUERADE"}
+		if err := m.ipt.Append("nat", masqChain, masq...); err != nil {
+			return errors.Wrapf(err, "error add masquerade rule of host port %s/%d for %s", p.Protocol, p.HostPort, podKey)
+		}
+	}
+	if err := m.ipt.AppendUnique("nat", hostPortChain, "-j", dnatChain); err != nil {
+		return errors.Wrapf(err, "error add jump rule to %s", dnatChain)
+	}
+	return errors.Wrapf(m.ipt.AppendUnique("nat", hostPortMasqChain, "-j", masqChain), "error add jump rule to %s", masqChain)
+}
+
+// teardown remove host port rules of pod, nil manager does nothing
+func (m *hostPortManager) teardown(podKey string) error {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.teardownLocked(podKey)
+}
+
+func (m *hostPortManager) teardownLocked(podKey string) error {
+	dnatChain, masqChain := hostPortChains(podKey)
+	chains, err := m.ipt.ListChains("nat")
+	if err != nil {
+		return errors.Wrapf(err, "error list nat chains")
+	}
+	exists := make(map[string]bool)
+	for _, c := range chains {
+		exists[c] = true
+	}
+	if exists[dnatChain] {
+		if err = m.deletePodChain(hostPortChain, dnatChain); err != nil {
+			return err
+		}
+	}
+	if exists[masqChain] {
+		return m.deletePodChain(hostPortMasqChain, masqChain)
+	}
+	return nil
+}
+
+// deletePodChain delete jump rule from entry and the chain of pod
+func (m *hostPortManager) deletePodChain(entry, chain string) error {
+	exists, err := m.ipt.Exists("nat", entry, "-j", chain)
+	if err != nil {
+		return errors.Wrapf(err, "error check jump rule to %s", chain)
+	}
+	if exists {
+		if err = m.ipt.Delete("nat", entry, "-j", chain); err != nil {
+			return errors.Wrapf(err, "error delete jump rule to %s", chain)
+		}
+	}
+	if err = m.ipt.ClearChain("nat", chain); err != nil {
+		return errors.Wrapf(err, "error clear chain %s", chain)
+	}
+	return errors.Wrapf(m.ipt.DeleteChain("nat", chain), "error delete chain %s", chain)
+}
+
+// gc remove host port rules of pods not in active pods, nil manager does nothing
+func (m *hostPortManager) gc(activePods map[string]bool) error {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	active := make(map[string]bool)
+	for podKey := range activePods {
+		dnatChain, masqChain := hostPortChains(podKey)
+		active[dnatChain] = true
+		active[masqChain] = true
+	}
+	chains, err := m.ipt.ListChains("nat")
+	if err != nil {
+		return errors.Wrapf(err, "error list nat chains")
+	}
+	for _, chain := range chains {
+		if active[chain] {
+			continue
+		}
+		entry := ""
+		switch {
+		case strings.HasPrefix(chain, hostPortPodChainPrefix):
+			entry = hostPortChain
+		case strings.HasPrefix(chain, hostPortPodMasqChainPrefix):
+			entry = hostPortMasqChain
+		default:
+			continue
+		}
+		log.Infof("gc host port chain %s of deleted pod", chain)
+		if err = m.deletePodChain(entry, chain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodHostPorts(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Ports: []corev1.ContainerPort{{ContainerPort: 80}, {ContainerPort: 80, HostPort: 8080}}},
+		{Ports: []corev1.ContainerPort{{ContainerPort: 53, HostPort: 53, Protocol: corev1.ProtocolUDP, HostIP: "10.0.0.1"}}},
+	}}}
+	assert.Equal(t, []hostPort{
+		{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		{HostIP: "10.0.0.1", HostPort: 53, ContainerPort: 53, Protocol: "udp"},
+	}, podHostPorts(pod))
+}
+
+func TestHostPortChains(t *testing.T) {
+	dnat, masq := hostPortChains("default/nginx")
+	assert.True(t, strings.HasPrefix(dnat, hostPortPodChainPrefix))
+	assert.True(t, strings.HasPrefix(masq, hostPortPodMasqChainPrefix))
+	// max length of chain name of iptables
+	assert.True(t, len(masq) <= 28)
+
+	other, _ := hostPortChains("default/nginx-2")
+	assert.NotEqual(t, dnat, other)
+}
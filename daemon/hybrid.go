@@ -0,0 +1,152 @@
+package daemon
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	eniPartitionDBPath = "/var/lib/cni/terway/ENIPartition.db"
+	eniPartitionDBName = "eni_partition"
+
+	// network modes of pod selected by policy key network mode in hybrid and dual daemon mode
+	podNetworkModeShared    = annotation.NetworkModeShared
+	podNetworkModeExclusive = annotation.NetworkModeExclusive
+	podNetworkModeVPC       = annotation.NetworkModeVPC
+)
+
+// eniPartitionRecord eni created for pods of exclusive network mode
+type eniPartitionRecord struct {
+	MAC string `json:"mac"`
+}
+
+// eniPartition partition enis of instance between pools of exclusive eni and shared eni ips in hybrid
+// daemon mode, enis created by exclusive pool persisted in storage, others belong to shared pool
+type eniPartition struct {
+	lock      sync.RWMutex
+	store     storage.Storage
+	exclusive map[string]bool
+	// exclusiveENIs enis of instance reserved for exclusive pool
+	exclusiveENIs int
+}
+
+func newENIPartition(store storage.Storage, exclusiveENIs int) (*eniPartition, error) {
+	p := &eniPartition{
+		store:         store,
+		exclusive:     make(map[string]bool),
+		exclusiveENIs: exclusiveENIs,
+	}
+	records, err := store.List()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list eni partition")
+	}
+	for _, r := range records {
+		p.exclusive[r.(eniPartitionRecord).MAC] = true
+	}
+	return p, nil
+}
+
+func newENIPartitionStorage() (storage.Storage, error) {
+	return storage.NewDiskStorage(eniPartitionDBName, eniPartitionDBPath, json.Marshal, func(bytes []byte) (interface{}, error) {
+		record := eniPartitionRecord{}
+		if err := json.Unmarshal(bytes, &record); err != nil {
+			return nil, errors.Wrapf(err, "error unmarshal eni partition record")
+		}
+		return record, nil
+	})
+}
+
+// owns return whether eni of mac belongs to pool of exclusive or shared enis, all enis if no partition
+func (p *eniPartition) owns(mac string, exclusive bool) bool {
+	if p == nil {
+		return true
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.exclusive[mac] == exclusive
+}
+
+// filter return enis belong to pool of exclusive or shared enis
+func (p *eniPartition) filter(enis []*types.ENI, exclusive bool) []*types.ENI {
+	if p == nil {
+		return enis
+	}
+	var owned []*types.ENI
+	for _, eni := range enis {
+		if p.owns(eni.MAC, exclusive) {
+			owned = append(owned, eni)
+		}
+	}
+	return owned
+}
+
+// sharedENIs wrap managedENIs to return macs of enis of shared pool only, eg: links of exclusive enis moved
+// into netns of pods not checked on host
+func (p *eniPartition) sharedENIs(managedENIs func() map[string]string) func() map[string]string {
+	if p == nil {
+		return managedENIs
+	}
+	return func() map[string]string {
+		enis := managedENIs()
+		for mac := range enis {
+			if !p.owns(mac, false) {
+				delete(enis, mac)
+			}
+		}
+		return enis
+	}
+}
+
+// add record eni of mac created by exclusive pool
+func (p *eniPartition) add(mac string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err := p.store.Put(mac, eniPartitionRecord{MAC: mac}); err != nil {
+		return errors.Wrapf(err, "error persist partition of eni %s", mac)
+	}
+	p.exclusive[mac] = true
+	return nil
+}
+
+// remove forget eni of mac disposed by exclusive pool
+func (p *eniPartition) remove(mac string) error {
+	if p == nil {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.exclusive[mac] {
+		return nil
+	}
+	if err := p.store.Delete(mac); err != nil {
+		return errors.Wrapf(err, "error delete partition of eni %s", mac)
+	}
+	delete(p.exclusive, mac)
+	return nil
+}
+
+// sharedIPCapacity capacity of eni ips on enis of instance not reserved for exclusive pool
+func (p *eniPartition) sharedIPCapacity(ecs aliyun.ECS, instanceID string, capacity int) (int, error) {
+	if p == nil {
+		return capacity, nil
+	}
+	maxENI, err := ecs.GetInstanceMaxENI(instanceID)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error get max eni of instance for eni partition")
+	}
+	// main eni not used for eni ips
+	sharedENIs := maxENI - 1 - p.exclusiveENIs
+	if sharedENIs <= 0 {
+		return 0, errors.Errorf("no eni left for eni ips, %d of %d enis reserved for exclusive eni", p.exclusiveENIs, maxENI-1)
+	}
+	shared := capacity / (maxENI - 1) * sharedENIs
+	log.Infof("%d of %d enis reserved for exclusive eni, capacity of eni ips %d", p.exclusiveENIs, maxENI-1, shared)
+	return shared, nil
+}
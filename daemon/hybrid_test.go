@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestENIPartition(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	p, err := newENIPartition(store, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, p.add("mac-1"))
+
+	enis := []*types.ENI{{MAC: "mac-1"}, {MAC: "mac-2"}}
+	assert.Equal(t, []*types.ENI{{MAC: "mac-1"}}, p.filter(enis, true))
+	assert.Equal(t, []*types.ENI{{MAC: "mac-2"}}, p.filter(enis, false))
+	managed := p.sharedENIs(func() map[string]string {
+		return map[string]string{"mac-1": "", "mac-2": ""}
+	})
+	assert.Equal(t, map[string]string{"mac-2": ""}, managed())
+
+	// partition persisted
+	p, err = newENIPartition(store, 2)
+	assert.NoError(t, err)
+	assert.True(t, p.owns("mac-1", true))
+	assert.NoError(t, p.remove("mac-1"))
+	assert.True(t, p.owns("mac-1", false))
+
+	var none *eniPartition
+	assert.Equal(t, enis, none.filter(enis, true))
+	assert.NoError(t, none.remove("mac-1"))
+}
+
+func TestHybridPodNetworkType(t *testing.T) {
+	pod := &corev1.Pod{}
+	shared := podPolicy{}
+	exclusive := podPolicy{
+		policyKeyNetworkMode: {Key: policyKeyNetworkMode, Value: podNetworkModeExclusive, Source: policySourcePod},
+	}
+	assert.Equal(t, podNetworkTypeENIMultiIP, podNetworkType(daemonModeHybrid, pod, shared))
+	assert.Equal(t, podNetworkTypeVPCENI, podNetworkType(daemonModeHybrid, pod, exclusive))
+	assert.Error(t, annotation.ValidatePod(map[string]string{policyKeyNetworkMode: "dedicated"}))
+}
+
+type dualVSwitchECS struct {
+	aliyun.ECS
+	vSwitches []*aliyun.VSwitch
+}
+
+func (e *dualVSwitchECS) ListVSwitches(vpcID, zone string) ([]*aliyun.VSwitch, error) {
+	return e.vSwitches, nil
+}
+
+func TestDualPodNetworkType(t *testing.T) {
+	pod := &corev1.Pod{}
+	vpc := podPolicy{
+		policyKeyNetworkMode: {Key: policyKeyNetworkMode, Value: podNetworkModeVPC, Source: policySourcePod},
+	}
+	assert.Equal(t, podNetworkTypeENIMultiIP, podNetworkType(daemonModeDual, pod, podPolicy{}))
+	assert.Equal(t, podNetworkTypeVPCIP, podNetworkType(daemonModeDual, pod, vpc))
+	assert.NoError(t, annotation.ValidatePod(map[string]string{policyKeyNetworkMode: podNetworkModeVPC}))
+
+	ecs := &dualVSwitchECS{vSwitches: []*aliyun.VSwitch{
+		{ID: "vsw-1", CIDR: mustCIDR("192.168.0.0/24")},
+		{ID: "vsw-2", CIDR: mustCIDR("172.20.0.0/16")},
+	}}
+	assert.NoError(t, checkNodeCidrOverlap(ecs, "vpc-1", mustCIDR("172.16.1.0/24")))
+	assert.Error(t, checkNodeCidrOverlap(ecs, "vpc-1", mustCIDR("172.20.1.0/24")))
+	assert.Error(t, checkNodeCidrOverlap(ecs, "vpc-1", mustCIDR("192.168.0.0/16")))
+}
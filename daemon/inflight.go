@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/pkg/errcode"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// limitedMethods rpc methods of cni binary limited by inflight limiter
+var limitedMethods = map[string]bool{
+	"/rpc.TerwayBackend/AllocIP":   true,
+	"/rpc.TerwayBackend/ReleaseIP": true,
+	"/rpc.TerwayBackend/GetIPInfo": true,
+}
+
+// inflightLimiter limit concurrent requests of cni binary, requests over the limit wait in a bounded queue
+// and requests over the queue fail fast, so a pod storm not piles up goroutines and openapi calls
+type inflightLimiter struct {
+	// admitted requests running or queued, capacity of max running and max queued
+	admitted chan struct{}
+	// running requests running, capacity of max running
+	running chan struct{}
+}
+
+// newInflightLimiter return limiter of max concurrent and max queued requests, nil if concurrent unlimited
+func newInflightLimiter(maxConcurrent, maxQueued int) *inflightLimiter {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	return &inflightLimiter{
+		admitted: make(chan struct{}, maxConcurrent+maxQueued),
+		running:  make(chan struct{}, maxConcurrent),
+	}
+}
+
+// acquire wait a slot to run request, error with retryable code if queue full or context done while waiting
+func (l *inflightLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.admitted <- struct{}{}:
+	default:
+		return errcode.Status(errcode.Overloaded, fmt.Errorf("too many requests in flight, %d running or queued", cap(l.admitted)))
+	}
+	select {
+	case l.running <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		<-l.admitted
+		return errcode.Status(errcode.Overloaded, fmt.Errorf("request queued until %v", ctx.Err()))
+	}
+}
+
+func (l *inflightLimiter) release() {
+	<-l.running
+	<-l.admitted
+}
+
+// interceptor limit methods of cni binary, then convert errors of rpc by errorCodeInterceptor
+func (l *inflightLimiter) interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if l == nil || !limitedMethods[info.FullMethod] {
+		return errorCodeInterceptor(ctx, req, info, handler)
+	}
+	if err := l.acquire(ctx); err != nil {
+		metric.RPCShed.WithLabelValues(info.FullMethod).Inc()
+		return nil, err
+	}
+	defer l.release()
+	return errorCodeInterceptor(ctx, req, info, handler)
+}
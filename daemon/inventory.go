@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resourceIP return ip address of resource, empty if no ip
+func resourceIP(res types.NetworkResource) string {
+	switch r := res.(type) {
+	case *types.ENIIP:
+		return r.SecAddress.String()
+	case *types.ENI:
+		if r.Address.IP != nil {
+			return r.Address.IP.String()
+		}
+	}
+	return ""
+}
+
+// resourceOwners return owner pods of resources by type and id of resource
+func (networkService *networkService) resourceOwners() (map[ResourceItem]*podInfo, error) {
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[ResourceItem]*podInfo)
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil {
+			continue
+		}
+		for _, res := range resRelate.Resources {
+			owners[res] = resRelate.PodInfo
+		}
+	}
+	return owners, nil
+}
+
+// inventory return resources of pools with owner pods, filtered by substring of id, ip or namespace/name of pod
+func (networkService *networkService) inventory(filter string) ([]*rpc.PooledResource, error) {
+	owners, err := networkService.resourceOwners()
+	if err != nil {
+		return nil, err
+	}
+	networkService.RLock()
+	var items []pool.InventoryItem
+	for _, mgr := range networkService.mgrForResource {
+		items = append(items, mgr.Inventory()...)
+	}
+	networkService.RUnlock()
+
+	var resources []*rpc.PooledResource
+	for _, item := range items {
+		res := &rpc.PooledResource{
+			ID:      item.ID,
+			Type:    item.Type,
+			IP:      resourceIP(item.Resource),
+			State:   item.State,
+			Created: item.Created.Unix(),
+			Pinned:  item.Pinned,
+		}
+		if !item.LastUsed.IsZero() {
+			res.LastUsed = item.LastUsed.Unix()
+		}
+		if owner, ok := owners[ResourceItem{Type: item.Type, ID: item.ID}]; ok {
+			res.PodNamespace, res.PodName = owner.Namespace, owner.Name
+		}
+		if filter != "" && !strings.Contains(res.ID, filter) && !strings.Contains(res.IP, filter) &&
+			!strings.Contains(fmt.Sprintf("%s/%s", res.PodNamespace, res.PodName), filter) {
+			continue
+		}
+		resources = append(resources, res)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Type != resources[j].Type {
+			return resources[i].Type < resources[j].Type
+		}
+		return resources[i].ID < resources[j].ID
+	})
+	return resources, nil
+}
+
+// GetResourceInventory return resources of pools with state, age and owner pod, eg: which pod holds an ip
+func (networkService *networkService) GetResourceInventory(ctx context.Context, r *rpc.GetResourceInventoryRequest) (*rpc.ResourceInventoryReply, error) {
+	resources, err := networkService.inventory(r.Filter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error list resources: %v", err)
+	}
+	return &rpc.ResourceInventoryReply{Resources: resources}, nil
+}
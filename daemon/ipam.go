@@ -0,0 +1,434 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/containernetworking/plugins/plugins/ipam/host-local/backend"
+	"github.com/containernetworking/plugins/plugins/ipam/host-local/backend/disk"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// backends of ipam of pods with vpc ip
+const (
+	// vpcIPAMHostLocal ips reserved by files on disk in the layout of host-local ipam
+	vpcIPAMHostLocal = "host-local"
+	// vpcIPAMCRD ips reserved in NodeIPAM custom resource of node, for read-only root filesystem or disk loss
+	vpcIPAMCRD = "crd"
+
+	// ipamRangeID range of node cidr ips allocated from, same as the only range of host-local ipam
+	ipamRangeID = "0"
+	// ipamUpdateRetries retries of update of NodeIPAM on conflict
+	ipamUpdateRetries = 3
+)
+
+// vpcIPAM allocate ips of node cidr to sandboxes of pods with vpc ip
+type vpcIPAM interface {
+	// Allocate return ip of subnet for sandbox, the ip allocated before returned for the same sandbox
+	Allocate(subnet *net.IPNet, sandboxID string) (net.IP, error)
+	// Release ips of sandbox
+	Release(sandboxID string) error
+	// Transfer move ip of subnet reserved by a sandbox to another sandbox, eg: sandbox of pod recreated in place
+	Transfer(subnet *net.IPNet, fromSandbox, toSandbox string) (net.IP, error)
+	// Reserve reserve the given ip of subnet for sandbox, eg: ip of pod set up by previous cni
+	Reserve(subnet *net.IPNet, ip net.IP, sandboxID string) error
+	// GarbageCollection release ips of sandboxes not running, except allocated within protection
+	GarbageCollection(running map[string]bool, protection time.Duration) error
+}
+
+// ipamReservation sandbox ip reserved by and the time reserved
+type ipamReservation struct {
+	sandboxID string
+	time      time.Time
+}
+
+// ipamStore ips reserved by sandboxes, store of host-local ipam listing the reservations
+type ipamStore interface {
+	backend.Store
+	// reservations return reservations by ip
+	reservations() (map[string]ipamReservation, error)
+}
+
+// newVPCIPAM return ipam of backend in config
+func newVPCIPAM(env *ResourceManagerEnv) (vpcIPAM, error) {
+	switch env.Config.VPCIPAM {
+	case vpcIPAMCRD:
+		store := newCRDIPAMStore(crd.NewIPAMClient(env.KubeClient.Discovery().RESTClient()),
+			env.netSrv.k8s.GetNodeName(), env.netSrv.k8s.GetNodeCidr())
+		// ips of pods set up by host-local ipam before kept
+		if err := store.importHostLocal(defaultIpamPath); err != nil {
+			return nil, errors.Wrapf(err, "error import ips of host-local ipam")
+		}
+		return &storeIPAM{store: store}, nil
+	default:
+		store, err := disk.New("", defaultIpamPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error init host-local ipam store")
+		}
+		return &storeIPAM{store: &diskIPAMStore{Store: store, dir: defaultIpamPath}}, nil
+	}
+}
+
+// ipamGateway gateway of pods in subnet, the first ip as host-local ipam
+func ipamGateway(subnet *net.IPNet) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(subnet.IP.To4())+1)
+	return ip
+}
+
+// storeIPAM allocate ips of subnet round robin after the last reserved, skipping the gateway and broadcast
+type storeIPAM struct {
+	store ipamStore
+}
+
+func (s *storeIPAM) Allocate(subnet *net.IPNet, sandboxID string) (net.IP, error) {
+	if subnet.IP.To4() == nil {
+		return nil, fmt.Errorf("ipv4 node cidr required, got %s", subnet)
+	}
+	if err := s.store.Lock(); err != nil {
+		return nil, err
+	}
+	defer s.store.Unlock()
+
+	reservations, err := s.store.reservations()
+	if err != nil {
+		return nil, err
+	}
+	for ip, r := range reservations {
+		if r.sandboxID == sandboxID && subnet.Contains(net.ParseIP(ip)) {
+			return net.ParseIP(ip).To4(), nil
+		}
+	}
+
+	ones, bits := subnet.Mask.Size()
+	if bits-ones < 2 {
+		return nil, fmt.Errorf("node cidr %s too small to allocate", subnet)
+	}
+	// ips between the gateway and broadcast
+	first := binary.BigEndian.Uint32(subnet.IP.To4()) + 2
+	count := uint32(1)<<uint(bits-ones) - 3
+	start := uint32(0)
+	if last, err := s.store.LastReservedIP(ipamRangeID); err == nil && last != nil && last.To4() != nil {
+		if offset := binary.BigEndian.Uint32(last.To4()) - first; offset < count {
+			start = offset + 1
+		}
+	}
+	for i := uint32(0); i < count; i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, first+(start+i)%count)
+		if _, ok := reservations[ip.String()]; ok {
+			continue
+		}
+		reserved, err := s.store.Reserve(sandboxID, ip, ipamRangeID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reserve ip %s", ip)
+		}
+		if reserved {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("no ip available in node cidr %s", subnet)
+}
+
+func (s *storeIPAM) Release(sandboxID string) error {
+	if sandboxID == "" {
+		return nil
+	}
+	if err := s.store.Lock(); err != nil {
+		return err
+	}
+	defer s.store.Unlock()
+	return s.store.ReleaseByID(sandboxID)
+}
+
+func (s *storeIPAM) Transfer(subnet *net.IPNet, fromSandbox, toSandbox string) (net.IP, error) {
+	if err := s.store.Lock(); err != nil {
+		return nil, err
+	}
+	defer s.store.Unlock()
+
+	reservations, err := s.store.reservations()
+	if err != nil {
+		return nil, err
+	}
+	for ip, r := range reservations {
+		if r.sandboxID != fromSandbox || !subnet.Contains(net.ParseIP(ip)) {
+			continue
+		}
+		transferred := net.ParseIP(ip).To4()
+		if err = s.store.Release(transferred); err != nil {
+			return nil, errors.Wrapf(err, "error release ip %s of sandbox %s", ip, fromSandbox)
+		}
+		reserved, err := s.store.Reserve(toSandbox, transferred, ipamRangeID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reserve ip %s", ip)
+		}
+		if !reserved {
+			return nil, fmt.Errorf("ip %s reserved by others on transfer", ip)
+		}
+		return transferred, nil
+	}
+	return nil, fmt.Errorf("no ip of sandbox %s in node cidr %s", fromSandbox, subnet)
+}
+
+func (s *storeIPAM) Reserve(subnet *net.IPNet, ip net.IP, sandboxID string) error {
+	if !subnet.Contains(ip) {
+		return fmt.Errorf("ip %s not in node cidr %s", ip, subnet)
+	}
+	if err := s.store.Lock(); err != nil {
+		return err
+	}
+	defer s.store.Unlock()
+
+	reservations, err := s.store.reservations()
+	if err != nil {
+		return err
+	}
+	if r, ok := reservations[ip.String()]; ok {
+		if r.sandboxID == sandboxID {
+			return nil
+		}
+		return fmt.Errorf("ip %s reserved by sandbox %s", ip, r.sandboxID)
+	}
+	reserved, err := s.store.Reserve(sandboxID, ip.To4(), ipamRangeID)
+	if err != nil {
+		return errors.Wrapf(err, "error reserve ip %s", ip)
+	}
+	if !reserved {
+		return fmt.Errorf("ip %s reserved by others", ip)
+	}
+	return nil
+}
+
+func (s *storeIPAM) GarbageCollection(running map[string]bool, protection time.Duration) error {
+	if err := s.store.Lock(); err != nil {
+		return err
+	}
+	defer s.store.Unlock()
+	reservations, err := s.store.reservations()
+	if err != nil {
+		return err
+	}
+	for ip, r := range reservations {
+		if r.sandboxID == "" || running[r.sandboxID] || time.Since(r.time) < protection {
+			continue
+		}
+		log.Warnf("detect ip address leak: %s, removing", ip)
+		if err = s.store.Release(net.ParseIP(ip)); err != nil {
+			log.Errorf("error remove leak ip: %s, err: %v", ip, err)
+		}
+	}
+	return nil
+}
+
+// diskIPAMStore ips reserved by files of host-local ipam, shared with host-local ipam run by cni binary
+type diskIPAMStore struct {
+	*disk.Store
+	dir string
+}
+
+func (s *diskIPAMStore) reservations() (map[string]ipamReservation, error) {
+	return hostLocalReservations(s.dir)
+}
+
+// hostLocalReservations return ips reserved by files of host-local ipam in dir
+func hostLocalReservations(dir string) (map[string]ipamReservation, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %q: %v", dir, err)
+	}
+	reservations := make(map[string]ipamReservation)
+	for _, file := range files {
+		// skip non checkpoint file
+		if ip := net.ParseIP(file.Name()); ip == nil {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			log.Errorf("Failed to read file %v: %v", file, err)
+			continue
+		}
+		reservations[file.Name()] = ipamReservation{sandboxID: strings.TrimSpace(string(content)), time: file.ModTime()}
+	}
+	return reservations, nil
+}
+
+// crdIPAMStore ips reserved in NodeIPAM of node, the daemon the only writer of it
+type crdIPAMStore struct {
+	lock     sync.Mutex
+	client   crd.IPAMClient
+	nodeName string
+	cidr     string
+	// ipam last read or written, nil to read again
+	ipam *crd.NodeIPAM
+}
+
+func newCRDIPAMStore(client crd.IPAMClient, nodeName string, cidr *net.IPNet) *crdIPAMStore {
+	s := &crdIPAMStore{client: client, nodeName: nodeName}
+	if cidr != nil {
+		s.cidr = cidr.String()
+	}
+	return s
+}
+
+func (s *crdIPAMStore) Lock() error {
+	s.lock.Lock()
+	return nil
+}
+
+func (s *crdIPAMStore) Unlock() error {
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *crdIPAMStore) Close() error {
+	return nil
+}
+
+// get return NodeIPAM of node, created if not exist
+func (s *crdIPAMStore) get() (*crd.NodeIPAM, error) {
+	if s.ipam != nil {
+		return s.ipam, nil
+	}
+	ipam, err := s.client.Get(s.nodeName)
+	if apierrors.IsNotFound(err) {
+		ipam, err = s.client.Create(&crd.NodeIPAM{
+			ObjectMeta: metav1.ObjectMeta{Name: s.nodeName},
+			Spec:       crd.NodeIPAMSpec{CIDR: s.cidr},
+		})
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error get node ipam %s", s.nodeName)
+	}
+	if ipam.Status.Allocations == nil {
+		ipam.Status.Allocations = make(map[string]crd.IPAllocation)
+	}
+	if ipam.Status.LastReservedIPs == nil {
+		ipam.Status.LastReservedIPs = make(map[string]string)
+	}
+	s.ipam = ipam
+	return ipam, nil
+}
+
+// update apply mutate to NodeIPAM and write it if changed, read again and retried on conflict
+func (s *crdIPAMStore) update(mutate func(ipam *crd.NodeIPAM) bool) error {
+	var err error
+	for i := 0; i < ipamUpdateRetries; i++ {
+		var ipam *crd.NodeIPAM
+		if ipam, err = s.get(); err != nil {
+			return err
+		}
+		if !mutate(ipam) {
+			return nil
+		}
+		var updated *crd.NodeIPAM
+		if updated, err = s.client.Update(ipam); err == nil {
+			s.ipam = updated
+			return nil
+		}
+		s.ipam = nil
+		if !apierrors.IsConflict(err) {
+			return errors.Wrapf(err, "error update node ipam %s", s.nodeName)
+		}
+	}
+	return errors.Wrapf(err, "error update node ipam %s", s.nodeName)
+}
+
+func (s *crdIPAMStore) Reserve(id string, ip net.IP, rangeID string) (bool, error) {
+	var reserved bool
+	err := s.update(func(ipam *crd.NodeIPAM) bool {
+		if _, ok := ipam.Status.Allocations[ip.String()]; ok {
+			reserved = false
+			return false
+		}
+		ipam.Status.Allocations[ip.String()] = crd.IPAllocation{SandboxID: strings.TrimSpace(id), AllocatedAt: metav1.Now()}
+		ipam.Status.LastReservedIPs[rangeID] = ip.String()
+		reserved = true
+		return true
+	})
+	return reserved && err == nil, err
+}
+
+func (s *crdIPAMStore) LastReservedIP(rangeID string) (net.IP, error) {
+	ipam, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+	return net.ParseIP(ipam.Status.LastReservedIPs[rangeID]), nil
+}
+
+func (s *crdIPAMStore) Release(ip net.IP) error {
+	return s.update(func(ipam *crd.NodeIPAM) bool {
+		if _, ok := ipam.Status.Allocations[ip.String()]; !ok {
+			return false
+		}
+		delete(ipam.Status.Allocations, ip.String())
+		return true
+	})
+}
+
+func (s *crdIPAMStore) ReleaseByID(id string) error {
+	return s.update(func(ipam *crd.NodeIPAM) bool {
+		changed := false
+		for ip, allocation := range ipam.Status.Allocations {
+			if allocation.SandboxID == strings.TrimSpace(id) {
+				delete(ipam.Status.Allocations, ip)
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// importHostLocal reserve ips in files of host-local ipam in dir, only if no ip reserved in NodeIPAM yet,
+// eg: on first start with crd backend, ignored if no such dir
+func (s *crdIPAMStore) importHostLocal(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ipam, err := s.get()
+	if err != nil {
+		return err
+	}
+	if len(ipam.Status.Allocations) != 0 || len(ipam.Status.LastReservedIPs) != 0 {
+		return nil
+	}
+	reservations, err := hostLocalReservations(dir)
+	if err != nil {
+		return err
+	}
+	for ip, r := range reservations {
+		if r.sandboxID == "" {
+			continue
+		}
+		if _, err = s.Reserve(r.sandboxID, net.ParseIP(ip), ipamRangeID); err != nil {
+			return errors.Wrapf(err, "error import ip %s", ip)
+		}
+	}
+	return nil
+}
+
+func (s *crdIPAMStore) reservations() (map[string]ipamReservation, error) {
+	ipam, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+	reservations := make(map[string]ipamReservation, len(ipam.Status.Allocations))
+	for ip, allocation := range ipam.Status.Allocations {
+		reservations[ip] = ipamReservation{sandboxID: allocation.SandboxID, time: allocation.AllocatedAt.Time}
+	}
+	return reservations, nil
+}
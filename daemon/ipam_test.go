@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeIPAMClient struct {
+	ipams map[string]*crd.NodeIPAM
+}
+
+func copyNodeIPAM(ipam *crd.NodeIPAM) *crd.NodeIPAM {
+	data, _ := json.Marshal(ipam)
+	copied := &crd.NodeIPAM{}
+	_ = json.Unmarshal(data, copied)
+	return copied
+}
+
+func (c *fakeIPAMClient) Get(name string) (*crd.NodeIPAM, error) {
+	ipam, ok := c.ipams[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: crd.GroupName, Resource: crd.NodeIPAMPlural}, name)
+	}
+	return copyNodeIPAM(ipam), nil
+}
+
+func (c *fakeIPAMClient) Create(ipam *crd.NodeIPAM) (*crd.NodeIPAM, error) {
+	c.ipams[ipam.Name] = copyNodeIPAM(ipam)
+	return ipam, nil
+}
+
+func (c *fakeIPAMClient) Update(ipam *crd.NodeIPAM) (*crd.NodeIPAM, error) {
+	c.ipams[ipam.Name] = copyNodeIPAM(ipam)
+	return ipam, nil
+}
+
+func TestCRDIPAM(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("172.30.0.0/29")
+	client := &fakeIPAMClient{ipams: make(map[string]*crd.NodeIPAM)}
+	ipam := &storeIPAM{store: newCRDIPAMStore(client, "node1", subnet)}
+
+	assert.Equal(t, "172.30.0.1", ipamGateway(subnet).String())
+	// gateway skipped
+	ip, err := ipam.Allocate(subnet, "sandbox1")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.2", ip.String())
+	// same ip for the same sandbox
+	ip, err = ipam.Allocate(subnet, "sandbox1")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.2", ip.String())
+	ip, err = ipam.Allocate(subnet, "sandbox2")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.3", ip.String())
+	assert.Equal(t, "172.30.0.0/29", client.ipams["node1"].Spec.CIDR)
+
+	// ip kept for the sandbox recreated in place
+	ip, err = ipam.Transfer(subnet, "sandbox2", "sandbox2-new")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.3", ip.String())
+	assert.Equal(t, "sandbox2-new", client.ipams["node1"].Status.Allocations["172.30.0.3"].SandboxID)
+	_, err = ipam.Transfer(subnet, "sandbox2", "sandbox2-new")
+	assert.Error(t, err)
+	ip, err = ipam.Transfer(subnet, "sandbox2-new", "sandbox2")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.3", ip.String())
+
+	// round robin after the last reserved, not the released ip
+	assert.NoError(t, ipam.Release("sandbox1"))
+	ip, err = ipam.Allocate(subnet, "sandbox3")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.4", ip.String())
+	for _, id := range []string{"sandbox4", "sandbox5"} {
+		_, err = ipam.Allocate(subnet, id)
+		assert.NoError(t, err)
+	}
+	// wrapped, broadcast skipped
+	ip, err = ipam.Allocate(subnet, "sandbox6")
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.2", ip.String())
+	_, err = ipam.Allocate(subnet, "sandbox7")
+	assert.Error(t, err)
+
+	// sandboxes not running released, except the one allocated within protection
+	allocations := client.ipams["node1"].Status.Allocations
+	for ip, allocation := range allocations {
+		if allocation.SandboxID != "sandbox6" {
+			allocation.AllocatedAt = metav1.NewTime(time.Now().Add(-time.Hour))
+			allocations[ip] = allocation
+		}
+	}
+	ipam.store.(*crdIPAMStore).ipam = nil
+	assert.NoError(t, ipam.GarbageCollection(map[string]bool{"sandbox2": true}, time.Minute))
+	reservations, err := ipam.store.reservations()
+	assert.NoError(t, err)
+	assert.Len(t, reservations, 2)
+	assert.Equal(t, "sandbox2", reservations["172.30.0.3"].sandboxID)
+	assert.Equal(t, "sandbox6", reservations["172.30.0.2"].sandboxID)
+}
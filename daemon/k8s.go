@@ -8,10 +8,11 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"unicode"
 
 	"github.com/AliyunContainerService/terway/deviceplugin"
+	"github.com/AliyunContainerService/terway/pkg/annotation"
 	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -19,6 +20,8 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -34,20 +37,55 @@ type podInfo struct {
 	//K8sPod *v1.Pod
 	Name           string
 	Namespace      string
+	UID            string
 	TcIngress      uint64
 	TcEgress       uint64
 	PodNetworkType string
 	PodIP          string
 	IPStickTime    time.Duration
+	// LinkLocalAccess access of pod to link-local services
+	LinkLocalAccess string
+	// NamespaceLimits resource type to max count the namespace of pod can consume on node
+	NamespaceLimits map[string]int
+	// HostPorts host ports of containers, mapped by daemon for pods with exclusive eni
+	HostPorts []hostPort
+	// Routes extra routes installed in pod netns by cni binary
+	Routes []annotation.Route
+	// Sysctls sysctls set in pod netns by cni binary
+	Sysctls map[string]string
+	// InterfaceTuning tuning of interface of pod with exclusive eni by cni binary, nil if not tuned
+	InterfaceTuning *types.InterfaceTuning
+	// PriorityClassName priority class of pod, pods of critical priority classes can take the slots reserved
+	PriorityClassName string
+	// Owner kind/name of controller of pod, eg: ReplicaSet/web-5d8f
+	Owner string
+	// ENISpread spread ips of pod and other pods of its owner across enis
+	ENISpread bool
+	// EIP eip associated to ip of pod, nil if none
+	EIP *annotation.EIP
+	// Mirror target traffic of pod veth mirrored to, nil if not mirrored
+	Mirror *annotation.Mirror
+	// DeletionDeadline time termination grace period of pod deleted elapses, zero if not deleting
+	DeletionDeadline time.Time
 }
 
 // Kubernetes operation set
 type Kubernetes interface {
 	GetLocalPods() ([]*podInfo, error)
+	GetPendingPods() ([]*podInfo, error)
+	WatchLocalPods(stop <-chan struct{}, onEvent func())
 	GetPod(namespace, name string) (*podInfo, error)
+	// PeekPod return info of pod without recording it, a pod of namespace with priority class if name empty,
+	// eg: pod not created yet
+	PeekPod(namespace, name, priorityClassName string) (*podInfo, error)
 	GetServiceCidr() *net.IPNet
 	GetNodeCidr() *net.IPNet
 	SetNodeAllocatablePod(count int) error
+	GetNodeName() string
+	ExplainPod(namespace, name string) ([]policyDecision, error)
+	MarkPodENIHealth(namespace, name, reason string) error
+	// NodeCordoned whether node marked unschedulable, eg: to be drained before terminated
+	NodeCordoned() (bool, error)
 }
 
 type k8s struct {
@@ -57,10 +95,13 @@ type k8s struct {
 	nodeName string
 	nodeCidr *net.IPNet
 	svcCidr  *net.IPNet
+	// clusterPolicy policies from cluster config, the lowest precedence
+	clusterPolicy map[string]string
+	policyCache   *policyCache
 }
 
 // newK8S return Kubernetes service by pod spec and daemon mode
-func newK8S(client kubernetes.Interface, svcCidr *net.IPNet, daemonMode string) (Kubernetes, error) {
+func newK8S(client kubernetes.Interface, svcCidr *net.IPNet, daemonMode string, clusterPolicy map[string]string) (Kubernetes, error) {
 
 	nodeName, err := getNodeName(client)
 	if err != nil {
@@ -68,7 +109,7 @@ func newK8S(client kubernetes.Interface, svcCidr *net.IPNet, daemonMode string)
 	}
 
 	var nodeCidr *net.IPNet
-	if daemonMode == daemonModeVPC {
+	if daemonMode == daemonModeVPC || daemonMode == daemonModeDual {
 		nodeCidr, err = nodeCidrFromAPIServer(client, nodeName)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed getting node cidr")
@@ -93,6 +134,9 @@ func newK8S(client kubernetes.Interface, svcCidr *net.IPNet, daemonMode string)
 		nodeCidr: nodeCidr,
 		svcCidr:  svcCidr,
 		storage:  storage,
+
+		clusterPolicy: clusterPolicy,
+		policyCache:   newPolicyCache(policyCacheTTL),
 	}
 
 	go func() {
@@ -107,6 +151,18 @@ func newK8S(client kubernetes.Interface, svcCidr *net.IPNet, daemonMode string)
 	return k8sObj, nil
 }
 
+func (k *k8s) GetNodeName() string {
+	return k.nodeName
+}
+
+func (k *k8s) NodeCordoned() (bool, error) {
+	node, err := k.client.CoreV1().Nodes().Get(k.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return false, errors.Wrapf(err, "error get node %s", k.nodeName)
+	}
+	return node.Spec.Unschedulable, nil
+}
+
 func getNodeName(client kubernetes.Interface) (string, error) {
 	nodeName := os.Getenv("NODE_NAME")
 	if nodeName == "" {
@@ -184,25 +240,21 @@ const k8sKubeadmConfigmap = "kubeadm-config"
 const k8sKubeadmConfigmapNetworking = "MasterConfiguration"
 const k8sKubeadmConfigmapClusterconfiguration = "ClusterConfiguration"
 
-const podNeedEni = "k8s.aliyun.com/ENI"
-const podIngressBandwidth = "k8s.aliyun.com/ingress-bandwidth"
-const podEgressBandwidth = "k8s.aliyun.com/egress-bandwidth"
-
 const defaultStickTimeForSts = 5 * time.Minute
 
 var (
 	storageCleanTimeout = 1 * time.Hour
 	storageCleanPeriod  = 5 * time.Minute
+	podWatchRetryPeriod = 5 * time.Second
 )
 
-func podNetworkType(daemonMode string, pod *corev1.Pod) string {
+func podNetworkType(daemonMode string, pod *corev1.Pod, policy podPolicy) string {
 	switch daemonMode {
 	case daemonModeENIMultiIP:
 		return podNetworkTypeENIMultiIP
 	case daemonModeVPC:
-		podAnnotation := pod.GetAnnotations()
 		useENI := false
-		if needEni, ok := podAnnotation[podNeedEni]; ok && (needEni != "" && needEni != conditionFalse && needEni != "0") {
+		if needEni, ok := policy.get(policyKeyENI); ok && (needEni != "" && needEni != conditionFalse && needEni != "0") {
 			useENI = true
 		}
 
@@ -219,34 +271,121 @@ func podNetworkType(daemonMode string, pod *corev1.Pod) string {
 		return podNetworkTypeVPCIP
 	case daemonModeENIOnly:
 		return podNetworkTypeVPCENI
+	case daemonModeHybrid:
+		if mode, ok := policy.get(policyKeyNetworkMode); ok && mode == podNetworkModeExclusive {
+			return podNetworkTypeVPCENI
+		}
+		for _, c := range pod.Spec.Containers {
+			if _, ok := c.Resources.Requests[deviceplugin.DefaultResourceName]; ok {
+				return podNetworkTypeVPCENI
+			}
+		}
+		return podNetworkTypeENIMultiIP
+	case daemonModeDual:
+		if mode, ok := policy.get(policyKeyNetworkMode); ok && mode == podNetworkModeVPC {
+			return podNetworkTypeVPCIP
+		}
+		return podNetworkTypeENIMultiIP
 	}
 
 	panic(fmt.Errorf("unknown daemon mode %s", daemonMode))
 }
 
-func convertPod(daemonMode string, pod *corev1.Pod) *podInfo {
+func convertPod(daemonMode string, pod *corev1.Pod, policy podPolicy) *podInfo {
 
 	pi := &podInfo{
 		Name:      pod.Name,
 		Namespace: pod.Namespace,
+		UID:       string(pod.UID),
 	}
 
-	pi.PodNetworkType = podNetworkType(daemonMode, pod)
+	pi.PodNetworkType = podNetworkType(daemonMode, pod, policy)
 
 	pi.PodIP = pod.Status.PodIP
+	pi.PriorityClassName = pod.Spec.PriorityClassName
+	if pod.DeletionTimestamp != nil {
+		pi.DeletionDeadline = pod.DeletionTimestamp.Time
+	}
+	if ref := metav1.GetControllerOf(pod); ref != nil {
+		pi.Owner = ref.Kind + "/" + ref.Name
+	}
+	if value, ok := policy.get(policyKeyENISpread); ok {
+		pi.ENISpread = value == "true"
+	}
 
-	podAnnotation := pod.GetAnnotations()
-	if ingressBandwidth, ok := podAnnotation[podIngressBandwidth]; ok {
-		if ingress, err := parseBandwidth(ingressBandwidth); err == nil {
+	if ingressBandwidth, ok := policy.get(policyKeyIngressBandwidth); ok {
+		if ingress, err := annotation.ParseBandwidth(ingressBandwidth); err == nil {
 			pi.TcIngress = ingress
 		}
 		//TODO write event on pod if parse bandwidth fail
 	}
-	if egressBandwidth, ok := podAnnotation[podEgressBandwidth]; ok {
-		if egress, err := parseBandwidth(egressBandwidth); err == nil {
+	if egressBandwidth, ok := policy.get(policyKeyEgressBandwidth); ok {
+		if egress, err := annotation.ParseBandwidth(egressBandwidth); err == nil {
 			pi.TcEgress = egress
 		}
 	}
+	pi.LinkLocalAccess, _ = policy.get(policyKeyLinkLocal)
+	for resType, key := range limitPolicyKeys {
+		value, ok := policy.get(key)
+		if !ok {
+			continue
+		}
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			log.Warnf("invalid %s %q of pod %s/%s, ignored", key, value, pod.Namespace, pod.Name)
+			continue
+		}
+		if pi.NamespaceLimits == nil {
+			pi.NamespaceLimits = make(map[string]int)
+		}
+		pi.NamespaceLimits[resType] = limit
+	}
+
+	pi.HostPorts = podHostPorts(pod)
+
+	if value, ok := policy.get(policyKeyPodRoutes); ok {
+		routes, err := annotation.ParseRoutes(value)
+		if err != nil {
+			log.Warnf("invalid %s %q of pod %s/%s, ignored: %v", policyKeyPodRoutes, value, pod.Namespace, pod.Name, err)
+		} else {
+			pi.Routes = routes
+		}
+	}
+
+	if value, ok := policy.get(policyKeyPodEIP); ok {
+		bandwidth, _ := policy.get(policyKeyPodEIPBandwidth)
+		retain, _ := policy.get(policyKeyPodEIPRetain)
+		eip, err := annotation.ParseEIP(value, bandwidth, retain)
+		if err != nil {
+			log.Warnf("invalid eip of pod %s/%s, ignored: %v", pod.Namespace, pod.Name, err)
+		} else {
+			pi.EIP = eip
+		}
+	}
+	if value, ok := policy.get(policyKeyTrafficMirror); ok {
+		mirror, err := annotation.ParseMirror(value)
+		if err != nil {
+			log.Warnf("invalid %s %q of pod %s/%s, ignored: %v", policyKeyTrafficMirror, value, pod.Namespace, pod.Name, err)
+		} else {
+			pi.Mirror = mirror
+		}
+	}
+	if value, ok := policy.get(policyKeyPodSysctls); ok {
+		sysctls, err := annotation.ParseSysctls(value)
+		if err != nil {
+			log.Warnf("invalid %s %q of pod %s/%s, ignored: %v", policyKeyPodSysctls, value, pod.Namespace, pod.Name, err)
+		} else {
+			pi.Sysctls = sysctls
+		}
+	}
+	if value, ok := policy.get(policyKeyInterfaceTuning); ok {
+		tuning, err := annotation.ParseInterfaceTuning(value)
+		if err != nil {
+			log.Warnf("invalid %s %q of pod %s/%s, ignored: %v", policyKeyInterfaceTuning, value, pod.Namespace, pod.Name, err)
+		} else {
+			pi.InterfaceTuning = tuning
+		}
+	}
 
 	if len(pod.OwnerReferences) != 0 {
 		switch strings.ToLower(pod.OwnerReferences[0].Kind) {
@@ -259,44 +398,6 @@ func convertPod(daemonMode string, pod *corev1.Pod) *podInfo {
 	return pi
 }
 
-// bandwidth limit unit
-const (
-	BYTE = 1 << (10 * iota)
-	KILOBYTE
-	MEGABYTE
-	GIGABYTE
-	TERABYTE
-)
-
-func parseBandwidth(s string) (uint64, error) {
-
-	s = strings.TrimSpace(s)
-	s = strings.ToUpper(s)
-
-	i := strings.IndexFunc(s, unicode.IsLetter)
-
-	bytesString, multiple := s[:i], s[i:]
-	bytes, err := strconv.ParseFloat(bytesString, 64)
-	if err != nil || bytes <= 0 {
-		return 0, fmt.Errorf("invalid bandwidth %s", s)
-	}
-
-	switch multiple {
-	case "T", "TB", "TIB":
-		return uint64(bytes * TERABYTE), nil
-	case "G", "GB", "GIB":
-		return uint64(bytes * GIGABYTE), nil
-	case "M", "MB", "MIB":
-		return uint64(bytes * MEGABYTE), nil
-	case "K", "KB", "KIB":
-		return uint64(bytes * KILOBYTE), nil
-	case "B", "":
-		return uint64(bytes), nil
-	default:
-		return 0, fmt.Errorf("invalid bandwidth %s", s)
-	}
-}
-
 type storageItem struct {
 	Pod          *podInfo
 	deletionTime *time.Time
@@ -318,6 +419,56 @@ func deserialize(data []byte) (interface{}, error) {
 	return item, nil
 }
 
+// MarkPodENIHealth annotate pod with reason of eni it uses failed and record event, annotation removed if reason empty
+func (k *k8s) MarkPodENIHealth(namespace, name, reason string) error {
+	var value interface{}
+	if reason != "" {
+		value = reason
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{podAnnotationENIUnhealthy: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	pod, err := k.client.CoreV1().Pods(namespace).Patch(name, k8stypes.MergePatchType, patch)
+	if err != nil {
+		return errors.Wrapf(err, "error annotate pod %s/%s", namespace, name)
+	}
+
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: name + ".",
+			Namespace:    namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:       "Pod",
+			APIVersion: "v1",
+			Namespace:  namespace,
+			Name:       name,
+			UID:        pod.UID,
+		},
+		Type:    corev1.EventTypeNormal,
+		Reason:  "ENIRecovered",
+		Message: "eni of pod recovered",
+		Source:  corev1.EventSource{Component: "terway", Host: k.nodeName},
+		Count:   1,
+	}
+	if reason != "" {
+		event.Type = corev1.EventTypeWarning
+		event.Reason = "ENIUnhealthy"
+		event.Message = fmt.Sprintf("eni of pod failed, recreate pod to get network on another eni: %s", reason)
+	}
+	event.FirstTimestamp = metav1.Now()
+	event.LastTimestamp = event.FirstTimestamp
+	if _, err = k.client.CoreV1().Events(namespace).Create(event); err != nil {
+		return errors.Wrapf(err, "error record event of pod %s/%s", namespace, name)
+	}
+	return nil
+}
+
 func (k *k8s) GetPod(namespace, name string) (*podInfo, error) {
 	pod, err := k.client.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
 	if err != nil {
@@ -335,7 +486,7 @@ func (k *k8s) GetPod(namespace, name string) (*podInfo, error) {
 		}
 		return nil, err
 	}
-	podInfo := convertPod(k.mode, pod)
+	podInfo := convertPod(k.mode, pod, k.getPodPolicy(pod))
 	item := &storageItem{
 		Pod: podInfo,
 	}
@@ -346,6 +497,20 @@ func (k *k8s) GetPod(namespace, name string) (*podInfo, error) {
 	return podInfo, nil
 }
 
+func (k *k8s) PeekPod(namespace, name, priorityClassName string) (*podInfo, error) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		Spec:       corev1.PodSpec{PriorityClassName: priorityClassName},
+	}
+	if name != "" {
+		var err error
+		if pod, err = k.client.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{}); err != nil {
+			return nil, errors.Wrapf(err, "error get pod %s/%s", namespace, name)
+		}
+	}
+	return convertPod(k.mode, pod, k.getPodPolicy(pod)), nil
+}
+
 func (k *k8s) GetNodeCidr() *net.IPNet {
 	return k.nodeCidr
 }
@@ -360,12 +525,61 @@ func (k *k8s) GetLocalPods() ([]*podInfo, error) {
 	}
 	var ret []*podInfo
 	for _, pod := range list.Items {
-		podInfo := convertPod(k.mode, &pod)
+		podInfo := convertPod(k.mode, &pod, k.getPodPolicy(&pod))
 		ret = append(ret, podInfo)
 	}
 
 	return ret, nil
 }
+
+// GetPendingPods pods scheduled to node but network not set up yet
+func (k *k8s) GetPendingPods() ([]*podInfo, error) {
+	options := metav1.ListOptions{
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermEqualSelector("spec.nodeName", k.nodeName),
+			fields.OneTermEqualSelector("status.phase", string(corev1.PodPending)),
+		).String(),
+	}
+	list, err := k.client.CoreV1().Pods(corev1.NamespaceAll).List(options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed listting pending pods on %s from apiserver", k.nodeName)
+	}
+	var ret []*podInfo
+	for _, pod := range list.Items {
+		if pod.Spec.HostNetwork || pod.Status.PodIP != "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		ret = append(ret, convertPod(k.mode, &pod, k.getPodPolicy(&pod)))
+	}
+	return ret, nil
+}
+
+// WatchLocalPods call onEvent on changes of pods on node until stop closed, rewatch if watch closed by apiserver
+func (k *k8s) WatchLocalPods(stop <-chan struct{}, onEvent func()) {
+	options := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", k.nodeName).String(),
+	}
+	wait.Until(func() {
+		w, err := k.client.CoreV1().Pods(corev1.NamespaceAll).Watch(options)
+		if err != nil {
+			log.Warnf("error watch pods on %s: %v", k.nodeName, err)
+			return
+		}
+		defer w.Stop()
+		for {
+			select {
+			case _, ok := <-w.ResultChan():
+				if !ok {
+					return
+				}
+				onEvent()
+			case <-stop:
+				return
+			}
+		}
+	}, podWatchRetryPeriod, stop)
+}
+
 func (k *k8s) GetServiceCidr() *net.IPNet {
 	return k.svcCidr
 }
@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/netfilter"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	// kubeProxyModeURL mode of kube-proxy served on its metrics address
+	kubeProxyModeURL      = "http://127.0.0.1:10249/proxyMode"
+	kubeProxyModeIPTables = "iptables"
+	kubeProxyModeIPVS     = "ipvs"
+	kubeProxyCheckPeriod  = 10 * time.Minute
+
+	kubeServicesChain = "KUBE-SERVICES"
+	kubeClusterIPSet  = "KUBE-CLUSTER-IP"
+	kubeIPVSLink      = "kube-ipvs0"
+
+	rpFilterAllPath = "/proc/sys/net/ipv4/conf/all/rp_filter"
+	rpFilterStrict  = 1
+
+	// checks of kube-proxy compatibility, label of metric
+	kubeProxyCheckMode     = "proxy_mode"
+	kubeProxyCheckRPFilter = "rp_filter"
+	kubeProxyCheckChain    = "iptables_chain"
+	kubeProxyCheckIPSet    = "ipset"
+	kubeProxyCheckIPVSLink = "ipvs_link"
+)
+
+// kubeProxyIssue incompatibility of node with routing of terway found by check
+type kubeProxyIssue struct {
+	check   string
+	message string
+}
+
+// kubeProxyChecker check mode of kube-proxy, rp_filter, and the chains and ipsets kube-proxy requires
+// on node are compatible with routing of terway, so broken service connectivity warned in advance
+type kubeProxyChecker struct {
+	daemonMode string
+	period     time.Duration
+	// proxyMode return mode of kube-proxy on node
+	proxyMode func() (string, error)
+	// rpFilter return net.ipv4.conf.all.rp_filter of host
+	rpFilter func() (int, error)
+	// chainExists return whether chain of table exists in iptables
+	chainExists func(table, chain string) (bool, error)
+	// ipsetExists return whether ipset of name exists
+	ipsetExists func(name string) (bool, error)
+	// linkExists return whether link of name exists on host
+	linkExists func(name string) bool
+}
+
+func newKubeProxyChecker(daemonMode string) *kubeProxyChecker {
+	return &kubeProxyChecker{
+		daemonMode:  daemonMode,
+		period:      kubeProxyCheckPeriod,
+		proxyMode:   getKubeProxyMode,
+		rpFilter:    getRPFilter,
+		chainExists: iptablesChainExists,
+		ipsetExists: ipsetExists,
+		linkExists: func(name string) bool {
+			_, err := netlink.LinkByName(name)
+			return err == nil
+		},
+	}
+}
+
+// check return incompatibilities of node with routing of terway
+func (c *kubeProxyChecker) check() []kubeProxyIssue {
+	var issues []kubeProxyIssue
+
+	// enis of pods routed by policy rules, replies on secondary enis dropped by strict reverse path filter
+	if c.daemonMode != daemonModeVPC {
+		rpFilter, err := c.rpFilter()
+		if err != nil {
+			log.Warnf("error get rp_filter of host: %v", err)
+		} else if rpFilter == rpFilterStrict {
+			issues = append(issues, kubeProxyIssue{kubeProxyCheckRPFilter,
+				"net.ipv4.conf.all.rp_filter is strict, traffic of pods on secondary enis may be dropped, set it to 0 or 2"})
+		}
+	}
+
+	mode, err := c.proxyMode()
+	if err != nil {
+		// metrics of kube-proxy not served on localhost, guess mode by the ipvs link
+		log.Debugf("error get mode of kube-proxy: %v", err)
+		mode = kubeProxyModeIPTables
+		if c.linkExists(kubeIPVSLink) {
+			mode = kubeProxyModeIPVS
+		}
+	}
+	switch mode {
+	case kubeProxyModeIPTables:
+		exists, err := c.chainExists("nat", kubeServicesChain)
+		if err != nil {
+			log.Warnf("error check chain %s of iptables: %v", kubeServicesChain, err)
+		} else if !exists {
+			issues = append(issues, kubeProxyIssue{kubeProxyCheckChain,
+				fmt.Sprintf("chain %s not found in nat table, is kube-proxy running in iptables mode on node?", kubeServicesChain)})
+		}
+	case kubeProxyModeIPVS:
+		exists, err := c.ipsetExists(kubeClusterIPSet)
+		if err != nil {
+			log.Warnf("error check ipset %s: %v", kubeClusterIPSet, err)
+		} else if !exists {
+			issues = append(issues, kubeProxyIssue{kubeProxyCheckIPSet,
+				fmt.Sprintf("ipset %s not found, is kube-proxy running in ipvs mode on node?", kubeClusterIPSet)})
+		}
+		if !c.linkExists(kubeIPVSLink) {
+			issues = append(issues, kubeProxyIssue{kubeProxyCheckIPVSLink,
+				fmt.Sprintf("link %s of cluster ips not found, is kube-proxy running in ipvs mode on node?", kubeIPVSLink)})
+		}
+	default:
+		issues = append(issues, kubeProxyIssue{kubeProxyCheckMode,
+			fmt.Sprintf("kube-proxy mode %q not supported by terway, run kube-proxy in iptables or ipvs mode", mode)})
+	}
+	return issues
+}
+
+// update warn issues found and export them by metric
+func (c *kubeProxyChecker) update(issues []kubeProxyIssue) {
+	metric.KubeProxyIncompatible.Reset()
+	for _, issue := range issues {
+		log.Warnf("node incompatible with terway, service connectivity of pods may fail: %s", issue.message)
+		metric.KubeProxyIncompatible.WithLabelValues(issue.check).Set(1)
+	}
+}
+
+func (c *kubeProxyChecker) start() {
+	go func() {
+		for {
+			c.update(c.check())
+			time.Sleep(c.period)
+		}
+	}()
+}
+
+func getKubeProxyMode() (string, error) {
+	client := &http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(kubeProxyModeURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d of %s", resp.StatusCode, kubeProxyModeURL)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func getRPFilter() (int, error) {
+	data, err := ioutil.ReadFile(rpFilterAllPath)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func iptablesChainExists(table, chain string) (bool, error) {
+	ipt, err := netfilter.New()
+	if err != nil {
+		return false, err
+	}
+	chains, err := ipt.ListChains(table)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range chains {
+		if c == chain {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func ipsetExists(name string) (bool, error) {
+	out, err := exec.Command("ipset", "list", "-n").Output()
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,58 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func issueChecks(issues []kubeProxyIssue) []string {
+	var checks []string
+	for _, issue := range issues {
+		checks = append(checks, issue.check)
+	}
+	return checks
+}
+
+func TestKubeProxyChecker(t *testing.T) {
+	var (
+		mode     = kubeProxyModeIPTables
+		modeErr  error
+		rpFilter = 2
+		chains   = map[string]bool{kubeServicesChain: true}
+		ipsets   = map[string]bool{}
+		links    = map[string]bool{}
+	)
+	c := &kubeProxyChecker{
+		daemonMode:  daemonModeENIMultiIP,
+		proxyMode:   func() (string, error) { return mode, modeErr },
+		rpFilter:    func() (int, error) { return rpFilter, nil },
+		chainExists: func(table, chain string) (bool, error) { return chains[chain], nil },
+		ipsetExists: func(name string) (bool, error) { return ipsets[name], nil },
+		linkExists:  func(name string) bool { return links[name] },
+	}
+	assert.Empty(t, c.check())
+
+	rpFilter = rpFilterStrict
+	assert.Equal(t, []string{kubeProxyCheckRPFilter}, issueChecks(c.check()))
+	// only policy routing of enis affected by strict rp_filter
+	c.daemonMode = daemonModeVPC
+	assert.Empty(t, c.check())
+
+	mode = kubeProxyModeIPVS
+	assert.Equal(t, []string{kubeProxyCheckIPSet, kubeProxyCheckIPVSLink}, issueChecks(c.check()))
+	ipsets[kubeClusterIPSet] = true
+	links[kubeIPVSLink] = true
+	assert.Empty(t, c.check())
+
+	mode = "userspace"
+	assert.Equal(t, []string{kubeProxyCheckMode}, issueChecks(c.check()))
+
+	// mode guessed by ipvs link when not served
+	modeErr = errors.New("connection refused")
+	assert.Empty(t, c.check())
+	links[kubeIPVSLink] = false
+	delete(chains, kubeServicesChain)
+	assert.Equal(t, []string{kubeProxyCheckChain}, issueChecks(c.check()))
+}
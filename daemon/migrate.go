@@ -0,0 +1,249 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// cnis ips of pods imported from on migration to terway
+const (
+	migrateFromFlannel = "flannel"
+	migrateFromCalico  = "calico"
+)
+
+// results of import of ip of pod
+const (
+	migratePlanned  = "planned"
+	migrateImported = "imported"
+	migrateSkipped  = "skipped"
+	migrateFailed   = "failed"
+)
+
+var (
+	// flannelIPAMDir files of host-local ipam of flannel, network named cbr0
+	flannelIPAMDir = filepath.Join(defaultIpamPath, "cbr0")
+	// calicoIPAMDir files of calico with host-local ipam, calico-ipam keeps no files on node
+	calicoIPAMDir = filepath.Join(defaultIpamPath, "k8s-pod-network")
+)
+
+// MigrateEntry ip of pod set up by previous cni and result of its import
+type MigrateEntry struct {
+	IP        string `json:"ip"`
+	SandboxID string `json:"sandboxID,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Result    string `json:"result"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// MigrateReport ips of pods planned or imported from previous cni
+type MigrateReport struct {
+	From    string         `json:"from"`
+	Entries []MigrateEntry `json:"entries"`
+	Applied bool           `json:"applied"`
+}
+
+// hostLocalSandboxIPs return sandbox ids by ip reserved by files of host-local ipam in dir, the first line
+// of file the sandbox id, newer host-local writes the interface name in the second
+func hostLocalSandboxIPs(dir string) (map[string]string, error) {
+	reservations, err := hostLocalReservations(dir)
+	if err != nil {
+		return nil, err
+	}
+	ips := make(map[string]string, len(reservations))
+	for ip, r := range reservations {
+		ips[ip] = ""
+		if fields := strings.Fields(r.sandboxID); len(fields) > 0 {
+			ips[ip] = fields[0]
+		}
+	}
+	return ips, nil
+}
+
+// migrationSource return sandbox ids by ip of pods set up by previous cni, from files of host-local ipam in
+// dir if exists, or else for calico, ips in status of pods of running sandboxes, eg: calico-ipam
+func migrationSource(from, dir string, sandboxes map[string]string, pods map[string]*podInfo) (map[string]string, error) {
+	if dir == "" {
+		switch from {
+		case migrateFromFlannel:
+			dir = flannelIPAMDir
+		case migrateFromCalico:
+			dir = calicoIPAMDir
+		default:
+			return nil, fmt.Errorf("unsupported cni %q to migrate from, flannel or calico", from)
+		}
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return hostLocalSandboxIPs(dir)
+	} else if !os.IsNotExist(err) || from != migrateFromCalico {
+		return nil, errors.Wrapf(err, "error read host-local ipam dir %s", dir)
+	}
+	ips := make(map[string]string)
+	for sandboxID, key := range sandboxes {
+		if pod, ok := pods[key]; ok && pod.PodIP != "" {
+			ips[pod.PodIP] = sandboxID
+		}
+	}
+	return ips, nil
+}
+
+// planMigration match ips of previous cni with running sandboxes and pods on node, the ip importable if the pod
+// gets vpc ip of node cidr by terway and has no resources allocated by terway yet
+func planMigration(ips map[string]string, sandboxes map[string]string, pods map[string]*podInfo, allocated map[string]bool, cidr *net.IPNet) []MigrateEntry {
+	var entries []MigrateEntry
+	for ip, sandboxID := range ips {
+		entry := MigrateEntry{IP: ip, SandboxID: sandboxID, Pod: sandboxes[sandboxID], Result: migrateSkipped}
+		pod := pods[entry.Pod]
+		switch {
+		case sandboxID == "":
+			entry.Reason = "no sandbox recorded"
+		case entry.Pod == "":
+			entry.Reason = "sandbox not running"
+		case pod == nil:
+			entry.Reason = "pod not found on node"
+		case pod.PodNetworkType != podNetworkTypeVPCIP:
+			entry.Reason = fmt.Sprintf("pod gets %s by terway, recreate the pod to migrate", pod.PodNetworkType)
+		case cidr == nil || !cidr.Contains(net.ParseIP(ip)):
+			entry.Reason = fmt.Sprintf("ip not in node cidr %s", cidr)
+		case pod.PodIP != "" && pod.PodIP != ip:
+			entry.Reason = fmt.Sprintf("pod has ip %s", pod.PodIP)
+		case allocated[entry.Pod]:
+			entry.Reason = "pod has resources of terway"
+		default:
+			entry.Result = migratePlanned
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].IP < entries[j].IP
+	})
+	return entries
+}
+
+// migrate import ips of pods set up by cni migrated from, the ips reserved in vpc ipam and pods related to them,
+// so the ips not taken by new pods and released on deletion of the pods, plan only if not apply
+func (networkService *networkService) migrate(from, dir string, apply bool) (*MigrateReport, error) {
+	if from != migrateFromFlannel && from != migrateFromCalico {
+		return nil, fmt.Errorf("unsupported cni %q to migrate from, flannel or calico", from)
+	}
+	networkService.Lock()
+	defer networkService.Unlock()
+	mgr, ok := networkService.mgrForResource[types.ResourceTypeVeth].(*vethResourceManager)
+	if !ok {
+		return nil, fmt.Errorf("pods with vpc ip not supported in daemon mode %s", networkService.daemonMode)
+	}
+
+	sandboxes, err := mgr.runtimeAPI.GetRunningPodSandboxes()
+	if err != nil {
+		return nil, err
+	}
+	localPods, err := networkService.k8s.GetLocalPods()
+	if err != nil {
+		return nil, err
+	}
+	pods := make(map[string]*podInfo, len(localPods))
+	for _, pod := range localPods {
+		pods[podInfoKey(pod.Namespace, pod.Name)] = pod
+	}
+	ips, err := migrationSource(from, dir, sandboxes, pods)
+	if err != nil {
+		return nil, err
+	}
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list resource db")
+	}
+	allocated := make(map[string]bool, len(resRelateList))
+	for _, resRelateObj := range resRelateList {
+		if resRelate := resRelateObj.(PodResources); resRelate.PodInfo != nil {
+			allocated[podInfoKey(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name)] = true
+		}
+	}
+
+	cidr := networkService.k8s.GetNodeCidr()
+	report := &MigrateReport{From: from, Applied: apply, Entries: planMigration(ips, sandboxes, pods, allocated, cidr)}
+	if !apply {
+		return report, nil
+	}
+	for i := range report.Entries {
+		entry := &report.Entries[i]
+		if entry.Result != migratePlanned {
+			continue
+		}
+		if err = networkService.importPodIP(mgr, pods[entry.Pod], entry, cidr); err != nil {
+			log.Warnf("error import ip %s of pod %s from %s: %v", entry.IP, entry.Pod, from, err)
+			entry.Result, entry.Reason = migrateFailed, err.Error()
+			continue
+		}
+		log.Infof("ip %s of pod %s imported from %s", entry.IP, entry.Pod, from)
+		entry.Result = migrateImported
+	}
+	return report, nil
+}
+
+// importPodIP reserve ip of entry for its sandbox and relate the pod to it as allocated by terway
+func (networkService *networkService) importPodIP(mgr *vethResourceManager, pod *podInfo, entry *MigrateEntry, cidr *net.IPNet) error {
+	if err := mgr.ipam.Reserve(cidr, net.ParseIP(entry.IP), entry.SandboxID); err != nil {
+		return err
+	}
+	veth := &types.Veth{HostVeth: hostVethOfPod(pod.Name, pod.Namespace)}
+	pod.PodIP = entry.IP
+	res := &PodResources{
+		PodInfo:     pod,
+		SandboxID:   entry.SandboxID,
+		AllocatedAt: time.Now().Unix(),
+		Resources: []ResourceItem{
+			{
+				ID:   veth.GetResourceID(),
+				Type: veth.GetType(),
+			},
+		},
+	}
+	err := networkService.resourceDB.Put(podResourceKey(res), *res)
+	if err != nil {
+		if releaseErr := mgr.ipam.Release(entry.SandboxID); releaseErr != nil {
+			log.Errorf("error release ip %s reserved for import: %v", entry.IP, releaseErr)
+		}
+		return errors.Wrapf(err, "error put resource into store")
+	}
+	return nil
+}
+
+// migrateHandler debug handler of migration from other cnis: GET /debug/migrate to plan, POST /debug/migrate
+// to import if allowApply, from of query the cni migrated from, dir of query the dir of its host-local ipam if not default
+func migrateHandler(networkService *networkService, allowApply bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var apply bool
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if !allowApply {
+				http.Error(w, errDebugActionsDisabled, http.StatusForbidden)
+				return
+			}
+			apply = true
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		report, err := networkService.migrate(r.URL.Query().Get("from"), r.URL.Query().Get("dir"), apply)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(report); err != nil {
+			log.Errorf("error write migrate report: %v", err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostLocalSandboxIPs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "10.244.1.2"), []byte("sandbox1\r\neth0"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "10.244.1.3"), []byte("sandbox2"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "last_reserved_ip.0"), []byte("10.244.1.3"), 0644))
+
+	ips, err := hostLocalSandboxIPs(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"10.244.1.2": "sandbox1", "10.244.1.3": "sandbox2"}, ips)
+
+	// calico-ipam keeps no files, ips in pod status of running sandboxes
+	pods := map[string]*podInfo{"default/web": {Name: "web", Namespace: "default", PodIP: "10.244.1.5"}}
+	ips, err = migrationSource(migrateFromCalico, filepath.Join(dir, "absent"), map[string]string{"sandbox5": "default/web"}, pods)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"10.244.1.5": "sandbox5"}, ips)
+	_, err = migrationSource(migrateFromFlannel, filepath.Join(dir, "absent"), nil, pods)
+	assert.Error(t, err)
+}
+
+func TestPlanMigration(t *testing.T) {
+	_, cidr, _ := net.ParseCIDR("10.244.1.0/24")
+	ips := map[string]string{
+		"10.244.1.2": "sandbox1",
+		"10.244.1.3": "sandbox2",
+		"10.244.1.4": "exited",
+		"10.244.1.5": "sandbox3",
+		"10.244.2.6": "sandbox4",
+	}
+	sandboxes := map[string]string{
+		"sandbox1": "default/web",
+		"sandbox2": "default/db",
+		"sandbox3": "default/eni",
+		"sandbox4": "default/other",
+	}
+	pods := map[string]*podInfo{
+		"default/web":   {Name: "web", Namespace: "default", PodIP: "10.244.1.2", PodNetworkType: podNetworkTypeVPCIP},
+		"default/db":    {Name: "db", Namespace: "default", PodIP: "10.244.1.3", PodNetworkType: podNetworkTypeVPCIP},
+		"default/eni":   {Name: "eni", Namespace: "default", PodIP: "10.244.1.5", PodNetworkType: podNetworkTypeVPCENI},
+		"default/other": {Name: "other", Namespace: "default", PodIP: "10.244.2.6", PodNetworkType: podNetworkTypeVPCIP},
+	}
+	entries := planMigration(ips, sandboxes, pods, map[string]bool{"default/db": true}, cidr)
+	assert.Len(t, entries, 5)
+	var results []string
+	for _, entry := range entries {
+		results = append(results, entry.Result)
+	}
+	assert.Equal(t, []string{migratePlanned, migrateSkipped, migrateSkipped, migrateSkipped, migrateSkipped}, results)
+	assert.Equal(t, "default/web", entries[0].Pod)
+	assert.Equal(t, "sandbox not running", entries[2].Reason)
+}
+
+func TestIPAMReserve(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.244.1.0/29")
+	ipam := &storeIPAM{store: newCRDIPAMStore(&fakeIPAMClient{ipams: make(map[string]*crd.NodeIPAM)}, "node1", subnet)}
+
+	assert.NoError(t, ipam.Reserve(subnet, net.ParseIP("10.244.1.2"), "sandbox1"))
+	assert.NoError(t, ipam.Reserve(subnet, net.ParseIP("10.244.1.2"), "sandbox1"))
+	assert.Error(t, ipam.Reserve(subnet, net.ParseIP("10.244.1.2"), "sandbox2"))
+	assert.Error(t, ipam.Reserve(subnet, net.ParseIP("10.244.2.2"), "sandbox2"))
+	// reserved ip skipped by allocation
+	ip, err := ipam.Allocate(subnet, "sandbox2")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.244.1.3", ip.String())
+}
@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// mirrorFilterPriority priority of tc filters mirroring traffic of pod on host veth
+	mirrorFilterPriority = 0xc000
+	// mirrorReconcilePeriod period to ensure mirrors of pods and remove vxlan links not used
+	mirrorReconcilePeriod = time.Minute
+)
+
+var (
+	ingressQdiscHandle = netlink.MakeHandle(0xffff, 0)
+	rootQdiscHandle    = netlink.MakeHandle(1, 0)
+)
+
+// ensureMirrorLink return host link of mirror target, vxlan link created if not exists
+func ensureMirrorLink(m *annotation.Mirror) (netlink.Link, error) {
+	target, err := netlink.LinkByName(m.LinkName())
+	if err == nil || m.Interface != "" {
+		return target, errors.Wrapf(err, "error get mirror interface %s", m.Interface)
+	}
+	vxlan := &netlink.Vxlan{
+		LinkAttrs: netlink.LinkAttrs{Name: m.LinkName()},
+		VxlanId:   m.VXLANVNI,
+		Group:     net.ParseIP(m.VXLANRemote),
+		Port:      m.VXLANPort,
+	}
+	if err = netlink.LinkAdd(vxlan); err != nil {
+		return nil, errors.Wrapf(err, "error add vxlan link %s to %s", vxlan.Name, m.VXLANRemote)
+	}
+	if err = netlink.LinkSetUp(vxlan); err != nil {
+		return nil, errors.Wrapf(err, "error set vxlan link %s up", vxlan.Name)
+	}
+	log.Infof("add vxlan link %s to %s:%d vni %d for traffic mirror", vxlan.Name, m.VXLANRemote, m.VXLANPort, m.VXLANVNI)
+	return netlink.LinkByName(vxlan.Name)
+}
+
+// mirrorFilter tc filter on parent of host veth mirroring all packets to target
+func mirrorFilter(veth netlink.Link, parent uint32, target netlink.Link) *netlink.U32 {
+	return &netlink.U32{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: veth.Attrs().Index,
+			Parent:    parent,
+			Priority:  mirrorFilterPriority,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Actions: []netlink.Action{
+			&netlink.MirredAction{
+				ActionAttrs:  netlink.ActionAttrs{Action: netlink.TC_ACT_PIPE},
+				MirredAction: netlink.TCA_EGRESS_MIRROR,
+				Ifindex:      target.Attrs().Index,
+			},
+		},
+	}
+}
+
+// removeMirrorFilters remove filters of mirror on host veth
+func removeMirrorFilters(veth netlink.Link) error {
+	for _, parent := range []uint32{ingressQdiscHandle, rootQdiscHandle} {
+		filters, err := netlink.FilterList(veth, parent)
+		if err != nil {
+			return errors.Wrapf(err, "error list filters of %s", veth.Attrs().Name)
+		}
+		for _, filter := range filters {
+			if filter.Attrs().Priority != mirrorFilterPriority {
+				continue
+			}
+			if err = netlink.FilterDel(filter); err != nil {
+				return errors.Wrapf(err, "error delete mirror filter of %s", veth.Attrs().Name)
+			}
+		}
+	}
+	return nil
+}
+
+// setupMirror mirror traffic of host veth to target, replacing mirror set before. packets from pod
+// mirrored on ingress of host veth, packets to pod on root qdisc of host veth
+func setupMirror(veth netlink.Link, m *annotation.Mirror) error {
+	target, err := ensureMirrorLink(m)
+	if err != nil {
+		return err
+	}
+	if err = removeMirrorFilters(veth); err != nil {
+		return err
+	}
+	if m.Direction != annotation.MirrorDirectionIngress {
+		ingress := &netlink.Ingress{QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: veth.Attrs().Index,
+			Handle:    ingressQdiscHandle,
+			Parent:    netlink.HANDLE_INGRESS,
+		}}
+		if err = netlink.QdiscReplace(ingress); err != nil {
+			return errors.Wrapf(err, "error add ingress qdisc of %s", veth.Attrs().Name)
+		}
+		if err = netlink.FilterAdd(mirrorFilter(veth, ingressQdiscHandle, target)); err != nil {
+			return errors.Wrapf(err, "error add egress mirror filter of %s", veth.Attrs().Name)
+		}
+	}
+	if m.Direction != annotation.MirrorDirectionEgress {
+		qdiscs, err := netlink.QdiscList(veth)
+		if err != nil {
+			return errors.Wrapf(err, "error list qdiscs of %s", veth.Attrs().Name)
+		}
+		for _, qdisc := range qdiscs {
+			// tbf of ingress bandwidth classless, no filter attached
+			if qdisc.Attrs().Parent == netlink.HANDLE_ROOT && qdisc.Type() == "tbf" {
+				return fmt.Errorf("ingress mirror of %s conflicts with ingress bandwidth of pod", veth.Attrs().Name)
+			}
+		}
+		prio := netlink.NewPrio(netlink.QdiscAttrs{
+			LinkIndex: veth.Attrs().Index,
+			Handle:    rootQdiscHandle,
+			Parent:    netlink.HANDLE_ROOT,
+		})
+		if err = netlink.QdiscReplace(prio); err != nil {
+			return errors.Wrapf(err, "error add root qdisc of %s", veth.Attrs().Name)
+		}
+		if err = netlink.FilterAdd(mirrorFilter(veth, rootQdiscHandle, target)); err != nil {
+			return errors.Wrapf(err, "error add ingress mirror filter of %s", veth.Attrs().Name)
+		}
+	}
+	return nil
+}
+
+// ensureMirror set up or remove mirror of pod traffic on host veth by policy of pod, nothing done
+// if pod without host veth, eg: ipvlan datapath
+func ensureMirror(pod *podInfo) error {
+	veth, err := netlink.LinkByName(hostVethOfPod(pod.Name, pod.Namespace))
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			if pod.Mirror != nil {
+				log.Debugf("no host veth of pod %s/%s to mirror traffic", pod.Namespace, pod.Name)
+			}
+			return nil
+		}
+		return err
+	}
+	// traffic of pod with exclusive eni not through host veth
+	if pod.Mirror == nil || pod.PodNetworkType == podNetworkTypeVPCENI {
+		return removeMirrorFilters(veth)
+	}
+	return setupMirror(veth, pod.Mirror)
+}
+
+// teardownMirror remove mirror of pod traffic on host veth, if not deleted with pod yet
+func teardownMirror(namespace, name string) error {
+	veth, err := netlink.LinkByName(hostVethOfPod(name, namespace))
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+	return removeMirrorFilters(veth)
+}
+
+// gcMirrorLinks delete vxlan links of mirror targets not used by pods
+func gcMirrorLinks(used map[string]bool) error {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return errors.Wrapf(err, "error list links")
+	}
+	for _, l := range links {
+		name := l.Attrs().Name
+		if l.Type() != "vxlan" || !strings.HasPrefix(name, annotation.MirrorLinkPrefix) || used[name] {
+			continue
+		}
+		if err = netlink.LinkDel(l); err != nil {
+			return errors.Wrapf(err, "error delete vxlan link %s", name)
+		}
+		log.Infof("delete vxlan link %s of traffic mirror not used", name)
+	}
+	return nil
+}
+
+// startMirrorReconcile ensure mirrors of pods on node and remove vxlan links not used in period
+func (networkService *networkService) startMirrorReconcile() {
+	go func() {
+		for {
+			time.Sleep(mirrorReconcilePeriod)
+			pods, err := networkService.k8s.GetLocalPods()
+			if err != nil {
+				log.Warnf("error get local pods for traffic mirror: %v", err)
+				continue
+			}
+			used := make(map[string]bool)
+			for _, pod := range pods {
+				if pod.Mirror != nil {
+					used[pod.Mirror.LinkName()] = true
+				}
+				if err = ensureMirror(pod); err != nil {
+					log.Warnf("error ensure traffic mirror of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+				}
+			}
+			if err = gcMirrorLinks(used); err != nil {
+				log.Warnf("error gc links of traffic mirror: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,274 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// netnsLeakMinAge netns files younger not taken as leaked, their sandboxes may be starting
+	netnsLeakMinAge = 10 * time.Minute
+
+	// kinds of leaked resources, label of metrics
+	netnsLeakKindNetns = "netns"
+	netnsLeakKindVeth  = "veth"
+)
+
+// netnsDirs dirs of netns files bind mounted by container runtimes
+var netnsDirs = []string{"/var/run/netns", "/var/run/docker/netns"}
+
+// netnsFile netns bind mounted on file by container runtime
+type netnsFile struct {
+	Path    string    `json:"path"`
+	Inode   uint64    `json:"inode"`
+	ModTime time.Time `json:"modTime"`
+	// Veths host side peers of veths in the netns
+	Veths []string `json:"veths,omitempty"`
+}
+
+// netnsLeakReport netns files leaked and their host veths found by scan
+type netnsLeakReport struct {
+	Leaks   []netnsFile `json:"leaks"`
+	Cleaned bool        `json:"cleaned"`
+}
+
+// netnsLeakScanner find netns files without any process in the netns left by crashed runtimes, with
+// the host veths of their pods, and remove them if cleanup enabled
+type netnsLeakScanner struct {
+	cleanup bool
+	minAge  time.Duration
+	// netnsFiles return netns files mounted in dirs of runtimes
+	netnsFiles func() ([]netnsFile, error)
+	// inUse return inodes of netns of processes on host
+	inUse func() (map[uint64]bool, error)
+	// peerVeths return host veths peered with veths in netns of file
+	peerVeths func(path string) ([]string, error)
+	// remove delete host veths of netns, and unmount and remove its file
+	remove func(leak netnsFile) error
+	// lock scans of period and debug requests
+	lock sync.Mutex
+}
+
+func newNetnsLeakScanner(cleanup bool) *netnsLeakScanner {
+	return &netnsLeakScanner{
+		cleanup:    cleanup,
+		minAge:     netnsLeakMinAge,
+		netnsFiles: listNetnsFiles,
+		inUse:      netnsOfProcesses,
+		peerVeths:  peerVethsOfNetns,
+		remove:     removeLeakedNetns,
+	}
+}
+
+// orphanNetns return netns files older than minAge not of any process
+func orphanNetns(files []netnsFile, inUse map[uint64]bool, now time.Time, minAge time.Duration) []netnsFile {
+	var orphans []netnsFile
+	for _, f := range files {
+		if inUse[f.Inode] || now.Sub(f.ModTime) < minAge {
+			continue
+		}
+		orphans = append(orphans, f)
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Path < orphans[j].Path })
+	return orphans
+}
+
+// scan find leaked netns and their host veths, removed if clean, and report the counts by metrics
+func (s *netnsLeakScanner) scan(clean bool) (*netnsLeakReport, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	files, err := s.netnsFiles()
+	if err != nil {
+		return nil, err
+	}
+	inUse, err := s.inUse()
+	if err != nil {
+		return nil, err
+	}
+	report := &netnsLeakReport{Leaks: orphanNetns(files, inUse, time.Now(), s.minAge), Cleaned: clean}
+	veths := 0
+	for i := range report.Leaks {
+		leak := &report.Leaks[i]
+		if leak.Veths, err = s.peerVeths(leak.Path); err != nil {
+			log.Warnf("error get host veths of leaked netns %s: %v", leak.Path, err)
+		}
+		veths += len(leak.Veths)
+	}
+	metric.NetnsLeaks.WithLabelValues(netnsLeakKindNetns).Set(float64(len(report.Leaks)))
+	metric.NetnsLeaks.WithLabelValues(netnsLeakKindVeth).Set(float64(veths))
+	if !clean || len(report.Leaks) == 0 {
+		return report, nil
+	}
+
+	for _, leak := range report.Leaks {
+		if err = s.remove(leak); err != nil {
+			return report, errors.Wrapf(err, "error remove leaked netns %s", leak.Path)
+		}
+		log.Infof("leaked netns %s with host veths %v removed", leak.Path, leak.Veths)
+		metric.NetnsLeakCleaned.WithLabelValues(netnsLeakKindNetns).Inc()
+		metric.NetnsLeakCleaned.WithLabelValues(netnsLeakKindVeth).Add(float64(len(leak.Veths)))
+	}
+	metric.NetnsLeaks.WithLabelValues(netnsLeakKindNetns).Set(0)
+	metric.NetnsLeaks.WithLabelValues(netnsLeakKindVeth).Set(0)
+	return report, nil
+}
+
+// collect scan leaked netns in garbage collection, removed if cleanup enabled
+func (s *netnsLeakScanner) collect() error {
+	report, err := s.scan(s.cleanup)
+	if err != nil {
+		return errors.Wrapf(err, "error scan leaked netns")
+	}
+	if len(report.Leaks) != 0 && !s.cleanup {
+		log.Warnf("%d leaked netns found, not removed as netns_leak_cleanup disabled", len(report.Leaks))
+	}
+	return nil
+}
+
+// handler debug handler of netns leaks: GET /debug/netns to scan, POST /debug/netns to scan and remove if allowClean
+func (s *netnsLeakScanner) handler(allowClean bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var clean bool
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if !allowClean {
+				http.Error(w, errDebugActionsDisabled, http.StatusForbidden)
+				return
+			}
+			clean = true
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		report, err := s.scan(clean)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(report); err != nil {
+			log.Errorf("error write netns leak report: %v", err)
+		}
+	})
+}
+
+// listNetnsFiles return files in dirs of runtimes with netns mounted, files not mounted in view of
+// daemon skipped, eg: without mount propagation of the host
+func listNetnsFiles() ([]netnsFile, error) {
+	var files []netnsFile
+	for _, dir := range netnsDirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "error list netns in %s", dir)
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.IsDir() || ns.IsNSorErr(path) != nil {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			files = append(files, netnsFile{
+				Path:    path,
+				Inode:   info.Sys().(*syscall.Stat_t).Ino,
+				ModTime: entry.ModTime(),
+			})
+		}
+	}
+	return files, nil
+}
+
+// netnsOfProcesses return inodes of netns of processes on host, requires hostPID
+func netnsOfProcesses() (map[uint64]bool, error) {
+	procs, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list processes")
+	}
+	inUse := make(map[uint64]bool)
+	for _, proc := range procs {
+		if _, err = strconv.Atoi(proc.Name()); err != nil {
+			continue
+		}
+		info, err := os.Stat(filepath.Join("/proc", proc.Name(), "ns", "net"))
+		if err != nil {
+			// process exited
+			continue
+		}
+		inUse[info.Sys().(*syscall.Stat_t).Ino] = true
+	}
+	return inUse, nil
+}
+
+// peerVethsOfNetns return host veths peered with veths in netns, the peer on host must point back to the veth
+func peerVethsOfNetns(path string) ([]string, error) {
+	// host ifindex of peer to index of veth in netns
+	peers := make(map[int]int)
+	err := ns.WithNetNSPath(path, func(_ ns.NetNS) error {
+		links, err := netlink.LinkList()
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			if _, ok := l.(*netlink.Veth); ok && l.Attrs().ParentIndex != 0 {
+				peers[l.Attrs().ParentIndex] = l.Attrs().Index
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var veths []string
+	for peerIndex, index := range peers {
+		l, err := netlink.LinkByIndex(peerIndex)
+		if err != nil {
+			continue
+		}
+		if _, ok := l.(*netlink.Veth); ok && l.Attrs().ParentIndex == index {
+			veths = append(veths, l.Attrs().Name)
+		}
+	}
+	sort.Strings(veths)
+	return veths, nil
+}
+
+// removeLeakedNetns delete host veths of leaked netns, and unmount and remove its file
+func removeLeakedNetns(leak netnsFile) error {
+	for _, name := range leak.Veths {
+		l, err := netlink.LinkByName(name)
+		if err != nil {
+			continue
+		}
+		if err = netlink.LinkDel(l); err != nil {
+			return errors.Wrapf(err, "error delete host veth %s", name)
+		}
+	}
+	if err := unix.Unmount(leak.Path, unix.MNT_DETACH); err != nil && err != unix.EINVAL {
+		return errors.Wrapf(err, "error unmount %s", leak.Path)
+	}
+	if err := os.Remove(leak.Path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error remove %s", leak.Path)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetnsLeakScanner(t *testing.T) {
+	now := time.Now()
+	files := []netnsFile{
+		{Path: "/var/run/netns/cni-live", Inode: 1, ModTime: now.Add(-time.Hour)},
+		{Path: "/var/run/netns/cni-starting", Inode: 2, ModTime: now.Add(-time.Minute)},
+		{Path: "/var/run/netns/cni-leaked", Inode: 3, ModTime: now.Add(-time.Hour)},
+	}
+	var removed []string
+	s := &netnsLeakScanner{
+		minAge:     netnsLeakMinAge,
+		netnsFiles: func() ([]netnsFile, error) { return files, nil },
+		inUse:      func() (map[uint64]bool, error) { return map[uint64]bool{1: true}, nil },
+		peerVeths:  func(path string) ([]string, error) { return []string{"calic95a4947e07"}, nil },
+		remove: func(leak netnsFile) error {
+			removed = append(removed, leak.Path)
+			return nil
+		},
+	}
+
+	report, err := s.scan(false)
+	assert.NoError(t, err)
+	assert.Len(t, report.Leaks, 1)
+	assert.Equal(t, "/var/run/netns/cni-leaked", report.Leaks[0].Path)
+	assert.Equal(t, []string{"calic95a4947e07"}, report.Leaks[0].Veths)
+	assert.Empty(t, removed)
+
+	report, err = s.scan(true)
+	assert.NoError(t, err)
+	assert.True(t, report.Cleaned)
+	assert.Equal(t, []string{"/var/run/netns/cni-leaked"}, removed)
+}
@@ -0,0 +1,152 @@
+package daemon
+
+import (
+	"strings"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	directionReceive  = "receive"
+	directionTransmit = "transmit"
+)
+
+var (
+	eniBytesDesc = prometheus.NewDesc("terway_eni_bytes_total",
+		"bytes received and transmitted by eni on node", []string{"eni", "direction"}, nil)
+	eniPacketsDesc = prometheus.NewDesc("terway_eni_packets_total",
+		"packets received and transmitted by eni on node", []string{"eni", "direction"}, nil)
+	eniDroppedDesc = prometheus.NewDesc("terway_eni_dropped_total",
+		"packets dropped on receive and transmit by eni on node", []string{"eni", "direction"}, nil)
+	podBytesDesc = prometheus.NewDesc("terway_pod_interface_bytes_total",
+		"bytes received and transmitted by pod through its host veth", []string{"namespace", "pod", "eni", "direction"}, nil)
+	podPacketsDesc = prometheus.NewDesc("terway_pod_interface_packets_total",
+		"packets received and transmitted by pod through its host veth", []string{"namespace", "pod", "eni", "direction"}, nil)
+	podDroppedDesc = prometheus.NewDesc("terway_pod_interface_dropped_total",
+		"packets of pod dropped on receive and transmit by its host veth", []string{"namespace", "pod", "eni", "direction"}, nil)
+)
+
+// podLink host veth of pod and mac of the eni its ip belongs to
+type podLink struct {
+	namespace string
+	name      string
+	eni       string
+	link      string
+}
+
+// linkStatsCollector collect counters of enis and host veths of pods on scrape
+type linkStatsCollector struct {
+	// enis return macs of enis in pools
+	enis func() map[string]string
+	// podLinks return host veths of pods on node
+	podLinks func() []podLink
+	// links return links on host with statistics
+	links func() ([]netlink.Link, error)
+}
+
+func (c *linkStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{eniBytesDesc, eniPacketsDesc, eniDroppedDesc, podBytesDesc, podPacketsDesc, podDroppedDesc} {
+		ch <- desc
+	}
+}
+
+func (c *linkStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	links, err := c.links()
+	if err != nil {
+		log.Warnf("error list links for statistics: %v", err)
+		return
+	}
+	byName := make(map[string]netlink.Link)
+	byAlias := make(map[string]netlink.Link)
+	byMAC := make(map[string]netlink.Link)
+	for _, l := range links {
+		byName[l.Attrs().Name] = l
+		if l.Attrs().Alias != "" {
+			byAlias[l.Attrs().Alias] = l
+		}
+		byMAC[l.Attrs().HardwareAddr.String()] = l
+	}
+	emit := func(bytes, packets, dropped *prometheus.Desc, stats *netlink.LinkStatistics, labels ...string) {
+		counters := []struct {
+			desc   *prometheus.Desc
+			rx, tx uint64
+		}{
+			{bytes, stats.RxBytes, stats.TxBytes},
+			{packets, stats.RxPackets, stats.TxPackets},
+			{dropped, stats.RxDropped, stats.TxDropped},
+		}
+		for _, counter := range counters {
+			ch <- prometheus.MustNewConstMetric(counter.desc, prometheus.CounterValue, float64(counter.rx), append(labels, directionReceive)...)
+			ch <- prometheus.MustNewConstMetric(counter.desc, prometheus.CounterValue, float64(counter.tx), append(labels, directionTransmit)...)
+		}
+	}
+	for mac := range c.enis() {
+		l, ok := byMAC[mac]
+		if !ok || l.Attrs().Statistics == nil {
+			continue
+		}
+		emit(eniBytesDesc, eniPacketsDesc, eniDroppedDesc, l.Attrs().Statistics, mac)
+	}
+	for _, pod := range c.podLinks() {
+		// veth named with fallback on collision found by its alias
+		l, ok := byAlias[link.VethAlias(pod.name, pod.namespace)]
+		if !ok {
+			l, ok = byName[pod.link]
+		}
+		if !ok || l.Attrs().Statistics == nil {
+			continue
+		}
+		// received by pod is transmitted by its host veth, and vice versa
+		stats := l.Attrs().Statistics
+		reversed := &netlink.LinkStatistics{
+			RxBytes: stats.TxBytes, TxBytes: stats.RxBytes,
+			RxPackets: stats.TxPackets, TxPackets: stats.RxPackets,
+			RxDropped: stats.TxDropped, TxDropped: stats.RxDropped,
+		}
+		emit(podBytesDesc, podPacketsDesc, podDroppedDesc, reversed, pod.namespace, pod.name, pod.eni)
+	}
+}
+
+// podLinks return host veths of pods in resource db with the eni of their resources
+func (networkService *networkService) podLinks() []podLink {
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		log.Warnf("error list resource db for links of pods: %v", err)
+		return nil
+	}
+	var links []podLink
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil {
+			continue
+		}
+		pod := podLink{
+			namespace: resRelate.PodInfo.Namespace,
+			name:      resRelate.PodInfo.Name,
+			link:      hostVethOfPod(resRelate.PodInfo.Name, resRelate.PodInfo.Namespace),
+		}
+		for _, res := range resRelate.Resources {
+			switch res.Type {
+			case types.ResourceTypeENIIP:
+				pod.eni = strings.SplitN(res.ID, ".", 2)[0]
+			case types.ResourceTypeENI:
+				pod.eni = res.ID
+			}
+		}
+		links = append(links, pod)
+	}
+	return links
+}
+
+// linkStatsCollector return collector of counters of enis and pods on node
+func (networkService *networkService) linkStatsCollector() *linkStatsCollector {
+	return &linkStatsCollector{
+		enis:     networkService.managedENIs,
+		podLinks: networkService.podLinks,
+		links:    netlink.LinkList,
+	}
+}
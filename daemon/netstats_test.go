@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/vishvananda/netlink"
+)
+
+func TestLinkStatsCollector(t *testing.T) {
+	mac, _ := net.ParseMAC("00:16:3e:00:00:01")
+	eni := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: "eth1", HardwareAddr: mac,
+		Statistics: &netlink.LinkStatistics{RxBytes: 1000, TxBytes: 2000, RxPackets: 10, TxPackets: 20, RxDropped: 1}}}
+	veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "cali123",
+		Statistics: &netlink.LinkStatistics{RxBytes: 300, TxBytes: 400, RxPackets: 3, TxPackets: 4, TxDropped: 2}}}
+	c := &linkStatsCollector{
+		enis: func() map[string]string { return map[string]string{"00:16:3e:00:00:01": "", "00:16:3e:00:00:02": ""} },
+		podLinks: func() []podLink {
+			return []podLink{
+				{namespace: "default", name: "web", eni: "00:16:3e:00:00:01", link: "cali123"},
+				{namespace: "default", name: "gone", link: "cali456"},
+			}
+		},
+		links: func() ([]netlink.Link, error) { return []netlink.Link{eni, veth}, nil },
+	}
+
+	names := map[*prometheus.Desc]string{
+		eniBytesDesc: "eni_bytes", eniPacketsDesc: "eni_packets", eniDroppedDesc: "eni_dropped",
+		podBytesDesc: "pod_bytes", podPacketsDesc: "pod_packets", podDroppedDesc: "pod_dropped",
+	}
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(ch)
+	close(ch)
+	// metric name and label values sorted by label name
+	values := make(map[string]float64)
+	for m := range ch {
+		pb := &dto.Metric{}
+		assert.NoError(t, m.Write(pb))
+		key := names[m.Desc()]
+		for _, label := range pb.Label {
+			key += " " + label.GetValue()
+		}
+		values[key] = pb.Counter.GetValue()
+	}
+	assert.Len(t, values, 12)
+	assert.Equal(t, float64(1000), values["eni_bytes receive 00:16:3e:00:00:01"])
+	assert.Equal(t, float64(2000), values["eni_bytes transmit 00:16:3e:00:00:01"])
+	assert.Equal(t, float64(1), values["eni_dropped receive 00:16:3e:00:00:01"])
+	// counters of pod reversed from its host veth
+	assert.Equal(t, float64(400), values["pod_bytes receive 00:16:3e:00:00:01 default web"])
+	assert.Equal(t, float64(3), values["pod_packets transmit 00:16:3e:00:00:01 default web"])
+	assert.Equal(t, float64(2), values["pod_dropped receive 00:16:3e:00:00:01 default web"])
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+const (
+	// pendingReleaseDir releases of pods deleted while daemon unreachable, queued by cni binary
+	pendingReleaseDir = "/var/lib/cni/terway/pending-release"
+	// pendingReleaseRetryPeriod period to retry pending releases failed
+	pendingReleaseRetryPeriod = time.Minute
+)
+
+// releasePending replay releases queued in dir by cni binary, return count of releases still pending
+func (networkService *networkService) releasePending(dir string) (int, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "error list pending releases")
+	}
+	pending := 0
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Warnf("error read pending release %s: %v", path, err)
+			pending++
+			continue
+		}
+		request := &rpc.ReleaseIPRequest{}
+		if err = json.Unmarshal(data, request); err != nil {
+			log.Warnf("invalid pending release %s, removed: %v", path, err)
+			os.Remove(path)
+			continue
+		}
+		reply, err := networkService.ReleaseIP(context.Background(), request)
+		if err != nil || !reply.GetSuccess() {
+			log.Warnf("error replay pending release of pod %s/%s: %v", request.K8SPodNamespace, request.K8SPodName, err)
+			pending++
+			continue
+		}
+		if err = os.Remove(path); err != nil {
+			log.Warnf("error remove pending release %s: %v", path, err)
+		}
+	}
+	return pending, nil
+}
+
+// startReleasePending replay releases queued while daemon down until all released
+func (networkService *networkService) startReleasePending() {
+	go func() {
+		for {
+			pending, err := networkService.releasePending(pendingReleaseDir)
+			if err != nil {
+				log.Warnf("error replay pending releases: %v", err)
+			} else if pending == 0 {
+				return
+			}
+			time.Sleep(pendingReleaseRetryPeriod)
+		}
+	}()
+}
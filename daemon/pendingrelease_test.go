@@ -0,0 +1,33 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleasePending(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pending-release")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	networkService := &networkService{}
+	pending, err := networkService.releasePending(filepath.Join(dir, "absent"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pending)
+
+	invalid := filepath.Join(dir, "default_pod_abc.json")
+	assert.NoError(t, ioutil.WriteFile(invalid, []byte("{"), 0600))
+	partial := filepath.Join(dir, "default_pod_def.json.tmp")
+	assert.NoError(t, ioutil.WriteFile(partial, []byte("{"), 0600))
+	pending, err = networkService.releasePending(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, pending)
+	_, err = os.Stat(invalid)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(partial)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,15 @@
+package daemon
+
+import (
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/AliyunContainerService/terway/rpc"
+)
+
+// rpcRoutes convert routes of pod to rpc
+func rpcRoutes(routes []annotation.Route) []*rpc.Route {
+	var ret []*rpc.Route
+	for _, route := range routes {
+		ret = append(ret, &rpc.Route{Dst: route.Dst, Gateway: route.Gateway})
+	}
+	return ret
+}
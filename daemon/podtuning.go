@@ -0,0 +1,28 @@
+package daemon
+
+import (
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// rpcInterfaceTuning convert interface tuning of pod to rpc, nil if not tuned
+func rpcInterfaceTuning(tuning *types.InterfaceTuning) *rpc.InterfaceTuning {
+	if tuning == nil {
+		return nil
+	}
+	ret := &rpc.InterfaceTuning{
+		CombinedQueues: int32(tuning.CombinedQueues),
+		RxRing:         int32(tuning.RxRing),
+		TxRing:         int32(tuning.TxRing),
+	}
+	for name, on := range map[string]*bool{"gro": tuning.GRO, "lro": tuning.LRO, "tso": tuning.TSO} {
+		if on == nil {
+			continue
+		}
+		if ret.Offloads == nil {
+			ret.Offloads = make(map[string]bool)
+		}
+		ret.Offloads[name] = *on
+	}
+	return ret
+}
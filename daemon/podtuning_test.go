@@ -0,0 +1,16 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCInterfaceTuning(t *testing.T) {
+	on, off := true, false
+	ret := rpcInterfaceTuning(&types.InterfaceTuning{CombinedQueues: 4, RxRing: 1024, GRO: &on, LRO: &off})
+	assert.Equal(t, int32(4), ret.CombinedQueues)
+	assert.Equal(t, map[string]bool{"gro": true, "lro": false}, ret.Offloads)
+	assert.Nil(t, rpcInterfaceTuning(nil))
+}
@@ -0,0 +1,266 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/AliyunContainerService/terway/types"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// policy keys of pod network, used as pod/namespace annotation and node label. security groups and vswitches
+// are not pod policies, enis of pods are allocated from pools of node in security group and vswitches of config
+const (
+	policyKeyENI              = annotation.ENI
+	policyKeyIngressBandwidth = annotation.IngressBandwidth
+	policyKeyEgressBandwidth  = annotation.EgressBandwidth
+	policyKeyLinkLocal        = annotation.LinkLocalAccess
+	policyKeyPodRoutes        = annotation.PodRoutes
+	// policyKeyPodSysctls and policyKeyInterfaceTuning replacing pod_sysctls and interface_tuning of config
+	policyKeyPodSysctls      = annotation.PodSysctls
+	policyKeyInterfaceTuning = annotation.InterfaceTuning
+	policyKeyENISpread       = annotation.ENISpread
+	policyKeyPodEIP          = annotation.PodEIP
+	policyKeyPodEIPBandwidth = annotation.PodEIPBandwidth
+	policyKeyPodEIPRetain    = annotation.PodEIPRetain
+	policyKeyTrafficMirror   = annotation.TrafficMirror
+	policyKeyNetworkMode     = annotation.NetworkMode
+	// limits of resources the namespace of pod can consume on node
+	policyKeyMaxNodeENIs   = "k8s.aliyun.com/max-node-enis"
+	policyKeyMaxNodeENIIPs = "k8s.aliyun.com/max-node-eniips"
+)
+
+var policyKeys = []string{
+	policyKeyENI,
+	policyKeyIngressBandwidth,
+	policyKeyEgressBandwidth,
+	policyKeyLinkLocal,
+	policyKeyPodRoutes,
+	policyKeyPodSysctls,
+	policyKeyInterfaceTuning,
+	policyKeyENISpread,
+	policyKeyNetworkMode,
+	policyKeyPodEIP,
+	policyKeyPodEIPBandwidth,
+	policyKeyPodEIPRetain,
+	policyKeyTrafficMirror,
+	policyKeyMaxNodeENIs,
+	policyKeyMaxNodeENIIPs,
+}
+
+// namespacePolicyKeys policies of namespace, can not be overridden by pod
+var namespacePolicyKeys = map[string]bool{
+	policyKeyMaxNodeENIs:   true,
+	policyKeyMaxNodeENIIPs: true,
+}
+
+// resource types limited by policy keys
+var limitPolicyKeys = map[string]string{
+	types.ResourceTypeENI:   policyKeyMaxNodeENIs,
+	types.ResourceTypeENIIP: policyKeyMaxNodeENIIPs,
+}
+
+// policy sources in precedence order
+const (
+	policySourcePod       = "pod annotation"
+	policySourceNamespace = "namespace annotation"
+	policySourceNode      = "node label"
+	policySourceCluster   = "cluster config"
+)
+
+// policyLayer values of policy keys from one source
+type policyLayer struct {
+	source string
+	values map[string]string
+}
+
+// policyDecision resolved value of policy key and the source won
+type policyDecision struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// podPolicy resolved policies of pod
+type podPolicy map[string]policyDecision
+
+func (p podPolicy) get(key string) (string, bool) {
+	d, ok := p[key]
+	return d.Value, ok
+}
+
+// decisions return decisions sorted by key for explain
+func (p podPolicy) decisions() []policyDecision {
+	var ret []policyDecision
+	for _, d := range p {
+		ret = append(ret, d)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Key < ret[j].Key
+	})
+	return ret
+}
+
+// resolvePolicy resolve policy keys by precedence:
+// pod annotation > namespace annotation > node label > cluster config,
+// namespace policies skip the pod annotation
+func resolvePolicy(pod *corev1.Pod, ns *corev1.Namespace, node *corev1.Node, cluster map[string]string) podPolicy {
+	return resolvePolicyKeys(policyKeys, pod, ns, node, cluster)
+}
+
+func resolvePolicyKeys(keys []string, pod *corev1.Pod, ns *corev1.Namespace, node *corev1.Node, cluster map[string]string) podPolicy {
+	layers := []policyLayer{{source: policySourcePod, values: pod.GetAnnotations()}}
+	if ns != nil {
+		layers = append(layers, policyLayer{source: policySourceNamespace, values: ns.GetAnnotations()})
+	}
+	if node != nil {
+		layers = append(layers, policyLayer{source: policySourceNode, values: node.GetLabels()})
+	}
+	layers = append(layers, policyLayer{source: policySourceCluster, values: cluster})
+
+	policy := make(podPolicy)
+	for _, key := range keys {
+		for _, layer := range layers {
+			if layer.source == policySourcePod && namespacePolicyKeys[key] {
+				continue
+			}
+			if value, ok := layer.values[key]; ok && value != "" {
+				policy[key] = policyDecision{Key: key, Value: value, Source: layer.source}
+				break
+			}
+		}
+	}
+	return policy
+}
+
+// PodPlacement network requirements of pod on node, used by scheduler extender
+type PodPlacement struct {
+	NetworkType  string
+	ResourceType string
+	// VSwitches vswitches of pod annotation, node must be in zone of one of them, empty for any
+	VSwitches []string
+}
+
+// ResolvePodPlacement resolve network requirements of pod on node of daemon mode by policies of pod, namespace
+// and node, the cluster config of daemon not visible to scheduler, and zones of pod by vswitches of pod annotation
+// only. nil if daemon mode unknown
+func ResolvePodPlacement(daemonMode string, pod *corev1.Pod, ns *corev1.Namespace, node *corev1.Node) *PodPlacement {
+	if !daemonModeSupported(daemonMode) {
+		return nil
+	}
+	policy := resolvePolicy(pod, ns, node, nil)
+	networkType := podNetworkType(daemonMode, pod, policy)
+	placement := &PodPlacement{
+		NetworkType:  networkType,
+		ResourceType: podResourceType(networkType),
+	}
+	if value := pod.GetAnnotations()[annotation.VSwitch]; value != "" {
+		for _, vSwitch := range strings.Split(value, ",") {
+			if vSwitch = strings.TrimSpace(vSwitch); vSwitch != "" {
+				placement.VSwitches = append(placement.VSwitches, vSwitch)
+			}
+		}
+	}
+	return placement
+}
+
+// policyCacheTTL namespaces and node cached for resolving policies of pods, changes of their
+// annotations and labels take effect on pods after it
+const policyCacheTTL = 30 * time.Second
+
+type policyCacheEntry struct {
+	object interface{}
+	expire time.Time
+}
+
+// policyCache cache namespaces and node shared by policy resolution of pods, avoid apiserver
+// requests on every pod
+type policyCache struct {
+	lock    sync.Mutex
+	ttl     time.Duration
+	entries map[string]*policyCacheEntry
+}
+
+func newPolicyCache(ttl time.Duration) *policyCache {
+	return &policyCache{ttl: ttl, entries: make(map[string]*policyCacheEntry)}
+}
+
+// get object by key from cache or fetch it out of lock, failures are not cached
+func (c *policyCache) get(key string, now time.Time, fetch func() (interface{}, error)) (interface{}, error) {
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	c.lock.Unlock()
+	if ok && now.Before(entry.expire) {
+		return entry.object, nil
+	}
+
+	object, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.entries[key] = &policyCacheEntry{object: object, expire: now.Add(c.ttl)}
+	c.lock.Unlock()
+	return object, nil
+}
+
+// getPodPolicy get namespace and node of pod then resolve pod policies
+func (k *k8s) getPodPolicy(pod *corev1.Pod) podPolicy {
+	var ns *corev1.Namespace
+	object, err := k.policyCache.get("namespace/"+pod.Namespace, time.Now(), func() (interface{}, error) {
+		return k.client.CoreV1().Namespaces().Get(pod.Namespace, metav1.GetOptions{})
+	})
+	if err != nil {
+		log.Warnf("error get namespace %s for pod policy: %v", pod.Namespace, err)
+	} else {
+		ns = object.(*corev1.Namespace)
+	}
+
+	var node *corev1.Node
+	object, err = k.policyCache.get("node/"+k.nodeName, time.Now(), func() (interface{}, error) {
+		return k.client.CoreV1().Nodes().Get(k.nodeName, metav1.GetOptions{})
+	})
+	if err != nil {
+		log.Warnf("error get node %s for pod policy: %v", k.nodeName, err)
+	} else {
+		node = object.(*corev1.Node)
+	}
+	return resolvePolicy(pod, ns, node, k.clusterPolicy)
+}
+
+// ExplainPod dry-run policy resolution of pod, shows which rule won for each policy
+func (k *k8s) ExplainPod(namespace, name string) ([]policyDecision, error) {
+	pod, err := k.client.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return k.getPodPolicy(pod).decisions(), nil
+}
+
+// explainHandler debug handler of pod policy explain: /debug/explain?namespace=&name=
+func explainHandler(k Kubernetes) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace, name := r.URL.Query().Get("namespace"), r.URL.Query().Get("name")
+		if namespace == "" || name == "" {
+			http.Error(w, "namespace and name of pod required", http.StatusBadRequest)
+			return
+		}
+		decisions, err := k.ExplainPod(namespace, name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error explain pod %s/%s: %v", namespace, name, err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(decisions); err != nil {
+			log.Errorf("error write explain result: %v", err)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolvePolicy(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		policyKeyIngressBandwidth: "10M",
+		policyKeyMaxNodeENIIPs:    "100",
+	}}}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		policyKeyIngressBandwidth: "20M",
+		policyKeyLinkLocal:        "block",
+	}}}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+		policyKeyLinkLocal: "allow",
+		policyKeyENISpread: "true",
+		annotation.VSwitch: "vsw-node",
+	}}}
+	cluster := map[string]string{
+		policyKeyLinkLocal:     "masquerade",
+		policyKeyENISpread:     "false",
+		policyKeyENI:           "",
+		policyKeyMaxNodeENIIPs: "10",
+	}
+
+	policy := resolvePolicy(pod, ns, node, cluster)
+	assert.Equal(t, policyDecision{Key: policyKeyIngressBandwidth, Value: "10M", Source: policySourcePod}, policy[policyKeyIngressBandwidth])
+	assert.Equal(t, policyDecision{Key: policyKeyLinkLocal, Value: "block", Source: policySourceNamespace}, policy[policyKeyLinkLocal])
+	assert.Equal(t, policyDecision{Key: policyKeyENISpread, Value: "true", Source: policySourceNode}, policy[policyKeyENISpread])
+	// vswitch not a pod policy, only of pod annotation for placement
+	_, ok := policy.get(annotation.VSwitch)
+	assert.False(t, ok)
+	assert.Empty(t, ResolvePodPlacement(daemonModeENIMultiIP, pod, ns, node).VSwitches)
+	pod.Annotations[annotation.VSwitch] = "vsw-1, vsw-2"
+	assert.Equal(t, []string{"vsw-1", "vsw-2"}, ResolvePodPlacement(daemonModeENIMultiIP, pod, ns, node).VSwitches)
+	delete(pod.Annotations, annotation.VSwitch)
+	// namespace limit not overridden by pod
+	assert.Equal(t, policyDecision{Key: policyKeyMaxNodeENIIPs, Value: "10", Source: policySourceCluster}, policy[policyKeyMaxNodeENIIPs])
+	_, ok = policy.get(policyKeyENI)
+	assert.False(t, ok)
+
+	policy = resolvePolicy(pod, nil, nil, cluster)
+	assert.Equal(t, policySourceCluster, policy[policyKeyLinkLocal].Source)
+	assert.Len(t, policy.decisions(), 4)
+}
+
+func TestPolicyCache(t *testing.T) {
+	cache := newPolicyCache(time.Minute)
+	fetched := 0
+	fetch := func() (interface{}, error) {
+		fetched++
+		return fetched, nil
+	}
+	now := time.Now()
+
+	object, err := cache.get("node/n1", now, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, object)
+	object, _ = cache.get("node/n1", now.Add(time.Second), fetch)
+	assert.Equal(t, 1, object)
+	// refetched after ttl
+	object, _ = cache.get("node/n1", now.Add(2*time.Minute), fetch)
+	assert.Equal(t, 2, object)
+
+	// failures not cached
+	_, err = cache.get("namespace/ns1", now, func() (interface{}, error) {
+		return nil, errors.New("apiserver down")
+	})
+	assert.Error(t, err)
+	object, err = cache.get("namespace/ns1", now, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, object)
+}
@@ -0,0 +1,193 @@
+package daemon
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/crd"
+)
+
+const (
+	// poolStatsWindow acquires of pools in the window recommendations based on
+	poolStatsWindow = 24 * time.Hour
+	// poolStatsMinWindow acquires of pools recorded at least before recommending
+	poolStatsMinWindow = time.Hour
+	// poolRefillPeriod pools refilled to min idle in period roughly, min idle serves acquires of the period
+	poolRefillPeriod = time.Minute
+	// poolBurstPeriod idle resources up to max idle serve bursts of acquires in period
+	poolBurstPeriod = 10 * time.Minute
+	// minIdlePercentile percentile of acquires per refill period covered by min idle
+	minIdlePercentile = 95
+)
+
+// poolUsage acquires of pool in a minute
+type poolUsage struct {
+	acquires int
+	// misses acquires without idle resource, waited for resource created
+	misses int
+	wait   time.Duration
+}
+
+// poolStats record hits, misses and wait time of acquires of pools by minute, and recommend min and
+// max idle of pools for the acquires in window
+type poolStats struct {
+	lock  sync.Mutex
+	start time.Time
+	// minIdle and maxIdle configured for pools
+	minIdle, maxIdle int
+	// usage resource type to usage by unix minute
+	usage map[string]map[int64]*poolUsage
+}
+
+func newPoolStats(minIdle, maxIdle int) *poolStats {
+	return &poolStats{
+		start:   time.Now(),
+		minIdle: minIdle,
+		maxIdle: maxIdle,
+		usage:   make(map[string]map[int64]*poolUsage),
+	}
+}
+
+// observe acquire of resource type at time, missed if created for the acquire
+func (s *poolStats) observe(resType string, at time.Time, miss bool, wait time.Duration) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	minutes, ok := s.usage[resType]
+	if !ok {
+		minutes = make(map[int64]*poolUsage)
+		s.usage[resType] = minutes
+	}
+	minute := at.Unix() / 60
+	usage, ok := minutes[minute]
+	if !ok {
+		usage = &poolUsage{}
+		minutes[minute] = usage
+	}
+	usage.acquires++
+	if miss {
+		usage.misses++
+	}
+	usage.wait += wait
+}
+
+// observeAllocation record acquire of allocation timed
+func (s *poolStats) observeAllocation(resType string, t *allocTiming) {
+	t.lock.Lock()
+	wait := t.phases[phasePoolWait]
+	created, miss := t.phases[t.createPhase]
+	t.lock.Unlock()
+	s.observe(resType, t.start, miss, wait+created)
+}
+
+// recommend min and max idle of pool of resource type by acquires in window till now, nil if window
+// recorded not long enough
+func (s *poolStats) recommend(resType string, now time.Time, capacity int) *crd.PoolRecommendation {
+	if s == nil {
+		return nil
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	window := now.Sub(s.start)
+	if window < poolStatsMinWindow {
+		return nil
+	}
+	if window > poolStatsWindow {
+		window = poolStatsWindow
+	}
+	// minutes completed in window, oldest first
+	current := now.Unix() / 60
+	first := current - int64(window/time.Minute)
+	minutes := s.usage[resType]
+	counts := make([]int, 0, current-first)
+	rec := &crd.PoolRecommendation{
+		CurrentMinIdle: s.minIdle,
+		CurrentMaxIdle: s.maxIdle,
+		WindowMinutes:  int(current - first),
+	}
+	var wait time.Duration
+	misses := 0
+	for minute := range minutes {
+		if minute < first {
+			delete(minutes, minute)
+		}
+	}
+	for minute := first; minute < current; minute++ {
+		usage, ok := minutes[minute]
+		if !ok {
+			counts = append(counts, 0)
+			continue
+		}
+		counts = append(counts, usage.acquires)
+		rec.Acquires += usage.acquires
+		misses += usage.misses
+		wait += usage.wait
+	}
+	if rec.Acquires != 0 {
+		rec.HitRatePercent = (rec.Acquires - misses) * 100 / rec.Acquires
+		rec.AvgWaitMs = int64(wait/time.Millisecond) / int64(rec.Acquires)
+	}
+
+	rec.MinIdle = percentile(sumOver(counts, int(poolRefillPeriod/time.Minute)), minIdlePercentile)
+	rec.MaxIdle = maxOf(sumOver(counts, int(poolBurstPeriod/time.Minute)))
+	if rec.MaxIdle < rec.MinIdle {
+		rec.MaxIdle = rec.MinIdle
+	}
+	if capacity > 0 {
+		rec.MinIdle, rec.MaxIdle = minInt(rec.MinIdle, capacity), minInt(rec.MaxIdle, capacity)
+	}
+	return rec
+}
+
+// sumOver return sums of counts in sliding windows of size
+func sumOver(counts []int, size int) []int {
+	if len(counts) < size {
+		size = len(counts)
+	}
+	var sums []int
+	sum := 0
+	for i, c := range counts {
+		sum += c
+		if i >= size {
+			sum -= counts[i-size]
+		}
+		if i >= size-1 {
+			sums = append(sums, sum)
+		}
+	}
+	return sums
+}
+
+// percentile return the nearest rank percentile of values, 0 if empty
+func percentile(values []int, p int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	rank := (len(sorted)*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func maxOf(values []int) int {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
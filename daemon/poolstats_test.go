@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolStatsRecommend(t *testing.T) {
+	start := time.Unix(1600000000, 0).Truncate(time.Minute)
+	s := newPoolStats(2, 5)
+	s.start = start
+	assert.Nil(t, s.recommend(types.ResourceTypeENIIP, start.Add(time.Minute), 0))
+
+	// 1 acquire each minute, a burst of 8 acquires in the 30th minute missing idle resources
+	for i := 0; i < 120; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		s.observe(types.ResourceTypeENIIP, at, false, time.Millisecond)
+		if i == 30 {
+			for j := 0; j < 8; j++ {
+				s.observe(types.ResourceTypeENIIP, at, true, 100*time.Millisecond)
+			}
+		}
+	}
+	rec := s.recommend(types.ResourceTypeENIIP, start.Add(120*time.Minute), 0)
+	assert.Equal(t, 1, rec.MinIdle)
+	assert.Equal(t, 18, rec.MaxIdle)
+	assert.Equal(t, 2, rec.CurrentMinIdle)
+	assert.Equal(t, 5, rec.CurrentMaxIdle)
+	assert.Equal(t, 128, rec.Acquires)
+	assert.Equal(t, 93, rec.HitRatePercent)
+	assert.Equal(t, int64(7), rec.AvgWaitMs)
+	assert.Equal(t, 120, rec.WindowMinutes)
+
+	// capped by capacity
+	rec = s.recommend(types.ResourceTypeENIIP, start.Add(120*time.Minute), 10)
+	assert.Equal(t, 10, rec.MaxIdle)
+
+	// acquires out of window pruned
+	rec = s.recommend(types.ResourceTypeENIIP, start.Add(poolStatsWindow+3*time.Hour), 0)
+	assert.Equal(t, 0, rec.MinIdle)
+	assert.Equal(t, 0, rec.Acquires)
+	assert.Empty(t, s.usage[types.ResourceTypeENIIP])
+
+	assert.Equal(t, 1, percentile([]int{1, 1, 1, 9}, 50))
+	assert.Equal(t, 9, percentile([]int{1, 1, 1, 9}, 95))
+	assert.Equal(t, []int{3, 5}, sumOver([]int{1, 2, 3}, 2))
+}
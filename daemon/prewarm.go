@@ -0,0 +1,57 @@
+package daemon
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// prewarmInterval min interval of pool prewarm, coalesces bursts of pod events in rollouts
+const prewarmInterval = time.Second
+
+// startPrewarm watch pods scheduled to node, grow pools toward the demand of pods pending network
+// setup ahead of their cni add
+func (networkService *networkService) startPrewarm() {
+	changed := make(chan struct{}, 1)
+	go networkService.k8s.WatchLocalPods(wait.NeverStop, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	go func() {
+		for range changed {
+			networkService.prewarm()
+			time.Sleep(prewarmInterval)
+		}
+	}()
+}
+
+func (networkService *networkService) prewarm() {
+	pods, err := networkService.k8s.GetPendingPods()
+	if err != nil {
+		log.Warnf("error get pending pods for prewarm: %v", err)
+		return
+	}
+	networkService.RLock()
+	defer networkService.RUnlock()
+	for resType, count := range networkService.pendingDemand(pods) {
+		if mgr := networkService.getResourceManagerForRes(resType); mgr != nil {
+			mgr.Prewarm(count)
+		}
+	}
+}
+
+// pendingDemand count of resources by type expected by pending pods, pods with resources allocated are skipped
+func (networkService *networkService) pendingDemand(pods []*podInfo) map[string]int {
+	demand := make(map[string]int)
+	for _, pod := range pods {
+		res, err := networkService.getPodResource(pod)
+		if err != nil || len(res.Resources) != 0 {
+			continue
+		}
+		demand[podResourceType(pod.PodNetworkType)]++
+	}
+	return demand
+}
@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// profileCheckPeriod period to check whether labels of node select another profile
+const profileCheckPeriod = time.Minute
+
+// applyProfile return config with top-level fields replaced by config of profile
+func applyProfile(config *types.Configure, profile *types.ConfigProfile) (*types.Configure, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	overrides := make(map[string]json.RawMessage)
+	if len(profile.Config) > 0 {
+		if err = json.Unmarshal(profile.Config, &overrides); err != nil {
+			return nil, errors.Wrapf(err, "error parse config of profile %s", profile.Name)
+		}
+	}
+	for key, value := range overrides {
+		if _, ok := fields[key]; !ok || key == "profiles" {
+			return nil, fmt.Errorf("unsupported config field %s of profile %s", key, profile.Name)
+		}
+		fields[key] = value
+	}
+	if data, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+	merged := &types.Configure{}
+	if err = json.Unmarshal(data, merged); err != nil {
+		return nil, errors.Wrapf(err, "error parse config of profile %s", profile.Name)
+	}
+	merged.Profiles = nil
+	return merged, nil
+}
+
+// selectProfile return the first profile selecting node of labels, nil if none
+func selectProfile(profiles []types.ConfigProfile, nodeLabels map[string]string) *types.ConfigProfile {
+	for i := range profiles {
+		if labels.SelectorFromSet(profiles[i].NodeSelector).Matches(labels.Set(nodeLabels)) {
+			return &profiles[i]
+		}
+	}
+	return nil
+}
+
+// profileName name of profile selected, empty for the global config
+func profileName(profile *types.ConfigProfile) string {
+	if profile == nil {
+		return ""
+	}
+	return profile.Name
+}
+
+// getNodeConfig read config file and apply the profile selected by labels of node, profile nil if none selected
+func getNodeConfig(configFilePath string, client kubernetes.Interface, nodeName string) (*types.Configure, *types.ConfigProfile, error) {
+	config, err := getConfigFromPath(configFilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(config.Profiles) == 0 {
+		return config, nil, nil
+	}
+	node, err := client.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error get labels of node %s", nodeName)
+	}
+	profile := selectProfile(config.Profiles, node.Labels)
+	if profile == nil {
+		config.Profiles = nil
+		return config, nil, nil
+	}
+	config, err = applyProfile(config, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, profile, nil
+}
+
+// watchProfile call changed once labels of node or profiles of config file select another profile than selected,
+// the daemon restarts to apply the profile
+func watchProfile(configFilePath string, client kubernetes.Interface, nodeName, selected string, changed func()) {
+	for {
+		time.Sleep(profileCheckPeriod)
+		_, profile, err := getNodeConfig(configFilePath, client, nodeName)
+		if err != nil {
+			log.Warnf("error check config profile of node: %v", err)
+			continue
+		}
+		if profileName(profile) != selected {
+			log.Infof("config profile of node changed from %q to %q, restarting", selected, profileName(profile))
+			changed()
+			return
+		}
+	}
+}
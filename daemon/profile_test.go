@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigProfile(t *testing.T) {
+	config := &types.Configure{
+		VSwitches:     map[string][]string{"zone-a": {"vsw-1"}, "zone-b": {"vsw-2"}},
+		SecurityGroup: "sg-1",
+		MaxPoolSize:   5,
+		Profiles: []types.ConfigProfile{
+			{
+				Name:         "gpu",
+				NodeSelector: map[string]string{"nodepool": "gpu"},
+				DaemonMode:   daemonModeENIOnly,
+				Config:       json.RawMessage(`{"vswitches": {"zone-a": ["vsw-3"]}, "max_pool_size": 10}`),
+			},
+			{Name: "default"},
+		},
+	}
+	assert.NoError(t, validateConfig(config))
+
+	profile := selectProfile(config.Profiles, map[string]string{"nodepool": "gpu", "zone": "a"})
+	assert.Equal(t, "gpu", profileName(profile))
+	assert.Equal(t, "default", profileName(selectProfile(config.Profiles, map[string]string{"nodepool": "cpu"})))
+	assert.Nil(t, selectProfile(config.Profiles[:1], nil))
+
+	// top-level fields replaced, others kept
+	merged, err := applyProfile(config, profile)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"zone-a": {"vsw-3"}}, merged.VSwitches)
+	assert.Equal(t, 10, merged.MaxPoolSize)
+	assert.Equal(t, "sg-1", merged.SecurityGroup)
+	assert.Nil(t, merged.Profiles)
+	assert.Equal(t, 5, config.MaxPoolSize)
+
+	config.Profiles[1].Config = json.RawMessage(`{"max_pool_sizes": 10}`)
+	assert.Error(t, validateConfig(config))
+	config.Profiles[1].Config = json.RawMessage(`{"min_pool_size": 10}`)
+	assert.Error(t, validateConfig(config))
+	config.Profiles[1].Config = nil
+	config.Profiles[1].Name = "gpu"
+	assert.Error(t, validateConfig(config))
+}
@@ -0,0 +1,156 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// drifts of pool from resources in cloud
+const (
+	driftVanished      = "vanished"
+	driftVanishedInuse = "vanished_inuse"
+	driftAdopted       = "adopted"
+)
+
+// cloudView resources of pool in cloud
+type cloudView struct {
+	// present ids of resources existing in cloud
+	present map[string]bool
+	// adoptable resources created by terway, adopted if not in pool
+	adoptable []types.NetworkResource
+	// enis ids of enis attached to instance
+	enis map[string]bool
+}
+
+func newCloudView() *cloudView {
+	return &cloudView{present: make(map[string]bool), enis: make(map[string]bool)}
+}
+
+// poolReconciler correct bookkeeping of pool by resources in cloud, eg: changed in console,
+// drifts are only acted on if seen in two rounds in a row to skip resources creating or disposing
+type poolReconciler struct {
+	resType   string
+	pool      pool.ObjectPool
+	listCloud func() (*cloudView, error)
+	// adopt track resource in factory before adopted by pool, nil if not tracked
+	adopt func(res types.NetworkResource)
+	// evict untrack resource in factory after evicted from pool, nil if not tracked
+	evict func(res types.NetworkResource, view *cloudView)
+
+	// suspects drifts seen in last round by resource id
+	suspects map[string]string
+}
+
+// reconcile compare pool with cloud, evict resources vanished and adopt resources unknown
+func (r *poolReconciler) reconcile() error {
+	view, err := r.listCloud()
+	if err != nil {
+		return errors.Wrapf(err, "error list %s resources in cloud", r.resType)
+	}
+	suspects := make(map[string]string)
+	known := make(map[string]bool)
+	vanishedInuse := 0
+	for _, item := range r.pool.Inventory() {
+		known[item.ID] = true
+		if view.present[item.ID] {
+			continue
+		}
+		drift := driftVanished
+		if item.State == pool.StateInuse {
+			drift = driftVanishedInuse
+		}
+		suspects[item.ID] = drift
+		if r.suspects[item.ID] != drift {
+			continue
+		}
+		if drift == driftVanishedInuse {
+			vanishedInuse++
+			log.Warnf("%s %s in use by pod vanished in cloud, evicted once released", r.resType, item.ID)
+			continue
+		}
+		if err = r.pool.Evict(item.ID); err != nil {
+			log.Warnf("error evict %s %s vanished in cloud: %v", r.resType, item.ID, err)
+			continue
+		}
+		if r.evict != nil {
+			r.evict(item.Resource, view)
+		}
+		log.Warnf("evicted %s %s vanished in cloud", r.resType, item.ID)
+		metric.ResourceDrift.WithLabelValues(r.resType, drift).Inc()
+	}
+	metric.ResourceVanishedInuse.WithLabelValues(r.resType).Set(float64(vanishedInuse))
+
+	for _, res := range view.adoptable {
+		id := res.GetResourceID()
+		if known[id] {
+			continue
+		}
+		suspects[id] = driftAdopted
+		if r.suspects[id] != driftAdopted {
+			continue
+		}
+		if r.adopt != nil {
+			r.adopt(res)
+		}
+		if err = r.pool.Adopt(res); err != nil {
+			log.Warnf("error adopt %s %s found in cloud: %v", r.resType, id, err)
+			if r.evict != nil {
+				r.evict(res, view)
+			}
+			continue
+		}
+		log.Warnf("adopted %s %s found in cloud", r.resType, id)
+		metric.ResourceDrift.WithLabelValues(r.resType, driftAdopted).Inc()
+	}
+	r.suspects = suspects
+	return nil
+}
+
+// ownedENIs return ids of enis created by terway attached to instance
+func ownedENIs(ecs aliyun.ECS, instanceID string) (map[string]bool, error) {
+	enis, err := ecs.ListOwnedENIs(instanceID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list enis created for instance")
+	}
+	owned := make(map[string]bool)
+	for _, eni := range enis {
+		if eni.InstanceID == instanceID {
+			owned[eni.ID] = true
+		}
+	}
+	return owned, nil
+}
+
+// poolReconcilerOf resource managers reconcilable with cloud
+type poolReconcilerOf interface {
+	poolReconciler() *poolReconciler
+}
+
+// startReconcile reconcile pools of resource managers with cloud in period
+func (networkService *networkService) startReconcile(period time.Duration) {
+	var reconcilers []*poolReconciler
+	for _, mgr := range networkService.mgrForResource {
+		if of, ok := mgr.(poolReconcilerOf); ok {
+			reconcilers = append(reconcilers, of.poolReconciler())
+		}
+	}
+	if len(reconcilers) == 0 {
+		return
+	}
+	go func() {
+		for {
+			time.Sleep(period)
+			for _, r := range reconcilers {
+				if err := r.reconcile(); err != nil {
+					log.Warnf("error reconcile pool with cloud: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/pkg/pool/pooltest"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolReconciler(t *testing.T) {
+	p, err := pool.NewSimpleObjectPool(pool.Config{
+		Factory: pooltest.NewFactory(),
+		Initializer: func(holder pool.ResourceHolder) error {
+			holder.AddIdle(&pooltest.Resource{ID: "idle"})
+			holder.AddIdle(&pooltest.Resource{ID: "gone"})
+			holder.AddInuse(&pooltest.Resource{ID: "gone-inuse"})
+			return nil
+		},
+		MaxIdle:  5,
+		Capacity: 10,
+	})
+	assert.NoError(t, err)
+
+	view := newCloudView()
+	view.present["idle"] = true
+	view.present["unknown"] = true
+	view.adoptable = []types.NetworkResource{&pooltest.Resource{ID: "idle"}, &pooltest.Resource{ID: "unknown"}}
+	var adopted, evicted []string
+	r := &poolReconciler{
+		resType:   pooltest.ResourceType,
+		pool:      p,
+		listCloud: func() (*cloudView, error) { return view, nil },
+		adopt:     func(res types.NetworkResource) { adopted = append(adopted, res.GetResourceID()) },
+		evict: func(res types.NetworkResource, view *cloudView) {
+			evicted = append(evicted, res.GetResourceID())
+		},
+	}
+
+	// drifts seen once are not acted on
+	assert.NoError(t, r.reconcile())
+	assert.Equal(t, pool.Snapshot{Inuse: []string{"gone-inuse"}, Idle: []string{"gone", "idle"}}, p.Snapshot())
+
+	assert.NoError(t, r.reconcile())
+	assert.Equal(t, pool.Snapshot{Inuse: []string{"gone-inuse"}, Idle: []string{"idle", "unknown"}}, p.Snapshot())
+	assert.Equal(t, []string{"unknown"}, adopted)
+	assert.Equal(t, []string{"gone"}, evicted)
+	assert.Equal(t, driftVanishedInuse, r.suspects["gone-inuse"])
+
+	// resource of pod released, evicted after seen again
+	assert.NoError(t, p.Release("gone-inuse"))
+	assert.NoError(t, r.reconcile())
+	assert.NoError(t, r.reconcile())
+	assert.Equal(t, pool.Snapshot{Idle: []string{"idle", "unknown"}}, p.Snapshot())
+}
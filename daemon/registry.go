@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ResourceManagerEnv dependencies to create resource managers of daemon mode
+type ResourceManagerEnv struct {
+	Config     *types.Configure
+	PoolConfig *types.PoolConfig
+	ECS        aliyun.ECS
+	// LocalResources ids of resources allocated to pods by resource type, restored from resource db
+	LocalResources map[string][]string
+	KubeClient     kubernetes.Interface
+
+	netSrv *networkService
+}
+
+// ResourceManagerFactory create resource managers of daemon mode by resource type
+type ResourceManagerFactory func(env *ResourceManagerEnv) (map[string]ResourceManager, error)
+
+var resourceManagerFactories = make(map[string]ResourceManagerFactory)
+
+// RegisterResourceManagers register factory of resource managers for daemon mode, eg: by init of
+// experimental modes built out of tree, panic if the mode registered twice
+func RegisterResourceManagers(daemonMode string, factory ResourceManagerFactory) {
+	if _, ok := resourceManagerFactories[daemonMode]; ok {
+		panic(fmt.Sprintf("resource managers of daemon mode %s registered twice", daemonMode))
+	}
+	resourceManagerFactories[daemonMode] = factory
+}
+
+// daemonModeSupported return whether resource managers of daemon mode registered
+func daemonModeSupported(daemonMode string) bool {
+	_, ok := resourceManagerFactories[daemonMode]
+	return ok
+}
+
+func init() {
+	RegisterResourceManagers(daemonModeVPC, newVPCModeResourceManagers)
+	RegisterResourceManagers(daemonModeENIMultiIP, newENIMultiIPModeResourceManagers)
+	RegisterResourceManagers(daemonModeENIOnly, newENIOnlyModeResourceManagers)
+	RegisterResourceManagers(daemonModeHybrid, newHybridModeResourceManagers)
+	RegisterResourceManagers(daemonModeDual, newDualModeResourceManagers)
+}
+
+func newVPCModeResourceManagers(env *ResourceManagerEnv) (map[string]ResourceManager, error) {
+	eniResMgr, err := newENIResourceManager(env.PoolConfig, env.ECS, env.LocalResources[types.ResourceTypeENI], nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init ENI resource manager")
+	}
+	ipam, err := newVPCIPAM(env)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init ipam of vpc ip")
+	}
+	vethResMgr, err := newVPCResourceManager(ipam, env.netSrv.gcProtection)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init vpc resource manager")
+	}
+	return map[string]ResourceManager{
+		types.ResourceTypeENI:  eniResMgr,
+		types.ResourceTypeVeth: vethResMgr,
+	}, nil
+}
+
+func newENIMultiIPModeResourceManagers(env *ResourceManagerEnv) (map[string]ResourceManager, error) {
+	eniIPResMgr, err := newENIIPModeResourceManager(env, nil)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]ResourceManager{
+		types.ResourceTypeENIIP: eniIPResMgr,
+	}, nil
+}
+
+// newENIIPModeResourceManager create eni ip resource manager with route tables and health monitor of enis
+func newENIIPModeResourceManager(env *ResourceManagerEnv, partition *eniPartition) (ResourceManager, error) {
+	netSrv, poolConfig := env.netSrv, env.PoolConfig
+	routeTableStore, err := newRouteTableStorage()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init route table storage")
+	}
+	routeTables, err := newRouteTableAllocator(routeTableStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init route table allocator")
+	}
+	netSrv.routeTables = routeTables
+
+	var eniHealth *eniHealthMonitor
+	if env.Config.ENIHealthCheckSeconds > 0 {
+		eniHealth = &eniHealthMonitor{
+			ecs:         env.ECS.WithPriority(aliyun.PriorityBackground),
+			instanceID:  poolConfig.InstanceID,
+			period:      time.Duration(env.Config.ENIHealthCheckSeconds) * time.Second,
+			managedENIs: partition.sharedENIs(netSrv.managedENIs),
+			checkLink:   link.CheckLinkUp,
+			onChange:    netSrv.markENIPods,
+		}
+		poolConfig.DeniedResource = func(res types.NetworkResource) bool {
+			return netSrv.ipDenyList.denied(res) || eniHealth.denied(res)
+		}
+	}
+	eniIPResMgr, err := newENIIPResourceManager(poolConfig, env.ECS, env.LocalResources[types.ResourceTypeENIIP], routeTables, eniHealth, partition)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init ENI ip resource manager")
+	}
+	if eniHealth != nil {
+		eniHealth.start()
+	}
+	return eniIPResMgr, nil
+}
+
+func newENIOnlyModeResourceManagers(env *ResourceManagerEnv) (map[string]ResourceManager, error) {
+	eniResMgr, err := newENIResourceManager(env.PoolConfig, env.ECS, env.LocalResources[types.ResourceTypeENI], nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init eni resource manager")
+	}
+	return map[string]ResourceManager{
+		types.ResourceTypeENI: eniResMgr,
+	}, nil
+}
+
+// newHybridModeResourceManagers create pools of exclusive enis and shared eni ips partitioning enis of instance
+func newHybridModeResourceManagers(env *ResourceManagerEnv) (map[string]ResourceManager, error) {
+	if env.Config.HybridExclusiveENIs <= 0 {
+		return nil, fmt.Errorf("hybrid_exclusive_enis must be positive in %s mode", daemonModeHybrid)
+	}
+	partitionStore, err := newENIPartitionStorage()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init eni partition storage")
+	}
+	partition, err := newENIPartition(partitionStore, env.Config.HybridExclusiveENIs)
+	if err != nil {
+		return nil, err
+	}
+
+	// pool sizes of config apply to eni ips, enis created for exclusive pods on demand
+	exclusiveConfig := *env.PoolConfig
+	exclusiveConfig.MinPoolSize = 0
+	eniResMgr, err := newENIResourceManager(&exclusiveConfig, env.ECS, env.LocalResources[types.ResourceTypeENI], partition)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init eni resource manager")
+	}
+	eniIPResMgr, err := newENIIPModeResourceManager(env, partition)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]ResourceManager{
+		types.ResourceTypeENI:   eniResMgr,
+		types.ResourceTypeENIIP: eniIPResMgr,
+	}, nil
+}
+
+// newDualModeResourceManagers create pool of eni ips and ipam of vpc ips of node cidr, the node cidr must not
+// overlap vswitches of vpc the eni ips allocated from
+func newDualModeResourceManagers(env *ResourceManagerEnv) (map[string]ResourceManager, error) {
+	nodeCidr := env.netSrv.k8s.GetNodeCidr()
+	if nodeCidr == nil {
+		return nil, fmt.Errorf("node cidr required in %s mode", daemonModeDual)
+	}
+	if err := checkNodeCidrOverlap(env.ECS, env.PoolConfig.VPC, nodeCidr); err != nil {
+		return nil, err
+	}
+
+	eniIPResMgr, err := newENIIPModeResourceManager(env, nil)
+	if err != nil {
+		return nil, err
+	}
+	ipam, err := newVPCIPAM(env)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init ipam of vpc ip")
+	}
+	vethResMgr, err := newVPCResourceManager(ipam, env.netSrv.gcProtection)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init vpc resource manager")
+	}
+	return map[string]ResourceManager{
+		types.ResourceTypeENIIP: eniIPResMgr,
+		types.ResourceTypeVeth:  vethResMgr,
+	}, nil
+}
+
+// checkNodeCidrOverlap fail if node cidr overlaps any vswitch of vpc, ips of node cidr would collide with eni ips
+func checkNodeCidrOverlap(ecs aliyun.ECS, vpcID string, nodeCidr *net.IPNet) error {
+	vSwitches, err := ecs.ListVSwitches(vpcID, "")
+	if err != nil {
+		return errors.Wrapf(err, "error list vswitches of vpc %s", vpcID)
+	}
+	for _, vSwitch := range vSwitches {
+		if vSwitch.CIDR != nil && (vSwitch.CIDR.Contains(nodeCidr.IP) || nodeCidr.Contains(vSwitch.CIDR.IP)) {
+			return fmt.Errorf("node cidr %s overlaps vswitch %s %s of eni ips", nodeCidr, vSwitch.ID, vSwitch.CIDR)
+		}
+	}
+	return nil
+}
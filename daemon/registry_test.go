@@ -0,0 +1,23 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterResourceManagers(t *testing.T) {
+	for _, mode := range []string{daemonModeVPC, daemonModeENIMultiIP, daemonModeENIOnly, daemonModeHybrid, daemonModeDual} {
+		assert.True(t, daemonModeSupported(mode), mode)
+	}
+	assert.False(t, daemonModeSupported("trunk"))
+
+	RegisterResourceManagers("trunk", func(env *ResourceManagerEnv) (map[string]ResourceManager, error) {
+		return nil, nil
+	})
+	defer delete(resourceManagerFactories, "trunk")
+	assert.True(t, daemonModeSupported("trunk"))
+	assert.Panics(t, func() {
+		RegisterResourceManagers(daemonModeVPC, newVPCModeResourceManagers)
+	})
+}
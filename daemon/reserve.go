@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+)
+
+// slotReserve reserve slots of pools for critical pods, eg: agents of daemonsets, so they still get network
+// after application pods consumed the rest of node
+type slotReserve struct {
+	reserved        int
+	namespaces      map[string]bool
+	priorityClasses map[string]bool
+
+	lock sync.Mutex
+	// pending resource type to allocations of non-critical pods in flight
+	pending map[string]int
+}
+
+// newSlotReserve return slot reserve of config, nil if no slot reserved
+func newSlotReserve(cfg *types.Configure) *slotReserve {
+	if cfg.ReservedSlots <= 0 {
+		return nil
+	}
+	r := &slotReserve{
+		reserved:        cfg.ReservedSlots,
+		namespaces:      make(map[string]bool),
+		priorityClasses: make(map[string]bool),
+		pending:         make(map[string]int),
+	}
+	for _, ns := range cfg.CriticalNamespaces {
+		r.namespaces[ns] = true
+	}
+	for _, pc := range cfg.CriticalPriorityClasses {
+		r.priorityClasses[pc] = true
+	}
+	return r
+}
+
+// critical return whether pod can take the slots reserved
+func (r *slotReserve) critical(pod *podInfo) bool {
+	return r.namespaces[pod.Namespace] || (pod.PriorityClassName != "" && r.priorityClasses[pod.PriorityClassName])
+}
+
+// check return error if pod refused by slots reserved, without taking a slot
+func (r *slotReserve) check(pod *podInfo, resType string, stats pool.Stats) error {
+	if r == nil || r.critical(pod) {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.checkLocked(pod, resType, stats)
+}
+
+func (r *slotReserve) checkLocked(pod *podInfo, resType string, stats pool.Stats) error {
+	free := stats.Capacity - stats.Inuse - stats.Quarantine - r.pending[resType]
+	if free <= r.reserved {
+		return errors.Wrapf(pool.ErrNoAvailableResource, "%d free %s slots on node reserved for critical pods, pod %s/%s not allocated",
+			free, resType, pod.Namespace, pod.Name)
+	}
+	return nil
+}
+
+// acquire take a slot of resource type from free slots of pool in stats for pod allocating, non-critical pods
+// refused once only the reserved slots left, done called after allocation finished
+func (r *slotReserve) acquire(pod *podInfo, resType string, stats pool.Stats) (done func(), err error) {
+	if r == nil || r.critical(pod) {
+		return func() {}, nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err = r.checkLocked(pod, resType, stats); err != nil {
+		return nil, err
+	}
+	r.pending[resType]++
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.pending[resType]--
+	}, nil
+}
@@ -0,0 +1,75 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlotReserve(t *testing.T) {
+	assert.Nil(t, newSlotReserve(&types.Configure{}))
+	var disabled *slotReserve
+	_, err := disabled.acquire(&podInfo{Namespace: "default"}, types.ResourceTypeENIIP, pool.Stats{Capacity: 1, Inuse: 1})
+	assert.NoError(t, err)
+
+	r := newSlotReserve(&types.Configure{
+		ReservedSlots:           2,
+		CriticalNamespaces:      []string{"kube-system"},
+		CriticalPriorityClasses: []string{"system-node-critical"},
+	})
+	app := &podInfo{Namespace: "default", Name: "app"}
+	agent := &podInfo{Namespace: "monitoring", Name: "agent", PriorityClassName: "system-node-critical"}
+	stats := pool.Stats{Capacity: 10, Inuse: 6, Quarantine: 1}
+
+	// allocations in flight take free slots
+	done, err := r.acquire(app, types.ResourceTypeENIIP, stats)
+	assert.NoError(t, err)
+	_, err = r.acquire(app, types.ResourceTypeENIIP, stats)
+	assert.Equal(t, pool.ErrNoAvailableResource, errors.Cause(err))
+	done()
+
+	stats.Inuse = 8
+	_, err = r.acquire(app, types.ResourceTypeENIIP, stats)
+	assert.Error(t, err)
+	_, err = r.acquire(agent, types.ResourceTypeENIIP, stats)
+	assert.NoError(t, err)
+	_, err = r.acquire(&podInfo{Namespace: "kube-system", Name: "proxy"}, types.ResourceTypeENIIP, stats)
+	assert.NoError(t, err)
+}
+
+// nodeK8s kubernetes of node name only
+type nodeK8s struct {
+	Kubernetes
+}
+
+func (k *nodeK8s) GetNodeName() string {
+	return "node-1"
+}
+
+func TestReserveNamespaceLimit(t *testing.T) {
+	networkService := &networkService{resourceDB: storage.NewMemoryStorage(), k8s: &nodeK8s{}}
+	assert.NoError(t, networkService.resourceDB.Put(podInfoKey("default", "running"), PodResources{
+		PodInfo:   &podInfo{Namespace: "default", Name: "running"},
+		Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: "eni-1.10.0.0.1"}},
+	}))
+
+	done, err := networkService.reserveNamespaceLimit(&podInfo{Namespace: "default", Name: "p1"}, types.ResourceTypeENIIP, 2)
+	assert.NoError(t, err)
+	// allocation of p1 in flight counted
+	_, err = networkService.reserveNamespaceLimit(&podInfo{Namespace: "default", Name: "p2"}, types.ResourceTypeENIIP, 2)
+	assert.Error(t, err)
+	assert.Error(t, networkService.checkNamespaceLimit(&podInfo{Namespace: "default", Name: "p2"}, types.ResourceTypeENIIP, 2))
+	// other namespaces not affected
+	_, err = networkService.reserveNamespaceLimit(&podInfo{Namespace: "other", Name: "p2"}, types.ResourceTypeENIIP, 1)
+	assert.NoError(t, err)
+
+	// released on allocation failed
+	done()
+	done, err = networkService.reserveNamespaceLimit(&podInfo{Namespace: "default", Name: "p2"}, types.ResourceTypeENIIP, 2)
+	assert.NoError(t, err)
+	done()
+}
@@ -1,12 +1,19 @@
 package daemon
 
 import (
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
 	"github.com/AliyunContainerService/terway/types"
 )
 
 const (
 	resDBPath = "/var/lib/cni/terway/ResRelation.db"
 	resDBName = "relation"
+
+	// defaultQuarantinePeriod cool-down of resources released with quarantine policy
+	// if quarantine period not configured
+	defaultQuarantinePeriod = time.Minute
 )
 
 // ResourceItem to be store
@@ -19,6 +26,25 @@ type ResourceItem struct {
 type PodResources struct {
 	Resources []ResourceItem
 	PodInfo   *podInfo
+	// SandboxID the infra container of pod which the resources bound to
+	SandboxID string
+	// AllocatedAt unix time the resources allocated to the sandbox, protected from gc in protection window
+	AllocatedAt int64
+}
+
+// ownedBy check the resources bound to the pod sandbox, relations recorded before
+// sandbox tracking or requests without sandbox id are treated as owned
+func (p PodResources) ownedBy(sandboxID string) bool {
+	return p.SandboxID == "" || sandboxID == "" || p.SandboxID == sandboxID
+}
+
+// transferableTo check the resources of previous sandbox can be handed over to new sandbox of pod without
+// release, the pod restarted in place, or recreated with sticky ip
+func (p PodResources) transferableTo(pod *podInfo, sandboxID string) bool {
+	if p.PodInfo == nil || p.SandboxID == "" || sandboxID == "" || p.SandboxID == sandboxID {
+		return false
+	}
+	return p.PodInfo.UID == "" || p.PodInfo.UID == pod.UID || pod.IPStickTime > 0
 }
 
 // GetResourceItemByType get pod resource by resource type
@@ -32,10 +58,42 @@ func (p PodResources) GetResourceItemByType(resType string) []ResourceItem {
 	return ret
 }
 
+// releaseToPool release resource to pool by release policy, the resource
+// stick to pod is always kept in pool for the pod to take back
+func releaseToPool(p pool.ObjectPool, policy string, context *networkContext, resID string) error {
+	var stick time.Duration
+	if context != nil && context.pod != nil {
+		stick = context.pod.IPStickTime
+	}
+	switch policy {
+	case types.ReleasePolicyCloud:
+		if stick == 0 {
+			return p.Dispose(resID)
+		}
+	case types.ReleasePolicyQuarantine:
+		// at least the quarantine period of pool if configured
+		return p.ReleaseWithQuarantine(resID, stick, defaultQuarantinePeriod)
+	}
+	if stick > 0 {
+		return p.ReleaseWithReverse(resID, stick)
+	}
+	return p.Release(resID)
+}
+
 // ResourceManager Allocate/Release/Pool/Stick/GC pod resource
 // managed pod and resource relationship
 type ResourceManager interface {
 	Allocate(context *networkContext, prefer string) (types.NetworkResource, error)
 	Release(context *networkContext, resID string) error
+	// Transfer hand resource of previous sandbox of pod over to the sandbox of context without release
+	Transfer(context *networkContext, fromSandbox, resID string) (types.NetworkResource, error)
 	GarbageCollection(inUseResList map[string]interface{}, expireResList map[string]interface{}) error
+	GetResourceIDs() []string
+	Stats() pool.Stats
+	// Inventory return resources in pool with state and age
+	Inventory() []pool.InventoryItem
+	// Prewarm grow pool toward count idle resources for pods expected
+	Prewarm(count int)
+	// Drain dispose all resources of pool not inuse and create no more, return count disposed
+	Drain() (int, error)
 }
@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"net"
+	"sort"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// priorities of policy rules of pods, same as the cni binary
+const (
+	toPodRulePriority   = 512
+	fromPodRulePriority = 2048
+	mainRouteTable      = 254
+)
+
+// podHostNetwork host side network of pod with eni ip in veth datapath, set up by cni binary on ADD
+type podHostNetwork struct {
+	podKey   string
+	hostVeth string
+	ip       net.IP
+	eni      *types.ENI
+}
+
+// hostNetworksToRestore host side networks of pods from bindings persisted and eni ips in pools,
+// pods of ipvlan datapath have no host side network
+func hostNetworksToRestore(pods []PodResources, resources map[ResourceItem]types.NetworkResource, ipvlan bool) []podHostNetwork {
+	if ipvlan {
+		return nil
+	}
+	var networks []podHostNetwork
+	for _, pod := range pods {
+		if pod.PodInfo == nil {
+			continue
+		}
+		for _, item := range pod.GetResourceItemByType(types.ResourceTypeENIIP) {
+			ip, ok := resources[item].(*types.ENIIP)
+			if !ok || ip.Eni == nil {
+				log.Warnf("eni ip %s of pod %s/%s not in pool, skip restore", item.ID, pod.PodInfo.Namespace, pod.PodInfo.Name)
+				continue
+			}
+			networks = append(networks, podHostNetwork{
+				podKey:   podInfoKey(pod.PodInfo.Namespace, pod.PodInfo.Name),
+				hostVeth: hostVethOfPod(pod.PodInfo.Name, pod.PodInfo.Namespace),
+				ip:       ip.SecAddress,
+				eni:      ip.Eni,
+			})
+		}
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].podKey < networks[j].podKey })
+	return networks
+}
+
+// ensureRule add policy rule if no same rule on host
+func ensureRule(rule *netlink.Rule, existing []netlink.Rule) error {
+	for _, r := range existing {
+		if r.Priority == rule.Priority && r.Table == rule.Table && r.IifName == rule.IifName &&
+			ipNetEqual(r.Src, rule.Src) && ipNetEqual(r.Dst, rule.Dst) {
+			return nil
+		}
+	}
+	return netlink.RuleAdd(rule)
+}
+
+func ipNetEqual(a, b *net.IPNet) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.IP.Equal(b.IP) && a.Mask.String() == b.Mask.String()
+}
+
+// restore rebuild routes and policy rules of pod on host, tableID the route table of eni of pod
+func (n *podHostNetwork) restore(veth, eni netlink.Link, tableID int) error {
+	podDst := &net.IPNet{IP: n.ip, Mask: net.CIDRMask(32, 32)}
+	err := netlink.RouteReplace(&netlink.Route{
+		LinkIndex: veth.Attrs().Index,
+		Scope:     netlink.SCOPE_LINK,
+		Dst:       podDst,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error restore route to pod")
+	}
+
+	if err = netlink.LinkSetUp(eni); err != nil {
+		return errors.Wrapf(err, "error set eni %s up", n.eni.MAC)
+	}
+	err = netlink.RouteReplace(&netlink.Route{
+		LinkIndex: eni.Attrs().Index,
+		Scope:     netlink.SCOPE_UNIVERSE,
+		Dst:       &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+		Table:     tableID,
+		Flags:     int(netlink.FLAG_ONLINK),
+		Gw:        n.eni.Gateway,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error restore default route of eni %s in table %d", n.eni.MAC, tableID)
+	}
+
+	rules, err := netlink.RuleList(netlink.FAMILY_V4)
+	if err != nil {
+		return errors.Wrapf(err, "error list rules")
+	}
+	toPod := netlink.NewRule()
+	toPod.Dst = podDst
+	toPod.Table = mainRouteTable
+	toPod.Priority = toPodRulePriority
+	if err = ensureRule(toPod, rules); err != nil {
+		return errors.Wrapf(err, "error restore rule to pod")
+	}
+	fromPod := netlink.NewRule()
+	fromPod.IifName = n.hostVeth
+	fromPod.Src = podDst
+	fromPod.Table = tableID
+	fromPod.Priority = fromPodRulePriority
+	return errors.Wrapf(ensureRule(fromPod, rules), "error restore rule from pod")
+}
+
+// restoreHostNetwork rebuild host side network of pods still running on node from persisted bindings,
+// eg: routes and rules flushed on reboot while sandboxes kept. pods without host veth are set up again
+// by ADD of kubelet, skipped.
+func (networkService *networkService) restoreHostNetwork() error {
+	if networkService.eniIPResMgr == nil {
+		return nil
+	}
+	ipvlan := false
+	if dp, err := loadDatapath(datapathStatePath); err == nil {
+		ipvlan = dp.ENIIPVirtualType == eniIPVirtualTypeIPVlan
+	}
+	pods, err := networkService.resourceDB.List()
+	if err != nil {
+		return errors.Wrapf(err, "error list resource db")
+	}
+	var bindings []PodResources
+	for _, pod := range pods {
+		bindings = append(bindings, pod.(PodResources))
+	}
+	resources := make(map[ResourceItem]types.NetworkResource)
+	for _, item := range networkService.eniIPResMgr.Inventory() {
+		resources[ResourceItem{Type: item.Type, ID: item.ID}] = item.Resource
+	}
+
+	for _, n := range hostNetworksToRestore(bindings, resources, ipvlan) {
+		veth, err := netlink.LinkByName(n.hostVeth)
+		if err != nil {
+			log.Debugf("no host veth %s of pod %s, skip restore: %v", n.hostVeth, n.podKey, err)
+			continue
+		}
+		index, err := linkIndexByMAC(n.eni.MAC)
+		if err != nil {
+			log.Warnf("error find eni %s of pod %s, skip restore: %v", n.eni.MAC, n.podKey, err)
+			continue
+		}
+		eni, err := netlink.LinkByIndex(index)
+		if err != nil {
+			log.Warnf("error get link of eni %s of pod %s, skip restore: %v", n.eni.MAC, n.podKey, err)
+			continue
+		}
+		tableID, err := networkService.routeTables.allocate(n.eni.MAC)
+		if err != nil || tableID == 0 {
+			tableID = legacyRouteTableBase + index
+		}
+		if err = n.restore(veth, eni, tableID); err != nil {
+			log.Warnf("error restore host network of pod %s: %v", n.podKey, err)
+			continue
+		}
+		log.Infof("restore host network of pod %s with ip %s on eni %s", n.podKey, n.ip, n.eni.MAC)
+	}
+	return nil
+}
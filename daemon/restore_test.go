@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostNetworksToRestore(t *testing.T) {
+	eni := &types.ENI{ID: "eni-1", MAC: "00:16:3e:00:00:01", Gateway: net.ParseIP("192.168.0.253")}
+	ip := &types.ENIIP{Eni: eni, SecAddress: net.ParseIP("192.168.0.10")}
+	resources := map[ResourceItem]types.NetworkResource{
+		{Type: types.ResourceTypeENIIP, ID: ip.GetResourceID()}: ip,
+	}
+	pods := []PodResources{
+		{
+			PodInfo:   &podInfo{Namespace: "default", Name: "web"},
+			Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: ip.GetResourceID()}},
+		},
+		// eni ip released from pool meanwhile
+		{
+			PodInfo:   &podInfo{Namespace: "default", Name: "gone"},
+			Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: eni.MAC + ".192.168.0.11"}},
+		},
+		{
+			PodInfo:   &podInfo{Namespace: "default", Name: "vpc"},
+			Resources: []ResourceItem{{Type: types.ResourceTypeVeth, ID: "veth"}},
+		},
+		{Resources: []ResourceItem{{Type: types.ResourceTypeENIIP, ID: ip.GetResourceID()}}},
+	}
+
+	networks := hostNetworksToRestore(pods, resources, false)
+	assert.Equal(t, []podHostNetwork{{
+		podKey:   podInfoKey("default", "web"),
+		hostVeth: link.VethNameForPod("web", "default", defaultPrefix),
+		ip:       ip.SecAddress,
+		eni:      eni,
+	}}, networks)
+
+	assert.Empty(t, hostNetworksToRestore(pods, resources, true))
+}
@@ -0,0 +1,249 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	routeTableDBPath = "/var/lib/cni/terway/RouteTable.db"
+	routeTableDBName = "route_table"
+
+	// policy route tables of enis allocated in [routeTableMin, routeTableMax]
+	routeTableMin = 1000
+	routeTableMax = 1999
+	// legacyRouteTableBase table id of eni was link index plus base before allocator
+	legacyRouteTableBase = 1000
+
+	rtTablesPath = "/etc/iproute2/rt_tables"
+)
+
+// routeTableRecord assignment of route table id to eni
+type routeTableRecord struct {
+	MAC     string `json:"mac"`
+	TableID int    `json:"table_id"`
+}
+
+// hostRouteTables usage of route tables on host
+type hostRouteTables struct {
+	// routes table id to link indexes of routes in table
+	routes map[int]map[int]bool
+	// rules table ids referenced by policy rules
+	rules map[int]bool
+	// named table ids named by user in rt_tables
+	named map[int]bool
+}
+
+// ownedBy return whether table is free or only used by routes of the link
+func (h *hostRouteTables) ownedBy(tableID, linkIndex int) bool {
+	if h.named[tableID] {
+		return false
+	}
+	links := h.routes[tableID]
+	if len(links) == 0 {
+		// rules to empty table not managed by us
+		return !h.rules[tableID]
+	}
+	for link := range links {
+		if link != linkIndex {
+			return false
+		}
+	}
+	return true
+}
+
+// routeTableAllocator allocate ids of policy route tables for enis, assignments persisted in storage
+type routeTableAllocator struct {
+	lock     sync.Mutex
+	store    storage.Storage
+	assigned map[string]int
+	// hostTables and linkIndex query host, replaced in tests
+	hostTables func() (*hostRouteTables, error)
+	linkIndex  func(mac string) (int, error)
+}
+
+func newRouteTableAllocator(store storage.Storage) (*routeTableAllocator, error) {
+	a := &routeTableAllocator{
+		store:      store,
+		assigned:   make(map[string]int),
+		hostTables: listHostRouteTables,
+		linkIndex:  linkIndexByMAC,
+	}
+	records, err := store.List()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list route table assignments")
+	}
+	for _, r := range records {
+		record := r.(routeTableRecord)
+		a.assigned[record.MAC] = record.TableID
+	}
+	return a, nil
+}
+
+func newRouteTableStorage() (storage.Storage, error) {
+	return storage.NewDiskStorage(routeTableDBName, routeTableDBPath, json.Marshal, func(bytes []byte) (interface{}, error) {
+		record := routeTableRecord{}
+		if err := json.Unmarshal(bytes, &record); err != nil {
+			return nil, errors.Wrapf(err, "error unmarshal route table record")
+		}
+		return record, nil
+	})
+}
+
+// allocate return route table id of eni, the legacy id preferred for enis set up before allocator
+func (a *routeTableAllocator) allocate(mac string) (int, error) {
+	if a == nil {
+		return 0, nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if id, ok := a.assigned[mac]; ok {
+		return id, nil
+	}
+
+	linkIndex, err := a.linkIndex(mac)
+	if err != nil {
+		return 0, err
+	}
+	host, err := a.hostTables()
+	if err != nil {
+		return 0, err
+	}
+	taken := make(map[int]bool, len(a.assigned))
+	for _, id := range a.assigned {
+		taken[id] = true
+	}
+
+	candidates := []int{legacyRouteTableBase + linkIndex}
+	for id := routeTableMin; id <= routeTableMax; id++ {
+		candidates = append(candidates, id)
+	}
+	for _, id := range candidates {
+		if taken[id] {
+			continue
+		}
+		if !host.ownedBy(id, linkIndex) {
+			log.Debugf("route table %d used on host, skip for eni %s", id, mac)
+			continue
+		}
+		if err = a.store.Put(mac, routeTableRecord{MAC: mac, TableID: id}); err != nil {
+			return 0, errors.Wrapf(err, "error persist route table of eni %s", mac)
+		}
+		a.assigned[mac] = id
+		log.Infof("allocate route table %d for eni %s", id, mac)
+		return id, nil
+	}
+	return 0, fmt.Errorf("no route table id available in [%d, %d] for eni %s", routeTableMin, routeTableMax, mac)
+}
+
+// release reclaim route table id of eni detached
+func (a *routeTableAllocator) release(mac string) error {
+	if a == nil {
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.releaseLocked(mac)
+}
+
+func (a *routeTableAllocator) releaseLocked(mac string) error {
+	id, ok := a.assigned[mac]
+	if !ok {
+		return nil
+	}
+	if err := a.store.Delete(mac); err != nil {
+		return errors.Wrapf(err, "error delete route table of eni %s", mac)
+	}
+	delete(a.assigned, mac)
+	log.Infof("release route table %d of eni %s", id, mac)
+	return nil
+}
+
+// reconcile reclaim route table ids of enis not attached, eg: detached when daemon not running
+func (a *routeTableAllocator) reconcile(attached map[string]bool) error {
+	if a == nil {
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for mac := range a.assigned {
+		if attached[mac] {
+			continue
+		}
+		if err := a.releaseLocked(mac); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func linkIndexByMAC(mac string) (int, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return 0, errors.Wrapf(err, "error list links")
+	}
+	for _, link := range links {
+		if link.Attrs().HardwareAddr.String() == mac {
+			return link.Attrs().Index, nil
+		}
+	}
+	return 0, fmt.Errorf("link of eni %s not found", mac)
+}
+
+// listHostRouteTables collect usage of route tables by routes, rules and rt_tables on host
+func listHostRouteTables() (*hostRouteTables, error) {
+	host := &hostRouteTables{
+		routes: make(map[int]map[int]bool),
+		rules:  make(map[int]bool),
+		named:  make(map[int]bool),
+	}
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V4,
+		&netlink.Route{Table: unix.RT_TABLE_UNSPEC}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list routes of all tables")
+	}
+	for _, route := range routes {
+		if host.routes[route.Table] == nil {
+			host.routes[route.Table] = make(map[int]bool)
+		}
+		host.routes[route.Table][route.LinkIndex] = true
+	}
+	rules, err := netlink.RuleList(netlink.FAMILY_V4)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list rules")
+	}
+	for _, rule := range rules {
+		host.rules[rule.Table] = true
+	}
+
+	f, err := os.Open(rtTablesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return host, nil
+		}
+		return nil, errors.Wrapf(err, "error read %s", rtTablesPath)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if id, err := strconv.Atoi(fields[0]); err == nil {
+			host.named[id] = true
+		}
+	}
+	return host, scanner.Err()
+}
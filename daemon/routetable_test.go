@@ -0,0 +1,50 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTableAllocator(t *testing.T) {
+	host := &hostRouteTables{
+		// legacy table of eni on link 3, user table on link 2 conflicts with legacy id of link 5
+		routes: map[int]map[int]bool{1003: {3: true}, 1005: {2: true}},
+		rules:  map[int]bool{1003: true, 1000: true},
+		named:  map[int]bool{1001: true},
+	}
+	links := map[string]int{"mac-3": 3, "mac-5": 5, "mac-6": 6}
+	store := storage.NewMemoryStorage()
+	a, err := newRouteTableAllocator(store)
+	assert.NoError(t, err)
+	a.hostTables = func() (*hostRouteTables, error) { return host, nil }
+	a.linkIndex = func(mac string) (int, error) { return links[mac], nil }
+
+	id, err := a.allocate("mac-3")
+	assert.NoError(t, err)
+	assert.Equal(t, 1003, id, "legacy table of eni kept")
+
+	id, err = a.allocate("mac-5")
+	assert.NoError(t, err)
+	assert.Equal(t, 1002, id, "skip tables used by rules, rt_tables and other links")
+
+	id, err = a.allocate("mac-6")
+	assert.NoError(t, err)
+	assert.Equal(t, 1006, id)
+
+	// assignments persisted
+	a, err = newRouteTableAllocator(store)
+	assert.NoError(t, err)
+	a.hostTables = func() (*hostRouteTables, error) { return host, nil }
+	a.linkIndex = func(mac string) (int, error) { return links[mac], nil }
+	id, err = a.allocate("mac-5")
+	assert.NoError(t, err)
+	assert.Equal(t, 1002, id)
+
+	assert.NoError(t, a.reconcile(map[string]bool{"mac-3": true}))
+	assert.NoError(t, a.release("mac-3"))
+	records, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
@@ -0,0 +1,330 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/kernel"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/version"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+)
+
+// status of checks of self check
+const (
+	selfCheckPass = "pass"
+	selfCheckWarn = "warn"
+	selfCheckFail = "fail"
+)
+
+// policies of self check on daemon start
+const (
+	// selfCheckPolicyWarn refuse to serve on failures, warnings only logged and reported
+	selfCheckPolicyWarn = "warn"
+	// selfCheckPolicyStrict refuse to serve on warnings as well
+	selfCheckPolicyStrict = "strict"
+)
+
+// selfCheckResult result of a check of self check, with hint to fix it unless passed
+type selfCheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// selfCheckReport results of self check of node and cloud resources on daemon start
+type selfCheckReport struct {
+	Time time.Time `json:"time"`
+	// Ready whether daemon serves with the results under policy
+	Ready   bool              `json:"ready"`
+	Policy  string            `json:"policy"`
+	Results []selfCheckResult `json:"results"`
+}
+
+// blocking return results refusing daemon to serve under policy of report
+func (r *selfCheckReport) blocking() []selfCheckResult {
+	var blocking []selfCheckResult
+	for _, result := range r.Results {
+		if result.Status == selfCheckFail || (result.Status == selfCheckWarn && r.Policy == selfCheckPolicyStrict) {
+			blocking = append(blocking, result)
+		}
+	}
+	return blocking
+}
+
+// err return error of results refusing daemon to serve, nil if ready
+func (r *selfCheckReport) err() error {
+	blocking := r.blocking()
+	if len(blocking) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(blocking))
+	for _, result := range blocking {
+		messages = append(messages, fmt.Sprintf("%s: %s (%s)", result.Name, result.Message, result.Hint))
+	}
+	return fmt.Errorf("self check %s failed: %s", r.Policy, strings.Join(messages, "; "))
+}
+
+// log each result of report, the ones not passed with hint
+func (r *selfCheckReport) log() {
+	for _, result := range r.Results {
+		switch result.Status {
+		case selfCheckPass:
+			log.Infof("self check %s passed: %s", result.Name, result.Message)
+		case selfCheckWarn:
+			log.Warnf("self check %s warning: %s, hint: %s", result.Name, result.Message, result.Hint)
+		default:
+			log.Errorf("self check %s failed: %s, hint: %s", result.Name, result.Message, result.Hint)
+		}
+	}
+}
+
+// selfChecker check permission of credential, cloud resources of config and prerequisites of datapath on node
+type selfChecker struct {
+	poolConfig *types.PoolConfig
+	daemonMode string
+	datapath   *datapath
+	ecs        aliyun.ECS
+	// moduleAvailable return whether kernel module loaded, built in or loadable, error if unknown
+	moduleAvailable func(name string) (bool, error)
+	// commandExists return whether command found in PATH
+	commandExists func(name string) bool
+	// linkExists return whether link of name exists on host
+	linkExists func(name string) bool
+	// rpFilter return net.ipv4.conf.all.rp_filter of host
+	rpFilter func() (int, error)
+}
+
+func newSelfChecker(poolConfig *types.PoolConfig, daemonMode string, ecs aliyun.ECS) *selfChecker {
+	dp, err := loadDatapath(datapathStatePath)
+	if err != nil || dp.DaemonMode != daemonMode {
+		dp = &datapath{DaemonMode: daemonMode}
+	}
+	return &selfChecker{
+		poolConfig:      poolConfig,
+		daemonMode:      daemonMode,
+		datapath:        dp,
+		ecs:             ecs,
+		moduleAvailable: kernelModuleAvailable,
+		commandExists: func(name string) bool {
+			_, err := exec.LookPath(name)
+			return err == nil
+		},
+		linkExists: func(name string) bool {
+			_, err := netlink.LinkByName(name)
+			return err == nil
+		},
+		rpFilter: getRPFilter,
+	}
+}
+
+// run all checks, report ready under policy
+func (c *selfChecker) run(policy string) *selfCheckReport {
+	if policy == "" {
+		policy = selfCheckPolicyWarn
+	}
+	report := &selfCheckReport{Time: time.Now(), Policy: policy}
+	for _, check := range []func() selfCheckResult{
+		c.checkPermission,
+		c.checkSecurityGroup,
+		c.checkVSwitches,
+		c.checkKernelModules,
+		c.checkIPTables,
+		c.checkIPVS,
+		c.checkRPFilter,
+	} {
+		report.Results = append(report.Results, check())
+	}
+	report.Ready = len(report.blocking()) == 0
+	return report
+}
+
+func (c *selfChecker) checkPermission() selfCheckResult {
+	result := selfCheckResult{Name: "openapi_permission", Status: selfCheckPass, Message: "describe and dry run of eni apis permitted"}
+	if err := c.ecs.CheckPermission(c.poolConfig.InstanceID); err != nil {
+		result.Status, result.Message = selfCheckFail, err.Error()
+		result.Hint = "grant the actions to the RAM role of instance, or the access key of eni.json"
+	}
+	return result
+}
+
+func (c *selfChecker) checkSecurityGroup() selfCheckResult {
+	result := selfCheckResult{Name: "security_group", Status: selfCheckPass}
+	if c.poolConfig.SecurityGroup == "" {
+		result.Message = "security group of instance used"
+		return result
+	}
+	vpc, err := c.ecs.GetSecurityGroupVPC(c.poolConfig.SecurityGroup)
+	switch {
+	case err != nil:
+		result.Status, result.Message = selfCheckFail, err.Error()
+		result.Hint = "set security_group of eni.json to a security group existing in region of node"
+	case vpc != c.poolConfig.VPC:
+		result.Status = selfCheckFail
+		result.Message = fmt.Sprintf("security group %s belongs to vpc %s, not the vpc %s of node",
+			c.poolConfig.SecurityGroup, vpc, c.poolConfig.VPC)
+		result.Hint = "set security_group of eni.json to a security group of vpc of node"
+	default:
+		result.Message = fmt.Sprintf("security group %s in vpc %s", c.poolConfig.SecurityGroup, vpc)
+	}
+	return result
+}
+
+func (c *selfChecker) checkVSwitches() selfCheckResult {
+	result := selfCheckResult{Name: "vswitch", Status: selfCheckPass}
+	vSwitches := append(append([]string{}, c.poolConfig.VSwitch...), c.poolConfig.OverflowVSwitch...)
+	var problems []string
+	for _, vSwitch := range vSwitches {
+		zone, _, err := c.ecs.DescribeVSwitch(vSwitch)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if zone != c.poolConfig.Zone {
+			problems = append(problems, fmt.Sprintf("vswitch %s in zone %s, not the zone %s of node", vSwitch, zone, c.poolConfig.Zone))
+		}
+	}
+	if len(problems) > 0 {
+		result.Status, result.Message = selfCheckFail, strings.Join(problems, "; ")
+		result.Hint = "set vswitches of eni.json to vswitches existing in zone of node"
+		return result
+	}
+	result.Message = fmt.Sprintf("vswitches %v in zone %s", vSwitches, c.poolConfig.Zone)
+	return result
+}
+
+// checkKernelModules check modules of links of pods in datapath available
+func (c *selfChecker) checkKernelModules() selfCheckResult {
+	result := selfCheckResult{Name: "kernel_module", Status: selfCheckPass}
+	var modules, missing, unknown []string
+	for _, capability := range c.datapath.requiredCapabilities() {
+		if capability != version.CapabilityVeth && capability != version.CapabilityIPVlan {
+			continue
+		}
+		modules = append(modules, capability)
+		available, err := c.moduleAvailable(capability)
+		switch {
+		case err != nil:
+			unknown = append(unknown, capability)
+		case !available:
+			missing = append(missing, capability)
+		}
+	}
+	switch {
+	case len(missing) > 0:
+		result.Status = selfCheckFail
+		result.Message = fmt.Sprintf("modules %v of datapath %s not available in kernel", missing, c.datapath)
+		result.Hint = "install the modules, or switch to datapath supported by kernel"
+	case len(unknown) > 0:
+		result.Status = selfCheckWarn
+		result.Message = fmt.Sprintf("modules %v of datapath %s neither loaded nor found in /lib/modules", unknown, c.datapath)
+		result.Hint = "load the modules by modprobe on node, or mount /lib/modules of host into daemon"
+	default:
+		result.Message = fmt.Sprintf("modules %v of datapath %s available", modules, c.datapath)
+	}
+	return result
+}
+
+func (c *selfChecker) checkIPTables() selfCheckResult {
+	if !c.commandExists("iptables") {
+		return selfCheckResult{Name: "iptables", Status: selfCheckWarn, Message: "iptables not found",
+			Hint: "install iptables in image of daemon, host ports and masquerade of pods require it"}
+	}
+	return selfCheckResult{Name: "iptables", Status: selfCheckPass, Message: "iptables found"}
+}
+
+// checkIPVS check ipvs prerequisites of kube-proxy if running in ipvs mode
+func (c *selfChecker) checkIPVS() selfCheckResult {
+	result := selfCheckResult{Name: "ipvs", Status: selfCheckPass}
+	if !c.linkExists(kubeIPVSLink) {
+		result.Message = "kube-proxy not in ipvs mode"
+		return result
+	}
+	if available, err := c.moduleAvailable("ip_vs"); err == nil && !available {
+		result.Status, result.Message = selfCheckWarn, "link kube-ipvs0 found but module ip_vs not available"
+		result.Hint = "load module ip_vs on node, or run kube-proxy in iptables mode"
+		return result
+	}
+	if !c.commandExists("ipset") {
+		result.Status, result.Message = selfCheckWarn, "kube-proxy in ipvs mode but ipset not found"
+		result.Hint = "install ipset in image of daemon to check ipsets of kube-proxy"
+		return result
+	}
+	result.Message = "kube-proxy in ipvs mode, ip_vs and ipset available"
+	return result
+}
+
+func (c *selfChecker) checkRPFilter() selfCheckResult {
+	result := selfCheckResult{Name: "rp_filter", Status: selfCheckPass}
+	// vpc mode routes pods by the primary eni only
+	if c.daemonMode == daemonModeVPC {
+		result.Message = "not required in vpc mode"
+		return result
+	}
+	rpFilter, err := c.rpFilter()
+	switch {
+	case err != nil:
+		result.Status, result.Message = selfCheckWarn, fmt.Sprintf("error get rp_filter: %v", err)
+		result.Hint = "check /proc/sys of host readable by daemon"
+	case rpFilter == rpFilterStrict:
+		result.Status = selfCheckWarn
+		result.Message = "net.ipv4.conf.all.rp_filter is strict, traffic of pods on secondary enis may be dropped"
+		result.Hint = "set net.ipv4.conf.all.rp_filter to 0 or 2 on node"
+	default:
+		result.Message = fmt.Sprintf("net.ipv4.conf.all.rp_filter is %d", rpFilter)
+	}
+	return result
+}
+
+// kernelModuleAvailable return whether module loaded, or built in or loadable by modules index of running kernel
+func kernelModuleAvailable(name string) (bool, error) {
+	if _, err := os.Stat(filepath.Join("/sys/module", name)); err == nil {
+		return true, nil
+	}
+	release, err := kernel.GetReleaseVersion()
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, index := range []string{"modules.dep", "modules.builtin"} {
+		data, err := ioutil.ReadFile(filepath.Join("/lib/modules", release, index))
+		if err != nil {
+			continue
+		}
+		found = true
+		for _, line := range strings.Split(string(data), "\n") {
+			path := strings.SplitN(line, ":", 2)[0]
+			if strings.SplitN(filepath.Base(path), ".ko", 2)[0] == name {
+				return true, nil
+			}
+		}
+	}
+	if !found {
+		return false, fmt.Errorf("modules index of kernel %s not found", release)
+	}
+	return false, nil
+}
+
+// readyzHandler serve report of self check, 503 if daemon refused to serve by it
+func readyzHandler(networkService *networkService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := networkService.selfCheck
+		w.Header().Set("Content-Type", "application/json")
+		if report == nil || !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Warnf("error write self check report: %v", err)
+		}
+	})
+}
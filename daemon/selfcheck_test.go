@@ -0,0 +1,74 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func resultStatus(report *selfCheckReport) map[string]string {
+	status := make(map[string]string)
+	for _, result := range report.Results {
+		status[result.Name] = result.Status
+	}
+	return status
+}
+
+func TestSelfCheck(t *testing.T) {
+	ecs, err := aliyun.NewFakeECS(&aliyun.FakeConfig{Zone: "cn-hangzhou-a", VPC: "vpc-1", VSwitch: "vsw-1",
+		SecurityGroup: "sg-1", InstanceID: "i-1"}, nil)
+	assert.NoError(t, err)
+	var (
+		modules  = map[string]bool{"veth": true}
+		commands = map[string]bool{"iptables": true}
+		links    = map[string]bool{}
+		rpFilter = 0
+	)
+	c := &selfChecker{
+		poolConfig: &types.PoolConfig{InstanceID: "i-1", Zone: "cn-hangzhou-a", VPC: "vpc-1",
+			VSwitch: []string{"vsw-1"}, SecurityGroup: "sg-1"},
+		daemonMode:      daemonModeENIMultiIP,
+		datapath:        &datapath{DaemonMode: daemonModeENIMultiIP},
+		ecs:             ecs,
+		moduleAvailable: func(name string) (bool, error) { return modules[name], nil },
+		commandExists:   func(name string) bool { return commands[name] },
+		linkExists:      func(name string) bool { return links[name] },
+		rpFilter:        func() (int, error) { return rpFilter, nil },
+	}
+	report := c.run("")
+	assert.True(t, report.Ready)
+	assert.Equal(t, selfCheckPolicyWarn, report.Policy)
+	assert.NoError(t, report.err())
+
+	// warnings served unless strict
+	rpFilter = rpFilterStrict
+	links[kubeIPVSLink] = true
+	report = c.run(selfCheckPolicyWarn)
+	assert.True(t, report.Ready)
+	assert.Equal(t, selfCheckWarn, resultStatus(report)["rp_filter"])
+	assert.Equal(t, selfCheckWarn, resultStatus(report)["ipvs"])
+	report = c.run(selfCheckPolicyStrict)
+	assert.False(t, report.Ready)
+	assert.Error(t, report.err())
+
+	// modules of datapath and cloud resources required
+	rpFilter = 0
+	links[kubeIPVSLink] = false
+	c.datapath.ENIIPVirtualType = eniIPVirtualTypeIPVlan
+	c.poolConfig.VSwitch = []string{"vsw-1", "vsw-2"}
+	report = c.run(selfCheckPolicyWarn)
+	assert.False(t, report.Ready)
+	assert.Equal(t, selfCheckFail, resultStatus(report)["kernel_module"])
+	assert.Equal(t, selfCheckFail, resultStatus(report)["vswitch"])
+	assert.Equal(t, selfCheckPass, resultStatus(report)["security_group"])
+
+	// unknown modules only warned, eg: /lib/modules not mounted
+	c.poolConfig.VSwitch = []string{"vsw-1"}
+	c.moduleAvailable = func(name string) (bool, error) { return false, errors.New("modules index not found") }
+	report = c.run(selfCheckPolicyWarn)
+	assert.True(t, report.Ready)
+	assert.Equal(t, selfCheckWarn, resultStatus(report)["kernel_module"])
+}
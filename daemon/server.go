@@ -5,16 +5,6 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
-
-	"github.com/AliyunContainerService/terway/pkg/metric"
-	"github.com/AliyunContainerService/terway/rpc"
-	"github.com/pkg/errors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	log "github.com/sirupsen/logrus"
-	"google.golang.org/grpc"
-
-	//import pprof for diagnose
-	_ "net/http/pprof"
 	"os"
 	"os/signal"
 	"path"
@@ -22,6 +12,19 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+
+	"github.com/AliyunContainerService/terway/pkg/fault"
+	"github.com/AliyunContainerService/terway/pkg/feature"
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/tracing"
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // stackTriger Print golang stack trace to log
@@ -49,7 +52,7 @@ func stackTriger() {
 }
 
 // Run terway daemon
-func Run(pidFilePath, socketFilePath, debugSocketListen, configFilePath, kubeconfig, master, daemonMode, logLevel string) error {
+func Run(pidFilePath, socketFilePath, debugSocketListen, configFilePath, kubeconfig, master, daemonMode, logLevel string, grpcConfig *GRPCConfig, cloudConfig *CloudConfig, enablePprof, enableDebugActions bool) error {
 	level, err := log.ParseLevel(logLevel)
 	if err != nil {
 		return errors.Wrapf(err, "error set log level: %s", logLevel)
@@ -76,23 +79,77 @@ func Run(pidFilePath, socketFilePath, debugSocketListen, configFilePath, kubecon
 	if err := syscall.Unlink(socketFilePath); err != nil && !os.IsNotExist(err) {
 		return err
 	}
-	mask := syscall.Umask(0777)
-	defer syscall.Umask(mask)
+	l, err := listenSecureSocket(socketFilePath, grpcConfig)
+	if err != nil {
+		return err
+	}
 
-	l, err := net.Listen("unix", socketFilePath)
+	k8sRestConfig, err := clientcmd.BuildConfigFromFlags(master, kubeconfig)
 	if err != nil {
-		return fmt.Errorf("error listen at %s: %v", socketFilePath, err)
+		return err
+	}
+	k8sClient, err := kubernetes.NewForConfig(k8sRestConfig)
+	if err != nil {
+		return err
+	}
+	nodeName, err := getNodeName(k8sClient)
+	if err != nil {
+		return err
+	}
+	config, profile, err := getNodeConfig(configFilePath, k8sClient, nodeName)
+	if err != nil {
+		return err
+	}
+	log.Infof("got config: %+v from: %s, profile: %q", config, configFilePath, profileName(profile))
+	if profile != nil && profile.DaemonMode != "" {
+		daemonMode = profile.DaemonMode
+	}
+
+	err = tracing.Init(&tracing.Config{
+		Endpoint:    config.TracingEndpoint,
+		ServiceName: "terway",
+		Attributes:  map[string]string{"host.name": nodeName},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error init tracing")
 	}
 
-	networkService, err := newNetworkService(configFilePath, kubeconfig, master, daemonMode)
+	daemonMode, err = ensureDatapath(daemonMode, config.CNIConf)
 	if err != nil {
+		return errors.Wrapf(err, "error check datapath compatibility")
+	}
+	if err = feature.Setup(config.FeatureGates, daemonMode); err != nil {
 		return err
 	}
 
-	grpcServer := grpc.NewServer()
+	networkService, err := newNetworkService(config, k8sClient, daemonMode, cloudConfig)
+	if err != nil {
+		return err
+	}
+
+	limiter := newInflightLimiter(config.MaxInflightRequests, config.MaxQueuedRequests)
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(limiter.interceptor))
 	rpc.RegisterTerwayBackendServer(grpcServer, networkService)
 	stop := make(chan struct{})
 
+	go watchProfile(configFilePath, k8sClient, nodeName, profileName(profile), func() {
+		stop <- struct{}{}
+	})
+
+	if grpcConfig.TLSListen != "" {
+		tlsServer, tlsListener, err := newTLSServer(grpcConfig, networkService)
+		if err != nil {
+			return err
+		}
+		defer tlsServer.Stop()
+		go func() {
+			if err := tlsServer.Serve(tlsListener); err != nil {
+				log.Errorf("error start grpc tls server: %v", err)
+				stop <- struct{}{}
+			}
+		}()
+	}
+
 	go func() {
 		sigs := make(chan os.Signal, 1)
 		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
@@ -102,7 +159,7 @@ func Run(pidFilePath, socketFilePath, debugSocketListen, configFilePath, kubecon
 	}()
 
 	stackTriger()
-	err = runDebugServer(debugSocketListen)
+	err = runDebugServer(debugSocketListen, networkService, enablePprof, enableDebugActions)
 	if err != nil {
 		return err
 	}
@@ -120,7 +177,9 @@ func Run(pidFilePath, socketFilePath, debugSocketListen, configFilePath, kubecon
 	return nil
 }
 
-func runDebugServer(debugSocketListen string) error {
+// runDebugServer serve metrics and debug endpoints on debugSocketListen, which may be tcp address, so the
+// endpoints changing state of daemon only allowed with enableDebugActions
+func runDebugServer(debugSocketListen string, networkService *networkService, enablePprof, enableDebugActions bool) error {
 	var (
 		l   net.Listener
 		err error
@@ -147,7 +206,21 @@ func runDebugServer(debugSocketListen string) error {
 	}
 
 	metric.RegisterPrometheus()
+	prometheus.MustRegister(networkService.linkStatsCollector())
 	http.DefaultServeMux.Handle("/metrics", promhttp.Handler())
+	http.DefaultServeMux.Handle("/readyz", readyzHandler(networkService))
+	http.DefaultServeMux.Handle("/debug/features", feature.Handler())
+	http.DefaultServeMux.Handle("/debug/defrag", defragHandler(networkService, enableDebugActions))
+	http.DefaultServeMux.Handle("/debug/migrate", migrateHandler(networkService, enableDebugActions))
+	http.DefaultServeMux.Handle("/debug/netns", networkService.netnsLeaks.handler(enableDebugActions))
+	if enablePprof {
+		registerPprof(http.DefaultServeMux)
+		http.DefaultServeMux.Handle("/debug/dump", dumpHandler(networkService))
+	}
+	if fault.Enabled {
+		log.Warnf("fault injection enabled, serve faults at /debug/faults")
+		http.DefaultServeMux.Handle("/debug/faults", fault.Handler())
+	}
 
 	go func() {
 		err := http.Serve(l, http.DefaultServeMux)
@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// simulateAllocation check whether pod could be allocated on node now by the checks of allocation, without
+// side effects, eg: for scheduler extender or webhook keeping pods failing at cni add off the node
+func (networkService *networkService) simulateAllocation(podinfo *podInfo) (*rpc.SimulateAllocationReply, error) {
+	reply := &rpc.SimulateAllocationReply{PodNetworkType: podinfo.PodNetworkType}
+	if !networkService.verifyPodNetworkType(podinfo.PodNetworkType) {
+		reply.Reason = fmt.Sprintf("pod network type %s not supported in %s mode", podinfo.PodNetworkType, networkService.daemonMode)
+		return reply, nil
+	}
+	resType := podResourceType(podinfo.PodNetworkType)
+	reply.ResourceType = resType
+	mgr := networkService.getResourceManagerForRes(resType)
+	// veth not pooled
+	if mgr == nil || resType == types.ResourceTypeVeth {
+		reply.Allocatable = true
+		return reply, nil
+	}
+	// pods taking back resources allocated before
+	if podinfo.Name != "" {
+		oldRes, err := networkService.getPodResource(podinfo)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error get pod resources from db for pod %s/%s", podinfo.Namespace, podinfo.Name)
+		}
+		if len(oldRes.GetResourceItemByType(resType)) != 0 {
+			reply.Allocatable = true
+			return reply, nil
+		}
+	}
+	if limit, ok := podinfo.NamespaceLimits[resType]; ok {
+		if err := networkService.checkNamespaceLimit(podinfo, resType, limit); err != nil {
+			reply.Reason = err.Error()
+			return reply, nil
+		}
+	}
+
+	stats := mgr.Stats()
+	reply.Idle = int32(stats.Idle)
+	reply.Free = int32(stats.Capacity - stats.Inuse - stats.Quarantine)
+	if reply.Free <= 0 {
+		reply.Reason = fmt.Sprintf("no free %s slot on node, %d inuse and %d in quarantine of %d", resType,
+			stats.Inuse, stats.Quarantine, stats.Capacity)
+		return reply, nil
+	}
+	if err := networkService.slotReserve.check(podinfo, resType, stats); err != nil {
+		reply.Reason = err.Error()
+		return reply, nil
+	}
+	reply.NeedCreate = stats.Idle == 0
+	reply.Allocatable = true
+	return reply, nil
+}
+
+// SimulateAllocation answer whether pod of the network requirements could be allocated on node now, nothing allocated
+func (networkService *networkService) SimulateAllocation(ctx context.Context, r *rpc.SimulateAllocationRequest) (*rpc.SimulateAllocationReply, error) {
+	if r.K8SPodNamespace == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "namespace of pod required")
+	}
+	podinfo, err := networkService.k8s.PeekPod(r.K8SPodNamespace, r.K8SPodName, r.PriorityClassName)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "error get network requirements of pod: %v", err)
+	}
+	if r.PodNetworkType != "" {
+		podinfo.PodNetworkType = r.PodNetworkType
+	}
+
+	networkService.RLock()
+	defer networkService.RUnlock()
+	return networkService.simulateAllocation(podinfo)
+}
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// statsManager resource manager of pool stats only
+type statsManager struct {
+	ResourceManager
+	stats pool.Stats
+}
+
+func (m *statsManager) Stats() pool.Stats {
+	return m.stats
+}
+
+func TestSimulateAllocation(t *testing.T) {
+	mgr := &statsManager{stats: pool.Stats{Capacity: 10, Inuse: 8, Idle: 1}}
+	networkService := &networkService{
+		daemonMode:     daemonModeENIMultiIP,
+		mgrForResource: map[string]ResourceManager{types.ResourceTypeENIIP: mgr},
+		slotReserve:    newSlotReserve(&types.Configure{ReservedSlots: 1, CriticalNamespaces: []string{"kube-system"}}),
+	}
+	pod := &podInfo{Namespace: "default", PodNetworkType: podNetworkTypeENIMultiIP}
+
+	reply, err := networkService.simulateAllocation(pod)
+	assert.NoError(t, err)
+	assert.True(t, reply.Allocatable)
+	assert.False(t, reply.NeedCreate)
+	assert.Equal(t, int32(2), reply.Free)
+
+	// last slot reserved for critical pods
+	mgr.stats = pool.Stats{Capacity: 10, Inuse: 9}
+	reply, err = networkService.simulateAllocation(pod)
+	assert.NoError(t, err)
+	assert.False(t, reply.Allocatable)
+	reply, err = networkService.simulateAllocation(&podInfo{Namespace: "kube-system", PodNetworkType: podNetworkTypeENIMultiIP})
+	assert.NoError(t, err)
+	assert.True(t, reply.Allocatable)
+	assert.True(t, reply.NeedCreate)
+
+	mgr.stats = pool.Stats{Capacity: 10, Inuse: 8, Quarantine: 2}
+	reply, err = networkService.simulateAllocation(&podInfo{Namespace: "kube-system", PodNetworkType: podNetworkTypeENIMultiIP})
+	assert.NoError(t, err)
+	assert.False(t, reply.Allocatable)
+	assert.NotEmpty(t, reply.Reason)
+
+	reply, err = networkService.simulateAllocation(&podInfo{Namespace: "default", PodNetworkType: podNetworkTypeVPCENI})
+	assert.NoError(t, err)
+	assert.False(t, reply.Allocatable)
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"golang.org/x/net/context"
+)
+
+// allocCall allocation in flight for sandbox, result shared by concurrent requests of the sandbox
+type allocCall struct {
+	done  chan struct{}
+	reply *rpc.AllocIPReply
+	err   error
+}
+
+// allocFlights collapse concurrent allocations of the same sandbox onto one, eg: kubelet retries
+// ADD while the first attempt is still running, so ips are not allocated twice
+type allocFlights struct {
+	lock  sync.Mutex
+	calls map[string]*allocCall
+}
+
+func newAllocFlights() *allocFlights {
+	return &allocFlights{calls: make(map[string]*allocCall)}
+}
+
+// pods return keys of pods with allocations in flight
+func (f *allocFlights) pods() map[string]bool {
+	pods := make(map[string]bool)
+	if f == nil {
+		return pods
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for key := range f.calls {
+		pods[key[:strings.LastIndex(key, "/")]] = true
+	}
+	return pods
+}
+
+// sandboxKey key of allocation for sandbox of pod
+func sandboxKey(namespace, name, sandboxID string) string {
+	return podInfoKey(namespace, name) + "/" + sandboxID
+}
+
+// do run alloc for key, or wait for the allocation in flight of key and return its result,
+// shared reports whether result of another request returned
+func (f *allocFlights) do(ctx context.Context, key string, alloc func() (*rpc.AllocIPReply, error)) (reply *rpc.AllocIPReply, shared bool, err error) {
+	if f == nil {
+		reply, err = alloc()
+		return reply, false, err
+	}
+	f.lock.Lock()
+	if call, ok := f.calls[key]; ok {
+		f.lock.Unlock()
+		select {
+		case <-call.done:
+			return call.reply, true, call.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	call := &allocCall{done: make(chan struct{})}
+	f.calls[key] = call
+	f.lock.Unlock()
+
+	defer func() {
+		f.lock.Lock()
+		delete(f.calls, key)
+		f.lock.Unlock()
+		close(call.done)
+	}()
+	call.reply, call.err = alloc()
+	return call.reply, false, call.err
+}
@@ -0,0 +1,72 @@
+package daemon
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestAllocFlights(t *testing.T) {
+	f := newAllocFlights()
+	release := make(chan struct{})
+	var (
+		lock  sync.Mutex
+		calls int
+	)
+	alloc := func() (*rpc.AllocIPReply, error) {
+		lock.Lock()
+		calls++
+		lock.Unlock()
+		<-release
+		return &rpc.AllocIPReply{Success: true}, nil
+	}
+
+	key := sandboxKey("default", "pod", "sandbox-1")
+	replies := make(chan *rpc.AllocIPReply, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reply, _, err := f.do(context.Background(), key, alloc)
+			assert.NoError(t, err)
+			replies <- reply
+		}()
+	}
+	// wait both requests in flight
+	for {
+		f.lock.Lock()
+		inflight := len(f.calls)
+		f.lock.Unlock()
+		lock.Lock()
+		started := calls
+		lock.Unlock()
+		if inflight == 1 && started == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// waiter gives up on its own context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, shared, err := f.do(ctx, key, alloc)
+	assert.True(t, shared)
+	assert.Equal(t, context.Canceled, err)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, 1, calls)
+	assert.True(t, <-replies == <-replies, "same result for both requests")
+
+	// another sandbox allocated separately after the flight done
+	_, shared, err = f.do(context.Background(), sandboxKey("default", "pod", "sandbox-2"), alloc)
+	assert.NoError(t, err)
+	assert.False(t, shared)
+	assert.Equal(t, 2, calls)
+}
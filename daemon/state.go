@@ -0,0 +1,279 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/pkg/feature"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/AliyunContainerService/terway/version"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	statePublishPeriod = time.Minute
+	maxRecentErrors    = 10
+)
+
+// statePublisher publish allocation state of daemon to NodeNetworkState for controller to compare with cloud
+type statePublisher struct {
+	client       crd.Client
+	nodeName     string
+	instanceID   string
+	daemonMode   string
+	getResources func() []string
+	getSummary   func() *crd.NodeNetworkSummary
+}
+
+// nodeFacts collect facts of node for upgrade preflight
+func (p *statePublisher) nodeFacts() *crd.NodeFacts {
+	facts := &crd.NodeFacts{DBSchemaVersion: version.DBSchemaVersion}
+	for _, state := range feature.List() {
+		if state.Enabled {
+			facts.FeatureGates = append(facts.FeatureGates, state.Name)
+		}
+	}
+	if dp, err := loadDatapath(datapathStatePath); err == nil {
+		facts.Datapath = dp.String()
+	}
+	files, err := ioutil.ReadDir(filepath.Dir(cniConfPath))
+	if err != nil {
+		log.Warnf("error list cni config files: %v", err)
+		return facts
+	}
+	for _, f := range files {
+		switch filepath.Ext(f.Name()) {
+		case ".conf", ".conflist", ".json":
+			facts.CNIConfFiles = append(facts.CNIConfFiles, f.Name())
+		}
+	}
+	sort.Strings(facts.CNIConfFiles)
+	return facts
+}
+
+func (p *statePublisher) publish() error {
+	resources := p.getResources()
+	sort.Strings(resources)
+	checksum := crd.Checksum(resources)
+	facts := p.nodeFacts()
+	summary := p.getSummary()
+
+	state, err := p.client.Get(p.nodeName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error get node network state")
+		}
+		_, err = p.client.Create(&crd.NodeNetworkState{
+			ObjectMeta: metav1.ObjectMeta{Name: p.nodeName},
+			Spec: crd.NodeNetworkStateSpec{
+				InstanceID: p.instanceID,
+				DaemonMode: p.daemonMode,
+			},
+			Status: crd.NodeNetworkStateStatus{
+				Resources:    resources,
+				Checksum:     checksum,
+				StateVersion: 1,
+				UpdateTime:   metav1.Now(),
+				Node:         facts,
+				Summary:      summary,
+			},
+		})
+		return errors.Wrapf(err, "error create node network state")
+	}
+
+	if state.Status.Diverged {
+		log.Warnf("allocation state diverged from cloud: %s", state.Status.DivergedReason)
+	}
+	if state.Status.Checksum == checksum && state.Spec.InstanceID == p.instanceID && state.Spec.DaemonMode == p.daemonMode &&
+		reflect.DeepEqual(state.Status.Node, facts) && summaryEqual(state.Status.Summary, summary) {
+		return nil
+	}
+
+	state.Spec.InstanceID = p.instanceID
+	state.Spec.DaemonMode = p.daemonMode
+	state.Status.Node = facts
+	state.Status.Summary = summary
+	if state.Status.Checksum != checksum {
+		state.Status.Resources = resources
+		state.Status.Checksum = checksum
+		state.Status.StateVersion++
+		state.Status.UpdateTime = metav1.Now()
+	}
+	_, err = p.client.Update(state)
+	return errors.Wrapf(err, "error update node network state")
+}
+
+func (p *statePublisher) start() {
+	go func() {
+		for {
+			if err := p.publish(); err != nil {
+				log.Warnf("error publish node network state: %v", err)
+			}
+			time.Sleep(statePublishPeriod)
+		}
+	}()
+}
+
+// getResourceIDs return cloud resource ids managed by daemon
+func (networkService *networkService) getResourceIDs() []string {
+	networkService.RLock()
+	defer networkService.RUnlock()
+	var ids []string
+	for _, mgr := range networkService.mgrForResource {
+		ids = append(ids, mgr.GetResourceIDs()...)
+	}
+	return ids
+}
+
+// summaryEqual compare summaries in the form stored in apiserver
+func summaryEqual(a, b *crd.NodeNetworkSummary) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aData) == string(bData)
+}
+
+// networkSummary summarize enis, pools, allocated pods and recent errors of daemon
+func (networkService *networkService) networkSummary() *crd.NodeNetworkSummary {
+	networkService.RLock()
+	defer networkService.RUnlock()
+	summary := &crd.NodeNetworkSummary{
+		Pools:        make(map[string]crd.PoolStats, len(networkService.mgrForResource)),
+		RecentErrors: networkService.recentErrors.list(),
+	}
+	for _, ip := range networkService.ipDenyList.list() {
+		summary.DeniedIPs = append(summary.DeniedIPs, crd.DeniedIP{
+			IP:       ip.IP,
+			Reason:   ip.Reason,
+			DeniedAt: metav1.NewTime(ip.DeniedAt),
+		})
+	}
+
+	eniIPs := make(map[string]int)
+	for resType, mgr := range networkService.mgrForResource {
+		stats := mgr.Stats()
+		poolStats := crd.PoolStats{
+			Capacity:   stats.Capacity,
+			Inuse:      stats.Inuse,
+			Idle:       stats.Idle,
+			Quarantine: stats.Quarantine,
+		}
+		// size of veth pool not configurable
+		if resType != types.ResourceTypeVeth {
+			poolStats.Recommendation = networkService.poolStats.recommend(resType, time.Now(), stats.Capacity)
+		}
+		summary.Pools[resType] = poolStats
+		for _, id := range mgr.GetResourceIDs() {
+			switch resType {
+			case types.ResourceTypeENI:
+				// eni exclusive by pod
+				eniIPs[id] = 0
+			case types.ResourceTypeENIIP:
+				// id of eni ip: <mac>.<ip>
+				eniIPs[strings.SplitN(id, ".", 2)[0]]++
+			}
+		}
+	}
+	for mac, ips := range eniIPs {
+		summary.ENIs = append(summary.ENIs, crd.ENIState{MAC: mac, IPs: ips})
+	}
+	sort.Slice(summary.ENIs, func(i, j int) bool {
+		return summary.ENIs[i].MAC < summary.ENIs[j].MAC
+	})
+
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		log.Warnf("error list resource db for network summary: %v", err)
+		return summary
+	}
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil {
+			continue
+		}
+		summary.Pods = append(summary.Pods, podInfoKey(resRelate.PodInfo.Namespace, resRelate.PodInfo.Name))
+	}
+	sort.Strings(summary.Pods)
+	summary.PodCount = len(summary.Pods)
+	return summary
+}
+
+// managedENIs return macs of enis in pools to namespace of pod using the eni exclusively, empty if
+// eni shared by pods or idle
+func (networkService *networkService) managedENIs() map[string]string {
+	networkService.RLock()
+	defer networkService.RUnlock()
+	enis := make(map[string]string)
+	for resType, mgr := range networkService.mgrForResource {
+		for _, id := range mgr.GetResourceIDs() {
+			switch resType {
+			case types.ResourceTypeENI:
+				enis[id] = ""
+			case types.ResourceTypeENIIP:
+				enis[strings.SplitN(id, ".", 2)[0]] = ""
+			}
+		}
+	}
+
+	resRelateList, err := networkService.resourceDB.List()
+	if err != nil {
+		log.Warnf("error list resource db for enis of pods: %v", err)
+		return enis
+	}
+	for _, resRelateObj := range resRelateList {
+		resRelate := resRelateObj.(PodResources)
+		if resRelate.PodInfo == nil {
+			continue
+		}
+		for _, res := range resRelate.GetResourceItemByType(types.ResourceTypeENI) {
+			if _, ok := enis[res.ID]; ok {
+				enis[res.ID] = resRelate.PodInfo.Namespace
+			}
+		}
+	}
+	return enis
+}
+
+// recentErrors errors of allocations and releases recently, published in network summary
+type recentErrors struct {
+	lock   sync.Mutex
+	errors []crd.ErrorRecord
+}
+
+// record error of operation on pod, keep the latest maxRecentErrors
+func (r *recentErrors) record(op, pod string, err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.errors = append(r.errors, crd.ErrorRecord{
+		Operation: op,
+		Pod:       pod,
+		Error:     err.Error(),
+		// in precision of apiserver
+		Time: metav1.NewTime(time.Now().Truncate(time.Second)),
+	})
+	if len(r.errors) > maxRecentErrors {
+		r.errors = r.errors[len(r.errors)-maxRecentErrors:]
+	}
+}
+
+// list recent errors, oldest first
+func (r *recentErrors) list() []crd.ErrorRecord {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]crd.ErrorRecord{}, r.errors...)
+}
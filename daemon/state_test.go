@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentErrors(t *testing.T) {
+	var nilErrors *recentErrors
+	nilErrors.record("AllocIP", "default/pod", errors.New("error"))
+	assert.Empty(t, nilErrors.list())
+
+	r := &recentErrors{}
+	r.record("AllocIP", "default/pod", nil)
+	assert.Empty(t, r.list())
+	for i := 0; i < maxRecentErrors+2; i++ {
+		r.record("ReleaseIP", fmt.Sprintf("default/pod-%d", i), errors.New("error"))
+	}
+	errs := r.list()
+	assert.Len(t, errs, maxRecentErrors)
+	assert.Equal(t, "default/pod-2", errs[0].Pod)
+	assert.Equal(t, fmt.Sprintf("default/pod-%d", maxRecentErrors+1), errs[maxRecentErrors-1].Pod)
+}
+
+func TestSummaryEqual(t *testing.T) {
+	r := &recentErrors{}
+	r.record("AllocIP", "default/pod", errors.New("error"))
+	summary := &crd.NodeNetworkSummary{PodCount: 1, Pods: []string{"default/pod"}, RecentErrors: r.list()}
+	assert.True(t, summaryEqual(summary, &crd.NodeNetworkSummary{PodCount: 1, Pods: []string{"default/pod"}, RecentErrors: r.list()}))
+	assert.False(t, summaryEqual(summary, nil))
+}
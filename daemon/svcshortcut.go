@@ -0,0 +1,311 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/netfilter"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// service local shortcut of eni multi ip pods in veth datapath: connections of pods to cluster ip of services
+// opted in are dnat to the ready backends on the node before kube-proxy, so they stay on the node
+const (
+	// labelServiceLocalShortcut label of services opted in, copied to their endpoints by kubernetes
+	labelServiceLocalShortcut = "k8s.aliyun.com/service-local-shortcut"
+	svcShortcutChain          = "TERWAY-SVC-LOCAL"
+	svcShortcutComment        = "terway service local shortcut"
+	svcShortcutResyncPeriod   = 30 * time.Second
+	svcShortcutSyncInterval   = time.Second
+)
+
+// shortcutPort port of service cluster ip to its ready backends on node
+type shortcutPort struct {
+	// Service namespace/name:port of service
+	Service   string
+	ClusterIP string
+	Protocol  string
+	Port      int32
+	// Backends ip:port of backends, sorted
+	Backends []string
+}
+
+// localShortcutPorts return ports of services to their ready backends on node, ports without local backend omitted
+func localShortcutPorts(services []corev1.Service, endpoints []corev1.Endpoints, nodeName string) []shortcutPort {
+	endpointsOf := make(map[string]*corev1.Endpoints)
+	for i := range endpoints {
+		endpointsOf[podInfoKey(endpoints[i].Namespace, endpoints[i].Name)] = &endpoints[i]
+	}
+	var ports []shortcutPort
+	for _, svc := range services {
+		if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+			continue
+		}
+		ep, ok := endpointsOf[podInfoKey(svc.Namespace, svc.Name)]
+		if !ok {
+			continue
+		}
+		for _, svcPort := range svc.Spec.Ports {
+			var backends []string
+			for _, subset := range ep.Subsets {
+				for _, epPort := range subset.Ports {
+					if epPort.Name != svcPort.Name {
+						continue
+					}
+					for _, addr := range subset.Addresses {
+						if addr.NodeName == nil || *addr.NodeName != nodeName {
+							continue
+						}
+						backends = append(backends, net.JoinHostPort(addr.IP, strconv.Itoa(int(epPort.Port))))
+					}
+				}
+			}
+			if len(backends) == 0 {
+				continue
+			}
+			sort.Strings(backends)
+			protocol := strings.ToLower(string(svcPort.Protocol))
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			ports = append(ports, shortcutPort{
+				Service:   fmt.Sprintf("%s/%s:%s", svc.Namespace, svc.Name, svcPort.Name),
+				ClusterIP: svc.Spec.ClusterIP,
+				Protocol:  protocol,
+				Port:      svcPort.Port,
+				Backends:  backends,
+			})
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Service < ports[j].Service
+	})
+	return ports
+}
+
+// shortcutRules return rules of shortcut chain for ports: connections from a backend of the port left to
+// kube-proxy to handle hairpin, others dnat to a random backend
+func shortcutRules(ports []shortcutPort) [][]string {
+	var rules [][]string
+	for _, p := range ports {
+		match := []string{"-d", p.ClusterIP + "/32", "-p", p.Protocol, "-m", p.Protocol, "--dport", strconv.Itoa(int(p.Port)),
+			"-m", "comment", "--comment", p.Service}
+		for _, backend := range p.Backends {
+			ip, _, _ := net.SplitHostPort(backend)
+			rules = append(rules, append([]string{"-s", ip + "/32"}, append(match, "-j", "RETURN")...))
+		}
+		for i, backend := range p.Backends {
+			rule := append([]string{}, match...)
+			if remain := len(p.Backends) - i; remain > 1 {
+				rule = append(rule, "-m", "statistic", "--mode", "random", "--probability", fmt.Sprintf("%.5f", 1/float64(remain)))
+			}
+			rules = append(rules, append(rule, "-j", "DNAT", "--to-destination", backend))
+		}
+	}
+	return rules
+}
+
+// shortcutConntrackFilter match conntrack flows of cluster ip dnat to backend ip
+type shortcutConntrackFilter struct {
+	clusterIP net.IP
+	backend   net.IP
+}
+
+// MatchConntrackFlow match flows to cluster ip replied by backend
+func (f shortcutConntrackFilter) MatchConntrackFlow(flow *netlink.ConntrackFlow) bool {
+	return f.clusterIP.Equal(flow.Forward.DstIP) && f.backend.Equal(flow.Reverse.SrcIP)
+}
+
+// staleUDPBackends return cluster ip and backend ip of udp ports in previous not in current, their
+// connectionless flows would be dnat to the backend gone until expired
+func staleUDPBackends(previous, current []shortcutPort) []shortcutConntrackFilter {
+	key := func(p shortcutPort, backend string) string {
+		return fmt.Sprintf("%s/%s/%d/%s", p.ClusterIP, p.Protocol, p.Port, backend)
+	}
+	exists := make(map[string]bool)
+	for _, p := range current {
+		for _, backend := range p.Backends {
+			exists[key(p, backend)] = true
+		}
+	}
+	var stale []shortcutConntrackFilter
+	for _, p := range previous {
+		if p.Protocol != "udp" {
+			continue
+		}
+		for _, backend := range p.Backends {
+			if exists[key(p, backend)] {
+				continue
+			}
+			ip, _, _ := net.SplitHostPort(backend)
+			stale = append(stale, shortcutConntrackFilter{clusterIP: net.ParseIP(p.ClusterIP), backend: net.ParseIP(ip)})
+		}
+	}
+	return stale
+}
+
+// serviceShortcut sync shortcut rules of services opted in with their backends on node
+type serviceShortcut struct {
+	client   kubernetes.Interface
+	nodeName string
+	ipt      netfilter.IPTables
+	// ports of last sync
+	ports []shortcutPort
+}
+
+// newServiceShortcut ensure shortcut chain jumped to from PREROUTING ahead of kube-proxy
+func newServiceShortcut(client kubernetes.Interface, nodeName string) (*serviceShortcut, error) {
+	ipt, err := netfilter.New()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error init iptables")
+	}
+	s := &serviceShortcut{client: client, nodeName: nodeName, ipt: ipt}
+	if err = s.ensureChain(); err != nil {
+		return nil, err
+	}
+	jump := []string{"-m", "comment", "--comment", svcShortcutComment, "-j", svcShortcutChain}
+	exists, err := ipt.Exists("nat", "PREROUTING", jump...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error check jump rule to %s", svcShortcutChain)
+	}
+	if !exists {
+		if err = ipt.Insert("nat", "PREROUTING", 1, jump...); err != nil {
+			return nil, errors.Wrapf(err, "error add jump rule to %s", svcShortcutChain)
+		}
+	}
+	return s, nil
+}
+
+func (s *serviceShortcut) ensureChain() error {
+	chains, err := s.ipt.ListChains("nat")
+	if err != nil {
+		return errors.Wrapf(err, "error list nat chains")
+	}
+	for _, c := range chains {
+		if c == svcShortcutChain {
+			return nil
+		}
+	}
+	return errors.Wrapf(s.ipt.NewChain("nat", svcShortcutChain), "error create chain %s", svcShortcutChain)
+}
+
+// sync rewrite shortcut chain by services opted in and their endpoints if changed
+func (s *serviceShortcut) sync() error {
+	options := metav1.ListOptions{LabelSelector: labelServiceLocalShortcut + "=true"}
+	services, err := s.client.CoreV1().Services(corev1.NamespaceAll).List(options)
+	if err != nil {
+		return errors.Wrapf(err, "error list services of local shortcut")
+	}
+	endpoints, err := s.client.CoreV1().Endpoints(corev1.NamespaceAll).List(options)
+	if err != nil {
+		return errors.Wrapf(err, "error list endpoints of local shortcut")
+	}
+	ports := localShortcutPorts(services.Items, endpoints.Items, s.nodeName)
+	if ports == nil {
+		ports = []shortcutPort{}
+	}
+	if s.ports != nil && reflect.DeepEqual(ports, s.ports) {
+		return nil
+	}
+	// connections fall through to kube-proxy while the chain rewritten
+	if err = s.ipt.ClearChain("nat", svcShortcutChain); err != nil {
+		return errors.Wrapf(err, "error clear chain %s", svcShortcutChain)
+	}
+	for _, rule := range shortcutRules(ports) {
+		if err = s.ipt.Append("nat", svcShortcutChain, rule...); err != nil {
+			s.ports = nil
+			return errors.Wrapf(err, "error add rule of local shortcut")
+		}
+	}
+	for _, f := range staleUDPBackends(s.ports, ports) {
+		if _, err = netlink.ConntrackDeleteFilter(netlink.ConntrackTable, netlink.InetFamily(netlink.FAMILY_V4), f); err != nil {
+			log.Warnf("error flush conntrack of %s to stale backend %s: %v", f.clusterIP, f.backend, err)
+		}
+	}
+	s.ports = ports
+	log.Infof("local shortcut of %d service ports synced", len(ports))
+	return nil
+}
+
+// start sync on pods of node changed, and periodically for changes of services
+func (s *serviceShortcut) start(k8s Kubernetes) {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	go k8s.WatchLocalPods(wait.NeverStop, notify)
+	go func() {
+		for {
+			time.Sleep(svcShortcutResyncPeriod)
+			notify()
+		}
+	}()
+	notify()
+	go func() {
+		for range changed {
+			if err := s.sync(); err != nil {
+				log.Warnf("error sync local shortcut of services: %v", err)
+			}
+			time.Sleep(svcShortcutSyncInterval)
+		}
+	}()
+}
+
+// removeServiceShortcut remove shortcut chain and its jump rule left by previous run with shortcut enabled
+func removeServiceShortcut() error {
+	ipt, err := netfilter.New()
+	if err != nil {
+		return err
+	}
+	jump := []string{"-m", "comment", "--comment", svcShortcutComment, "-j", svcShortcutChain}
+	exists, err := ipt.Exists("nat", "PREROUTING", jump...)
+	if err != nil || !exists {
+		return err
+	}
+	if err = ipt.Delete("nat", "PREROUTING", jump...); err != nil {
+		return errors.Wrapf(err, "error delete jump rule to %s", svcShortcutChain)
+	}
+	if err = ipt.ClearChain("nat", svcShortcutChain); err != nil {
+		return errors.Wrapf(err, "error clear chain %s", svcShortcutChain)
+	}
+	return errors.Wrapf(ipt.DeleteChain("nat", svcShortcutChain), "error delete chain %s", svcShortcutChain)
+}
+
+// setupServiceShortcut start local shortcut of services if enabled for pods of veth datapath, or remove the
+// shortcut left by previous run
+func (networkService *networkService) setupServiceShortcut(enabled bool, client kubernetes.Interface) error {
+	if !enabled {
+		if err := removeServiceShortcut(); err != nil {
+			log.Warnf("error remove local shortcut of services: %v", err)
+		}
+		return nil
+	}
+	ipvlan := false
+	if dp, err := loadDatapath(datapathStatePath); err == nil {
+		ipvlan = dp.ENIIPVirtualType == eniIPVirtualTypeIPVlan
+	}
+	if networkService.eniIPResMgr == nil || ipvlan {
+		log.Warnf("local shortcut of services only supported for eni multi ip pods in veth datapath, ignored")
+		return nil
+	}
+	shortcut, err := newServiceShortcut(client, networkService.k8s.GetNodeName())
+	if err != nil {
+		return errors.Wrapf(err, "error init local shortcut of services")
+	}
+	shortcut.start(networkService.k8s)
+	return nil
+}
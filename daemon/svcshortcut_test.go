@@ -0,0 +1,71 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLocalShortcutPorts(t *testing.T) {
+	local, remote := "node-1", "node-2"
+	services := []corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec: corev1.ServiceSpec{ClusterIP: "172.21.0.10", Ports: []corev1.ServicePort{
+				{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80},
+				{Name: "dns", Protocol: corev1.ProtocolUDP, Port: 53},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "headless"},
+			Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone, Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+		},
+	}
+	endpoints := []corev1.Endpoints{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{
+					{IP: "10.0.0.3", NodeName: &local},
+					{IP: "10.0.0.2", NodeName: &local},
+					{IP: "10.0.1.2", NodeName: &remote},
+				},
+				NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.4", NodeName: &local}},
+				Ports:             []corev1.EndpointPort{{Name: "http", Port: 8080}},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "headless"},
+			Subsets: []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{{IP: "10.0.0.5", NodeName: &local}},
+				Ports:     []corev1.EndpointPort{{Name: "http", Port: 80}},
+			}},
+		},
+	}
+	ports := localShortcutPorts(services, endpoints, local)
+	assert.Equal(t, []shortcutPort{{
+		Service:   "default/web:http",
+		ClusterIP: "172.21.0.10",
+		Protocol:  "tcp",
+		Port:      80,
+		Backends:  []string{"10.0.0.2:8080", "10.0.0.3:8080"},
+	}}, ports)
+	assert.Nil(t, localShortcutPorts(services, endpoints, "node-3"))
+
+	rules := shortcutRules(ports)
+	assert.Len(t, rules, 4)
+	assert.Equal(t, []string{"-s", "10.0.0.2/32", "-d", "172.21.0.10/32", "-p", "tcp", "-m", "tcp", "--dport", "80",
+		"-m", "comment", "--comment", "default/web:http", "-j", "RETURN"}, rules[0])
+	assert.Contains(t, rules[2], "0.50000")
+	assert.Equal(t, []string{"-j", "DNAT", "--to-destination", "10.0.0.3:8080"}, rules[3][len(rules[3])-4:])
+	assert.NotContains(t, rules[3], "statistic")
+
+	udp := []shortcutPort{{ClusterIP: "172.21.0.10", Protocol: "udp", Port: 53, Backends: []string{"10.0.0.2:53", "10.0.0.3:53"}}}
+	assert.Empty(t, staleUDPBackends(udp, udp))
+	assert.Empty(t, staleUDPBackends(ports, nil))
+	stale := staleUDPBackends(udp, []shortcutPort{{ClusterIP: "172.21.0.10", Protocol: "udp", Port: 53, Backends: []string{"10.0.0.3:53"}}})
+	assert.Equal(t, []shortcutConntrackFilter{{clusterIP: net.ParseIP("172.21.0.10"), backend: net.ParseIP("10.0.0.2")}}, stale)
+}
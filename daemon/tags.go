@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// tags of enis created by terway
+const (
+	tagKeyClusterID    = aliyun.TagKeyClusterID
+	tagKeyNodeName     = "terway.node-name"
+	tagKeyPodNamespace = "terway.pod-namespace"
+
+	tagReconcilePeriod = 10 * time.Minute
+)
+
+// validateTagKey check extra tag key not reserved by cloud or terway
+func validateTagKey(key string) error {
+	lower := strings.ToLower(key)
+	switch {
+	case key == "":
+		return fmt.Errorf("empty key of eni_tags")
+	case strings.HasPrefix(lower, "aliyun") || strings.HasPrefix(lower, "acs:"):
+		return fmt.Errorf("key %s of eni_tags reserved by cloud", key)
+	case strings.HasPrefix(lower, "terway."):
+		return fmt.Errorf("key %s of eni_tags reserved by terway", key)
+	}
+	return nil
+}
+
+// eniTags tags of enis created on node, nil if tagging not configured
+func eniTags(cfg *types.Configure, nodeName string) map[string]string {
+	if cfg.ClusterID == "" && len(cfg.ENITags) == 0 && !cfg.TagPodNamespace {
+		return nil
+	}
+	tags := make(map[string]string, len(cfg.ENITags)+2)
+	for k, v := range cfg.ENITags {
+		tags[k] = v
+	}
+	tags[tagKeyNodeName] = nodeName
+	if cfg.ClusterID != "" {
+		tags[tagKeyClusterID] = cfg.ClusterID
+	}
+	return tags
+}
+
+// eniTagger re-apply missing tags of enis in pools, so cloud cost and audit tools can attribute enis
+type eniTagger struct {
+	ecs        aliyun.ECS
+	instanceID string
+	tags       map[string]string
+	// podNamespace tag exclusive enis with namespace of the pod
+	podNamespace bool
+	// managedENIs return macs of enis in pools to namespace of pod using the eni exclusively
+	managedENIs func() map[string]string
+}
+
+// desired tags of eni used by pod of namespace, empty if shared or idle
+func (t *eniTagger) desired(namespace string) map[string]string {
+	tags := make(map[string]string, len(t.tags)+1)
+	for k, v := range t.tags {
+		tags[k] = v
+	}
+	if t.podNamespace && namespace != "" {
+		tags[tagKeyPodNamespace] = namespace
+	}
+	return tags
+}
+
+// reconcileENI add missing tags of eni and remove namespace tag of pod gone
+func (t *eniTagger) reconcileENI(eniID string, desired map[string]string) error {
+	current, err := t.ecs.GetENITags(eniID)
+	if err != nil {
+		return err
+	}
+	missing := make(map[string]string)
+	for k, v := range desired {
+		if cur, ok := current[k]; !ok || cur != v {
+			missing[k] = v
+		}
+	}
+	if len(missing) > 0 {
+		if err = t.ecs.TagENI(eniID, missing); err != nil {
+			return err
+		}
+		log.Infof("tagged eni %s: %v", eniID, missing)
+	}
+	if ns, ok := current[tagKeyPodNamespace]; ok {
+		if _, keep := desired[tagKeyPodNamespace]; !keep {
+			return t.ecs.UntagENI(eniID, map[string]string{tagKeyPodNamespace: ns})
+		}
+	}
+	return nil
+}
+
+func (t *eniTagger) reconcile() error {
+	managed := t.managedENIs()
+	enis, err := t.ecs.GetAttachedENIs(t.instanceID, false)
+	if err != nil {
+		return errors.Wrapf(err, "error get attached enis")
+	}
+	for _, eni := range enis {
+		namespace, ok := managed[eni.MAC]
+		if !ok {
+			continue
+		}
+		if err = t.reconcileENI(eni.ID, t.desired(namespace)); err != nil {
+			log.Warnf("error reconcile tags of eni %s: %v", eni.ID, err)
+		}
+	}
+	return nil
+}
+
+func (t *eniTagger) start() {
+	go func() {
+		for {
+			if err := t.reconcile(); err != nil {
+				log.Warnf("error reconcile tags of enis: %v", err)
+			}
+			time.Sleep(tagReconcilePeriod)
+		}
+	}()
+}
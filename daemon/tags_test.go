@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type tagECS struct {
+	aliyun.ECS
+	tags  map[string]string
+	calls int
+}
+
+func (e *tagECS) GetENITags(eniID string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for k, v := range e.tags {
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+func (e *tagECS) TagENI(eniID string, tags map[string]string) error {
+	e.calls++
+	for k, v := range tags {
+		e.tags[k] = v
+	}
+	return nil
+}
+
+func (e *tagECS) UntagENI(eniID string, tags map[string]string) error {
+	e.calls++
+	for k := range tags {
+		delete(e.tags, k)
+	}
+	return nil
+}
+
+func TestENITags(t *testing.T) {
+	assert.Nil(t, eniTags(&types.Configure{}, "node-1"))
+	assert.Equal(t, map[string]string{tagKeyClusterID: "c-1", tagKeyNodeName: "node-1", "team": "a"},
+		eniTags(&types.Configure{ClusterID: "c-1", ENITags: map[string]string{"team": "a"}}, "node-1"))
+
+	assert.NoError(t, validateTagKey("team"))
+	assert.Error(t, validateTagKey("acs:cost"))
+	assert.Error(t, validateTagKey("terway.node-name"))
+}
+
+func TestENITaggerReconcile(t *testing.T) {
+	ecs := &tagECS{tags: map[string]string{tagKeyNodeName: "node-1", "other": "x"}}
+	tagger := &eniTagger{
+		ecs:          ecs,
+		tags:         map[string]string{tagKeyClusterID: "c-1", tagKeyNodeName: "node-1"},
+		podNamespace: true,
+	}
+
+	assert.NoError(t, tagger.reconcileENI("eni-1", tagger.desired("ns-1")))
+	assert.Equal(t, map[string]string{tagKeyClusterID: "c-1", tagKeyNodeName: "node-1", tagKeyPodNamespace: "ns-1", "other": "x"}, ecs.tags)
+	assert.Equal(t, 1, ecs.calls)
+
+	// nothing missing
+	assert.NoError(t, tagger.reconcileENI("eni-1", tagger.desired("ns-1")))
+	assert.Equal(t, 1, ecs.calls)
+
+	// namespace tag removed once eni back to pool
+	assert.NoError(t, tagger.reconcileENI("eni-1", tagger.desired("")))
+	assert.Equal(t, map[string]string{tagKeyClusterID: "c-1", tagKeyNodeName: "node-1", "other": "x"}, ecs.tags)
+}
@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// throttleSyncPeriod period of reporting throttled calls and following the level of cluster
+	throttleSyncPeriod = 30 * time.Second
+	// throttleRaiseInterval level raised once in interval at most, daemons report throttled calls of the same storm
+	throttleRaiseInterval = time.Minute
+	// throttleCalmPeriod level lowered by one after no throttled calls reported in period
+	throttleCalmPeriod = 5 * time.Minute
+	// maxThrottleLevel refill delayed 16s between creates and chunks shrunk to one at max level
+	maxThrottleLevel = 5
+)
+
+// throttleCoordinator report openapi throttling seen by daemon to OpenAPIThrottle of region and follow the
+// level of it, so daemons of cluster slow down refill of pools together instead of each retrying into the storm
+type throttleCoordinator struct {
+	client   crd.ThrottleClient
+	name     string
+	nodeName string
+	// reported count of throttled calls reported
+	reported       uint64
+	throttledCalls func() uint64
+	now            func() time.Time
+	current        int32
+}
+
+func newThrottleCoordinator(client crd.ThrottleClient, region, nodeName string) *throttleCoordinator {
+	return &throttleCoordinator{
+		client:         client,
+		name:           region,
+		nodeName:       nodeName,
+		throttledCalls: aliyun.ThrottledCalls,
+		now:            time.Now,
+	}
+}
+
+// level throttle level of cluster last synced
+func (c *throttleCoordinator) level() int {
+	return int(atomic.LoadInt32(&c.current))
+}
+
+// sync report calls throttled since last sync, raise level on throttling or lower it once calm, the level
+// kept on error and the throttled calls reported on next sync
+func (c *throttleCoordinator) sync() error {
+	calls := c.throttledCalls()
+	throttled := calls > c.reported
+	throttle, err := c.client.Get(c.name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "error get openapi throttle %s", c.name)
+		}
+		throttle = &crd.OpenAPIThrottle{ObjectMeta: metav1.ObjectMeta{Name: c.name}}
+	}
+
+	now := c.now()
+	status := &throttle.Status
+	changed := false
+	switch {
+	case throttled:
+		raise := status.Level < maxThrottleLevel && now.Sub(status.LevelChangedAt.Time) >= throttleRaiseInterval
+		// reported by one of daemons in sync period is enough to keep the level
+		if !raise && now.Sub(status.ThrottledAt.Time) < throttleSyncPeriod {
+			break
+		}
+		status.ThrottledAt = metav1.NewTime(now)
+		status.Reporter = c.nodeName
+		if raise {
+			status.Level++
+			status.LevelChangedAt = status.ThrottledAt
+			log.Warnf("%d openapi calls throttled, refill of pools in cluster slowed down to level %d",
+				calls-c.reported, status.Level)
+		}
+		changed = true
+	case status.Level > 0 && now.Sub(status.ThrottledAt.Time) >= throttleCalmPeriod &&
+		now.Sub(status.LevelChangedAt.Time) >= throttleCalmPeriod:
+		status.Level--
+		status.LevelChangedAt = metav1.NewTime(now)
+		log.Infof("no openapi calls throttled in %s, refill of pools in cluster lowered to level %d",
+			throttleCalmPeriod, status.Level)
+		changed = true
+	}
+	if changed {
+		// conflicts with other daemons retried on next sync
+		if throttle.ResourceVersion == "" {
+			throttle, err = c.client.Create(throttle)
+		} else {
+			throttle, err = c.client.Update(throttle)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "error update openapi throttle %s", c.name)
+		}
+	}
+	c.reported = calls
+	atomic.StoreInt32(&c.current, int32(throttle.Status.Level))
+	return nil
+}
+
+func (c *throttleCoordinator) start() {
+	go func() {
+		for {
+			if err := c.sync(); err != nil {
+				log.Warnf("error sync openapi throttle: %v", err)
+			}
+			time.Sleep(throttleSyncPeriod)
+		}
+	}()
+}
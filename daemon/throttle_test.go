@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeThrottleClient struct {
+	throttles map[string]*crd.OpenAPIThrottle
+}
+
+func (c *fakeThrottleClient) Get(name string) (*crd.OpenAPIThrottle, error) {
+	throttle, ok := c.throttles[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: crd.GroupName, Resource: crd.OpenAPIThrottlePlural}, name)
+	}
+	copied := *throttle
+	return &copied, nil
+}
+
+func (c *fakeThrottleClient) Create(throttle *crd.OpenAPIThrottle) (*crd.OpenAPIThrottle, error) {
+	throttle.ResourceVersion = "1"
+	return c.Update(throttle)
+}
+
+func (c *fakeThrottleClient) Update(throttle *crd.OpenAPIThrottle) (*crd.OpenAPIThrottle, error) {
+	copied := *throttle
+	c.throttles[throttle.Name] = &copied
+	return throttle, nil
+}
+
+func TestThrottleCoordinator(t *testing.T) {
+	client := &fakeThrottleClient{throttles: make(map[string]*crd.OpenAPIThrottle)}
+	now := time.Now()
+	var calls uint64
+	newCoordinator := func(node string) *throttleCoordinator {
+		c := newThrottleCoordinator(client, "cn-hangzhou", node)
+		c.throttledCalls = func() uint64 { return calls }
+		c.now = func() time.Time { return now }
+		return c
+	}
+	node1, node2 := newCoordinator("node1"), newCoordinator("node2")
+
+	// nothing created without throttling
+	assert.NoError(t, node1.sync())
+	assert.Empty(t, client.throttles)
+
+	calls = 3
+	assert.NoError(t, node1.sync())
+	assert.Equal(t, 1, node1.level())
+	// throttling of the same storm not raise level again, followed by other daemons
+	assert.NoError(t, node2.sync())
+	assert.Equal(t, 1, node2.level())
+	assert.Equal(t, "node1", client.throttles["cn-hangzhou"].Status.Reporter)
+
+	now = now.Add(throttleRaiseInterval)
+	calls = 5
+	assert.NoError(t, node2.sync())
+	assert.Equal(t, 2, node2.level())
+	assert.NoError(t, node1.sync())
+	assert.Equal(t, 2, node1.level())
+
+	// lowered one level each calm period
+	now = now.Add(throttleCalmPeriod)
+	assert.NoError(t, node1.sync())
+	assert.Equal(t, 1, node1.level())
+	assert.NoError(t, node2.sync())
+	assert.Equal(t, 1, node2.level())
+	now = now.Add(throttleCalmPeriod)
+	assert.NoError(t, node2.sync())
+	assert.Equal(t, 0, node2.level())
+}
@@ -0,0 +1,127 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	log "github.com/sirupsen/logrus"
+)
+
+// phases of pod network allocation tracked
+const (
+	phasePoolWait     = "pool_wait"
+	phaseENIAttach    = "eni_attach"
+	phaseIPAssign     = "ip_assign"
+	phaseNetlinkSetup = "netlink_setup"
+	phaseTotal        = "total"
+)
+
+// setupReportTimeout allocations waiting for setup report of cni binary longer are dropped
+const setupReportTimeout = 5 * time.Minute
+
+// allocTiming per-phase durations of allocation for pod
+type allocTiming struct {
+	lock   sync.Mutex
+	start  time.Time
+	end    time.Time
+	phases map[string]time.Duration
+	// createPhase the phase of resource created by pool for the pod network type
+	createPhase string
+	slow        bool
+}
+
+// observe duration of phase of pool acquire
+func (t *allocTiming) observe(phase string, d time.Duration) {
+	if phase == pool.PhaseCreate {
+		phase = t.createPhase
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.phases[phase] += d
+}
+
+// fields of slow allocation record in ms
+func (t *allocTiming) fields(pod string, total time.Duration) log.Fields {
+	fields := log.Fields{"pod": pod, "total_ms": total.Nanoseconds() / int64(time.Millisecond)}
+	for phase, d := range t.phases {
+		fields[phase+"_ms"] = d.Nanoseconds() / int64(time.Millisecond)
+	}
+	return fields
+}
+
+// allocTimings track phases of allocations, log slow allocation and export phase latency
+type allocTimings struct {
+	lock      sync.Mutex
+	threshold time.Duration
+	// pending allocations of pods waiting for setup report of cni binary
+	pending map[string]*allocTiming
+}
+
+func newAllocTimings(threshold time.Duration) *allocTimings {
+	return &allocTimings{
+		threshold: threshold,
+		pending:   make(map[string]*allocTiming),
+	}
+}
+
+// begin tracking allocation for pod
+func (a *allocTimings) begin(podNetworkType string) *allocTiming {
+	t := &allocTiming{start: time.Now(), phases: make(map[string]time.Duration)}
+	switch podNetworkType {
+	case podNetworkTypeVPCENI:
+		t.createPhase = phaseENIAttach
+	default:
+		t.createPhase = phaseIPAssign
+	}
+	return t
+}
+
+// finish the allocation in daemon, wait setup report of cni binary if succeed
+func (a *allocTimings) finish(pod string, t *allocTiming, succeed bool) {
+	t.lock.Lock()
+	t.end = time.Now()
+	elapsed := t.end.Sub(t.start)
+	for phase, d := range t.phases {
+		metric.AllocationPhaseLatency.WithLabelValues(phase).Observe(float64(d / time.Millisecond))
+	}
+	if elapsed > a.threshold {
+		t.slow = true
+		log.WithFields(t.fields(pod, elapsed)).Warnf("slow allocation, network setup of cni binary pending")
+	}
+	t.lock.Unlock()
+	if !succeed {
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for key, pending := range a.pending {
+		if time.Since(pending.end) > setupReportTimeout {
+			delete(a.pending, key)
+		}
+	}
+	a.pending[pod] = t
+}
+
+// reportSetup complete the allocation with setup duration of cni binary
+func (a *allocTimings) reportSetup(pod string, setup time.Duration) {
+	a.lock.Lock()
+	t, ok := a.pending[pod]
+	delete(a.pending, pod)
+	a.lock.Unlock()
+
+	metric.AllocationPhaseLatency.WithLabelValues(phaseNetlinkSetup).Observe(float64(setup / time.Millisecond))
+	if !ok {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.phases[phaseNetlinkSetup] = setup
+	total := t.end.Sub(t.start) + setup
+	metric.AllocationPhaseLatency.WithLabelValues(phaseTotal).Observe(float64(total / time.Millisecond))
+	if total > a.threshold {
+		log.WithFields(t.fields(pod, total)).Warnf("slow allocation")
+	}
+}
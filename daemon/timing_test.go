@@ -0,0 +1,32 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocTimings(t *testing.T) {
+	timings := newAllocTimings(time.Second)
+
+	timing := timings.begin(podNetworkTypeVPCENI)
+	timing.observe(pool.PhaseWait, 10*time.Millisecond)
+	timing.observe(pool.PhaseCreate, 20*time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, timing.phases[phasePoolWait])
+	assert.Equal(t, 20*time.Millisecond, timing.phases[phaseENIAttach])
+
+	timings.finish("default/pod", timing, true)
+	assert.Contains(t, timings.pending, "default/pod")
+	timings.reportSetup("default/pod", 30*time.Millisecond)
+	assert.NotContains(t, timings.pending, "default/pod")
+	assert.Equal(t, 30*time.Millisecond, timing.phases[phaseNetlinkSetup])
+
+	// failed allocation not waiting for setup report
+	timing = timings.begin(podNetworkTypeENIMultiIP)
+	timing.observe(pool.PhaseCreate, time.Millisecond)
+	assert.Equal(t, time.Millisecond, timing.phases[phaseIPAssign])
+	timings.finish("default/failed", timing, false)
+	assert.NotContains(t, timings.pending, "default/failed")
+}
@@ -3,16 +3,12 @@ package daemon
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"net"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/pkg/pool"
 	"github.com/AliyunContainerService/terway/types"
-	"github.com/containernetworking/plugins/plugins/ipam/host-local/backend/disk"
 	dockerTypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
@@ -26,80 +22,100 @@ const (
 
 type vethResourceManager struct {
 	runtimeAPI containerRuntime
+	// ipam ips of pods with vpc ip
+	ipam vpcIPAM
+	// protection ips of host-local ipam allocated within the window not reclaimed, their sandboxes may be
+	// not running yet
+	protection time.Duration
 }
 
-func (*vethResourceManager) Allocate(context *networkContext, prefer string) (types.NetworkResource, error) {
+func (f *vethResourceManager) Allocate(context *networkContext, prefer string) (types.NetworkResource, error) {
+	subnet := context.k8sService.GetNodeCidr()
+	ip, err := f.ipam.Allocate(subnet, context.sandboxID)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Veth{
+		HostVeth: link.VethNameForPod(context.pod.Name, context.pod.Namespace, defaultPrefix),
+		PodIP:    &net.IPNet{IP: ip, Mask: subnet.Mask},
+		Gateway:  ipamGateway(subnet),
+	}, nil
+}
+
+// Transfer move ip reserved by previous sandbox to the sandbox of context
+func (f *vethResourceManager) Transfer(context *networkContext, fromSandbox, resID string) (types.NetworkResource, error) {
+	subnet := context.k8sService.GetNodeCidr()
+	ip, err := f.ipam.Transfer(subnet, fromSandbox, context.sandboxID)
+	if err != nil {
+		return nil, err
+	}
 	return &types.Veth{
 		HostVeth: link.VethNameForPod(context.pod.Name, context.pod.Namespace, defaultPrefix),
+		PodIP:    &net.IPNet{IP: ip, Mask: subnet.Mask},
+		Gateway:  ipamGateway(subnet),
 	}, nil
 }
 
-func (*vethResourceManager) Release(context *networkContext, resID string) error {
+// hostVethOfPod return host veth of pod recorded in its alias by cni, the default name if not found
+func hostVethOfPod(name, namespace string) string {
+	if veth, err := link.VethByAlias(name, namespace); err == nil && veth != "" {
+		return veth
+	}
+	return link.VethNameForPod(name, namespace, defaultPrefix)
+}
+
+func (f *vethResourceManager) Release(context *networkContext, resID string) error {
+	return f.ipam.Release(context.sandboxID)
+}
+
+// GetResourceIDs veth not cloud resource, so nothing to publish
+func (*vethResourceManager) GetResourceIDs() []string {
+	return nil
+}
+
+// Stats veth not pooled, so empty stats
+func (*vethResourceManager) Stats() pool.Stats {
+	return pool.Stats{}
+}
+
+// Inventory veth not pooled
+func (*vethResourceManager) Inventory() []pool.InventoryItem {
 	return nil
 }
 
+// Prewarm veth not pooled, nothing to prepare
+func (*vethResourceManager) Prewarm(count int) {
+}
+
+// Drain veth not pooled, ips released to vswitch of node with pods
+func (*vethResourceManager) Drain() (int, error) {
+	return 0, nil
+}
+
 func (f *vethResourceManager) GarbageCollection(inUseSet map[string]interface{}, expireResSet map[string]interface{}) error {
-	// fixme do gc on cni binary
-	lock, err := disk.NewFileLock(defaultIpamPath)
-	if err != nil {
-		return err
-	}
-	defer lock.Close()
-	err = lock.Lock()
-	if err != nil {
-		return err
-	}
 	sandboxList, err := f.runtimeAPI.GetRunningSandbox()
 	if err != nil {
 		return err
 	}
-
-	sandboxStubSet := make(map[string]interface{})
+	running := make(map[string]bool)
 	for _, sandbox := range sandboxList {
-		sandboxStubSet[sandbox] = struct{}{}
-	}
-
-	files, err := ioutil.ReadDir(defaultIpamPath)
-	if err != nil {
-		log.Errorf("Failed to list files in %q: %v", defaultIpamPath, err)
-		return fmt.Errorf("failed to list files in %q: %v", defaultIpamPath, err)
-	}
-
-	// gather containerIDs for allocated ips
-	ipContainerIDMap := make(map[string]string)
-	for _, file := range files {
-		// skip non checkpoint file
-		if ip := net.ParseIP(file.Name()); ip == nil {
-			continue
-		}
-
-		content, err := ioutil.ReadFile(filepath.Join(defaultIpamPath, file.Name()))
-		if err != nil {
-			log.Errorf("Failed to read file %v: %v", file, err)
-		}
-		ipContainerIDMap[file.Name()] = strings.TrimSpace(string(content))
-	}
-
-	for ip, containerID := range ipContainerIDMap {
-		if _, ok := sandboxStubSet[containerID]; !ok && containerID != "" {
-			log.Warnf("detect ip address leak: %s, removing", ip)
-			err := os.Remove(filepath.Join(defaultIpamPath, ip))
-			if err != nil {
-				log.Errorf("error remove leak ip: %s, err: %v", ip, err)
-			}
-		}
+		running[sandbox] = true
 	}
-	return nil
+	return f.ipam.GarbageCollection(running, f.protection)
 }
 
-func newVPCResourceManager() (ResourceManager, error) {
+func newVPCResourceManager(ipam vpcIPAM, protection time.Duration) (ResourceManager, error) {
 	return &vethResourceManager{
 		runtimeAPI: dockerRuntime{},
+		ipam:       ipam,
+		protection: protection,
 	}, nil
 }
 
 type containerRuntime interface {
 	GetRunningSandbox() ([]string, error)
+	// GetRunningPodSandboxes return pod keys of running sandboxes by sandbox id
+	GetRunningPodSandboxes() (map[string]string, error)
 }
 
 type dockerRuntime struct{}
@@ -148,3 +164,37 @@ func (dockerRuntime) GetRunningSandbox() ([]string, error) {
 	}
 	return containerList, nil
 }
+
+// GetRunningPodSandboxes return pod keys of running sandboxes by sandbox id, by labels of sandboxes set by kubelet
+func (dockerRuntime) GetRunningPodSandboxes() (map[string]string, error) {
+	dockerCli, err := client.NewClientWithOpts(
+		client.WithVersion("v1.21"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error init docker client: %+v", err)
+	}
+	defer dockerCli.Close()
+
+	timeoutContext, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	listFilter := filters.NewArgs()
+	listFilter.Add("label", fmt.Sprintf("%s=%s", "io.kubernetes.docker.type", "podsandbox"))
+	listFilter.Add("status", "running")
+	sandboxContainer, err := dockerCli.ContainerList(timeoutContext,
+		dockerTypes.ContainerListOptions{
+			Filters: listFilter,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error get docker containers: %+v", err)
+	}
+	sandboxes := make(map[string]string)
+	for _, container := range sandboxContainer {
+		name, namespace := container.Labels["io.kubernetes.pod.name"], container.Labels["io.kubernetes.pod.namespace"]
+		if name == "" || namespace == "" {
+			continue
+		}
+		sandboxes[container.ID] = podInfoKey(namespace, name)
+	}
+	return sandboxes, nil
+}
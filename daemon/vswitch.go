@@ -0,0 +1,228 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// autoVSwitchPrefix name prefix of vswitches created by terway, shared by nodes of zone
+	autoVSwitchPrefix = "terway-auto-"
+	// autoVSwitchMaxMaskSize the smallest vswitch created, vswitch of vpc at least /29
+	autoVSwitchMaxMaskSize = 29
+	// autoVSwitchCreateInterval vswitches created by node once in the interval at most
+	autoVSwitchCreateInterval = 10 * time.Minute
+	// vSwitchFreeIPsTTL free ips of vswitch described again after the ttl for selection of vswitch
+	vSwitchFreeIPsTTL = time.Minute
+)
+
+// vSwitchFreeIP free ips of vswitch and the time described
+type vSwitchFreeIP struct {
+	count     int
+	described time.Time
+}
+
+// vSwitchFreeIPs free ips of vswitches cached for ttl, so selection of vswitch not describe vswitches
+// on every eni created
+type vSwitchFreeIPs struct {
+	lock   sync.Mutex
+	ecs    aliyun.ECS
+	ttl    time.Duration
+	counts map[string]vSwitchFreeIP
+}
+
+func newVSwitchFreeIPs(ecs aliyun.ECS, ttl time.Duration) *vSwitchFreeIPs {
+	return &vSwitchFreeIPs{ecs: ecs, ttl: ttl, counts: make(map[string]vSwitchFreeIP)}
+}
+
+// get return free ips of vswitch, described if not cached or expired
+func (c *vSwitchFreeIPs) get(vSwitch string, now time.Time) (int, error) {
+	c.lock.Lock()
+	cached, ok := c.counts[vSwitch]
+	c.lock.Unlock()
+	if ok && now.Sub(cached.described) < c.ttl {
+		return cached.count, nil
+	}
+	_, count, err := c.ecs.DescribeVSwitch(vSwitch)
+	if err != nil {
+		return 0, err
+	}
+	c.lock.Lock()
+	c.counts[vSwitch] = vSwitchFreeIP{count: count, described: now}
+	c.lock.Unlock()
+	return count, nil
+}
+
+// exhausted mark vswitch no free ip till described again, eg: eni failed to create for no ip
+func (c *vSwitchFreeIPs) exhausted(vSwitch string, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counts[vSwitch] = vSwitchFreeIP{described: now}
+}
+
+// validateVSwitchAutoCreate check cidr pool and mask size of vswitches auto created, zero values for defaults
+func validateVSwitchAutoCreate(cfg *types.VSwitchAutoCreate) error {
+	if len(cfg.CIDRPool) == 0 {
+		return fmt.Errorf("cidr_pool required")
+	}
+	if cfg.MaxVSwitches < 0 {
+		return fmt.Errorf("max_vswitches %d must not be negative", cfg.MaxVSwitches)
+	}
+	for _, cidr := range cfg.CIDRPool {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || ipNet.IP.To4() == nil {
+			return fmt.Errorf("invalid ipv4 cidr %q of cidr_pool", cidr)
+		}
+		ones, _ := ipNet.Mask.Size()
+		if cfg.MaskSize != 0 && (cfg.MaskSize < ones || cfg.MaskSize > autoVSwitchMaxMaskSize) {
+			return fmt.Errorf("mask_size %d out of range [%d, %d] of cidr %s", cfg.MaskSize, ones, autoVSwitchMaxMaskSize, cidr)
+		}
+	}
+	return nil
+}
+
+// cidrOverlap return whether the cidrs overlap
+func cidrOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// nextVSwitchCIDR return the first cidr of mask size in pool not overlapping the ones used, nil if pool exhausted
+func nextVSwitchCIDR(pool []*net.IPNet, maskSize int, used []*net.IPNet) *net.IPNet {
+	mask := net.CIDRMask(maskSize, 32)
+	for _, block := range pool {
+		ones, _ := block.Mask.Size()
+		if ones > maskSize {
+			continue
+		}
+		base := binary.BigEndian.Uint32(block.IP.To4())
+		step := uint32(1) << uint(32-maskSize)
+		for i := uint64(0); i < uint64(1)<<uint(maskSize-ones); i++ {
+			ip := make(net.IP, net.IPv4len)
+			binary.BigEndian.PutUint32(ip, base+uint32(i)*step)
+			candidate := &net.IPNet{IP: ip, Mask: mask}
+			overlapped := false
+			for _, cidr := range used {
+				if cidrOverlap(candidate, cidr) {
+					overlapped = true
+					break
+				}
+			}
+			if !overlapped {
+				return candidate
+			}
+		}
+	}
+	return nil
+}
+
+// autoVSwitchName name of vswitch auto created of cidr, eg: terway-auto-10-100-1-0-24
+func autoVSwitchName(cidr *net.IPNet) string {
+	return autoVSwitchPrefix + strings.NewReplacer(".", "-", "/", "-").Replace(cidr.String())
+}
+
+// vSwitchAutoCreator create vswitches from cidr pool once vswitches configured of zone run out of ips,
+// vswitches auto created are found by name and shared by nodes of the zone
+type vSwitchAutoCreator struct {
+	ecs      aliyun.ECS
+	vpc      string
+	zone     string
+	pool     []*net.IPNet
+	maskSize int
+	// maxVSwitches vswitches auto created in zone at most, counted across nodes
+	maxVSwitches int
+	interval     time.Duration
+
+	lock        sync.Mutex
+	lastCreated time.Time
+}
+
+func newVSwitchAutoCreator(poolConfig *types.PoolConfig, ecs aliyun.ECS) (*vSwitchAutoCreator, error) {
+	cfg := poolConfig.VSwitchAutoCreate
+	if cfg == nil {
+		return nil, nil
+	}
+	creator := &vSwitchAutoCreator{
+		ecs:          ecs,
+		vpc:          poolConfig.VPC,
+		zone:         poolConfig.Zone,
+		maskSize:     cfg.MaskSize,
+		maxVSwitches: cfg.MaxVSwitches,
+		interval:     autoVSwitchCreateInterval,
+	}
+	for _, cidr := range cfg.CIDRPool {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cidr of vswitch auto create")
+		}
+		creator.pool = append(creator.pool, ipNet)
+	}
+	return creator, nil
+}
+
+// vSwitches return vswitches auto created in zone with available ips by free ips, one created from pool if none
+func (c *vSwitchAutoCreator) vSwitches() ([]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	all, err := c.ecs.ListVSwitches(c.vpc, "")
+	if err != nil {
+		return nil, err
+	}
+	var (
+		available []*aliyun.VSwitch
+		used      []*net.IPNet
+		created   int
+	)
+	for _, vSwitch := range all {
+		used = append(used, vSwitch.CIDR)
+		if vSwitch.Zone != c.zone || !strings.HasPrefix(vSwitch.Name, autoVSwitchPrefix) {
+			continue
+		}
+		created++
+		if vSwitch.AvailableIPs > 0 {
+			available = append(available, vSwitch)
+		}
+	}
+	if len(available) == 0 {
+		vSwitch, err := c.create(used, created)
+		if err != nil {
+			return nil, err
+		}
+		return []string{vSwitch}, nil
+	}
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].AvailableIPs > available[j].AvailableIPs
+	})
+	var switches []string
+	for _, vSwitch := range available {
+		switches = append(switches, vSwitch.ID)
+	}
+	return switches, nil
+}
+
+// create create vswitch of the next cidr in pool not used by vswitches of vpc
+func (c *vSwitchAutoCreator) create(used []*net.IPNet, created int) (string, error) {
+	if created >= c.maxVSwitches {
+		return "", fmt.Errorf("%d vswitches auto created in zone %s, no more created", created, c.zone)
+	}
+	if since := time.Since(c.lastCreated); since < c.interval {
+		return "", fmt.Errorf("vswitch auto created %v ago by node, next one after %v", since.Round(time.Second), c.interval)
+	}
+	cidr := nextVSwitchCIDR(c.pool, c.maskSize, used)
+	if cidr == nil {
+		return "", fmt.Errorf("no cidr of /%d left in cidr pool %v", c.maskSize, c.pool)
+	}
+	c.lastCreated = time.Now()
+	log.Warnf("vswitches of zone %s run out of ips, create vswitch of %s", c.zone, cidr)
+	// cidr taken by other nodes concurrently rejected by vpc, their vswitch found on next list
+	return c.ecs.CreateVSwitch(c.vpc, c.zone, cidr, autoVSwitchName(cidr))
+}
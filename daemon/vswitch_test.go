@@ -0,0 +1,122 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+type vSwitchECS struct {
+	aliyun.ECS
+	vSwitches []*aliyun.VSwitch
+	created   []string
+	described int
+}
+
+func (e *vSwitchECS) ListVSwitches(vpcID, zone string) ([]*aliyun.VSwitch, error) {
+	return e.vSwitches, nil
+}
+
+func (e *vSwitchECS) CreateVSwitch(vpcID, zone string, cidr *net.IPNet, name string) (string, error) {
+	e.created = append(e.created, cidr.String())
+	e.vSwitches = append(e.vSwitches, &aliyun.VSwitch{ID: name, Name: name, CIDR: cidr, Zone: zone, AvailableIPs: 252})
+	return name, nil
+}
+
+// DescribeVSwitch free ips of vswitches by id, counted
+func (e *vSwitchECS) DescribeVSwitch(vSwitch string) (string, int, error) {
+	e.described++
+	for _, vsw := range e.vSwitches {
+		if vsw.ID == vSwitch {
+			return vsw.Zone, vsw.AvailableIPs, nil
+		}
+	}
+	return "", 0, fmt.Errorf("vswitch %s not found", vSwitch)
+}
+
+func TestVSwitchFreeIPs(t *testing.T) {
+	ecs := &vSwitchECS{vSwitches: []*aliyun.VSwitch{{ID: "vsw-a", AvailableIPs: 10}}}
+	freeIPs := newVSwitchFreeIPs(ecs, time.Minute)
+	now := time.Now()
+	count, err := freeIPs.get("vsw-a", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, count)
+
+	// cached within ttl
+	ecs.vSwitches[0].AvailableIPs = 5
+	count, _ = freeIPs.get("vsw-a", now.Add(time.Second))
+	assert.Equal(t, 10, count)
+	assert.Equal(t, 1, ecs.described)
+	freeIPs.exhausted("vsw-a", now)
+	count, _ = freeIPs.get("vsw-a", now.Add(time.Second))
+	assert.Equal(t, 0, count)
+
+	count, _ = freeIPs.get("vsw-a", now.Add(2*time.Minute))
+	assert.Equal(t, 5, count)
+	assert.Equal(t, 2, ecs.described)
+
+	_, err = freeIPs.get("vsw-b", now)
+	assert.Error(t, err)
+}
+
+func TestNextVSwitchCIDR(t *testing.T) {
+	pool := []*net.IPNet{mustCIDR("10.100.0.0/23"), mustCIDR("10.200.0.0/24")}
+	assert.Equal(t, "10.100.0.0/24", nextVSwitchCIDR(pool, 24, nil).String())
+	assert.Equal(t, "10.100.1.0/24", nextVSwitchCIDR(pool, 24, []*net.IPNet{mustCIDR("10.100.0.128/25")}).String())
+	assert.Equal(t, "10.200.0.0/24", nextVSwitchCIDR(pool, 24, []*net.IPNet{mustCIDR("10.100.0.0/16")}).String())
+	assert.Nil(t, nextVSwitchCIDR(pool, 24, []*net.IPNet{mustCIDR("10.0.0.0/8")}))
+
+	assert.NoError(t, validateVSwitchAutoCreate(&types.VSwitchAutoCreate{CIDRPool: []string{"10.100.0.0/16"}}))
+	assert.Error(t, validateVSwitchAutoCreate(&types.VSwitchAutoCreate{}))
+	assert.Error(t, validateVSwitchAutoCreate(&types.VSwitchAutoCreate{CIDRPool: []string{"10.100.0.0/16"}, MaskSize: 12}))
+	assert.Error(t, validateVSwitchAutoCreate(&types.VSwitchAutoCreate{CIDRPool: []string{"fd00::/64"}}))
+}
+
+func TestVSwitchAutoCreate(t *testing.T) {
+	ecs := &vSwitchECS{vSwitches: []*aliyun.VSwitch{
+		{ID: "vsw-1", CIDR: mustCIDR("10.100.0.0/24"), Zone: "zone-a"},
+	}}
+	creator, err := newVSwitchAutoCreator(&types.PoolConfig{
+		VPC:               "vpc-1",
+		Zone:              "zone-a",
+		VSwitchAutoCreate: &types.VSwitchAutoCreate{CIDRPool: []string{"10.100.0.0/16"}, MaskSize: 24, MaxVSwitches: 1},
+	}, ecs)
+	assert.NoError(t, err)
+
+	switches, err := creator.vSwitches()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"terway-auto-10-100-1-0-24"}, switches)
+
+	// vswitch created reused while it has available ips
+	switches, err = creator.vSwitches()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"terway-auto-10-100-1-0-24"}, switches)
+
+	// no more created over max vswitches
+	ecs.vSwitches[1].AvailableIPs = 0
+	_, err = creator.vSwitches()
+	assert.Error(t, err)
+	assert.Equal(t, []string{"10.100.1.0/24"}, ecs.created)
+
+	// and created by node once in the interval
+	creator.maxVSwitches = 2
+	_, err = creator.vSwitches()
+	assert.Error(t, err)
+	creator.interval = 0
+	switches, err = creator.vSwitches()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"terway-auto-10-100-2-0-24"}, switches)
+}
@@ -0,0 +1,142 @@
+package deviceplugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
+)
+
+// pendingTimeout devices allocated by kubelet but not bound to eni in timeout are dropped, eg: pod failed before network setup
+const pendingTimeout = 5 * time.Minute
+
+// Binding relation of eni device allocated by kubelet and the eni attached to pod by daemon
+type Binding struct {
+	DeviceID string `json:"deviceID"`
+	Pod      string `json:"pod"`
+	MAC      string `json:"mac"`
+}
+
+type pendingDevice struct {
+	id   string
+	time time.Time
+}
+
+// eniDevices state of eni devices, correlates device allocations of kubelet with enis attached by daemon.
+// Devices allocated by kubelet are pending until bound to the eni of the next pod requesting the resource,
+// enis of pods not requesting the resource take free devices away by reporting them unhealthy
+type eniDevices struct {
+	lock    sync.Mutex
+	count   int
+	pending []pendingDevice
+	// bound device id to binding
+	bound map[string]Binding
+	// unmanaged mac to pod of enis used by pods without device allocated
+	unmanaged map[string]string
+	now       func() time.Time
+}
+
+func newENIDevices(count int) *eniDevices {
+	return &eniDevices{
+		count:     count,
+		bound:     make(map[string]Binding),
+		unmanaged: make(map[string]string),
+		now:       time.Now,
+	}
+}
+
+func deviceID(i int) string {
+	return fmt.Sprintf("eni-%d", i)
+}
+
+// allocate record devices allocated by kubelet as pending
+func (d *eniDevices) allocate(ids []string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, id := range ids {
+		d.pending = append(d.pending, pendingDevice{id: id, time: d.now()})
+	}
+}
+
+// bind the eni of pod to the oldest pending device, the eni is unmanaged if no device pending
+func (d *eniDevices) bind(pod, mac string) (string, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for id, b := range d.bound {
+		if b.MAC == mac {
+			return id, true
+		}
+	}
+	d.expireLocked()
+	if len(d.pending) == 0 {
+		d.unmanaged[mac] = pod
+		return "", false
+	}
+	id := d.pending[0].id
+	d.pending = d.pending[1:]
+	delete(d.unmanaged, mac)
+	d.bound[id] = Binding{DeviceID: id, Pod: pod, MAC: mac}
+	return id, true
+}
+
+// unbind the eni from device on eni released
+func (d *eniDevices) unbind(mac string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.unmanaged, mac)
+	for id, b := range d.bound {
+		if b.MAC == mac {
+			delete(d.bound, id)
+		}
+	}
+}
+
+func (d *eniDevices) expireLocked() {
+	now := d.now()
+	var pending []pendingDevice
+	for _, p := range d.pending {
+		if now.Sub(p.time) < pendingTimeout {
+			pending = append(pending, p)
+		}
+	}
+	d.pending = pending
+}
+
+// bindings of devices to enis
+func (d *eniDevices) bindings() []Binding {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var ret []Binding
+	for i := 0; i < d.count; i++ {
+		if b, ok := d.bound[deviceID(i)]; ok {
+			ret = append(ret, b)
+		}
+	}
+	return ret
+}
+
+// devices list devices, free ones taken by unmanaged enis are unhealthy from the last
+func (d *eniDevices) devices() []*pluginapi.Device {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	inuse := make(map[string]bool, len(d.bound)+len(d.pending))
+	for id := range d.bound {
+		inuse[id] = true
+	}
+	for _, p := range d.pending {
+		inuse[p.id] = true
+	}
+	devs := make([]*pluginapi.Device, d.count)
+	taken := len(d.unmanaged)
+	for i := d.count - 1; i >= 0; i-- {
+		id := deviceID(i)
+		health := pluginapi.Healthy
+		if taken > 0 && !inuse[id] {
+			health = pluginapi.Unhealthy
+			taken--
+		}
+		devs[i] = &pluginapi.Device{ID: id, Health: health}
+	}
+	return devs
+}
@@ -0,0 +1,49 @@
+package deviceplugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	pluginapi "k8s.io/kubernetes/pkg/kubelet/apis/deviceplugin/v1beta1"
+)
+
+func healthOf(devs []*pluginapi.Device) []string {
+	var ret []string
+	for _, dev := range devs {
+		ret = append(ret, dev.Health)
+	}
+	return ret
+}
+
+func TestENIDevices(t *testing.T) {
+	now := time.Now()
+	d := newENIDevices(3)
+	d.now = func() time.Time { return now }
+
+	d.allocate([]string{"eni-0"})
+	id, ok := d.bind("default/a", "00:16:3e:00:00:01")
+	assert.True(t, ok)
+	assert.Equal(t, "eni-0", id)
+	// bound again on retry of same eni
+	id, ok = d.bind("default/a", "00:16:3e:00:00:01")
+	assert.True(t, ok)
+	assert.Equal(t, "eni-0", id)
+	assert.Equal(t, []Binding{{DeviceID: "eni-0", Pod: "default/a", MAC: "00:16:3e:00:00:01"}}, d.bindings())
+
+	// eni of pod without device takes a free device away
+	_, ok = d.bind("default/b", "00:16:3e:00:00:02")
+	assert.False(t, ok)
+	assert.Equal(t, []string{pluginapi.Healthy, pluginapi.Healthy, pluginapi.Unhealthy}, healthOf(d.devices()))
+
+	d.unbind("00:16:3e:00:00:02")
+	d.unbind("00:16:3e:00:00:01")
+	assert.Empty(t, d.bindings())
+	assert.Equal(t, []string{pluginapi.Healthy, pluginapi.Healthy, pluginapi.Healthy}, healthOf(d.devices()))
+
+	// pending device expired
+	d.allocate([]string{"eni-1"})
+	now = now.Add(pendingTimeout)
+	_, ok = d.bind("default/c", "00:16:3e:00:00:03")
+	assert.False(t, ok)
+}
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"path"
 	"regexp"
+	"strings"
 	"sync"
 	"syscall"
 
@@ -24,16 +25,21 @@ const (
 	// DefaultResourceName aliyun eni resource name in kubernetes container resource
 	DefaultResourceName = "aliyun/eni"
 	serverSock          = pluginapi.DevicePluginPath + "%d-" + "eni.sock"
+	// envENIDevices env of container with eni device ids allocated
+	envENIDevices = "ALIYUN_ENI_DEVICES"
 )
 
 var eniServerSockRegex = regexp.MustCompile("^.*" + "-eni.sock")
 
 // EniDevicePlugin implements the Kubernetes device plugin API
 type EniDevicePlugin struct {
-	socket string
-	server *grpc.Server
-	count  int
-	stop   chan struct{}
+	socket  string
+	server  *grpc.Server
+	count   int
+	stop    chan struct{}
+	devices *eniDevices
+	// changed notify ListAndWatch the health of devices changed
+	changed chan struct{}
 	sync.Locker
 }
 
@@ -41,8 +47,36 @@ type EniDevicePlugin struct {
 func NewEniDevicePlugin(count int) *EniDevicePlugin {
 	pluginEndpoint := fmt.Sprintf(serverSock, time.Now().Unix())
 	return &EniDevicePlugin{
-		socket: pluginEndpoint,
-		count:  count,
+		socket:  pluginEndpoint,
+		count:   count,
+		devices: newENIDevices(count),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+// Bind correlate the eni attached to pod with the device allocated by kubelet, return the device id
+// bound, or false if no device allocated for the pod which takes a free device away
+func (m *EniDevicePlugin) Bind(pod, mac string) (string, bool) {
+	id, ok := m.devices.bind(pod, mac)
+	m.notify()
+	return id, ok
+}
+
+// Unbind the eni released from device
+func (m *EniDevicePlugin) Unbind(mac string) {
+	m.devices.unbind(mac)
+	m.notify()
+}
+
+// Bindings devices bound to enis
+func (m *EniDevicePlugin) Bindings() []Binding {
+	return m.devices.bindings()
+}
+
+func (m *EniDevicePlugin) notify() {
+	select {
+	case m.changed <- struct{}{}:
+	default:
 	}
 }
 
@@ -136,23 +170,22 @@ func (m *EniDevicePlugin) Register(request pluginapi.RegisterRequest) error {
 
 // ListAndWatch lists devices and update that list according to the health status
 func (m *EniDevicePlugin) ListAndWatch(e *pluginapi.Empty, s pluginapi.DevicePlugin_ListAndWatchServer) error {
-	var devs []*pluginapi.Device
-	for i := 0; i < m.count; i++ {
-		devs = append(devs, &pluginapi.Device{ID: fmt.Sprintf("eni-%d", i), Health: pluginapi.Healthy})
-	}
-	s.Send(&pluginapi.ListAndWatchResponse{Devices: devs})
+	s.Send(&pluginapi.ListAndWatchResponse{Devices: m.devices.devices()})
 	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			log.Debugf("send list and watch res: %+v", devs)
-			err := s.Send(&pluginapi.ListAndWatchResponse{Devices: devs})
-			if err != nil {
-				log.Errorf("error send device informance: error: %v", err)
-			}
+		case <-m.changed:
 		case <-m.stop:
 			return nil
 		}
+		devs := m.devices.devices()
+		log.Debugf("send list and watch res: %+v", devs)
+		err := s.Send(&pluginapi.ListAndWatchResponse{Devices: devs})
+		if err != nil {
+			log.Errorf("error send device informance: error: %v", err)
+		}
 	}
 }
 
@@ -163,9 +196,13 @@ func (m *EniDevicePlugin) Allocate(ctx context.Context, r *pluginapi.AllocateReq
 	}
 
 	log.Infof("Request Containers: %v", r.GetContainerRequests())
-	for range r.GetContainerRequests() {
+	for _, req := range r.GetContainerRequests() {
+		// bound to eni on network setup of the pod
+		m.devices.allocate(req.GetDevicesIDs())
 		response.ContainerResponses = append(response.ContainerResponses,
-			&pluginapi.ContainerAllocateResponse{},
+			&pluginapi.ContainerAllocateResponse{
+				Envs: map[string]string{envENIDevices: strings.Join(req.GetDevicesIDs(), ",")},
+			},
 		)
 	}
 
@@ -2,6 +2,8 @@ package main
 
 import (
 	"flag"
+	"os"
+	"strconv"
 
 	"github.com/AliyunContainerService/terway/daemon"
 	log "github.com/sirupsen/logrus"
@@ -11,6 +13,7 @@ const defaultConfigPath = "/etc/eni/eni.json"
 const defaultPidPath = "/var/run/eni/eni.pid"
 const defaultSocketPath = "/var/run/eni/eni.socket"
 const debugSocketPath = "unix:///var/run/eni/eni_debug.socket"
+const defaultAuditLogPath = "/var/lib/cni/terway/audit.log"
 
 var (
 	gitVer         string
@@ -19,6 +22,13 @@ var (
 	readonlyListen string
 	kubeconfig     string
 	master         string
+	socketPath     string
+	socketMode     string
+	enablePprof    bool
+	grpcConfig     = &daemon.GRPCConfig{}
+	cloudConfig    = &daemon.CloudConfig{}
+
+	enableDebugActions bool
 )
 
 func init() {
@@ -27,13 +37,29 @@ func init() {
 	flag.StringVar(&readonlyListen, "readonly-listen", debugSocketPath, "terway readonly listen")
 	flag.StringVar(&master, "master", "", "The address of the Kubernetes API server (overrides any value in kubeconfig).")
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
-
+	flag.StringVar(&socketPath, "socket-path", defaultSocketPath, "unix socket of terway grpc for cni binary")
+	flag.StringVar(&socketMode, "socket-mode", "0600", "file mode of the grpc unix socket")
+	flag.StringVar(&grpcConfig.SocketGroup, "socket-group", "", "group name or gid owning the grpc unix socket")
+	flag.StringVar(&grpcConfig.TLSListen, "grpc-tls-listen", "", "tcp address serving read-only grpc with mTLS for debugging tools, disabled if empty")
+	flag.StringVar(&grpcConfig.TLSCertFile, "grpc-tls-cert-file", "", "tls certificate of grpc tls listener")
+	flag.StringVar(&grpcConfig.TLSKeyFile, "grpc-tls-private-key-file", "", "tls private key of grpc tls listener")
+	flag.StringVar(&grpcConfig.TLSClientCAFile, "grpc-tls-client-ca-file", "", "ca to verify client certificates of grpc tls listener")
+	flag.StringVar(&cloudConfig.Backend, "cloud-backend", "aliyun", "cloud backend of network resources: aliyun, or fake for development and e2e tests without cloud credentials")
+	flag.StringVar(&cloudConfig.FakeConfigFile, "fake-cloud-config", "", "json config of the fake cloud backend, defaults used if empty")
+	flag.StringVar(&cloudConfig.AuditLog, "audit-log", defaultAuditLogPath, "file recording openapi calls mutating enis and ips, disabled if empty")
+	flag.BoolVar(&enablePprof, "enable-pprof", false, "serve pprof and runtime dump on the readonly listen for diagnose")
+	flag.BoolVar(&enableDebugActions, "enable-debug-actions", false, "allow debug endpoints on the readonly listen to change state of daemon: defrag -apply, migrate -apply and netns leaks -clean")
 }
 
 func main() {
 	flag.Parse()
 	log.Infof("Starting terway of version: %s", gitVer)
-	if err := daemon.Run(defaultPidPath, defaultSocketPath, readonlyListen, defaultConfigPath, kubeconfig, master, daemonMode, logLevel); err != nil {
+	mode, err := strconv.ParseUint(socketMode, 8, 32)
+	if err != nil {
+		log.Fatalf("invalid socket mode %s: %v", socketMode, err)
+	}
+	grpcConfig.SocketMode = os.FileMode(mode)
+	if err := daemon.Run(defaultPidPath, socketPath, readonlyListen, defaultConfigPath, kubeconfig, master, daemonMode, logLevel, grpcConfig, cloudConfig, enablePprof, enableDebugActions); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,145 @@
+package aliyun
+
+import (
+	"time"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/denverdino/aliyungo/metadata"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	stsEndpoint   = "https://sts.aliyuncs.com"
+	stsAPIVersion = "2015-04-01"
+
+	assumeRoleSessionName = "terway"
+	assumeRoleDuration    = time.Hour
+	// assumeRoleRefreshPeriod refresh credential of role assumed well before it expired
+	assumeRoleRefreshPeriod = 20 * time.Minute
+)
+
+// ENIAccount account and resource group of enis, when enis created apart from the node instance,
+// eg: vswitches and security groups of shared vpc owned by network team
+type ENIAccount struct {
+	// AssumeRoleARN ram role of account owning enis assumed with credential of node, eg: acs:ram::<uid>:role/<name>,
+	// enis in account of node if empty
+	AssumeRoleARN string
+	// ResourceGroupID resource group of enis created, default resource group if empty
+	ResourceGroupID string
+}
+
+// NewENIAccount return account and resource group of enis in config, nil if enis in account of node
+func NewENIAccount(config *types.Configure) *ENIAccount {
+	if config.ENIAssumeRoleARN == "" && config.ENIResourceGroupID == "" {
+		return nil
+	}
+	return &ENIAccount{AssumeRoleARN: config.ENIAssumeRoleARN, ResourceGroupID: config.ENIResourceGroupID}
+}
+
+type assumeRoleArgs struct {
+	RoleArn         string
+	RoleSessionName string
+	DurationSeconds int
+}
+
+type assumeRoleResponse struct {
+	common.Response
+	Credentials struct {
+		AccessKeyId     string
+		AccessKeySecret string
+		SecurityToken   string
+	}
+}
+
+type createENIArgs struct {
+	ecs.CreateNetworkInterfaceArgs
+	ResourceGroupId string
+}
+
+// assumeRole return credential of ram role assumed with credential of client manager
+func (c *ClientMgr) assumeRole(roleARN string) (metadata.RoleAuth, error) {
+	keyID, secret, token := c.token.authid()
+	client := &common.Client{}
+	client.Init(stsEndpoint, stsAPIVersion, keyID, secret)
+	client.SetSecurityToken(token)
+	client.SetUserAgent(kubernetesAlicloudIdentity)
+
+	resp := assumeRoleResponse{}
+	start := time.Now()
+	err := client.Invoke("AssumeRole", &assumeRoleArgs{
+		RoleArn:         roleARN,
+		RoleSessionName: assumeRoleSessionName,
+		DurationSeconds: int(assumeRoleDuration / time.Second),
+	}, &resp)
+	err = observeOpenAPI("AssumeRole", start, err)
+	if err != nil {
+		return metadata.RoleAuth{}, errors.Wrapf(err, "error assume role %s", roleARN)
+	}
+	return metadata.RoleAuth{
+		AccessKeyId:     resp.Credentials.AccessKeyId,
+		AccessKeySecret: resp.Credentials.AccessKeySecret,
+		SecurityToken:   resp.Credentials.SecurityToken,
+	}, nil
+}
+
+// newAssumedClientMgr return client manager calling openapi with ram role assumed with credential of base,
+// calls on the node instance still with credential of base
+func newAssumedClientMgr(base *ClientMgr, roleARN string, region common.Region) (*ClientMgr, error) {
+	role, err := base.assumeRole(roleARN)
+	if err != nil {
+		return nil, err
+	}
+	ecsclient := ecs.NewECSClientWithSecurityToken(role.AccessKeyId, role.AccessKeySecret, role.SecurityToken, region)
+	ecsclient.SetUserAgent(kubernetesAlicloudIdentity)
+	vpcclient := ecs.NewVPCClientWithSecurityToken(role.AccessKeyId, role.AccessKeySecret, role.SecurityToken, region)
+
+	mgr := &ClientMgr{
+		token:    &tokenAuth{auth: role, active: true},
+		meta:     base.meta,
+		ecs:      ecsclient,
+		vpc:      vpcclient,
+		instance: base.instance,
+	}
+	go func() {
+		for {
+			time.Sleep(assumeRoleRefreshPeriod)
+			role, err := base.assumeRole(roleARN)
+			if err != nil {
+				log.Errorf("alicloud: clientmgr, error refresh credential of assumed role: %v", err)
+				continue
+			}
+			mgr.token.lock.Lock()
+			mgr.token.auth = role
+			ecsclient.WithSecurityToken(role.SecurityToken).
+				WithAccessKeyId(role.AccessKeyId).
+				WithAccessKeySecret(role.AccessKeySecret)
+			vpcclient.WithSecurityToken(role.SecurityToken).
+				WithAccessKeyId(role.AccessKeyId).
+				WithAccessKeySecret(role.AccessKeySecret)
+			mgr.token.lock.Unlock()
+		}
+	}()
+	return mgr, nil
+}
+
+// WithENIAccount return view of ecs managing enis in account and resource group, nil account for ecs itself
+func (e *ecsImpl) WithENIAccount(account *ENIAccount) (ECS, error) {
+	if account == nil {
+		return e, nil
+	}
+	view := *e
+	view.resourceGroupID = account.ResourceGroupID
+	if account.AssumeRoleARN != "" {
+		clientSet, err := newAssumedClientMgr(e.clientSet, account.AssumeRoleARN, e.region)
+		if err != nil {
+			return nil, err
+		}
+		view.clientSet = clientSet
+		view.openapiInfoGetter = &eniOpenAPI{clientSet: clientSet, region: e.region}
+		log.Infof("alicloud: managing enis with assumed role %s", account.AssumeRoleARN)
+	}
+	return &view, nil
+}
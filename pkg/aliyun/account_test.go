@@ -0,0 +1,32 @@
+package aliyun
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/denverdino/aliyungo/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestENIAccount(t *testing.T) {
+	assert.Nil(t, NewENIAccount(&types.Configure{}))
+	account := NewENIAccount(&types.Configure{ENIResourceGroupID: "rg-1"})
+	assert.Equal(t, &ENIAccount{ResourceGroupID: "rg-1"}, account)
+
+	e := &ecsImpl{}
+	view, err := e.WithENIAccount(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, e, view)
+	view, err = e.WithENIAccount(account)
+	assert.NoError(t, err)
+	assert.Equal(t, "rg-1", view.(*ecsImpl).resourceGroupID)
+	assert.Empty(t, e.resourceGroupID)
+
+	query := util.ConvertToQueryValues(&createENIArgs{
+		CreateNetworkInterfaceArgs: ecs.CreateNetworkInterfaceArgs{VSwitchId: "vsw-1"},
+		ResourceGroupId:            "rg-1",
+	})
+	assert.Equal(t, "vsw-1", query.Get("VSwitchId"))
+	assert.Equal(t, "rg-1", query.Get("ResourceGroupId"))
+}
@@ -41,6 +41,8 @@ type ClientMgr struct {
 	meta *metadata.MetaData
 	ecs  *ecs.Client
 	vpc  *ecs.Client
+	// instance ecs client of account of node instance, differs from ecs when enis managed by assumed role
+	instance *ecs.Client
 }
 
 // NewClientMgr return new aliyun client manager
@@ -80,11 +82,12 @@ func NewClientMgr(key, secret string) (*ClientMgr, error) {
 	vpcclient := ecs.NewVPCClientWithSecurityToken(keyid, sec, tok, regionID)
 
 	mgr := &ClientMgr{
-		stop:  make(<-chan struct{}, 1),
-		token: token,
-		meta:  m,
-		ecs:   ecsclient,
-		vpc:   vpcclient,
+		stop:     make(<-chan struct{}, 1),
+		token:    token,
+		meta:     m,
+		ecs:      ecsclient,
+		vpc:      vpcclient,
+		instance: ecsclient,
 	}
 	if !token.active {
 		// use key and secret
@@ -0,0 +1,151 @@
+package aliyun
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAuditLogMaxSize size of audit log file before rotated
+const DefaultAuditLogMaxSize = 10 << 20
+
+// AuditRecord record of openapi call mutating cloud resources
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Resource  string    `json:"resource"`
+	Detail    string    `json:"detail,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	Error     string    `json:"error,omitempty"`
+}
+
+var auditLog = struct {
+	sync.Mutex
+	path    string
+	maxSize int64
+	size    int64
+	file    *os.File
+}{}
+
+// EnableAuditLog append audit records of openapi calls mutating cloud resources to file of path in json lines,
+// the file rotated to path.1 once larger than maxSize
+func EnableAuditLog(path string, maxSize int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrapf(err, "error create dir of audit log")
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "error open audit log")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrapf(err, "error stat audit log")
+	}
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file != nil {
+		auditLog.file.Close()
+	}
+	auditLog.path, auditLog.maxSize, auditLog.size, auditLog.file = path, maxSize, info.Size(), file
+	return nil
+}
+
+// rotateAuditLogLocked move audit log to path.1 and reopen it, older records of path.1 dropped
+func rotateAuditLogLocked() error {
+	auditLog.file.Close()
+	auditLog.file = nil
+	if err := os.Rename(auditLog.path, auditLog.path+".1"); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(auditLog.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	auditLog.file, auditLog.size = file, 0
+	return nil
+}
+
+// writeAudit append record to audit log if enabled
+func writeAudit(record AuditRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return
+	}
+	if auditLog.maxSize > 0 && auditLog.size+int64(len(data)) > auditLog.maxSize {
+		if err = rotateAuditLogLocked(); err != nil {
+			logrus.Errorf("error rotate audit log, audit disabled: %v", err)
+			return
+		}
+	}
+	n, err := auditLog.file.Write(data)
+	auditLog.size += int64(n)
+	if err != nil {
+		logrus.Errorf("error write audit log of %s on %s: %v", record.Action, record.Resource, err)
+	}
+}
+
+// observeMutation observe openapi call mutating resource and record it in audit log,
+// request id of failed call taken from the openapi error, return err classified by typed errors
+func observeMutation(action, resource, detail, requestID string, start time.Time, err error) error {
+	classified := observeOpenAPI(action, start, err)
+	record := AuditRecord{
+		Time:      start,
+		Action:    action,
+		Resource:  resource,
+		Detail:    detail,
+		RequestID: requestID,
+		LatencyMs: int64(time.Since(start) / time.Millisecond),
+	}
+	if err != nil {
+		record.Error = err.Error()
+		if respErr, ok := errors.Cause(err).(*common.Error); ok && record.RequestID == "" {
+			record.RequestID = respErr.RequestId
+		}
+	}
+	writeAudit(record)
+	return classified
+}
+
+// ReadAuditLog read audit records of audit log in path and its rotated file, oldest first,
+// malformed lines skipped, eg: truncated on crash
+func ReadAuditLog(path string) ([]AuditRecord, error) {
+	var records []AuditRecord
+	for _, p := range []string{path + ".1", path} {
+		file, err := os.Open(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "error open audit log")
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1<<20)
+		for scanner.Scan() {
+			var record AuditRecord
+			if json.Unmarshal(scanner.Bytes(), &record) == nil {
+				records = append(records, record)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "error read audit log %s", p)
+		}
+	}
+	return records, nil
+}
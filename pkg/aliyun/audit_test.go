@@ -0,0 +1,48 @@
+package aliyun
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terway-audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	defer func() {
+		auditLog.Lock()
+		auditLog.file.Close()
+		auditLog.file = nil
+		auditLog.Unlock()
+	}()
+
+	assert.NoError(t, EnableAuditLog(path, 400))
+	observeMutation("CreateNetworkInterface", "eni-1", "instance i-1, vswitch vsw-1", "req-1", time.Now(), nil)
+	respErr := &common.Error{ErrorResponse: common.ErrorResponse{Response: common.Response{RequestId: "req-2"}, Code: "Forbidden.RAM"}}
+	observeMutation("AttachNetworkInterface", "eni-1", "instance i-1", "", time.Now(), errors.Wrap(respErr, "error attach"))
+
+	records, err := ReadAuditLog(path)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "req-1", records[0].RequestID)
+	assert.Empty(t, records[0].Error)
+	assert.Equal(t, "req-2", records[1].RequestID)
+	assert.Contains(t, records[1].Error, "Forbidden.RAM")
+
+	// rotated once larger than max size, records of rotated file still read
+	observeMutation("DeleteNetworkInterface", "eni-1", "", "req-3", time.Now(), nil)
+	_, err = os.Stat(path + ".1")
+	assert.NoError(t, err)
+	records, err = ReadAuditLog(path)
+	assert.NoError(t, err)
+	assert.Len(t, records, 3)
+	assert.Equal(t, "DeleteNetworkInterface", records[2].Action)
+}
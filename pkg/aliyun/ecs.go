@@ -3,10 +3,10 @@ package aliyun
 import (
 	"fmt"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/AliyunContainerService/terway/pkg/metric"
 	"github.com/AliyunContainerService/terway/types"
 	"github.com/denverdino/aliyungo/common"
 	"github.com/denverdino/aliyungo/ecs"
@@ -26,23 +26,95 @@ type ECS interface {
 	AssignIPForENI(eniID string) (net.IP, error)
 	AssignNIPsForENI(eniID string, count int) ([]net.IP, error)
 	UnAssignIPForENI(eniID string, ip net.IP) error
+	UnAssignIPsForENI(eniID string, ips []net.IP) error
+	GetENIPrefixes(eniID string) ([]*net.IPNet, error)
+	AssignPrefixForENI(eniID string) (*net.IPNet, error)
+	UnAssignPrefixForENI(eniID string, prefix *net.IPNet) error
 	GetInstanceMaxENI(instanceID string) (int, error)
 	GetInstanceMaxPrivateIP(intanceID string) (int, error)
 	GetENIMaxIP(instanceID string, eniID string) (int, error)
 	GetAttachedSecurityGroup(instanceID string) (string, error)
+	DescribeVSwitch(vSwitch string) (zone string, availIPCount int, err error)
+	GetSecurityGroupVPC(securityGroup string) (string, error)
+	// ListVSwitches list vswitches of vpc, all zones if zone empty
+	ListVSwitches(vpcID, zone string) ([]*VSwitch, error)
+	// CreateVSwitch create vswitch of cidr in zone of vpc, return after the vswitch available
+	CreateVSwitch(vpcID, zone string, cidr *net.IPNet, name string) (string, error)
+	CheckPermission(instanceID string) error
+	ListInstanceENIs() ([]*InstanceENI, error)
+	// ListTerwayENIs list enis created by terway in region, attached or not
+	ListTerwayENIs() ([]*InstanceENI, error)
+	// ListOwnedENIs list enis created by terway for instance, attached or not
+	ListOwnedENIs(instanceID string) ([]*InstanceENI, error)
+	// AttachENI attach eni not attached to instance
+	AttachENI(eniID, instanceID string) (*types.ENI, error)
+	// TagENI add tags to eni, value of existing keys overwritten
+	TagENI(eniID string, tags map[string]string) error
+	// UntagENI remove tags from eni
+	UntagENI(eniID string, tags map[string]string) error
+	// GetENITags return tags of eni
+	GetENITags(eniID string) (map[string]string, error)
+	// DeleteENI delete eni not attached to instance
+	DeleteENI(eniID string) error
+	// AllocateEIP allocate eip of bandwidth in Mbps charged by traffic
+	AllocateEIP(bandwidth int) (*EIP, error)
+	// GetEIP return eip of allocation id
+	GetEIP(eipID string) (*EIP, error)
+	// AssociateEIP associate eip to private ip of eni, the primary or a secondary one
+	AssociateEIP(eipID, eniID string, privateIP net.IP) error
+	// UnassociateEIP unassociate eip from private ip of eni, nothing done if not associated to it
+	UnassociateEIP(eipID, eniID string, privateIP net.IP) error
+	// ReleaseEIP release eip not associated
+	ReleaseEIP(eipID string) error
+	// GetVPCRouteTables return ids of system route tables of vrouter of vpc
+	GetVPCRouteTables(vpcID string) ([]string, error)
+	// ListRouteEntries list custom entries in route table
+	ListRouteEntries(routeTableID string) ([]*RouteEntry, error)
+	// CreateRouteEntry create entry of cidr to instance in route table, return after the entry available
+	CreateRouteEntry(routeTableID string, cidr *net.IPNet, instanceID string) error
+	// DeleteRouteEntry delete entry of cidr to instance from route table, return after the entry gone
+	DeleteRouteEntry(routeTableID string, cidr *net.IPNet, instanceID string) error
+	// WithPriority return view of ECS calling openapi in priority of rate limiter
+	WithPriority(priority Priority) ECS
+	// WithENIAccount return view of ECS managing enis in account and resource group, nil account for ECS itself
+	WithENIAccount(account *ENIAccount) (ECS, error)
+}
+
+// InstanceENI secondary eni attached to instance, view from openapi
+type InstanceENI struct {
+	ID         string
+	InstanceID string
+	MAC        string
+	IPs        []net.IP
+	// Status of eni, eg: "Available" not attached, "InUse" attached
+	Status         string
+	VSwitchID      string
+	SecurityGroups []string
+	// Owner instance the eni created for, empty for enis created before named by instance
+	Owner string
 }
 
 type ecsImpl struct {
-	privateIPMutex sync.RWMutex
+	privateIPMutex *sync.RWMutex
 	clientSet      *ClientMgr
 	eniInfoGetter  ENIInfoGetter
 	// avoid conflict on ecs
 	openapiInfoGetter ENIInfoGetter
 	region            common.Region
+	// limiter shared by priority views of ecs, nil for unlimited
+	limiter  *RateLimiter
+	priority Priority
+	// resourceGroupID resource group of enis created, default if empty
+	resourceGroupID string
 }
 
 // NewECS return new ECS implement object
 func NewECS(ak, sk string, region common.Region) (ECS, error) {
+	return NewRateLimitedECS(ak, sk, region, nil)
+}
+
+// NewRateLimitedECS return ECS implement object with openapi calls limited by limiter
+func NewRateLimitedECS(ak, sk string, region common.Region, limiter *RateLimiter) (ECS, error) {
 	clientSet, err := NewClientMgr(ak, sk)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error get clientset")
@@ -62,32 +134,52 @@ func NewECS(ak, sk string, region common.Region) (ECS, error) {
 	}
 
 	return &ecsImpl{
-		privateIPMutex:    sync.RWMutex{},
+		privateIPMutex:    &sync.RWMutex{},
 		clientSet:         clientSet,
 		eniInfoGetter:     &eniMetadata{},
 		openapiInfoGetter: &openapiENIInfoGetter,
 		region:            region,
+		limiter:           limiter,
+		priority:          PriorityCritical,
 	}, nil
 }
 
+// WithPriority return copy of ecs sharing the limiter in priority
+func (e *ecsImpl) WithPriority(priority Priority) ECS {
+	view := *e
+	view.priority = priority
+	return &view
+}
+
+// wait for token of rate limiter before call openapi
+func (e *ecsImpl) wait() {
+	e.limiter.Wait(e.priority)
+}
+
 // AllocateENI for instance
 func (e *ecsImpl) AllocateENI(vSwitch string, securityGroup string, instanceID string) (*types.ENI, error) {
 	if vSwitch == "" || len(securityGroup) == 0 || instanceID == "" {
-		return nil, errors.Errorf("invalid eni args for allocate")
+		return nil, fmt.Errorf("invalid eni args for allocate")
 	}
 	var (
 		start = time.Now()
 		err   error
 	)
-	createNetworkInterfaceArgs := &ecs.CreateNetworkInterfaceArgs{
-		RegionId:             common.Region(e.region),
-		VSwitchId:            vSwitch,
-		SecurityGroupId:      securityGroup,
-		NetworkInterfaceName: generateEniName(),
-		Description:          eniDescription,
-	}
-	createNetworkInterfaceResponse, err := e.clientSet.ecs.CreateNetworkInterface(createNetworkInterfaceArgs)
-	metric.OpenAPILatency.WithLabelValues("CreateNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	createNetworkInterfaceArgs := &createENIArgs{
+		CreateNetworkInterfaceArgs: ecs.CreateNetworkInterfaceArgs{
+			RegionId:             common.Region(e.region),
+			VSwitchId:            vSwitch,
+			SecurityGroupId:      securityGroup,
+			NetworkInterfaceName: eniName(instanceID),
+			Description:          eniDescription,
+		},
+		ResourceGroupId: e.resourceGroupID,
+	}
+	e.wait()
+	createNetworkInterfaceResponse := &ecs.CreateNetworkInterfaceResponse{}
+	err = e.clientSet.ecs.Invoke("CreateNetworkInterface", createNetworkInterfaceArgs, createNetworkInterfaceResponse)
+	err = observeMutation("CreateNetworkInterface", createNetworkInterfaceResponse.NetworkInterfaceId,
+		fmt.Sprintf("instance %s, vswitch %s", instanceID, vSwitch), createNetworkInterfaceResponse.RequestId, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -101,49 +193,67 @@ func (e *ecsImpl) AllocateENI(vSwitch string, securityGroup string, instanceID s
 		}
 	}()
 
+	e.wait()
 	start = time.Now()
 	err = e.clientSet.ecs.WaitForNetworkInterface(createNetworkInterfaceArgs.RegionId,
 		createNetworkInterfaceResponse.NetworkInterfaceId, eniStatusAvailable, eniCreateTimeout)
-	metric.OpenAPILatency.WithLabelValues("WaitForNetworkInterfaceCreate/"+eniStatusAvailable, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	err = observeOpenAPI("WaitForNetworkInterfaceCreate/"+eniStatusAvailable, start, err)
 	if err != nil {
 		return nil, err
 	}
 
-	start = time.Now()
+	eni, err := e.attachENI(createNetworkInterfaceResponse.NetworkInterfaceId, instanceID)
+	return eni, err
+}
+
+// AttachENI attach eni not attached to instance, eg: enis of instance left detached before daemon reinstalled
+func (e *ecsImpl) AttachENI(eniID, instanceID string) (*types.ENI, error) {
+	return e.attachENI(eniID, instanceID)
+}
+
+// attachENI attach eni to instance and wait for the eni config in metadata of instance
+func (e *ecsImpl) attachENI(eniID, instanceID string) (*types.ENI, error) {
+	var (
+		start = time.Now()
+		err   error
+	)
 	attachNetworkInterfaceArgs := &ecs.AttachNetworkInterfaceArgs{
 		RegionId:           common.Region(e.region),
-		NetworkInterfaceId: createNetworkInterfaceResponse.NetworkInterfaceId,
+		NetworkInterfaceId: eniID,
 		InstanceId:         instanceID,
 	}
-	err = e.clientSet.ecs.AttachNetworkInterface(attachNetworkInterfaceArgs)
-	metric.OpenAPILatency.WithLabelValues("AttachNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	e.wait()
+	attachResponse := common.Response{}
+	err = e.clientSet.ecs.Invoke("AttachNetworkInterface", attachNetworkInterfaceArgs, &attachResponse)
+	err = observeMutation("AttachNetworkInterface", eniID, "instance "+instanceID, attachResponse.RequestId, start, err)
 	if err != nil {
 		return nil, err
 	}
 
+	e.wait()
 	start = time.Now()
-	err = e.clientSet.ecs.WaitForNetworkInterface(createNetworkInterfaceArgs.RegionId,
-		createNetworkInterfaceResponse.NetworkInterfaceId, eniStatusInUse, eniBindTimeout)
-	metric.OpenAPILatency.WithLabelValues("WaitForNetworkInterfaceBind/"+eniStatusInUse, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	err = e.clientSet.ecs.WaitForNetworkInterface(e.region, eniID, eniStatusInUse, eniBindTimeout)
+	err = observeOpenAPI("WaitForNetworkInterfaceBind/"+eniStatusInUse, start, err)
 
 	if err != nil {
 		return nil, err
 	}
 
 	describeNetworkInterfacesArgs := &ecs.DescribeNetworkInterfacesArgs{
-		RegionId:           createNetworkInterfaceArgs.RegionId,
-		NetworkInterfaceId: []string{createNetworkInterfaceResponse.NetworkInterfaceId},
+		RegionId:           e.region,
+		NetworkInterfaceId: []string{eniID},
 	}
 	var describeNetworkInterfacesResp *ecs.DescribeNetworkInterfacesResponse
+	e.wait()
 	start = time.Now()
 	describeNetworkInterfacesResp, err = e.clientSet.ecs.DescribeNetworkInterfaces(describeNetworkInterfacesArgs)
-	metric.OpenAPILatency.WithLabelValues("DescribeNetworkInterfaces", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	err = observeOpenAPI("DescribeNetworkInterfaces", start, err)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(describeNetworkInterfacesResp.NetworkInterfaceSets.NetworkInterfaceSet) != 1 {
-		err = fmt.Errorf("error get ENIInfoGetter interface: %s", createNetworkInterfaceResponse.NetworkInterfaceId)
+		err = fmt.Errorf("error get ENIInfoGetter interface: %s", eniID)
 		return nil, err
 	}
 	var eni *types.ENI
@@ -157,7 +267,7 @@ func (e *ecsImpl) AllocateENI(vSwitch string, securityGroup string, instanceID s
 		},
 		func() (done bool, err error) {
 			eni, err = e.eniInfoGetter.GetENIConfigByMac(describeNetworkInterfacesResp.NetworkInterfaceSets.NetworkInterfaceSet[0].MacAddress)
-			if err != nil || eni.ID != createNetworkInterfaceResponse.NetworkInterfaceId {
+			if err != nil || eni.ID != eniID {
 				logrus.Warnf("error get eni config by mac: %v, retrying...", err)
 				return false, nil
 			}
@@ -198,9 +308,10 @@ func (e *ecsImpl) destroyInterface(eniID string, instanceID string, force bool)
 			Steps:    5,
 		},
 		func() (done bool, err error) {
+			e.wait()
 			start = time.Now()
-			_, err = e.clientSet.ecs.DetachNetworkInterface(detachNetworkInterfaceArgs)
-			metric.OpenAPILatency.WithLabelValues("DetachNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+			resp, err := e.clientSet.ecs.DetachNetworkInterface(detachNetworkInterfaceArgs)
+			err = observeMutation("DetachNetworkInterface", eniID, "instance "+instanceID, resp.RequestId, start, err)
 			if err != nil {
 				retryErr = err
 				logrus.Warnf("error detach eni: %v, retrying...", err)
@@ -213,10 +324,11 @@ func (e *ecsImpl) destroyInterface(eniID string, instanceID string, force bool)
 		return errors.Wrapf(err, "cannot detach eni: %v", retryErr)
 	}
 
+	e.wait()
 	start = time.Now()
 	err = e.clientSet.ecs.WaitForNetworkInterface(detachNetworkInterfaceArgs.RegionId,
 		eniID, eniStatusAvailable, eniBindTimeout)
-	metric.OpenAPILatency.WithLabelValues("WaitForNetworkInterfaceDestroy/"+eniStatusAvailable, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	err = observeOpenAPI("WaitForNetworkInterfaceDestroy/"+eniStatusAvailable, start, err)
 
 	if err != nil && !force {
 		return errors.Wrapf(err, "cannot wait detach network interface")
@@ -235,9 +347,10 @@ func (e *ecsImpl) destroyInterface(eniID string, instanceID string, force bool)
 			Steps:    5,
 		},
 		func() (done bool, err error) {
+			e.wait()
 			start = time.Now()
-			_, err = e.clientSet.ecs.DeleteNetworkInterface(deleteNetworkInterfaceArgs)
-			metric.OpenAPILatency.WithLabelValues("DeleteNetworkInterface", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+			resp, err := e.clientSet.ecs.DeleteNetworkInterface(deleteNetworkInterfaceArgs)
+			err = observeMutation("DeleteNetworkInterface", eniID, "", resp.RequestId, start, err)
 			if err != nil {
 				logrus.Warnf("error delete eni: %v, retrying...", err)
 				return false, nil
@@ -245,7 +358,10 @@ func (e *ecsImpl) destroyInterface(eniID string, instanceID string, force bool)
 			return true, nil
 		},
 	)
-	return errors.Wrapf(err, "cannot detach eni: %v", retryErr)
+	if err != nil {
+		return errors.Wrapf(err, "cannot detach eni: %v", retryErr)
+	}
+	return nil
 }
 
 // GetAttachedENIs of instanceId
@@ -286,6 +402,7 @@ func (e *ecsImpl) AssignIPForENI(eniID string) (net.IP, error) {
 func (e *ecsImpl) AssignNIPsForENI(eniID string, count int) ([]net.IP, error) {
 	e.privateIPMutex.Lock()
 	defer e.privateIPMutex.Unlock()
+	e.wait()
 	addressesBefore, err := e.openapiInfoGetter.GetENIPrivateAddresses(eniID)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error get before address for eniID: %v", eniID)
@@ -297,9 +414,10 @@ func (e *ecsImpl) AssignNIPsForENI(eniID string, count int) ([]net.IP, error) {
 		SecondaryPrivateIpAddressCount: count,
 	}
 
+	e.wait()
 	start := time.Now()
-	_, err = e.clientSet.ecs.AssignPrivateIpAddresses(assignPrivateIPAddressesArgs)
-	metric.OpenAPILatency.WithLabelValues("AssignPrivateIpAddresses", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	assignResp, err := e.clientSet.ecs.AssignPrivateIpAddresses(assignPrivateIPAddressesArgs)
+	err = observeMutation("AssignPrivateIpAddresses", eniID, fmt.Sprintf("count %d", count), assignResp.RequestId, start, err)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error assign address for eniID: %v", eniID)
 	}
@@ -315,6 +433,7 @@ func (e *ecsImpl) AssignNIPsForENI(eniID string, count int) ([]net.IP, error) {
 			Steps:    5,
 		},
 		func() (done bool, err error) {
+			e.wait()
 			addressesAfter, err = e.openapiInfoGetter.GetENIPrivateAddresses(eniID)
 			if err != nil {
 				return false, errors.Wrapf(err, "error get after eni private address for %s", eniID)
@@ -326,7 +445,7 @@ func (e *ecsImpl) AssignNIPsForENI(eniID string, count int) ([]net.IP, error) {
 			return true, nil
 		},
 	)
-	metric.OpenAPILatency.WithLabelValues("AssignPrivateIpAddressesAsync", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	err = observeOpenAPI("AssignPrivateIpAddressesAsync", start, err)
 
 	if err != nil {
 		return nil, errors.Wrapf(err, "error allocate eni private address for %s", eniID)
@@ -345,34 +464,44 @@ func (e *ecsImpl) AssignNIPsForENI(eniID string, count int) ([]net.IP, error) {
 }
 
 func (e *ecsImpl) UnAssignIPForENI(eniID string, ip net.IP) error {
+	return e.UnAssignIPsForENI(eniID, []net.IP{ip})
+}
+
+// UnAssignIPsForENI unassign secondary ips of eni by one openapi call, ips not on eni are ignored
+func (e *ecsImpl) UnAssignIPsForENI(eniID string, ips []net.IP) error {
 	e.privateIPMutex.Lock()
 	defer e.privateIPMutex.Unlock()
 
+	e.wait()
 	addressesBefore, err := e.openapiInfoGetter.GetENIPrivateAddresses(eniID)
 	if err != nil {
 		return errors.Wrapf(err, "error get before address for eniID: %v", eniID)
 	}
 
-	found := false
-	for _, addr := range addressesBefore {
-		if addr.Equal(ip) {
-			found = true
+	var toUnAssign []string
+	for _, ip := range ips {
+		for _, addr := range addressesBefore {
+			if addr.Equal(ip) {
+				toUnAssign = append(toUnAssign, ip.String())
+				break
+			}
 		}
 	}
-	// ip not exist on eni
-	if !found {
+	// ips not exist on eni
+	if len(toUnAssign) == 0 {
 		return nil
 	}
 
 	unAssignPrivateIPAddressesArgs := &ecs.UnassignPrivateIpAddressesArgs{
 		RegionId:           e.region,
 		NetworkInterfaceId: eniID,
-		PrivateIpAddress:   []string{ip.String()},
+		PrivateIpAddress:   toUnAssign,
 	}
 
+	e.wait()
 	start := time.Now()
-	_, err = e.clientSet.ecs.UnassignPrivateIpAddresses(unAssignPrivateIPAddressesArgs)
-	metric.OpenAPILatency.WithLabelValues("UnassignPrivateIpAddresses", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	unassignResp, err := e.clientSet.ecs.UnassignPrivateIpAddresses(unAssignPrivateIPAddressesArgs)
+	err = observeMutation("UnassignPrivateIpAddresses", eniID, "ips "+strings.Join(toUnAssign, ","), unassignResp.RequestId, start, err)
 	if err != nil {
 		return errors.Wrapf(err, "error unassign address for eniID: %v", eniID)
 	}
@@ -388,19 +517,23 @@ func (e *ecsImpl) UnAssignIPForENI(eniID string, ip net.IP) error {
 			Steps:    5,
 		},
 		func() (done bool, err error) {
+			e.wait()
 			addressesAfter, err = e.openapiInfoGetter.GetENIPrivateAddresses(eniID)
 			if err != nil {
 				return false, errors.Wrapf(err, "error get after eni private address for %s", eniID)
 			}
 
-			if len(addressesBefore)-len(addressesAfter) != 1 {
+			if len(addressesBefore)-len(addressesAfter) != len(toUnAssign) {
 				return false, nil
 			}
 			return true, nil
 		},
 	)
-	metric.OpenAPILatency.WithLabelValues("UnassignPrivateIpAddressesAsync", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
-	return errors.Wrapf(err, "error unassign eni private address for %s", eniID)
+	err = observeOpenAPI("UnassignPrivateIpAddressesAsync", start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error unassign eni private address for %s", eniID)
+	}
+	return nil
 }
 
 func (e *ecsImpl) GetInstanceMaxENI(instanceID string) (int, error) {
@@ -412,19 +545,21 @@ func (e *ecsImpl) GetInstanceMaxENI(instanceID string) (int, error) {
 			Jitter:   0,
 			Steps:    5,
 		}, func() (done bool, err error) {
+			e.wait()
 			start := time.Now()
-			insType, err := e.clientSet.ecs.DescribeInstanceAttribute(instanceID)
-			metric.OpenAPILatency.WithLabelValues("DescribeInstanceAttribute", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+			insType, err := e.clientSet.instance.DescribeInstanceAttribute(instanceID)
+			err = observeOpenAPI("DescribeInstanceAttribute", start, err)
 			if err != nil {
 				logrus.Warnf("error get instance info: %s: %v， retry...", instanceID, err)
 				return false, nil
 			}
 
+			e.wait()
 			start = time.Now()
 			instanceTypeItems, err := e.clientSet.ecs.DescribeInstanceTypesNew(&ecs.DescribeInstanceTypesArgs{
 				InstanceTypeFamily: insType.InstanceTypeFamily,
 			})
-			metric.OpenAPILatency.WithLabelValues("DescribeInstanceTypesNew", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+			err = observeOpenAPI("DescribeInstanceTypesNew", start, err)
 
 			if err != nil {
 				logrus.Warnf("error get instance types info: %v， retry...", err)
@@ -440,12 +575,15 @@ func (e *ecsImpl) GetInstanceMaxENI(instanceID string) (int, error) {
 
 			if eniCap == 0 {
 				logrus.Warnf("error get instance type info: %v", insType.InstanceType)
-				return false, errors.Errorf("error get instance type info: %v", insType.InstanceType)
+				return false, fmt.Errorf("error get instance type info: %v", insType.InstanceType)
 			}
 			return true, nil
 		})
 
-	return eniCap, errors.Wrapf(err, "error get instance max eni: %v", instanceID)
+	if err != nil {
+		return eniCap, errors.Wrapf(err, "error get instance max eni: %v", instanceID)
+	}
+	return eniCap, nil
 }
 
 func (e *ecsImpl) GetInstanceMaxPrivateIP(instanceID string) (int, error) {
@@ -459,7 +597,7 @@ func (e *ecsImpl) GetInstanceMaxPrivateIP(instanceID string) (int, error) {
 	}
 	maxIPForInstance := (maxEni - 1) * maxIP
 	if maxIPForInstance <= 0 {
-		return 0, errors.Errorf("instance not support multi ip address: %v ", instanceID)
+		return 0, fmt.Errorf("instance not support multi ip address: %v ", instanceID)
 	}
 	return maxIPForInstance, nil
 }
@@ -474,18 +612,20 @@ func (e *ecsImpl) GetENIMaxIP(instanceID string, eniID string) (int, error) {
 			Jitter:   0,
 			Steps:    5,
 		}, func() (done bool, err error) {
+			e.wait()
 			start := time.Now()
-			insType, err := e.clientSet.ecs.DescribeInstanceAttribute(instanceID)
-			metric.OpenAPILatency.WithLabelValues("DescribeInstanceAttribute", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+			insType, err := e.clientSet.instance.DescribeInstanceAttribute(instanceID)
+			err = observeOpenAPI("DescribeInstanceAttribute", start, err)
 			if err != nil {
 				return false, nil
 			}
 
+			e.wait()
 			start = time.Now()
 			instanceTypeItems, err := e.clientSet.ecs.DescribeInstanceTypesNew(&ecs.DescribeInstanceTypesArgs{
 				InstanceTypeFamily: insType.InstanceTypeFamily,
 			})
-			metric.OpenAPILatency.WithLabelValues("DescribeInstanceTypesNew", fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+			err = observeOpenAPI("DescribeInstanceTypesNew", start, err)
 
 			if err != nil {
 				logrus.Warnf("error get instance info: %v， retry...", err)
@@ -501,12 +641,15 @@ func (e *ecsImpl) GetENIMaxIP(instanceID string, eniID string) (int, error) {
 
 			if eniIPCap == 0 {
 				logrus.Warnf("error get instance type info: %v", insType.InstanceType)
-				return false, errors.Errorf("error get instance type info: %v", insType.InstanceType)
+				return false, fmt.Errorf("error get instance type info: %v", insType.InstanceType)
 			}
 			return true, nil
 		})
 
-	return eniIPCap, errors.Wrapf(err, "error get instance max eni ip: %v", instanceID)
+	if err != nil {
+		return eniIPCap, errors.Wrapf(err, "error get instance max eni ip: %v", instanceID)
+	}
+	return eniIPCap, nil
 }
 
 func (e *ecsImpl) GetENIByID(instanceID, eniID string) (*types.ENI, error) {
@@ -536,7 +679,8 @@ func (e *ecsImpl) GetENIByMac(instanceID, mac string) (*types.ENI, error) {
 }
 
 func (e *ecsImpl) GetAttachedSecurityGroup(instanceID string) (string, error) {
-	ins, err := e.clientSet.ecs.DescribeInstanceAttribute(instanceID)
+	e.wait()
+	ins, err := e.clientSet.instance.DescribeInstanceAttribute(instanceID)
 	if err != nil {
 		return "", errors.Wrapf(err, "error describe instance attribute for security group: %s", instanceID)
 	}
@@ -545,3 +689,188 @@ func (e *ecsImpl) GetAttachedSecurityGroup(instanceID string) (string, error) {
 	}
 	return "", fmt.Errorf("error get instance security groups: %s", instanceID)
 }
+
+func (e *ecsImpl) DescribeVSwitch(vSwitch string) (string, int, error) {
+	e.wait()
+	start := time.Now()
+	vsw, _, err := e.clientSet.vpc.DescribeVSwitches(&ecs.DescribeVSwitchesArgs{
+		RegionId:  e.region,
+		VSwitchId: vSwitch,
+	})
+	err = observeOpenAPI("DescribeVSwitches", start, err)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "error describe vswitch: %s", vSwitch)
+	}
+	if len(vsw) == 0 {
+		return "", 0, fmt.Errorf("vswitch %s not found", vSwitch)
+	}
+	return vsw[0].ZoneId, vsw[0].AvailableIpAddressCount, nil
+}
+
+func (e *ecsImpl) GetSecurityGroupVPC(securityGroup string) (string, error) {
+	e.wait()
+	start := time.Now()
+	sg, err := e.clientSet.ecs.DescribeSecurityGroupAttribute(&ecs.DescribeSecurityGroupAttributeArgs{
+		SecurityGroupId: securityGroup,
+		RegionId:        e.region,
+	})
+	err = observeOpenAPI("DescribeSecurityGroupAttribute", start, err)
+	if err != nil {
+		return "", errors.Wrapf(err, "error describe security group: %s", securityGroup)
+	}
+	return sg.VpcId, nil
+}
+
+// dryRunArgs args of openapi changing resources, validated by dry run without any change
+type dryRunArgs struct {
+	RegionId                       common.Region
+	DryRun                         bool
+	InstanceId                     string
+	NetworkInterfaceId             string
+	VSwitchId                      string
+	SecurityGroupId                string
+	SecondaryPrivateIpAddressCount int
+}
+
+// dryRunPassed code of dry run the request would have succeeded
+const dryRunPassed = "DryRunOperation"
+
+// CheckPermission check the credential has permission of the openapi terway depends on, the apis changing
+// resources checked by dry run against the primary eni of instance
+func (e *ecsImpl) CheckPermission(instanceID string) error {
+	var forbidden []string
+	check := func(action string, err error) {
+		if IsForbidden(err) {
+			forbidden = append(forbidden, action)
+		}
+	}
+
+	e.wait()
+	_, err := e.clientSet.instance.DescribeInstanceAttribute(instanceID)
+	check("DescribeInstanceAttribute", err)
+	e.wait()
+	_, err = e.clientSet.ecs.DescribeInstanceTypesNew(&ecs.DescribeInstanceTypesArgs{})
+	check("DescribeInstanceTypes", err)
+	e.wait()
+	enis, err := e.clientSet.ecs.DescribeNetworkInterfaces(&ecs.DescribeNetworkInterfacesArgs{
+		RegionId:   e.region,
+		InstanceId: instanceID,
+	})
+	check("DescribeNetworkInterfaces", err)
+	if err == nil {
+		for _, eni := range enis.NetworkInterfaceSets.NetworkInterfaceSet {
+			if eni.Type != "Primary" {
+				continue
+			}
+			securityGroup := ""
+			if len(eni.SecurityGroupIds.SecurityGroupId) > 0 {
+				securityGroup = eni.SecurityGroupIds.SecurityGroupId[0]
+			}
+			for _, call := range []struct {
+				action string
+				args   *dryRunArgs
+			}{
+				{"CreateNetworkInterface", &dryRunArgs{VSwitchId: eni.VSwitchId, SecurityGroupId: securityGroup}},
+				{"AttachNetworkInterface", &dryRunArgs{InstanceId: instanceID, NetworkInterfaceId: eni.NetworkInterfaceId}},
+				{"DetachNetworkInterface", &dryRunArgs{InstanceId: instanceID, NetworkInterfaceId: eni.NetworkInterfaceId}},
+				{"DeleteNetworkInterface", &dryRunArgs{NetworkInterfaceId: eni.NetworkInterfaceId}},
+				{"AssignPrivateIpAddresses", &dryRunArgs{NetworkInterfaceId: eni.NetworkInterfaceId, SecondaryPrivateIpAddressCount: 1}},
+			} {
+				call.args.RegionId, call.args.DryRun = e.region, true
+				e.wait()
+				err = e.clientSet.ecs.Invoke(call.action, call.args, &common.Response{})
+				if !isCode(err, func(code string) bool { return code == dryRunPassed }) {
+					check(call.action, err)
+				}
+			}
+		}
+	}
+	e.wait()
+	_, _, err = e.clientSet.vpc.DescribeVSwitches(&ecs.DescribeVSwitchesArgs{
+		RegionId: e.region,
+	})
+	check("DescribeVSwitches", err)
+
+	if len(forbidden) > 0 {
+		return fmt.Errorf("credential has no permission of openapi: %v", forbidden)
+	}
+	return nil
+}
+
+// ListInstanceENIs list attached secondary enis of all instances in region
+func (e *ecsImpl) ListInstanceENIs() ([]*InstanceENI, error) {
+	return e.listSecondaryENIs(ecs.DescribeNetworkInterfacesArgs{}, func(eni *ecs.NetworkInterfaceType) bool {
+		return eni.InstanceId != ""
+	})
+}
+
+// ListTerwayENIs list enis created by terway in region, attached or not
+func (e *ecsImpl) ListTerwayENIs() ([]*InstanceENI, error) {
+	return e.listSecondaryENIs(ecs.DescribeNetworkInterfacesArgs{}, func(eni *ecs.NetworkInterfaceType) bool {
+		return eni.Description == eniDescription
+	})
+}
+
+// ListOwnedENIs list enis created by terway for instance, attached or not
+func (e *ecsImpl) ListOwnedENIs(instanceID string) ([]*InstanceENI, error) {
+	name := eniName(instanceID)
+	return e.listSecondaryENIs(ecs.DescribeNetworkInterfacesArgs{NetworkInterfaceName: name}, func(eni *ecs.NetworkInterfaceType) bool {
+		return eni.Description == eniDescription && eni.NetworkInterfaceName == name
+	})
+}
+
+// listSecondaryENIs list secondary enis in region matching conditions of args and filter
+func (e *ecsImpl) listSecondaryENIs(args ecs.DescribeNetworkInterfacesArgs, filter func(eni *ecs.NetworkInterfaceType) bool) ([]*InstanceENI, error) {
+	var enis []*InstanceENI
+	args.RegionId = e.region
+	args.Type = "Secondary"
+	args.PageSize = describeENIPageSize
+	for page := 1; ; page++ {
+		e.wait()
+		start := time.Now()
+		args.PageNumber = page
+		resp, err := e.clientSet.ecs.DescribeNetworkInterfaces(&args)
+		err = observeOpenAPI("DescribeNetworkInterfaces", start, err)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error describe network interfaces of region")
+		}
+		for i := range resp.NetworkInterfaceSets.NetworkInterfaceSet {
+			eni := &resp.NetworkInterfaceSets.NetworkInterfaceSet[i]
+			if !filter(eni) {
+				continue
+			}
+			instanceENI := &InstanceENI{
+				ID:             eni.NetworkInterfaceId,
+				InstanceID:     eni.InstanceId,
+				MAC:            eni.MacAddress,
+				Status:         eni.Status,
+				VSwitchID:      eni.VSwitchId,
+				SecurityGroups: eni.SecurityGroupIds.SecurityGroupId,
+				Owner:          ownerOfENI(eni.NetworkInterfaceName),
+			}
+			for _, ip := range eni.PrivateIpSets.PrivateIpSet {
+				instanceENI.IPs = append(instanceENI.IPs, net.ParseIP(ip.PrivateIpAddress))
+			}
+			enis = append(enis, instanceENI)
+		}
+		if len(resp.NetworkInterfaceSets.NetworkInterfaceSet) < describeENIPageSize {
+			break
+		}
+	}
+	return enis, nil
+}
+
+// DeleteENI delete eni not attached to instance
+func (e *ecsImpl) DeleteENI(eniID string) error {
+	e.wait()
+	start := time.Now()
+	resp, err := e.clientSet.ecs.DeleteNetworkInterface(&ecs.DeleteNetworkInterfaceArgs{
+		RegionId:           e.region,
+		NetworkInterfaceId: eniID,
+	})
+	err = observeMutation("DeleteNetworkInterface", eniID, "", resp.RequestId, start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error delete eni %s", eniID)
+	}
+	return nil
+}
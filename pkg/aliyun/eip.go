@@ -0,0 +1,204 @@
+package aliyun
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// eip associated to eni or its secondary ip not covered by the vendored sdk, invoked with the sdk client directly
+
+const (
+	eipInstanceTypeENI = "NetworkInterface"
+	// eip status in cloud
+	eipStatusAvailable = "Available"
+	eipStatusInUse     = "InUse"
+)
+
+// EIP elastic ip address in cloud
+type EIP struct {
+	// ID allocation id of eip
+	ID      string
+	Address net.IP
+	Status  string
+	// ENIID eni the eip associated to, empty if not associated
+	ENIID string
+	// PrivateIP ip of eni the eip associated to
+	PrivateIP net.IP
+}
+
+type allocateEIPArgs struct {
+	RegionId           common.Region
+	Bandwidth          string
+	InternetChargeType common.InternetChargeType
+}
+
+type allocateEIPResponse struct {
+	common.Response
+	EipAddress   string
+	AllocationId string
+}
+
+type eipAssociationArgs struct {
+	RegionId         common.Region
+	AllocationId     string
+	InstanceId       string
+	InstanceType     string
+	PrivateIpAddress string
+}
+
+type releaseEIPArgs struct {
+	RegionId     common.Region
+	AllocationId string
+}
+
+type describeEIPArgs struct {
+	RegionId     common.Region
+	AllocationId string
+}
+
+type describeEIPResponse struct {
+	common.Response
+	EipAddresses struct {
+		EipAddress []struct {
+			AllocationId     string
+			IpAddress        string
+			Status           string
+			InstanceId       string
+			InstanceType     string
+			PrivateIpAddress string
+		}
+	}
+}
+
+// AllocateEIP allocate eip of bandwidth in Mbps charged by traffic
+func (e *ecsImpl) AllocateEIP(bandwidth int) (*EIP, error) {
+	e.wait()
+	start := time.Now()
+	resp := &allocateEIPResponse{}
+	err := e.clientSet.vpc.Invoke("AllocateEipAddress", &allocateEIPArgs{
+		RegionId:           e.region,
+		Bandwidth:          fmt.Sprint(bandwidth),
+		InternetChargeType: common.PayByTraffic,
+	}, resp)
+	err = observeMutation("AllocateEipAddress", resp.AllocationId, fmt.Sprintf("bandwidth %d", bandwidth), resp.RequestId, start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error allocate eip")
+	}
+	return &EIP{ID: resp.AllocationId, Address: net.ParseIP(resp.EipAddress), Status: eipStatusAvailable}, nil
+}
+
+// GetEIP return eip of allocation id
+func (e *ecsImpl) GetEIP(eipID string) (*EIP, error) {
+	e.wait()
+	start := time.Now()
+	resp := &describeEIPResponse{}
+	err := e.clientSet.vpc.Invoke("DescribeEipAddresses", &describeEIPArgs{
+		RegionId:     e.region,
+		AllocationId: eipID,
+	}, resp)
+	err = observeOpenAPI("DescribeEipAddresses", start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error describe eip %s", eipID)
+	}
+	if len(resp.EipAddresses.EipAddress) != 1 {
+		return nil, fmt.Errorf("eip %s not found", eipID)
+	}
+	item := resp.EipAddresses.EipAddress[0]
+	eip := &EIP{ID: item.AllocationId, Address: net.ParseIP(item.IpAddress), Status: item.Status}
+	if item.InstanceType == eipInstanceTypeENI {
+		eip.ENIID = item.InstanceId
+		eip.PrivateIP = net.ParseIP(item.PrivateIpAddress)
+	}
+	return eip, nil
+}
+
+// waitEIPStatus wait eip in status after associated or unassociated asynchronously
+func (e *ecsImpl) waitEIPStatus(eipID, status string) error {
+	return wait.ExponentialBackoff(
+		wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0,
+			Steps:    5,
+		},
+		func() (done bool, err error) {
+			eip, err := e.GetEIP(eipID)
+			if err != nil {
+				return false, err
+			}
+			return eip.Status == status, nil
+		},
+	)
+}
+
+// AssociateEIP associate eip to private ip of eni, the primary or a secondary one
+func (e *ecsImpl) AssociateEIP(eipID, eniID string, privateIP net.IP) error {
+	e.wait()
+	start := time.Now()
+	resp := common.Response{}
+	err := e.clientSet.vpc.Invoke("AssociateEipAddress", &eipAssociationArgs{
+		RegionId:         e.region,
+		AllocationId:     eipID,
+		InstanceId:       eniID,
+		InstanceType:     eipInstanceTypeENI,
+		PrivateIpAddress: privateIP.String(),
+	}, &resp)
+	err = observeMutation("AssociateEipAddress", eipID, fmt.Sprintf("eni %s ip %s", eniID, privateIP), resp.RequestId, start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error associate eip %s to eni %s", eipID, eniID)
+	}
+	if err = e.waitEIPStatus(eipID, eipStatusInUse); err != nil {
+		return errors.Wrapf(err, "error wait eip %s associated", eipID)
+	}
+	return nil
+}
+
+// UnassociateEIP unassociate eip from private ip of eni, nothing done if not associated to it
+func (e *ecsImpl) UnassociateEIP(eipID, eniID string, privateIP net.IP) error {
+	eip, err := e.GetEIP(eipID)
+	if err != nil {
+		return err
+	}
+	if eip.ENIID != eniID || !eip.PrivateIP.Equal(privateIP) {
+		return nil
+	}
+	e.wait()
+	start := time.Now()
+	resp := common.Response{}
+	err = e.clientSet.vpc.Invoke("UnassociateEipAddress", &eipAssociationArgs{
+		RegionId:         e.region,
+		AllocationId:     eipID,
+		InstanceId:       eniID,
+		InstanceType:     eipInstanceTypeENI,
+		PrivateIpAddress: privateIP.String(),
+	}, &resp)
+	err = observeMutation("UnassociateEipAddress", eipID, fmt.Sprintf("eni %s ip %s", eniID, privateIP), resp.RequestId, start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error unassociate eip %s from eni %s", eipID, eniID)
+	}
+	if err = e.waitEIPStatus(eipID, eipStatusAvailable); err != nil {
+		return errors.Wrapf(err, "error wait eip %s unassociated", eipID)
+	}
+	return nil
+}
+
+// ReleaseEIP release eip not associated
+func (e *ecsImpl) ReleaseEIP(eipID string) error {
+	e.wait()
+	start := time.Now()
+	resp := common.Response{}
+	err := e.clientSet.vpc.Invoke("ReleaseEipAddress", &releaseEIPArgs{
+		RegionId:     e.region,
+		AllocationId: eipID,
+	}, &resp)
+	err = observeMutation("ReleaseEipAddress", eipID, "", resp.RequestId, start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error release eip %s", eipID)
+	}
+	return nil
+}
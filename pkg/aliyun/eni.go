@@ -30,40 +30,40 @@ func (e *eniMetadata) GetENIConfigByMac(mac string) (*types.ENI, error) {
 		err error
 	)
 
-	eni.ID, err = metadataValue(fmt.Sprintf(metadataBase+eniIDPath, mac))
+	eni.ID, err = metadataValue(fmt.Sprintf(eniIDPath, mac))
 	if err != nil {
-		errors.Wrapf(err, "error get eni id from metaserver, mac: %s", mac)
+		return nil, errors.Wrapf(err, "error get eni id from metaserver, mac: %s", mac)
 	}
 	eni.MAC = mac
 
-	ipAddr, err := metadataValue(fmt.Sprintf(metadataBase+eniAddrPath, mac))
+	ipAddr, err := metadataValue(fmt.Sprintf(eniAddrPath, mac))
 	if err != nil {
-		errors.Wrapf(err, "error get eni address from metaserver, mac: %s", mac)
+		return nil, errors.Wrapf(err, "error get eni address from metaserver, mac: %s", mac)
 	}
-	netmask, err := metadataValue(fmt.Sprintf(metadataBase+eniNetmaskPath, mac))
+	netmask, err := metadataValue(fmt.Sprintf(eniNetmaskPath, mac))
 	if err != nil {
-		errors.Wrapf(err, "error get eni netmask from metaserver, mac: %s", mac)
+		return nil, errors.Wrapf(err, "error get eni netmask from metaserver, mac: %s", mac)
 	}
 	ip := net.ParseIP(ipAddr)
 	if ip == nil {
-		return nil, errors.Errorf("error parse eni address: %s from metadata", ipAddr)
+		return nil, fmt.Errorf("error parse eni address: %s from metadata", ipAddr)
 	}
 	mask := net.ParseIP(netmask)
 	if mask == nil {
-		return nil, errors.Errorf("error parse eni mask: %s from metadata", ipAddr)
+		return nil, fmt.Errorf("error parse eni mask: %s from metadata", ipAddr)
 	}
 	eni.Address = net.IPNet{
 		IP: ip,
 		// fixme: dual stack support
 		Mask: net.IPv4Mask(mask[12], mask[13], mask[14], mask[15]),
 	}
-	gw, err := metadataValue(fmt.Sprintf(metadataBase+eniGatewayPath, mac))
+	gw, err := metadataValue(fmt.Sprintf(eniGatewayPath, mac))
 	if err != nil {
 		return nil, errors.Wrapf(err, "error get eni gateway from metaserver, mac: %s", mac)
 	}
 	gateway := net.ParseIP(gw)
 	if mask == nil {
-		return nil, errors.Errorf("error parse eni mask: %s from metadata", ipAddr)
+		return nil, fmt.Errorf("error parse eni mask: %s from metadata", ipAddr)
 	}
 	eni.Gateway = gateway
 
@@ -86,7 +86,7 @@ func (e *eniMetadata) GetENIConfigByID(eniID string) (*types.ENI, error) {
 		return nil, err
 	}
 	for _, mac := range macs {
-		id, err := metadataValue(fmt.Sprintf(metadataBase+eniIDPath, mac))
+		id, err := metadataValue(fmt.Sprintf(eniIDPath, mac))
 		if err != nil {
 			return nil, errors.Wrapf(err, "error get eni id for mac: %s from metadata", mac)
 		}
@@ -94,7 +94,7 @@ func (e *eniMetadata) GetENIConfigByID(eniID string) (*types.ENI, error) {
 			return e.GetENIConfigByMac(mac)
 		}
 	}
-	return nil, errors.Errorf("not found eni id: %s", eniID)
+	return nil, errors.Wrapf(ErrENIDetachedExternally, "not found eni id: %s", eniID)
 }
 
 func (e *eniMetadata) GetENIPrivateAddresses(eniID string) ([]net.IP, error) {
@@ -105,7 +105,7 @@ func (e *eniMetadata) GetENIPrivateAddresses(eniID string) ([]net.IP, error) {
 	}
 
 	addressStrList := &[]string{}
-	ipsStr, err := metadataValue(fmt.Sprintf(metadataBase+eniPrivateIPs, eni.MAC))
+	ipsStr, err := metadataValue(fmt.Sprintf(eniPrivateIPs, eni.MAC))
 	if err != nil {
 		return nil, errors.Wrapf(err, "error get private ips from metadata")
 	}
@@ -116,7 +116,7 @@ func (e *eniMetadata) GetENIPrivateAddresses(eniID string) ([]net.IP, error) {
 	for _, ipStr := range *addressStrList {
 		ip := net.ParseIP(ipStr)
 		if ip == nil {
-			return nil, errors.Errorf("error parse private ip: %v", ip)
+			return nil, fmt.Errorf("error parse private ip: %v", ip)
 		}
 		addressList = append(addressList, ip)
 	}
@@ -124,14 +124,17 @@ func (e *eniMetadata) GetENIPrivateAddresses(eniID string) ([]net.IP, error) {
 }
 
 func (e *eniMetadata) getAttachMACList() ([]string, error) {
-	macs, err := metadataArray(metadataBase + enisPath)
-	return macs, errors.Wrapf(err, "error get eni list from metadata")
+	macs, err := metadataArray(enisPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error get eni list from metadata")
+	}
+	return macs, nil
 }
 
 func (e *eniMetadata) GetAttachedENIs(instanceID string, containsMainENI bool) ([]*types.ENI, error) {
 	var enis []*types.ENI
 
-	mainENIMac, err := metadataValue(metadataBase + mainEniPath)
+	mainENIMac, err := metadataValue(mainEniPath)
 	if err != nil {
 		return enis, errors.Wrapf(err, "error get main eni form metadata")
 	}
@@ -171,9 +174,12 @@ func (eoa *eniOpenAPI) GetENIPrivateAddresses(eniID string) ([]net.IP, error) {
 	}
 	resp, err := eoa.clientSet.ecs.DescribeNetworkInterfaces(describeNetworkInterfacesArgs)
 	if err != nil {
-		return nil, errors.Wrapf(err, "error get info from openapi: eniid: %s", eniID)
+		return nil, errors.Wrapf(classify(err), "error get info from openapi: eniid: %s", eniID)
 	}
 
+	if len(resp.NetworkInterfaceSets.NetworkInterfaceSet) == 0 {
+		return nil, errors.Wrapf(ErrENIDetachedExternally, "eni %s not found", eniID)
+	}
 	if len(resp.NetworkInterfaceSets.NetworkInterfaceSet) != 1 {
 		return nil, fmt.Errorf("unexpect number of eni of id: %s", eniID)
 	}
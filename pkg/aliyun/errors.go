@@ -1 +1,147 @@
 package aliyun
+
+import (
+	"strings"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/pkg/errors"
+)
+
+// error codes of openapi
+const (
+	ErrInvalidVSwitchIDIPNotEnough = "InvalidVSwitchId.IpNotEnough"
+	ErrInsufficientIPAddress       = "InsufficientIpAddress"
+	ErrInvalidENIIDNotFound        = "InvalidEniId.NotFound"
+)
+
+// typed errors of openapi calls and enis, errors returned by ECS wrap them, check by IsQuotaExceeded and the like
+var (
+	// ErrQuotaExceeded quota of enis or ips exceeded
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrIPExhausted vswitch has no available ip
+	ErrIPExhausted = errors.New("ip of vswitch exhausted")
+	// ErrThrottled openapi call rejected by flow control
+	ErrThrottled = errors.New("openapi throttled")
+	// ErrENIDetachedExternally eni gone from instance not by terway, eg: detached or deleted in console
+	ErrENIDetachedExternally = errors.New("eni detached externally")
+)
+
+// OpenAPIError error of openapi response with the typed error of its code
+type OpenAPIError struct {
+	// Kind typed error of the code
+	Kind error
+	Err  *common.Error
+}
+
+func (e *OpenAPIError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap return the error of openapi response
+func (e *OpenAPIError) Unwrap() error {
+	return e.Err
+}
+
+// kindOf return typed error of openapi error code, nil if none
+func kindOf(code string) error {
+	switch {
+	case strings.HasPrefix(code, "QuotaExceed"):
+		return ErrQuotaExceeded
+	case code == ErrInvalidVSwitchIDIPNotEnough || code == ErrInsufficientIPAddress:
+		return ErrIPExhausted
+	case strings.HasPrefix(code, "Throttling"):
+		return ErrThrottled
+	case code == ErrInvalidENIIDNotFound:
+		return ErrENIDetachedExternally
+	}
+	return nil
+}
+
+// classify wrap error of openapi response with typed error of its code, other errors returned as is
+func classify(err error) error {
+	if respErr, ok := err.(*common.Error); ok {
+		if kind := kindOf(respErr.Code); kind != nil {
+			return &OpenAPIError{Kind: kind, Err: respErr}
+		}
+	}
+	return err
+}
+
+// causeMatch check err and errors it wraps by Cause of pkg/errors or Unwrap of OpenAPIError
+func causeMatch(err error, match func(err error) bool) bool {
+	for err != nil {
+		if match(err) {
+			return true
+		}
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapper.Unwrap()
+		case interface{ Cause() error }:
+			err = wrapper.Cause()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// isKind return whether err is the typed error or caused by openapi error of its codes
+func isKind(err, kind error) bool {
+	return causeMatch(err, func(err error) bool {
+		if respErr, ok := err.(*common.Error); ok {
+			return kindOf(respErr.Code) == kind
+		}
+		return err == kind
+	})
+}
+
+// isCode return whether err caused by openapi error of code matched
+func isCode(err error, match func(code string) bool) bool {
+	return causeMatch(err, func(err error) bool {
+		respErr, ok := err.(*common.Error)
+		return ok && match(respErr.Code)
+	})
+}
+
+// IsForbidden return whether openapi error caused by lack of RAM permission
+func IsForbidden(err error) bool {
+	return isCode(err, func(code string) bool {
+		return strings.HasPrefix(code, "Forbidden") || code == "NoPermission"
+	})
+}
+
+// IsPrefixUnsupported return whether openapi error caused by ipv4 prefix not supported by instance or region
+func IsPrefixUnsupported(err error) bool {
+	return isCode(err, func(code string) bool {
+		return strings.HasPrefix(code, "Unsupported") || strings.Contains(code, "Ipv4Prefix")
+	})
+}
+
+// IsIPNotEnough return whether err caused by vswitch has no available ip
+func IsIPNotEnough(err error) bool {
+	return isKind(err, ErrIPExhausted)
+}
+
+// IsQuotaExceeded return whether err caused by quota of eni or ip exceeded
+func IsQuotaExceeded(err error) bool {
+	return isKind(err, ErrQuotaExceeded)
+}
+
+// IsThrottling return whether err caused by flow control of openapi, eg: "Throttling.User"
+func IsThrottling(err error) bool {
+	return isKind(err, ErrThrottled)
+}
+
+// IsENIDetachedExternally return whether err caused by eni gone from instance not by terway
+func IsENIDetachedExternally(err error) bool {
+	return isKind(err, ErrENIDetachedExternally)
+}
+
+// IsCredentialInvalid return whether openapi error caused by invalid or expired credential
+func IsCredentialInvalid(err error) bool {
+	return isCode(err, func(code string) bool {
+		return strings.HasPrefix(code, "InvalidAccessKeyId") ||
+			strings.HasPrefix(code, "InvalidSecurityToken") ||
+			code == "SignatureDoesNotMatch"
+	})
+}
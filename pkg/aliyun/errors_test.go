@@ -0,0 +1,34 @@
+package aliyun
+
+import (
+	"testing"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedErrors(t *testing.T) {
+	respErr := &common.Error{ErrorResponse: common.ErrorResponse{Code: "QuotaExceed.ENI", RequestId: "req-1"}}
+	err := errors.Wrapf(observeOpenAPI("CreateNetworkInterface", time.Now(), respErr), "error create eni")
+	assert.True(t, IsQuotaExceeded(err))
+	assert.False(t, IsThrottling(err))
+	openAPIErr, ok := errors.Cause(err).(*OpenAPIError)
+	assert.True(t, ok)
+	assert.Equal(t, ErrQuotaExceeded, openAPIErr.Kind)
+	assert.Equal(t, "req-1", openAPIErr.Err.RequestId)
+
+	// errors not of openapi kept as is
+	plain := errors.New("timeout")
+	assert.Equal(t, plain, classify(plain))
+	assert.Nil(t, classify(nil))
+
+	// raw openapi errors wrapped, eg: injected by fault
+	err = errors.Wrap(&common.Error{ErrorResponse: common.ErrorResponse{Code: ErrInvalidVSwitchIDIPNotEnough}}, "error create eni")
+	assert.True(t, IsIPNotEnough(errors.Wrapf(err, "error allocate")))
+	assert.False(t, IsThrottling(err))
+
+	err = errors.Wrapf(errors.Wrapf(ErrENIDetachedExternally, "eni eni-1 not found"), "error get ips")
+	assert.True(t, IsENIDetachedExternally(err))
+}
@@ -0,0 +1,1000 @@
+package aliyun
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/link"
+	"github.com/AliyunContainerService/terway/pkg/metadata"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/denverdino/aliyungo/common"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// error codes of the fake cloud not covered by the codes terway handles
+const (
+	fakeErrThrottling  = "Throttling"
+	fakeErrENIQuota    = "QuotaExceed.NetworkInterface"
+	fakeErrIPQuota     = "QuotaExceed.PrivateIpAddress"
+	fakeErrENINotFound = "InvalidEniId.NotFound"
+	fakeErrEIPNotFound = "InvalidAllocationId.NotFound"
+	fakeErrRouteQuota  = "QuotaExceeded.RouteEntry"
+)
+
+const (
+	fakePrefixSize        = 28
+	fakeDummyLinkPrefix   = "fakeeni"
+	fakeMainENIDeviceName = "eth0"
+	// fakeEIPCIDR public addresses of eips allocated in fake cloud
+	fakeEIPCIDR = "198.51.100.0/24"
+)
+
+// FakeConfig model of the simulated cloud, for running daemon and cni without cloud credentials
+type FakeConfig struct {
+	Region        string `yaml:"region" json:"region"`
+	Zone          string `yaml:"zone" json:"zone"`
+	VPC           string `yaml:"vpc" json:"vpc"`
+	VSwitch       string `yaml:"vswitch" json:"vswitch"`
+	InstanceID    string `yaml:"instance_id" json:"instance_id"`
+	SecurityGroup string `yaml:"security_group" json:"security_group"`
+	// VSwitchCIDR cidr of vswitch the eni ips allocated from
+	VSwitchCIDR string `yaml:"vswitch_cidr" json:"vswitch_cidr"`
+	// MaxENI max enis of instance, including the main eni
+	MaxENI int `yaml:"max_eni" json:"max_eni"`
+	// MaxIPPerENI max private ips of each eni, including the primary ip
+	MaxIPPerENI int `yaml:"max_ip_per_eni" json:"max_ip_per_eni"`
+	// Latency of each openapi call, eg: "100ms"
+	Latency string `yaml:"latency" json:"latency"`
+	// ActionLatency latency of specified openapi actions, eg: {"AttachNetworkInterface": "2s"}
+	ActionLatency map[string]string `yaml:"action_latency" json:"action_latency"`
+	// ErrorRate probability of openapi call failed with ErrorCode, 0 to disable
+	ErrorRate float64 `yaml:"error_rate" json:"error_rate"`
+	// ErrorCode code of injected errors, "Throttling" by default
+	ErrorCode string `yaml:"error_code" json:"error_code"`
+	// ErrorActions openapi actions errors injected into, all actions if empty
+	ErrorActions []string `yaml:"error_actions" json:"error_actions"`
+	// CreateLinks create dummy links on host for attached enis, so the datapath can be set up on them
+	CreateLinks bool `yaml:"create_links" json:"create_links"`
+	// RouteTable the system route table of vpc
+	RouteTable string `yaml:"route_table" json:"route_table"`
+	// MaxRouteEntries quota of custom entries in route table
+	MaxRouteEntries int `yaml:"max_route_entries" json:"max_route_entries"`
+}
+
+// LoadFakeConfig load config of fake cloud from file, defaults used if path empty
+func LoadFakeConfig(path string) (*FakeConfig, error) {
+	cfg := &FakeConfig{}
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error read fake cloud config %s", path)
+		}
+		if err = json.Unmarshal(data, cfg); err != nil {
+			return nil, errors.Wrapf(err, "error parse fake cloud config %s", path)
+		}
+	}
+	setFakeDefaults(cfg)
+	return cfg, nil
+}
+
+func setFakeDefaults(cfg *FakeConfig) {
+	if cfg.Region == "" {
+		cfg.Region = "cn-fake"
+	}
+	if cfg.Zone == "" {
+		cfg.Zone = cfg.Region + "-a"
+	}
+	if cfg.VPC == "" {
+		cfg.VPC = "vpc-fake"
+	}
+	if cfg.VSwitch == "" {
+		cfg.VSwitch = "vsw-fake"
+	}
+	if cfg.InstanceID == "" {
+		cfg.InstanceID = "i-fake"
+	}
+	if cfg.SecurityGroup == "" {
+		cfg.SecurityGroup = "sg-fake"
+	}
+	if cfg.VSwitchCIDR == "" {
+		cfg.VSwitchCIDR = "172.30.0.0/16"
+	}
+	if cfg.MaxENI == 0 {
+		cfg.MaxENI = 4
+	}
+	if cfg.MaxIPPerENI == 0 {
+		cfg.MaxIPPerENI = 10
+	}
+	if cfg.ErrorCode == "" {
+		cfg.ErrorCode = fakeErrThrottling
+	}
+	if cfg.RouteTable == "" {
+		cfg.RouteTable = "vtb-fake"
+	}
+	if cfg.MaxRouteEntries == 0 {
+		cfg.MaxRouteEntries = 48
+	}
+}
+
+// fakeENI eni in the fake cloud
+type fakeENI struct {
+	id           string
+	mac          string
+	name         string
+	deviceNumber int32
+	primary      net.IP
+	ips          []net.IP
+	prefixes     []*net.IPNet
+	tags         map[string]string
+}
+
+// fakeEIP eip in the fake cloud
+type fakeEIP struct {
+	id        string
+	address   net.IP
+	eniID     string
+	privateIP net.IP
+}
+
+// fakeCloud state of the fake cloud shared by priority views of fake ecs
+type fakeCloud struct {
+	lock         sync.Mutex
+	cfg          *FakeConfig
+	rand         *rand.Rand
+	latency      time.Duration
+	latencies    map[string]time.Duration
+	errorActions map[string]bool
+	subnet       *net.IPNet
+	gateway      net.IP
+	// used ips of vswitch
+	used    map[string]bool
+	mainENI *fakeENI
+	enis    map[string]*fakeENI
+	eips    map[string]*fakeEIP
+	// routes custom entries of the route table by cidr
+	routes map[string]*RouteEntry
+	nextID int
+}
+
+type fakeECS struct {
+	*fakeCloud
+	limiter  *RateLimiter
+	priority Priority
+}
+
+// NewFakeECS return ECS simulated in memory with latencies and errors injected, the node
+// metadata of terway served by the fake instance as well, state of fake cloud lost on restart
+func NewFakeECS(cfg *FakeConfig, limiter *RateLimiter) (ECS, error) {
+	setFakeDefaults(cfg)
+	cloud := &fakeCloud{
+		cfg:          cfg,
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		latencies:    make(map[string]time.Duration),
+		errorActions: make(map[string]bool),
+		used:         make(map[string]bool),
+		enis:         make(map[string]*fakeENI),
+		eips:         make(map[string]*fakeEIP),
+		routes:       make(map[string]*RouteEntry),
+	}
+	var err error
+	if cfg.Latency != "" {
+		if cloud.latency, err = time.ParseDuration(cfg.Latency); err != nil {
+			return nil, errors.Wrapf(err, "invalid latency of fake cloud")
+		}
+	}
+	for action, latency := range cfg.ActionLatency {
+		if cloud.latencies[action], err = time.ParseDuration(latency); err != nil {
+			return nil, errors.Wrapf(err, "invalid latency of fake cloud action %s", action)
+		}
+	}
+	for _, action := range cfg.ErrorActions {
+		cloud.errorActions[action] = true
+	}
+	if cfg.ErrorRate < 0 || cfg.ErrorRate > 1 {
+		return nil, fmt.Errorf("error rate of fake cloud %v out of range [0, 1]", cfg.ErrorRate)
+	}
+	_, cloud.subnet, err = net.ParseCIDR(cfg.VSwitchCIDR)
+	if err != nil || cloud.subnet.IP.To4() == nil {
+		return nil, fmt.Errorf("invalid ipv4 vswitch cidr of fake cloud: %s", cfg.VSwitchCIDR)
+	}
+	if ones, _ := cloud.subnet.Mask.Size(); ones > fakePrefixSize {
+		return nil, fmt.Errorf("vswitch cidr of fake cloud %s smaller than /%d", cfg.VSwitchCIDR, fakePrefixSize)
+	}
+	cloud.gateway = cloud.ipAt(1)
+	cloud.used[cloud.gateway.String()] = true
+
+	primary, err := cloud.allocateIP()
+	if err != nil {
+		return nil, err
+	}
+	cloud.mainENI = &fakeENI{
+		id:           cloud.newID("eni"),
+		mac:          cloud.newMAC(),
+		name:         fakeMainENIDeviceName,
+		deviceNumber: 1,
+		primary:      primary,
+		ips:          []net.IP{primary},
+	}
+
+	metadata.Default().SetLocal(map[string]string{
+		instanceIDPath: cfg.InstanceID,
+		regionIDPath:   cfg.Region,
+		zoneIDPath:     cfg.Zone,
+		vswitchIDPath:  cfg.VSwitch,
+		vpcIDPath:      cfg.VPC,
+	})
+	logrus.Infof("fake cloud initialized: %+v", cfg)
+	return &fakeECS{fakeCloud: cloud, limiter: limiter, priority: PriorityCritical}, nil
+}
+
+// WithPriority return view of fake ecs sharing the cloud and limiter in priority
+func (e *fakeECS) WithPriority(priority Priority) ECS {
+	return &fakeECS{fakeCloud: e.fakeCloud, limiter: e.limiter, priority: priority}
+}
+
+// WithENIAccount return fake ecs itself, resources of fake cloud are in one account
+func (e *fakeECS) WithENIAccount(account *ENIAccount) (ECS, error) {
+	return e, nil
+}
+
+// call simulate openapi call of action, return the injected error
+func (e *fakeECS) call(action string) error {
+	e.limiter.Wait(e.priority)
+	start := time.Now()
+	latency, ok := e.latencies[action]
+	if !ok {
+		latency = e.latency
+	}
+	time.Sleep(latency)
+
+	var err error
+	e.lock.Lock()
+	if e.cfg.ErrorRate > 0 && (len(e.errorActions) == 0 || e.errorActions[action]) && e.rand.Float64() < e.cfg.ErrorRate {
+		err = fakeError(e.cfg.ErrorCode, "injected error of fake cloud on %s", action)
+	}
+	e.lock.Unlock()
+	return observeOpenAPI(action, start, err)
+}
+
+func fakeError(code, format string, args ...interface{}) error {
+	return &common.Error{
+		ErrorResponse: common.ErrorResponse{Code: code, Message: fmt.Sprintf(format, args...)},
+		StatusCode:    400,
+	}
+}
+
+// ipAt return the ip at offset of vswitch cidr
+func (c *fakeCloud) ipAt(offset uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(c.subnet.IP.To4())+offset)
+	return ip
+}
+
+func (c *fakeCloud) size() uint32 {
+	ones, bits := c.subnet.Mask.Size()
+	return uint32(1) << uint(bits-ones)
+}
+
+// allocateIP allocate free ip of vswitch, the network and broadcast address excluded
+func (c *fakeCloud) allocateIP() (net.IP, error) {
+	for offset := uint32(1); offset < c.size()-1; offset++ {
+		ip := c.ipAt(offset)
+		if !c.used[ip.String()] {
+			c.used[ip.String()] = true
+			return ip, nil
+		}
+	}
+	return nil, fakeError(ErrInvalidVSwitchIDIPNotEnough, "no available ip in vswitch %s", c.cfg.VSwitch)
+}
+
+// allocatePrefix allocate aligned prefix of vswitch without any ip used
+func (c *fakeCloud) allocatePrefix() (*net.IPNet, error) {
+	prefixSize := uint32(1) << uint(32-fakePrefixSize)
+	for offset := uint32(0); offset+prefixSize <= c.size(); offset += prefixSize {
+		free := true
+		for i := uint32(0); i < prefixSize; i++ {
+			if c.used[c.ipAt(offset+i).String()] {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		for i := uint32(0); i < prefixSize; i++ {
+			c.used[c.ipAt(offset+i).String()] = true
+		}
+		return &net.IPNet{IP: c.ipAt(offset), Mask: net.CIDRMask(fakePrefixSize, 32)}, nil
+	}
+	return nil, fakeError(ErrInvalidVSwitchIDIPNotEnough, "no available prefix in vswitch %s", c.cfg.VSwitch)
+}
+
+func (c *fakeCloud) releasePrefix(prefix *net.IPNet) {
+	ones, _ := prefix.Mask.Size()
+	base := binary.BigEndian.Uint32(prefix.IP.To4())
+	for i := uint32(0); i < uint32(1)<<uint(32-ones); i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, base+i)
+		delete(c.used, ip.String())
+	}
+}
+
+func (c *fakeCloud) newID(prefix string) string {
+	c.nextID++
+	return fmt.Sprintf("%s-fake%08d", prefix, c.nextID)
+}
+
+// newMAC return locally administered mac address
+func (c *fakeCloud) newMAC() string {
+	return fmt.Sprintf("02:fa:%02x:%02x:%02x:%02x", c.rand.Intn(256), c.rand.Intn(256), c.rand.Intn(256), c.rand.Intn(256))
+}
+
+// eniByID return secondary eni of id
+func (c *fakeCloud) eniByID(eniID string) (*fakeENI, error) {
+	eni, ok := c.enis[eniID]
+	if !ok {
+		return nil, fakeError(fakeErrENINotFound, "eni %s not found", eniID)
+	}
+	return eni, nil
+}
+
+func (c *fakeCloud) toENI(eni *fakeENI) *types.ENI {
+	return &types.ENI{
+		ID:           eni.id,
+		Name:         eni.name,
+		Address:      net.IPNet{IP: eni.primary, Mask: c.subnet.Mask},
+		MAC:          eni.mac,
+		Gateway:      c.gateway,
+		DeviceNumber: eni.deviceNumber,
+		MaxIPs:       c.cfg.MaxIPPerENI,
+	}
+}
+
+// sortedENIs secondary enis in order of creation
+func (c *fakeCloud) sortedENIs() []*fakeENI {
+	enis := make([]*fakeENI, 0, len(c.enis))
+	for _, eni := range c.enis {
+		enis = append(enis, eni)
+	}
+	sort.Slice(enis, func(i, j int) bool {
+		return enis[i].id < enis[j].id
+	})
+	return enis
+}
+
+func (e *fakeECS) checkInstance(instanceID string) error {
+	if instanceID != e.cfg.InstanceID {
+		return fakeError("InvalidInstanceId.NotFound", "instance %s not found", instanceID)
+	}
+	return nil
+}
+
+func (e *fakeECS) AllocateENI(vSwitch string, securityGroup string, instanceID string) (*types.ENI, error) {
+	if vSwitch == "" || len(securityGroup) == 0 || instanceID == "" {
+		return nil, errors.Errorf("invalid eni args for allocate")
+	}
+	if err := e.call("CreateNetworkInterface"); err != nil {
+		return nil, err
+	}
+	if err := e.call("AttachNetworkInterface"); err != nil {
+		return nil, err
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if err := e.checkInstance(instanceID); err != nil {
+		return nil, err
+	}
+	if vSwitch != e.cfg.VSwitch {
+		return nil, fakeError("InvalidVSwitchId.NotFound", "vswitch %s not found", vSwitch)
+	}
+	if len(e.enis)+1 >= e.cfg.MaxENI {
+		return nil, fakeError(fakeErrENIQuota, "instance %s has max %d enis attached", instanceID, e.cfg.MaxENI)
+	}
+	primary, err := e.allocateIP()
+	if err != nil {
+		return nil, err
+	}
+	eni := &fakeENI{
+		id:      e.newID("eni"),
+		mac:     e.newMAC(),
+		primary: primary,
+		ips:     []net.IP{primary},
+	}
+	eni.name = fmt.Sprintf("%s%d", fakeDummyLinkPrefix, e.nextID)
+	eni.deviceNumber = int32(e.nextID + 1)
+	if e.cfg.CreateLinks {
+		if err = link.EnsureDummyLink(eni.name, eni.mac); err != nil {
+			delete(e.used, primary.String())
+			return nil, errors.Wrapf(err, "error create link of fake eni %s", eni.id)
+		}
+		if eni.deviceNumber, err = link.GetDeviceNumber(eni.mac); err != nil {
+			return nil, err
+		}
+	}
+	e.enis[eni.id] = eni
+	return e.toENI(eni), nil
+}
+
+func (e *fakeECS) GetAttachedENIs(instanceID string, containsMainENI bool) ([]*types.ENI, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if err := e.checkInstance(instanceID); err != nil {
+		return nil, err
+	}
+	var enis []*types.ENI
+	if containsMainENI {
+		enis = append(enis, e.toENI(e.mainENI))
+	}
+	for _, eni := range e.sortedENIs() {
+		enis = append(enis, e.toENI(eni))
+	}
+	return enis, nil
+}
+
+func (e *fakeECS) GetENIByID(instanceID, eniID string) (*types.ENI, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if eniID == e.mainENI.id {
+		return e.toENI(e.mainENI), nil
+	}
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return nil, err
+	}
+	return e.toENI(eni), nil
+}
+
+func (e *fakeECS) GetENIByMac(instanceID, mac string) (*types.ENI, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if mac == e.mainENI.mac {
+		return e.toENI(e.mainENI), nil
+	}
+	for _, eni := range e.enis {
+		if eni.mac == mac {
+			return e.toENI(eni), nil
+		}
+	}
+	return nil, errors.Errorf("not found eni of mac: %s", mac)
+}
+
+func (e *fakeECS) FreeENI(eniID string, instanceID string) error {
+	if err := e.call("DetachNetworkInterface"); err != nil {
+		return err
+	}
+	if err := e.call("DeleteNetworkInterface"); err != nil {
+		return err
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return err
+	}
+	if e.cfg.CreateLinks {
+		if err = link.DeleteLinkByMAC(eni.mac); err != nil {
+			return errors.Wrapf(err, "error delete link of fake eni %s", eniID)
+		}
+	}
+	for _, ip := range eni.ips {
+		delete(e.used, ip.String())
+	}
+	for _, prefix := range eni.prefixes {
+		e.releasePrefix(prefix)
+	}
+	delete(e.enis, eniID)
+	return nil
+}
+
+func (e *fakeECS) GetENIIPs(eniID string) ([]net.IP, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]net.IP{}, eni.ips...), nil
+}
+
+func (e *fakeECS) AssignIPForENI(eniID string) (net.IP, error) {
+	ipList, err := e.AssignNIPsForENI(eniID, 1)
+	if err != nil {
+		return nil, err
+	}
+	return ipList[0], nil
+}
+
+func (e *fakeECS) AssignNIPsForENI(eniID string, count int) ([]net.IP, error) {
+	if err := e.call("AssignPrivateIpAddresses"); err != nil {
+		return nil, errors.Wrapf(err, "error assign address for eniID: %v", eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return nil, err
+	}
+	if len(eni.ips)+count > e.cfg.MaxIPPerENI {
+		return nil, fakeError(fakeErrIPQuota, "eni %s has max %d ips", eniID, e.cfg.MaxIPPerENI)
+	}
+	var ips []net.IP
+	for i := 0; i < count; i++ {
+		ip, err := e.allocateIP()
+		if err != nil {
+			for _, allocated := range ips {
+				delete(e.used, allocated.String())
+			}
+			return nil, errors.Wrapf(err, "error assign address for eniID: %v", eniID)
+		}
+		ips = append(ips, ip)
+	}
+	eni.ips = append(eni.ips, ips...)
+	return ips, nil
+}
+
+func (e *fakeECS) UnAssignIPForENI(eniID string, ip net.IP) error {
+	return e.UnAssignIPsForENI(eniID, []net.IP{ip})
+}
+
+func (e *fakeECS) UnAssignIPsForENI(eniID string, ips []net.IP) error {
+	if err := e.call("UnassignPrivateIpAddresses"); err != nil {
+		return errors.Wrapf(err, "error unassign address for eniID: %v", eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return err
+	}
+	remove := make(map[string]bool)
+	for _, ip := range ips {
+		if !ip.Equal(eni.primary) {
+			remove[ip.String()] = true
+		}
+	}
+	var remain []net.IP
+	for _, ip := range eni.ips {
+		if remove[ip.String()] {
+			delete(e.used, ip.String())
+			continue
+		}
+		remain = append(remain, ip)
+	}
+	eni.ips = remain
+	return nil
+}
+
+func (e *fakeECS) GetENIPrefixes(eniID string) ([]*net.IPNet, error) {
+	if err := e.call("DescribeNetworkInterfaces"); err != nil {
+		return nil, errors.Wrapf(err, "error describe prefixes of eni: %s", eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*net.IPNet{}, eni.prefixes...), nil
+}
+
+func (e *fakeECS) AssignPrefixForENI(eniID string) (*net.IPNet, error) {
+	if err := e.call("AssignIpv4Prefix"); err != nil {
+		return nil, errors.Wrapf(err, "error assign prefix for eniID: %v", eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := e.allocatePrefix()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error assign prefix for eniID: %v", eniID)
+	}
+	eni.prefixes = append(eni.prefixes, prefix)
+	return prefix, nil
+}
+
+func (e *fakeECS) UnAssignPrefixForENI(eniID string, prefix *net.IPNet) error {
+	if err := e.call("UnassignIpv4Prefix"); err != nil {
+		return errors.Wrapf(err, "error unassign prefix %s for eniID: %v", prefix, eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return err
+	}
+	for i, p := range eni.prefixes {
+		if p.String() == prefix.String() {
+			e.releasePrefix(p)
+			eni.prefixes = append(eni.prefixes[:i], eni.prefixes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (e *fakeECS) GetInstanceMaxENI(instanceID string) (int, error) {
+	if err := e.call("DescribeInstanceTypesNew"); err != nil {
+		return 0, errors.Wrapf(err, "error get instance max eni: %v", instanceID)
+	}
+	return e.cfg.MaxENI, e.checkInstance(instanceID)
+}
+
+func (e *fakeECS) GetInstanceMaxPrivateIP(instanceID string) (int, error) {
+	if err := e.call("DescribeInstanceTypesNew"); err != nil {
+		return 0, errors.Wrapf(err, "error get instance max eni: %v", instanceID)
+	}
+	return (e.cfg.MaxENI - 1) * e.cfg.MaxIPPerENI, e.checkInstance(instanceID)
+}
+
+func (e *fakeECS) GetENIMaxIP(instanceID string, eniID string) (int, error) {
+	if err := e.call("DescribeInstanceTypesNew"); err != nil {
+		return 0, errors.Wrapf(err, "error get instance max eni ip: %v", instanceID)
+	}
+	return e.cfg.MaxIPPerENI, e.checkInstance(instanceID)
+}
+
+func (e *fakeECS) GetAttachedSecurityGroup(instanceID string) (string, error) {
+	if err := e.call("DescribeInstanceAttribute"); err != nil {
+		return "", errors.Wrapf(err, "error describe instance attribute for security group: %s", instanceID)
+	}
+	return e.cfg.SecurityGroup, e.checkInstance(instanceID)
+}
+
+func (e *fakeECS) DescribeVSwitch(vSwitch string) (string, int, error) {
+	if err := e.call("DescribeVSwitches"); err != nil {
+		return "", 0, errors.Wrapf(err, "error describe vswitch: %s", vSwitch)
+	}
+	if vSwitch != e.cfg.VSwitch {
+		return "", 0, errors.Errorf("vswitch %s not found", vSwitch)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.cfg.Zone, int(e.size()) - 2 - len(e.used), nil
+}
+
+func (e *fakeECS) ListVSwitches(vpcID, zone string) ([]*VSwitch, error) {
+	if err := e.call("DescribeVSwitches"); err != nil {
+		return nil, errors.Wrapf(err, "error describe vswitches of vpc %s", vpcID)
+	}
+	if vpcID != e.cfg.VPC || (zone != "" && zone != e.cfg.Zone) {
+		return nil, nil
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return []*VSwitch{{
+		ID:           e.cfg.VSwitch,
+		CIDR:         e.subnet,
+		Zone:         e.cfg.Zone,
+		AvailableIPs: int(e.size()) - 2 - len(e.used),
+		Status:       "Available",
+	}}, nil
+}
+
+// CreateVSwitch not supported by fake cloud, which has the only vswitch configured
+func (e *fakeECS) CreateVSwitch(vpcID, zone string, cidr *net.IPNet, name string) (string, error) {
+	if err := e.call("CreateVSwitch"); err != nil {
+		return "", errors.Wrapf(err, "error create vswitch of %s in zone %s", cidr, zone)
+	}
+	return "", fakeError("OperationUnsupported", "create vswitch not supported by fake cloud")
+}
+
+func (e *fakeECS) GetSecurityGroupVPC(securityGroup string) (string, error) {
+	if err := e.call("DescribeSecurityGroupAttribute"); err != nil {
+		return "", errors.Wrapf(err, "error describe security group: %s", securityGroup)
+	}
+	if securityGroup != e.cfg.SecurityGroup {
+		return "", fakeError("InvalidSecurityGroupId.NotFound", "security group %s not found", securityGroup)
+	}
+	return e.cfg.VPC, nil
+}
+
+func (e *fakeECS) CheckPermission(instanceID string) error {
+	return nil
+}
+
+func (e *fakeECS) ListInstanceENIs() ([]*InstanceENI, error) {
+	return e.ListTerwayENIs()
+}
+
+// ListTerwayENIs list enis of fake cloud, all attached to the fake instance
+func (e *fakeECS) ListTerwayENIs() ([]*InstanceENI, error) {
+	if err := e.call("DescribeNetworkInterfaces"); err != nil {
+		return nil, errors.Wrapf(err, "error describe network interfaces of region")
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	var enis []*InstanceENI
+	for _, eni := range e.sortedENIs() {
+		enis = append(enis, &InstanceENI{
+			ID:             eni.id,
+			InstanceID:     e.cfg.InstanceID,
+			MAC:            eni.mac,
+			IPs:            append([]net.IP{}, eni.ips...),
+			Status:         eniStatusInUse,
+			VSwitchID:      e.cfg.VSwitch,
+			SecurityGroups: []string{e.cfg.SecurityGroup},
+			Owner:          e.cfg.InstanceID,
+		})
+	}
+	return enis, nil
+}
+
+// ListOwnedENIs list enis of fake cloud as all created for the fake instance
+func (e *fakeECS) ListOwnedENIs(instanceID string) ([]*InstanceENI, error) {
+	if err := e.checkInstance(instanceID); err != nil {
+		return nil, err
+	}
+	return e.ListTerwayENIs()
+}
+
+// AttachENI always fails as enis of fake cloud are attached
+func (e *fakeECS) AttachENI(eniID, instanceID string) (*types.ENI, error) {
+	if err := e.call("AttachNetworkInterface"); err != nil {
+		return nil, err
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, err := e.eniByID(eniID); err != nil {
+		return nil, err
+	}
+	return nil, fakeError("InvalidOperation.InvalidEniState", "eni %s attached to instance", eniID)
+}
+
+// DeleteENI always fails as enis of fake cloud are attached
+func (e *fakeECS) DeleteENI(eniID string) error {
+	if err := e.call("DeleteNetworkInterface"); err != nil {
+		return errors.Wrapf(err, "error delete eni %s", eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, err := e.eniByID(eniID); err != nil {
+		return err
+	}
+	return fakeError("InvalidOperation.InvalidEniState", "eni %s attached to instance", eniID)
+}
+
+func (e *fakeECS) TagENI(eniID string, tags map[string]string) error {
+	if err := e.call("AddTags"); err != nil {
+		return errors.Wrapf(err, "error add tags to eni %s", eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return err
+	}
+	merged := make(map[string]string, len(eni.tags)+len(tags))
+	for k, v := range eni.tags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	if len(merged) > maxTagsPerResource {
+		return fakeError("InvalidTagCount", "eni %s has more than %d tags", eniID, maxTagsPerResource)
+	}
+	eni.tags = merged
+	return nil
+}
+
+func (e *fakeECS) UntagENI(eniID string, tags map[string]string) error {
+	if err := e.call("RemoveTags"); err != nil {
+		return errors.Wrapf(err, "error remove tags from eni %s", eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return err
+	}
+	for k := range tags {
+		delete(eni.tags, k)
+	}
+	return nil
+}
+
+func (e *fakeECS) GetENITags(eniID string) (map[string]string, error) {
+	if err := e.call("DescribeTags"); err != nil {
+		return nil, errors.Wrapf(err, "error describe tags of eni %s", eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(eni.tags))
+	for k, v := range eni.tags {
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// eipByID return eip of allocation id
+func (c *fakeCloud) eipByID(eipID string) (*fakeEIP, error) {
+	eip, ok := c.eips[eipID]
+	if !ok {
+		return nil, fakeError(fakeErrEIPNotFound, "eip %s not found", eipID)
+	}
+	return eip, nil
+}
+
+func (e *fakeECS) AllocateEIP(bandwidth int) (*EIP, error) {
+	if err := e.call("AllocateEipAddress"); err != nil {
+		return nil, errors.Wrapf(err, "error allocate eip")
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	_, cidr, _ := net.ParseCIDR(fakeEIPCIDR)
+	for offset := 1; offset < 255; offset++ {
+		address := net.IPv4(cidr.IP[0], cidr.IP[1], cidr.IP[2], byte(offset))
+		used := false
+		for _, eip := range e.eips {
+			if eip.address.Equal(address) {
+				used = true
+				break
+			}
+		}
+		if used {
+			continue
+		}
+		eip := &fakeEIP{id: e.newID("eip"), address: address}
+		e.eips[eip.id] = eip
+		return &EIP{ID: eip.id, Address: address, Status: eipStatusAvailable}, nil
+	}
+	return nil, fakeError("QuotaExceeded.Eip", "no eip available in %s", fakeEIPCIDR)
+}
+
+func (e *fakeECS) GetEIP(eipID string) (*EIP, error) {
+	if err := e.call("DescribeEipAddresses"); err != nil {
+		return nil, errors.Wrapf(err, "error describe eip %s", eipID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eip, err := e.eipByID(eipID)
+	if err != nil {
+		return nil, err
+	}
+	status := eipStatusAvailable
+	if eip.eniID != "" {
+		status = eipStatusInUse
+	}
+	return &EIP{ID: eip.id, Address: eip.address, Status: status, ENIID: eip.eniID, PrivateIP: eip.privateIP}, nil
+}
+
+func (e *fakeECS) AssociateEIP(eipID, eniID string, privateIP net.IP) error {
+	if err := e.call("AssociateEipAddress"); err != nil {
+		return errors.Wrapf(err, "error associate eip %s to eni %s", eipID, eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eip, err := e.eipByID(eipID)
+	if err != nil {
+		return err
+	}
+	if eip.eniID != "" {
+		return fakeError("IncorrectEipStatus", "eip %s associated to eni %s", eipID, eip.eniID)
+	}
+	eni, err := e.eniByID(eniID)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, ip := range eni.ips {
+		if ip.Equal(privateIP) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fakeError("InvalidPrivateIpAddress.NotFound", "ip %s not found on eni %s", privateIP, eniID)
+	}
+	eip.eniID, eip.privateIP = eniID, privateIP
+	return nil
+}
+
+func (e *fakeECS) UnassociateEIP(eipID, eniID string, privateIP net.IP) error {
+	if err := e.call("UnassociateEipAddress"); err != nil {
+		return errors.Wrapf(err, "error unassociate eip %s from eni %s", eipID, eniID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eip, err := e.eipByID(eipID)
+	if err != nil {
+		return err
+	}
+	if eip.eniID == eniID && eip.privateIP.Equal(privateIP) {
+		eip.eniID, eip.privateIP = "", nil
+	}
+	return nil
+}
+
+func (e *fakeECS) ReleaseEIP(eipID string) error {
+	if err := e.call("ReleaseEipAddress"); err != nil {
+		return errors.Wrapf(err, "error release eip %s", eipID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	eip, err := e.eipByID(eipID)
+	if err != nil {
+		return err
+	}
+	if eip.eniID != "" {
+		return fakeError("IncorrectEipStatus", "eip %s associated to eni %s", eipID, eip.eniID)
+	}
+	delete(e.eips, eipID)
+	return nil
+}
+
+func (e *fakeECS) GetVPCRouteTables(vpcID string) ([]string, error) {
+	if err := e.call("DescribeVpcs"); err != nil {
+		return nil, errors.Wrapf(err, "error describe vpc %s", vpcID)
+	}
+	if vpcID != e.cfg.VPC {
+		return nil, fmt.Errorf("vpc %s not found", vpcID)
+	}
+	return []string{e.cfg.RouteTable}, nil
+}
+
+func (e *fakeECS) ListRouteEntries(routeTableID string) ([]*RouteEntry, error) {
+	if err := e.call("DescribeRouteTables"); err != nil {
+		return nil, errors.Wrapf(err, "error describe route tables of vrouter %q table %q", "", routeTableID)
+	}
+	if routeTableID != e.cfg.RouteTable {
+		return nil, nil
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	var entries []*RouteEntry
+	for _, entry := range e.routes {
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CIDR.String() < entries[j].CIDR.String() })
+	return entries, nil
+}
+
+func (e *fakeECS) CreateRouteEntry(routeTableID string, cidr *net.IPNet, instanceID string) error {
+	if err := e.call("CreateRouteEntry"); err != nil {
+		return errors.Wrapf(err, "error create route entry of %s to instance %s in %s", cidr, instanceID, routeTableID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if routeTableID != e.cfg.RouteTable {
+		return fakeError("InvalidRouteTableId.NotFound", "route table %s not found", routeTableID)
+	}
+	if _, ok := e.routes[cidr.String()]; ok {
+		return fakeError("InvalidCIDRBlock.Duplicate", "route entry of %s exists", cidr)
+	}
+	if len(e.routes) >= e.cfg.MaxRouteEntries {
+		return fakeError(fakeErrRouteQuota, "route entries of %s exceed quota %d", routeTableID, e.cfg.MaxRouteEntries)
+	}
+	e.routes[cidr.String()] = &RouteEntry{RouteTableID: routeTableID, CIDR: cidr, NextHopType: RouteNextHopInstance,
+		NextHopID: instanceID, InstanceID: instanceID, Status: "Available"}
+	return nil
+}
+
+func (e *fakeECS) DeleteRouteEntry(routeTableID string, cidr *net.IPNet, instanceID string) error {
+	if err := e.call("DeleteRouteEntry"); err != nil {
+		return errors.Wrapf(err, "error delete route entry of %s to instance %s in %s", cidr, instanceID, routeTableID)
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	entry, ok := e.routes[cidr.String()]
+	if routeTableID != e.cfg.RouteTable || !ok || entry.InstanceID != instanceID {
+		return fakeError("InvalidRouteEntry.NotFound", "route entry of %s to instance %s not found", cidr, instanceID)
+	}
+	delete(e.routes, cidr.String())
+	return nil
+}
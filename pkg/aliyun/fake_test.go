@@ -0,0 +1,59 @@
+package aliyun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeECS(t *testing.T) {
+	ecs, err := NewFakeECS(&FakeConfig{VSwitchCIDR: "10.0.0.0/24", MaxENI: 2, MaxIPPerENI: 3}, nil)
+	assert.NoError(t, err)
+	instanceID, err := GetLocalInstanceID()
+	assert.NoError(t, err)
+	assert.Equal(t, "i-fake", instanceID)
+
+	eni, err := ecs.AllocateENI("vsw-fake", "sg-fake", instanceID)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", eni.Gateway.String())
+	assert.Equal(t, 3, eni.MaxIPs)
+	// main eni counted in max enis
+	_, err = ecs.AllocateENI("vsw-fake", "sg-fake", instanceID)
+	assert.Error(t, err)
+
+	ips, err := ecs.AssignNIPsForENI(eni.ID, 2)
+	assert.NoError(t, err)
+	assert.Len(t, ips, 2)
+	_, err = ecs.AssignIPForENI(eni.ID)
+	assert.Error(t, err)
+	assert.NoError(t, ecs.UnAssignIPForENI(eni.ID, ips[0]))
+	all, err := ecs.GetENIIPs(eni.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{eni.Address.IP.String(), ips[1].String()}, []string{all[0].String(), all[1].String()})
+
+	prefix, err := ecs.AssignPrefixForENI(eni.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.16/28", prefix.String())
+
+	enis, err := ecs.ListInstanceENIs()
+	assert.NoError(t, err)
+	assert.Len(t, enis, 1)
+	assert.NoError(t, ecs.FreeENI(eni.ID, instanceID))
+	enis, err = ecs.ListInstanceENIs()
+	assert.NoError(t, err)
+	assert.Empty(t, enis)
+}
+
+func TestFakeECSErrorInjection(t *testing.T) {
+	ecs, err := NewFakeECS(&FakeConfig{
+		ErrorRate:    1,
+		ErrorCode:    ErrInvalidVSwitchIDIPNotEnough,
+		ErrorActions: []string{"CreateNetworkInterface"},
+	}, nil)
+	assert.NoError(t, err)
+
+	_, err = ecs.AllocateENI("vsw-fake", "sg-fake", "i-fake")
+	assert.True(t, IsIPNotEnough(err))
+	_, err = ecs.GetAttachedENIs("i-fake", true)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,117 @@
+package aliyun
+
+import (
+	"sync"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// Priority the priority of openapi caller in rate limiter
+type Priority int
+
+// priorities of openapi callers
+const (
+	// PriorityCritical calls on the path of pod creating, eg: assign ip for pending pod
+	PriorityCritical Priority = iota
+	// PriorityBackground calls not blocking pods, eg: release idle resources, gc
+	PriorityBackground
+
+	priorityCount
+)
+
+// weights of priorities, critical callers get 4 of every 5 tokens under contention
+var priorityWeights = [priorityCount]float64{
+	PriorityCritical:   4,
+	PriorityBackground: 1,
+}
+
+type waiter struct {
+	finish float64
+	ready  chan struct{}
+}
+
+// RateLimiter limit the qps of openapi calls on node, share the tokens
+// between callers by weighted fair queueing of priorities
+type RateLimiter struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	bucket flowcontrol.RateLimiter
+	queues [priorityCount][]*waiter
+	// virtual time of queue and last finish tag of priorities
+	virtual    float64
+	lastFinish [priorityCount]float64
+}
+
+// NewRateLimiter return rate limiter of qps and burst
+func NewRateLimiter(qps float32, burst int) *RateLimiter {
+	return newRateLimiter(flowcontrol.NewTokenBucketRateLimiter(qps, burst))
+}
+
+func newRateLimiter(bucket flowcontrol.RateLimiter) *RateLimiter {
+	l := &RateLimiter{
+		bucket: bucket,
+	}
+	l.cond = sync.NewCond(&l.lock)
+	go l.dispatch()
+	return l
+}
+
+// Wait block until the caller of priority can call openapi, nil limiter not limit
+func (l *RateLimiter) Wait(priority Priority) {
+	if l == nil {
+		return
+	}
+	if priority < 0 || priority >= priorityCount {
+		priority = PriorityBackground
+	}
+	l.lock.Lock()
+	start := l.virtual
+	if l.lastFinish[priority] > start {
+		start = l.lastFinish[priority]
+	}
+	w := &waiter{
+		finish: start + 1/priorityWeights[priority],
+		ready:  make(chan struct{}),
+	}
+	l.lastFinish[priority] = w.finish
+	l.queues[priority] = append(l.queues[priority], w)
+	l.cond.Signal()
+	l.lock.Unlock()
+	<-w.ready
+}
+
+func (l *RateLimiter) pendingLocked() bool {
+	for _, q := range l.queues {
+		if len(q) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch hand out tokens to the waiter with smallest finish tag
+func (l *RateLimiter) dispatch() {
+	for {
+		l.lock.Lock()
+		for !l.pendingLocked() {
+			l.cond.Wait()
+		}
+		l.lock.Unlock()
+
+		// pick waiter after got token, so critical callers arrived while waiting take precedence
+		l.bucket.Accept()
+
+		l.lock.Lock()
+		next := -1
+		for i, q := range l.queues {
+			if len(q) != 0 && (next < 0 || q[0].finish < l.queues[next][0].finish) {
+				next = i
+			}
+		}
+		w := l.queues[next][0]
+		l.queues[next] = l.queues[next][1:]
+		l.virtual = w.finish
+		l.lock.Unlock()
+		close(w.ready)
+	}
+}
@@ -0,0 +1,61 @@
+package aliyun
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// manualBucket hand out a token on each send of tokens
+type manualBucket struct {
+	flowcontrol.RateLimiter
+	tokens chan struct{}
+}
+
+func (b *manualBucket) Accept() {
+	<-b.tokens
+}
+
+func (l *RateLimiter) queued() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	n := 0
+	for _, q := range l.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func TestRateLimiterPriority(t *testing.T) {
+	bucket := &manualBucket{tokens: make(chan struct{})}
+	l := newRateLimiter(bucket)
+
+	order := make(chan Priority, 10)
+	for i := 0; i < 5; i++ {
+		for _, p := range []Priority{PriorityBackground, PriorityCritical} {
+			go func(p Priority) {
+				l.Wait(p)
+				order <- p
+			}(p)
+		}
+	}
+	for l.queued() != 10 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var served []Priority
+	for i := 0; i < 10; i++ {
+		bucket.tokens <- struct{}{}
+		served = append(served, <-order)
+	}
+	// weight 4:1, background served once in every 5 calls under contention
+	assert.Equal(t, []Priority{
+		PriorityCritical, PriorityCritical, PriorityCritical, PriorityCritical, PriorityBackground,
+		PriorityCritical, PriorityBackground, PriorityBackground, PriorityBackground, PriorityBackground,
+	}, served)
+
+	var unlimited *RateLimiter
+	unlimited.Wait(PriorityBackground)
+}
@@ -1,18 +1,11 @@
 package aliyun
 
 import (
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strings"
-	"time"
-
-	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/metadata"
 	"github.com/denverdino/aliyungo/common"
 )
 
 const (
-	metadataBase   = "http://100.100.100.200/latest/meta-data/"
 	mainEniPath    = "mac"
 	enisPath       = "network/interfaces/macs/"
 	eniIDPath      = "network/interfaces/macs/%s/network-interface-id"
@@ -27,65 +20,12 @@ const (
 	vpcIDPath      = "vpc-id"
 )
 
-func metadataValue(url string) (string, error) {
-	if !strings.HasPrefix(url, metadataBase) {
-		url = metadataBase + url
-	}
-	var (
-		start = time.Now()
-		err   error
-	)
-	defer func() {
-		metric.MetadataLatency.WithLabelValues(url, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
-	}()
-	resp, err := http.DefaultClient.Get(url)
-	if err != nil {
-		return "", err
-	}
-
-	if resp.StatusCode >= http.StatusBadRequest {
-		return "", fmt.Errorf("error get url: %s from metaserver, code: %v", url, resp.StatusCode)
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	result := strings.Split(string(body), "\n")
-	trimResult := strings.Trim(result[0], "/")
-	return trimResult, nil
+func metadataValue(path string) (string, error) {
+	return metadata.Default().Value(path)
 }
 
-func metadataArray(url string) ([]string, error) {
-	if !strings.HasPrefix(url, metadataBase) {
-		url = metadataBase + url
-	}
-	var (
-		start = time.Now()
-		err   error
-	)
-	defer func() {
-		metric.MetadataLatency.WithLabelValues(url, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
-	}()
-
-	resp, err := http.DefaultClient.Get(url)
-	if err != nil {
-		return []string{}, err
-	}
-
-	if resp.StatusCode >= http.StatusBadRequest {
-		return []string{}, fmt.Errorf("error get url: %s from metaserver, code: %v", url, resp.StatusCode)
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return []string{}, err
-	}
-	result := strings.Split(string(body), "\n")
-	for i, str := range result {
-		result[i] = strings.Trim(str, "/")
-	}
-	return result, nil
+func metadataArray(path string) ([]string, error) {
+	return metadata.Default().Array(path)
 }
 
 // GetLocalInstanceID get instance id of this node
@@ -0,0 +1,149 @@
+package aliyun
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// ipv4 prefix of eni not covered by the vendored sdk, invoked with the sdk client directly
+
+type assignIPv4PrefixArgs struct {
+	RegionId           common.Region
+	NetworkInterfaceId string
+	Ipv4PrefixCount    int
+}
+
+type unassignIPv4PrefixArgs struct {
+	RegionId           common.Region
+	NetworkInterfaceId string
+	Ipv4Prefix         []string `query:"list"`
+}
+
+type describeENIPrefixesArgs struct {
+	RegionId           common.Region
+	NetworkInterfaceId []string `query:"list"`
+}
+
+type describeENIPrefixesResponse struct {
+	common.Response
+	NetworkInterfaceSets struct {
+		NetworkInterfaceSet []struct {
+			NetworkInterfaceId string
+			Ipv4PrefixSets     struct {
+				Ipv4PrefixSet []struct {
+					Ipv4Prefix string
+				}
+			}
+		}
+	}
+}
+
+// GetENIPrefixes return ipv4 prefixes assigned to eni
+func (e *ecsImpl) GetENIPrefixes(eniID string) ([]*net.IPNet, error) {
+	e.wait()
+	start := time.Now()
+	resp := &describeENIPrefixesResponse{}
+	err := e.clientSet.ecs.Invoke("DescribeNetworkInterfaces", &describeENIPrefixesArgs{
+		RegionId:           e.region,
+		NetworkInterfaceId: []string{eniID},
+	}, resp)
+	err = observeOpenAPI("DescribeNetworkInterfaces", start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error describe prefixes of eni: %s", eniID)
+	}
+	if len(resp.NetworkInterfaceSets.NetworkInterfaceSet) != 1 {
+		return nil, fmt.Errorf("unexpect number of eni of id: %s", eniID)
+	}
+
+	var prefixes []*net.IPNet
+	for _, p := range resp.NetworkInterfaceSets.NetworkInterfaceSet[0].Ipv4PrefixSets.Ipv4PrefixSet {
+		_, prefix, err := net.ParseCIDR(p.Ipv4Prefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid prefix %s of eni: %s", p.Ipv4Prefix, eniID)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// AssignPrefixForENI assign an ipv4 prefix to eni, the size of prefix decided by vpc, eg: /28
+func (e *ecsImpl) AssignPrefixForENI(eniID string) (*net.IPNet, error) {
+	e.privateIPMutex.Lock()
+	defer e.privateIPMutex.Unlock()
+	prefixesBefore, err := e.GetENIPrefixes(eniID)
+	if err != nil {
+		return nil, err
+	}
+
+	e.wait()
+	start := time.Now()
+	resp := common.Response{}
+	err = e.clientSet.ecs.Invoke("AssignPrivateIpAddresses", &assignIPv4PrefixArgs{
+		RegionId:           e.region,
+		NetworkInterfaceId: eniID,
+		Ipv4PrefixCount:    1,
+	}, &resp)
+	err = observeMutation("AssignIpv4Prefix", eniID, "count 1", resp.RequestId, start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error assign prefix for eniID: %v", eniID)
+	}
+
+	start = time.Now()
+	var prefixesAfter []*net.IPNet
+	// backoff get interface prefixes
+	err = wait.ExponentialBackoff(
+		wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0,
+			Steps:    5,
+		},
+		func() (done bool, err error) {
+			prefixesAfter, err = e.GetENIPrefixes(eniID)
+			if err != nil {
+				return false, err
+			}
+			return len(prefixesAfter) > len(prefixesBefore), nil
+		},
+	)
+	err = observeOpenAPI("AssignIpv4PrefixAsync", start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error allocate eni prefix for %s", eniID)
+	}
+
+	before := make(map[string]bool)
+	for _, prefix := range prefixesBefore {
+		before[prefix.String()] = true
+	}
+	for _, prefix := range prefixesAfter {
+		if !before[prefix.String()] {
+			return prefix, nil
+		}
+	}
+	return nil, fmt.Errorf("no new prefix found on eni: %s", eniID)
+}
+
+// UnAssignPrefixForENI unassign ipv4 prefix of eni
+func (e *ecsImpl) UnAssignPrefixForENI(eniID string, prefix *net.IPNet) error {
+	e.privateIPMutex.Lock()
+	defer e.privateIPMutex.Unlock()
+
+	e.wait()
+	start := time.Now()
+	resp := common.Response{}
+	err := e.clientSet.ecs.Invoke("UnassignPrivateIpAddresses", &unassignIPv4PrefixArgs{
+		RegionId:           e.region,
+		NetworkInterfaceId: eniID,
+		Ipv4Prefix:         []string{prefix.String()},
+	}, &resp)
+	err = observeMutation("UnassignIpv4Prefix", eniID, "prefix "+prefix.String(), resp.RequestId, start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error unassign prefix %s for eniID: %v", prefix, eniID)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package aliyun
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/AliyunContainerService/terway/pkg/tracing"
+)
+
+const maxRecentCalls = 200
+
+// APICall record of openapi call
+type APICall struct {
+	Action   string        `json:"action"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+var recentCalls = struct {
+	sync.Mutex
+	calls []APICall
+	next  int
+}{}
+
+// throttledCalls count of openapi calls failed with throttling of process
+var throttledCalls uint64
+
+// observeOpenAPI observe latency of openapi call and record it in recent calls, return err classified by typed errors
+func observeOpenAPI(action string, start time.Time, err error) error {
+	metric.OpenAPILatency.WithLabelValues(action, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	// calls of factories without context of allocation, traced standalone
+	tracing.Record(context.Background(), "aliyun/"+action, tracing.KindClient, start, time.Now(), err)
+	call := APICall{Action: action, Start: start, Duration: time.Since(start)}
+	if err != nil {
+		call.Error = err.Error()
+	}
+	if IsThrottling(err) {
+		atomic.AddUint64(&throttledCalls, 1)
+	}
+	recentCalls.Lock()
+	defer recentCalls.Unlock()
+	if len(recentCalls.calls) < maxRecentCalls {
+		recentCalls.calls = append(recentCalls.calls, call)
+	} else {
+		recentCalls.calls[recentCalls.next] = call
+		recentCalls.next = (recentCalls.next + 1) % maxRecentCalls
+	}
+	return classify(err)
+}
+
+// RecentCalls return recent openapi calls of process, oldest first
+func RecentCalls() []APICall {
+	recentCalls.Lock()
+	defer recentCalls.Unlock()
+	ret := make([]APICall, 0, len(recentCalls.calls))
+	ret = append(ret, recentCalls.calls[recentCalls.next:]...)
+	return append(ret, recentCalls.calls[:recentCalls.next]...)
+}
+
+// ThrottledCalls return count of openapi calls throttled since process started
+func ThrottledCalls() uint64 {
+	return atomic.LoadUint64(&throttledCalls)
+}
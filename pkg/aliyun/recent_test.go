@@ -0,0 +1,26 @@
+package aliyun
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentCalls(t *testing.T) {
+	observeOpenAPI("DescribeVSwitches", time.Now(), errors.New("throttling"))
+	calls := RecentCalls()
+	assert.Equal(t, "DescribeVSwitches", calls[len(calls)-1].Action)
+	assert.Equal(t, "throttling", calls[len(calls)-1].Error)
+
+	for i := 0; i < maxRecentCalls+10; i++ {
+		observeOpenAPI(fmt.Sprintf("Action%d", i), time.Now(), nil)
+	}
+	calls = RecentCalls()
+	assert.Len(t, calls, maxRecentCalls)
+	assert.Equal(t, "Action10", calls[0].Action)
+	assert.Equal(t, fmt.Sprintf("Action%d", maxRecentCalls+9), calls[maxRecentCalls-1].Action)
+	assert.Empty(t, calls[0].Error)
+}
@@ -0,0 +1,203 @@
+package aliyun
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// route entries of vpc route tables, the vendored sdk lacks region of the calls, invoked with the sdk client directly
+
+const (
+	describeRouteTablePageSize = 50
+	// RouteNextHopInstance next hop type of entries to ecs instances
+	RouteNextHopInstance = "Instance"
+)
+
+// RouteEntry custom entry of vpc route table, view from openapi
+type RouteEntry struct {
+	RouteTableID string
+	CIDR         *net.IPNet
+	// NextHopType type of next hop, eg: "Instance", "HaVip" or "NetworkInterface"
+	NextHopType string
+	NextHopID   string
+	// InstanceID next hop of the entry, empty if next hop not an instance
+	InstanceID string
+	// Status of entry, eg: "Pending" or "Available"
+	Status string
+}
+
+type describeRouteTablesArgs struct {
+	RegionId     common.Region
+	VRouterId    string
+	RouteTableId string
+	PageNumber   int
+	PageSize     int
+}
+
+type routeEntryArgs struct {
+	RegionId             common.Region
+	RouteTableId         string
+	DestinationCidrBlock string
+	NextHopType          string
+	NextHopId            string
+}
+
+// GetVPCRouteTables return ids of system route tables of vrouter of vpc
+func (e *ecsImpl) GetVPCRouteTables(vpcID string) ([]string, error) {
+	e.wait()
+	start := time.Now()
+	vpcs, _, err := e.clientSet.vpc.DescribeVpcs(&ecs.DescribeVpcsArgs{
+		RegionId: e.region,
+		VpcId:    vpcID,
+	})
+	err = observeOpenAPI("DescribeVpcs", start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error describe vpc %s", vpcID)
+	}
+	if len(vpcs) != 1 {
+		return nil, fmt.Errorf("vpc %s not found", vpcID)
+	}
+	tables, err := e.describeRouteTables(vpcs[0].VRouterId, "")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, table := range tables {
+		if table.RouteTableType == ecs.RouteTableSystem {
+			ids = append(ids, table.RouteTableId)
+		}
+	}
+	return ids, nil
+}
+
+func (e *ecsImpl) describeRouteTables(vRouterID, routeTableID string) ([]ecs.RouteTableSetType, error) {
+	var tables []ecs.RouteTableSetType
+	for page := 1; ; page++ {
+		e.wait()
+		start := time.Now()
+		resp := &ecs.DescribeRouteTablesResponse{}
+		err := e.clientSet.vpc.Invoke("DescribeRouteTables", &describeRouteTablesArgs{
+			RegionId:     e.region,
+			VRouterId:    vRouterID,
+			RouteTableId: routeTableID,
+			PageNumber:   page,
+			PageSize:     describeRouteTablePageSize,
+		}, resp)
+		err = observeOpenAPI("DescribeRouteTables", start, err)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error describe route tables of vrouter %q table %q", vRouterID, routeTableID)
+		}
+		tables = append(tables, resp.RouteTables.RouteTable...)
+		if len(resp.RouteTables.RouteTable) < describeRouteTablePageSize {
+			return tables, nil
+		}
+	}
+}
+
+// ListRouteEntries list custom entries in route table
+func (e *ecsImpl) ListRouteEntries(routeTableID string) ([]*RouteEntry, error) {
+	tables, err := e.describeRouteTables("", routeTableID)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*RouteEntry
+	for _, table := range tables {
+		for _, entry := range table.RouteEntrys.RouteEntry {
+			if entry.Type != ecs.RouteTableCustom {
+				continue
+			}
+			_, cidr, err := net.ParseCIDR(entry.DestinationCidrBlock)
+			if err != nil {
+				continue
+			}
+			routeEntry := &RouteEntry{
+				RouteTableID: table.RouteTableId,
+				CIDR:         cidr,
+				NextHopType:  entry.NextHopType,
+				NextHopID:    entry.NextHopId,
+				Status:       string(entry.Status),
+			}
+			if entry.NextHopType == RouteNextHopInstance {
+				routeEntry.InstanceID = entry.InstanceId
+				if routeEntry.InstanceID == "" {
+					routeEntry.InstanceID = entry.NextHopId
+				}
+			}
+			entries = append(entries, routeEntry)
+		}
+	}
+	return entries, nil
+}
+
+// CreateRouteEntry create entry of cidr to instance in route table, return after the entry available
+func (e *ecsImpl) CreateRouteEntry(routeTableID string, cidr *net.IPNet, instanceID string) error {
+	e.wait()
+	start := time.Now()
+	resp := common.Response{}
+	err := e.clientSet.vpc.Invoke("CreateRouteEntry", &routeEntryArgs{
+		RegionId:             e.region,
+		RouteTableId:         routeTableID,
+		DestinationCidrBlock: cidr.String(),
+		NextHopType:          RouteNextHopInstance,
+		NextHopId:            instanceID,
+	}, &resp)
+	err = observeMutation("CreateRouteEntry", routeTableID, fmt.Sprintf("cidr %s to instance %s", cidr, instanceID), resp.RequestId, start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error create route entry of %s to instance %s in %s", cidr, instanceID, routeTableID)
+	}
+	if err = e.waitRouteEntry(routeTableID, cidr, true); err != nil {
+		return errors.Wrapf(err, "error wait route entry of %s available", cidr)
+	}
+	return nil
+}
+
+// DeleteRouteEntry delete entry of cidr to instance from route table, return after the entry gone
+func (e *ecsImpl) DeleteRouteEntry(routeTableID string, cidr *net.IPNet, instanceID string) error {
+	e.wait()
+	start := time.Now()
+	resp := common.Response{}
+	err := e.clientSet.vpc.Invoke("DeleteRouteEntry", &routeEntryArgs{
+		RegionId:             e.region,
+		RouteTableId:         routeTableID,
+		DestinationCidrBlock: cidr.String(),
+		NextHopId:            instanceID,
+	}, &resp)
+	err = observeMutation("DeleteRouteEntry", routeTableID, fmt.Sprintf("cidr %s to instance %s", cidr, instanceID), resp.RequestId, start, err)
+	if err != nil {
+		return errors.Wrapf(err, "error delete route entry of %s to instance %s in %s", cidr, instanceID, routeTableID)
+	}
+	if err = e.waitRouteEntry(routeTableID, cidr, false); err != nil {
+		return errors.Wrapf(err, "error wait route entry of %s deleted", cidr)
+	}
+	return nil
+}
+
+// waitRouteEntry wait entry of cidr available or gone, the route table is not writable while any entry pending
+func (e *ecsImpl) waitRouteEntry(routeTableID string, cidr *net.IPNet, available bool) error {
+	return wait.ExponentialBackoff(
+		wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0,
+			Steps:    5,
+		},
+		func() (done bool, err error) {
+			entries, err := e.ListRouteEntries(routeTableID)
+			if err != nil {
+				return false, err
+			}
+			for _, entry := range entries {
+				if entry.CIDR.String() == cidr.String() {
+					return available && entry.Status == string(ecs.RouteEntryStatusAvailable), nil
+				}
+			}
+			return !available, nil
+		},
+	)
+}
@@ -0,0 +1,102 @@
+package aliyun
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/pkg/errors"
+)
+
+const (
+	tagResourceENI = ecs.TagResourceType("eni")
+	// maxTagsPerCall tags added or removed by one openapi call
+	maxTagsPerCall = 5
+	// maxTagsPerResource tags of one resource, all listed in one page
+	maxTagsPerResource = 20
+)
+
+// sortedKeys keys of tags in order
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// chunkTags split tags into chunks of maxTagsPerCall in order of keys
+func chunkTags(tags map[string]string) []map[string]string {
+	var chunks []map[string]string
+	for i, k := range sortedKeys(tags) {
+		if i%maxTagsPerCall == 0 {
+			chunks = append(chunks, make(map[string]string, maxTagsPerCall))
+		}
+		chunks[len(chunks)-1][k] = tags[k]
+	}
+	return chunks
+}
+
+// TagENI add tags to eni, value of existing keys overwritten
+func (e *ecsImpl) TagENI(eniID string, tags map[string]string) error {
+	for _, chunk := range chunkTags(tags) {
+		e.wait()
+		start := time.Now()
+		resp := common.Response{}
+		err := e.clientSet.ecs.Invoke("AddTags", &ecs.AddTagsArgs{
+			ResourceId:   eniID,
+			ResourceType: tagResourceENI,
+			RegionId:     e.region,
+			Tag:          chunk,
+		}, &resp)
+		err = observeMutation("AddTags", eniID, "tags "+strings.Join(sortedKeys(chunk), ","), resp.RequestId, start, err)
+		if err != nil {
+			return errors.Wrapf(err, "error add tags to eni %s", eniID)
+		}
+	}
+	return nil
+}
+
+// UntagENI remove tags from eni
+func (e *ecsImpl) UntagENI(eniID string, tags map[string]string) error {
+	for _, chunk := range chunkTags(tags) {
+		e.wait()
+		start := time.Now()
+		resp := common.Response{}
+		err := e.clientSet.ecs.Invoke("RemoveTags", &ecs.RemoveTagsArgs{
+			ResourceId:   eniID,
+			ResourceType: tagResourceENI,
+			RegionId:     e.region,
+			Tag:          chunk,
+		}, &resp)
+		err = observeMutation("RemoveTags", eniID, "tags "+strings.Join(sortedKeys(chunk), ","), resp.RequestId, start, err)
+		if err != nil {
+			return errors.Wrapf(err, "error remove tags from eni %s", eniID)
+		}
+	}
+	return nil
+}
+
+// GetENITags return tags of eni
+func (e *ecsImpl) GetENITags(eniID string) (map[string]string, error) {
+	e.wait()
+	start := time.Now()
+	items, _, err := e.clientSet.ecs.DescribeTags(&ecs.DescribeTagsArgs{
+		RegionId:     e.region,
+		ResourceType: tagResourceENI,
+		ResourceId:   eniID,
+		Pagination:   common.Pagination{PageNumber: 1, PageSize: maxTagsPerResource},
+	})
+	err = observeOpenAPI("DescribeTags", start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error describe tags of eni %s", eniID)
+	}
+	tags := make(map[string]string, len(items))
+	for _, item := range items {
+		tags[item.TagKey] = item.TagValue
+	}
+	return tags, nil
+}
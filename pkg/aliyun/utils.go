@@ -1,10 +1,9 @@
 package aliyun
 
-import (
-	"encoding/hex"
-	"math/rand"
-	"time"
-)
+import "strings"
+
+// TagKeyClusterID tag of enis created by terway with id of cluster
+const TagKeyClusterID = "terway.cluster-id"
 
 const (
 	eniNamePrefix    = "eni-cni-"
@@ -12,16 +11,21 @@ const (
 	eniCreateTimeout = 30
 	eniBindTimeout   = 60
 
+	describeENIPageSize = 100
+
 	eniStatusInUse     = "InUse"
 	eniStatusAvailable = "Available"
 )
 
-func generateEniName() string {
-	b := make([]byte, 3)
-	rand.Seed(time.Now().UnixNano())
-	_, err := rand.Read(b)
-	if err != nil {
-		panic(err)
+// eniName name of enis created for instance, enis of instance listed by name on daemon reinstall
+func eniName(instanceID string) string {
+	return eniNamePrefix + instanceID
+}
+
+// ownerOfENI instance the eni created for by name of eni, empty for enis created before named by instance
+func ownerOfENI(name string) string {
+	if !strings.HasPrefix(name, eniNamePrefix+"i-") {
+		return ""
 	}
-	return eniNamePrefix + hex.EncodeToString(b)
+	return strings.TrimPrefix(name, eniNamePrefix)
 }
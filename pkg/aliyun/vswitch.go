@@ -0,0 +1,108 @@
+package aliyun
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	"github.com/denverdino/aliyungo/ecs"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	describeVSwitchPageSize = 50
+	vSwitchDescription      = "vswitch create by terway"
+)
+
+// VSwitch vswitch of vpc, view from openapi
+type VSwitch struct {
+	ID           string
+	Name         string
+	CIDR         *net.IPNet
+	Zone         string
+	AvailableIPs int
+	// Status of vswitch, eg: "Pending" or "Available"
+	Status string
+}
+
+// ListVSwitches list vswitches of vpc, all zones if zone empty
+func (e *ecsImpl) ListVSwitches(vpcID, zone string) ([]*VSwitch, error) {
+	var vSwitches []*VSwitch
+	args := &ecs.DescribeVSwitchesArgs{
+		RegionId:   e.region,
+		VpcId:      vpcID,
+		ZoneId:     zone,
+		Pagination: common.Pagination{PageSize: describeVSwitchPageSize},
+	}
+	for page := 1; ; page++ {
+		e.wait()
+		start := time.Now()
+		args.PageNumber = page
+		resp, _, err := e.clientSet.vpc.DescribeVSwitches(args)
+		err = observeOpenAPI("DescribeVSwitches", start, err)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error describe vswitches of vpc %s", vpcID)
+		}
+		for _, vsw := range resp {
+			_, cidr, err := net.ParseCIDR(vsw.CidrBlock)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid cidr of vswitch %s", vsw.VSwitchId)
+			}
+			vSwitches = append(vSwitches, &VSwitch{
+				ID:           vsw.VSwitchId,
+				Name:         vsw.VSwitchName,
+				CIDR:         cidr,
+				Zone:         vsw.ZoneId,
+				AvailableIPs: vsw.AvailableIpAddressCount,
+				Status:       string(vsw.Status),
+			})
+		}
+		if len(resp) < describeVSwitchPageSize {
+			return vSwitches, nil
+		}
+	}
+}
+
+// CreateVSwitch create vswitch of cidr in zone of vpc, return after the vswitch available
+func (e *ecsImpl) CreateVSwitch(vpcID, zone string, cidr *net.IPNet, name string) (string, error) {
+	e.wait()
+	start := time.Now()
+	vSwitch, err := e.clientSet.vpc.CreateVSwitch(&ecs.CreateVSwitchArgs{
+		ZoneId:      zone,
+		CidrBlock:   cidr.String(),
+		VpcId:       vpcID,
+		VSwitchName: name,
+		Description: vSwitchDescription,
+	})
+	err = observeMutation("CreateVSwitch", vSwitch, fmt.Sprintf("cidr %s in zone %s", cidr, zone), "", start, err)
+	if err != nil {
+		return "", errors.Wrapf(err, "error create vswitch of %s in zone %s", cidr, zone)
+	}
+	err = wait.ExponentialBackoff(
+		wait.Backoff{
+			Duration: time.Second,
+			Factor:   2,
+			Jitter:   0,
+			Steps:    5,
+		},
+		func() (done bool, err error) {
+			e.wait()
+			start := time.Now()
+			vSwitches, _, err := e.clientSet.vpc.DescribeVSwitches(&ecs.DescribeVSwitchesArgs{
+				RegionId:  e.region,
+				VSwitchId: vSwitch,
+			})
+			err = observeOpenAPI("DescribeVSwitches", start, err)
+			if err != nil {
+				return false, err
+			}
+			return len(vSwitches) == 1 && vSwitches[0].Status == ecs.VSwitchStatusAvailable, nil
+		},
+	)
+	if err != nil {
+		return vSwitch, errors.Wrapf(err, "error wait vswitch %s available", vSwitch)
+	}
+	return vSwitch, nil
+}
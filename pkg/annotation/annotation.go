@@ -0,0 +1,100 @@
+// Package annotation terway annotations of pods, namespaces and labels of nodes, parsed by daemon and validated
+// by webhook on admission
+package annotation
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// keys of annotations
+const (
+	ENI              = "k8s.aliyun.com/ENI"
+	IngressBandwidth = "k8s.aliyun.com/ingress-bandwidth"
+	EgressBandwidth  = "k8s.aliyun.com/egress-bandwidth"
+	LinkLocalAccess  = "k8s.aliyun.com/link-local-access"
+	// VSwitch candidate vswitches of pod ip separated by comma
+	VSwitch = "k8s.aliyun.com/vswitch"
+	// PodRoutes extra routes installed in pod netns, json list of dst and optional gateway
+	PodRoutes = "k8s.aliyun.com/pod-routes"
+	// PodSysctls sysctls set in pod netns, json object of sysctl to value
+	PodSysctls = "k8s.aliyun.com/pod-sysctls"
+	// InterfaceTuning queues, ring sizes and offloads of interface of pod with exclusive eni, json of interface tuning
+	InterfaceTuning = "k8s.aliyun.com/interface-tuning"
+	// ENISpread "true" to spread ips of pods of the same owner across enis for bandwidth isolation
+	ENISpread = "k8s.aliyun.com/eni-spread"
+	// PodEIP "true" to associate eip allocated by terway to ip of pod, or allocation id of eip allocated by user
+	PodEIP = "k8s.aliyun.com/pod-eip"
+	// PodEIPBandwidth bandwidth in Mbps of eip allocated by terway
+	PodEIPBandwidth = "k8s.aliyun.com/pod-eip-bandwidth"
+	// PodEIPRetain duration to keep eip allocated by terway after pod deleted, eg: pods of statefulset recreated
+	PodEIPRetain = "k8s.aliyun.com/pod-eip-retain"
+	// TrafficMirror mirror traffic of pod veth to collector interface or vxlan remote, json of target and direction
+	TrafficMirror = "k8s.aliyun.com/traffic-mirror"
+	// NetworkMode network mode of pod in hybrid and dual daemon mode
+	NetworkMode = "k8s.aliyun.com/network-mode"
+)
+
+// values of network mode
+const (
+	NetworkModeShared    = "shared"
+	NetworkModeExclusive = "exclusive"
+	// NetworkModeVPC vpc ip of node cidr in dual daemon mode
+	NetworkModeVPC = "vpc"
+)
+
+// ValidatePod static validation of terway annotations on pod, the vswitch and security group should be
+// validated against cloud resources by caller
+func ValidatePod(annotations map[string]string) error {
+	var errs []error
+	for _, key := range []string{IngressBandwidth, EgressBandwidth} {
+		if value, ok := annotations[key]; ok {
+			if _, err := ParseBandwidth(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s %q: %v", key, value, err))
+			}
+		}
+	}
+	if value, ok := annotations[LinkLocalAccess]; ok {
+		switch value {
+		case types.LinkLocalAccessAllow, types.LinkLocalAccessBlock, types.LinkLocalAccessMasquerade:
+		default:
+			errs = append(errs, fmt.Errorf("invalid %s %q", LinkLocalAccess, value))
+		}
+	}
+	if value, ok := annotations[PodRoutes]; ok {
+		if _, err := ParseRoutes(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", PodRoutes, value, err))
+		}
+	}
+	if value, ok := annotations[PodSysctls]; ok {
+		if _, err := ParseSysctls(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", PodSysctls, value, err))
+		}
+	}
+	if value, ok := annotations[InterfaceTuning]; ok {
+		if _, err := ParseInterfaceTuning(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", InterfaceTuning, value, err))
+		}
+	}
+	if value, ok := annotations[ENISpread]; ok && value != "true" && value != "false" {
+		errs = append(errs, fmt.Errorf("invalid %s %q, must be true or false", ENISpread, value))
+	}
+	if value, ok := annotations[PodEIP]; ok {
+		if _, err := ParseEIP(value, annotations[PodEIPBandwidth], annotations[PodEIPRetain]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %v", PodEIP, err))
+		}
+	}
+	if value, ok := annotations[TrafficMirror]; ok {
+		if _, err := ParseMirror(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: %v", TrafficMirror, value, err))
+		}
+	}
+	if value, ok := annotations[NetworkMode]; ok && value != NetworkModeShared && value != NetworkModeExclusive &&
+		value != NetworkModeVPC {
+		errs = append(errs, fmt.Errorf("invalid %s %q, must be %s, %s or %s", NetworkMode, value,
+			NetworkModeShared, NetworkModeExclusive, NetworkModeVPC))
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,50 @@
+package annotation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// bandwidth limit unit
+const (
+	BYTE = 1 << (10 * iota)
+	KILOBYTE
+	MEGABYTE
+	GIGABYTE
+	TERABYTE
+)
+
+// ParseBandwidth parse bandwidth of ingress and egress bandwidth annotations in bytes, eg: 10M
+func ParseBandwidth(s string) (uint64, error) {
+
+	s = strings.TrimSpace(s)
+	s = strings.ToUpper(s)
+
+	i := strings.IndexFunc(s, unicode.IsLetter)
+	if i < 0 {
+		i = len(s)
+	}
+
+	bytesString, multiple := s[:i], s[i:]
+	bytes, err := strconv.ParseFloat(bytesString, 64)
+	if err != nil || bytes <= 0 {
+		return 0, fmt.Errorf("invalid bandwidth %s", s)
+	}
+
+	switch multiple {
+	case "T", "TB", "TIB":
+		return uint64(bytes * TERABYTE), nil
+	case "G", "GB", "GIB":
+		return uint64(bytes * GIGABYTE), nil
+	case "M", "MB", "MIB":
+		return uint64(bytes * MEGABYTE), nil
+	case "K", "KB", "KIB":
+		return uint64(bytes * KILOBYTE), nil
+	case "B", "":
+		return uint64(bytes), nil
+	default:
+		return 0, fmt.Errorf("invalid bandwidth %s", s)
+	}
+}
@@ -0,0 +1,57 @@
+package annotation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// eipAllocate value of pod eip annotation to allocate eip by terway
+	eipAllocate = "true"
+	// eipIDPrefix prefix of allocation id of eip
+	eipIDPrefix = "eip-"
+	// DefaultEIPBandwidth bandwidth in Mbps of eip allocated by terway
+	DefaultEIPBandwidth = 5
+)
+
+// EIP eip of pod by annotations
+type EIP struct {
+	// ID allocation id of eip allocated by user, empty to allocate by terway
+	ID string
+	// Bandwidth in Mbps of eip allocated by terway
+	Bandwidth int
+	// Retain keep eip allocated by terway after pod deleted, associated again to pod of same name recreated in time
+	Retain time.Duration
+}
+
+// ParseEIP parse eip of pod from values of pod eip, bandwidth and retain annotations, nil if no eip
+func ParseEIP(value, bandwidth, retain string) (*EIP, error) {
+	if value == "" || value == "false" {
+		return nil, nil
+	}
+	eip := &EIP{Bandwidth: DefaultEIPBandwidth}
+	switch {
+	case value == eipAllocate:
+	case strings.HasPrefix(value, eipIDPrefix):
+		eip.ID = value
+	default:
+		return nil, fmt.Errorf("invalid eip %q, must be true, false or allocation id of eip", value)
+	}
+	if bandwidth != "" {
+		mbps, err := strconv.Atoi(bandwidth)
+		if err != nil || mbps <= 0 {
+			return nil, fmt.Errorf("invalid eip bandwidth %q, must be positive Mbps", bandwidth)
+		}
+		eip.Bandwidth = mbps
+	}
+	if retain != "" {
+		duration, err := time.ParseDuration(retain)
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("invalid eip retain %q, must be duration, eg: 10m", retain)
+		}
+		eip.Retain = duration
+	}
+	return eip, nil
+}
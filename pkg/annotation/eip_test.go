@@ -0,0 +1,27 @@
+package annotation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEIP(t *testing.T) {
+	eip, err := ParseEIP("true", "", "10m")
+	assert.NoError(t, err)
+	assert.Equal(t, &EIP{Bandwidth: DefaultEIPBandwidth, Retain: 10 * time.Minute}, eip)
+	eip, err = ParseEIP("eip-user", "20", "")
+	assert.NoError(t, err)
+	assert.Equal(t, &EIP{ID: "eip-user", Bandwidth: 20}, eip)
+	eip, err = ParseEIP("false", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, eip)
+
+	_, err = ParseEIP("1.2.3.4", "", "")
+	assert.Error(t, err)
+	_, err = ParseEIP("true", "0", "")
+	assert.Error(t, err)
+	_, err = ParseEIP("true", "", "forever")
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package annotation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// MirrorLinkPrefix prefix of vxlan links created for mirror targets, suffixed by hash of target
+	MirrorLinkPrefix = "tmirror"
+	DefaultVXLANPort = 4789
+	maxVXLANVNI      = 1<<24 - 1
+
+	// directions of pod traffic mirrored
+	MirrorDirectionBoth    = "both"
+	MirrorDirectionEgress  = "egress"
+	MirrorDirectionIngress = "ingress"
+)
+
+// Mirror target and direction of mirroring traffic of pod, a host interface or vxlan remote
+type Mirror struct {
+	// Interface host interface of collector
+	Interface string `json:"interface,omitempty"`
+	// VXLANRemote address of collector receiving the traffic in vxlan
+	VXLANRemote string `json:"vxlan_remote,omitempty"`
+	VXLANVNI    int    `json:"vxlan_vni,omitempty"`
+	VXLANPort   int    `json:"vxlan_port,omitempty"`
+	// Direction egress from pod, ingress to pod, or both by default
+	Direction string `json:"direction,omitempty"`
+}
+
+// ParseMirror parse and validate mirror in json of traffic mirror annotation,
+// eg: {"interface": "ids0"}, {"vxlan_remote": "10.0.0.5", "vxlan_vni": 100, "direction": "egress"}
+func ParseMirror(value string) (*Mirror, error) {
+	mirror := &Mirror{}
+	if err := json.Unmarshal([]byte(value), mirror); err != nil {
+		return nil, fmt.Errorf("error parse traffic mirror: %v", err)
+	}
+	switch {
+	case mirror.Interface != "" && mirror.VXLANRemote != "":
+		return nil, fmt.Errorf("only one of interface and vxlan_remote of traffic mirror allowed")
+	case mirror.Interface != "":
+		if strings.HasPrefix(mirror.Interface, MirrorLinkPrefix) {
+			return nil, fmt.Errorf("interface %s of traffic mirror reserved by terway", mirror.Interface)
+		}
+	case mirror.VXLANRemote != "":
+		if ip := net.ParseIP(mirror.VXLANRemote); ip == nil || ip.To4() == nil {
+			return nil, fmt.Errorf("invalid ipv4 vxlan_remote %q of traffic mirror", mirror.VXLANRemote)
+		}
+		if mirror.VXLANVNI <= 0 || mirror.VXLANVNI > maxVXLANVNI {
+			return nil, fmt.Errorf("vxlan_vni %d of traffic mirror out of range [1, %d]", mirror.VXLANVNI, maxVXLANVNI)
+		}
+		if mirror.VXLANPort == 0 {
+			mirror.VXLANPort = DefaultVXLANPort
+		}
+		if mirror.VXLANPort < 0 || mirror.VXLANPort > 65535 {
+			return nil, fmt.Errorf("invalid vxlan_port %d of traffic mirror", mirror.VXLANPort)
+		}
+	default:
+		return nil, fmt.Errorf("interface or vxlan_remote of traffic mirror required")
+	}
+	switch mirror.Direction {
+	case "":
+		mirror.Direction = MirrorDirectionBoth
+	case MirrorDirectionBoth, MirrorDirectionEgress, MirrorDirectionIngress:
+	default:
+		return nil, fmt.Errorf("invalid direction %q of traffic mirror, must be %s, %s or %s", mirror.Direction,
+			MirrorDirectionBoth, MirrorDirectionEgress, MirrorDirectionIngress)
+	}
+	return mirror, nil
+}
+
+// LinkName return name of host link the traffic mirrored to
+func (m *Mirror) LinkName() string {
+	if m.Interface != "" {
+		return m.Interface
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d:%d", m.VXLANRemote, m.VXLANPort, m.VXLANVNI)))
+	return MirrorLinkPrefix + hex.EncodeToString(hash[:])[:8]
+}
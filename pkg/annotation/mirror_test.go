@@ -0,0 +1,41 @@
+package annotation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMirror(t *testing.T) {
+	mirror, err := ParseMirror(`{"interface": "ids0"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, &Mirror{Interface: "ids0", Direction: MirrorDirectionBoth}, mirror)
+	assert.Equal(t, "ids0", mirror.LinkName())
+
+	mirror, err = ParseMirror(`{"vxlan_remote": "10.0.0.5", "vxlan_vni": 100, "direction": "egress"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, &Mirror{VXLANRemote: "10.0.0.5", VXLANVNI: 100, VXLANPort: DefaultVXLANPort, Direction: MirrorDirectionEgress}, mirror)
+	name := mirror.LinkName()
+	assert.True(t, strings.HasPrefix(name, MirrorLinkPrefix))
+	assert.True(t, len(name) <= 15)
+
+	other, err := ParseMirror(`{"vxlan_remote": "10.0.0.5", "vxlan_vni": 101}`)
+	assert.NoError(t, err)
+	assert.NotEqual(t, name, other.LinkName())
+
+	for _, value := range []string{
+		`"ids0"`,
+		`{}`,
+		`{"interface": "ids0", "vxlan_remote": "10.0.0.5", "vxlan_vni": 100}`,
+		`{"interface": "tmirror0"}`,
+		`{"vxlan_remote": "fd00::1", "vxlan_vni": 100}`,
+		`{"vxlan_remote": "10.0.0.5"}`,
+		`{"vxlan_remote": "10.0.0.5", "vxlan_vni": 16777216}`,
+		`{"vxlan_remote": "10.0.0.5", "vxlan_vni": 100, "vxlan_port": 70000}`,
+		`{"interface": "ids0", "direction": "all"}`,
+	} {
+		_, err = ParseMirror(value)
+		assert.Error(t, err, value)
+	}
+}
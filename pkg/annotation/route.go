@@ -0,0 +1,39 @@
+package annotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+)
+
+// Route extra route requested by pod, eg: to on-prem cidr over a specific next hop
+type Route struct {
+	// Dst destination cidr of route
+	Dst string `json:"dst"`
+	// Gateway next hop of route, the gateway of pod default route if empty
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// ParseRoutes parse and validate routes in json of pod routes annotation,
+// eg: [{"dst": "10.0.0.0/8", "gateway": "192.168.0.253"}]
+func ParseRoutes(value string) ([]Route, error) {
+	var routes []Route
+	if err := json.Unmarshal([]byte(value), &routes); err != nil {
+		return nil, fmt.Errorf("error parse routes: %v", err)
+	}
+	for _, route := range routes {
+		ip, dst, err := net.ParseCIDR(route.Dst)
+		if err != nil || ip.To4() == nil {
+			return nil, fmt.Errorf("invalid ipv4 cidr of route dst: %q", route.Dst)
+		}
+		if ones, _ := dst.Mask.Size(); ones == 0 {
+			return nil, fmt.Errorf("default route %s managed by terway", route.Dst)
+		}
+		if route.Gateway != "" {
+			if gw := net.ParseIP(route.Gateway); gw == nil || gw.To4() == nil {
+				return nil, fmt.Errorf("invalid ipv4 gateway of route %s: %q", route.Dst, route.Gateway)
+			}
+		}
+	}
+	return routes, nil
+}
@@ -0,0 +1,24 @@
+package annotation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes(`[{"dst": "10.0.0.0/8", "gateway": "192.168.0.253"}, {"dst": "172.16.1.0/24"}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []Route{{Dst: "10.0.0.0/8", Gateway: "192.168.0.253"}, {Dst: "172.16.1.0/24"}}, routes)
+
+	for _, value := range []string{
+		`{"dst": "10.0.0.0/8"}`,
+		`[{"dst": "10.0.0.1"}]`,
+		`[{"dst": "0.0.0.0/0"}]`,
+		`[{"dst": "fd00::/64"}]`,
+		`[{"dst": "10.0.0.0/8", "gateway": "abc"}]`,
+	} {
+		_, err = ParseRoutes(value)
+		assert.Error(t, err, value)
+	}
+}
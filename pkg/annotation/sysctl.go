@@ -0,0 +1,62 @@
+package annotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// sysctlRange sysctl allowed in pod netns and the range of its value
+type sysctlRange struct {
+	key      *regexp.Regexp
+	min, max int
+}
+
+// sysctlRanges sysctls pods can set in netns, others are rejected as they may affect the node or other pods
+var sysctlRanges = []sysctlRange{
+	{key: regexp.MustCompile(`^net\.ipv4\.conf\.(all|default|eth0)\.rp_filter$`), min: 0, max: 2},
+	{key: regexp.MustCompile(`^net\.ipv4\.conf\.(all|default|eth0)\.arp_notify$`), min: 0, max: 1},
+	{key: regexp.MustCompile(`^net\.ipv4\.tcp_keepalive_(time|intvl|probes)$`), min: 1, max: 1 << 15},
+	{key: regexp.MustCompile(`^net\.core\.somaxconn$`), min: 1, max: 1 << 16},
+}
+
+// ValidateSysctls check the sysctls are allowed in pod netns and the values in range
+func ValidateSysctls(sysctls map[string]string) error {
+	keys := make([]string, 0, len(sysctls))
+	for key := range sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var allowed *sysctlRange
+		for i := range sysctlRanges {
+			if sysctlRanges[i].key.MatchString(key) {
+				allowed = &sysctlRanges[i]
+				break
+			}
+		}
+		if allowed == nil {
+			return fmt.Errorf("sysctl %s not allowed in pod netns", key)
+		}
+		value, err := strconv.Atoi(sysctls[key])
+		if err != nil || value < allowed.min || value > allowed.max {
+			return fmt.Errorf("invalid value %q of sysctl %s, must be in [%d, %d]", sysctls[key], key, allowed.min, allowed.max)
+		}
+	}
+	return nil
+}
+
+// ParseSysctls parse and validate sysctls in json of pod sysctls annotation,
+// eg: {"net.core.somaxconn": "4096", "net.ipv4.tcp_keepalive_time": "600"}
+func ParseSysctls(value string) (map[string]string, error) {
+	var sysctls map[string]string
+	if err := json.Unmarshal([]byte(value), &sysctls); err != nil {
+		return nil, fmt.Errorf("error parse sysctls: %v", err)
+	}
+	if err := ValidateSysctls(sysctls); err != nil {
+		return nil, err
+	}
+	return sysctls, nil
+}
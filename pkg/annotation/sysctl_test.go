@@ -0,0 +1,29 @@
+package annotation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSysctls(t *testing.T) {
+	sysctls, err := ParseSysctls(`{"net.core.somaxconn": "4096", "net.ipv4.conf.eth0.rp_filter": "0", "net.ipv4.tcp_keepalive_time": "600"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"net.core.somaxconn":           "4096",
+		"net.ipv4.conf.eth0.rp_filter": "0",
+		"net.ipv4.tcp_keepalive_time":  "600",
+	}, sysctls)
+
+	for _, value := range []string{
+		`["net.core.somaxconn"]`,
+		`{"net.ipv4.ip_forward": "1"}`,
+		`{"net.ipv4.conf.eth1.arp_notify": "1"}`,
+		`{"net.ipv4.conf.all.rp_filter": "3"}`,
+		`{"net.ipv4.tcp_keepalive_probes": "0"}`,
+		`{"net.core.somaxconn": "abc"}`,
+	} {
+		_, err = ParseSysctls(value)
+		assert.Error(t, err, value)
+	}
+}
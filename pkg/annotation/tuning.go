@@ -0,0 +1,40 @@
+package annotation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// limits of interface tuning, the queues and rings supported by driver of eni checked by cni binary on setup
+const (
+	maxTuningQueues = 64
+	maxTuningRing   = 1 << 16
+)
+
+// ValidateInterfaceTuning check queues and ring sizes of interface tuning in range
+func ValidateInterfaceTuning(tuning *types.InterfaceTuning) error {
+	if tuning.CombinedQueues < 0 || tuning.CombinedQueues > maxTuningQueues {
+		return fmt.Errorf("combined_queues %d out of range [0, %d]", tuning.CombinedQueues, maxTuningQueues)
+	}
+	for name, ring := range map[string]int{"rx_ring": tuning.RxRing, "tx_ring": tuning.TxRing} {
+		if ring < 0 || ring > maxTuningRing {
+			return fmt.Errorf("%s %d out of range [0, %d]", name, ring, maxTuningRing)
+		}
+	}
+	return nil
+}
+
+// ParseInterfaceTuning parse and validate interface tuning in json of interface tuning annotation,
+// eg: {"combined_queues": 4, "rx_ring": 1024, "gro": true, "lro": false}
+func ParseInterfaceTuning(value string) (*types.InterfaceTuning, error) {
+	tuning := &types.InterfaceTuning{}
+	if err := json.Unmarshal([]byte(value), tuning); err != nil {
+		return nil, fmt.Errorf("error parse interface tuning: %v", err)
+	}
+	if err := ValidateInterfaceTuning(tuning); err != nil {
+		return nil, err
+	}
+	return tuning, nil
+}
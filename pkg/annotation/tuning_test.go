@@ -0,0 +1,22 @@
+package annotation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInterfaceTuning(t *testing.T) {
+	tuning, err := ParseInterfaceTuning(`{"combined_queues": 4, "rx_ring": 1024, "gro": true, "lro": false}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, tuning.CombinedQueues)
+	assert.Equal(t, 1024, tuning.RxRing)
+	assert.Nil(t, tuning.TSO)
+
+	_, err = ParseInterfaceTuning(`{"combined_queues": 128}`)
+	assert.Error(t, err)
+	_, err = ParseInterfaceTuning(`{"tx_ring": -1}`)
+	assert.Error(t, err)
+	_, err = ParseInterfaceTuning(`invalid`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+//+build linux
+
+package arp
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	etherTypeARP   = 0x0806
+	etherTypeIPv4  = 0x0800
+	arpHardwareEth = 1
+	arpOpRequest   = 1
+	arpOpReply     = 2
+
+	ethHeaderLen = 14
+	arpPacketLen = 28
+)
+
+func htons(v uint16) uint16 {
+	return (v << 8) | (v >> 8)
+}
+
+// Probe send arp probe (RFC 5227) for ip on link and return the mac addresses replied in timeout
+func Probe(ifName string, ip net.IP, timeout time.Duration) ([]net.HardwareAddr, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, errors.Errorf("arp probe only support ipv4: %s", ip)
+	}
+	iface, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error get interface %s", ifName)
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(etherTypeARP)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error create arp socket")
+	}
+	defer syscall.Close(fd)
+
+	addr := &syscall.SockaddrLinklayer{
+		Protocol: htons(etherTypeARP),
+		Ifindex:  iface.Index,
+		Halen:    6,
+	}
+	if err = syscall.Bind(fd, addr); err != nil {
+		return nil, errors.Wrapf(err, "error bind arp socket on %s", ifName)
+	}
+	tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+	if err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return nil, errors.Wrapf(err, "error set arp socket timeout")
+	}
+
+	// ethernet header + arp request with sender ip 0.0.0.0
+	frame := make([]byte, ethHeaderLen+arpPacketLen)
+	copy(frame[0:6], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	copy(frame[6:12], iface.HardwareAddr)
+	binary.BigEndian.PutUint16(frame[12:], etherTypeARP)
+	packet := frame[ethHeaderLen:]
+	binary.BigEndian.PutUint16(packet[0:], arpHardwareEth)
+	binary.BigEndian.PutUint16(packet[2:], etherTypeIPv4)
+	packet[4] = 6
+	packet[5] = 4
+	binary.BigEndian.PutUint16(packet[6:], arpOpRequest)
+	copy(packet[8:14], iface.HardwareAddr)
+	copy(packet[24:28], ip4)
+
+	copy(addr.Addr[:], frame[0:6])
+	if err = syscall.Sendto(fd, frame, 0, addr); err != nil {
+		return nil, errors.Wrapf(err, "error send arp probe for %s on %s", ip, ifName)
+	}
+
+	var macs []net.HardwareAddr
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 1500)
+	for time.Now().Before(deadline) {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				break
+			}
+			return nil, errors.Wrapf(err, "error receive arp reply on %s", ifName)
+		}
+		if n < ethHeaderLen+arpPacketLen {
+			continue
+		}
+		reply := buf[ethHeaderLen:n]
+		if binary.BigEndian.Uint16(reply[6:]) != arpOpReply || !net.IP(reply[14:18]).Equal(ip4) {
+			continue
+		}
+		mac := make(net.HardwareAddr, 6)
+		copy(mac, reply[8:14])
+		macs = append(macs, mac)
+	}
+	return macs, nil
+}
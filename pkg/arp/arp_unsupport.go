@@ -0,0 +1,15 @@
+//+build !linux
+
+package arp
+
+import (
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Probe send arp probe for ip on link and return the mac addresses replied in timeout
+func Probe(ifName string, ip net.IP, timeout time.Duration) ([]net.HardwareAddr, error) {
+	return nil, errors.Errorf("not supported arch")
+}
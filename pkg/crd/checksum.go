@@ -0,0 +1,41 @@
+package crd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Checksum return checksum of resource ids in any order
+func Checksum(resources []string) string {
+	sorted := append([]string{}, resources...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Diff return description of the difference between daemon and cloud resources
+func Diff(daemon, cloud []string) string {
+	cloudSet := make(map[string]bool)
+	for _, res := range cloud {
+		cloudSet[res] = true
+	}
+	daemonSet := make(map[string]bool)
+	var onlyDaemon, onlyCloud []string
+	for _, res := range daemon {
+		daemonSet[res] = true
+		if !cloudSet[res] {
+			onlyDaemon = append(onlyDaemon, res)
+		}
+	}
+	for _, res := range cloud {
+		if !daemonSet[res] {
+			onlyCloud = append(onlyCloud, res)
+		}
+	}
+	sort.Strings(onlyDaemon)
+	sort.Strings(onlyCloud)
+	return fmt.Sprintf("only in daemon: %v, only in cloud: %v", onlyDaemon, onlyCloud)
+}
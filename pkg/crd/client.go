@@ -0,0 +1,87 @@
+package crd
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+)
+
+// Client operation set of NodeNetworkState
+type Client interface {
+	Get(name string) (*NodeNetworkState, error)
+	List() ([]NodeNetworkState, error)
+	Create(state *NodeNetworkState) (*NodeNetworkState, error)
+	Update(state *NodeNetworkState) (*NodeNetworkState, error)
+}
+
+type restClient struct {
+	client rest.Interface
+}
+
+// NewClient return NodeNetworkState client on rest client, eg: clientset.Discovery().RESTClient()
+func NewClient(client rest.Interface) Client {
+	return &restClient{client: client}
+}
+
+func (c *restClient) Get(name string) (*NodeNetworkState, error) {
+	data, err := c.client.Get().AbsPath(nodeNetworkStateAPIPath, name).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	state := &NodeNetworkState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal node network state %s", name)
+	}
+	return state, nil
+}
+
+func (c *restClient) List() ([]NodeNetworkState, error) {
+	data, err := c.client.Get().AbsPath(nodeNetworkStateAPIPath).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	list := &NodeNetworkStateList{}
+	if err = json.Unmarshal(data, list); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal node network state list")
+	}
+	return list.Items, nil
+}
+
+func (c *restClient) Create(state *NodeNetworkState) (*NodeNetworkState, error) {
+	state.APIVersion = GroupName + "/" + Version
+	state.Kind = NodeNetworkStateKind
+	body, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.Post().AbsPath(nodeNetworkStateAPIPath).
+		SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	created := &NodeNetworkState{}
+	if err = json.Unmarshal(data, created); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal node network state %s", state.Name)
+	}
+	return created, nil
+}
+
+func (c *restClient) Update(state *NodeNetworkState) (*NodeNetworkState, error) {
+	state.APIVersion = GroupName + "/" + Version
+	state.Kind = NodeNetworkStateKind
+	body, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.Put().AbsPath(nodeNetworkStateAPIPath, state.Name).
+		SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	updated := &NodeNetworkState{}
+	if err = json.Unmarshal(data, updated); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal node network state %s", state.Name)
+	}
+	return updated, nil
+}
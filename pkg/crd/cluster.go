@@ -0,0 +1,128 @@
+package crd
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// custom resource of ip usage of cluster aggregated from node network states by controller
+const (
+	ClusterNetworkStateKind    = "ClusterNetworkState"
+	ClusterNetworkStatePlural  = "clusternetworkstates"
+	clusterNetworkStateAPIPath = "/apis/" + GroupName + "/" + Version + "/" + ClusterNetworkStatePlural
+	// ClusterNetworkStateName name of the only cluster network state
+	ClusterNetworkStateName = "cluster"
+)
+
+// ClusterNetworkState ip usage of cluster by vswitch and nodepool, for capacity planning without scraping nodes
+type ClusterNetworkState struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ClusterNetworkStateStatus `json:"status,omitempty"`
+}
+
+// ClusterNetworkStateStatus ip usage aggregated by controller
+type ClusterNetworkStateStatus struct {
+	// Nodes count of nodes with network state
+	Nodes int `json:"nodes"`
+	// Pods count of pods allocated network resources
+	Pods int `json:"pods"`
+	// VSwitches ip usage of vswitches enis of nodes in
+	VSwitches []VSwitchUsage `json:"vswitches,omitempty"`
+	// NodePools ip usage of pools of nodes by nodepool
+	NodePools []NodePoolUsage `json:"nodepools,omitempty"`
+	// UpdateTime time of last aggregation
+	UpdateTime metav1.Time `json:"updateTime,omitempty"`
+}
+
+// VSwitchUsage ips of vswitch used by enis of nodes and available
+type VSwitchUsage struct {
+	ID   string `json:"id"`
+	Zone string `json:"zone,omitempty"`
+	CIDR string `json:"cidr,omitempty"`
+	// ENIs count of enis of nodes in vswitch
+	ENIs int `json:"enis"`
+	// UsedIPs ips of enis of nodes in vswitch, including primary ips
+	UsedIPs int `json:"usedIPs"`
+	// AvailableIPs ips of vswitch free to allocate, by cloud
+	AvailableIPs int `json:"availableIPs"`
+}
+
+// NodePoolUsage stats of resource pools of nodes in nodepool
+type NodePoolUsage struct {
+	Name  string `json:"name"`
+	Nodes int    `json:"nodes"`
+	Pods  int    `json:"pods"`
+	// Pools sum of stats of resource pools of nodes by resource type
+	Pools map[string]PoolStats `json:"pools,omitempty"`
+}
+
+// ClusterClient operation set of ClusterNetworkState
+type ClusterClient interface {
+	Get(name string) (*ClusterNetworkState, error)
+	Create(state *ClusterNetworkState) (*ClusterNetworkState, error)
+	Update(state *ClusterNetworkState) (*ClusterNetworkState, error)
+}
+
+type clusterRESTClient struct {
+	client rest.Interface
+}
+
+// NewClusterClient return ClusterNetworkState client on rest client, eg: clientset.Discovery().RESTClient()
+func NewClusterClient(client rest.Interface) ClusterClient {
+	return &clusterRESTClient{client: client}
+}
+
+func (c *clusterRESTClient) Get(name string) (*ClusterNetworkState, error) {
+	data, err := c.client.Get().AbsPath(clusterNetworkStateAPIPath, name).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	state := &ClusterNetworkState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal cluster network state %s", name)
+	}
+	return state, nil
+}
+
+func (c *clusterRESTClient) Create(state *ClusterNetworkState) (*ClusterNetworkState, error) {
+	state.APIVersion = GroupName + "/" + Version
+	state.Kind = ClusterNetworkStateKind
+	body, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.Post().AbsPath(clusterNetworkStateAPIPath).
+		SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	created := &ClusterNetworkState{}
+	if err = json.Unmarshal(data, created); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal cluster network state %s", state.Name)
+	}
+	return created, nil
+}
+
+func (c *clusterRESTClient) Update(state *ClusterNetworkState) (*ClusterNetworkState, error) {
+	state.APIVersion = GroupName + "/" + Version
+	state.Kind = ClusterNetworkStateKind
+	body, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.Put().AbsPath(clusterNetworkStateAPIPath, state.Name).
+		SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	updated := &ClusterNetworkState{}
+	if err = json.Unmarshal(data, updated); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal cluster network state %s", state.Name)
+	}
+	return updated, nil
+}
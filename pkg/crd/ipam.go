@@ -0,0 +1,110 @@
+package crd
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// custom resource of pod ips allocated on node in VPC mode
+const (
+	NodeIPAMKind    = "NodeIPAM"
+	NodeIPAMPlural  = "nodeipams"
+	nodeIPAMAPIPath = "/apis/" + GroupName + "/" + Version + "/" + NodeIPAMPlural
+)
+
+// NodeIPAM ips of node cidr allocated to pod sandboxes in VPC mode, named by node name
+type NodeIPAM struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeIPAMSpec   `json:"spec"`
+	Status NodeIPAMStatus `json:"status,omitempty"`
+}
+
+// NodeIPAMSpec the node cidr ips allocated from
+type NodeIPAMSpec struct {
+	CIDR string `json:"cidr"`
+}
+
+// NodeIPAMStatus the ips allocated by daemon
+type NodeIPAMStatus struct {
+	// Allocations sandboxes ips allocated to by ip
+	Allocations map[string]IPAllocation `json:"allocations,omitempty"`
+	// LastReservedIPs last ip allocated by range, allocation continues after it
+	LastReservedIPs map[string]string `json:"lastReservedIPs,omitempty"`
+}
+
+// IPAllocation sandbox ip allocated to
+type IPAllocation struct {
+	SandboxID   string      `json:"sandboxID"`
+	AllocatedAt metav1.Time `json:"allocatedAt"`
+}
+
+// IPAMClient operation set of NodeIPAM
+type IPAMClient interface {
+	Get(name string) (*NodeIPAM, error)
+	Create(ipam *NodeIPAM) (*NodeIPAM, error)
+	Update(ipam *NodeIPAM) (*NodeIPAM, error)
+}
+
+type ipamRESTClient struct {
+	client rest.Interface
+}
+
+// NewIPAMClient return NodeIPAM client on rest client, eg: clientset.Discovery().RESTClient()
+func NewIPAMClient(client rest.Interface) IPAMClient {
+	return &ipamRESTClient{client: client}
+}
+
+func (c *ipamRESTClient) Get(name string) (*NodeIPAM, error) {
+	data, err := c.client.Get().AbsPath(nodeIPAMAPIPath, name).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	ipam := &NodeIPAM{}
+	if err = json.Unmarshal(data, ipam); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal node ipam %s", name)
+	}
+	return ipam, nil
+}
+
+func (c *ipamRESTClient) Create(ipam *NodeIPAM) (*NodeIPAM, error) {
+	ipam.APIVersion = GroupName + "/" + Version
+	ipam.Kind = NodeIPAMKind
+	body, err := json.Marshal(ipam)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.Post().AbsPath(nodeIPAMAPIPath).
+		SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	created := &NodeIPAM{}
+	if err = json.Unmarshal(data, created); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal node ipam %s", ipam.Name)
+	}
+	return created, nil
+}
+
+func (c *ipamRESTClient) Update(ipam *NodeIPAM) (*NodeIPAM, error) {
+	ipam.APIVersion = GroupName + "/" + Version
+	ipam.Kind = NodeIPAMKind
+	body, err := json.Marshal(ipam)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.Put().AbsPath(nodeIPAMAPIPath, ipam.Name).
+		SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	updated := &NodeIPAM{}
+	if err = json.Unmarshal(data, updated); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal node ipam %s", ipam.Name)
+	}
+	return updated, nil
+}
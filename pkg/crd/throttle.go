@@ -0,0 +1,102 @@
+package crd
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// custom resource of openapi throttling shared by daemons of cluster
+const (
+	OpenAPIThrottleKind    = "OpenAPIThrottle"
+	OpenAPIThrottlePlural  = "openapithrottles"
+	openAPIThrottleAPIPath = "/apis/" + GroupName + "/" + Version + "/" + OpenAPIThrottlePlural
+)
+
+// OpenAPIThrottle throttling of openapi seen by daemons in region, named by region
+type OpenAPIThrottle struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status OpenAPIThrottleStatus `json:"status,omitempty"`
+}
+
+// OpenAPIThrottleStatus the slow down level of background refill of pools, raised and lowered by daemons
+type OpenAPIThrottleStatus struct {
+	// Level refill intervals of pools stretched and batches shrunk by 2^Level, 0 for no throttling
+	Level int `json:"level"`
+	// ThrottledAt the time daemon last reported throttled calls
+	ThrottledAt metav1.Time `json:"throttledAt,omitempty"`
+	// Reporter node of daemon last reported throttled calls
+	Reporter string `json:"reporter,omitempty"`
+	// LevelChangedAt the time Level last raised or lowered
+	LevelChangedAt metav1.Time `json:"levelChangedAt,omitempty"`
+}
+
+// ThrottleClient operation set of OpenAPIThrottle
+type ThrottleClient interface {
+	Get(name string) (*OpenAPIThrottle, error)
+	Create(throttle *OpenAPIThrottle) (*OpenAPIThrottle, error)
+	Update(throttle *OpenAPIThrottle) (*OpenAPIThrottle, error)
+}
+
+type throttleRESTClient struct {
+	client rest.Interface
+}
+
+// NewThrottleClient return OpenAPIThrottle client on rest client, eg: clientset.Discovery().RESTClient()
+func NewThrottleClient(client rest.Interface) ThrottleClient {
+	return &throttleRESTClient{client: client}
+}
+
+func (c *throttleRESTClient) Get(name string) (*OpenAPIThrottle, error) {
+	data, err := c.client.Get().AbsPath(openAPIThrottleAPIPath, name).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	throttle := &OpenAPIThrottle{}
+	if err = json.Unmarshal(data, throttle); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal openapi throttle %s", name)
+	}
+	return throttle, nil
+}
+
+func (c *throttleRESTClient) Create(throttle *OpenAPIThrottle) (*OpenAPIThrottle, error) {
+	throttle.APIVersion = GroupName + "/" + Version
+	throttle.Kind = OpenAPIThrottleKind
+	body, err := json.Marshal(throttle)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.Post().AbsPath(openAPIThrottleAPIPath).
+		SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	created := &OpenAPIThrottle{}
+	if err = json.Unmarshal(data, created); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal openapi throttle %s", throttle.Name)
+	}
+	return created, nil
+}
+
+func (c *throttleRESTClient) Update(throttle *OpenAPIThrottle) (*OpenAPIThrottle, error) {
+	throttle.APIVersion = GroupName + "/" + Version
+	throttle.Kind = OpenAPIThrottleKind
+	body, err := json.Marshal(throttle)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.client.Put().AbsPath(openAPIThrottleAPIPath, throttle.Name).
+		SetHeader("Content-Type", "application/json").Body(body).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	updated := &OpenAPIThrottle{}
+	if err = json.Unmarshal(data, updated); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshal openapi throttle %s", throttle.Name)
+	}
+	return updated, nil
+}
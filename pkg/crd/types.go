@@ -0,0 +1,143 @@
+package crd
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// custom resource definition of terway
+const (
+	GroupName               = "terway.aliyun.com"
+	Version                 = "v1"
+	NodeNetworkStateKind    = "NodeNetworkState"
+	NodeNetworkStatePlural  = "nodenetworkstates"
+	nodeNetworkStateAPIPath = "/apis/" + GroupName + "/" + Version + "/" + NodeNetworkStatePlural
+)
+
+// NodeNetworkState network state of terway on node, named by node name
+type NodeNetworkState struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeNetworkStateSpec   `json:"spec"`
+	Status NodeNetworkStateStatus `json:"status,omitempty"`
+}
+
+// NodeNetworkStateSpec the node info of network state
+type NodeNetworkStateSpec struct {
+	InstanceID string `json:"instanceID"`
+	DaemonMode string `json:"daemonMode"`
+}
+
+// NodeNetworkStateStatus the allocation state published by daemon and the check result of controller
+type NodeNetworkStateStatus struct {
+	// Resources ids of cloud resources in daemon pool, published by daemon
+	Resources []string `json:"resources,omitempty"`
+	// Checksum of Resources, published by daemon
+	Checksum string `json:"checksum,omitempty"`
+	// StateVersion increased by daemon when Resources changed
+	StateVersion int64 `json:"stateVersion,omitempty"`
+	// UpdateTime the time daemon Resources last changed
+	UpdateTime metav1.Time `json:"updateTime,omitempty"`
+
+	// CloudChecksum checksum of resources view from cloud, set by controller
+	CloudChecksum string `json:"cloudChecksum,omitempty"`
+	// Diverged whether daemon state diverged with cloud, set by controller
+	Diverged bool `json:"diverged,omitempty"`
+	// DivergedReason the difference between daemon and cloud
+	DivergedReason string `json:"divergedReason,omitempty"`
+	// CheckTime the time controller last checked
+	CheckTime metav1.Time `json:"checkTime,omitempty"`
+
+	// Node facts of node for upgrade preflight, published by daemon
+	Node *NodeFacts `json:"node,omitempty"`
+
+	// Summary network state of node for cluster admins, published by daemon periodically
+	Summary *NodeNetworkSummary `json:"summary,omitempty"`
+}
+
+// NodeNetworkSummary summary of enis, pools, pods and errors of daemon on node
+type NodeNetworkSummary struct {
+	// ENIs enis attached to node managed by daemon
+	ENIs []ENIState `json:"enis,omitempty"`
+	// Pools stats of resource pools by resource type
+	Pools map[string]PoolStats `json:"pools,omitempty"`
+	// PodCount count of pods allocated network resources
+	PodCount int `json:"podCount"`
+	// Pods namespace/name of pods allocated network resources
+	Pods []string `json:"pods,omitempty"`
+	// RecentErrors recent errors of allocations and releases, oldest first
+	RecentErrors []ErrorRecord `json:"recentErrors,omitempty"`
+	// DeniedIPs ips denied to allocate on node, kept in quarantine of pools
+	DeniedIPs []DeniedIP `json:"deniedIPs,omitempty"`
+}
+
+// ENIState eni attached to node
+type ENIState struct {
+	MAC string `json:"mac"`
+	// IPs count of ips of eni in pool, 0 for eni exclusive by pod
+	IPs int `json:"ips,omitempty"`
+}
+
+// PoolStats stats of resource pool
+type PoolStats struct {
+	Capacity   int `json:"capacity"`
+	Inuse      int `json:"inuse"`
+	Idle       int `json:"idle"`
+	Quarantine int `json:"quarantine,omitempty"`
+	// Recommendation min and max idle recommended by acquires of pool, nil until acquires recorded long enough
+	Recommendation *PoolRecommendation `json:"recommendation,omitempty"`
+}
+
+// PoolRecommendation min and max idle of pool recommended by acquires of pool in window
+type PoolRecommendation struct {
+	// MinIdle idle resources serving acquires in a minute at 95th percentile
+	MinIdle int `json:"minIdle"`
+	// MaxIdle idle resources serving the max burst of acquires in 10 minutes
+	MaxIdle int `json:"maxIdle"`
+	// CurrentMinIdle and CurrentMaxIdle min_pool_size and max_pool_size configured
+	CurrentMinIdle int `json:"currentMinIdle"`
+	CurrentMaxIdle int `json:"currentMaxIdle"`
+	// Acquires count of acquires in window
+	Acquires int `json:"acquires"`
+	// HitRatePercent acquires served by idle resources in percent
+	HitRatePercent int `json:"hitRatePercent"`
+	// AvgWaitMs average wait of acquires for resources in ms
+	AvgWaitMs int64 `json:"avgWaitMs"`
+	// WindowMinutes minutes of acquires recorded the recommendation based on
+	WindowMinutes int `json:"windowMinutes"`
+}
+
+// ErrorRecord error of operation on pod
+type ErrorRecord struct {
+	Operation string      `json:"operation"`
+	Pod       string      `json:"pod"`
+	Error     string      `json:"error"`
+	Time      metav1.Time `json:"time"`
+}
+
+// DeniedIP ip denied to allocate and the reason
+type DeniedIP struct {
+	IP       string      `json:"ip"`
+	Reason   string      `json:"reason,omitempty"`
+	DeniedAt metav1.Time `json:"deniedAt"`
+}
+
+// NodeFacts the facts of node which upgrade depends on
+type NodeFacts struct {
+	// DBSchemaVersion schema version of resource relation db
+	DBSchemaVersion int `json:"dbSchemaVersion"`
+	// Datapath the datapath active on node
+	Datapath string `json:"datapath,omitempty"`
+	// CNIConfFiles cni config files on node in the order of kubelet loading
+	CNIConfFiles []string `json:"cniConfFiles,omitempty"`
+	// FeatureGates feature gates enabled on node
+	FeatureGates []string `json:"featureGates,omitempty"`
+}
+
+// NodeNetworkStateList list of NodeNetworkState
+type NodeNetworkStateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []NodeNetworkState `json:"items"`
+}
@@ -0,0 +1,249 @@
+package defaults
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/pkg/feature"
+	"github.com/AliyunContainerService/terway/pkg/kernel"
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// daemon modes of terway
+const (
+	ModeVPC        = "VPC"
+	ModeENIMultiIP = "ENIMultiIP"
+	ModeENIOnly    = "ENIOnly"
+	// ModeDual eni multi ip, with pods of vpc ip in node cidr
+	ModeDual = "Dual"
+	// ModeHybrid eni multi ip, with pods of exclusive eni
+	ModeHybrid = "Hybrid"
+)
+
+// virtual types of eni multi ip datapath
+const (
+	VirtualTypeVeth   = "Veth"
+	VirtualTypeIPVlan = "IPVlan"
+)
+
+// vswitch selection policies for allocate eni
+const (
+	VSwitchSelectionOrdered  = "ordered"
+	VSwitchSelectionMostFree = "most_free"
+)
+
+const (
+	defaultCNIVersion   = "0.3.0"
+	defaultCNIName      = "terway"
+	defaultMaxPoolSize  = 5
+	defaultMinPoolSize  = 0
+	defaultOpenAPIQPS   = 10
+	defaultOpenAPIBurst = 20
+	defaultGCTimeout    = "1m"
+
+	defaultGCProtectionWindow = "1m"
+
+	defaultSlowAllocationThreshold = "5s"
+	defaultAllocationBudget        = "100s"
+	defaultPoolDrainRate           = 10
+
+	defaultVSwitchMaskSize  = 24
+	defaultMaxAutoVSwitches = 2
+)
+
+// minimal kernel version of ipvlan datapath
+const (
+	IPVlanKernelMajor = 4
+	IPVlanKernelMinor = 19
+)
+
+// InstanceType the network limits of ecs instance type
+type InstanceType struct {
+	ID          string
+	ENIQuantity int
+	IPv4PerENI  int
+}
+
+// ClusterSpec the spec of cluster to generate terway config for
+type ClusterSpec struct {
+	Mode          string
+	InstanceTypes []InstanceType
+	// VSwitches zone to vswitches for pod
+	VSwitches     map[string][]string
+	SecurityGroup string
+	ServiceCIDR   string
+	// KernelVersion the oldest kernel release of nodes, eg: "4.19.24-7.al7.x86_64"
+	KernelVersion string
+}
+
+// CNIConfig the cni network config of terway
+type CNIConfig struct {
+	CNIVersion        string `json:"cniVersion"`
+	Name              string `json:"name"`
+	Type              string `json:"type"`
+	ENIIPVirtualType  string `json:"eniip_virtual_type,omitempty"`
+	ConnectivityCheck bool   `json:"connectivity_check,omitempty"`
+}
+
+// Recommendation the generated config of terway daemon and cni
+type Recommendation struct {
+	Config    *types.Configure
+	CNIConfig *CNIConfig
+}
+
+// SetDefault setup default value of daemon config
+func SetDefault(cfg *types.Configure) error {
+	if cfg.EniCapRatio == 0 {
+		cfg.EniCapRatio = 1
+	}
+
+	if cfg.HotPlug == "" {
+		cfg.HotPlug = "true"
+	}
+
+	if cfg.HotPlug == "false" || cfg.HotPlug == "0" {
+		cfg.HotPlug = "false"
+	}
+
+	if cfg.VSwitchSelectionPolicy == "" {
+		cfg.VSwitchSelectionPolicy = VSwitchSelectionOrdered
+	}
+
+	if cfg.LinkLocalAccess == "" {
+		cfg.LinkLocalAccess = types.LinkLocalAccessAllow
+	}
+
+	if cfg.OpenAPIQPS == 0 {
+		cfg.OpenAPIQPS = defaultOpenAPIQPS
+	}
+
+	if cfg.OpenAPIBurst == 0 {
+		cfg.OpenAPIBurst = defaultOpenAPIBurst
+	}
+
+	if cfg.GCTimeout == "" {
+		cfg.GCTimeout = defaultGCTimeout
+	}
+
+	if cfg.GCProtectionWindow == "" {
+		cfg.GCProtectionWindow = defaultGCProtectionWindow
+	}
+
+	if cfg.SlowAllocationThreshold == "" {
+		cfg.SlowAllocationThreshold = defaultSlowAllocationThreshold
+	}
+
+	if cfg.AllocationBudget == "" {
+		cfg.AllocationBudget = defaultAllocationBudget
+	}
+
+	if cfg.PoolDrainRate == 0 {
+		cfg.PoolDrainRate = defaultPoolDrainRate
+	}
+
+	if cfg.VSwitchAutoCreate != nil {
+		if cfg.VSwitchAutoCreate.MaskSize == 0 {
+			cfg.VSwitchAutoCreate.MaskSize = defaultVSwitchMaskSize
+		}
+		if cfg.VSwitchAutoCreate.MaxVSwitches == 0 {
+			cfg.VSwitchAutoCreate.MaxVSwitches = defaultMaxAutoVSwitches
+		}
+	}
+
+	return nil
+}
+
+// sharedENIMode whether pods share eni secondary ips in mode
+func sharedENIMode(mode string) bool {
+	return mode == ModeENIMultiIP || mode == ModeHybrid || mode == ModeDual
+}
+
+// instanceCapacity the max pod resources of instance type in mode, pods of exclusive eni in hybrid mode
+// and of vpc ip in dual mode not counted
+func instanceCapacity(mode string, instanceType InstanceType) int {
+	if sharedENIMode(mode) {
+		return (instanceType.ENIQuantity - 1) * instanceType.IPv4PerENI
+	}
+	return instanceType.ENIQuantity - 1
+}
+
+// Recommend generate recommended terway config for cluster spec
+func Recommend(spec *ClusterSpec) (*Recommendation, error) {
+	switch spec.Mode {
+	case ModeVPC, ModeENIMultiIP, ModeENIOnly, ModeHybrid, ModeDual:
+	default:
+		return nil, fmt.Errorf("unsupport daemon mode: %s", spec.Mode)
+	}
+	if len(spec.InstanceTypes) == 0 {
+		return nil, fmt.Errorf("no instance type in cluster spec")
+	}
+	if spec.Mode != ModeVPC && len(spec.VSwitches) == 0 {
+		return nil, fmt.Errorf("vswitches required for mode %s", spec.Mode)
+	}
+
+	// pool size limited by the smallest instance type
+	capacity := -1
+	ipPerENI := -1
+	for _, instanceType := range spec.InstanceTypes {
+		c := instanceCapacity(spec.Mode, instanceType)
+		if c <= 0 {
+			return nil, fmt.Errorf("instance type %s has no capacity for mode %s", instanceType.ID, spec.Mode)
+		}
+		if capacity < 0 || c < capacity {
+			capacity = c
+		}
+		if ipPerENI < 0 || instanceType.IPv4PerENI < ipPerENI {
+			ipPerENI = instanceType.IPv4PerENI
+		}
+	}
+
+	cfg := &types.Configure{
+		Version:       "1",
+		ServiceCIDR:   spec.ServiceCIDR,
+		SecurityGroup: spec.SecurityGroup,
+		VSwitches:     spec.VSwitches,
+		MaxPoolSize:   defaultMaxPoolSize,
+		MinPoolSize:   defaultMinPoolSize,
+		FeatureGates:  feature.Recommended(spec.Mode),
+	}
+	// keep an ENI's ips warm on modes of shared eni
+	if sharedENIMode(spec.Mode) && ipPerENI > cfg.MaxPoolSize {
+		cfg.MaxPoolSize = ipPerENI
+	}
+	if cfg.MaxPoolSize > capacity {
+		cfg.MaxPoolSize = capacity
+	}
+	// only balance by free ips when there are alternates in zone
+	cfg.VSwitchSelectionPolicy = VSwitchSelectionOrdered
+	for _, vSwitches := range spec.VSwitches {
+		if len(vSwitches) > 1 {
+			cfg.VSwitchSelectionPolicy = VSwitchSelectionMostFree
+		}
+	}
+	if err := SetDefault(cfg); err != nil {
+		return nil, err
+	}
+
+	cniConfig := &CNIConfig{
+		CNIVersion: defaultCNIVersion,
+		Name:       defaultCNIName,
+		Type:       defaultCNIName,
+	}
+	if sharedENIMode(spec.Mode) {
+		cniConfig.ENIIPVirtualType = VirtualTypeVeth
+		// pods of vpc ip in dual mode routed through host by veth only
+		if spec.Mode != ModeDual && spec.KernelVersion != "" {
+			ver, err := kernel.ParseVersion(spec.KernelVersion)
+			if err != nil {
+				return nil, err
+			}
+			if !ver.LessThan(kernel.Version{Major: IPVlanKernelMajor, Minor: IPVlanKernelMinor}) {
+				cniConfig.ENIIPVirtualType = VirtualTypeIPVlan
+			}
+		}
+	}
+
+	return &Recommendation{
+		Config:    cfg,
+		CNIConfig: cniConfig,
+	}, nil
+}
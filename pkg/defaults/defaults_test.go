@@ -0,0 +1,72 @@
+package defaults
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/pkg/feature"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendENIMultiIP(t *testing.T) {
+	rec, err := Recommend(&ClusterSpec{
+		Mode: ModeENIMultiIP,
+		InstanceTypes: []InstanceType{
+			{ID: "ecs.g5.large", ENIQuantity: 2, IPv4PerENI: 6},
+			{ID: "ecs.g5.xlarge", ENIQuantity: 3, IPv4PerENI: 10},
+		},
+		VSwitches: map[string][]string{
+			"cn-hangzhou-g": {"vsw-a", "vsw-b"},
+		},
+		KernelVersion: "4.19.24-7.al7.x86_64",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, rec.Config.MaxPoolSize)
+	assert.Equal(t, 0, rec.Config.MinPoolSize)
+	assert.Equal(t, VSwitchSelectionMostFree, rec.Config.VSwitchSelectionPolicy)
+	assert.Equal(t, VirtualTypeIPVlan, rec.CNIConfig.ENIIPVirtualType)
+	assert.Equal(t, "true", rec.Config.HotPlug)
+}
+
+func TestRecommendENIOnly(t *testing.T) {
+	rec, err := Recommend(&ClusterSpec{
+		Mode:          ModeENIOnly,
+		InstanceTypes: []InstanceType{{ID: "ecs.g5.large", ENIQuantity: 2, IPv4PerENI: 6}},
+		VSwitches:     map[string][]string{"cn-hangzhou-g": {"vsw-a"}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rec.Config.MaxPoolSize)
+	assert.Equal(t, VSwitchSelectionOrdered, rec.Config.VSwitchSelectionPolicy)
+	assert.Equal(t, "", rec.CNIConfig.ENIIPVirtualType)
+
+	_, err = Recommend(&ClusterSpec{
+		Mode:          ModeENIOnly,
+		InstanceTypes: []InstanceType{{ID: "ecs.t5.small", ENIQuantity: 1}},
+		VSwitches:     map[string][]string{"cn-hangzhou-g": {"vsw-a"}},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestRecommendHybridAndDual(t *testing.T) {
+	spec := &ClusterSpec{
+		Mode:          ModeHybrid,
+		InstanceTypes: []InstanceType{{ID: "ecs.g5.large", ENIQuantity: 2, IPv4PerENI: 6}},
+		VSwitches:     map[string][]string{"cn-hangzhou-g": {"vsw-a"}},
+		KernelVersion: "4.19.24-7.al7.x86_64",
+	}
+	rec, err := Recommend(spec)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, rec.Config.MaxPoolSize)
+	assert.Equal(t, VirtualTypeIPVlan, rec.CNIConfig.ENIIPVirtualType)
+	// gates of hybrid mode, alpha ones disabled
+	assert.Equal(t, map[string]bool{feature.Trunking: false, feature.EBPFDatapath: false}, rec.Config.FeatureGates)
+
+	spec.Mode = ModeDual
+	rec, err = Recommend(spec)
+	assert.Nil(t, err)
+	assert.Equal(t, VirtualTypeVeth, rec.CNIConfig.ENIIPVirtualType)
+	assert.Equal(t, map[string]bool{feature.EBPFDatapath: false}, rec.Config.FeatureGates)
+
+	spec.Mode = "Unknown"
+	_, err = Recommend(spec)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,131 @@
+// Package errcode stable error codes of terway in cni error results, mapped to grpc status of daemon rpc
+package errcode
+
+import (
+	"fmt"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code stable error code of terway in cni error result, plugin specific codes start from 100 by cni spec
+type Code uint
+
+// error codes of terway, never renumber
+const (
+	Unknown           Code = 100
+	QuotaExceeded     Code = 101
+	VSwitchExhausted  Code = 102
+	CredentialInvalid Code = 103
+	DaemonUnreachable Code = 104
+	Timeout           Code = 105
+	Overloaded        Code = 106
+)
+
+type codeInfo struct {
+	name      string
+	retryable bool
+	grpcCode  codes.Code
+}
+
+var codeInfos = map[Code]codeInfo{
+	Unknown:           {"Unknown", true, codes.Unknown},
+	QuotaExceeded:     {"QuotaExceeded", false, codes.ResourceExhausted},
+	VSwitchExhausted:  {"VSwitchExhausted", false, codes.ResourceExhausted},
+	CredentialInvalid: {"CredentialInvalid", false, codes.PermissionDenied},
+	DaemonUnreachable: {"DaemonUnreachable", true, codes.Unavailable},
+	Timeout:           {"Timeout", true, codes.DeadlineExceeded},
+	Overloaded:        {"Overloaded", true, codes.ResourceExhausted},
+}
+
+func (c Code) info() codeInfo {
+	if info, ok := codeInfos[c]; ok {
+		return info
+	}
+	return codeInfos[Unknown]
+}
+
+func (c Code) String() string {
+	return c.info().name
+}
+
+// Retryable return whether failure of code may succeed on retry without operator action
+func (c Code) Retryable() bool {
+	return c.info().retryable
+}
+
+// GRPCCode grpc status code of code
+func (c Code) GRPCCode() codes.Code {
+	return c.info().grpcCode
+}
+
+// Parse code by name, Unknown if name not known
+func Parse(name string) Code {
+	for c, info := range codeInfos {
+		if info.name == name {
+			return c
+		}
+	}
+	return Unknown
+}
+
+// Status grpc status error of err with code in details
+func Status(code Code, err error) error {
+	s := status.New(code.GRPCCode(), err.Error())
+	if detailed, detailErr := s.WithDetails(&rpc.ErrorDetail{Code: code.String()}); detailErr == nil {
+		s = detailed
+	}
+	return s.Err()
+}
+
+// FromError code of error returned by rpc of daemon, from details of status or the grpc status code
+func FromError(err error) Code {
+	s, ok := status.FromError(errors.Cause(err))
+	if !ok {
+		return Unknown
+	}
+	for _, detail := range s.Details() {
+		if d, ok := detail.(*rpc.ErrorDetail); ok {
+			return Parse(d.Code)
+		}
+	}
+	switch s.Code() {
+	case codes.Unavailable:
+		return DaemonUnreachable
+	case codes.DeadlineExceeded, codes.Canceled:
+		return Timeout
+	}
+	return Unknown
+}
+
+// Is report whether err or any error it wraps matches target like errors.Is, following Cause as well, as
+// errors wrapped by the vendored pkg/errors can not be unwrapped
+func Is(err, target error) bool {
+	for err != nil {
+		if err == target {
+			return true
+		}
+		if matcher, ok := err.(interface{ Is(error) bool }); ok && matcher.Is(target) {
+			return true
+		}
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapper.Unwrap()
+		case interface{ Cause() error }:
+			err = wrapper.Cause()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// Describe code and whether retryable in human readable form
+func Describe(code Code) string {
+	if code.Retryable() {
+		return fmt.Sprintf("terway error %s, retryable", code)
+	}
+	return fmt.Sprintf("terway error %s, not retryable", code)
+}
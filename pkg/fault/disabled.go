@@ -0,0 +1,6 @@
+//+build !faultinjection
+
+package fault
+
+// Enabled faults injected in this build
+const Enabled = false
@@ -0,0 +1,160 @@
+// Package fault injects failures into operations of resource factories for chaos testing,
+// only effective in builds with tag faultinjection
+package fault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// operations of resource factories faults injected into
+const (
+	OpCreate  = "create"
+	OpDispose = "dispose"
+	// OpAttach attach eni to instance, both by eni and eniIp factory
+	OpAttach = "attach"
+)
+
+// Point return injection point of operation on resource type, eg: "eniIp/create"
+func Point(resType, op string) string {
+	return resType + "/" + op
+}
+
+// Fault failure injected at point
+type Fault struct {
+	// Point where the fault injected, eg: "eni/attach"
+	Point string `json:"point"`
+	// Code aliyun error code the operation fails with, eg: "InvalidVSwitchId.IpNotEnough", proceed after hang if empty
+	Code string `json:"code,omitempty"`
+	// Hang duration the operation blocked before fail or proceed, eg: "30s"
+	Hang string `json:"hang,omitempty"`
+	// Count times the fault triggered before removed, unlimited if 0
+	Count int `json:"count,omitempty"`
+	// Triggered times the fault triggered
+	Triggered int `json:"triggered"`
+
+	hang time.Duration
+}
+
+var registry = struct {
+	sync.Mutex
+	faults map[string]*Fault
+}{faults: make(map[string]*Fault)}
+
+// Set inject fault at its point, replace the fault exist
+func Set(f Fault) error {
+	if f.Point == "" {
+		return fmt.Errorf("point of fault not set")
+	}
+	if f.Code == "" && f.Hang == "" {
+		return fmt.Errorf("fault at %s neither fails nor hangs", f.Point)
+	}
+	if f.Hang != "" {
+		hang, err := time.ParseDuration(f.Hang)
+		if err != nil || hang <= 0 {
+			return fmt.Errorf("invalid hang of fault: %s", f.Hang)
+		}
+		f.hang = hang
+	}
+	if f.Count < 0 {
+		return fmt.Errorf("invalid count of fault: %d", f.Count)
+	}
+	f.Triggered = 0
+	registry.Lock()
+	defer registry.Unlock()
+	registry.faults[f.Point] = &f
+	return nil
+}
+
+// Clear remove fault at point, all faults if point empty
+func Clear(point string) {
+	registry.Lock()
+	defer registry.Unlock()
+	if point == "" {
+		registry.faults = make(map[string]*Fault)
+		return
+	}
+	delete(registry.faults, point)
+}
+
+// List return faults injected in order of point
+func List() []Fault {
+	registry.Lock()
+	defer registry.Unlock()
+	faults := make([]Fault, 0, len(registry.faults))
+	for _, f := range registry.faults {
+		faults = append(faults, *f)
+	}
+	sort.Slice(faults, func(i, j int) bool {
+		return faults[i].Point < faults[j].Point
+	})
+	return faults
+}
+
+// Inject trigger fault at point, return the injected error, no-op unless built with tag faultinjection
+func Inject(point string) error {
+	if !Enabled {
+		return nil
+	}
+	return inject(point)
+}
+
+func inject(point string) error {
+	registry.Lock()
+	f, ok := registry.faults[point]
+	if !ok {
+		registry.Unlock()
+		return nil
+	}
+	f.Triggered++
+	if f.Count > 0 && f.Triggered >= f.Count {
+		delete(registry.faults, point)
+	}
+	code, hang := f.Code, f.hang
+	registry.Unlock()
+
+	log.Warnf("fault injected at %s, code: %q, hang: %v", point, code, hang)
+	time.Sleep(hang)
+	if code == "" {
+		return nil
+	}
+	return &common.Error{
+		ErrorResponse: common.ErrorResponse{Code: code, Message: fmt.Sprintf("fault injected at %s", point)},
+		StatusCode:    http.StatusBadRequest,
+	}
+}
+
+// Handler debug handler of faults: GET list faults, POST json of fault to inject, DELETE ?point= to clear
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			var f Fault
+			if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+				http.Error(w, fmt.Sprintf("invalid fault: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err := Set(f); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		case http.MethodDelete:
+			Clear(r.URL.Query().Get("point"))
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(List()); err != nil {
+			log.Errorf("error write faults: %v", err)
+		}
+	})
+}
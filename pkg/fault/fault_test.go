@@ -0,0 +1,51 @@
+package fault
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInject(t *testing.T) {
+	defer Clear("")
+	assert.NoError(t, inject("eni/attach"))
+
+	assert.Error(t, Set(Fault{Point: "eni/attach"}))
+	assert.NoError(t, Set(Fault{Point: "eni/attach", Code: aliyun.ErrInvalidVSwitchIDIPNotEnough, Count: 2}))
+	assert.True(t, aliyun.IsIPNotEnough(inject("eni/attach")))
+	assert.Equal(t, 1, List()[0].Triggered)
+	assert.Error(t, inject("eni/attach"))
+	// removed after count triggered
+	assert.NoError(t, inject("eni/attach"))
+	assert.Empty(t, List())
+
+	assert.NoError(t, Set(Fault{Point: "eniIp/dispose", Hang: "50ms"}))
+	start := time.Now()
+	assert.NoError(t, inject("eniIp/dispose"))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+}
+
+func TestHandler(t *testing.T) {
+	defer Clear("")
+	handler := Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/faults",
+		bytes.NewBufferString(`{"point": "eni/create", "code": "Throttling"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "eni/create")
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/debug/faults", bytes.NewBufferString(`{"point": "eni/create"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/debug/faults?point=eni/create", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, List())
+}
@@ -0,0 +1,177 @@
+// Package feature gates experimental capabilities of daemon by config, so behavior changes can be rolled out
+// gradually, eg: per nodepool by config profiles
+package feature
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// feature gates
+const (
+	// Trunking pods of exclusive eni on member enis of trunk eni, beyond the enis attachable to instance
+	Trunking = "Trunking"
+	// EBPFDatapath forward traffic of pods with eni ips by ebpf programs instead of routes and netfilter
+	EBPFDatapath = "EBPFDatapath"
+)
+
+// stages of feature gates
+const (
+	StageAlpha = "alpha"
+	StageBeta  = "beta"
+)
+
+// Spec default and daemon modes of feature gate
+type Spec struct {
+	Default bool
+	Stage   string
+	// Modes daemon modes the feature supported in, all if empty
+	Modes []string
+	// Implemented whether the capability gated landed, gates not implemented can not be enabled
+	Implemented bool
+}
+
+var specs = map[string]Spec{
+	Trunking:     {Stage: StageAlpha, Modes: []string{"ENIOnly", "Hybrid"}},
+	EBPFDatapath: {Stage: StageAlpha, Modes: []string{"ENIMultiIP", "Hybrid", "Dual"}},
+}
+
+// State of feature gate on node
+type State struct {
+	Name    string `json:"name"`
+	Stage   string `json:"stage"`
+	Default bool   `json:"default"`
+	Enabled bool   `json:"enabled"`
+	// Reason why gate configured on not enabled, eg: not supported in daemon mode
+	Reason string `json:"reason,omitempty"`
+}
+
+var gates = struct {
+	sync.RWMutex
+	states map[string]State
+}{states: defaultStates()}
+
+func defaultStates() map[string]State {
+	states := make(map[string]State, len(specs))
+	for name, spec := range specs {
+		states[name] = State{Name: name, Stage: spec.Stage, Default: spec.Default, Enabled: spec.Default}
+	}
+	return states
+}
+
+// Validate check gates configured are known, and implemented if enabled
+func Validate(configured map[string]bool) error {
+	var unknown, unimplemented []string
+	for name, enabled := range configured {
+		spec, ok := specs[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if enabled && !spec.Implemented {
+			unimplemented = append(unimplemented, name)
+		}
+	}
+	if len(unknown) != 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown feature gates: %s", strings.Join(unknown, ", "))
+	}
+	if len(unimplemented) != 0 {
+		sort.Strings(unimplemented)
+		return fmt.Errorf("feature gates not implemented yet: %s", strings.Join(unimplemented, ", "))
+	}
+	return nil
+}
+
+func supported(spec Spec, daemonMode string) bool {
+	if len(spec.Modes) == 0 {
+		return true
+	}
+	for _, mode := range spec.Modes {
+		if mode == daemonMode {
+			return true
+		}
+	}
+	return false
+}
+
+// Setup set gates of node by config in daemon mode, gates not supported in the mode kept disabled
+func Setup(configured map[string]bool, daemonMode string) error {
+	if err := Validate(configured); err != nil {
+		return err
+	}
+	states := defaultStates()
+	for name, enabled := range configured {
+		state := states[name]
+		state.Enabled = enabled
+		if enabled && !supported(specs[name], daemonMode) {
+			state.Enabled = false
+			state.Reason = fmt.Sprintf("not supported in %s mode", daemonMode)
+			log.Warnf("feature gate %s not supported in %s mode, disabled", name, daemonMode)
+		}
+		states[name] = state
+	}
+	gates.Lock()
+	gates.states = states
+	gates.Unlock()
+
+	var summary []string
+	for _, state := range List() {
+		summary = append(summary, fmt.Sprintf("%s=%v", state.Name, state.Enabled))
+	}
+	log.Infof("feature gates of %s mode: %s", daemonMode, strings.Join(summary, ","))
+	return nil
+}
+
+// Recommended recommended gates of daemon mode, gates supported in the mode enabled only if implemented and
+// past alpha
+func Recommended(daemonMode string) map[string]bool {
+	recommended := make(map[string]bool)
+	for name, spec := range specs {
+		if supported(spec, daemonMode) {
+			recommended[name] = spec.Implemented && spec.Stage != StageAlpha
+		}
+	}
+	return recommended
+}
+
+// Enabled return whether feature gate enabled on node
+func Enabled(name string) bool {
+	gates.RLock()
+	defer gates.RUnlock()
+	return gates.states[name].Enabled
+}
+
+// List return states of feature gates in order of name
+func List() []State {
+	gates.RLock()
+	defer gates.RUnlock()
+	states := make([]State, 0, len(gates.states))
+	for _, state := range gates.states {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+// Handler debug handler of feature gates: GET list states of gates
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(List()); err != nil {
+			log.Errorf("error write feature gates: %v", err)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package feature
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetup(t *testing.T) {
+	defer Setup(nil, "")
+	assert.False(t, Enabled(Trunking))
+
+	assert.Error(t, Setup(map[string]bool{"Unknown": true}, "ENIOnly"))
+	// not implemented yet
+	assert.Error(t, Setup(map[string]bool{Trunking: true}, "ENIOnly"))
+	assert.NoError(t, Setup(map[string]bool{Trunking: false}, "ENIOnly"))
+	assert.False(t, Enabled(Trunking))
+
+	saved := specs
+	defer func() { specs = saved }()
+	specs = map[string]Spec{
+		Trunking:     {Stage: StageAlpha, Modes: []string{"ENIOnly", "Hybrid"}, Implemented: true},
+		EBPFDatapath: {Stage: StageAlpha, Modes: []string{"ENIMultiIP", "Hybrid", "Dual"}, Implemented: true},
+	}
+	assert.NoError(t, Setup(map[string]bool{Trunking: true, EBPFDatapath: true}, "ENIOnly"))
+	assert.True(t, Enabled(Trunking))
+	// not supported in mode
+	assert.False(t, Enabled(EBPFDatapath))
+
+	states := List()
+	assert.Len(t, states, 2)
+	assert.Equal(t, EBPFDatapath, states[0].Name)
+	assert.NotEmpty(t, states[0].Reason)
+
+	assert.NoError(t, Setup(nil, "ENIMultiIP"))
+	assert.False(t, Enabled(Trunking))
+}
+
+func TestRecommended(t *testing.T) {
+	assert.Equal(t, map[string]bool{Trunking: false}, Recommended("ENIOnly"))
+	assert.Equal(t, map[string]bool{Trunking: false, EBPFDatapath: false}, Recommended("Hybrid"))
+	assert.Empty(t, Recommended("VPC"))
+
+	saved := specs
+	defer func() { specs = saved }()
+	specs = map[string]Spec{
+		Trunking:     {Stage: StageBeta, Modes: []string{"ENIOnly", "Hybrid"}, Implemented: true},
+		EBPFDatapath: {Stage: StageAlpha, Modes: []string{"ENIMultiIP", "Hybrid", "Dual"}, Implemented: true},
+	}
+	assert.Equal(t, map[string]bool{Trunking: true, EBPFDatapath: false}, Recommended("Hybrid"))
+}
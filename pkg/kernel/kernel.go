@@ -0,0 +1,25 @@
+//+build linux
+
+package kernel
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// GetReleaseVersion get kernel release version of this node, eg: "4.19.24-7.el7.x86_64"
+func GetReleaseVersion() (string, error) {
+	uname := &syscall.Utsname{}
+	if err := syscall.Uname(uname); err != nil {
+		return "", errors.Wrapf(err, "error get uname")
+	}
+	var buf []byte
+	for _, c := range uname.Release {
+		if c == 0 {
+			break
+		}
+		buf = append(buf, byte(c))
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,12 @@
+//+build !linux
+
+package kernel
+
+import (
+	"github.com/pkg/errors"
+)
+
+// GetReleaseVersion get kernel release version of this node
+func GetReleaseVersion() (string, error) {
+	return "", errors.Errorf("not supported arch")
+}
@@ -0,0 +1,67 @@
+package kernel
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version kernel major.minor.patch version
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parse version from kernel release, eg: "4.19.24-7.el7.x86_64"
+func ParseVersion(release string) (Version, error) {
+	var ver Version
+	release = strings.TrimSpace(release)
+	if i := strings.IndexAny(release, "-+_ "); i >= 0 {
+		release = release[:i]
+	}
+	fields := strings.Split(release, ".")
+	if len(fields) < 2 {
+		return ver, errors.Errorf("invalid kernel release: %s", release)
+	}
+	nums := []*int{&ver.Major, &ver.Minor, &ver.Patch}
+	for i, field := range fields {
+		if i >= len(nums) {
+			break
+		}
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return ver, errors.Wrapf(err, "invalid kernel release: %s", release)
+		}
+		*nums[i] = n
+	}
+	return ver, nil
+}
+
+// LessThan return whether version v is older than other
+func (v Version) LessThan(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// CheckVersion return error if running kernel older than major.minor
+func CheckVersion(major, minor int) error {
+	release, err := GetReleaseVersion()
+	if err != nil {
+		return err
+	}
+	ver, err := ParseVersion(release)
+	if err != nil {
+		return err
+	}
+	if ver.LessThan(Version{Major: major, Minor: minor}) {
+		return errors.Errorf("kernel version %s older than required %d.%d", release, major, minor)
+	}
+	return nil
+}
@@ -3,8 +3,11 @@
 package link
 
 import (
+	"net"
+
 	"github.com/pkg/errors"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
 // GetDeviceNumber get interface device number by mac address
@@ -36,3 +39,93 @@ func GetDeviceName(mac string) (string, error) {
 	}
 	return "", errors.Errorf("cannot found mac address: %s", mac)
 }
+
+// EnsureDummyLink create dummy link of name with mac address if not exist, used to simulate eni on host
+func EnsureDummyLink(name, mac string) error {
+	if _, err := GetDeviceName(mac); err == nil {
+		return nil
+	}
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return errors.Wrapf(err, "invalid mac address: %s", mac)
+	}
+	dummy := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: name, HardwareAddr: hwAddr}}
+	if err = netlink.LinkAdd(dummy); err != nil {
+		return errors.Wrapf(err, "error add dummy link %s", name)
+	}
+	return errors.Wrapf(netlink.LinkSetUp(dummy), "error set dummy link %s up", name)
+}
+
+// DeleteLinkByMAC delete link of mac address, ignored if not exist
+func DeleteLinkByMAC(mac string) error {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		return errors.Wrapf(err, "error get link list from netlink")
+	}
+
+	for _, link := range linkList {
+		if link.Attrs().HardwareAddr.String() == mac {
+			return errors.Wrapf(netlink.LinkDel(link), "error delete link %s", link.Attrs().Name)
+		}
+	}
+	return nil
+}
+
+// CheckLinkUp check link of mac address exists, up and with carrier
+func CheckLinkUp(mac string) error {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		return errors.Wrapf(err, "error get link list from netlink")
+	}
+
+	for _, link := range linkList {
+		attrs := link.Attrs()
+		if attrs.HardwareAddr.String() != mac {
+			continue
+		}
+		if attrs.Flags&net.FlagUp == 0 {
+			return errors.Errorf("link %s is down", attrs.Name)
+		}
+		if attrs.RawFlags&unix.IFF_LOWER_UP == 0 {
+			return errors.Errorf("link %s has no carrier", attrs.Name)
+		}
+		return nil
+	}
+	return errors.Errorf("cannot found mac address: %s", mac)
+}
+
+// HostVethName return host veth name of pod by scheme, fallback to names with index suffix if taken by veth
+// of another pod recorded in its alias
+func (s VethNameScheme) HostVethName(name, namespace string) (string, error) {
+	return s.hostVethName(name, namespace, func(vethName string) (string, bool) {
+		link, err := netlink.LinkByName(vethName)
+		if err != nil {
+			return "", false
+		}
+		return link.Attrs().Alias, true
+	})
+}
+
+// SetVethAlias record the pod host veth belongs to in its alias, ignored if veth not exist
+func SetVethAlias(vethName, name, namespace string) error {
+	link, err := netlink.LinkByName(vethName)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return errors.Wrapf(err, "error get link %s", vethName)
+	}
+	return errors.Wrapf(netlink.LinkSetAlias(link, VethAlias(name, namespace)), "error set alias of link %s", vethName)
+}
+
+// VethByAlias return name of host veth of pod by its alias, empty if not found
+func VethByAlias(name, namespace string) (string, error) {
+	link, err := netlink.LinkByAlias(VethAlias(name, namespace))
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "error get link by alias of pod %s/%s", namespace, name)
+	}
+	return link.Attrs().Name, nil
+}
@@ -15,3 +15,34 @@ func GetDeviceNumber(mac string) (int32, error) {
 func GetDeviceName(mac string) (string, error) {
 	return "", errors.Errorf("not supported arch")
 }
+
+// EnsureDummyLink create dummy link of name with mac address if not exist, used to simulate eni on host
+func EnsureDummyLink(name, mac string) error {
+	return errors.Errorf("not supported arch")
+}
+
+// DeleteLinkByMAC delete link of mac address, ignored if not exist
+func DeleteLinkByMAC(mac string) error {
+	return errors.Errorf("not supported arch")
+}
+
+// CheckLinkUp check link of mac address exists, up and with carrier
+func CheckLinkUp(mac string) error {
+	return errors.Errorf("not supported arch")
+}
+
+// HostVethName return host veth name of pod by scheme, fallback to names with index suffix if taken by veth
+// of another pod recorded in its alias
+func (s VethNameScheme) HostVethName(name, namespace string) (string, error) {
+	return "", errors.Errorf("not supported arch")
+}
+
+// SetVethAlias record the pod host veth belongs to in its alias, ignored if veth not exist
+func SetVethAlias(vethName, name, namespace string) error {
+	return errors.Errorf("not supported arch")
+}
+
+// VethByAlias return name of host veth of pod by its alias, empty if not found
+func VethByAlias(name, namespace string) (string, error) {
+	return "", errors.Errorf("not supported arch")
+}
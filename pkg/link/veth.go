@@ -2,16 +2,100 @@ package link
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash"
+	"strconv"
+
+	"github.com/pkg/errors"
 )
 
+const (
+	// VethHashSHA1 hash of veth names by sha1, the default
+	VethHashSHA1 = "sha1"
+	// VethHashSHA256 hash of veth names by sha256
+	VethHashSHA256 = "sha256"
+
+	// max veth length is 15
+	maxVethNameLen = 15
+	// min hex of hash in veth names, too short to avoid collisions
+	minVethHashLen = 6
+	// maxVethNameFallback names with index suffix tried on collision
+	maxVethNameFallback = 9
+)
+
+// VethNameScheme scheme of host veth names of pods: prefix and hex of hash of pod namespace and name
+type VethNameScheme struct {
+	Prefix string
+	// Length hex of hash in names
+	Length int
+	// Hash algorithm of names: sha1 or sha256
+	Hash string
+}
+
+// DefaultVethNameScheme scheme of host veth names by default
+var DefaultVethNameScheme = VethNameScheme{Prefix: "cali", Length: 11, Hash: VethHashSHA1}
+
+// Validate check names of scheme valid link names with enough hash
+func (s VethNameScheme) Validate() error {
+	if s.Hash != VethHashSHA1 && s.Hash != VethHashSHA256 {
+		return errors.Errorf("unsupported veth name hash %q, %s or %s expected", s.Hash, VethHashSHA1, VethHashSHA256)
+	}
+	if s.Length < minVethHashLen {
+		return errors.Errorf("veth name hash length %d less than %d", s.Length, minVethHashLen)
+	}
+	if len(s.Prefix)+s.Length > maxVethNameLen {
+		return errors.Errorf("veth name of prefix %q and hash length %d longer than %d", s.Prefix, s.Length, maxVethNameLen)
+	}
+	return nil
+}
+
+// Name return host veth name of pod, index > 0 for the fallback names on collision, whose last hex of
+// hash replaced by the index
+func (s VethNameScheme) Name(name, namespace string, index int) string {
+	var h hash.Hash
+	switch s.Hash {
+	case VethHashSHA256:
+		h = sha256.New()
+	default:
+		// A SHA1 is always 20 bytes long, and so is sufficient for generating the
+		// veth name and mac addr.
+		h = sha1.New()
+	}
+	h.Write([]byte(namespace + "." + name))
+	sum := hex.EncodeToString(h.Sum(nil))[:s.Length]
+	if index > 0 {
+		suffix := strconv.Itoa(index)
+		sum = sum[:s.Length-len(suffix)] + suffix
+	}
+	return s.Prefix + sum
+}
+
+// hostVethName return first name of pod not taken by veth of another pod, aliasOf return alias of link
+// of name and whether it exists
+func (s VethNameScheme) hostVethName(name, namespace string, aliasOf func(string) (string, bool)) (string, error) {
+	owner := VethAlias(name, namespace)
+	for index := 0; index <= maxVethNameFallback; index++ {
+		vethName := s.Name(name, namespace, index)
+		alias, exists := aliasOf(vethName)
+		// veths without alias created by previous version, taken as of the pod as before
+		if !exists || alias == "" || alias == owner {
+			return vethName, nil
+		}
+	}
+	return "", errors.Errorf("veth names of pod %s all taken by other pods", owner)
+}
+
+// VethAlias return alias of host veth recording the pod it belongs to
+func VethAlias(name, namespace string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
 // VethNameForPod return host-side veth name for pod
 // max veth length is 15
 func VethNameForPod(name, namespace, prefix string) string {
-	// A SHA1 is always 20 bytes long, and so is sufficient for generating the
-	// veth name and mac addr.
-	h := sha1.New()
-	h.Write([]byte(namespace + "." + name))
-	return fmt.Sprintf("%s%s", prefix, hex.EncodeToString(h.Sum(nil))[:11])
+	scheme := DefaultVethNameScheme
+	scheme.Prefix = prefix
+	return scheme.Name(name, namespace, 0)
 }
@@ -7,3 +7,43 @@ func TestVethNameForPod(t *testing.T) {
 		t.Fatalf("veth name failed: expect: %s, actual: %s", "calic95a4947e07", veth)
 	}
 }
+
+func TestVethNameScheme(t *testing.T) {
+	scheme := VethNameScheme{Prefix: "veth", Length: 11, Hash: VethHashSHA256}
+	if err := scheme.Validate(); err != nil {
+		t.Fatalf("scheme should be valid: %v", err)
+	}
+	if veth := scheme.Name("client-b6989bf87-2bgtc", "default", 0); len(veth) != 15 || veth[:4] != "veth" {
+		t.Fatalf("unexpected veth name of sha256: %s", veth)
+	}
+	for _, invalid := range []VethNameScheme{
+		{Prefix: "cali", Length: 12, Hash: VethHashSHA1},
+		{Prefix: "cali", Length: 4, Hash: VethHashSHA1},
+		{Prefix: "cali", Length: 11, Hash: "md5"},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Fatalf("scheme %+v should be invalid", invalid)
+		}
+	}
+
+	aliases := map[string]string{
+		// veth of another pod colliding with the default name
+		"calic95a4947e07": "other/client",
+		"calic95a4947e01": "default/client-b6989bf87-2bgtc",
+	}
+	aliasOf := func(name string) (string, bool) {
+		alias, ok := aliases[name]
+		return alias, ok
+	}
+	if veth, _ := DefaultVethNameScheme.hostVethName("client-b6989bf87-2bgtc", "default", aliasOf); veth != "calic95a4947e01" {
+		t.Fatalf("veth name on collision failed: expect: %s, actual: %s", "calic95a4947e01", veth)
+	}
+	// veth without alias created by previous version
+	legacy := func(string) (string, bool) { return "", true }
+	if veth, _ := DefaultVethNameScheme.hostVethName("client-b6989bf87-2bgtc", "default", legacy); veth != "calic95a4947e07" {
+		t.Fatalf("veth name of legacy veth failed: expect: %s, actual: %s", "calic95a4947e07", veth)
+	}
+	if _, err := DefaultVethNameScheme.hostVethName("client", "another", func(string) (string, bool) { return "default/web", true }); err == nil {
+		t.Fatalf("veth names all taken should fail")
+	}
+}
@@ -0,0 +1,241 @@
+// Package metadata client of metaserver of ecs instance, values of the instance and enis by mac never change
+// cached for life of process, the token of hardened mode used where supported, and the last value served when
+// metaserver failing
+package metadata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/metric"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultEndpoint metaserver of ecs instances
+	DefaultEndpoint = "http://100.100.100.200"
+
+	metadataPrefix = "/latest/meta-data/"
+	tokenPath      = "/latest/api/token"
+	// headers of hardened mode of metaserver
+	tokenHeader    = "X-aliyun-ecs-metadata-token"
+	tokenTTLHeader = "X-aliyun-ecs-metadata-token-ttl-seconds"
+
+	defaultTimeout = 2 * time.Second
+	defaultRetries = 3
+	retryInterval  = 100 * time.Millisecond
+	tokenTTL       = 6 * time.Hour
+	// tokenRenewAhead token renewed before expired, skew of clock of metaserver tolerated
+	tokenRenewAhead = time.Minute
+)
+
+// ErrNotFound path not found in metaserver, eg: eni not attached yet
+var ErrNotFound = errors.New("not found in metaserver")
+
+// volatilePaths paths of values changing during life of instance, fetched each time
+var volatilePaths = []string{
+	"network/interfaces/macs/",
+	"/private-ipv4s",
+}
+
+// Client of metaserver
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	retries    int
+
+	lock sync.Mutex
+	// values last fetched by path
+	values map[string]string
+	// local values served in process instead of metaserver, eg: by fake cloud
+	local            map[string]string
+	token            string
+	tokenExpiration  time.Time
+	tokenUnsupported bool
+}
+
+// NewClient return client of metaserver at endpoint
+func NewClient(endpoint string) *Client {
+	return &Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		retries:    defaultRetries,
+		values:     make(map[string]string),
+	}
+}
+
+var defaultClient = NewClient(DefaultEndpoint)
+
+// Default return client of the metaserver of instance
+func Default() *Client {
+	return defaultClient
+}
+
+// SetLocal serve values of paths in process instead of metaserver
+func (c *Client) SetLocal(values map[string]string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.local = values
+}
+
+// Value return first line of value of path, trimmed of "/"
+func (c *Client) Value(path string) (string, error) {
+	body, err := c.get(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(strings.Split(body, "\n")[0], "/"), nil
+}
+
+// Array return lines of value of path, each trimmed of "/"
+func (c *Client) Array(path string) ([]string, error) {
+	body, err := c.get(path)
+	if err != nil {
+		return []string{}, err
+	}
+	result := strings.Split(body, "\n")
+	for i, str := range result {
+		result[i] = strings.Trim(str, "/")
+	}
+	return result, nil
+}
+
+func volatile(path string) bool {
+	for _, p := range volatilePaths {
+		if strings.HasSuffix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// get value of path, from cache if never changed, the last value fetched returned on failure of metaserver
+func (c *Client) get(path string) (string, error) {
+	path = strings.TrimPrefix(path, "/")
+	c.lock.Lock()
+	if value, ok := c.local[path]; ok {
+		c.lock.Unlock()
+		return value, nil
+	}
+	last, cached := c.values[path]
+	c.lock.Unlock()
+	if cached && !volatile(path) {
+		return last, nil
+	}
+
+	value, err := c.fetch(path)
+	if err != nil {
+		if cached && errors.Cause(err) != ErrNotFound {
+			log.Warnf("error get %s from metaserver, serve the last value: %v", path, err)
+			return last, nil
+		}
+		return "", err
+	}
+	c.lock.Lock()
+	c.values[path] = value
+	c.lock.Unlock()
+	return value, nil
+}
+
+// fetch value of path from metaserver, retried on errors other than not found
+func (c *Client) fetch(path string) (string, error) {
+	var err error
+	for i := 0; i < c.retries; i++ {
+		if i > 0 {
+			time.Sleep(retryInterval << uint(i-1))
+		}
+		var (
+			value     string
+			retryable bool
+		)
+		value, retryable, err = c.fetchOnce(path)
+		if err == nil || !retryable {
+			return value, err
+		}
+	}
+	return "", err
+}
+
+func (c *Client) fetchOnce(path string) (value string, retryable bool, err error) {
+	url := c.endpoint + metadataPrefix + path
+	start := time.Now()
+	defer func() {
+		metric.MetadataLatency.WithLabelValues(url, fmt.Sprint(err != nil)).Observe(metric.MsSince(start))
+	}()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", false, err
+	}
+	if token := c.getToken(); token != "" {
+		req.Header.Set(tokenHeader, token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", true, errors.Wrapf(err, "error get url: %s from metaserver", url)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", false, errors.Wrapf(ErrNotFound, "error get url: %s", url)
+	case resp.StatusCode == http.StatusUnauthorized:
+		// token expired or revoked, fetched again on retry
+		c.lock.Lock()
+		c.token = ""
+		c.lock.Unlock()
+		return "", true, fmt.Errorf("error get url: %s from metaserver, token rejected", url)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return "", resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests,
+			fmt.Errorf("error get url: %s from metaserver, code: %v", url, resp.StatusCode)
+	}
+	if err != nil {
+		return "", true, errors.Wrapf(err, "error read url: %s from metaserver", url)
+	}
+	return string(body), false, nil
+}
+
+// getToken return token of hardened mode, empty if not supported by metaserver or failed, the plain access used
+func (c *Client) getToken() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.tokenUnsupported {
+		return ""
+	}
+	if c.token != "" && time.Now().Before(c.tokenExpiration) {
+		return c.token
+	}
+	req, err := http.NewRequest(http.MethodPut, c.endpoint+tokenPath, nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set(tokenTTLHeader, strconv.Itoa(int(tokenTTL/time.Second)))
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		log.Debugf("error get token of metaserver: %v", err)
+		return ""
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusForbidden ||
+		resp.StatusCode == http.StatusMethodNotAllowed:
+		log.Infof("token of metaserver not supported, code: %v, access without token", resp.StatusCode)
+		c.tokenUnsupported = true
+		return ""
+	case resp.StatusCode >= http.StatusBadRequest:
+		log.Debugf("error get token of metaserver, code: %v", resp.StatusCode)
+		return ""
+	}
+	token, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return ""
+	}
+	c.token = strings.TrimSpace(string(token))
+	c.tokenExpiration = time.Now().Add(tokenTTL - tokenRenewAhead)
+	return c.token
+}
@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMetaserver struct {
+	lock     sync.Mutex
+	values   map[string]string
+	token    string
+	requests map[string]int
+	// fail requests of values with code if not zero
+	failCode int
+}
+
+func (s *fakeMetaserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests[r.Method+" "+r.URL.Path]++
+	if r.URL.Path == tokenPath {
+		if s.token == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(s.token))
+		return
+	}
+	if s.token != "" && r.Header.Get(tokenHeader) != s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if s.failCode != 0 {
+		w.WriteHeader(s.failCode)
+		return
+	}
+	value, ok := s.values[r.URL.Path[len(metadataPrefix):]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write([]byte(value))
+}
+
+func TestClient(t *testing.T) {
+	server := &fakeMetaserver{
+		values: map[string]string{
+			"instance-id":              "i-1",
+			"network/interfaces/macs/": "00:16:3e:00:00:01/\n00:16:3e:00:00:02/",
+		},
+		token:    "token-1",
+		requests: make(map[string]int),
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	value, err := client.Value("instance-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "i-1", value)
+	macs, err := client.Array("network/interfaces/macs/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"00:16:3e:00:00:01", "00:16:3e:00:00:02"}, macs)
+	assert.Equal(t, 1, server.requests["PUT "+tokenPath])
+
+	// values never changed cached, the volatile fetched each time
+	_, _ = client.Value("instance-id")
+	_, _ = client.Array("network/interfaces/macs/")
+	assert.Equal(t, 1, server.requests["GET "+metadataPrefix+"instance-id"])
+	assert.Equal(t, 2, server.requests["GET "+metadataPrefix+"network/interfaces/macs/"])
+
+	// token renewed once rejected
+	server.token = "token-2"
+	_, err = client.Array("network/interfaces/macs/")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, server.requests["PUT "+tokenPath])
+
+	// not found not retried
+	_, err = client.Value("vpc-id")
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+	assert.Equal(t, 1, server.requests["GET "+metadataPrefix+"vpc-id"])
+
+	// the last value served on failure of metaserver
+	server.failCode = http.StatusInternalServerError
+	macs, err = client.Array("network/interfaces/macs/")
+	assert.NoError(t, err)
+	assert.Len(t, macs, 2)
+	_, err = client.Value("zone-id")
+	assert.Error(t, err)
+	assert.Equal(t, defaultRetries, server.requests["GET "+metadataPrefix+"zone-id"])
+}
+
+func TestClientWithoutToken(t *testing.T) {
+	server := &fakeMetaserver{
+		values:   map[string]string{"region-id": "cn-hangzhou"},
+		requests: make(map[string]int),
+	}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.SetLocal(map[string]string{"zone-id": "cn-hangzhou-a"})
+
+	for i := 0; i < 2; i++ {
+		value, err := client.Value("region-id")
+		assert.NoError(t, err)
+		assert.Equal(t, "cn-hangzhou", value)
+	}
+	value, err := client.Value("zone-id")
+	assert.NoError(t, err)
+	assert.Equal(t, "cn-hangzhou-a", value)
+	// unsupported token not requested again
+	assert.Equal(t, 1, server.requests["PUT "+tokenPath])
+}
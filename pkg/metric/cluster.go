@@ -0,0 +1,29 @@
+package metric
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// ClusterVSwitchIPs ips of vswitches used by enis of nodes in cluster and available, by controller
+	ClusterVSwitchIPs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_cluster_vswitch_ips",
+			Help: "ips of vswitch used by enis of nodes in cluster or available to allocate",
+		},
+		[]string{"vswitch", "zone", "state"},
+	)
+
+	// ClusterNodePoolIPs sum of stats of resource pools of nodes in nodepool, by controller
+	ClusterNodePoolIPs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_cluster_nodepool_ips",
+			Help: "capacity, inuse and idle of resource pools of nodes in nodepool",
+		},
+		[]string{"nodepool", "resource_type", "state"},
+	)
+)
+
+// RegisterClusterPrometheus register metrics of cluster aggregated by controller
+func RegisterClusterPrometheus() {
+	prometheus.MustRegister(ClusterVSwitchIPs)
+	prometheus.MustRegister(ClusterNodePoolIPs)
+}
@@ -0,0 +1,58 @@
+package metric
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// DatapathIncompatible whether requested datapath incompatible with cni binary or kernel and previous one kept
+	DatapathIncompatible = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_datapath_incompatible",
+			Help: "requested datapath is incompatible with node and previous datapath kept active",
+		},
+		[]string{"requested", "active"},
+	)
+
+	// ConntrackFlushed conntrack entries of released pod ips flushed on host
+	ConntrackFlushed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "terway_conntrack_flushed_total",
+			Help: "conntrack entries of released pod ips flushed on host",
+		},
+		[]string{"pod_network_type"},
+	)
+
+	// KubeProxyIncompatible whether check of kube-proxy, rp_filter, iptables or ipset on node failed
+	KubeProxyIncompatible = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_kube_proxy_incompatible",
+			Help: "node is incompatible with routing of terway by the check, service connectivity of pods may fail",
+		},
+		[]string{"check"},
+	)
+
+	// CNIConfRepaired cni config list installed by daemon found drifted and repaired
+	CNIConfRepaired = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "terway_cni_conf_repaired_total",
+			Help: "cni config list installed by daemon found missing or changed and repaired",
+		},
+	)
+
+	// NetnsLeaks netns files without any process and their host veths found by last scan
+	NetnsLeaks = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_netns_leaks",
+			Help: "netns files without any process left by container runtimes and their host veths on node",
+		},
+		[]string{"kind"},
+	)
+
+	// NetnsLeakCleaned leaked netns files and their host veths removed
+	NetnsLeakCleaned = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "terway_netns_leak_cleaned_total",
+			Help: "leaked netns files and their host veths removed on node",
+		},
+		[]string{"kind"},
+	)
+)
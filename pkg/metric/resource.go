@@ -0,0 +1,48 @@
+package metric
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// ResourceConflict count of allocated resource disposed for address conflict
+	ResourceConflict = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "terway_resource_conflict_total",
+			Help: "count of resource disposed for ip address conflict detected",
+		},
+		[]string{"type"},
+	)
+
+	// ENIUnhealthy enis of node failed health check, not used for new allocations
+	ENIUnhealthy = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "terway_eni_unhealthy",
+			Help: "count of enis on node failed health check",
+		},
+	)
+
+	// ResourceDrift count of pool resources corrected by reconcile with cloud
+	ResourceDrift = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "terway_resource_drift_total",
+			Help: "count of pool resources vanished in cloud and evicted, or found in cloud and adopted",
+		},
+		[]string{"type", "drift"},
+	)
+
+	// ResourceVanishedInuse resources of pods vanished in cloud, left to the pods deleted
+	ResourceVanishedInuse = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "terway_resource_vanished_inuse",
+			Help: "count of resources used by pods but vanished in cloud",
+		},
+		[]string{"type"},
+	)
+
+	// ENIDefragFreed enis freed by moving their idle ips to other enis
+	ENIDefragFreed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "terway_eni_defrag_freed_total",
+			Help: "count of enis freed by moving their idle ips to other enis",
+		},
+	)
+)
@@ -11,4 +11,23 @@ var (
 		},
 		[]string{"rpc_api", "error"},
 	)
+
+	// AllocationPhaseLatency latency of phases of pod network allocation, netlink_setup reported by cni binary
+	AllocationPhaseLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "terway_allocation_phase_latency_ms",
+			Help:    "latency of phases of pod network allocation in ms",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 16),
+		},
+		[]string{"phase"},
+	)
+
+	// RPCShed requests of cni binary rejected by daemon over inflight limit
+	RPCShed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "terway_rpc_shed_total",
+			Help: "terway rpc requests rejected over inflight limit",
+		},
+		[]string{"rpc_api"},
+	)
 )
@@ -16,4 +16,17 @@ func RegisterPrometheus() {
 	prometheus.MustRegister(RPCLatency)
 	prometheus.MustRegister(OpenAPILatency)
 	prometheus.MustRegister(MetadataLatency)
+	prometheus.MustRegister(DatapathIncompatible)
+	prometheus.MustRegister(ResourceConflict)
+	prometheus.MustRegister(ENIUnhealthy)
+	prometheus.MustRegister(ResourceDrift)
+	prometheus.MustRegister(ResourceVanishedInuse)
+	prometheus.MustRegister(ConntrackFlushed)
+	prometheus.MustRegister(AllocationPhaseLatency)
+	prometheus.MustRegister(RPCShed)
+	prometheus.MustRegister(KubeProxyIncompatible)
+	prometheus.MustRegister(NetnsLeaks)
+	prometheus.MustRegister(NetnsLeakCleaned)
+	prometheus.MustRegister(CNIConfRepaired)
+	prometheus.MustRegister(ENIDefragFreed)
 }
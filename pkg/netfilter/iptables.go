@@ -0,0 +1,126 @@
+package netfilter
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// iptables rules by iptables command of backend
+type iptables struct {
+	path    string
+	backend string
+}
+
+func (ipt *iptables) Backend() string {
+	return ipt.backend
+}
+
+// run iptables with args waiting for xtables lock, return stdout and exit status of command
+func (ipt *iptables) run(args ...string) (string, int, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(ipt.path, append(args, "--wait")...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		status := -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+				status = ws.ExitStatus()
+			}
+		}
+		return "", status, errors.Errorf("error run %s %s: %v: %s", ipt.path, strings.Join(args, " "), err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), 0, nil
+}
+
+func (ipt *iptables) Exists(table, chain string, rulespec ...string) (bool, error) {
+	_, status, err := ipt.run(append([]string{"-t", table, "-C", chain}, rulespec...)...)
+	switch {
+	case err == nil:
+		return true, nil
+	case status == 1:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+func (ipt *iptables) Insert(table, chain string, pos int, rulespec ...string) error {
+	_, _, err := ipt.run(append([]string{"-t", table, "-I", chain, strconv.Itoa(pos)}, rulespec...)...)
+	return err
+}
+
+func (ipt *iptables) Append(table, chain string, rulespec ...string) error {
+	_, _, err := ipt.run(append([]string{"-t", table, "-A", chain}, rulespec...)...)
+	return err
+}
+
+func (ipt *iptables) AppendUnique(table, chain string, rulespec ...string) error {
+	exists, err := ipt.Exists(table, chain, rulespec...)
+	if err != nil || exists {
+		return err
+	}
+	return ipt.Append(table, chain, rulespec...)
+}
+
+func (ipt *iptables) Delete(table, chain string, rulespec ...string) error {
+	_, _, err := ipt.run(append([]string{"-t", table, "-D", chain}, rulespec...)...)
+	return err
+}
+
+func (ipt *iptables) List(table, chain string) ([]string, error) {
+	out, _, err := ipt.run("-t", table, "-S", chain)
+	if err != nil {
+		return nil, err
+	}
+	rules := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	// iptables-nft list nothing without error for chain not exists
+	if len(rules) == 1 && rules[0] == "" {
+		return nil, errors.Errorf("chain %s not exists in table %s", chain, table)
+	}
+	return rules, nil
+}
+
+func (ipt *iptables) ListChains(table string) ([]string, error) {
+	out, _, err := ipt.run("-t", table, "-S")
+	if err != nil {
+		return nil, err
+	}
+	var chains []string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && (fields[0] == "-P" || fields[0] == "-N") {
+			chains = append(chains, fields[1])
+		}
+	}
+	return chains, nil
+}
+
+func (ipt *iptables) NewChain(table, chain string) error {
+	_, _, err := ipt.run("-t", table, "-N", chain)
+	return err
+}
+
+func (ipt *iptables) ClearChain(table, chain string) error {
+	chains, err := ipt.ListChains(table)
+	if err != nil {
+		return err
+	}
+	for _, c := range chains {
+		if c == chain {
+			_, _, err = ipt.run("-t", table, "-F", chain)
+			return err
+		}
+	}
+	return ipt.NewChain(table, chain)
+}
+
+func (ipt *iptables) DeleteChain(table, chain string) error {
+	_, _, err := ipt.run("-t", table, "-X", chain)
+	return err
+}
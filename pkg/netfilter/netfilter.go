@@ -0,0 +1,154 @@
+package netfilter
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// backends of netfilter rules programmed by terway: legacy xtables, or nf_tables by iptables-nft, rules of
+// both backends applied by kernel, so rules must be in the backend of kube-proxy and others on node, or shadowed.
+// rules are programmed by the iptables command of backend in both, not native nft rules
+const (
+	BackendLegacy = "legacy"
+	BackendNFT    = "nft"
+)
+
+// StatePath backend detected by daemon on node, used by cni binary as well
+const StatePath = "/var/lib/cni/terway/netfilter_backend"
+
+// IPTables rules of a table in netfilter backend, in iptables rule spec
+type IPTables interface {
+	// Backend return backend of rules
+	Backend() string
+	Exists(table, chain string, rulespec ...string) (bool, error)
+	Insert(table, chain string, pos int, rulespec ...string) error
+	Append(table, chain string, rulespec ...string) error
+	// AppendUnique append rule if not exists
+	AppendUnique(table, chain string, rulespec ...string) error
+	Delete(table, chain string, rulespec ...string) error
+	// List return rules of chain in iptables -S format, the chain definition first
+	List(table, chain string) ([]string, error)
+	ListChains(table string) ([]string, error)
+	NewChain(table, chain string) error
+	// ClearChain flush rules of chain, created if not exists
+	ClearChain(table, chain string) error
+	DeleteChain(table, chain string) error
+}
+
+// commandOf return path of iptables command of backend, eg: iptables-nft-save of iptables-save, or the plain
+// command in the backend
+func commandOf(name, backend string) (string, error) {
+	named := strings.Replace(name, "iptables", "iptables-"+backend, 1)
+	if path, err := exec.LookPath(named); err == nil {
+		return path, nil
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "neither %s nor %s found", named, name)
+	}
+	if plainBackend() != backend {
+		return "", errors.Errorf("%s not found and %s not in %s backend", named, name, backend)
+	}
+	return path, nil
+}
+
+// plainBackend return backend of the plain iptables command, by its version, eg: "iptables v1.8.4 (nf_tables)"
+func plainBackend() string {
+	out, err := exec.Command("iptables", "--version").Output()
+	if err == nil && strings.Contains(string(out), "nf_tables") {
+		return BackendNFT
+	}
+	return BackendLegacy
+}
+
+// countRules return rules in output of iptables-save
+func countRules(save []byte) int {
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(save))
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "-A ") {
+			count++
+		}
+	}
+	return count
+}
+
+// chooseBackend return backend with more rules on node, programmed by kube-proxy and others, or the backend of
+// plain iptables command if no rules in neither
+func chooseBackend(legacyRules, nftRules int, plain string) string {
+	switch {
+	case nftRules > legacyRules:
+		return BackendNFT
+	case legacyRules > nftRules:
+		return BackendLegacy
+	default:
+		return plain
+	}
+}
+
+// Detect return backend of rules on node
+func Detect() string {
+	rules := make(map[string]int)
+	for _, backend := range []string{BackendLegacy, BackendNFT} {
+		save, err := commandOf("iptables-save", backend)
+		if err != nil {
+			continue
+		}
+		out, err := exec.Command(save).Output()
+		if err != nil {
+			continue
+		}
+		rules[backend] = countRules(out)
+	}
+	return chooseBackend(rules[BackendLegacy], rules[BackendNFT], plainBackend())
+}
+
+// Setup resolve backend of node, detected if not configured, and save it for cni binary
+func Setup(configured string) (string, error) {
+	backend := configured
+	switch backend {
+	case "":
+		backend = Detect()
+	case BackendLegacy, BackendNFT:
+	default:
+		return "", errors.Errorf("unsupported netfilter backend %s", configured)
+	}
+	if _, err := commandOf("iptables", backend); err != nil {
+		return "", errors.Wrapf(err, "iptables of %s backend not available", backend)
+	}
+	if err := os.MkdirAll(filepath.Dir(StatePath), 0700); err != nil {
+		return "", errors.Wrapf(err, "error create dir of %s", StatePath)
+	}
+	if err := ioutil.WriteFile(StatePath, []byte(backend), 0600); err != nil {
+		return "", errors.Wrapf(err, "error save netfilter backend")
+	}
+	return backend, nil
+}
+
+// New return rules in backend saved by daemon, or detected if not saved, eg: daemon not upgraded yet
+func New() (IPTables, error) {
+	backend := ""
+	if data, err := ioutil.ReadFile(StatePath); err == nil {
+		backend = strings.TrimSpace(string(data))
+	}
+	if backend != BackendLegacy && backend != BackendNFT {
+		backend = Detect()
+	}
+	return NewWithBackend(backend)
+}
+
+// NewWithBackend return rules in backend
+func NewWithBackend(backend string) (IPTables, error) {
+	path, err := commandOf("iptables", backend)
+	if err != nil {
+		return nil, err
+	}
+	return &iptables{path: path, backend: backend}, nil
+}
@@ -0,0 +1,25 @@
+package netfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChooseBackend(t *testing.T) {
+	save := []byte(`# Generated by iptables-nft-save v1.8.4 on Mon Jan  1 00:00:00 2024
+*nat
+:PREROUTING ACCEPT [0:0]
+:KUBE-SERVICES - [0:0]
+-A PREROUTING -m comment --comment "kubernetes service portals" -j KUBE-SERVICES
+-A KUBE-SERVICES -m addrtype --dst-type LOCAL -j KUBE-NODEPORTS
+COMMIT
+`)
+	assert.Equal(t, 2, countRules(save))
+	assert.Equal(t, 0, countRules(nil))
+
+	assert.Equal(t, BackendNFT, chooseBackend(0, 2, BackendLegacy))
+	assert.Equal(t, BackendLegacy, chooseBackend(3, 2, BackendNFT))
+	// no rules on node, backend of plain iptables
+	assert.Equal(t, BackendNFT, chooseBackend(0, 0, BackendNFT))
+}
@@ -0,0 +1,31 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// phases of acquire observed by PhaseObserver
+const (
+	// PhaseWait waiting for idle resource or token to create
+	PhaseWait = "pool_wait"
+	// PhaseCreate creating resource by factory
+	PhaseCreate = "create"
+)
+
+// PhaseObserver observe the duration of phase of acquire
+type PhaseObserver func(phase string, d time.Duration)
+
+type phaseObserverKey struct{}
+
+// WithPhaseObserver return context with observer of acquire phases
+func WithPhaseObserver(ctx context.Context, observer PhaseObserver) context.Context {
+	return context.WithValue(ctx, phaseObserverKey{}, observer)
+}
+
+// observePhase report duration of phase to observer in ctx if any
+func observePhase(ctx context.Context, phase string, d time.Duration) {
+	if observer, ok := ctx.Value(phaseObserverKey{}).(PhaseObserver); ok {
+		observer(phase, d)
+	}
+}
@@ -2,13 +2,14 @@ package pool
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/AliyunContainerService/terway/pkg/tracing"
 	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -19,20 +20,104 @@ var (
 	ErrNotFound            = errors.New("not found")
 	ErrContextDone         = errors.New("context done")
 	ErrInvalidArguments    = errors.New("invalid arguments")
+	ErrDraining            = errors.New("pool draining")
 )
 
 const (
 	// CheckIdleInterval the interval of check and process idle eni
 	CheckIdleInterval = 2 * time.Minute
+	// drainWindow window of drain rate of overfull idle resources
+	drainWindow = time.Minute
+	// throttleRefillDelay delay between background creates at throttle level 1, doubled each level above
+	throttleRefillDelay = time.Second
+	// maxThrottleLevel throttle levels above taken as the max, refill chunks shrunk to one long before
+	maxThrottleLevel = 5
 )
 
 // ObjectPool object pool interface
 type ObjectPool interface {
 	Acquire(ctx context.Context, resID string) (types.NetworkResource, error)
+	// Transfer take resource over for another owner without release, eg: pod sandbox recreated in place
+	Transfer(resID string) (types.NetworkResource, error)
+	// AcquireWithHints acquire resource choosing among idle ones by hints
+	AcquireWithHints(ctx context.Context, hints AcquireHints) (types.NetworkResource, error)
 	ReleaseWithReverse(resID string, reverse time.Duration) error
+	ReleaseWithQuarantine(resID string, reverse, period time.Duration) error
 	Release(resID string) error
 	AcquireAny(ctx context.Context) (types.NetworkResource, error)
 	Stat(resID string) error
+	GetResourceIDs() []string
+	Dispose(resID string) error
+	Stats() Stats
+	// Snapshot return ids of resources in pool by state
+	Snapshot() Snapshot
+	// Inventory return resources in pool with state and age
+	Inventory() []InventoryItem
+	// Evict remove idle or quarantined resource vanished outside of pool without disposing, eg: deleted in console
+	Evict(resID string) error
+	// Adopt add resource found outside of pool as idle, eg: created by terway but not recorded
+	Adopt(res types.NetworkResource) error
+	// Pin mark resource in pool never disposed as overfull idle, eg: eni holding ip reserved for pod
+	Pin(resID string) error
+	// Unpin make pinned resource disposable again
+	Unpin(resID string) error
+	// Prewarm create resources in background toward count idle for demand expected, bounded by max idle and capacity
+	Prewarm(count int)
+	// Drain dispose all idle and quarantined resources and create no more, eg: node to be terminated,
+	// return count of resources disposed
+	Drain() (int, error)
+}
+
+// Stats the count of resources in pool by state
+type Stats struct {
+	Capacity   int
+	Inuse      int
+	Idle       int
+	Quarantine int
+}
+
+// Snapshot ids of resources in pool by state, sorted
+type Snapshot struct {
+	Inuse      []string
+	Idle       []string
+	Quarantine []string
+	Pinned     []string
+}
+
+// states of resources in inventory
+const (
+	StateIdle       = "idle"
+	StateInuse      = "inuse"
+	StateQuarantine = "quarantine"
+)
+
+// InventoryItem resource in pool with its state and age
+type InventoryItem struct {
+	ID    string
+	Type  string
+	State string
+	// Created time resource added to pool, time of daemon start for resources restored by initializer
+	Created time.Time
+	// LastUsed time resource last acquired or released, zero if never used
+	LastUsed time.Time
+	Pinned   bool
+	// Resource the resource itself, eg: for ip of it
+	Resource types.NetworkResource
+}
+
+// resourceMeta age of resource in pool
+type resourceMeta struct {
+	created  time.Time
+	lastUsed time.Time
+}
+
+// AcquireHints hints to choose among idle resources on acquire
+type AcquireHints struct {
+	// Prefer id of resource preferred, eg: resource used by the pod before
+	Prefer string
+	// Score rank of idle resources, the highest chosen and the earliest reverse on ties,
+	// eg: anti-affinity to enis of ips used by replicas of pod
+	Score func(res types.NetworkResource) int
 }
 
 // ResourceHolder interface to initialize pool
@@ -47,6 +132,23 @@ type ObjectFactory interface {
 	Dispose(types.NetworkResource) error
 }
 
+// BatchFactory object factory able to create and dispose resources in chunk, eg: eni secondary ips by one openapi call
+type BatchFactory interface {
+	ObjectFactory
+	// CreateBatch create at most count resources, at least one on success
+	CreateBatch(count int) ([]types.NetworkResource, error)
+	// DisposeBatch dispose resources, return resources failed to dispose
+	DisposeBatch(resources []types.NetworkResource) ([]types.NetworkResource, error)
+}
+
+// BackgroundFactory object factory with a view creating resources in background, eg: openapi calls yielding
+// to the ones of acquires, used to fill idle resources by prewarm and preload
+type BackgroundFactory interface {
+	ObjectFactory
+	// Background return view of factory creating resources in background
+	Background() ObjectFactory
+}
+
 type simpleObjectPool struct {
 	inuse      map[string]types.NetworkResource
 	idle       *priorityQeueu
@@ -56,9 +158,34 @@ type simpleObjectPool struct {
 	minIdle    int
 	capacity   int
 	maxBackoff time.Duration
+	clock      Clock
+	tick       <-chan time.Time
 	notifyCh   chan interface{}
 	// concurrency to create resource. tokenCh = capacity - (idle + inuse + dispose)
 	tokenCh chan struct{}
+	// released resources in cool-down, can only be acquired by id until expired
+	quarantine       map[string]*quarantineItem
+	quarantinePeriod time.Duration
+	chunkSize        int
+	// prewarming count of resources creating by prewarm
+	prewarming int
+	denied     func(res types.NetworkResource) bool
+	// drainRate overfull idle resources disposed per drainWindow at most, unlimited if not positive
+	drainRate      int
+	drained        int
+	drainStart     time.Time
+	drainScheduled bool
+	// parallelCreates concurrent creates of refill, createCh slots of concurrent creates if bounded
+	parallelCreates int
+	createCh        chan struct{}
+	// pinned resources never disposed as overfull idle until unpinned
+	pinned map[string]bool
+	// throttle level of openapi throttling, nil if not throttle aware
+	throttle func() int
+	// meta age of resources by id
+	meta map[string]*resourceMeta
+	// draining no resource created any more, resources released disposed as overfull idle
+	draining bool
 }
 
 // Config configuration of pool
@@ -68,6 +195,35 @@ type Config struct {
 	MinIdle     int
 	MaxIdle     int
 	Capacity    int
+	// Clock time source of pool, real clock if nil
+	Clock Clock
+	// Tick ticks to check idle resources, ticker of CheckIdleInterval if nil, eg: fake ticker in tests
+	Tick <-chan time.Time
+	// QuarantinePeriod cool-down of released resources before acquired by others
+	QuarantinePeriod time.Duration
+	// ChunkSize count of resources created and disposed together if factory is BatchFactory
+	ChunkSize int
+	// Denied resources not allocated to anyone, skipped and kept in quarantine until no longer denied
+	Denied func(res types.NetworkResource) bool
+	// DrainRate overfull idle resources disposed per minute at most, eg: max idle lowered, unlimited if not positive
+	DrainRate int
+	// ParallelCreates concurrent factory creates at most, eg: to avoid throttling of openapi,
+	// refill and initializer replay create one by one and acquire is unlimited if not positive
+	ParallelCreates int
+	// Throttle level of openapi throttling, eg: shared by daemons of cluster, background creates delayed
+	// and chunks of creates halved each level, not throttle aware if nil
+	Throttle func() int
+}
+
+// Clock the time source of pool, replaced by fake clock in tests
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
 }
 
 type poolItem struct {
@@ -79,6 +235,11 @@ func (i *poolItem) lessThan(other *poolItem) bool {
 	return i.reverse.Before(other.reverse)
 }
 
+type quarantineItem struct {
+	*poolItem
+	until time.Time
+}
+
 // Initializer of pool
 type Initializer func(holder ResourceHolder) error
 
@@ -99,10 +260,32 @@ func NewSimpleObjectPool(cfg Config) (ObjectPool, error) {
 		maxIdle:  cfg.MaxIdle,
 		minIdle:  cfg.MinIdle,
 		capacity: cfg.Capacity,
+		clock:    cfg.Clock,
+		tick:     cfg.Tick,
 		notifyCh: make(chan interface{}),
 		tokenCh:  make(chan struct{}, cfg.Capacity),
 	}
 
+	pool.quarantine = make(map[string]*quarantineItem)
+	pool.pinned = make(map[string]bool)
+	pool.meta = make(map[string]*resourceMeta)
+	pool.quarantinePeriod = cfg.QuarantinePeriod
+	pool.chunkSize = cfg.ChunkSize
+	pool.denied = cfg.Denied
+	pool.drainRate = cfg.DrainRate
+	pool.throttle = cfg.Throttle
+	pool.parallelCreates = 1
+	if cfg.ParallelCreates > 0 {
+		pool.parallelCreates = cfg.ParallelCreates
+		pool.createCh = make(chan struct{}, cfg.ParallelCreates)
+	}
+	if pool.denied == nil {
+		pool.denied = func(types.NetworkResource) bool { return false }
+	}
+	if pool.clock == nil {
+		pool.clock = realClock{}
+	}
+
 	if cfg.Initializer != nil {
 		if err := cfg.Initializer(pool); err != nil {
 			return nil, err
@@ -127,10 +310,12 @@ func NewSimpleObjectPool(cfg Config) (ObjectPool, error) {
 
 func (p *simpleObjectPool) startCheckIdleTicker() {
 	p.checkIdle()
-	ticker := time.NewTicker(CheckIdleInterval)
+	if p.tick == nil {
+		p.tick = time.NewTicker(CheckIdleInterval).C
+	}
 	for {
 		select {
-		case <-ticker.C:
+		case <-p.tick:
 			p.checkIdle()
 		case <-p.notifyCh:
 			p.checkIdle()
@@ -165,30 +350,87 @@ func (p *simpleObjectPool) dispose(res types.NetworkResource) {
 }
 
 func (p *simpleObjectPool) tooManyIdleLocked() bool {
+	if p.draining {
+		return p.idle.Size() > 0
+	}
 	return p.idle.Size() > p.maxIdle || (p.idle.Size() > 0 && p.sizeLocked() > p.capacity)
 }
 
 func (p *simpleObjectPool) peekOverfullIdle() *poolItem {
 	p.lock.Lock()
 	defer p.lock.Unlock()
+	p.releaseQuarantineLocked()
 
 	if !p.tooManyIdleLocked() {
 		return nil
 	}
 
-	item := p.idle.Peek()
+	item := p.peekUnpinnedLocked()
 	if item == nil {
 		return nil
 	}
 
-	if item.reverse.After(time.Now()) {
+	if item.reverse.After(p.clock.Now()) {
 		return nil
 	}
-	return p.idle.Pop()
+	if !p.takeDrainLocked() {
+		return nil
+	}
+	return p.idle.Rob(item.res.GetResourceID())
+}
+
+// peekUnpinnedLocked return idle resource of earliest reverse not pinned
+func (p *simpleObjectPool) peekUnpinnedLocked() *poolItem {
+	if len(p.pinned) == 0 {
+		return p.idle.Peek()
+	}
+	var earliest *poolItem
+	for i := 0; i < p.idle.size; i++ {
+		item := p.idle.slots[i]
+		if p.pinned[item.res.GetResourceID()] {
+			continue
+		}
+		if earliest == nil || item.lessThan(earliest) {
+			earliest = item
+		}
+	}
+	return earliest
+}
+
+// takeDrainLocked return whether one more overfull idle resource can be disposed in window of drain rate,
+// otherwise check idle again once window passed
+func (p *simpleObjectPool) takeDrainLocked() bool {
+	if p.drainRate <= 0 {
+		return true
+	}
+	now := p.clock.Now()
+	if now.Sub(p.drainStart) >= drainWindow {
+		p.drainStart, p.drained = now, 0
+	}
+	if p.drained < p.drainRate {
+		p.drained++
+		return true
+	}
+	if !p.drainScheduled {
+		p.drainScheduled = true
+		log.Infof("dispose of overfull idle resources deferred by drain rate %d per minute, idle: %d, max idle: %d",
+			p.drainRate, p.idle.Size(), p.maxIdle)
+		time.AfterFunc(p.drainStart.Add(drainWindow).Sub(now), func() {
+			p.lock.Lock()
+			p.drainScheduled = false
+			p.lock.Unlock()
+			p.notify()
+		})
+	}
+	return false
 }
 
 //found resources that can be disposed, put them into dispose channel
 func (p *simpleObjectPool) checkIdle() {
+	if batch, ok := p.factory.(BatchFactory); ok && p.chunkSize > 1 {
+		p.checkIdleInChunk(batch)
+		return
+	}
 	for {
 		item := p.peekOverfullIdle()
 		if item == nil {
@@ -199,6 +441,7 @@ func (p *simpleObjectPool) checkIdle() {
 		log.Infof("try dispose res %+v", res)
 		err := p.factory.Dispose(res)
 		if err == nil {
+			p.forget(res.GetResourceID())
 			p.tokenCh <- struct{}{}
 		} else {
 			log.Warnf("error dispose res: %+v", err)
@@ -208,26 +451,293 @@ func (p *simpleObjectPool) checkIdle() {
 	}
 }
 
-func (p *simpleObjectPool) preload() error {
-	p.lock.Lock()
-	defer p.lock.Unlock()
+// checkIdleInChunk dispose overfull idle resources in chunk by batch factory
+func (p *simpleObjectPool) checkIdleInChunk(batch BatchFactory) {
 	for {
-		// init resource sequential to avoid huge creating request on startup
-		if p.idle.Size() >= p.minIdle {
-			break
+		var resources []types.NetworkResource
+		for len(resources) < p.chunkSize {
+			item := p.peekOverfullIdle()
+			if item == nil {
+				break
+			}
+			resources = append(resources, item.res)
+		}
+		if len(resources) == 0 {
+			return
 		}
 
-		if p.sizeLocked() >= p.capacity {
-			break
+		log.Infof("try dispose %d res in chunk", len(resources))
+		failed, err := batch.DisposeBatch(resources)
+		if err != nil {
+			log.Warnf("error dispose %d of %d res: %+v", len(failed), len(resources), err)
+		}
+		for _, res := range failed {
+			p.AddIdle(res)
+		}
+		p.forgetDisposed(resources, failed)
+		for i := len(failed); i < len(resources); i++ {
+			p.tokenCh <- struct{}{}
+		}
+		if len(failed) == len(resources) {
+			return
+		}
+	}
+}
+
+// create resource for acquire, batch factory creates up to a chunk with the rest put to idle
+// without exceeding max idle
+func (p *simpleObjectPool) create(ctx context.Context) (types.NetworkResource, error) {
+	if err := p.beginCreate(ctx); err != nil {
+		return nil, err
+	}
+	defer p.endCreate()
+	batch, ok := p.factory.(BatchFactory)
+	if !ok || p.chunkSize <= 1 {
+		return p.factory.Create()
+	}
+
+	extra := p.takeTokens(p.refillChunk() - 1)
+	resources, err := batch.CreateBatch(1 + extra)
+	if err == nil && len(resources) == 0 {
+		err = ErrNoAvailableResource
+	}
+	if err != nil {
+		for i := 0; i < extra; i++ {
+			p.tokenCh <- struct{}{}
 		}
+		return nil, err
+	}
+	for i := len(resources); i < 1+extra; i++ {
+		p.tokenCh <- struct{}{}
+	}
+	for _, res := range resources[1:] {
+		p.AddIdle(res)
+	}
+	return resources[0], nil
+}
 
-		res, err := p.factory.Create()
+// createWithin create resource for acquire within ctx, the token of acquire returned on error, resource created
+// after ctx done handed to idle for later acquires instead of left in limbo, eg: eni attached after cni timed out
+func (p *simpleObjectPool) createWithin(ctx context.Context) (types.NetworkResource, error) {
+	type result struct {
+		res types.NetworkResource
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		res, err := p.create(ctx)
+		ch <- result{res: res, err: err}
+	}()
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			p.tokenCh <- struct{}{}
+		}
+		return r.res, r.err
+	case <-ctx.Done():
+		go func() {
+			r := <-ch
+			if r.err != nil {
+				p.tokenCh <- struct{}{}
+				return
+			}
+			log.Infof("%s created after acquire done, put to idle", r.res.GetResourceID())
+			p.AddIdle(r.res)
+		}()
+		return nil, ErrContextDone
+	}
+}
+
+// beginCreate wait for a slot of concurrent creates if bounded
+func (p *simpleObjectPool) beginCreate(ctx context.Context) error {
+	if p.createCh == nil {
+		return nil
+	}
+	select {
+	case p.createCh <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ErrContextDone
+	}
+}
+
+// endCreate free the slot of concurrent creates taken by beginCreate
+func (p *simpleObjectPool) endCreate() {
+	if p.createCh != nil {
+		<-p.createCh
+	}
+}
+
+// takeTokens take tokens for at most count extra idle resources without waiting
+func (p *simpleObjectPool) takeTokens(count int) int {
+	p.lock.Lock()
+	if room := p.maxIdle - p.idle.Size(); count > room {
+		count = room
+	}
+	p.lock.Unlock()
+	if count <= 0 {
+		return 0
+	}
+	for taken := 0; taken < count; taken++ {
+		select {
+		case <-p.tokenCh:
+		default:
+			return taken
+		}
+	}
+	return count
+}
+
+// Prewarm create resources in background until count idle, for demand expected ahead of acquire,
+// eg: pods scheduled to node pending network setup
+func (p *simpleObjectPool) Prewarm(count int) {
+	if count > p.maxIdle {
+		count = p.maxIdle
+	}
+	p.lock.Lock()
+	need := count - p.idle.Size() - p.prewarming
+	if p.draining {
+		need = 0
+	}
+	p.lock.Unlock()
+	if need <= 0 {
+		return
+	}
+	tokens := p.takeTokens(need)
+	if tokens == 0 {
+		return
+	}
+	p.lock.Lock()
+	p.prewarming += tokens
+	p.lock.Unlock()
+	log.Infof("prewarm %d res for demand %d", tokens, count)
+	workers := p.parallelCreates
+	if workers > tokens {
+		workers = tokens
+	}
+	for i := 0; i < workers; i++ {
+		// share tokens among workers, the first ones take the remainder
+		share := tokens / workers
+		if i < tokens%workers {
+			share++
+		}
+		go p.prewarm(share)
+	}
+}
+
+// fillFactory return factory creating idle resources not acquired yet, the background view if supported
+func (p *simpleObjectPool) fillFactory() ObjectFactory {
+	if background, ok := p.factory.(BackgroundFactory); ok {
+		return background.Background()
+	}
+	return p.factory
+}
+
+func (p *simpleObjectPool) prewarm(count int) {
+	factory := p.fillFactory()
+	batch, isBatch := factory.(BatchFactory)
+	for count > 0 {
+		n := 1
+		var (
+			resources []types.NetworkResource
+			err       error
+		)
+		if isBatch && p.chunkSize > 1 {
+			if n = p.refillChunk(); n > count {
+				n = count
+			}
+			p.beginCreate(context.Background())
+			resources, err = batch.CreateBatch(n)
+			p.endCreate()
+		} else {
+			var res types.NetworkResource
+			p.beginCreate(context.Background())
+			if res, err = factory.Create(); err == nil {
+				resources = append(resources, res)
+			}
+			p.endCreate()
+		}
 		if err != nil {
-			return err
+			log.Warnf("error prewarm res: %v", err)
+			break
+		}
+		for _, res := range resources {
+			p.AddIdle(res)
+		}
+		for i := len(resources); i < n; i++ {
+			p.tokenCh <- struct{}{}
+		}
+		p.lock.Lock()
+		p.prewarming -= n
+		p.lock.Unlock()
+		count -= n
+		if delay := p.refillDelay(); delay > 0 && count > 0 {
+			time.Sleep(delay)
+		}
+	}
+	// tokens of resources not created on error
+	p.lock.Lock()
+	p.prewarming -= count
+	p.lock.Unlock()
+	for i := 0; i < count; i++ {
+		p.tokenCh <- struct{}{}
+	}
+}
+
+func (p *simpleObjectPool) preload() error {
+	p.lock.Lock()
+	need := p.minIdle - p.idle.Size()
+	if room := p.capacity - p.sizeLocked(); need > room {
+		need = room
+	}
+	p.lock.Unlock()
+
+	// init resource with bounded concurrency to avoid huge creating request on startup
+	factory := p.fillFactory()
+	jobs := make(chan struct{}, p.parallelCreates)
+	go func() {
+		for i := 0; i < need; i++ {
+			jobs <- struct{}{}
 		}
-		p.idle.Push(&poolItem{res: res, reverse: time.Now()})
+		close(jobs)
+	}()
+	var (
+		wg      sync.WaitGroup
+		errLock sync.Mutex
+		err     error
+	)
+	for i := 0; i < p.parallelCreates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				errLock.Lock()
+				failed := err != nil
+				errLock.Unlock()
+				if failed {
+					continue
+				}
+				if delay := p.refillDelay(); delay > 0 {
+					time.Sleep(delay)
+				}
+				res, createErr := factory.Create()
+				if createErr != nil {
+					errLock.Lock()
+					err = createErr
+					errLock.Unlock()
+					continue
+				}
+				p.AddIdle(res)
+			}
+		}()
+	}
+	wg.Wait()
+	if err != nil {
+		return err
 	}
 
+	p.lock.Lock()
+	defer p.lock.Unlock()
 	tokenCount := p.capacity - p.sizeLocked()
 	for i := 0; i < tokenCount; i++ {
 		p.tokenCh <- struct{}{}
@@ -236,30 +746,148 @@ func (p *simpleObjectPool) preload() error {
 	return nil
 }
 
+// throttleLevel level of openapi throttling bounded by max level, 0 if not throttle aware
+func (p *simpleObjectPool) throttleLevel() int {
+	if p.throttle == nil {
+		return 0
+	}
+	level := p.throttle()
+	if level < 0 {
+		return 0
+	}
+	if level > maxThrottleLevel {
+		return maxThrottleLevel
+	}
+	return level
+}
+
+// refillChunk count of resources created in a chunk, halved each throttle level
+func (p *simpleObjectPool) refillChunk() int {
+	chunk := p.chunkSize >> uint(p.throttleLevel())
+	if chunk < 1 {
+		return 1
+	}
+	return chunk
+}
+
+// refillDelay delay between background creates, doubled each throttle level, 0 if not throttled
+func (p *simpleObjectPool) refillDelay() time.Duration {
+	level := p.throttleLevel()
+	if level == 0 {
+		return 0
+	}
+	return throttleRefillDelay << uint(level-1)
+}
+
 func (p *simpleObjectPool) sizeLocked() int {
-	return p.idle.Size() + len(p.inuse)
+	return p.idle.Size() + len(p.inuse) + len(p.quarantine)
+}
+
+// releaseQuarantineLocked move resources finished cool-down and not denied to idle
+func (p *simpleObjectPool) releaseQuarantineLocked() {
+	now := p.clock.Now()
+	for id, item := range p.quarantine {
+		if !item.until.After(now) && !p.denied(item.res) {
+			delete(p.quarantine, id)
+			p.idle.Push(item.poolItem)
+		}
+	}
 }
 
-func (p *simpleObjectPool) getOneLocked(resID string) *poolItem {
+// quarantineDeniedLocked move denied idle resource to quarantine
+func (p *simpleObjectPool) quarantineDeniedLocked(item *poolItem) {
+	log.Infof("resource %s denied, move to quarantine", item.res.GetResourceID())
+	p.quarantine[item.res.GetResourceID()] = &quarantineItem{poolItem: item, until: p.clock.Now()}
+}
+
+// getOneLocked return idle resource of id preferred or chosen by hints, denied resources skipped, nil if no idle
+func (p *simpleObjectPool) getOneLocked(hints AcquireHints) *poolItem {
+	resID := hints.Prefer
 	if len(resID) > 0 {
 		item := p.idle.Rob(resID)
 		if item != nil {
+			if !p.denied(item.res) {
+				return item
+			}
+			p.quarantineDeniedLocked(item)
+		}
+	}
+	for p.idle.Size() > 0 {
+		var item *poolItem
+		if hints.Score == nil {
+			item = p.idle.Pop()
+		} else {
+			item = p.idle.Rob(p.bestIdleLocked(hints.Score).res.GetResourceID())
+		}
+		if !p.denied(item.res) {
 			return item
 		}
+		p.quarantineDeniedLocked(item)
+	}
+	return nil
+}
+
+// bestIdleLocked return idle resource of the highest score, the earliest reverse on ties
+func (p *simpleObjectPool) bestIdleLocked(score func(res types.NetworkResource) int) *poolItem {
+	var (
+		best      *poolItem
+		bestScore int
+	)
+	for i := 0; i < p.idle.size; i++ {
+		item := p.idle.slots[i]
+		itemScore := score(item.res)
+		if best == nil || itemScore > bestScore || (itemScore == bestScore && item.lessThan(best)) {
+			best, bestScore = item, itemScore
+		}
 	}
-	return p.idle.Pop()
+	return best
 }
 
 func (p *simpleObjectPool) Acquire(ctx context.Context, resID string) (types.NetworkResource, error) {
+	return p.AcquireWithHints(ctx, AcquireHints{Prefer: resID})
+}
+
+// AcquireWithHints acquire the resource preferred if available, otherwise the idle one of the highest score
+func (p *simpleObjectPool) AcquireWithHints(ctx context.Context, hints AcquireHints) (types.NetworkResource, error) {
+	resID := hints.Prefer
+	start := time.Now()
+	var createStart time.Time
+	defer func() {
+		if createStart.IsZero() {
+			observePhase(ctx, PhaseWait, time.Since(start))
+			tracing.Record(ctx, "pool/wait", tracing.KindInternal, start, time.Now(), nil)
+			return
+		}
+		observePhase(ctx, PhaseWait, createStart.Sub(start))
+		observePhase(ctx, PhaseCreate, time.Since(createStart))
+		tracing.Record(ctx, "pool/wait", tracing.KindInternal, start, createStart, nil)
+		tracing.Record(ctx, "pool/create", tracing.KindInternal, createStart, time.Now(), nil)
+	}()
 	p.lock.Lock()
 	//defer p.lock.Unlock()
-	if p.idle.Size() > 0 {
-		res := p.getOneLocked(resID).res
+	p.releaseQuarantineLocked()
+	// the resource in cool-down can still be taken back by its owner
+	if item, ok := p.quarantine[resID]; ok && !p.denied(item.res) {
+		delete(p.quarantine, resID)
+		p.inuse[resID] = item.res
+		p.touchLocked(resID, true)
+		p.lock.Unlock()
+		log.Infof("acquire (expect %s): return quarantined %s", resID, resID)
+		return item.res, nil
+	}
+	if item := p.getOneLocked(hints); item != nil {
+		res := item.res
 		p.inuse[res.GetResourceID()] = res
+		p.touchLocked(res.GetResourceID(), true)
 		p.lock.Unlock()
 		log.Infof("acquire (expect %s): return idle %s", resID, res.GetResourceID())
 		return res, nil
 	}
+	if p.draining {
+		p.lock.Unlock()
+		log.Infof("acquire (expect %s): return err %v", resID, ErrDraining)
+		return nil, ErrDraining
+	}
 	size := p.sizeLocked()
 	if size >= p.capacity {
 		p.lock.Unlock()
@@ -271,11 +899,10 @@ func (p *simpleObjectPool) Acquire(ctx context.Context, resID string) (types.Net
 
 	select {
 	case <-p.tokenCh:
-		//should we pass ctx into factory.Create?
-		res, err := p.factory.Create()
+		createStart = time.Now()
+		res, err := p.createWithin(ctx)
 		if err != nil {
-			p.tokenCh <- struct{}{}
-			return nil, fmt.Errorf("error create from factory: %v", err)
+			return nil, errors.Wrapf(err, "error create from factory")
 		}
 		log.Infof("acquire (expect %s): return newly %s", resID, res.GetResourceID())
 		p.AddInuse(res)
@@ -286,6 +913,33 @@ func (p *simpleObjectPool) Acquire(ctx context.Context, resID string) (types.Net
 	}
 }
 
+// Transfer return resource of id as inuse without release, kept as is if already inuse, the idle or quarantined
+// one taken back unless denied, ErrNotFound otherwise
+func (p *simpleObjectPool) Transfer(resID string) (types.NetworkResource, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if res, ok := p.inuse[resID]; ok {
+		p.touchLocked(resID, true)
+		log.Infof("transfer %s: return inuse", resID)
+		return res, nil
+	}
+	if item, ok := p.quarantine[resID]; ok && !p.denied(item.res) {
+		delete(p.quarantine, resID)
+		p.inuse[resID] = item.res
+		p.touchLocked(resID, true)
+		log.Infof("transfer %s: return quarantined", resID)
+		return item.res, nil
+	}
+	if item := p.idle.Find(resID); item != nil && !p.denied(item.res) {
+		p.idle.Rob(resID)
+		p.inuse[resID] = item.res
+		p.touchLocked(resID, true)
+		log.Infof("transfer %s: return idle", resID)
+		return item.res, nil
+	}
+	return nil, ErrNotFound
+}
+
 func (p *simpleObjectPool) AcquireAny(ctx context.Context) (types.NetworkResource, error) {
 	return p.Acquire(ctx, "")
 }
@@ -302,9 +956,30 @@ func (p *simpleObjectPool) Stat(resID string) error {
 		return nil
 	}
 
+	if _, ok = p.quarantine[resID]; ok {
+		return nil
+	}
+
 	return ErrNotFound
 }
 
+// GetResourceIDs return ids of idle and inuse resources in pool
+func (p *simpleObjectPool) GetResourceIDs() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	ids := make([]string, 0, p.sizeLocked())
+	for id := range p.inuse {
+		ids = append(ids, id)
+	}
+	for i := 0; i < p.idle.size; i++ {
+		ids = append(ids, p.idle.slots[i].res.GetResourceID())
+	}
+	for id := range p.quarantine {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func (p *simpleObjectPool) notify() {
 	select {
 	case p.notifyCh <- true:
@@ -313,6 +988,19 @@ func (p *simpleObjectPool) notify() {
 }
 
 func (p *simpleObjectPool) ReleaseWithReverse(resID string, reverse time.Duration) error {
+	return p.release(resID, reverse, p.quarantinePeriod)
+}
+
+// ReleaseWithQuarantine release resource to pool, it can not be acquired by others in period
+// or the quarantine period of pool if longer
+func (p *simpleObjectPool) ReleaseWithQuarantine(resID string, reverse, period time.Duration) error {
+	if period < p.quarantinePeriod {
+		period = p.quarantinePeriod
+	}
+	return p.release(resID, reverse, period)
+}
+
+func (p *simpleObjectPool) release(resID string, reverse, quarantine time.Duration) error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	res, ok := p.inuse[resID]
@@ -321,28 +1009,305 @@ func (p *simpleObjectPool) ReleaseWithReverse(resID string, reverse time.Duratio
 		return ErrInvalidState
 	}
 
-	log.Infof("release %s, reverse %v: return success", resID, reverse)
+	log.Infof("release %s, reverse %v, quarantine %v: return success", resID, reverse, quarantine)
 	delete(p.inuse, resID)
-	reverseTo := time.Now()
+	p.touchLocked(resID, true)
+	now := p.clock.Now()
+	reverseTo := now
 	if reverse > 0 {
 		reverseTo = reverseTo.Add(reverse)
 	}
-	p.idle.Push(&poolItem{res: res, reverse: reverseTo})
+	item := &poolItem{res: res, reverse: reverseTo}
+	if quarantine > 0 || p.denied(res) {
+		p.quarantine[resID] = &quarantineItem{poolItem: item, until: now.Add(quarantine)}
+		return nil
+	}
+	p.idle.Push(item)
 	p.notify()
 	return nil
 }
+
 func (p *simpleObjectPool) Release(resID string) error {
 	return p.ReleaseWithReverse(resID, time.Duration(0))
 }
 
+// Dispose remove inuse resource from pool and dispose it by factory, eg: resource is broken
+func (p *simpleObjectPool) Dispose(resID string) error {
+	p.lock.Lock()
+	res, ok := p.inuse[resID]
+	if !ok {
+		p.lock.Unlock()
+		log.Infof("dispose %s: return err %v", resID, ErrInvalidState)
+		return ErrInvalidState
+	}
+	delete(p.inuse, resID)
+	delete(p.pinned, resID)
+	p.lock.Unlock()
+
+	log.Infof("try dispose res %+v", res)
+	if err := p.factory.Dispose(res); err != nil {
+		log.Warnf("failed dispose %s: %v, put it back to inuse", resID, err)
+		p.AddInuse(res)
+		return err
+	}
+	p.forget(resID)
+	p.tokenCh <- struct{}{}
+	return nil
+}
+
+// Drain stop creating resources and dispose all idle and quarantined ones regardless of max idle, pins, reverse
+// and drain rate, resources failed to dispose kept idle, the ones released later disposed on idle check
+func (p *simpleObjectPool) Drain() (int, error) {
+	p.lock.Lock()
+	p.draining = true
+	p.lock.Unlock()
+	batch, isBatch := p.factory.(BatchFactory)
+	step := 1
+	if isBatch && p.chunkSize > 1 {
+		step = p.chunkSize
+	}
+	disposed := 0
+	// disposed in rounds, eg: primary ip of eni disposable once its secondary ips disposed
+	for {
+		resources := p.takeAllIdle()
+		if len(resources) == 0 {
+			return disposed, nil
+		}
+		log.Infof("drain %d res", len(resources))
+		var (
+			failed  []types.NetworkResource
+			lastErr error
+		)
+		for start := 0; start < len(resources); start += step {
+			end := start + step
+			if end > len(resources) {
+				end = len(resources)
+			}
+			chunk := resources[start:end]
+			var (
+				chunkFailed []types.NetworkResource
+				err         error
+			)
+			if step > 1 {
+				chunkFailed, err = batch.DisposeBatch(chunk)
+			} else if err = p.factory.Dispose(chunk[0]); err != nil {
+				chunkFailed = chunk
+			}
+			if err != nil {
+				log.Warnf("error drain %d of %d res: %v", len(chunkFailed), len(chunk), err)
+				lastErr = err
+			}
+			p.forgetDisposed(chunk, chunkFailed)
+			for i := len(chunkFailed); i < len(chunk); i++ {
+				p.tokenCh <- struct{}{}
+			}
+			failed = append(failed, chunkFailed...)
+		}
+		for _, res := range failed {
+			p.AddIdle(res)
+		}
+		disposed += len(resources) - len(failed)
+		if len(failed) == len(resources) {
+			return disposed, lastErr
+		}
+	}
+}
+
+// takeAllIdle take all idle and quarantined resources out of pool, pins dropped
+func (p *simpleObjectPool) takeAllIdle() []types.NetworkResource {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var resources []types.NetworkResource
+	for id, item := range p.quarantine {
+		delete(p.quarantine, id)
+		delete(p.pinned, id)
+		resources = append(resources, item.res)
+	}
+	for p.idle.Size() > 0 {
+		item := p.idle.Pop()
+		delete(p.pinned, item.res.GetResourceID())
+		resources = append(resources, item.res)
+	}
+	return resources
+}
+
+// Stats return count of resources in pool by state
+func (p *simpleObjectPool) Stats() Stats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.releaseQuarantineLocked()
+	return Stats{
+		Capacity:   p.capacity,
+		Inuse:      len(p.inuse),
+		Idle:       p.idle.Size(),
+		Quarantine: len(p.quarantine),
+	}
+}
+
+// Snapshot return ids of resources in pool by state
+func (p *simpleObjectPool) Snapshot() Snapshot {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.releaseQuarantineLocked()
+	snapshot := Snapshot{}
+	for id := range p.inuse {
+		snapshot.Inuse = append(snapshot.Inuse, id)
+	}
+	for i := 0; i < p.idle.size; i++ {
+		snapshot.Idle = append(snapshot.Idle, p.idle.slots[i].res.GetResourceID())
+	}
+	for id := range p.quarantine {
+		snapshot.Quarantine = append(snapshot.Quarantine, id)
+	}
+	for id := range p.pinned {
+		snapshot.Pinned = append(snapshot.Pinned, id)
+	}
+	sort.Strings(snapshot.Inuse)
+	sort.Strings(snapshot.Idle)
+	sort.Strings(snapshot.Quarantine)
+	sort.Strings(snapshot.Pinned)
+	return snapshot
+}
+
+// Evict remove idle or quarantined resource vanished outside of pool without disposing by factory,
+// resources in use can not be evicted
+func (p *simpleObjectPool) Evict(resID string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.inuse[resID]; ok {
+		return ErrInvalidState
+	}
+	if _, ok := p.quarantine[resID]; ok {
+		delete(p.quarantine, resID)
+	} else if p.idle.Rob(resID) == nil {
+		return ErrNotFound
+	}
+	log.Infof("evict res %s vanished outside pool", resID)
+	delete(p.pinned, resID)
+	delete(p.meta, resID)
+	p.tokenCh <- struct{}{}
+	return nil
+}
+
+// Adopt add resource found outside of pool as idle, fail if already in pool or pool full
+func (p *simpleObjectPool) Adopt(res types.NetworkResource) error {
+	if p.Stat(res.GetResourceID()) == nil {
+		return ErrInvalidState
+	}
+	select {
+	case <-p.tokenCh:
+	default:
+		return ErrNoAvailableResource
+	}
+	log.Infof("adopt res %s found outside pool", res.GetResourceID())
+	p.AddIdle(res)
+	p.notify()
+	return nil
+}
+
+// Inventory return resources in pool with state and age, sorted by id
+func (p *simpleObjectPool) Inventory() []InventoryItem {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.releaseQuarantineLocked()
+	items := make([]InventoryItem, 0, p.sizeLocked())
+	add := func(res types.NetworkResource, state string) {
+		item := InventoryItem{
+			ID:       res.GetResourceID(),
+			Type:     res.GetType(),
+			State:    state,
+			Pinned:   p.pinned[res.GetResourceID()],
+			Resource: res,
+		}
+		if meta, ok := p.meta[item.ID]; ok {
+			item.Created, item.LastUsed = meta.created, meta.lastUsed
+		}
+		items = append(items, item)
+	}
+	for _, res := range p.inuse {
+		add(res, StateInuse)
+	}
+	for i := 0; i < p.idle.size; i++ {
+		add(p.idle.slots[i].res, StateIdle)
+	}
+	for _, item := range p.quarantine {
+		add(item.res, StateQuarantine)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+// touchLocked record resource added to pool, and used if used
+func (p *simpleObjectPool) touchLocked(resID string, used bool) {
+	meta, ok := p.meta[resID]
+	if !ok {
+		meta = &resourceMeta{created: p.clock.Now()}
+		p.meta[resID] = meta
+	}
+	if used {
+		meta.lastUsed = p.clock.Now()
+	}
+}
+
+// forget drop age of resource disposed
+func (p *simpleObjectPool) forget(resID string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.meta, resID)
+}
+
+// forgetDisposed drop age of resources disposed in chunk except the failed ones
+func (p *simpleObjectPool) forgetDisposed(resources, failed []types.NetworkResource) {
+	failedIDs := make(map[string]bool, len(failed))
+	for _, res := range failed {
+		failedIDs[res.GetResourceID()] = true
+	}
+	for _, res := range resources {
+		if !failedIDs[res.GetResourceID()] {
+			p.forget(res.GetResourceID())
+		}
+	}
+}
+
+// Pin mark resource in pool never disposed as overfull idle until unpinned, still allocated as usual
+func (p *simpleObjectPool) Pin(resID string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	_, inuse := p.inuse[resID]
+	_, quarantined := p.quarantine[resID]
+	if !inuse && !quarantined && p.idle.Find(resID) == nil {
+		return ErrNotFound
+	}
+	log.Infof("pin res %s", resID)
+	p.pinned[resID] = true
+	return nil
+}
+
+// Unpin make pinned resource disposable again, the overfull idle resources are checked
+func (p *simpleObjectPool) Unpin(resID string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.pinned[resID] {
+		return ErrNotFound
+	}
+	log.Infof("unpin res %s", resID)
+	delete(p.pinned, resID)
+	p.notify()
+	return nil
+}
+
 func (p *simpleObjectPool) AddIdle(resource types.NetworkResource) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	p.idle.Push(&poolItem{res: resource, reverse: time.Now()})
+	p.idle.Push(&poolItem{res: resource, reverse: p.clock.Now()})
+	p.touchLocked(resource.GetResourceID(), false)
 }
 
 func (p *simpleObjectPool) AddInuse(res types.NetworkResource) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	p.inuse[res.GetResourceID()] = res
+	p.touchLocked(res.GetResourceID(), true)
 }
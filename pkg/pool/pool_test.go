@@ -3,11 +3,14 @@ package pool
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/AliyunContainerService/terway/pkg/pool/pooltest"
 	"github.com/AliyunContainerService/terway/types"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -74,21 +77,27 @@ func (f *mockObjectFactory) getTotalCreated() int {
 
 func TestInitializerWithoutAutoCreate(t *testing.T) {
 	factory := &mockObjectFactory{}
-	createPool(factory, 3, 0)
-	time.Sleep(time.Second)
+	ticker := pooltest.NewTicker()
+	createPoolWithTick(factory, 3, 0, ticker.C)
+	ticker.Sync(t, time.Second)
 	assert.Equal(t, 0, factory.getTotalCreated())
 	assert.Equal(t, 0, factory.getTotalDisposed())
 }
 
 func TestInitializerWithAutoCreate(t *testing.T) {
 	factory := &mockObjectFactory{}
-	createPool(factory, 0, 0)
-	time.Sleep(time.Second)
+	ticker := pooltest.NewTicker()
+	createPoolWithTick(factory, 0, 0, ticker.C)
+	ticker.Sync(t, time.Second)
 	assert.Equal(t, 3, factory.getTotalCreated())
 	assert.Equal(t, 0, factory.getTotalDisposed())
 }
 
 func createPool(factory ObjectFactory, initIdle, initInuse int) ObjectPool {
+	return createPoolWithTick(factory, initIdle, initInuse, nil)
+}
+
+func createPoolWithTick(factory ObjectFactory, initIdle, initInuse int, tick <-chan time.Time) ObjectPool {
 	id := 0
 	cfg := Config{
 		Factory: factory,
@@ -106,6 +115,7 @@ func createPool(factory ObjectFactory, initIdle, initInuse int) ObjectPool {
 		MinIdle:  3,
 		MaxIdle:  5,
 		Capacity: 10,
+		Tick:     tick,
 	}
 	pool, err := NewSimpleObjectPool(cfg)
 	if err != nil {
@@ -116,16 +126,18 @@ func createPool(factory ObjectFactory, initIdle, initInuse int) ObjectPool {
 
 func TestInitializerExceedMaxIdle(t *testing.T) {
 	factory := &mockObjectFactory{}
-	createPool(factory, 6, 0)
-	time.Sleep(1 * time.Second)
+	ticker := pooltest.NewTicker()
+	createPoolWithTick(factory, 6, 0, ticker.C)
+	ticker.Sync(t, time.Second)
 	assert.Equal(t, 0, factory.getTotalCreated())
 	assert.Equal(t, 1, factory.getTotalDisposed())
 }
 
 func TestInitializerExceedCapacity(t *testing.T) {
 	factory := &mockObjectFactory{}
-	createPool(factory, 1, 10)
-	time.Sleep(time.Second)
+	ticker := pooltest.NewTicker()
+	createPoolWithTick(factory, 1, 10, ticker.C)
+	ticker.Sync(t, time.Second)
 	assert.Equal(t, 0, factory.getTotalCreated())
 	assert.Equal(t, 1, factory.getTotalDisposed())
 }
@@ -196,7 +208,8 @@ func TestRelease(t *testing.T) {
 	factory := &mockObjectFactory{
 		createDelay: 1 * time.Millisecond,
 	}
-	pool := createPool(factory, 3, 0)
+	ticker := pooltest.NewTicker()
+	pool := createPoolWithTick(factory, 3, 0, ticker.C)
 	n1, _ := pool.Acquire(context.Background(), "")
 	n2, _ := pool.Acquire(context.Background(), "")
 	n3, _ := pool.Acquire(context.Background(), "")
@@ -207,14 +220,14 @@ func TestRelease(t *testing.T) {
 	pool.Release(n1.GetResourceID())
 	pool.Release(n2.GetResourceID())
 	pool.Release(n3.GetResourceID())
-	time.Sleep(1 * time.Second)
+	ticker.Sync(t, time.Second)
 	assert.Equal(t, 0, factory.getTotalDisposed())
 	pool.Release(n4.GetResourceID())
 	pool.Release(n5.GetResourceID())
-	time.Sleep(1 * time.Second)
+	ticker.Sync(t, time.Second)
 	assert.Equal(t, 0, factory.getTotalDisposed())
 	pool.Release(n6.GetResourceID())
-	time.Sleep(1 * time.Second)
+	ticker.Sync(t, time.Second)
 	assert.Equal(t, 1, factory.getTotalDisposed())
 }
 
@@ -224,3 +237,507 @@ func TestReleaseInvalid(t *testing.T) {
 	err := pool.Release("not-exists")
 	assert.Equal(t, err, ErrInvalidState)
 }
+
+func TestDispose(t *testing.T) {
+	factory := &mockObjectFactory{}
+	pool := createPool(factory, 3, 0)
+	res, err := pool.Acquire(context.Background(), "")
+	assert.Nil(t, err)
+	err = pool.Dispose(res.GetResourceID())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, factory.getTotalDisposed())
+	assert.Equal(t, ErrNotFound, pool.Stat(res.GetResourceID()))
+	assert.Equal(t, ErrInvalidState, pool.Dispose("not-exists"))
+}
+
+func TestReleaseWithQuarantine(t *testing.T) {
+	clock := pooltest.NewClock()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory:     pooltest.NewFactory(),
+		Initializer: func(holder ResourceHolder) error { holder.AddInuse(mockNetworkResource{"1"}); return nil },
+		MaxIdle:     5,
+		Capacity:    10,
+		Clock:       clock,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, pool.ReleaseWithQuarantine("1", 0, time.Minute))
+	assert.Nil(t, pool.Stat("1"))
+	res, err := pool.AcquireAny(context.Background())
+	assert.Nil(t, err)
+	assert.NotEqual(t, "1", res.GetResourceID())
+
+	// owner can take back the quarantined resource
+	res, err = pool.Acquire(context.Background(), "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", res.GetResourceID())
+
+	assert.Nil(t, pool.ReleaseWithQuarantine("1", 0, time.Minute))
+	clock.Step(time.Minute)
+	res, err = pool.AcquireAny(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "1", res.GetResourceID())
+}
+
+func TestTransfer(t *testing.T) {
+	factory := &mockObjectFactory{}
+	pool := createPool(factory, 1, 2)
+
+	// inuse kept for the new owner
+	res, err := pool.Transfer("2")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", res.GetResourceID())
+	// idle taken as inuse
+	res, err = pool.Transfer("1")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", res.GetResourceID())
+	assert.Nil(t, pool.Release("1"))
+	// quarantined taken back
+	assert.Nil(t, pool.ReleaseWithQuarantine("3", 0, time.Minute))
+	res, err = pool.Transfer("3")
+	assert.Nil(t, err)
+	assert.Equal(t, "3", res.GetResourceID())
+	assert.Nil(t, pool.Release("3"))
+
+	_, err = pool.Transfer("not-exists")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestQuarantinePeriod(t *testing.T) {
+	clock := pooltest.NewClock()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: pooltest.NewFactory(),
+		Initializer: func(holder ResourceHolder) error {
+			holder.AddInuse(mockNetworkResource{"1"})
+			holder.AddInuse(mockNetworkResource{"2"})
+			return nil
+		},
+		MaxIdle:          5,
+		Capacity:         10,
+		Clock:            clock,
+		QuarantinePeriod: 30 * time.Second,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, pool.Release("1"))
+	assert.Nil(t, pool.ReleaseWithQuarantine("2", 0, time.Second))
+	assert.Equal(t, Stats{Capacity: 10, Quarantine: 2}, pool.Stats())
+
+	clock.Step(30 * time.Second)
+	assert.Equal(t, Stats{Capacity: 10, Idle: 2}, pool.Stats())
+}
+
+func TestDenied(t *testing.T) {
+	denied := map[string]bool{"1": true}
+	var lock sync.Mutex
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: pooltest.NewFactory(),
+		Initializer: func(holder ResourceHolder) error {
+			holder.AddIdle(mockNetworkResource{"1"})
+			holder.AddInuse(mockNetworkResource{"2"})
+			return nil
+		},
+		MaxIdle:  5,
+		Capacity: 10,
+		Clock:    pooltest.NewClock(),
+		Denied: func(res types.NetworkResource) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return denied[res.GetResourceID()]
+		},
+	})
+	assert.Nil(t, err)
+
+	// denied idle resource is skipped even if expected
+	res, err := pool.Acquire(context.Background(), "1")
+	assert.Nil(t, err)
+	assert.NotEqual(t, "1", res.GetResourceID())
+	assert.Nil(t, pool.Stat("1"))
+
+	// denied resource released is kept in quarantine
+	lock.Lock()
+	denied["2"] = true
+	lock.Unlock()
+	assert.Nil(t, pool.Release("2"))
+	res, err = pool.Acquire(context.Background(), "2")
+	assert.Nil(t, err)
+	assert.NotEqual(t, "2", res.GetResourceID())
+	assert.Equal(t, 2, pool.Stats().Quarantine)
+
+	lock.Lock()
+	delete(denied, "1")
+	lock.Unlock()
+	assert.Equal(t, 1, pool.Stats().Idle)
+	res, err = pool.Acquire(context.Background(), "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", res.GetResourceID())
+}
+
+func TestChunk(t *testing.T) {
+	factory := pooltest.NewFactory()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: factory,
+		Initializer: func(holder ResourceHolder) error {
+			for _, res := range pooltest.NewResources("1", "2", "3", "4", "5", "6") {
+				holder.AddIdle(res)
+			}
+			return nil
+		},
+		MaxIdle:   3,
+		Capacity:  10,
+		ChunkSize: 4,
+	})
+	assert.Nil(t, err)
+	// overfull idle disposed in chunk
+	pooltest.AssertDisposed(t, factory, 3, time.Second)
+
+	for i := 0; i < 3; i++ {
+		_, err = pool.AcquireAny(context.Background())
+		assert.Nil(t, err)
+	}
+	// created in chunk, extra resources not exceed max idle
+	res, err := pool.AcquireAny(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "1001", res.GetResourceID())
+	assert.Equal(t, []string{"1001", "1002", "1003", "1004"}, factory.Created())
+	assert.Equal(t, Stats{Capacity: 10, Inuse: 4, Idle: 3}, pool.Stats())
+}
+
+func TestThrottle(t *testing.T) {
+	factory := pooltest.NewFactory()
+	level := 1
+	pool, err := NewSimpleObjectPool(Config{
+		Factory:   factory,
+		MaxIdle:   3,
+		Capacity:  10,
+		ChunkSize: 4,
+		Throttle:  func() int { return level },
+	})
+	assert.Nil(t, err)
+	// chunk halved by throttle
+	_, err = pool.AcquireAny(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1001", "1002"}, factory.Created())
+
+	p := pool.(*simpleObjectPool)
+	assert.Equal(t, throttleRefillDelay, p.refillDelay())
+	level = 2
+	assert.Equal(t, 1, p.refillChunk())
+	assert.Equal(t, 2*throttleRefillDelay, p.refillDelay())
+	level = 100
+	assert.Equal(t, 16*throttleRefillDelay, p.refillDelay())
+	level = 0
+	assert.Equal(t, 4, p.refillChunk())
+	assert.Equal(t, time.Duration(0), p.refillDelay())
+}
+
+func TestDrainRate(t *testing.T) {
+	factory := pooltest.NewFactory()
+	clock := pooltest.NewClock()
+	ticker := pooltest.NewTicker()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: factory,
+		Initializer: func(holder ResourceHolder) error {
+			for _, res := range pooltest.NewResources("1", "2", "3", "4", "5", "6", "7") {
+				holder.AddIdle(res)
+			}
+			return nil
+		},
+		MaxIdle:   2,
+		Capacity:  10,
+		Clock:     clock,
+		Tick:      ticker.C,
+		DrainRate: 2,
+	})
+	assert.Nil(t, err)
+	// overfull idle disposed at most drain rate in window
+	pooltest.AssertDisposed(t, factory, 2, time.Second)
+	ticker.Sync(t, time.Second)
+	assert.Equal(t, 5, pool.Stats().Idle)
+
+	clock.Step(drainWindow)
+	ticker.Sync(t, time.Second)
+	assert.Len(t, factory.Disposed(), 4)
+
+	clock.Step(drainWindow)
+	ticker.Sync(t, time.Second)
+	assert.Len(t, factory.Disposed(), 5)
+	assert.Equal(t, 2, pool.Stats().Idle)
+}
+
+func TestPrewarm(t *testing.T) {
+	factory := pooltest.NewFactory()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: factory,
+		Initializer: func(holder ResourceHolder) error {
+			holder.AddIdle(pooltest.NewResources("1")[0])
+			return nil
+		},
+		MaxIdle:  3,
+		Capacity: 10,
+	})
+	assert.Nil(t, err)
+
+	// bounded by max idle
+	pool.Prewarm(5)
+	pooltest.AssertCreated(t, factory, 2, time.Second)
+	pooltest.WaitFor(t, time.Second, func() bool {
+		return pool.Stats().Idle == 3
+	}, "3 idle res")
+
+	// enough idle, nothing to create in background
+	pool.Prewarm(2)
+	assert.Len(t, factory.Created(), 2)
+}
+
+// backgroundFactory factory of background view created by another fake factory
+type backgroundFactory struct {
+	*pooltest.Factory
+	background *pooltest.Factory
+}
+
+func (f *backgroundFactory) Background() ObjectFactory {
+	return f.background
+}
+
+func TestPrewarmInBackground(t *testing.T) {
+	factory := &backgroundFactory{Factory: pooltest.NewFactory(), background: pooltest.NewFactory()}
+	pool, err := NewSimpleObjectPool(Config{
+		Factory:  factory,
+		MinIdle:  1,
+		MaxIdle:  3,
+		Capacity: 10,
+	})
+	assert.Nil(t, err)
+	// preloaded by background view
+	pooltest.AssertCreated(t, factory.background, 1, time.Second)
+	_, err = pool.Acquire(context.Background(), "")
+	assert.Nil(t, err)
+	// created for acquire by the factory
+	_, err = pool.Acquire(context.Background(), "")
+	assert.Nil(t, err)
+	pooltest.AssertCreated(t, factory.Factory, 1, time.Second)
+
+	pool.Prewarm(2)
+	pooltest.AssertCreated(t, factory.background, 3, time.Second)
+	assert.Len(t, factory.Created(), 1)
+}
+
+func TestAcquirePhases(t *testing.T) {
+	factory := pooltest.NewFactory()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory:  factory,
+		MaxIdle:  3,
+		Capacity: 10,
+	})
+	assert.Nil(t, err)
+
+	var phases []string
+	ctx := WithPhaseObserver(context.Background(), func(phase string, d time.Duration) {
+		phases = append(phases, phase)
+	})
+	_, err = pool.AcquireAny(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{PhaseWait, PhaseCreate}, phases)
+}
+
+func TestAcquireCreateTimeout(t *testing.T) {
+	factory := pooltest.NewFactory()
+	factory.CreateDelay = 100 * time.Millisecond
+	pool, err := NewSimpleObjectPool(Config{
+		Factory:  factory,
+		MaxIdle:  3,
+		Capacity: 10,
+	})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.AcquireAny(ctx)
+	assert.Equal(t, ErrContextDone, errors.Cause(err))
+	// created after acquire done handed to idle
+	pooltest.WaitFor(t, time.Second, func() bool { return pool.Stats().Idle == 1 }, "resource created late not idle")
+	res, err := pool.AcquireAny(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{res.GetResourceID()}, factory.Created())
+}
+
+func TestParallelCreates(t *testing.T) {
+	factory := pooltest.NewFactory()
+	factory.CreateDelay = 50 * time.Millisecond
+	pool, err := NewSimpleObjectPool(Config{
+		Factory:         factory,
+		MinIdle:         6,
+		MaxIdle:         10,
+		Capacity:        20,
+		ParallelCreates: 3,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, pool.Stats().Idle)
+	assert.Equal(t, 3, factory.MaxConcurrentCreates())
+
+	// refill by prewarm bounded as well
+	pool.Prewarm(10)
+	pooltest.AssertCreated(t, factory, 10, time.Second)
+	assert.Equal(t, 3, factory.MaxConcurrentCreates())
+}
+
+func TestPin(t *testing.T) {
+	factory := pooltest.NewFactory()
+	ticker := pooltest.NewTicker()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: factory,
+		Initializer: func(holder ResourceHolder) error {
+			for _, res := range pooltest.NewResources("1", "2", "3", "4") {
+				holder.AddInuse(res)
+			}
+			return nil
+		},
+		MaxIdle:  1,
+		Capacity: 10,
+		Tick:     ticker.C,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ErrNotFound, pool.Pin("5"))
+	assert.Nil(t, pool.Pin("1"))
+	assert.Nil(t, pool.Pin("2"))
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		assert.Nil(t, pool.Release(id))
+	}
+	ticker.Sync(t, time.Second)
+	assert.ElementsMatch(t, []string{"3", "4"}, factory.Disposed())
+	assert.Equal(t, Snapshot{Idle: []string{"1", "2"}, Pinned: []string{"1", "2"}}, pool.Snapshot())
+
+	assert.Nil(t, pool.Unpin("1"))
+	assert.Equal(t, ErrNotFound, pool.Unpin("1"))
+	ticker.Sync(t, time.Second)
+	assert.ElementsMatch(t, []string{"3", "4", "1"}, factory.Disposed())
+	assert.Equal(t, Snapshot{Idle: []string{"2"}, Pinned: []string{"2"}}, pool.Snapshot())
+}
+
+func TestDrain(t *testing.T) {
+	factory := pooltest.NewFactory()
+	ticker := pooltest.NewTicker()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: factory,
+		Initializer: func(holder ResourceHolder) error {
+			for _, res := range pooltest.NewResources("1", "2", "3") {
+				holder.AddIdle(res)
+			}
+			holder.AddInuse(pooltest.NewResources("4")[0])
+			return nil
+		},
+		MaxIdle:  3,
+		Capacity: 10,
+		Tick:     ticker.C,
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, pool.Pin("1"))
+	assert.Nil(t, pool.ReleaseWithQuarantine("4", 0, time.Hour))
+
+	// pinned and quarantined disposed as well
+	disposed, err := pool.Drain()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, disposed)
+	assert.ElementsMatch(t, []string{"1", "2", "3", "4"}, factory.Disposed())
+	assert.Equal(t, Snapshot{}, pool.Snapshot())
+
+	// no more created
+	_, err = pool.AcquireAny(context.Background())
+	assert.Equal(t, ErrDraining, err)
+	pool.Prewarm(2)
+	assert.Empty(t, factory.Created())
+}
+
+func TestInventory(t *testing.T) {
+	clock := pooltest.NewClock()
+	start := clock.Now()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: pooltest.NewFactory(),
+		Initializer: func(holder ResourceHolder) error {
+			holder.AddIdle(pooltest.NewResources("1")[0])
+			holder.AddInuse(pooltest.NewResources("2")[0])
+			return nil
+		},
+		MaxIdle:  5,
+		Capacity: 10,
+		Clock:    clock,
+	})
+	assert.Nil(t, err)
+
+	clock.Step(time.Minute)
+	_, err = pool.Acquire(context.Background(), "1")
+	assert.Nil(t, err)
+	assert.Nil(t, pool.Pin("1"))
+	clock.Step(time.Minute)
+	assert.Nil(t, pool.Release("2"))
+
+	items := pool.Inventory()
+	assert.Len(t, items, 2)
+	assert.Equal(t, InventoryItem{ID: "1", Type: pooltest.ResourceType, State: StateInuse, Created: start,
+		LastUsed: start.Add(time.Minute), Pinned: true, Resource: items[0].Resource}, items[0])
+	assert.Equal(t, InventoryItem{ID: "2", Type: pooltest.ResourceType, State: StateIdle, Created: start,
+		LastUsed: start.Add(2 * time.Minute), Resource: items[1].Resource}, items[1])
+}
+
+func TestAcquireWithHints(t *testing.T) {
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: pooltest.NewFactory(),
+		Initializer: func(holder ResourceHolder) error {
+			for _, res := range pooltest.NewResources("a1", "a2", "b1") {
+				holder.AddIdle(res)
+			}
+			return nil
+		},
+		MaxIdle:  5,
+		Capacity: 10,
+	})
+	assert.Nil(t, err)
+
+	// anti-affinity to group of a1
+	avoidA := func(res types.NetworkResource) int {
+		if strings.HasPrefix(res.GetResourceID(), "a") {
+			return -1
+		}
+		return 0
+	}
+	res, err := pool.AcquireWithHints(context.Background(), AcquireHints{Score: avoidA})
+	assert.Nil(t, err)
+	assert.Equal(t, "b1", res.GetResourceID())
+
+	// preferred resource wins over score
+	res, err = pool.AcquireWithHints(context.Background(), AcquireHints{Prefer: "a2", Score: avoidA})
+	assert.Nil(t, err)
+	assert.Equal(t, "a2", res.GetResourceID())
+
+	// no better choice
+	res, err = pool.AcquireWithHints(context.Background(), AcquireHints{Score: avoidA})
+	assert.Nil(t, err)
+	assert.Equal(t, "a1", res.GetResourceID())
+}
+
+func TestEvictAndAdopt(t *testing.T) {
+	factory := pooltest.NewFactory()
+	pool, err := NewSimpleObjectPool(Config{
+		Factory: factory,
+		Initializer: func(holder ResourceHolder) error {
+			holder.AddIdle(pooltest.NewResources("1")[0])
+			holder.AddInuse(pooltest.NewResources("2")[0])
+			return nil
+		},
+		MaxIdle:  2,
+		Capacity: 2,
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrInvalidState, pool.Evict("2"))
+	assert.Equal(t, ErrNotFound, pool.Evict("3"))
+	assert.Equal(t, ErrNoAvailableResource, pool.Adopt(pooltest.NewResources("3")[0]))
+
+	assert.Nil(t, pool.Evict("1"))
+	assert.Equal(t, ErrInvalidState, pool.Adopt(pooltest.NewResources("2")[0]))
+	assert.Nil(t, pool.Adopt(pooltest.NewResources("3")[0]))
+	assert.Equal(t, Snapshot{Inuse: []string{"2"}, Idle: []string{"3"}}, pool.Snapshot())
+	assert.Empty(t, factory.Disposed())
+}
@@ -0,0 +1,226 @@
+// Package pooltest provides fakes and helpers to test components built on pool
+package pooltest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/types"
+)
+
+// ResourceType the type of fake resources
+const ResourceType = "fake"
+
+// Resource fake network resource
+type Resource struct {
+	ID string
+}
+
+// GetResourceID return id of resource
+func (r *Resource) GetResourceID() string {
+	return r.ID
+}
+
+// GetType return type of resource
+func (r *Resource) GetType() string {
+	return ResourceType
+}
+
+// NewResources return fake resources of ids
+func NewResources(ids ...string) []types.NetworkResource {
+	resources := make([]types.NetworkResource, 0, len(ids))
+	for _, id := range ids {
+		resources = append(resources, &Resource{ID: id})
+	}
+	return resources
+}
+
+// Factory fake object factory, create resources of sequential ids from 1001
+type Factory struct {
+	CreateDelay  time.Duration
+	DisposeDelay time.Duration
+
+	lock        sync.Mutex
+	createErr   error
+	disposeErr  error
+	idGenerator int
+	created     []string
+	disposed    []string
+	creating    int
+	maxCreating int
+}
+
+// NewFactory return fake factory without delay
+func NewFactory() *Factory {
+	return &Factory{idGenerator: 1000}
+}
+
+// SetCreateError make following Create return err, nil to recover
+func (f *Factory) SetCreateError(err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.createErr = err
+}
+
+// SetDisposeError make following Dispose return err, nil to recover
+func (f *Factory) SetDisposeError(err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.disposeErr = err
+}
+
+// Create a fake resource
+func (f *Factory) Create() (types.NetworkResource, error) {
+	f.lock.Lock()
+	f.creating++
+	if f.creating > f.maxCreating {
+		f.maxCreating = f.creating
+	}
+	f.lock.Unlock()
+	time.Sleep(f.CreateDelay)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.creating--
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	f.idGenerator++
+	id := fmt.Sprintf("%d", f.idGenerator)
+	f.created = append(f.created, id)
+	return &Resource{ID: id}, nil
+}
+
+// Dispose the resource, recorded even if failed
+func (f *Factory) Dispose(res types.NetworkResource) error {
+	time.Sleep(f.DisposeDelay)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.disposeErr != nil {
+		return f.disposeErr
+	}
+	f.disposed = append(f.disposed, res.GetResourceID())
+	return nil
+}
+
+// CreateBatch create count fake resources
+func (f *Factory) CreateBatch(count int) ([]types.NetworkResource, error) {
+	var resources []types.NetworkResource
+	for i := 0; i < count; i++ {
+		res, err := f.Create()
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, res)
+	}
+	return resources, nil
+}
+
+// DisposeBatch dispose resources, all failed if dispose error set
+func (f *Factory) DisposeBatch(resources []types.NetworkResource) ([]types.NetworkResource, error) {
+	for i, res := range resources {
+		if err := f.Dispose(res); err != nil {
+			return resources[i:], err
+		}
+	}
+	return nil, nil
+}
+
+// Created ids of resources created
+func (f *Factory) Created() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.created...)
+}
+
+// Disposed ids of resources disposed successfully
+func (f *Factory) Disposed() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.disposed...)
+}
+
+// MaxConcurrentCreates the most creates in progress at the same time
+func (f *Factory) MaxConcurrentCreates() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.maxCreating
+}
+
+// Clock fake clock of pool, only moves by Step
+type Clock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+// NewClock return fake clock start from now
+func NewClock() *Clock {
+	return &Clock{now: time.Now()}
+}
+
+// Now return current time of clock
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// Step move clock forward by d
+func (c *Clock) Step(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Ticker fake ticker of pool to check idle resources, only ticks by Sync
+type Ticker struct {
+	C chan time.Time
+}
+
+// NewTicker return fake ticker, set channel C as Tick of pool config
+func NewTicker() *Ticker {
+	return &Ticker{C: make(chan time.Time)}
+}
+
+// Sync tick the pool twice, return after a full pass of check idle started after called finished,
+// the second tick is not received until the first pass done. fail the test if not done in timeout
+func (t *Ticker) Sync(tb testing.TB, timeout time.Duration) {
+	tb.Helper()
+	deadline := time.After(timeout)
+	for i := 0; i < 2; i++ {
+		select {
+		case t.C <- time.Now():
+		case <-deadline:
+			tb.Fatalf("timeout after %v waiting for pool to check idle", timeout)
+		}
+	}
+}
+
+// WaitFor poll cond until true, fail the test if not true in timeout
+func WaitFor(t testing.TB, timeout time.Duration, cond func() bool, format string, args ...interface{}) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout after %v waiting for: %s", timeout, fmt.Sprintf(format, args...))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// AssertCreated wait the factory created count resources in timeout
+func AssertCreated(t testing.TB, f *Factory, count int, timeout time.Duration) {
+	t.Helper()
+	WaitFor(t, timeout, func() bool {
+		return len(f.Created()) == count
+	}, "%d resources created, got: %v", count, f.Created())
+}
+
+// AssertDisposed wait the factory disposed count resources in timeout
+func AssertDisposed(t testing.TB, f *Factory, count int, timeout time.Duration) {
+	t.Helper()
+	WaitFor(t, timeout, func() bool {
+		return len(f.Disposed()) == count
+	}, "%d resources disposed, got: %v", count, f.Disposed())
+}
@@ -0,0 +1,47 @@
+package pooltest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/pool"
+	"github.com/AliyunContainerService/terway/pkg/pool/pooltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolWithFakes(t *testing.T) {
+	factory := pooltest.NewFactory()
+	clock := pooltest.NewClock()
+	ticker := pooltest.NewTicker()
+	p, err := pool.NewSimpleObjectPool(pool.Config{
+		Factory: factory,
+		Initializer: func(holder pool.ResourceHolder) error {
+			for _, res := range pooltest.NewResources("1", "2") {
+				holder.AddInuse(res)
+			}
+			return nil
+		},
+		MinIdle:  0,
+		MaxIdle:  0,
+		Capacity: 5,
+		Clock:    clock,
+		Tick:     ticker.C,
+	})
+	assert.NoError(t, err)
+
+	res, err := p.AcquireAny(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "1001", res.GetResourceID())
+	pooltest.AssertCreated(t, factory, 1, time.Second)
+
+	// reversed on fake clock, not disposed until clock moved
+	assert.NoError(t, p.ReleaseWithReverse("1", time.Minute))
+	ticker.Sync(t, time.Second)
+	assert.Empty(t, factory.Disposed())
+	assert.Equal(t, pool.Snapshot{Inuse: []string{"1001", "2"}, Idle: []string{"1"}}, p.Snapshot())
+
+	clock.Step(time.Minute)
+	assert.NoError(t, p.Release("2"))
+	pooltest.AssertDisposed(t, factory, 2, time.Second)
+}
@@ -0,0 +1,226 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxQueuedSpans spans waiting for export at most, spans over dropped
+	maxQueuedSpans = 2048
+	// maxBatchSpans spans exported in one request at most
+	maxBatchSpans = 512
+	exportPeriod  = 5 * time.Second
+	exportTimeout = 10 * time.Second
+	tracesPath    = "/v1/traces"
+	scopeName     = "github.com/AliyunContainerService/terway"
+)
+
+// Config of exporter of spans
+type Config struct {
+	// Endpoint base url of OTLP/HTTP collector, eg: http://otel-collector:4318
+	Endpoint string
+	// ServiceName service.name of resource of spans
+	ServiceName string
+	// Attributes extra attributes of resource of spans, eg: host.name
+	Attributes map[string]string
+}
+
+// ValidateEndpoint check endpoint is http or https url of collector
+func ValidateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("endpoint %q must be http or https url", endpoint)
+	}
+	return nil
+}
+
+// Init export spans to collector of config in background, tracing disabled if endpoint empty
+func Init(cfg *Config) error {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	if err := ValidateEndpoint(cfg.Endpoint); err != nil {
+		return err
+	}
+	e := &exporter{
+		url:      strings.TrimSuffix(cfg.Endpoint, "/") + tracesPath,
+		resource: resourceOf(cfg),
+		client:   &http.Client{Timeout: exportTimeout},
+		queue:    make(chan *Span, maxQueuedSpans),
+	}
+	lock.Lock()
+	current = e
+	lock.Unlock()
+	go e.run()
+	log.Infof("export traces to %s", e.url)
+	return nil
+}
+
+type exporter struct {
+	url      string
+	resource otlpResource
+	client   *http.Client
+	queue    chan *Span
+}
+
+func (e *exporter) export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		log.Debugf("trace export queue full, span %s dropped", span.name)
+	}
+}
+
+func (e *exporter) run() {
+	ticker := time.NewTicker(exportPeriod)
+	defer ticker.Stop()
+	var batch []*Span
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < maxBatchSpans {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := e.post(batch); err != nil {
+			log.Warnf("error export %d spans: %v", len(batch), err)
+		}
+		batch = nil
+	}
+}
+
+func (e *exporter) post(spans []*Span) error {
+	data, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// otlp json encoding of ExportTraceServiceRequest
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// status codes of span in otlp
+const (
+	statusOK    = 1
+	statusError = 2
+)
+
+func attributesOf(m map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attributes := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		attr := otlpAttribute{Key: key}
+		attr.Value.StringValue = m[key]
+		attributes = append(attributes, attr)
+	}
+	return attributes
+}
+
+func resourceOf(cfg *Config) otlpResource {
+	attributes := map[string]string{"service.name": cfg.ServiceName}
+	for key, value := range cfg.Attributes {
+		attributes[key] = value
+	}
+	return otlpResource{Attributes: attributesOf(attributes)}
+}
+
+func (e *exporter) request(spans []*Span) *otlpRequest {
+	scope := otlpScopeSpans{}
+	scope.Scope.Name = scopeName
+	for _, s := range spans {
+		s.lock.Lock()
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributesOf(s.attributes),
+			Status:            otlpStatus{Code: statusOK},
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != "" {
+			span.Status = otlpStatus{Code: statusError, Message: s.err}
+		}
+		s.lock.Unlock()
+		scope.Spans = append(scope.Spans, span)
+	}
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{{Resource: e.resource, ScopeSpans: []otlpScopeSpans{scope}}}}
+}
@@ -0,0 +1,163 @@
+// Package tracing record spans of pod network setup and export them to OTLP/HTTP collector in json,
+// propagated by w3c trace context
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// kinds of span in otlp
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// Span operation traced, nil span of tracing disabled is noop
+type Span struct {
+	lock       sync.Mutex
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        string
+	ended      bool
+}
+
+type spanKey struct{}
+
+// remoteParent trace context of caller in w3c traceparent
+type remoteParent struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+type remoteKey struct{}
+
+var (
+	lock    sync.RWMutex
+	current *exporter
+)
+
+// Enabled return whether spans exported
+func Enabled() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return current != nil
+}
+
+// FromContext return span of context, nil if none
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithTraceparent return context with trace context of caller in w3c traceparent header,
+// eg: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01, context unchanged if invalid
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	parent := remoteParent{}
+	if _, err := hex.Decode(parent.traceID[:], []byte(parts[1])); err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(parent.spanID[:], []byte(parts[2])); err != nil {
+		return ctx
+	}
+	if parent.traceID == [16]byte{} || parent.spanID == [8]byte{} {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+// Traceparent w3c traceparent header of span, empty if nil
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+func newSpan(ctx context.Context, name string, kind int, start time.Time) *Span {
+	span := &Span{name: name, kind: kind, start: start, attributes: make(map[string]string)}
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID, span.parentID = parent.traceID, parent.spanID
+	} else if remote, ok := ctx.Value(remoteKey{}).(remoteParent); ok {
+		span.traceID, span.parentID = remote.traceID, remote.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// Start span child of span or remote parent in context, nil span and context unchanged if tracing disabled
+func Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	span := newSpan(ctx, name, kind, time.Now())
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Record span child of context finished already, eg: phases timed by caller
+func Record(ctx context.Context, name string, kind int, start, end time.Time, err error, attributes ...string) {
+	if !Enabled() {
+		return
+	}
+	span := newSpan(ctx, name, kind, start)
+	span.SetAttributes(attributes...)
+	span.finish(end, err)
+}
+
+// SetAttributes set attributes of span in pairs of key and value
+func (s *Span) SetAttributes(keyValues ...string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		s.attributes[keyValues[i]] = keyValues[i+1]
+	}
+}
+
+// End span with error of operation, exported once
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.finish(time.Now(), err)
+}
+
+func (s *Span) finish(end time.Time, err error) {
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = end
+	if err != nil {
+		s.err = err.Error()
+	}
+	s.lock.Unlock()
+
+	lock.RLock()
+	defer lock.RUnlock()
+	if current != nil {
+		current.export(s)
+	}
+}
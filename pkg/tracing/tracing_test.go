@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpans(t *testing.T) {
+	_, span := Start(context.Background(), "disabled", KindServer)
+	assert.Nil(t, span)
+	span.SetAttributes("k", "v")
+	span.End(nil)
+
+	var received *otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, tracesPath, r.URL.Path)
+		received = &otlpRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(received))
+	}))
+	defer server.Close()
+	e := &exporter{
+		url:      server.URL + tracesPath,
+		resource: resourceOf(&Config{ServiceName: "terway"}),
+		client:   server.Client(),
+		queue:    make(chan *Span, 4),
+	}
+	lock.Lock()
+	current = e
+	lock.Unlock()
+	defer func() {
+		lock.Lock()
+		current = nil
+		lock.Unlock()
+	}()
+
+	ctx := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, span = Start(ctx, "terway/AllocIP", KindServer)
+	span.SetAttributes("k8s.pod.name", "web")
+	Record(ctx, "pool/wait", KindInternal, time.Now().Add(-time.Second), time.Now(), errors.New("timeout"))
+	span.End(nil)
+	span.End(errors.New("ended twice"))
+
+	assert.Len(t, e.queue, 2)
+	assert.NoError(t, e.post([]*Span{<-e.queue, <-e.queue}))
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	assert.Equal(t, "pool/wait", spans[0].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, otlpStatus{Code: statusError, Message: "timeout"}, spans[0].Status)
+	assert.Equal(t, "terway/AllocIP", spans[1].Name)
+	assert.Equal(t, "00f067aa0ba902b7", spans[1].ParentSpanID)
+	assert.Equal(t, otlpStatus{Code: statusOK}, spans[1].Status)
+	assert.Equal(t, "k8s.pod.name", spans[1].Attributes[0].Key)
+	assert.Equal(t, "service.name", received.ResourceSpans[0].Resource.Attributes[0].Key)
+}
+
+func TestContextWithTraceparent(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	} {
+		ctx := ContextWithTraceparent(context.Background(), value)
+		assert.Nil(t, ctx.Value(remoteKey{}), value)
+	}
+	assert.Error(t, ValidateEndpoint("otel-collector:4318"))
+	assert.NoError(t, ValidateEndpoint("http://otel-collector:4318"))
+}
@@ -0,0 +1,145 @@
+package driver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	icmpEchoRequest = 8
+	icmpEchoReply   = 0
+
+	pingRetry = 3
+)
+
+// CheckConnectivity verify container network after setup: the route to gateway should via
+// container interface, and the gateway should reply ping if ping is set
+func CheckConnectivity(containerVeth string, gateway net.IP, ping bool, timeout time.Duration, netNS ns.NetNS) error {
+	return netNS.Do(func(_ ns.NetNS) error {
+		contLink, err := netlink.LinkByName(containerVeth)
+		if err != nil {
+			return errors.Wrapf(err, "check connectivity, error get container interface %s", containerVeth)
+		}
+		if contLink.Attrs().Flags&net.FlagUp == 0 {
+			return fmt.Errorf("check connectivity, container interface %s is not up", containerVeth)
+		}
+
+		routes, err := netlink.RouteGet(gateway)
+		if err != nil {
+			return errors.Wrapf(err, "check connectivity, no route to gateway %s", gateway)
+		}
+		if len(routes) == 0 || routes[0].LinkIndex != contLink.Attrs().Index {
+			return fmt.Errorf("check connectivity, route to gateway %s not via container interface %s: %v",
+				gateway, containerVeth, routes)
+		}
+
+		if !ping {
+			return nil
+		}
+		for i := 0; i < pingRetry; i++ {
+			if err = pingOnce(gateway, timeout/pingRetry, i); err == nil {
+				return nil
+			}
+		}
+		return errors.Wrapf(err, "check connectivity, gateway %s unreachable from container", gateway)
+	})
+}
+
+// pingOnce send an icmp echo request to dst and wait for the reply
+func pingOnce(dst net.IP, timeout time.Duration, seq int) error {
+	dst4 := dst.To4()
+	if dst4 == nil {
+		return fmt.Errorf("only ipv4 gateway supported: %s", dst)
+	}
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_ICMP)
+	if err != nil {
+		return errors.Wrapf(err, "error create icmp socket")
+	}
+	defer syscall.Close(fd)
+
+	tv := syscall.NsecToTimeval(timeout.Nanoseconds())
+	if err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return errors.Wrapf(err, "error set icmp socket timeout")
+	}
+
+	id := os.Getpid() & 0xffff
+	packet := make([]byte, 8)
+	packet[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(packet[4:], uint16(id))
+	binary.BigEndian.PutUint16(packet[6:], uint16(seq))
+	binary.BigEndian.PutUint16(packet[2:], icmpChecksum(packet))
+
+	addr := &syscall.SockaddrInet4{}
+	copy(addr.Addr[:], dst4)
+	if err = syscall.Sendto(fd, packet, 0, addr); err != nil {
+		return errors.Wrapf(err, "error send icmp echo to %s", dst)
+	}
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 1500)
+	for time.Now().Before(deadline) {
+		n, from, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return errors.Wrapf(err, "error wait icmp echo reply from %s", dst)
+		}
+		if src, ok := from.(*syscall.SockaddrInet4); !ok || !net.IP(src.Addr[:]).Equal(dst4) {
+			continue
+		}
+		// skip ip header
+		if n < 1 {
+			continue
+		}
+		hdrLen := int(buf[0]&0x0f) << 2
+		if n < hdrLen+8 {
+			continue
+		}
+		reply := buf[hdrLen:n]
+		if reply[0] == icmpEchoReply &&
+			int(binary.BigEndian.Uint16(reply[4:])) == id &&
+			int(binary.BigEndian.Uint16(reply[6:])) == seq {
+			return nil
+		}
+	}
+	return fmt.Errorf("timeout wait icmp echo reply from %s", dst)
+}
+
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	sum = (sum >> 16) + (sum & 0xffff)
+	sum += sum >> 16
+	return ^uint16(sum)
+}
+
+// CheckAddress verify container interface has the ip of address
+func CheckAddress(containerVeth string, address *net.IPNet, netNS ns.NetNS) error {
+	return netNS.Do(func(_ ns.NetNS) error {
+		contLink, err := netlink.LinkByName(containerVeth)
+		if err != nil {
+			return errors.Wrapf(err, "check address, error get container interface %s", containerVeth)
+		}
+		addrs, err := netlink.AddrList(contLink, netlink.FAMILY_V4)
+		if err != nil {
+			return errors.Wrapf(err, "check address, error list addresses of container interface %s", containerVeth)
+		}
+		for _, addr := range addrs {
+			if addr.IP.Equal(address.IP) {
+				return nil
+			}
+		}
+		return fmt.Errorf("check address, ip %s not found on container interface %s", address.IP, containerVeth)
+	})
+}
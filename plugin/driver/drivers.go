@@ -18,6 +18,9 @@ var (
 	VethDriver   NetnsDriver = &vethDriver{}
 	NicDriver    NetnsDriver = &rawNicDriver{}
 	IPVlanDriver NetnsDriver = &ipvlanDriver{}
+	// VethProxyARPDriver veth driver with arp of gateway in container answered by host veth with proxy_arp,
+	// instead of permanent neighbor entry of gateway in container
+	VethProxyARPDriver NetnsDriver = &vethDriver{proxyARP: true}
 )
 
 // NetnsDriver to config container netns interface and routes
@@ -29,6 +32,8 @@ type NetnsDriver interface {
 		gateway net.IP,
 		extraRoutes []*types.Route,
 		deviceID int,
+		tableID int,
+		mtu int,
 		ingress uint64,
 		egress uint64,
 		netNS ns.NetNS) error
@@ -36,10 +41,11 @@ type NetnsDriver interface {
 }
 
 type vethDriver struct {
+	proxyARP bool
 }
 
 const (
-	// MTU to config interfaces
+	// MTU default mtu of interfaces if neither configured nor detected
 	MTU = 1500
 	// mainRouteTable the system "main" route table id
 	mainRouteTable        = 254
@@ -63,6 +69,8 @@ func (d *vethDriver) Setup(
 	gateway net.IP,
 	extraRoutes []*types.Route,
 	deviceID int,
+	tableID int,
+	mtu int,
 	ingress uint64,
 	egress uint64,
 	netNS ns.NetNS) error {
@@ -87,7 +95,7 @@ func (d *vethDriver) Setup(
 	// config in container netns
 	err = netNS.Do(func(_ ns.NetNS) error {
 		// 1. create veth pair
-		hostVeth, contVeth, err = setupVethPair(containerVeth, hostIfName, MTU, hostNs)
+		hostVeth, contVeth, err = setupVethPair(containerVeth, hostIfName, mtu, hostNs)
 		if err != nil {
 			return errors.Wrap(err, "vethDriver, error create veth pair for container")
 		}
@@ -108,16 +116,18 @@ func (d *vethDriver) Setup(
 			return errors.Wrap(err, "error add addr for container veth")
 		}
 
-		// 3. add route and neigh for container
-		err = netlink.NeighAdd(&netlink.Neigh{
-			LinkIndex:    contLink.Attrs().Index,
-			IP:           linkIP.IP,
-			HardwareAddr: hostVeth.HardwareAddr,
-			State:        netlink.NUD_PERMANENT,
-			Family:       syscall.AF_INET,
-		})
-		if err != nil {
-			return errors.Wrap(err, "error add permanent arp for container veth")
+		// 3. add route and neigh for container, arp of gateway answered by host veth in proxy arp
+		if !d.proxyARP {
+			err = netlink.NeighAdd(&netlink.Neigh{
+				LinkIndex:    contLink.Attrs().Index,
+				IP:           linkIP.IP,
+				HardwareAddr: hostVeth.HardwareAddr,
+				State:        netlink.NUD_PERMANENT,
+				Family:       syscall.AF_INET,
+			})
+			if err != nil {
+				return errors.Wrap(err, "error add permanent arp for container veth")
+			}
 		}
 
 		err = netlink.RouteAdd(&netlink.Route{
@@ -171,6 +181,12 @@ func (d *vethDriver) Setup(
 		return errors.Wrap(err, "vethDriver, error get veth pair in host ns")
 	}
 
+	if d.proxyARP {
+		if err = EnableProxyARP(hostLink.Attrs().Name); err != nil {
+			return errors.Wrap(err, "vethDriver, error enable proxy arp of host veth")
+		}
+	}
+
 	err = netlink.LinkSetUp(hostLink)
 	if err != nil {
 		return errors.Wrap(err, "vethDriver, error set veth pair in host ns up")
@@ -211,7 +227,10 @@ func (d *vethDriver) Setup(
 			return errors.Wrapf(err, "vethDriver, error get eni parent Link, deviceID: %v", deviceID)
 		}
 
-		tableID := getRouteTableID(parentLink.Attrs().Index)
+		// table allocated by daemon, or the legacy one of daemon without allocator
+		if tableID == 0 {
+			tableID = getRouteTableID(parentLink.Attrs().Index)
+		}
 
 		// ensure eni config
 		err = d.ensureEniConfig(parentLink, tableID, gateway)
@@ -368,50 +387,63 @@ func (d *vethDriver) Teardown(hostIfName string, containerVeth string, netNS ns.
 
 	// 2. fixme remove ingress/egress rule for pod ip
 
-	// found table for container
+	// 3. cleanup policy route of route tables of containerip
+	if err = delPolicyRules(containerIP); err != nil {
+		return err
+	}
+
+	// 4. remove container veth
+	return netlink.LinkDel(hostVeth)
+}
+
+// delPolicyRules delete the policy rules from and to container ip
+func delPolicyRules(containerIP net.IP) error {
 	ruleList, err := netlink.RuleList(netlink.FAMILY_ALL)
 	if err != nil {
 		return errors.Wrapf(err, "failed list ip rule from netlink")
 	}
 
-	var toContainerRule *netlink.Rule
-	var fromContainerRule *netlink.Rule
-
 	for _, rule := range ruleList {
 		var bits int
 		ruleInner := rule
+		match := false
 		if ruleInner.Src != nil {
 			_, bits = ruleInner.Src.Mask.Size()
-			if bits == len(ruleInner.Src.IP)*8 && ruleInner.Src.IP.Equal(containerIP) {
-				fromContainerRule = &ruleInner
-			}
+			match = bits == len(ruleInner.Src.IP)*8 && ruleInner.Src.IP.Equal(containerIP)
 		}
-
-		if ruleInner.Dst != nil {
+		if !match && ruleInner.Dst != nil {
 			_, bits = ruleInner.Dst.Mask.Size()
-			if bits == len(ruleInner.Dst.IP)*8 && ruleInner.Dst.IP.Equal(containerIP) {
-				toContainerRule = &ruleInner
-			}
+			match = bits == len(ruleInner.Dst.IP)*8 && ruleInner.Dst.IP.Equal(containerIP)
 		}
-	}
-
-	// 4. cleanup policy route of route tables of containerip
-	if toContainerRule != nil {
-		err = netlink.RuleDel(toContainerRule)
-		if err != nil {
+		if !match {
+			continue
+		}
+		if err = netlink.RuleDel(&ruleInner); err != nil {
 			return errors.Wrapf(err, "VethDriver, error clean up policy rule for container")
 		}
 	}
+	return nil
+}
 
-	if fromContainerRule != nil {
-		err = netlink.RuleDel(fromContainerRule)
-		if err != nil {
-			return errors.Wrapf(err, "VethDriver, error clean up policy rule for container")
+// TeardownHostSide cleanup policy rules, link-local access rules and host veth of pod left on host,
+// when netns of pod already gone, by pod ip recorded on ADD, podIP can be nil if unknown
+func TeardownHostSide(hostVeth string, podIP net.IP) error {
+	if err := cleanupLinkLocalAccess(hostVeth, podIP); err != nil {
+		return err
+	}
+	if podIP != nil {
+		if err := delPolicyRules(podIP); err != nil {
+			return err
 		}
 	}
-
-	// 4. remove container veth
-	return netlink.LinkDel(hostVeth)
+	link, err := netlink.LinkByName(hostVeth)
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return errors.Wrapf(err, "error get host veth %s", hostVeth)
+	}
+	return errors.Wrapf(netlink.LinkDel(link), "error delete host veth %s", hostVeth)
 }
 
 func setupVethPair(contVethName, pairName string, mtu int, hostNS ns.NetNS) (net.Interface, net.Interface, error) {
@@ -0,0 +1,178 @@
+package driver
+
+import (
+	"unsafe"
+
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// ethtool commands of linux/ethtool.h
+const (
+	ethtoolGRingParam = 0x10
+	ethtoolSRingParam = 0x11
+	ethtoolGTSO       = 0x1e
+	ethtoolSTSO       = 0x1f
+	ethtoolGFlags     = 0x25
+	ethtoolSFlags     = 0x26
+	ethtoolGGRO       = 0x2b
+	ethtoolSGRO       = 0x2c
+	ethtoolGChannels  = 0x3c
+	ethtoolSChannels  = 0x3d
+
+	ethFlagLRO = 1 << 15
+)
+
+// ethtoolValue struct ethtool_value
+type ethtoolValue struct {
+	cmd  uint32
+	data uint32
+}
+
+// ethtoolRingParam struct ethtool_ringparam
+type ethtoolRingParam struct {
+	cmd               uint32
+	rxMaxPending      uint32
+	rxMiniMaxPending  uint32
+	rxJumboMaxPending uint32
+	txMaxPending      uint32
+	rxPending         uint32
+	rxMiniPending     uint32
+	rxJumboPending    uint32
+	txPending         uint32
+}
+
+// ethtoolChannels struct ethtool_channels
+type ethtoolChannels struct {
+	cmd           uint32
+	maxRx         uint32
+	maxTx         uint32
+	maxOther      uint32
+	maxCombined   uint32
+	rxCount       uint32
+	txCount       uint32
+	otherCount    uint32
+	combinedCount uint32
+}
+
+// ifreqData struct ifreq with pointer to ethtool command
+type ifreqData struct {
+	name [unix.IFNAMSIZ]byte
+	data uintptr
+}
+
+type ethtool struct {
+	fd     int
+	ifName string
+}
+
+func (e *ethtool) ioctl(data unsafe.Pointer) error {
+	ifr := ifreqData{data: uintptr(data)}
+	copy(ifr.name[:unix.IFNAMSIZ-1], e.ifName)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(e.fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// setValue set value of get and set command if changed
+func (e *ethtool) setValue(get, set uint32, value func(uint32) uint32) error {
+	v := ethtoolValue{cmd: get}
+	if err := e.ioctl(unsafe.Pointer(&v)); err != nil {
+		return err
+	}
+	if data := value(v.data); data != v.data {
+		v = ethtoolValue{cmd: set, data: data}
+		return e.ioctl(unsafe.Pointer(&v))
+	}
+	return nil
+}
+
+func boolValue(on bool) func(uint32) uint32 {
+	return func(uint32) uint32 {
+		if on {
+			return 1
+		}
+		return 0
+	}
+}
+
+// tune set combined channels, ring sizes and offloads of interface, unset ones kept as is
+func (e *ethtool) tune(tuning *types.InterfaceTuning) error {
+	if tuning.CombinedQueues > 0 {
+		channels := ethtoolChannels{cmd: ethtoolGChannels}
+		if err := e.ioctl(unsafe.Pointer(&channels)); err != nil {
+			return errors.Wrapf(err, "error get channels of %s", e.ifName)
+		}
+		if uint32(tuning.CombinedQueues) > channels.maxCombined {
+			return errors.Errorf("combined queues %d of %s over max %d", tuning.CombinedQueues, e.ifName, channels.maxCombined)
+		}
+		if channels.combinedCount != uint32(tuning.CombinedQueues) {
+			channels.cmd = ethtoolSChannels
+			channels.combinedCount = uint32(tuning.CombinedQueues)
+			if err := e.ioctl(unsafe.Pointer(&channels)); err != nil {
+				return errors.Wrapf(err, "error set combined queues of %s to %d", e.ifName, tuning.CombinedQueues)
+			}
+		}
+	}
+	if tuning.RxRing > 0 || tuning.TxRing > 0 {
+		ring := ethtoolRingParam{cmd: ethtoolGRingParam}
+		if err := e.ioctl(unsafe.Pointer(&ring)); err != nil {
+			return errors.Wrapf(err, "error get rings of %s", e.ifName)
+		}
+		if tuning.RxRing > 0 {
+			if uint32(tuning.RxRing) > ring.rxMaxPending {
+				return errors.Errorf("rx ring %d of %s over max %d", tuning.RxRing, e.ifName, ring.rxMaxPending)
+			}
+			ring.rxPending = uint32(tuning.RxRing)
+		}
+		if tuning.TxRing > 0 {
+			if uint32(tuning.TxRing) > ring.txMaxPending {
+				return errors.Errorf("tx ring %d of %s over max %d", tuning.TxRing, e.ifName, ring.txMaxPending)
+			}
+			ring.txPending = uint32(tuning.TxRing)
+		}
+		ring.cmd = ethtoolSRingParam
+		if err := e.ioctl(unsafe.Pointer(&ring)); err != nil {
+			return errors.Wrapf(err, "error set rings of %s to rx %d tx %d", e.ifName, ring.rxPending, ring.txPending)
+		}
+	}
+	if tuning.GRO != nil {
+		if err := e.setValue(ethtoolGGRO, ethtoolSGRO, boolValue(*tuning.GRO)); err != nil {
+			return errors.Wrapf(err, "error set gro of %s to %v", e.ifName, *tuning.GRO)
+		}
+	}
+	if tuning.TSO != nil {
+		if err := e.setValue(ethtoolGTSO, ethtoolSTSO, boolValue(*tuning.TSO)); err != nil {
+			return errors.Wrapf(err, "error set tso of %s to %v", e.ifName, *tuning.TSO)
+		}
+	}
+	if tuning.LRO != nil {
+		lro := *tuning.LRO
+		err := e.setValue(ethtoolGFlags, ethtoolSFlags, func(flags uint32) uint32 {
+			if lro {
+				return flags | ethFlagLRO
+			}
+			return flags &^ ethFlagLRO
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error set lro of %s to %v", e.ifName, lro)
+		}
+	}
+	return nil
+}
+
+// SetupInterfaceTuning set queues, ring sizes and offloads of interface in container netns, eg: exclusive eni
+func SetupInterfaceTuning(ifName string, tuning *types.InterfaceTuning, netNS ns.NetNS) error {
+	return netNS.Do(func(_ ns.NetNS) error {
+		fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+		if err != nil {
+			return errors.Wrapf(err, "error open socket for ethtool")
+		}
+		defer unix.Close(fd)
+		return (&ethtool{fd: fd, ifName: ifName}).tune(tuning)
+	})
+}
@@ -44,6 +44,8 @@ func (driver *ipvlanDriver) Setup(
 	gateway net.IP,
 	extraRoutes []*types.Route,
 	deviceID int,
+	tableID int,
+	mtu int,
 	ingress uint64,
 	egress uint64,
 	netNS ns.NetNS) error {
@@ -63,7 +65,7 @@ func (driver *ipvlanDriver) Setup(
 		LinkAttrs: netlink.LinkAttrs{
 			Name:        hostIPVlan,
 			ParentIndex: deviceID,
-			MTU:         MTU,
+			MTU:         mtu,
 		},
 		Mode: netlink.IPVLAN_MODE_L3S,
 	}
@@ -0,0 +1,163 @@
+package driver
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/AliyunContainerService/terway/pkg/netfilter"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// linkLocalPriority rules of link-local access, before from container rule
+	linkLocalPriority = 1024
+	// linkLocalBlockTable route table with prohibit default route for blocked pods
+	linkLocalBlockTable = 900
+
+	linkLocalMasqComment = "terway link-local masquerade"
+)
+
+// linkLocalServices link-local range and the metadata service of aliyun
+var linkLocalServices = []*net.IPNet{
+	{IP: net.IPv4(169, 254, 0, 0), Mask: net.CIDRMask(16, 32)},
+	{IP: net.IPv4(100, 100, 100, 200), Mask: net.CIDRMask(32, 32)},
+}
+
+// SetupLinkLocalAccess enforce pod access to link-local services on host side of pod veth:
+// block by policy route to prohibit table, masquerade by policy route to main table and snat to node ip
+func SetupLinkLocalAccess(hostVeth string, podIP net.IP, access string) error {
+	if err := cleanupLinkLocalAccess(hostVeth, podIP); err != nil {
+		return err
+	}
+
+	var table int
+	switch access {
+	case "", types.LinkLocalAccessAllow:
+		return nil
+	case types.LinkLocalAccessBlock:
+		if err := ensureBlockTable(); err != nil {
+			return err
+		}
+		table = linkLocalBlockTable
+	case types.LinkLocalAccessMasquerade:
+		ipt, err := netfilter.New()
+		if err != nil {
+			return errors.Wrapf(err, "error init iptables")
+		}
+		for _, dst := range linkLocalServices {
+			err = ipt.AppendUnique("nat", "POSTROUTING", masqRuleSpec(podIP, dst)...)
+			if err != nil {
+				return errors.Wrapf(err, "error add masquerade rule for %s to %s", podIP, dst)
+			}
+		}
+		table = mainRouteTable
+	default:
+		return fmt.Errorf("unsupported link-local access: %s", access)
+	}
+
+	for _, dst := range linkLocalServices {
+		rule := netlink.NewRule()
+		rule.IifName = hostVeth
+		rule.Dst = dst
+		rule.Table = table
+		rule.Priority = linkLocalPriority
+		if err := netlink.RuleAdd(rule); err != nil {
+			return errors.Wrapf(err, "error add link-local rule for %s to %s", hostVeth, dst)
+		}
+	}
+	return nil
+}
+
+// TeardownLinkLocalAccess cleanup link-local access rules of pod on host side of pod veth
+func TeardownLinkLocalAccess(hostVeth string, containerVeth string, netNS ns.NetNS) error {
+	podIP, err := getNSIP(containerVeth, netNS)
+	if err != nil {
+		// masquerade rules can not be found without pod ip, only cleanup policy rules
+		podIP = nil
+	}
+	return cleanupLinkLocalAccess(hostVeth, podIP)
+}
+
+// cleanupLinkLocalAccess cleanup link-local access rules of pod, podIP can be nil if unknown
+func cleanupLinkLocalAccess(hostVeth string, podIP net.IP) error {
+	ruleList, err := netlink.RuleList(netlink.FAMILY_V4)
+	if err != nil {
+		return errors.Wrapf(err, "error list rule")
+	}
+	for _, rule := range ruleList {
+		if rule.Priority != linkLocalPriority || rule.IifName != hostVeth {
+			continue
+		}
+		if err = netlink.RuleDel(&rule); err != nil {
+			return errors.Wrapf(err, "error clean up link-local rule: %+v", rule)
+		}
+	}
+
+	if podIP == nil {
+		return nil
+	}
+	ipt, err := netfilter.New()
+	if err != nil {
+		return errors.Wrapf(err, "error init iptables")
+	}
+	for _, dst := range linkLocalServices {
+		ruleSpec := masqRuleSpec(podIP, dst)
+		exist, err := ipt.Exists("nat", "POSTROUTING", ruleSpec...)
+		if err != nil {
+			return errors.Wrapf(err, "error check masquerade rule for %s", podIP)
+		}
+		if !exist {
+			continue
+		}
+		if err = ipt.Delete("nat", "POSTROUTING", ruleSpec...); err != nil {
+			return errors.Wrapf(err, "error clean up masquerade rule for %s", podIP)
+		}
+	}
+	return nil
+}
+
+// SetupLinkLocalAccessInNetns enforce pod access to link-local services in container netns,
+// for the datapath which bypass host network stack, only block supported
+func SetupLinkLocalAccessInNetns(access string, netNS ns.NetNS) error {
+	switch access {
+	case "", types.LinkLocalAccessAllow:
+		return nil
+	case types.LinkLocalAccessBlock:
+	default:
+		return fmt.Errorf("link-local access %s not supported by datapath", access)
+	}
+
+	return netNS.Do(func(_ ns.NetNS) error {
+		for _, dst := range linkLocalServices {
+			err := netlink.RouteReplace(&netlink.Route{
+				Dst:  dst,
+				Type: unix.RTN_PROHIBIT,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "error add prohibit route to %s", dst)
+			}
+		}
+		return nil
+	})
+}
+
+// ensureBlockTable ensure prohibit default route in link-local block table
+func ensureBlockTable() error {
+	return netlink.RouteReplace(&netlink.Route{
+		Dst:   defaultRoute,
+		Table: linkLocalBlockTable,
+		Type:  unix.RTN_PROHIBIT,
+	})
+}
+
+func masqRuleSpec(podIP net.IP, dst *net.IPNet) []string {
+	return []string{
+		"-s", podIP.String() + "/32", "-d", dst.String(),
+		"-m", "comment", "--comment", linkLocalMasqComment,
+		"-j", "MASQUERADE",
+	}
+}
@@ -0,0 +1,49 @@
+package driver
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	// minMTU the min mtu of ipv4 hosts must accept
+	minMTU = 576
+	// maxJumboMTU the max mtu of jumbo frame supported by eni
+	maxJumboMTU = 8500
+)
+
+// ResolveMTU mtu of pod interfaces with traffic through the device, the configured mtu validated
+// against the device, or the mtu of device if not configured
+func ResolveMTU(configured int, device netlink.Link) (int, error) {
+	deviceMTU := device.Attrs().MTU
+	if configured == 0 {
+		if deviceMTU <= 0 {
+			return MTU, nil
+		}
+		return deviceMTU, nil
+	}
+	if configured < minMTU || configured > maxJumboMTU {
+		return 0, fmt.Errorf("mtu %d out of range [%d, %d]", configured, minMTU, maxJumboMTU)
+	}
+	if configured > deviceMTU {
+		return 0, fmt.Errorf("mtu %d exceeds mtu %d of device %s, jumbo frame not enabled on the device",
+			configured, deviceMTU, device.Attrs().Name)
+	}
+	return configured, nil
+}
+
+// DefaultRouteLink the link of default route in host netns
+func DefaultRouteLink() (netlink.Link, error) {
+	routes, err := netlink.RouteListFiltered(netlink.FAMILY_V4, &netlink.Route{Dst: nil}, netlink.RT_FILTER_DST)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list default routes")
+	}
+	for _, route := range routes {
+		if route.LinkIndex > 0 {
+			return netlink.LinkByIndex(route.LinkIndex)
+		}
+	}
+	return nil, fmt.Errorf("no default route on host")
+}
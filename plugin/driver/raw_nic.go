@@ -22,6 +22,8 @@ func (r *rawNicDriver) Setup(hostVeth string,
 	gateway net.IP,
 	extraRoutes []*types.Route,
 	deviceID int,
+	tableID int,
+	mtu int,
 	ingress uint64,
 	egress uint64,
 	netNS ns.NetNS) error {
@@ -104,6 +106,13 @@ func (r *rawNicDriver) Setup(hostVeth string,
 			return errors.Wrapf(err, "setup nic link name failed")
 		}
 
+		if mtu != 0 && mtu != nicLink.Attrs().MTU {
+			err = netlink.LinkSetMTU(nicLink, mtu)
+			if err != nil {
+				return errors.Wrapf(err, "setup set nic link mtu %d", mtu)
+			}
+		}
+
 		err = netlink.LinkSetUp(nicLink)
 		if err != nil {
 			return errors.Wrapf(err, "setup set nic link up")
@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/AliyunContainerService/terway/pkg/netfilter"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// hostReturnMark connmark of connections from host veth in pod netns
+	hostReturnMark = 0x10
+	// hostReturnTable route table of replies to connections from host veth
+	hostReturnTable = 100
+	// hostReturnPriority rule of replies to connections from host veth, before the main table
+	hostReturnPriority = 1000
+
+	hostReturnComment = "terway host return path"
+)
+
+// hostReturnRules mangle rules marking connections from host veth and restoring the mark on replies of pod
+func hostReturnRules(vethName string) [][]string {
+	mark := fmt.Sprintf("0x%x", hostReturnMark)
+	return [][]string{
+		{"PREROUTING", "-i", vethName, "-m", "conntrack", "--ctstate", "NEW",
+			"-m", "comment", "--comment", hostReturnComment, "-j", "CONNMARK", "--set-mark", mark},
+		{"OUTPUT", "-m", "connmark", "--mark", mark,
+			"-m", "comment", "--comment", hostReturnComment, "-j", "CONNMARK", "--restore-mark"},
+	}
+}
+
+// SetupHostReturnPath route replies to connections from host veth back through it in netns of pod with
+// exclusive eni, instead of out of the eni by default route, eg: nodeport of externalTrafficPolicy Local
+// dnat to pod by kube-proxy with client ip kept, whose replies must be un-dnat on host
+func SetupHostReturnPath(vethName string, gw net.IP, netNS ns.NetNS) error {
+	return netNS.Do(func(_ ns.NetNS) error {
+		link, err := netlink.LinkByName(vethName)
+		if err != nil {
+			return errors.Wrapf(err, "error get link %s in container netns", vethName)
+		}
+		err = netlink.RouteReplace(&netlink.Route{
+			LinkIndex: link.Attrs().Index,
+			Scope:     netlink.SCOPE_UNIVERSE,
+			Flags:     int(netlink.FLAG_ONLINK),
+			Dst:       defaultRoute,
+			Gw:        gw,
+			Table:     hostReturnTable,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error add default route of host return table")
+		}
+
+		rule := netlink.NewRule()
+		rule.Mark = hostReturnMark
+		rule.Table = hostReturnTable
+		rule.Priority = hostReturnPriority
+		if err = netlink.RuleAdd(rule); err != nil && err != unix.EEXIST {
+			return errors.Wrapf(err, "error add rule of host return path")
+		}
+
+		ipt, err := netfilter.New()
+		if err != nil {
+			return errors.Wrapf(err, "error init iptables in container netns")
+		}
+		for _, spec := range hostReturnRules(vethName) {
+			if err = ipt.AppendUnique("mangle", spec[0], spec[1:]...); err != nil {
+				return errors.Wrapf(err, "error add mangle rule of host return path to %s", spec[0])
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package driver
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+)
+
+// SetupPodRoutes add extra routes requested by pod on interface of pod in container netns,
+// route without gateway goes by the gateway of pod default route
+func SetupPodRoutes(ifName string, routes []*types.Route, netNS ns.NetNS) error {
+	return netNS.Do(func(_ ns.NetNS) error {
+		contLink, err := netlink.LinkByName(ifName)
+		if err != nil {
+			return errors.Wrapf(err, "error get link %s in container netns", ifName)
+		}
+		var defaultGateway net.IP
+		for _, route := range routes {
+			gw := route.GW
+			if gw == nil {
+				if defaultGateway == nil {
+					if defaultGateway, err = linkDefaultGateway(contLink); err != nil {
+						return err
+					}
+				}
+				gw = defaultGateway
+			}
+			dst := route.Dst
+			err = netlink.RouteReplace(&netlink.Route{
+				LinkIndex: contLink.Attrs().Index,
+				Scope:     netlink.SCOPE_UNIVERSE,
+				Flags:     int(netlink.FLAG_ONLINK),
+				Dst:       &dst,
+				Gw:        gw,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "error add route to %s via %s", dst.String(), gw)
+			}
+		}
+		return nil
+	})
+}
+
+// linkDefaultGateway the gateway of default route on link
+func linkDefaultGateway(link netlink.Link) (net.IP, error) {
+	routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error list routes of %s", link.Attrs().Name)
+	}
+	for _, route := range routes {
+		if route.Dst == nil && route.Gw != nil {
+			return route.Gw, nil
+		}
+	}
+	return nil, fmt.Errorf("no default gateway on %s", link.Attrs().Name)
+}
@@ -3,7 +3,9 @@ package driver
 import (
 	"fmt"
 	"net"
+	"sort"
 
+	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/containernetworking/plugins/pkg/utils/sysctl"
 	"github.com/pkg/errors"
 	"github.com/vishvananda/netlink"
@@ -45,7 +47,24 @@ func ipNetEqual(ipn1 *net.IPNet, ipn2 *net.IPNet) bool {
 	return m1 == m2 && ipn1.IP.Equal(ipn2.IP)
 }
 
-const rpFilterSysctl = "net.ipv4.conf.%s.rp_filter"
+const (
+	rpFilterSysctl   = "net.ipv4.conf.%s.rp_filter"
+	proxyARPSysctl   = "net.ipv4.conf.%s.proxy_arp"
+	proxyDelaySysctl = "net.ipv4.neigh.%s.proxy_delay"
+)
+
+// EnableProxyARP answer arp of addresses routed by host on the link without delay
+func EnableProxyARP(ifName string) error {
+	for name, value := range map[string]string{
+		fmt.Sprintf(proxyARPSysctl, ifName):   "1",
+		fmt.Sprintf(proxyDelaySysctl, ifName): "0",
+	} {
+		if _, err := sysctl.Sysctl(name, value); err != nil {
+			return errors.Wrapf(err, "error set: %s sysctl value to %s", name, value)
+		}
+	}
+	return nil
+}
 
 // EnsureHostNsConfig setup host namespace configs
 func EnsureHostNsConfig() error {
@@ -75,3 +94,20 @@ func EnsureHostNsConfig() error {
 	}
 	return nil
 }
+
+// SetupPodSysctls set sysctls requested by pod in container netns
+func SetupPodSysctls(sysctls map[string]string, netNS ns.NetNS) error {
+	keys := make([]string, 0, len(sysctls))
+	for key := range sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return netNS.Do(func(_ ns.NetNS) error {
+		for _, key := range keys {
+			if _, err := sysctl.Sysctl(key, sysctls[key]); err != nil {
+				return errors.Wrapf(err, "error set sysctl %s to %s in container netns", key, sysctls[key])
+			}
+		}
+		return nil
+	})
+}
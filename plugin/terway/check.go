@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/AliyunContainerService/terway/plugin/driver"
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/plugins/pkg/ns"
+)
+
+// cmdCheck verify network of pod still matches the result persisted on ADD
+func cmdCheck(args *skel.CmdArgs) error {
+	cniNetns, err := ns.GetNS(args.Netns)
+	if err != nil {
+		return err
+	}
+
+	result, err := loadResult(args.ContainerID)
+	if err != nil {
+		return err
+	}
+	if result == nil || result.Result == nil {
+		return fmt.Errorf("no network result persisted for sandbox %s", args.ContainerID)
+	}
+	for _, ipConfig := range result.Result.IPs {
+		if err = driver.CheckAddress(args.IfName, &ipConfig.Address, cniNetns); err != nil {
+			return err
+		}
+		if ipConfig.Gateway == nil {
+			continue
+		}
+		if err = driver.CheckConnectivity(args.IfName, ipConfig.Gateway, false, defaultCheckTimeout, cniNetns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMain serve CHECK verb, which skel of the vendored cni library not dispatch
+func checkMain() {
+	err := withErrorCode(func(args *skel.CmdArgs) (err error) {
+		if args.StdinData, err = ioutil.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+		return cmdCheck(args)
+	})(&skel.CmdArgs{
+		ContainerID: os.Getenv("CNI_CONTAINERID"),
+		Netns:       os.Getenv("CNI_NETNS"),
+		IfName:      os.Getenv("CNI_IFNAME"),
+		Args:        os.Getenv("CNI_ARGS"),
+		Path:        os.Getenv("CNI_PATH"),
+	})
+	if err != nil {
+		err.(*types.Error).Print()
+		os.Exit(1)
+	}
+}
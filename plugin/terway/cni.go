@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"os"
 	"runtime"
+	"strings"
 	"time"
 
+	"github.com/AliyunContainerService/terway/pkg/errcode"
 	"github.com/AliyunContainerService/terway/pkg/link"
 	"github.com/AliyunContainerService/terway/plugin/driver"
 	"github.com/AliyunContainerService/terway/rpc"
+	terwayTypes "github.com/AliyunContainerService/terway/types"
 	"github.com/AliyunContainerService/terway/version"
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
@@ -25,9 +29,9 @@ import (
 
 const (
 	defaultSocketPath      = "/var/run/eni/eni.socket"
-	defaultVethPrefix      = "cali"
 	defaultCniTimeout      = 120
 	defaultVethForENI      = "veth1"
+	defaultCheckTimeout    = 3 * time.Second
 	delegateIpam           = "host-local"
 	eniIPVirtualTypeIPVlan = "IPVlan"
 	delegateConf           = `
@@ -48,7 +52,31 @@ func init() {
 }
 
 func main() {
-	skel.PluginMain(cmdAdd, cmdDel, version.GetSpecVersionSupported())
+	if os.Getenv("CNI_COMMAND") == "CHECK" {
+		checkMain()
+		return
+	}
+	skel.PluginMain(withErrorCode(cmdAdd), withErrorCode(cmdDel), version.GetSpecVersionSupported())
+}
+
+// withErrorCode return error of cmd as cni error result with terway error code, for kubelet events
+// and automation to tell retryable failures from fatal ones
+func withErrorCode(cmd func(args *skel.CmdArgs) error) func(args *skel.CmdArgs) error {
+	return func(args *skel.CmdArgs) error {
+		err := cmd(args)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(*types.Error); ok {
+			return err
+		}
+		code := errcode.FromError(err)
+		return &types.Error{
+			Code:    uint(code),
+			Msg:     err.Error(),
+			Details: errcode.Describe(code),
+		}
+	}
 }
 
 // NetConf is the cni network config
@@ -65,8 +93,60 @@ type NetConf struct {
 	// HostVethPrefix is the veth for container prefix on host
 	HostVethPrefix string `json:"veth_prefix"`
 
+	// VethNameLength hex of hash in host veth names, 15 - len(veth_prefix) at most
+	VethNameLength int `json:"veth_name_length,omitempty"`
+
+	// VethNameHash hash of host veth names: "sha1" or "sha256"
+	VethNameHash string `json:"veth_name_hash,omitempty"`
+
 	// eniIPVirtualType is the ipvlan for container
 	ENIIPVirtualType string `json:"eniip_virtual_type"`
+
+	// ConnectivityCheck verify pod network by route and ping gateway before return
+	ConnectivityCheck bool `json:"connectivity_check"`
+
+	// SocketPath the grpc unix socket of terway daemon
+	SocketPath string `json:"socket_path,omitempty"`
+
+	// MTU of pod interfaces, detected from the device pod traffic goes through if not set
+	MTU int `json:"mtu,omitempty"`
+
+	// ModeMTU mtu of pod network types overriding MTU: "VPCIP", "VPCENI" or "ENIMultiIP"
+	ModeMTU map[string]int `json:"mode_mtu,omitempty"`
+
+	// ProxyARP answer arp of gateway in pods of shared eni by proxy_arp of host veth instead of
+	// permanent neighbor entry in pod netns
+	ProxyARP bool `json:"proxy_arp,omitempty"`
+
+	// ExclusiveENIReturnPath route replies to connections from host veth of pods with exclusive eni back
+	// through it, eg: for nodeport services of externalTrafficPolicy Local
+	ExclusiveENIReturnPath bool `json:"exclusive_eni_return_path,omitempty"`
+
+	// ExclusiveENIDNS dns of pods with exclusive eni reached through the host veth, nil to disable
+	ExclusiveENIDNS *ExclusiveENIDNS `json:"exclusive_eni_dns,omitempty"`
+}
+
+// mtuOf configured mtu of pods of ip type, 0 if not configured
+func (conf *NetConf) mtuOf(ipType rpc.IPType) int {
+	if mtu, ok := conf.ModeMTU[strings.TrimPrefix(ipType.String(), "Type")]; ok {
+		return mtu
+	}
+	return conf.MTU
+}
+
+// vethNameScheme scheme of host veth names configured, default for fields not set
+func (conf *NetConf) vethNameScheme() (link.VethNameScheme, error) {
+	scheme := link.DefaultVethNameScheme
+	if conf.HostVethPrefix != "" {
+		scheme.Prefix = conf.HostVethPrefix
+	}
+	if conf.VethNameLength != 0 {
+		scheme.Length = conf.VethNameLength
+	}
+	if conf.VethNameHash != "" {
+		scheme.Hash = conf.VethNameHash
+	}
+	return scheme, scheme.Validate()
 }
 
 // K8SArgs is cni args of kubernetes
@@ -112,7 +192,7 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 		return errors.Wrapf(err, "add cmd: failed setup host namespace configs")
 	}
 
-	terwayBackendClient, closeConn, err := getNetworkClient()
+	terwayBackendClient, closeConn, err := getNetworkClient(conf.SocketPath)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("add cmd: create grpc client, pod: %s-%s",
 			string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME),
@@ -123,6 +203,13 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 	timeoutContext, cancel := context.WithTimeout(context.Background(), defaultCniTimeout*time.Second)
 	defer cancel()
 
+	features, err := handshake(timeoutContext, terwayBackendClient)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("add cmd: pod: %s-%s",
+			string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME),
+		))
+	}
+
 	allocResult, err := terwayBackendClient.AllocIP(
 		timeoutContext,
 		&rpc.AllocIPRequest{
@@ -156,10 +243,20 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 		}
 	}()
 
-	hostVethName := link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), defaultVethPrefix)
+	setupStart := time.Now()
+	vethNameScheme, err := conf.vethNameScheme()
+	if err != nil {
+		return errors.Wrap(err, "add cmd: invalid veth name config")
+	}
+	hostVethName, err := vethNameScheme.HostVethName(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE))
+	if err != nil {
+		return err
+	}
 	var (
 		allocatedIPAddr      net.IPNet
 		allocatedGatewayAddr net.IP
+		// gateway of vpc ip is virtual on host, do not ping it
+		pingGateway = true
 	)
 
 	switch allocResult.IPType {
@@ -172,6 +269,7 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 		gatewayStr := allocResult.GetENIMultiIP().GetEniConfig().GetGateway()
 		primaryIPStr := allocResult.GetENIMultiIP().GetEniConfig().GetPrimaryIPv4Addr()
 		deviceID := allocResult.GetENIMultiIP().GetEniConfig().GetDeviceNumber()
+		routeTableID := allocResult.GetENIMultiIP().GetEniConfig().GetRouteTableID()
 		ingress := allocResult.GetENIMultiIP().GetPodConfig().GetIngress()
 		egress := allocResult.GetENIMultiIP().GetPodConfig().GetEgress()
 
@@ -201,13 +299,39 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 		}
 		copy(primaryIpv4Addr.Mask, subnet.Mask)
 
+		var (
+			parentLink netlink.Link
+			mtu        int
+		)
+		parentLink, err = netlink.LinkByIndex(int(deviceID))
+		if err != nil {
+			return fmt.Errorf("error get eni link of device %d: %v", deviceID, err)
+		}
+		mtu, err = driver.ResolveMTU(conf.mtuOf(allocResult.IPType), parentLink)
+		if err != nil {
+			return err
+		}
+
 		if conf.ENIIPVirtualType == eniIPVirtualTypeIPVlan {
 			eniMultiIPDriver = driver.IPVlanDriver
+		} else if conf.ProxyARP {
+			eniMultiIPDriver = driver.VethProxyARPDriver
 		}
-		err = eniMultiIPDriver.Setup(hostVethName, args.IfName, subnet, primaryIpv4Addr, gw, nil, int(deviceID), ingress, egress, cniNetns)
+		err = eniMultiIPDriver.Setup(hostVethName, args.IfName, subnet, primaryIpv4Addr, gw, nil, int(deviceID), int(routeTableID), mtu, ingress, egress, cniNetns)
 		if err != nil {
 			return fmt.Errorf("setup network failed: %v", err)
 		}
+		if features[version.FeatureLinkLocalAccess] {
+			linkLocalAccess := allocResult.GetENIMultiIP().GetPodConfig().GetLinkLocalAccess()
+			if conf.ENIIPVirtualType == eniIPVirtualTypeIPVlan {
+				err = driver.SetupLinkLocalAccessInNetns(linkLocalAccess, cniNetns)
+			} else {
+				err = driver.SetupLinkLocalAccess(hostVethName, ip, linkLocalAccess)
+			}
+			if err != nil {
+				return fmt.Errorf("setup link-local access failed: %v", err)
+			}
+		}
 		allocatedIPAddr = *subnet
 		allocatedGatewayAddr = gw
 
@@ -222,38 +346,74 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 			return fmt.Errorf("vpc veth return subnet is not vaild: %v", allocResult.GetVpcIp().GetNodeCidr())
 		}
 
-		var r types.Result
-		r, err = ipam.ExecAdd(delegateIpam, []byte(fmt.Sprintf(delegateConf, subnet.String())))
-		if err != nil {
-			return fmt.Errorf("error allocate ip from delegate ipam %v: %v", delegateIpam, err)
-		}
-		var ipamResult *current.Result
-		ipamResult, err = current.NewResultFromResult(r)
-		if err != nil {
-			return fmt.Errorf("error get result from delegate ipam result %v: %v", delegateIpam, err)
-		}
-
-		defer func() {
+		var (
+			podIPAddr net.IPNet
+			gateway   net.IP
+		)
+		if allocResult.GetVpcIp().GetPodIP() != "" {
+			// allocated by ipam of daemon
+			var podIP net.IP
+			var podIPNet *net.IPNet
+			podIP, podIPNet, err = net.ParseCIDR(allocResult.GetVpcIp().GetPodIP())
 			if err != nil {
-				ipam.ExecDel(delegateIpam, []byte(fmt.Sprintf(delegateConf, subnet.String())))
+				return fmt.Errorf("vpc ip return pod ip is not vaild: %v", allocResult.GetVpcIp().GetPodIP())
 			}
-		}()
+			podIPAddr = net.IPNet{IP: podIP, Mask: podIPNet.Mask}
+			gateway = net.ParseIP(allocResult.GetVpcIp().GetGateway())
+		} else {
+			var r types.Result
+			r, err = ipam.ExecAdd(delegateIpam, []byte(fmt.Sprintf(delegateConf, subnet.String())))
+			if err != nil {
+				return fmt.Errorf("error allocate ip from delegate ipam %v: %v", delegateIpam, err)
+			}
+			var ipamResult *current.Result
+			ipamResult, err = current.NewResultFromResult(r)
+			if err != nil {
+				return fmt.Errorf("error get result from delegate ipam result %v: %v", delegateIpam, err)
+			}
+
+			defer func() {
+				if err != nil {
+					ipam.ExecDel(delegateIpam, []byte(fmt.Sprintf(delegateConf, subnet.String())))
+				}
+			}()
 
-		if len(ipamResult.IPs) != 1 {
-			return fmt.Errorf("error get result from delegate ipam result %v: ipam result is not one ip", delegateIpam)
+			if len(ipamResult.IPs) != 1 {
+				return fmt.Errorf("error get result from delegate ipam result %v: ipam result is not one ip", delegateIpam)
+			}
+			podIPAddr = ipamResult.IPs[0].Address
+			gateway = ipamResult.IPs[0].Gateway
 		}
-		podIPAddr := ipamResult.IPs[0].Address
-		gateway := ipamResult.IPs[0].Gateway
 
 		ingress := allocResult.GetVpcIp().GetPodConfig().GetIngress()
 		egress := allocResult.GetVpcIp().GetPodConfig().GetEgress()
 
-		err = networkDriver.Setup(hostVethName, args.IfName, &podIPAddr, nil, gateway, nil, 0, ingress, egress, cniNetns)
+		var (
+			hostLink netlink.Link
+			mtu      int
+		)
+		hostLink, err = driver.DefaultRouteLink()
+		if err != nil {
+			return err
+		}
+		mtu, err = driver.ResolveMTU(conf.mtuOf(allocResult.IPType), hostLink)
+		if err != nil {
+			return err
+		}
+
+		err = networkDriver.Setup(hostVethName, args.IfName, &podIPAddr, nil, gateway, nil, 0, 0, mtu, ingress, egress, cniNetns)
 		if err != nil {
 			return fmt.Errorf("setup network failed: %v", err)
 		}
+		if features[version.FeatureLinkLocalAccess] {
+			err = driver.SetupLinkLocalAccess(hostVethName, podIPAddr.IP, allocResult.GetVpcIp().GetPodConfig().GetLinkLocalAccess())
+			if err != nil {
+				return fmt.Errorf("setup link-local access failed: %v", err)
+			}
+		}
 		allocatedIPAddr = podIPAddr
 		allocatedGatewayAddr = gateway
+		pingGateway = false
 	case rpc.IPType_TypeVPCENI:
 		if allocResult.GetVpcEni() == nil || allocResult.GetVpcEni().GetServiceCidr() == "" ||
 			allocResult.GetVpcEni().GetEniConfig() == nil {
@@ -288,12 +448,16 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 		if allocResult.GetVpcEni().GetEniConfig().GetMacAddr() == "" {
 			return fmt.Errorf("error get devicenumber from alloc result: %v", allocResult.GetVpcEni().GetEniConfig().GetMacAddr())
 		}
-		var linkList []netlink.Link
+		var (
+			linkList []netlink.Link
+			eniLink  netlink.Link
+		)
 		linkList, err = netlink.LinkList()
 		found := false
 		for _, enilink := range linkList {
 			if enilink.Attrs().HardwareAddr.String() == allocResult.GetVpcEni().GetEniConfig().GetMacAddr() {
 				deviceNumber = enilink.Attrs().Index
+				eniLink = enilink
 				found = true
 				break
 			}
@@ -312,10 +476,25 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 				GW:  net.ParseIP("169.254.1.1"),
 			},
 		}
+		if conf.ExclusiveENIDNS != nil {
+			var dnsRoutes []*types.Route
+			dnsRoutes, err = conf.ExclusiveENIDNS.routes(net.ParseIP("169.254.1.1"))
+			if err != nil {
+				return err
+			}
+			extraRoutes = append(extraRoutes, dnsRoutes...)
+		}
+
+		// veth for service access shares the mtu of eni
+		var mtu int
+		mtu, err = driver.ResolveMTU(conf.mtuOf(allocResult.IPType), eniLink)
+		if err != nil {
+			return err
+		}
 
 		ingress := allocResult.GetVpcEni().GetPodConfig().GetIngress()
 		egress := allocResult.GetVpcEni().GetPodConfig().GetEgress()
-		err = networkDriver.Setup(hostVethName, defaultVethForENI, eniAddrSubnet, nil, gw, extraRoutes, 0, ingress, egress, cniNetns)
+		err = networkDriver.Setup(hostVethName, defaultVethForENI, eniAddrSubnet, nil, gw, extraRoutes, 0, 0, mtu, ingress, egress, cniNetns)
 		if err != nil {
 			return fmt.Errorf("setup veth network for eni failed: %v", err)
 		}
@@ -326,16 +505,78 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 			}
 		}()
 
-		err = nicDriver.Setup(hostVethName, args.IfName, eniAddrSubnet, nil, gw, nil, int(deviceNumber), 0, 0, cniNetns)
+		err = nicDriver.Setup(hostVethName, args.IfName, eniAddrSubnet, nil, gw, nil, int(deviceNumber), 0, mtu, 0, 0, cniNetns)
 		if err != nil {
 			return fmt.Errorf("setup network for vpc eni failed: %v", err)
 		}
+		if conf.ExclusiveENIReturnPath {
+			err = driver.SetupHostReturnPath(defaultVethForENI, net.ParseIP("169.254.1.1"), cniNetns)
+			if err != nil {
+				return fmt.Errorf("setup host return path for vpc eni failed: %v", err)
+			}
+		}
+		if features[version.FeatureLinkLocalAccess] {
+			err = driver.SetupLinkLocalAccessInNetns(allocResult.GetVpcEni().GetPodConfig().GetLinkLocalAccess(), cniNetns)
+			if err != nil {
+				return fmt.Errorf("setup link-local access failed: %v", err)
+			}
+		}
+		if tuning := allocResult.GetVpcEni().GetPodConfig().GetInterfaceTuning(); tuning != nil {
+			err = driver.SetupInterfaceTuning(args.IfName, interfaceTuningOf(tuning), cniNetns)
+			if err != nil {
+				return fmt.Errorf("setup interface tuning of pod failed: %v", err)
+			}
+		}
 		allocatedIPAddr = *eniAddrSubnet
 		allocatedGatewayAddr = gw
 	default:
 		return fmt.Errorf("not support this network type")
 	}
 
+	// record pod of host veth for detecting collisions of veth names, ipvlan pods have none
+	err = link.SetVethAlias(hostVethName, string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE))
+	if err != nil {
+		return err
+	}
+
+	if routes := podConfigOf(allocResult).GetRoutes(); len(routes) != 0 {
+		var podRoutes []*types.Route
+		podRoutes, err = parsePodRoutes(routes)
+		if err != nil {
+			return err
+		}
+		err = driver.SetupPodRoutes(args.IfName, podRoutes, cniNetns)
+		if err != nil {
+			return fmt.Errorf("setup routes of pod failed: %v", err)
+		}
+	}
+
+	if sysctls := podConfigOf(allocResult).GetSysctls(); len(sysctls) != 0 {
+		err = driver.SetupPodSysctls(sysctls, cniNetns)
+		if err != nil {
+			return fmt.Errorf("setup sysctls of pod failed: %v", err)
+		}
+	}
+
+	if features[version.FeatureSetupReport] {
+		// best effort, only for allocation latency tracking of daemon
+		terwayBackendClient.ReportSetup(timeoutContext,
+			&rpc.ReportSetupRequest{
+				K8SPodName:             string(k8sConfig.K8S_POD_NAME),
+				K8SPodNamespace:        string(k8sConfig.K8S_POD_NAMESPACE),
+				K8SPodInfraContainerId: string(k8sConfig.K8S_POD_INFRA_CONTAINER_ID),
+				SetupMicroseconds:      time.Since(setupStart).Nanoseconds() / int64(time.Microsecond),
+			})
+	}
+
+	if conf.ConnectivityCheck {
+		err = driver.CheckConnectivity(args.IfName, allocatedGatewayAddr, pingGateway, defaultCheckTimeout, cniNetns)
+		if err != nil {
+			return fmt.Errorf("connectivity check failed for pod: %s-%s: %v",
+				string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME), err)
+		}
+	}
+
 	result := &current.Result{
 		IPs: []*current.IPConfig{{
 			Version: "4",
@@ -343,6 +584,22 @@ func cmdAdd(args *skel.CmdArgs) (err error) {
 			Gateway: allocatedGatewayAddr,
 		}},
 	}
+	if allocResult.IPType == rpc.IPType_TypeVPCENI && conf.ExclusiveENIDNS != nil {
+		result.DNS = conf.ExclusiveENIDNS.dns()
+	}
+
+	err = saveResult(args.ContainerID, &podResult{
+		PodName:      string(k8sConfig.K8S_POD_NAME),
+		PodNamespace: string(k8sConfig.K8S_POD_NAMESPACE),
+		IfName:       args.IfName,
+		IPType:       allocResult.IPType,
+		NodeCidr:     allocResult.GetVpcIp().GetNodeCidr(),
+		DaemonIPAM:   allocResult.GetVpcIp().GetPodIP() != "",
+		Result:       result,
+	})
+	if err != nil {
+		return err
+	}
 
 	return types.PrintResult(result, confVersion)
 }
@@ -354,9 +611,13 @@ func cmdDel(args *skel.CmdArgs) error {
 		return err
 	}
 
-	cniNetns, err := ns.GetNS(args.Netns)
-	if err != nil {
-		return err
+	// netns removed by runtime or gone after reboot, links of pod removed with it
+	var cniNetns ns.NetNS
+	if args.Netns != "" {
+		cniNetns, err = ns.GetNS(args.Netns)
+		if _, ok := err.(ns.NSPathNotExistErr); err != nil && !ok {
+			return err
+		}
 	}
 
 	conf := NetConf{}
@@ -369,7 +630,7 @@ func cmdDel(args *skel.CmdArgs) error {
 		return errors.Wrap(err, "add cmd: failed to load k8s config from args")
 	}
 
-	terwayBackendClient, closeConn, err := getNetworkClient()
+	terwayBackendClient, closeConn, err := getNetworkClient(conf.SocketPath)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("add cmd: create grpc client, pod: %s-%s",
 			string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME),
@@ -380,6 +641,13 @@ func cmdDel(args *skel.CmdArgs) error {
 	timeoutContext, cancel := context.WithTimeout(context.Background(), defaultCniTimeout*time.Second)
 	defer cancel()
 
+	releaseRequest := &rpc.ReleaseIPRequest{
+		K8SPodName:             string(k8sConfig.K8S_POD_NAME),
+		K8SPodNamespace:        string(k8sConfig.K8S_POD_NAMESPACE),
+		K8SPodInfraContainerId: string(k8sConfig.K8S_POD_INFRA_CONTAINER_ID),
+		Reason:                 "normal release",
+	}
+
 	infoResult, err := terwayBackendClient.GetIPInfo(
 		timeoutContext,
 		&rpc.GetInfoRequest{
@@ -389,17 +657,79 @@ func cmdDel(args *skel.CmdArgs) error {
 		})
 
 	if err != nil {
+		if daemonUnreachable(err) {
+			if err = delOffline(&conf, args, &k8sConfig, cniNetns, releaseRequest); err != nil {
+				return err
+			}
+			return types.PrintResult(&current.Result{CNIVersion: confVersion}, confVersion)
+		}
 		return errors.Wrap(err, fmt.Sprintf("add cmd: error get ip info from grpc call, pod: %s-%s",
 			string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME),
 		))
 	}
 
-	hostVethName := link.VethNameForPod(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE), defaultVethPrefix)
+	err = teardown(&conf, args, &k8sConfig, infoResult.GetIPType(), infoResult.GetNodeCidr(), infoResult.GetDaemonIPAM(), cniNetns)
+	if err != nil {
+		return err
+	}
+
+	releaseRequest.IPType = infoResult.GetIPType()
+	reply, err := terwayBackendClient.ReleaseIP(context.Background(), releaseRequest)
 
-	switch infoResult.IPType {
+	if err != nil {
+		return errors.Wrapf(err, "error release ip for pod, maybe cause resource leak")
+	}
+	if !reply.GetSuccess() {
+		return fmt.Errorf("error release ip for pod, maybe cause resource leak: %v", reply)
+	}
+	if err = removeResult(args.ContainerID); err != nil {
+		return errors.Wrapf(err, "error remove result of pod")
+	}
+
+	result := &current.Result{
+		CNIVersion: confVersion,
+	}
+
+	return types.PrintResult(result, confVersion)
+}
+
+// teardown network of pod setup for ip type, daemonIPAM whether ip of vpc ip pod released by daemon
+// instead of host-local ipam
+func teardown(conf *NetConf, args *skel.CmdArgs, k8sConfig *K8SArgs, ipType rpc.IPType, nodeCidr string, daemonIPAM bool, cniNetns ns.NetNS) (err error) {
+	if cniNetns == nil {
+		// links in netns of pod gone with it, rules of pod ip left on host
+		if err = teardownHostSide(conf, args, k8sConfig, ipType); err != nil {
+			return errors.Wrapf(err, "error teardown host side network for pod: %s-%s",
+				string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME))
+		}
+		// ips of host-local ipam left on disk
+		if ipType != rpc.IPType_TypeVPCIP || daemonIPAM {
+			return nil
+		}
+		var subnet *net.IPNet
+		if _, subnet, err = net.ParseCIDR(nodeCidr); err != nil {
+			return fmt.Errorf("get info return subnet is not vaild: %v", nodeCidr)
+		}
+		return errors.Wrapf(ipam.ExecDel(delegateIpam, []byte(fmt.Sprintf(delegateConf, subnet.String()))),
+			"error teardown network ipam for pod: %s-%s", string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME))
+	}
+
+	vethNameScheme, err := conf.vethNameScheme()
+	if err != nil {
+		return errors.Wrap(err, "del cmd: invalid veth name config")
+	}
+	hostVethName, err := vethNameScheme.HostVethName(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE))
+	if err != nil {
+		return err
+	}
+
+	switch ipType {
 	case rpc.IPType_TypeENIMultiIP:
 		if conf.ENIIPVirtualType == eniIPVirtualTypeIPVlan {
 			eniMultiIPDriver = driver.IPVlanDriver
+		} else if err = driver.TeardownLinkLocalAccess(hostVethName, args.IfName, cniNetns); err != nil {
+			return errors.Wrapf(err, "error teardown link-local access for pod: %s-%s",
+				string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME))
 		}
 		err = eniMultiIPDriver.Teardown(hostVethName, args.IfName, cniNetns)
 		if err != nil {
@@ -408,9 +738,14 @@ func cmdDel(args *skel.CmdArgs) error {
 		}
 	case rpc.IPType_TypeVPCIP:
 		var subnet *net.IPNet
-		_, subnet, err = net.ParseCIDR(infoResult.GetNodeCidr())
+		_, subnet, err = net.ParseCIDR(nodeCidr)
 		if err != nil {
-			return fmt.Errorf("get info return subnet is not vaild: %v", infoResult.GetNodeCidr())
+			return fmt.Errorf("get info return subnet is not vaild: %v", nodeCidr)
+		}
+		err = driver.TeardownLinkLocalAccess(hostVethName, args.IfName, cniNetns)
+		if err != nil {
+			return errors.Wrapf(err, "error teardown link-local access for pod: %s-%s",
+				string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME))
 		}
 		err = networkDriver.Teardown(hostVethName, args.IfName, cniNetns)
 		if err != nil {
@@ -418,6 +753,9 @@ func cmdDel(args *skel.CmdArgs) error {
 				string(k8sConfig.K8S_POD_NAMESPACE), string(k8sConfig.K8S_POD_NAME))
 		}
 
+		if daemonIPAM {
+			break
+		}
 		err = ipam.ExecDel(delegateIpam, []byte(fmt.Sprintf(delegateConf, subnet.String())))
 		if err != nil {
 			return errors.Wrapf(err, "error teardown network ipam for pod: %s-%s",
@@ -440,31 +778,111 @@ func cmdDel(args *skel.CmdArgs) error {
 		return fmt.Errorf("not support this network type")
 	}
 
-	reply, err := terwayBackendClient.ReleaseIP(
-		context.Background(),
-		&rpc.ReleaseIPRequest{
-			K8SPodName:             string(k8sConfig.K8S_POD_NAME),
-			K8SPodNamespace:        string(k8sConfig.K8S_POD_NAMESPACE),
-			K8SPodInfraContainerId: string(k8sConfig.K8S_POD_INFRA_CONTAINER_ID),
-			IPType:                 infoResult.GetIPType(),
-			Reason:                 "normal release",
-		})
+	return nil
+}
 
-	if err != nil || !reply.GetSuccess() {
-		return fmt.Errorf("error release ip for pod, maybe cause resource leak: %v, %v", err, reply)
+// teardownHostSide cleanup rules of pod on host by the pod ip in result persisted on ADD, for netns of pod
+// already gone, as the rules would apply to the next pod of the ip
+func teardownHostSide(conf *NetConf, args *skel.CmdArgs, k8sConfig *K8SArgs, ipType rpc.IPType) error {
+	// no rules on host of pod with ipvlan
+	if ipType == rpc.IPType_TypeENIMultiIP && conf.ENIIPVirtualType == eniIPVirtualTypeIPVlan {
+		return nil
+	}
+	vethNameScheme, err := conf.vethNameScheme()
+	if err != nil {
+		return errors.Wrap(err, "del cmd: invalid veth name config")
+	}
+	hostVethName, err := vethNameScheme.HostVethName(string(k8sConfig.K8S_POD_NAME), string(k8sConfig.K8S_POD_NAMESPACE))
+	if err != nil {
+		return err
+	}
+	result, err := loadResult(args.ContainerID)
+	if err != nil {
+		return err
 	}
+	return driver.TeardownHostSide(hostVethName, result.podIP())
+}
 
-	result := &current.Result{
-		CNIVersion: confVersion,
+// delOffline teardown network of pod by the result persisted on ADD and queue the release for daemon,
+// so deleting pods, eg: on node drain, not blocked by daemon down
+func delOffline(conf *NetConf, args *skel.CmdArgs, k8sConfig *K8SArgs, cniNetns ns.NetNS, releaseRequest *rpc.ReleaseIPRequest) error {
+	result, err := loadResult(args.ContainerID)
+	if err != nil {
+		return err
+	}
+	// links in netns of sandbox set up without result persisted are removed with the netns
+	if result != nil {
+		if err = teardown(conf, args, k8sConfig, result.IPType, result.NodeCidr, result.DaemonIPAM, cniNetns); err != nil {
+			return err
+		}
+		releaseRequest.IPType = result.IPType
 	}
+	releaseRequest.Reason = "release queued while daemon unreachable"
+	if err = queueRelease(releaseRequest); err != nil {
+		return err
+	}
+	return removeResult(args.ContainerID)
+}
 
-	return types.PrintResult(result, confVersion)
+// podConfigOf the pod config in alloc result of ip type
+func podConfigOf(allocResult *rpc.AllocIPReply) *rpc.Pod {
+	switch allocResult.IPType {
+	case rpc.IPType_TypeENIMultiIP:
+		return allocResult.GetENIMultiIP().GetPodConfig()
+	case rpc.IPType_TypeVPCIP:
+		return allocResult.GetVpcIp().GetPodConfig()
+	case rpc.IPType_TypeVPCENI:
+		return allocResult.GetVpcEni().GetPodConfig()
+	}
+	return nil
 }
 
-func getNetworkClient() (rpc.TerwayBackendClient, func(), error) {
-	grpcConn, err := grpc.Dial(defaultSocketPath, grpc.WithInsecure(), grpc.WithDialer(
+// parsePodRoutes parse routes of pod returned by daemon
+func parsePodRoutes(routes []*rpc.Route) ([]*types.Route, error) {
+	var ret []*types.Route
+	for _, route := range routes {
+		_, dst, err := net.ParseCIDR(route.GetDst())
+		if err != nil {
+			return nil, fmt.Errorf("invalid dst of pod route: %v", route.GetDst())
+		}
+		var gw net.IP
+		if route.GetGateway() != "" {
+			if gw = net.ParseIP(route.GetGateway()); gw == nil {
+				return nil, fmt.Errorf("invalid gateway of pod route: %v", route.GetGateway())
+			}
+		}
+		ret = append(ret, &types.Route{Dst: *dst, GW: gw})
+	}
+	return ret, nil
+}
+
+// interfaceTuningOf return interface tuning of pod, offloads absent kept as is
+func interfaceTuningOf(tuning *rpc.InterfaceTuning) *terwayTypes.InterfaceTuning {
+	ret := &terwayTypes.InterfaceTuning{
+		CombinedQueues: int(tuning.GetCombinedQueues()),
+		RxRing:         int(tuning.GetRxRing()),
+		TxRing:         int(tuning.GetTxRing()),
+	}
+	offload := func(name string) *bool {
+		on, ok := tuning.GetOffloads()[name]
+		if !ok {
+			return nil
+		}
+		return &on
+	}
+	ret.GRO = offload("gro")
+	ret.LRO = offload("lro")
+	ret.TSO = offload("tso")
+	return ret
+}
+
+func getNetworkClient(socketPath string) (rpc.TerwayBackendClient, func(), error) {
+	if socketPath == "" {
+		socketPath = defaultSocketPath
+	}
+	grpcConn, err := grpc.Dial(socketPath, grpc.WithInsecure(), grpc.WithDialer(
 		func(s string, duration time.Duration) (net.Conn, error) {
-			unixAddr, err := net.ResolveUnixAddr("unix", defaultSocketPath)
+			unixAddr, err := net.ResolveUnixAddr("unix", socketPath)
 			if err != nil {
 				return nil, nil
 			}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// ExclusiveENIDNS dns of pods with exclusive eni, whose traffic goes through the eni bypassing kube-proxy and
+// dns cache on host, the nameservers routed through the host veth of pod like the service cidr
+type ExclusiveENIDNS struct {
+	// Nameservers of pods, eg: cluster dns not in service cidr or node local dns cache "169.254.20.10"
+	Nameservers []string `json:"nameservers,omitempty"`
+	// Search domains of pods in cni result
+	Search []string `json:"search,omitempty"`
+	// Options of resolver of pods in cni result, eg: "ndots:5"
+	Options []string `json:"options,omitempty"`
+	// HostRoutes extra cidrs routed through the host veth, eg: upstream dns servers reached by host network
+	HostRoutes []string `json:"host_routes,omitempty"`
+}
+
+// routes return routes of nameservers and host routes through the host veth of gateway
+func (d *ExclusiveENIDNS) routes(gw net.IP) ([]*types.Route, error) {
+	var routes []*types.Route
+	for _, nameserver := range d.Nameservers {
+		ip := net.ParseIP(nameserver)
+		if ip == nil || ip.To4() == nil {
+			return nil, fmt.Errorf("invalid nameserver of exclusive eni dns: %s", nameserver)
+		}
+		routes = append(routes, &types.Route{Dst: net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}, GW: gw})
+	}
+	for _, cidr := range d.HostRoutes {
+		_, dst, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host route of exclusive eni dns: %s", cidr)
+		}
+		routes = append(routes, &types.Route{Dst: *dst, GW: gw})
+	}
+	return routes, nil
+}
+
+// dns return dns of cni result, applied by runtimes honoring dns of cni result
+func (d *ExclusiveENIDNS) dns() types.DNS {
+	return types.DNS{
+		Nameservers: d.Nameservers,
+		Search:      d.Search,
+		Options:     d.Options,
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/AliyunContainerService/terway/version"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// handshake negotiate api version and features with daemon, daemon before
+// handshake is treated as legacy api version without features
+func handshake(ctx context.Context, client rpc.TerwayBackendClient) (map[string]bool, error) {
+	apiVersion := int32(version.LegacyAPIVersion)
+	var features []string
+	reply, err := client.Handshake(ctx, &rpc.HandshakeRequest{
+		APIVersion: version.APIVersion,
+		Features:   version.Features,
+	})
+	switch {
+	case err == nil:
+		apiVersion = reply.GetAPIVersion()
+		features = version.NegotiateFeatures(version.Features, reply.GetFeatures())
+	case status.Code(err) != codes.Unimplemented:
+		return nil, errors.Wrap(err, "error handshake with terway daemon")
+	}
+
+	if err = version.CheckAPIVersion(apiVersion); err != nil {
+		return nil, errors.Wrap(err, "terway daemon incompatible with cni binary, upgrade the daemon or cni binary")
+	}
+	ret := make(map[string]bool, len(features))
+	for _, f := range features {
+		ret[f] = true
+	}
+	return ret, nil
+}
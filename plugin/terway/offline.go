@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pendingReleaseDir releases of pods deleted while daemon unreachable, replayed by daemon on start
+const pendingReleaseDir = "/var/lib/cni/terway/pending-release"
+
+// queueRelease queue release of pod for daemon to replay on start
+func queueRelease(req *rpc.ReleaseIPRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s_%s.json", req.K8SPodNamespace, req.K8SPodName, req.K8SPodInfraContainerId)
+	return errors.Wrapf(writeFileAtomic(filepath.Join(pendingReleaseDir, name), data), "error queue release of pod %s/%s",
+		req.K8SPodNamespace, req.K8SPodName)
+}
+
+// daemonUnreachable return whether error of grpc call caused by daemon not serving
+func daemonUnreachable(err error) bool {
+	return status.Code(errors.Cause(err)) == codes.Unavailable
+}
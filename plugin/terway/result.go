@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/AliyunContainerService/terway/rpc"
+	"github.com/containernetworking/cni/pkg/types/current"
+	"github.com/pkg/errors"
+)
+
+const (
+	// resultDir network result of pods by sandbox, for CHECK and DEL without daemon or netns
+	resultDir = "/var/lib/cni/terway/cni-cache"
+
+	// resultVersion version of result files written by this binary:
+	// 1: ip type and node cidr of sandbox, no version field
+	// 2: with pod and cni result of ADD
+	resultVersion = 2
+)
+
+// podResult network result of pod sandbox persisted on ADD
+type podResult struct {
+	Version      int        `json:"version"`
+	PodName      string     `json:"pod_name,omitempty"`
+	PodNamespace string     `json:"pod_namespace,omitempty"`
+	IfName       string     `json:"if_name,omitempty"`
+	IPType       rpc.IPType `json:"ip_type"`
+	NodeCidr     string     `json:"node_cidr,omitempty"`
+	// DaemonIPAM ip of pod with vpc ip allocated and released by daemon instead of host-local ipam
+	DaemonIPAM bool            `json:"daemon_ipam,omitempty"`
+	Result     *current.Result `json:"result,omitempty"`
+}
+
+// resultMigrations migrate result of version to the next version
+var resultMigrations = map[int]func(result *podResult) error{
+	// no cni result recorded by version 1, CHECK of the sandbox fails until recreated
+	1: func(result *podResult) error { return nil },
+}
+
+// writeFileAtomic write data to file by rename, readers never see partial file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// saveResult persist network result of sandbox in current version
+func saveResult(containerID string, result *podResult) error {
+	result.Version = resultVersion
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(writeFileAtomic(filepath.Join(resultDir, containerID), data), "error persist result of sandbox %s", containerID)
+}
+
+// parseResult parse result file of any version known, migrated to current version
+func parseResult(data []byte) (*podResult, error) {
+	result := &podResult{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, errors.Wrapf(err, "error parse result")
+	}
+	if result.Version == 0 {
+		result.Version = 1
+	}
+	if result.Version > resultVersion {
+		return nil, fmt.Errorf("result version %d written by newer cni binary, supported up to %d", result.Version, resultVersion)
+	}
+	for ; result.Version < resultVersion; result.Version++ {
+		if err := resultMigrations[result.Version](result); err != nil {
+			return nil, errors.Wrapf(err, "error migrate result from version %d", result.Version)
+		}
+	}
+	return result, nil
+}
+
+// loadResult return persisted network result of sandbox, nil if not persisted
+func loadResult(containerID string) (*podResult, error) {
+	data, err := ioutil.ReadFile(filepath.Join(resultDir, containerID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result, err := parseResult(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error load result of sandbox %s", containerID)
+	}
+	return result, nil
+}
+
+// podIP ip of pod in cni result, nil if no result recorded
+func (r *podResult) podIP() net.IP {
+	if r == nil || r.Result == nil || len(r.Result.IPs) == 0 {
+		return nil
+	}
+	return r.Result.IPs[0].Address.IP
+}
+
+// removeResult remove persisted network result of sandbox
+func removeResult(containerID string) error {
+	err := os.Remove(filepath.Join(resultDir, containerID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -127,11 +127,15 @@ func (m *AllocIPRequest) GetIfName() string {
 
 // VETH Basic
 type Pod struct {
-	Ingress              uint64   `protobuf:"varint,1,opt,name=Ingress,proto3" json:"Ingress,omitempty"`
-	Egress               uint64   `protobuf:"varint,2,opt,name=Egress,proto3" json:"Egress,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Ingress              uint64            `protobuf:"varint,1,opt,name=Ingress,proto3" json:"Ingress,omitempty"`
+	Egress               uint64            `protobuf:"varint,2,opt,name=Egress,proto3" json:"Egress,omitempty"`
+	LinkLocalAccess      string            `protobuf:"bytes,3,opt,name=LinkLocalAccess,proto3" json:"LinkLocalAccess,omitempty"`
+	Routes               []*Route          `protobuf:"bytes,4,rep,name=Routes,proto3" json:"Routes,omitempty"`
+	Sysctls              map[string]string `protobuf:"bytes,5,rep,name=Sysctls,proto3" json:"Sysctls,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	InterfaceTuning      *InterfaceTuning  `protobuf:"bytes,6,opt,name=InterfaceTuning,proto3" json:"InterfaceTuning,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *Pod) Reset()         { *m = Pod{} }
@@ -173,10 +177,153 @@ func (m *Pod) GetEgress() uint64 {
 	return 0
 }
 
+func (m *Pod) GetLinkLocalAccess() string {
+	if m != nil {
+		return m.LinkLocalAccess
+	}
+	return ""
+}
+
+func (m *Pod) GetRoutes() []*Route {
+	if m != nil {
+		return m.Routes
+	}
+	return nil
+}
+
+func (m *Pod) GetSysctls() map[string]string {
+	if m != nil {
+		return m.Sysctls
+	}
+	return nil
+}
+
+func (m *Pod) GetInterfaceTuning() *InterfaceTuning {
+	if m != nil {
+		return m.InterfaceTuning
+	}
+	return nil
+}
+
+// InterfaceTuning queues, ring sizes and offloads of interface of pod with exclusive eni, 0 or absent kept as is
+type InterfaceTuning struct {
+	CombinedQueues int32 `protobuf:"varint,1,opt,name=CombinedQueues,proto3" json:"CombinedQueues,omitempty"`
+	RxRing         int32 `protobuf:"varint,2,opt,name=RxRing,proto3" json:"RxRing,omitempty"`
+	TxRing         int32 `protobuf:"varint,3,opt,name=TxRing,proto3" json:"TxRing,omitempty"`
+	// Offloads on or off by name: gro, lro or tso
+	Offloads             map[string]bool `protobuf:"bytes,4,rep,name=Offloads,proto3" json:"Offloads,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *InterfaceTuning) Reset()         { *m = InterfaceTuning{} }
+func (m *InterfaceTuning) String() string { return proto.CompactTextString(m) }
+func (*InterfaceTuning) ProtoMessage()    {}
+func (*InterfaceTuning) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{2}
+}
+
+func (m *InterfaceTuning) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InterfaceTuning.Unmarshal(m, b)
+}
+func (m *InterfaceTuning) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InterfaceTuning.Marshal(b, m, deterministic)
+}
+func (m *InterfaceTuning) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InterfaceTuning.Merge(m, src)
+}
+func (m *InterfaceTuning) XXX_Size() int {
+	return xxx_messageInfo_InterfaceTuning.Size(m)
+}
+func (m *InterfaceTuning) XXX_DiscardUnknown() {
+	xxx_messageInfo_InterfaceTuning.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InterfaceTuning proto.InternalMessageInfo
+
+func (m *InterfaceTuning) GetCombinedQueues() int32 {
+	if m != nil {
+		return m.CombinedQueues
+	}
+	return 0
+}
+
+func (m *InterfaceTuning) GetRxRing() int32 {
+	if m != nil {
+		return m.RxRing
+	}
+	return 0
+}
+
+func (m *InterfaceTuning) GetTxRing() int32 {
+	if m != nil {
+		return m.TxRing
+	}
+	return 0
+}
+
+func (m *InterfaceTuning) GetOffloads() map[string]bool {
+	if m != nil {
+		return m.Offloads
+	}
+	return nil
+}
+
+type Route struct {
+	Dst                  string   `protobuf:"bytes,1,opt,name=Dst,proto3" json:"Dst,omitempty"`
+	Gateway              string   `protobuf:"bytes,2,opt,name=Gateway,proto3" json:"Gateway,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Route) Reset()         { *m = Route{} }
+func (m *Route) String() string { return proto.CompactTextString(m) }
+func (*Route) ProtoMessage()    {}
+func (*Route) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{3}
+}
+
+func (m *Route) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Route.Unmarshal(m, b)
+}
+func (m *Route) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Route.Marshal(b, m, deterministic)
+}
+func (m *Route) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Route.Merge(m, src)
+}
+func (m *Route) XXX_Size() int {
+	return xxx_messageInfo_Route.Size(m)
+}
+func (m *Route) XXX_DiscardUnknown() {
+	xxx_messageInfo_Route.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Route proto.InternalMessageInfo
+
+func (m *Route) GetDst() string {
+	if m != nil {
+		return m.Dst
+	}
+	return ""
+}
+
+func (m *Route) GetGateway() string {
+	if m != nil {
+		return m.Gateway
+	}
+	return ""
+}
+
 // VPC route veth
 type VPCIP struct {
-	PodConfig            *Pod     `protobuf:"bytes,1,opt,name=PodConfig,proto3" json:"PodConfig,omitempty"`
-	NodeCidr             string   `protobuf:"bytes,2,opt,name=NodeCidr,proto3" json:"NodeCidr,omitempty"`
+	PodConfig *Pod   `protobuf:"bytes,1,opt,name=PodConfig,proto3" json:"PodConfig,omitempty"`
+	NodeCidr  string `protobuf:"bytes,2,opt,name=NodeCidr,proto3" json:"NodeCidr,omitempty"`
+	// PodIP ip of pod with mask of node cidr allocated by daemon, empty if left to host-local ipam of cni binary
+	PodIP                string   `protobuf:"bytes,3,opt,name=PodIP,proto3" json:"PodIP,omitempty"`
+	Gateway              string   `protobuf:"bytes,4,opt,name=Gateway,proto3" json:"Gateway,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -186,7 +333,7 @@ func (m *VPCIP) Reset()         { *m = VPCIP{} }
 func (m *VPCIP) String() string { return proto.CompactTextString(m) }
 func (*VPCIP) ProtoMessage()    {}
 func (*VPCIP) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{2}
+	return fileDescriptor_77a6da22d6a3feb1, []int{4}
 }
 
 func (m *VPCIP) XXX_Unmarshal(b []byte) error {
@@ -221,6 +368,20 @@ func (m *VPCIP) GetNodeCidr() string {
 	return ""
 }
 
+func (m *VPCIP) GetPodIP() string {
+	if m != nil {
+		return m.PodIP
+	}
+	return ""
+}
+
+func (m *VPCIP) GetGateway() string {
+	if m != nil {
+		return m.Gateway
+	}
+	return ""
+}
+
 // ENI Basic
 type ENI struct {
 	IPv4Addr             string   `protobuf:"bytes,1,opt,name=IPv4Addr,proto3" json:"IPv4Addr,omitempty"`
@@ -229,6 +390,7 @@ type ENI struct {
 	Gateway              string   `protobuf:"bytes,4,opt,name=Gateway,proto3" json:"Gateway,omitempty"`
 	DeviceNumber         int32    `protobuf:"varint,5,opt,name=DeviceNumber,proto3" json:"DeviceNumber,omitempty"`
 	PrimaryIPv4Addr      string   `protobuf:"bytes,6,opt,name=PrimaryIPv4Addr,proto3" json:"PrimaryIPv4Addr,omitempty"`
+	RouteTableID         int32    `protobuf:"varint,7,opt,name=RouteTableID,proto3" json:"RouteTableID,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -238,7 +400,7 @@ func (m *ENI) Reset()         { *m = ENI{} }
 func (m *ENI) String() string { return proto.CompactTextString(m) }
 func (*ENI) ProtoMessage()    {}
 func (*ENI) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{3}
+	return fileDescriptor_77a6da22d6a3feb1, []int{5}
 }
 
 func (m *ENI) XXX_Unmarshal(b []byte) error {
@@ -301,6 +463,13 @@ func (m *ENI) GetPrimaryIPv4Addr() string {
 	return ""
 }
 
+func (m *ENI) GetRouteTableID() int32 {
+	if m != nil {
+		return m.RouteTableID
+	}
+	return 0
+}
+
 // Dedicated ENI
 type VPCENI struct {
 	EniConfig            *ENI     `protobuf:"bytes,1,opt,name=EniConfig,proto3" json:"EniConfig,omitempty"`
@@ -315,7 +484,7 @@ func (m *VPCENI) Reset()         { *m = VPCENI{} }
 func (m *VPCENI) String() string { return proto.CompactTextString(m) }
 func (*VPCENI) ProtoMessage()    {}
 func (*VPCENI) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{4}
+	return fileDescriptor_77a6da22d6a3feb1, []int{6}
 }
 
 func (m *VPCENI) XXX_Unmarshal(b []byte) error {
@@ -374,7 +543,7 @@ func (m *ManagedK8SENI) Reset()         { *m = ManagedK8SENI{} }
 func (m *ManagedK8SENI) String() string { return proto.CompactTextString(m) }
 func (*ManagedK8SENI) ProtoMessage()    {}
 func (*ManagedK8SENI) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{5}
+	return fileDescriptor_77a6da22d6a3feb1, []int{7}
 }
 
 func (m *ManagedK8SENI) XXX_Unmarshal(b []byte) error {
@@ -450,7 +619,7 @@ func (m *ENIMultiIP) Reset()         { *m = ENIMultiIP{} }
 func (m *ENIMultiIP) String() string { return proto.CompactTextString(m) }
 func (*ENIMultiIP) ProtoMessage()    {}
 func (*ENIMultiIP) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{6}
+	return fileDescriptor_77a6da22d6a3feb1, []int{8}
 }
 
 func (m *ENIMultiIP) XXX_Unmarshal(b []byte) error {
@@ -503,7 +672,7 @@ func (m *AllocIPReply) Reset()         { *m = AllocIPReply{} }
 func (m *AllocIPReply) String() string { return proto.CompactTextString(m) }
 func (*AllocIPReply) ProtoMessage()    {}
 func (*AllocIPReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{7}
+	return fileDescriptor_77a6da22d6a3feb1, []int{9}
 }
 
 func (m *AllocIPReply) XXX_Unmarshal(b []byte) error {
@@ -730,7 +899,7 @@ func (m *ReleaseIPRequest) Reset()         { *m = ReleaseIPRequest{} }
 func (m *ReleaseIPRequest) String() string { return proto.CompactTextString(m) }
 func (*ReleaseIPRequest) ProtoMessage()    {}
 func (*ReleaseIPRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{8}
+	return fileDescriptor_77a6da22d6a3feb1, []int{10}
 }
 
 func (m *ReleaseIPRequest) XXX_Unmarshal(b []byte) error {
@@ -813,7 +982,7 @@ func (m *ReleaseIPReply) Reset()         { *m = ReleaseIPReply{} }
 func (m *ReleaseIPReply) String() string { return proto.CompactTextString(m) }
 func (*ReleaseIPReply) ProtoMessage()    {}
 func (*ReleaseIPReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{9}
+	return fileDescriptor_77a6da22d6a3feb1, []int{11}
 }
 
 func (m *ReleaseIPReply) XXX_Unmarshal(b []byte) error {
@@ -868,7 +1037,7 @@ func (m *GetInfoRequest) Reset()         { *m = GetInfoRequest{} }
 func (m *GetInfoRequest) String() string { return proto.CompactTextString(m) }
 func (*GetInfoRequest) ProtoMessage()    {}
 func (*GetInfoRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{10}
+	return fileDescriptor_77a6da22d6a3feb1, []int{12}
 }
 
 func (m *GetInfoRequest) XXX_Unmarshal(b []byte) error {
@@ -911,9 +1080,11 @@ func (m *GetInfoRequest) GetK8SPodInfraContainerId() string {
 }
 
 type GetInfoReply struct {
-	IPType               IPType   `protobuf:"varint,1,opt,name=IPType,proto3,enum=rpc.IPType" json:"IPType,omitempty"`
-	PodConfig            *Pod     `protobuf:"bytes,2,opt,name=PodConfig,proto3" json:"PodConfig,omitempty"`
-	NodeCidr             string   `protobuf:"bytes,3,opt,name=NodeCidr,proto3" json:"NodeCidr,omitempty"`
+	IPType    IPType `protobuf:"varint,1,opt,name=IPType,proto3,enum=rpc.IPType" json:"IPType,omitempty"`
+	PodConfig *Pod   `protobuf:"bytes,2,opt,name=PodConfig,proto3" json:"PodConfig,omitempty"`
+	NodeCidr  string `protobuf:"bytes,3,opt,name=NodeCidr,proto3" json:"NodeCidr,omitempty"`
+	// DaemonIPAM ip of pod with vpc ip released by daemon, not host-local ipam of cni binary
+	DaemonIPAM           bool     `protobuf:"varint,4,opt,name=DaemonIPAM,proto3" json:"DaemonIPAM,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -923,7 +1094,7 @@ func (m *GetInfoReply) Reset()         { *m = GetInfoReply{} }
 func (m *GetInfoReply) String() string { return proto.CompactTextString(m) }
 func (*GetInfoReply) ProtoMessage()    {}
 func (*GetInfoReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{11}
+	return fileDescriptor_77a6da22d6a3feb1, []int{13}
 }
 
 func (m *GetInfoReply) XXX_Unmarshal(b []byte) error {
@@ -965,167 +1136,1556 @@ func (m *GetInfoReply) GetNodeCidr() string {
 	return ""
 }
 
-func init() {
-	proto.RegisterEnum("rpc.IPType", IPType_name, IPType_value)
-	proto.RegisterType((*AllocIPRequest)(nil), "rpc.AllocIPRequest")
-	proto.RegisterType((*Pod)(nil), "rpc.Pod")
-	proto.RegisterType((*VPCIP)(nil), "rpc.VPCIP")
-	proto.RegisterType((*ENI)(nil), "rpc.ENI")
-	proto.RegisterType((*VPCENI)(nil), "rpc.VPCENI")
-	proto.RegisterType((*ManagedK8SENI)(nil), "rpc.ManagedK8SENI")
-	proto.RegisterType((*ENIMultiIP)(nil), "rpc.ENIMultiIP")
-	proto.RegisterType((*AllocIPReply)(nil), "rpc.AllocIPReply")
-	proto.RegisterType((*ReleaseIPRequest)(nil), "rpc.ReleaseIPRequest")
-	proto.RegisterType((*ReleaseIPReply)(nil), "rpc.ReleaseIPReply")
-	proto.RegisterType((*GetInfoRequest)(nil), "rpc.GetInfoRequest")
-	proto.RegisterType((*GetInfoReply)(nil), "rpc.GetInfoReply")
+func (m *GetInfoReply) GetDaemonIPAM() bool {
+	if m != nil {
+		return m.DaemonIPAM
+	}
+	return false
 }
 
-func init() { proto.RegisterFile("rpc.proto", fileDescriptor_77a6da22d6a3feb1) }
+type HandshakeRequest struct {
+	APIVersion           int32    `protobuf:"varint,1,opt,name=APIVersion,proto3" json:"APIVersion,omitempty"`
+	Features             []string `protobuf:"bytes,2,rep,name=Features,proto3" json:"Features,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
 
-var fileDescriptor_77a6da22d6a3feb1 = []byte{
-	// 761 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x56, 0x4f, 0x4f, 0xdb, 0x4a,
-	0x10, 0x8f, 0x13, 0xe2, 0x90, 0x31, 0x09, 0x79, 0xcb, 0x7b, 0x28, 0xe2, 0xf0, 0x84, 0xf6, 0xe9,
-	0x21, 0xd4, 0x03, 0x52, 0x03, 0x6d, 0xe9, 0x11, 0x52, 0x0b, 0x2c, 0x84, 0x6b, 0x39, 0x28, 0xa7,
-	0x5e, 0x16, 0x7b, 0x83, 0x5c, 0x82, 0xed, 0xae, 0x1d, 0xa2, 0xf4, 0x0b, 0xf4, 0x5a, 0xf5, 0xc3,
-	0xf4, 0x5c, 0xa9, 0x9f, 0xa0, 0xdf, 0xa8, 0xda, 0x3f, 0x8e, 0xff, 0xd0, 0x54, 0x3d, 0xb4, 0x12,
-	0xa7, 0xe4, 0xf7, 0x9b, 0xd9, 0x9d, 0x99, 0xdf, 0xec, 0x4c, 0x02, 0x6d, 0x16, 0x7b, 0x07, 0x31,
-	0x8b, 0xd2, 0x08, 0x35, 0x58, 0xec, 0xe1, 0x2f, 0x1a, 0x74, 0x4f, 0xa6, 0xd3, 0xc8, 0xb3, 0x1c,
-	0x97, 0xbe, 0x9b, 0xd1, 0x24, 0x45, 0xff, 0x02, 0x5c, 0x1c, 0x27, 0x4e, 0xe4, 0xdb, 0xe4, 0x8e,
-	0xf6, 0xb5, 0x5d, 0x6d, 0xbf, 0xed, 0x16, 0x18, 0xb4, 0x0f, 0x9b, 0x39, 0x4a, 0x62, 0xe2, 0xd1,
-	0x7e, 0x5d, 0x38, 0x55, 0x69, 0xf4, 0x1c, 0xb6, 0x25, 0x65, 0x85, 0x13, 0x46, 0x86, 0x51, 0x98,
-	0x92, 0x20, 0xa4, 0xcc, 0xf2, 0xfb, 0x0d, 0x71, 0x60, 0x85, 0x15, 0xfd, 0x0d, 0x4d, 0x9b, 0xa6,
-	0x61, 0xd2, 0x5f, 0x13, 0x6e, 0x12, 0xa0, 0x6d, 0xd0, 0xad, 0x89, 0xc8, 0xa9, 0x29, 0x68, 0x85,
-	0xf0, 0x0b, 0x68, 0x38, 0x91, 0x8f, 0xfa, 0xd0, 0xb2, 0xc2, 0x1b, 0x46, 0x93, 0x44, 0xe4, 0xbc,
-	0xe6, 0x66, 0x90, 0x1f, 0x34, 0xa5, 0xa1, 0x2e, 0x0c, 0x0a, 0xe1, 0x0b, 0x68, 0x8e, 0x9d, 0xa1,
-	0xe5, 0xa0, 0x3d, 0x68, 0x3b, 0x91, 0x3f, 0x8c, 0xc2, 0x49, 0x70, 0x23, 0x0e, 0x1b, 0x83, 0xf5,
-	0x03, 0x2e, 0x94, 0x13, 0xf9, 0x6e, 0x6e, 0x42, 0x3b, 0xb0, 0x6e, 0x47, 0x3e, 0x1d, 0x06, 0x3e,
-	0x53, 0x25, 0x2f, 0x31, 0xfe, 0xaa, 0x41, 0xc3, 0xb4, 0x2d, 0xee, 0x63, 0x39, 0xf7, 0x47, 0x27,
-	0xbe, 0xcf, 0x94, 0x76, 0x4b, 0xcc, 0x95, 0xe5, 0xdf, 0x47, 0xb3, 0xeb, 0x90, 0xa6, 0xea, 0x86,
-	0x02, 0xc3, 0x4b, 0xb8, 0x24, 0x9e, 0x38, 0x2a, 0x05, 0xca, 0x20, 0xb7, 0x9c, 0x91, 0x94, 0xce,
-	0xc9, 0x42, 0x69, 0x92, 0x41, 0x84, 0x61, 0xe3, 0x15, 0xbd, 0x0f, 0x3c, 0x6a, 0xcf, 0xee, 0xae,
-	0x29, 0x13, 0xda, 0x34, 0xdd, 0x12, 0xc7, 0x3b, 0xe6, 0xb0, 0xe0, 0x8e, 0xb0, 0xc5, 0x32, 0x35,
-	0x5d, 0x76, 0xac, 0x42, 0xe3, 0xf7, 0xa0, 0x8f, 0x9d, 0x21, 0xaf, 0x63, 0x0f, 0xda, 0x66, 0x18,
-	0xfc, 0x40, 0x13, 0xd3, 0xb6, 0xdc, 0xdc, 0x54, 0xd6, 0xae, 0xbe, 0x5a, 0xbb, 0x5d, 0x30, 0x46,
-	0x94, 0xf1, 0xa4, 0x84, 0x7c, 0xb2, 0xbe, 0x22, 0x85, 0xbf, 0x69, 0xd0, 0xb9, 0x24, 0x21, 0xb9,
-	0xa1, 0xfe, 0xc5, 0xf1, 0xe8, 0x4f, 0xe4, 0xd0, 0x87, 0x16, 0x07, 0x79, 0xfc, 0x0c, 0x72, 0xcb,
-	0x38, 0xf6, 0x84, 0x45, 0xe9, 0xab, 0x60, 0xa9, 0xe7, 0xcd, 0x72, 0xcf, 0xab, 0x35, 0xe9, 0x0f,
-	0x6b, 0x7a, 0x03, 0x60, 0xda, 0xd6, 0xe5, 0x6c, 0x9a, 0x06, 0xf2, 0x9d, 0xfd, 0xce, 0x7a, 0xf0,
-	0xc7, 0x3a, 0x6c, 0x2c, 0x87, 0x37, 0x9e, 0x2e, 0x78, 0x19, 0xa3, 0x99, 0xe7, 0x65, 0x33, 0xb0,
-	0xee, 0x66, 0x10, 0xfd, 0x07, 0xba, 0xe5, 0x5c, 0x2d, 0x62, 0x39, 0xab, 0xdd, 0x81, 0x21, 0xee,
-	0x93, 0x94, 0xab, 0x4c, 0x08, 0x43, 0x73, 0x1c, 0x7b, 0x56, 0x2c, 0xd4, 0x31, 0x06, 0x20, 0x7c,
-	0xc4, 0x88, 0x9c, 0xd7, 0x5c, 0x69, 0x42, 0xff, 0x83, 0x3e, 0x8e, 0x3d, 0x33, 0x0c, 0x84, 0x50,
-	0x86, 0xba, 0x48, 0x3e, 0x9a, 0xf3, 0x9a, 0xab, 0x8c, 0xe8, 0x08, 0x20, 0xef, 0xa5, 0x10, 0xce,
-	0x18, 0x20, 0xe1, 0x5a, 0x6a, 0xf1, 0x79, 0xcd, 0x2d, 0xf8, 0xa1, 0xa7, 0x45, 0xb9, 0x84, 0x9e,
-	0xc6, 0x60, 0x33, 0x53, 0x48, 0xd1, 0xfc, 0x48, 0x8e, 0x4e, 0x3b, 0x60, 0xd8, 0x34, 0x9d, 0x47,
-	0xec, 0xd6, 0x0a, 0x27, 0x11, 0xfe, 0x50, 0x87, 0x9e, 0x4b, 0xa7, 0x94, 0x24, 0xf4, 0x31, 0x6d,
-	0xb4, 0x5c, 0xfe, 0xb5, 0xd5, 0xf2, 0x17, 0x57, 0x47, 0xb3, 0xb2, 0x3a, 0x0a, 0xab, 0x41, 0x2f,
-	0xaf, 0x86, 0x6d, 0xd0, 0x5d, 0x4a, 0x92, 0x28, 0xec, 0xb7, 0xe4, 0x5a, 0x94, 0x08, 0xbf, 0x85,
-	0x6e, 0x41, 0x88, 0x9f, 0xbf, 0x8e, 0x62, 0xe4, 0x7a, 0x25, 0x72, 0x75, 0xc1, 0x34, 0x1e, 0x2e,
-	0x18, 0xfc, 0x49, 0x83, 0xee, 0x19, 0x4d, 0x79, 0x07, 0x1e, 0x8d, 0xe6, 0x78, 0x0e, 0x1b, 0xcb,
-	0x9c, 0x78, 0xf9, 0x79, 0x0f, 0xb4, 0xd5, 0x3d, 0xf8, 0xd5, 0x55, 0x52, 0x5c, 0x0b, 0x8d, 0xf2,
-	0x5a, 0x78, 0xf2, 0x3a, 0x0b, 0x84, 0x3a, 0xd0, 0xe6, 0x9f, 0x62, 0x84, 0x7a, 0x35, 0xd4, 0x05,
-	0x50, 0xd0, 0xb4, 0xad, 0x9e, 0x86, 0x10, 0x74, 0x39, 0xce, 0x07, 0xa0, 0x57, 0xcf, 0xb8, 0xfc,
-	0x85, 0xf7, 0x1a, 0x83, 0xcf, 0x1a, 0x74, 0xae, 0x28, 0x9b, 0x93, 0xc5, 0x29, 0xf1, 0x6e, 0x69,
-	0xe8, 0xa3, 0x43, 0x68, 0xa9, 0xc1, 0x47, 0x5b, 0x22, 0xbd, 0xf2, 0x6f, 0xf8, 0xce, 0x5f, 0x65,
-	0x32, 0x9e, 0x2e, 0x70, 0x0d, 0xbd, 0x84, 0xf6, 0xf2, 0x45, 0xa0, 0x7f, 0x84, 0x47, 0x75, 0x54,
-	0x76, 0xb6, 0xaa, 0xb4, 0x3c, 0xfa, 0x0c, 0xda, 0x5c, 0x4b, 0x87, 0xab, 0xa9, 0x22, 0x96, 0xfb,
-	0xad, 0x22, 0x16, 0x05, 0xc7, 0xb5, 0x6b, 0x5d, 0xfc, 0xd3, 0x38, 0xfc, 0x1e, 0x00, 0x00, 0xff,
-	0xff, 0xb6, 0x91, 0x53, 0xdc, 0x76, 0x08, 0x00, 0x00,
+func (m *HandshakeRequest) Reset()         { *m = HandshakeRequest{} }
+func (m *HandshakeRequest) String() string { return proto.CompactTextString(m) }
+func (*HandshakeRequest) ProtoMessage()    {}
+func (*HandshakeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{14}
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *HandshakeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HandshakeRequest.Unmarshal(m, b)
+}
+func (m *HandshakeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HandshakeRequest.Marshal(b, m, deterministic)
+}
+func (m *HandshakeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HandshakeRequest.Merge(m, src)
+}
+func (m *HandshakeRequest) XXX_Size() int {
+	return xxx_messageInfo_HandshakeRequest.Size(m)
+}
+func (m *HandshakeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HandshakeRequest.DiscardUnknown(m)
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+var xxx_messageInfo_HandshakeRequest proto.InternalMessageInfo
 
-// TerwayBackendClient is the client API for TerwayBackend service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type TerwayBackendClient interface {
-	AllocIP(ctx context.Context, in *AllocIPRequest, opts ...grpc.CallOption) (*AllocIPReply, error)
-	ReleaseIP(ctx context.Context, in *ReleaseIPRequest, opts ...grpc.CallOption) (*ReleaseIPReply, error)
-	GetIPInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoReply, error)
+func (m *HandshakeRequest) GetAPIVersion() int32 {
+	if m != nil {
+		return m.APIVersion
+	}
+	return 0
 }
 
-type terwayBackendClient struct {
-	cc *grpc.ClientConn
+func (m *HandshakeRequest) GetFeatures() []string {
+	if m != nil {
+		return m.Features
+	}
+	return nil
 }
 
-func NewTerwayBackendClient(cc *grpc.ClientConn) TerwayBackendClient {
-	return &terwayBackendClient{cc}
+type HandshakeReply struct {
+	APIVersion           int32    `protobuf:"varint,1,opt,name=APIVersion,proto3" json:"APIVersion,omitempty"`
+	Features             []string `protobuf:"bytes,2,rep,name=Features,proto3" json:"Features,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (c *terwayBackendClient) AllocIP(ctx context.Context, in *AllocIPRequest, opts ...grpc.CallOption) (*AllocIPReply, error) {
-	out := new(AllocIPReply)
-	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/AllocIP", in, out, opts...)
-	if err != nil {
-		return nil, err
-	}
-	return out, nil
+func (m *HandshakeReply) Reset()         { *m = HandshakeReply{} }
+func (m *HandshakeReply) String() string { return proto.CompactTextString(m) }
+func (*HandshakeReply) ProtoMessage()    {}
+func (*HandshakeReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{15}
 }
 
-func (c *terwayBackendClient) ReleaseIP(ctx context.Context, in *ReleaseIPRequest, opts ...grpc.CallOption) (*ReleaseIPReply, error) {
-	out := new(ReleaseIPReply)
-	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/ReleaseIP", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *HandshakeReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HandshakeReply.Unmarshal(m, b)
+}
+func (m *HandshakeReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HandshakeReply.Marshal(b, m, deterministic)
+}
+func (m *HandshakeReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HandshakeReply.Merge(m, src)
+}
+func (m *HandshakeReply) XXX_Size() int {
+	return xxx_messageInfo_HandshakeReply.Size(m)
+}
+func (m *HandshakeReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_HandshakeReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HandshakeReply proto.InternalMessageInfo
+
+func (m *HandshakeReply) GetAPIVersion() int32 {
+	if m != nil {
+		return m.APIVersion
 	}
-	return out, nil
+	return 0
 }
 
-func (c *terwayBackendClient) GetIPInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoReply, error) {
-	out := new(GetInfoReply)
-	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/GetIPInfo", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *HandshakeReply) GetFeatures() []string {
+	if m != nil {
+		return m.Features
 	}
-	return out, nil
+	return nil
 }
 
-// TerwayBackendServer is the server API for TerwayBackend service.
-type TerwayBackendServer interface {
-	AllocIP(context.Context, *AllocIPRequest) (*AllocIPReply, error)
-	ReleaseIP(context.Context, *ReleaseIPRequest) (*ReleaseIPReply, error)
-	GetIPInfo(context.Context, *GetInfoRequest) (*GetInfoReply, error)
+type CaptureRequest struct {
+	K8SPodName           string   `protobuf:"bytes,1,opt,name=K8sPodName,proto3" json:"K8sPodName,omitempty"`
+	K8SPodNamespace      string   `protobuf:"bytes,2,opt,name=K8sPodNamespace,proto3" json:"K8sPodNamespace,omitempty"`
+	Filter               string   `protobuf:"bytes,3,opt,name=Filter,proto3" json:"Filter,omitempty"`
+	DurationSeconds      int32    `protobuf:"varint,4,opt,name=DurationSeconds,proto3" json:"DurationSeconds,omitempty"`
+	MaxPackets           int32    `protobuf:"varint,5,opt,name=MaxPackets,proto3" json:"MaxPackets,omitempty"`
+	SnapLen              int32    `protobuf:"varint,6,opt,name=SnapLen,proto3" json:"SnapLen,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func RegisterTerwayBackendServer(s *grpc.Server, srv TerwayBackendServer) {
-	s.RegisterService(&_TerwayBackend_serviceDesc, srv)
+func (m *CaptureRequest) Reset()         { *m = CaptureRequest{} }
+func (m *CaptureRequest) String() string { return proto.CompactTextString(m) }
+func (*CaptureRequest) ProtoMessage()    {}
+func (*CaptureRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{16}
 }
 
-func _TerwayBackend_AllocIP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(AllocIPRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(TerwayBackendServer).AllocIP(ctx, in)
+func (m *CaptureRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CaptureRequest.Unmarshal(m, b)
+}
+func (m *CaptureRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CaptureRequest.Marshal(b, m, deterministic)
+}
+func (m *CaptureRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CaptureRequest.Merge(m, src)
+}
+func (m *CaptureRequest) XXX_Size() int {
+	return xxx_messageInfo_CaptureRequest.Size(m)
+}
+func (m *CaptureRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CaptureRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CaptureRequest proto.InternalMessageInfo
+
+func (m *CaptureRequest) GetK8SPodName() string {
+	if m != nil {
+		return m.K8SPodName
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/rpc.TerwayBackend/AllocIP",
+	return ""
+}
+
+func (m *CaptureRequest) GetK8SPodNamespace() string {
+	if m != nil {
+		return m.K8SPodNamespace
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(TerwayBackendServer).AllocIP(ctx, req.(*AllocIPRequest))
+	return ""
+}
+
+func (m *CaptureRequest) GetFilter() string {
+	if m != nil {
+		return m.Filter
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-func _TerwayBackend_ReleaseIP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ReleaseIPRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *CaptureRequest) GetDurationSeconds() int32 {
+	if m != nil {
+		return m.DurationSeconds
 	}
-	if interceptor == nil {
-		return srv.(TerwayBackendServer).ReleaseIP(ctx, in)
+	return 0
+}
+
+func (m *CaptureRequest) GetMaxPackets() int32 {
+	if m != nil {
+		return m.MaxPackets
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
+	return 0
+}
+
+func (m *CaptureRequest) GetSnapLen() int32 {
+	if m != nil {
+		return m.SnapLen
+	}
+	return 0
+}
+
+type CaptureReply struct {
+	Data                 []byte   `protobuf:"bytes,1,opt,name=Data,proto3" json:"Data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CaptureReply) Reset()         { *m = CaptureReply{} }
+func (m *CaptureReply) String() string { return proto.CompactTextString(m) }
+func (*CaptureReply) ProtoMessage()    {}
+func (*CaptureReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{17}
+}
+
+func (m *CaptureReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CaptureReply.Unmarshal(m, b)
+}
+func (m *CaptureReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CaptureReply.Marshal(b, m, deterministic)
+}
+func (m *CaptureReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CaptureReply.Merge(m, src)
+}
+func (m *CaptureReply) XXX_Size() int {
+	return xxx_messageInfo_CaptureReply.Size(m)
+}
+func (m *CaptureReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_CaptureReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CaptureReply proto.InternalMessageInfo
+
+func (m *CaptureReply) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type ReportSetupRequest struct {
+	K8SPodName             string   `protobuf:"bytes,1,opt,name=K8sPodName,proto3" json:"K8sPodName,omitempty"`
+	K8SPodNamespace        string   `protobuf:"bytes,2,opt,name=K8sPodNamespace,proto3" json:"K8sPodNamespace,omitempty"`
+	K8SPodInfraContainerId string   `protobuf:"bytes,3,opt,name=K8sPodInfraContainerId,proto3" json:"K8sPodInfraContainerId,omitempty"`
+	SetupMicroseconds      int64    `protobuf:"varint,4,opt,name=SetupMicroseconds,proto3" json:"SetupMicroseconds,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{} `json:"-"`
+	XXX_unrecognized       []byte   `json:"-"`
+	XXX_sizecache          int32    `json:"-"`
+}
+
+func (m *ReportSetupRequest) Reset()         { *m = ReportSetupRequest{} }
+func (m *ReportSetupRequest) String() string { return proto.CompactTextString(m) }
+func (*ReportSetupRequest) ProtoMessage()    {}
+func (*ReportSetupRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{18}
+}
+
+func (m *ReportSetupRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportSetupRequest.Unmarshal(m, b)
+}
+func (m *ReportSetupRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportSetupRequest.Marshal(b, m, deterministic)
+}
+func (m *ReportSetupRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportSetupRequest.Merge(m, src)
+}
+func (m *ReportSetupRequest) XXX_Size() int {
+	return xxx_messageInfo_ReportSetupRequest.Size(m)
+}
+func (m *ReportSetupRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportSetupRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportSetupRequest proto.InternalMessageInfo
+
+func (m *ReportSetupRequest) GetK8SPodName() string {
+	if m != nil {
+		return m.K8SPodName
+	}
+	return ""
+}
+
+func (m *ReportSetupRequest) GetK8SPodNamespace() string {
+	if m != nil {
+		return m.K8SPodNamespace
+	}
+	return ""
+}
+
+func (m *ReportSetupRequest) GetK8SPodInfraContainerId() string {
+	if m != nil {
+		return m.K8SPodInfraContainerId
+	}
+	return ""
+}
+
+func (m *ReportSetupRequest) GetSetupMicroseconds() int64 {
+	if m != nil {
+		return m.SetupMicroseconds
+	}
+	return 0
+}
+
+type ReportSetupReply struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReportSetupReply) Reset()         { *m = ReportSetupReply{} }
+func (m *ReportSetupReply) String() string { return proto.CompactTextString(m) }
+func (*ReportSetupReply) ProtoMessage()    {}
+func (*ReportSetupReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{19}
+}
+
+func (m *ReportSetupReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReportSetupReply.Unmarshal(m, b)
+}
+func (m *ReportSetupReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReportSetupReply.Marshal(b, m, deterministic)
+}
+func (m *ReportSetupReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReportSetupReply.Merge(m, src)
+}
+func (m *ReportSetupReply) XXX_Size() int {
+	return xxx_messageInfo_ReportSetupReply.Size(m)
+}
+func (m *ReportSetupReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReportSetupReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReportSetupReply proto.InternalMessageInfo
+
+type UpdateIPDenyListRequest struct {
+	Deny                 []string `protobuf:"bytes,1,rep,name=Deny,proto3" json:"Deny,omitempty"`
+	Allow                []string `protobuf:"bytes,2,rep,name=Allow,proto3" json:"Allow,omitempty"`
+	Reason               string   `protobuf:"bytes,3,opt,name=Reason,proto3" json:"Reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateIPDenyListRequest) Reset()         { *m = UpdateIPDenyListRequest{} }
+func (m *UpdateIPDenyListRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateIPDenyListRequest) ProtoMessage()    {}
+func (*UpdateIPDenyListRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{20}
+}
+
+func (m *UpdateIPDenyListRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateIPDenyListRequest.Unmarshal(m, b)
+}
+func (m *UpdateIPDenyListRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateIPDenyListRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateIPDenyListRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateIPDenyListRequest.Merge(m, src)
+}
+func (m *UpdateIPDenyListRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateIPDenyListRequest.Size(m)
+}
+func (m *UpdateIPDenyListRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateIPDenyListRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateIPDenyListRequest proto.InternalMessageInfo
+
+func (m *UpdateIPDenyListRequest) GetDeny() []string {
+	if m != nil {
+		return m.Deny
+	}
+	return nil
+}
+
+func (m *UpdateIPDenyListRequest) GetAllow() []string {
+	if m != nil {
+		return m.Allow
+	}
+	return nil
+}
+
+func (m *UpdateIPDenyListRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type GetIPDenyListRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetIPDenyListRequest) Reset()         { *m = GetIPDenyListRequest{} }
+func (m *GetIPDenyListRequest) String() string { return proto.CompactTextString(m) }
+func (*GetIPDenyListRequest) ProtoMessage()    {}
+func (*GetIPDenyListRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{21}
+}
+
+func (m *GetIPDenyListRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetIPDenyListRequest.Unmarshal(m, b)
+}
+func (m *GetIPDenyListRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetIPDenyListRequest.Marshal(b, m, deterministic)
+}
+func (m *GetIPDenyListRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetIPDenyListRequest.Merge(m, src)
+}
+func (m *GetIPDenyListRequest) XXX_Size() int {
+	return xxx_messageInfo_GetIPDenyListRequest.Size(m)
+}
+func (m *GetIPDenyListRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetIPDenyListRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetIPDenyListRequest proto.InternalMessageInfo
+
+type DeniedIP struct {
+	IP                   string   `protobuf:"bytes,1,opt,name=IP,proto3" json:"IP,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=Reason,proto3" json:"Reason,omitempty"`
+	DeniedAt             int64    `protobuf:"varint,3,opt,name=DeniedAt,proto3" json:"DeniedAt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeniedIP) Reset()         { *m = DeniedIP{} }
+func (m *DeniedIP) String() string { return proto.CompactTextString(m) }
+func (*DeniedIP) ProtoMessage()    {}
+func (*DeniedIP) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{22}
+}
+
+func (m *DeniedIP) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeniedIP.Unmarshal(m, b)
+}
+func (m *DeniedIP) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeniedIP.Marshal(b, m, deterministic)
+}
+func (m *DeniedIP) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeniedIP.Merge(m, src)
+}
+func (m *DeniedIP) XXX_Size() int {
+	return xxx_messageInfo_DeniedIP.Size(m)
+}
+func (m *DeniedIP) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeniedIP.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeniedIP proto.InternalMessageInfo
+
+func (m *DeniedIP) GetIP() string {
+	if m != nil {
+		return m.IP
+	}
+	return ""
+}
+
+func (m *DeniedIP) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *DeniedIP) GetDeniedAt() int64 {
+	if m != nil {
+		return m.DeniedAt
+	}
+	return 0
+}
+
+type IPDenyListReply struct {
+	IPs                  []*DeniedIP `protobuf:"bytes,1,rep,name=IPs,proto3" json:"IPs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *IPDenyListReply) Reset()         { *m = IPDenyListReply{} }
+func (m *IPDenyListReply) String() string { return proto.CompactTextString(m) }
+func (*IPDenyListReply) ProtoMessage()    {}
+func (*IPDenyListReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{23}
+}
+
+func (m *IPDenyListReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IPDenyListReply.Unmarshal(m, b)
+}
+func (m *IPDenyListReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IPDenyListReply.Marshal(b, m, deterministic)
+}
+func (m *IPDenyListReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IPDenyListReply.Merge(m, src)
+}
+func (m *IPDenyListReply) XXX_Size() int {
+	return xxx_messageInfo_IPDenyListReply.Size(m)
+}
+func (m *IPDenyListReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_IPDenyListReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IPDenyListReply proto.InternalMessageInfo
+
+func (m *IPDenyListReply) GetIPs() []*DeniedIP {
+	if m != nil {
+		return m.IPs
+	}
+	return nil
+}
+
+type GetResourceInventoryRequest struct {
+	// Filter substring of resource id, ip or namespace/name of owner pod, all resources if empty
+	Filter               string   `protobuf:"bytes,1,opt,name=Filter,proto3" json:"Filter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetResourceInventoryRequest) Reset()         { *m = GetResourceInventoryRequest{} }
+func (m *GetResourceInventoryRequest) String() string { return proto.CompactTextString(m) }
+func (*GetResourceInventoryRequest) ProtoMessage()    {}
+func (*GetResourceInventoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{24}
+}
+
+func (m *GetResourceInventoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetResourceInventoryRequest.Unmarshal(m, b)
+}
+func (m *GetResourceInventoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetResourceInventoryRequest.Marshal(b, m, deterministic)
+}
+func (m *GetResourceInventoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetResourceInventoryRequest.Merge(m, src)
+}
+func (m *GetResourceInventoryRequest) XXX_Size() int {
+	return xxx_messageInfo_GetResourceInventoryRequest.Size(m)
+}
+func (m *GetResourceInventoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetResourceInventoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetResourceInventoryRequest proto.InternalMessageInfo
+
+func (m *GetResourceInventoryRequest) GetFilter() string {
+	if m != nil {
+		return m.Filter
+	}
+	return ""
+}
+
+type PooledResource struct {
+	ID                   string   `protobuf:"bytes,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Type                 string   `protobuf:"bytes,2,opt,name=Type,proto3" json:"Type,omitempty"`
+	IP                   string   `protobuf:"bytes,3,opt,name=IP,proto3" json:"IP,omitempty"`
+	State                string   `protobuf:"bytes,4,opt,name=State,proto3" json:"State,omitempty"`
+	PodNamespace         string   `protobuf:"bytes,5,opt,name=PodNamespace,proto3" json:"PodNamespace,omitempty"`
+	PodName              string   `protobuf:"bytes,6,opt,name=PodName,proto3" json:"PodName,omitempty"`
+	Created              int64    `protobuf:"varint,7,opt,name=Created,proto3" json:"Created,omitempty"`
+	LastUsed             int64    `protobuf:"varint,8,opt,name=LastUsed,proto3" json:"LastUsed,omitempty"`
+	Pinned               bool     `protobuf:"varint,9,opt,name=Pinned,proto3" json:"Pinned,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PooledResource) Reset()         { *m = PooledResource{} }
+func (m *PooledResource) String() string { return proto.CompactTextString(m) }
+func (*PooledResource) ProtoMessage()    {}
+func (*PooledResource) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{25}
+}
+
+func (m *PooledResource) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PooledResource.Unmarshal(m, b)
+}
+func (m *PooledResource) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PooledResource.Marshal(b, m, deterministic)
+}
+func (m *PooledResource) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PooledResource.Merge(m, src)
+}
+func (m *PooledResource) XXX_Size() int {
+	return xxx_messageInfo_PooledResource.Size(m)
+}
+func (m *PooledResource) XXX_DiscardUnknown() {
+	xxx_messageInfo_PooledResource.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PooledResource proto.InternalMessageInfo
+
+func (m *PooledResource) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *PooledResource) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *PooledResource) GetIP() string {
+	if m != nil {
+		return m.IP
+	}
+	return ""
+}
+
+func (m *PooledResource) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *PooledResource) GetPodNamespace() string {
+	if m != nil {
+		return m.PodNamespace
+	}
+	return ""
+}
+
+func (m *PooledResource) GetPodName() string {
+	if m != nil {
+		return m.PodName
+	}
+	return ""
+}
+
+func (m *PooledResource) GetCreated() int64 {
+	if m != nil {
+		return m.Created
+	}
+	return 0
+}
+
+func (m *PooledResource) GetLastUsed() int64 {
+	if m != nil {
+		return m.LastUsed
+	}
+	return 0
+}
+
+func (m *PooledResource) GetPinned() bool {
+	if m != nil {
+		return m.Pinned
+	}
+	return false
+}
+
+type ResourceInventoryReply struct {
+	Resources            []*PooledResource `protobuf:"bytes,1,rep,name=Resources,proto3" json:"Resources,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ResourceInventoryReply) Reset()         { *m = ResourceInventoryReply{} }
+func (m *ResourceInventoryReply) String() string { return proto.CompactTextString(m) }
+func (*ResourceInventoryReply) ProtoMessage()    {}
+func (*ResourceInventoryReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{26}
+}
+
+func (m *ResourceInventoryReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResourceInventoryReply.Unmarshal(m, b)
+}
+func (m *ResourceInventoryReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResourceInventoryReply.Marshal(b, m, deterministic)
+}
+func (m *ResourceInventoryReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResourceInventoryReply.Merge(m, src)
+}
+func (m *ResourceInventoryReply) XXX_Size() int {
+	return xxx_messageInfo_ResourceInventoryReply.Size(m)
+}
+func (m *ResourceInventoryReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResourceInventoryReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResourceInventoryReply proto.InternalMessageInfo
+
+func (m *ResourceInventoryReply) GetResources() []*PooledResource {
+	if m != nil {
+		return m.Resources
+	}
+	return nil
+}
+
+type ErrorDetail struct {
+	Code                 string   `protobuf:"bytes,1,opt,name=Code,proto3" json:"Code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ErrorDetail) Reset()         { *m = ErrorDetail{} }
+func (m *ErrorDetail) String() string { return proto.CompactTextString(m) }
+func (*ErrorDetail) ProtoMessage()    {}
+func (*ErrorDetail) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{27}
+}
+
+func (m *ErrorDetail) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ErrorDetail.Unmarshal(m, b)
+}
+func (m *ErrorDetail) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ErrorDetail.Marshal(b, m, deterministic)
+}
+func (m *ErrorDetail) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ErrorDetail.Merge(m, src)
+}
+func (m *ErrorDetail) XXX_Size() int {
+	return xxx_messageInfo_ErrorDetail.Size(m)
+}
+func (m *ErrorDetail) XXX_DiscardUnknown() {
+	xxx_messageInfo_ErrorDetail.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ErrorDetail proto.InternalMessageInfo
+
+func (m *ErrorDetail) GetCode() string {
+	if m != nil {
+		return m.Code
+	}
+	return ""
+}
+
+type WatchEventsRequest struct {
+	// Types of events to watch, "Allocate", "Release" or "GC", all types if empty
+	Types []string `protobuf:"bytes,1,rep,name=Types,proto3" json:"Types,omitempty"`
+	// Filter substring of resource id or namespace/name of pod, all events if empty
+	Filter               string   `protobuf:"bytes,2,opt,name=Filter,proto3" json:"Filter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchEventsRequest) Reset()         { *m = WatchEventsRequest{} }
+func (m *WatchEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchEventsRequest) ProtoMessage()    {}
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{28}
+}
+
+func (m *WatchEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchEventsRequest.Unmarshal(m, b)
+}
+func (m *WatchEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchEventsRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchEventsRequest.Merge(m, src)
+}
+func (m *WatchEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchEventsRequest.Size(m)
+}
+func (m *WatchEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchEventsRequest proto.InternalMessageInfo
+
+func (m *WatchEventsRequest) GetTypes() []string {
+	if m != nil {
+		return m.Types
+	}
+	return nil
+}
+
+func (m *WatchEventsRequest) GetFilter() string {
+	if m != nil {
+		return m.Filter
+	}
+	return ""
+}
+
+type ResourceEvent struct {
+	Type                 string   `protobuf:"bytes,1,opt,name=Type,proto3" json:"Type,omitempty"`
+	Timestamp            int64    `protobuf:"varint,2,opt,name=Timestamp,proto3" json:"Timestamp,omitempty"`
+	PodNamespace         string   `protobuf:"bytes,3,opt,name=PodNamespace,proto3" json:"PodNamespace,omitempty"`
+	PodName              string   `protobuf:"bytes,4,opt,name=PodName,proto3" json:"PodName,omitempty"`
+	SandboxID            string   `protobuf:"bytes,5,opt,name=SandboxID,proto3" json:"SandboxID,omitempty"`
+	ResourceType         string   `protobuf:"bytes,6,opt,name=ResourceType,proto3" json:"ResourceType,omitempty"`
+	ResourceID           string   `protobuf:"bytes,7,opt,name=ResourceID,proto3" json:"ResourceID,omitempty"`
+	Error                string   `protobuf:"bytes,8,opt,name=Error,proto3" json:"Error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ResourceEvent) Reset()         { *m = ResourceEvent{} }
+func (m *ResourceEvent) String() string { return proto.CompactTextString(m) }
+func (*ResourceEvent) ProtoMessage()    {}
+func (*ResourceEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{29}
+}
+
+func (m *ResourceEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResourceEvent.Unmarshal(m, b)
+}
+func (m *ResourceEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResourceEvent.Marshal(b, m, deterministic)
+}
+func (m *ResourceEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResourceEvent.Merge(m, src)
+}
+func (m *ResourceEvent) XXX_Size() int {
+	return xxx_messageInfo_ResourceEvent.Size(m)
+}
+func (m *ResourceEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResourceEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResourceEvent proto.InternalMessageInfo
+
+func (m *ResourceEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *ResourceEvent) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *ResourceEvent) GetPodNamespace() string {
+	if m != nil {
+		return m.PodNamespace
+	}
+	return ""
+}
+
+func (m *ResourceEvent) GetPodName() string {
+	if m != nil {
+		return m.PodName
+	}
+	return ""
+}
+
+func (m *ResourceEvent) GetSandboxID() string {
+	if m != nil {
+		return m.SandboxID
+	}
+	return ""
+}
+
+func (m *ResourceEvent) GetResourceType() string {
+	if m != nil {
+		return m.ResourceType
+	}
+	return ""
+}
+
+func (m *ResourceEvent) GetResourceID() string {
+	if m != nil {
+		return m.ResourceID
+	}
+	return ""
+}
+
+func (m *ResourceEvent) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type DrainRequest struct {
+	// Force drain node not cordoned
+	Force                bool     `protobuf:"varint,1,opt,name=Force,proto3" json:"Force,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DrainRequest) Reset()         { *m = DrainRequest{} }
+func (m *DrainRequest) String() string { return proto.CompactTextString(m) }
+func (*DrainRequest) ProtoMessage()    {}
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{30}
+}
+
+func (m *DrainRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DrainRequest.Unmarshal(m, b)
+}
+func (m *DrainRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DrainRequest.Marshal(b, m, deterministic)
+}
+func (m *DrainRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DrainRequest.Merge(m, src)
+}
+func (m *DrainRequest) XXX_Size() int {
+	return xxx_messageInfo_DrainRequest.Size(m)
+}
+func (m *DrainRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DrainRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DrainRequest proto.InternalMessageInfo
+
+func (m *DrainRequest) GetForce() bool {
+	if m != nil {
+		return m.Force
+	}
+	return false
+}
+
+type SimulateAllocationRequest struct {
+	// K8sPodName pod the network requirements taken from, eg: pending pod, by the fields below if empty
+	K8SPodName      string `protobuf:"bytes,1,opt,name=K8sPodName,proto3" json:"K8sPodName,omitempty"`
+	K8SPodNamespace string `protobuf:"bytes,2,opt,name=K8sPodNamespace,proto3" json:"K8sPodNamespace,omitempty"`
+	// PodNetworkType "VPCIP", "VPCENI" or "ENIMultiIP", the default of daemon mode if empty
+	PodNetworkType       string   `protobuf:"bytes,3,opt,name=PodNetworkType,proto3" json:"PodNetworkType,omitempty"`
+	PriorityClassName    string   `protobuf:"bytes,4,opt,name=PriorityClassName,proto3" json:"PriorityClassName,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SimulateAllocationRequest) Reset()         { *m = SimulateAllocationRequest{} }
+func (m *SimulateAllocationRequest) String() string { return proto.CompactTextString(m) }
+func (*SimulateAllocationRequest) ProtoMessage()    {}
+func (*SimulateAllocationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{31}
+}
+
+func (m *SimulateAllocationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SimulateAllocationRequest.Unmarshal(m, b)
+}
+func (m *SimulateAllocationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SimulateAllocationRequest.Marshal(b, m, deterministic)
+}
+func (m *SimulateAllocationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SimulateAllocationRequest.Merge(m, src)
+}
+func (m *SimulateAllocationRequest) XXX_Size() int {
+	return xxx_messageInfo_SimulateAllocationRequest.Size(m)
+}
+func (m *SimulateAllocationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SimulateAllocationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SimulateAllocationRequest proto.InternalMessageInfo
+
+func (m *SimulateAllocationRequest) GetK8SPodName() string {
+	if m != nil {
+		return m.K8SPodName
+	}
+	return ""
+}
+
+func (m *SimulateAllocationRequest) GetK8SPodNamespace() string {
+	if m != nil {
+		return m.K8SPodNamespace
+	}
+	return ""
+}
+
+func (m *SimulateAllocationRequest) GetPodNetworkType() string {
+	if m != nil {
+		return m.PodNetworkType
+	}
+	return ""
+}
+
+func (m *SimulateAllocationRequest) GetPriorityClassName() string {
+	if m != nil {
+		return m.PriorityClassName
+	}
+	return ""
+}
+
+type SimulateAllocationReply struct {
+	Allocatable bool `protobuf:"varint,1,opt,name=Allocatable,proto3" json:"Allocatable,omitempty"`
+	// Reason why pod not allocatable
+	Reason         string `protobuf:"bytes,2,opt,name=Reason,proto3" json:"Reason,omitempty"`
+	PodNetworkType string `protobuf:"bytes,3,opt,name=PodNetworkType,proto3" json:"PodNetworkType,omitempty"`
+	ResourceType   string `protobuf:"bytes,4,opt,name=ResourceType,proto3" json:"ResourceType,omitempty"`
+	// Idle resources in pool
+	Idle int32 `protobuf:"varint,5,opt,name=Idle,proto3" json:"Idle,omitempty"`
+	// Free slots of pool, idle or to create
+	Free int32 `protobuf:"varint,6,opt,name=Free,proto3" json:"Free,omitempty"`
+	// NeedCreate resource created by cloud for the pod, slower and may fail, eg: vswitch out of ips
+	NeedCreate           bool     `protobuf:"varint,7,opt,name=NeedCreate,proto3" json:"NeedCreate,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SimulateAllocationReply) Reset()         { *m = SimulateAllocationReply{} }
+func (m *SimulateAllocationReply) String() string { return proto.CompactTextString(m) }
+func (*SimulateAllocationReply) ProtoMessage()    {}
+func (*SimulateAllocationReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{32}
+}
+
+func (m *SimulateAllocationReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SimulateAllocationReply.Unmarshal(m, b)
+}
+func (m *SimulateAllocationReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SimulateAllocationReply.Marshal(b, m, deterministic)
+}
+func (m *SimulateAllocationReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SimulateAllocationReply.Merge(m, src)
+}
+func (m *SimulateAllocationReply) XXX_Size() int {
+	return xxx_messageInfo_SimulateAllocationReply.Size(m)
+}
+func (m *SimulateAllocationReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SimulateAllocationReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SimulateAllocationReply proto.InternalMessageInfo
+
+func (m *SimulateAllocationReply) GetAllocatable() bool {
+	if m != nil {
+		return m.Allocatable
+	}
+	return false
+}
+
+func (m *SimulateAllocationReply) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *SimulateAllocationReply) GetPodNetworkType() string {
+	if m != nil {
+		return m.PodNetworkType
+	}
+	return ""
+}
+
+func (m *SimulateAllocationReply) GetResourceType() string {
+	if m != nil {
+		return m.ResourceType
+	}
+	return ""
+}
+
+func (m *SimulateAllocationReply) GetIdle() int32 {
+	if m != nil {
+		return m.Idle
+	}
+	return 0
+}
+
+func (m *SimulateAllocationReply) GetFree() int32 {
+	if m != nil {
+		return m.Free
+	}
+	return 0
+}
+
+func (m *SimulateAllocationReply) GetNeedCreate() bool {
+	if m != nil {
+		return m.NeedCreate
+	}
+	return false
+}
+
+type DrainProgress struct {
+	// Phase "release" of pod resources or "dispose" of pooled resources
+	Phase                string   `protobuf:"bytes,1,opt,name=Phase,proto3" json:"Phase,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=Message,proto3" json:"Message,omitempty"`
+	Done                 int32    `protobuf:"varint,3,opt,name=Done,proto3" json:"Done,omitempty"`
+	Total                int32    `protobuf:"varint,4,opt,name=Total,proto3" json:"Total,omitempty"`
+	Error                string   `protobuf:"bytes,5,opt,name=Error,proto3" json:"Error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DrainProgress) Reset()         { *m = DrainProgress{} }
+func (m *DrainProgress) String() string { return proto.CompactTextString(m) }
+func (*DrainProgress) ProtoMessage()    {}
+func (*DrainProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{33}
+}
+
+func (m *DrainProgress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DrainProgress.Unmarshal(m, b)
+}
+func (m *DrainProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DrainProgress.Marshal(b, m, deterministic)
+}
+func (m *DrainProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DrainProgress.Merge(m, src)
+}
+func (m *DrainProgress) XXX_Size() int {
+	return xxx_messageInfo_DrainProgress.Size(m)
+}
+func (m *DrainProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_DrainProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DrainProgress proto.InternalMessageInfo
+
+func (m *DrainProgress) GetPhase() string {
+	if m != nil {
+		return m.Phase
+	}
+	return ""
+}
+
+func (m *DrainProgress) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *DrainProgress) GetDone() int32 {
+	if m != nil {
+		return m.Done
+	}
+	return 0
+}
+
+func (m *DrainProgress) GetTotal() int32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *DrainProgress) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterEnum("rpc.IPType", IPType_name, IPType_value)
+	proto.RegisterType((*AllocIPRequest)(nil), "rpc.AllocIPRequest")
+	proto.RegisterType((*Pod)(nil), "rpc.Pod")
+	proto.RegisterMapType((map[string]string)(nil), "rpc.Pod.SysctlsEntry")
+	proto.RegisterType((*InterfaceTuning)(nil), "rpc.InterfaceTuning")
+	proto.RegisterMapType((map[string]bool)(nil), "rpc.InterfaceTuning.OffloadsEntry")
+	proto.RegisterType((*Route)(nil), "rpc.Route")
+	proto.RegisterType((*VPCIP)(nil), "rpc.VPCIP")
+	proto.RegisterType((*ENI)(nil), "rpc.ENI")
+	proto.RegisterType((*VPCENI)(nil), "rpc.VPCENI")
+	proto.RegisterType((*ManagedK8SENI)(nil), "rpc.ManagedK8SENI")
+	proto.RegisterType((*ENIMultiIP)(nil), "rpc.ENIMultiIP")
+	proto.RegisterType((*AllocIPReply)(nil), "rpc.AllocIPReply")
+	proto.RegisterType((*ReleaseIPRequest)(nil), "rpc.ReleaseIPRequest")
+	proto.RegisterType((*ReleaseIPReply)(nil), "rpc.ReleaseIPReply")
+	proto.RegisterType((*GetInfoRequest)(nil), "rpc.GetInfoRequest")
+	proto.RegisterType((*GetInfoReply)(nil), "rpc.GetInfoReply")
+	proto.RegisterType((*HandshakeRequest)(nil), "rpc.HandshakeRequest")
+	proto.RegisterType((*HandshakeReply)(nil), "rpc.HandshakeReply")
+	proto.RegisterType((*CaptureRequest)(nil), "rpc.CaptureRequest")
+	proto.RegisterType((*CaptureReply)(nil), "rpc.CaptureReply")
+	proto.RegisterType((*ReportSetupRequest)(nil), "rpc.ReportSetupRequest")
+	proto.RegisterType((*ReportSetupReply)(nil), "rpc.ReportSetupReply")
+	proto.RegisterType((*UpdateIPDenyListRequest)(nil), "rpc.UpdateIPDenyListRequest")
+	proto.RegisterType((*GetIPDenyListRequest)(nil), "rpc.GetIPDenyListRequest")
+	proto.RegisterType((*DeniedIP)(nil), "rpc.DeniedIP")
+	proto.RegisterType((*IPDenyListReply)(nil), "rpc.IPDenyListReply")
+	proto.RegisterType((*GetResourceInventoryRequest)(nil), "rpc.GetResourceInventoryRequest")
+	proto.RegisterType((*PooledResource)(nil), "rpc.PooledResource")
+	proto.RegisterType((*ResourceInventoryReply)(nil), "rpc.ResourceInventoryReply")
+	proto.RegisterType((*ErrorDetail)(nil), "rpc.ErrorDetail")
+	proto.RegisterType((*WatchEventsRequest)(nil), "rpc.WatchEventsRequest")
+	proto.RegisterType((*ResourceEvent)(nil), "rpc.ResourceEvent")
+	proto.RegisterType((*DrainRequest)(nil), "rpc.DrainRequest")
+	proto.RegisterType((*SimulateAllocationRequest)(nil), "rpc.SimulateAllocationRequest")
+	proto.RegisterType((*SimulateAllocationReply)(nil), "rpc.SimulateAllocationReply")
+	proto.RegisterType((*DrainProgress)(nil), "rpc.DrainProgress")
+}
+
+func init() { proto.RegisterFile("rpc.proto", fileDescriptor_77a6da22d6a3feb1) }
+
+var fileDescriptor_77a6da22d6a3feb1 = []byte{
+	// 1891 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x58, 0xcf, 0x73, 0x1b, 0x49,
+	0xf5, 0xf7, 0x68, 0x2c, 0xd9, 0x7a, 0xb2, 0x15, 0xa7, 0xe3, 0x75, 0xb4, 0xda, 0x54, 0xbe, 0xfe,
+	0x0e, 0x90, 0x4a, 0x51, 0x54, 0x60, 0x9d, 0x5d, 0x2a, 0x2c, 0x55, 0xa1, 0x1c, 0x49, 0x89, 0xa7,
+	0x62, 0x6b, 0x87, 0x91, 0xd7, 0x7b, 0x80, 0x4b, 0x7b, 0xa6, 0xed, 0x0c, 0x1e, 0xf7, 0x88, 0x99,
+	0x56, 0x12, 0xc1, 0x81, 0x23, 0x57, 0x8a, 0x0b, 0x27, 0xfe, 0x09, 0xae, 0x5c, 0x38, 0xf0, 0x0f,
+	0x70, 0xe1, 0x4f, 0x80, 0x3f, 0x81, 0x2b, 0xf5, 0xfa, 0xc7, 0xa8, 0x67, 0x64, 0x2d, 0xa9, 0x22,
+	0x54, 0xe5, 0xa4, 0x7e, 0x9f, 0xd7, 0xdd, 0xaf, 0xdf, 0xe7, 0x75, 0xbf, 0xf7, 0x34, 0xd0, 0xce,
+	0xa7, 0xd1, 0xa3, 0x69, 0x9e, 0x89, 0x8c, 0xb8, 0xf9, 0x34, 0xf2, 0xfe, 0xe2, 0x40, 0xf7, 0x30,
+	0x4d, 0xb3, 0xc8, 0x0f, 0x42, 0xf6, 0xcb, 0x19, 0x2b, 0x04, 0xb9, 0x0f, 0xf0, 0xf2, 0x49, 0x11,
+	0x64, 0xf1, 0x98, 0x5e, 0xb3, 0x9e, 0xb3, 0xef, 0x3c, 0x6c, 0x87, 0x16, 0x42, 0x1e, 0xc2, 0xad,
+	0x85, 0x54, 0x4c, 0x69, 0xc4, 0x7a, 0x0d, 0x39, 0xa9, 0x0e, 0x93, 0x1f, 0xc2, 0x9e, 0x82, 0x7c,
+	0x7e, 0x91, 0xd3, 0x41, 0xc6, 0x05, 0x4d, 0x38, 0xcb, 0xfd, 0xb8, 0xe7, 0xca, 0x05, 0x2b, 0xb4,
+	0x64, 0x17, 0x9a, 0x63, 0x26, 0x78, 0xd1, 0x5b, 0x97, 0xd3, 0x94, 0x40, 0xf6, 0xa0, 0xe5, 0x5f,
+	0xc8, 0x33, 0x35, 0x25, 0xac, 0x25, 0xef, 0x4f, 0x0d, 0x70, 0x83, 0x2c, 0x26, 0x3d, 0xd8, 0xf0,
+	0xf9, 0x65, 0xce, 0x8a, 0x42, 0x1e, 0x7a, 0x3d, 0x34, 0x22, 0xae, 0x1c, 0x29, 0x45, 0x43, 0x2a,
+	0xb4, 0x84, 0x9e, 0x1c, 0x27, 0xfc, 0xea, 0x38, 0x8b, 0x68, 0x7a, 0x18, 0x45, 0x38, 0x41, 0x1d,
+	0xac, 0x0e, 0x13, 0x0f, 0x5a, 0x61, 0x36, 0x13, 0x0c, 0x8f, 0xe4, 0x3e, 0xec, 0x1c, 0xc0, 0x23,
+	0xe4, 0x51, 0x42, 0xa1, 0xd6, 0x90, 0xef, 0xc3, 0xc6, 0x64, 0x5e, 0x44, 0x22, 0x2d, 0x7a, 0x4d,
+	0x39, 0xe9, 0x23, 0x39, 0x29, 0xc8, 0xe2, 0x47, 0x1a, 0x1f, 0x71, 0x91, 0xcf, 0x43, 0x33, 0x8b,
+	0x3c, 0x85, 0x5b, 0x3e, 0x17, 0x2c, 0xbf, 0xa0, 0x11, 0x3b, 0x9d, 0xf1, 0x84, 0x5f, 0xf6, 0x5a,
+	0xfb, 0xce, 0xc3, 0xce, 0xc1, 0xae, 0x5c, 0x58, 0xd3, 0x85, 0xf5, 0xc9, 0xfd, 0x2f, 0x60, 0xcb,
+	0xde, 0x98, 0xec, 0x80, 0x7b, 0xc5, 0xe6, 0x3a, 0x62, 0x38, 0x44, 0x22, 0x5f, 0xd3, 0x74, 0x66,
+	0x02, 0xa4, 0x84, 0x2f, 0x1a, 0x4f, 0x1c, 0xef, 0x1f, 0xce, 0x92, 0x71, 0xf2, 0x00, 0xba, 0x83,
+	0xec, 0xfa, 0x3c, 0xe1, 0x2c, 0xfe, 0xe9, 0x8c, 0xcd, 0x98, 0xe2, 0xb1, 0x19, 0xd6, 0x50, 0xa4,
+	0x33, 0x7c, 0x1b, 0xe2, 0x71, 0x1b, 0x52, 0xaf, 0x25, 0xc4, 0x4f, 0x15, 0xee, 0x2a, 0x5c, 0x49,
+	0xe4, 0x29, 0x6c, 0x7e, 0x79, 0x71, 0x91, 0x66, 0x34, 0x36, 0xf4, 0x79, 0x37, 0x39, 0xf8, 0xc8,
+	0x4c, 0x52, 0x34, 0x95, 0x6b, 0xfa, 0x3f, 0x86, 0xed, 0x8a, 0xea, 0x3f, 0x39, 0xba, 0x69, 0x3b,
+	0xfa, 0x18, 0x9a, 0x32, 0x3e, 0xb8, 0x68, 0x58, 0x08, 0xb3, 0x68, 0x58, 0x08, 0xbc, 0x30, 0x2f,
+	0xa8, 0x60, 0x6f, 0xe8, 0x5c, 0xf3, 0x63, 0x44, 0xef, 0xd7, 0xd0, 0x3c, 0x0b, 0x06, 0x7e, 0x40,
+	0x1e, 0x40, 0x3b, 0xc8, 0xe2, 0x41, 0xc6, 0x2f, 0x92, 0x4b, 0xb9, 0xb4, 0x73, 0xb0, 0x69, 0xa2,
+	0x1a, 0x2e, 0x54, 0xa4, 0x0f, 0x9b, 0xe3, 0x2c, 0x66, 0x83, 0x24, 0xce, 0xf5, 0x5e, 0xa5, 0x8c,
+	0x67, 0xc3, 0x4b, 0x1e, 0xe8, 0xbb, 0xa5, 0x04, 0xdb, 0xf8, 0x7a, 0xd5, 0xf8, 0x3f, 0x1d, 0x70,
+	0x47, 0x63, 0x1f, 0xf7, 0xf4, 0x83, 0xd7, 0x9f, 0x1d, 0xc6, 0x71, 0xae, 0x4f, 0x5d, 0xca, 0xf8,
+	0x46, 0x71, 0x3c, 0x99, 0x9d, 0x73, 0x26, 0xb4, 0x45, 0x0b, 0xc1, 0xdd, 0x4f, 0x68, 0x24, 0x97,
+	0x2a, 0xab, 0x46, 0x5c, 0x6d, 0x97, 0x78, 0xb0, 0x35, 0x64, 0xaf, 0x93, 0x88, 0x8d, 0x67, 0xd7,
+	0xe7, 0x2c, 0x97, 0xaf, 0xac, 0x19, 0x56, 0x30, 0x7c, 0x31, 0x41, 0x9e, 0x5c, 0xd3, 0x7c, 0x5e,
+	0x1e, 0xad, 0xa5, 0x5e, 0x4c, 0x0d, 0xc6, 0xdd, 0x24, 0xef, 0xa7, 0xf4, 0x3c, 0x65, 0xfe, 0xb0,
+	0xb7, 0xa1, 0x76, 0xb3, 0x31, 0xef, 0x57, 0xd0, 0x3a, 0x0b, 0x06, 0xe8, 0xeb, 0x03, 0x68, 0x8f,
+	0x78, 0x72, 0x03, 0xcf, 0xa3, 0xb1, 0x1f, 0x2e, 0x54, 0xd5, 0x78, 0x34, 0x56, 0xc7, 0x63, 0x1f,
+	0x3a, 0x13, 0x96, 0xe3, 0xc1, 0x07, 0x49, 0xc9, 0x81, 0x0d, 0x79, 0x7f, 0x73, 0x60, 0xfb, 0x84,
+	0x72, 0x7a, 0xc9, 0xe2, 0x97, 0x4f, 0x26, 0xff, 0x8b, 0x33, 0xf4, 0x60, 0x03, 0x85, 0x85, 0x7d,
+	0x23, 0xa2, 0xe6, 0x6c, 0x1a, 0x49, 0x8d, 0x8e, 0x81, 0x16, 0x2b, 0xf7, 0xa8, 0x59, 0xbb, 0x47,
+	0x35, 0x9f, 0x5a, 0xcb, 0x3e, 0xfd, 0x1c, 0x60, 0x34, 0xf6, 0x4f, 0x66, 0xa9, 0x48, 0xd4, 0xdd,
+	0x7d, 0x9f, 0xfe, 0x78, 0xbf, 0x6b, 0xc0, 0x56, 0x59, 0x2a, 0xa6, 0xe9, 0x1c, 0xdd, 0x98, 0xcc,
+	0x54, 0xda, 0x74, 0xe4, 0xb3, 0x33, 0x22, 0xf9, 0x16, 0xb4, 0xfc, 0xe0, 0x74, 0x3e, 0x55, 0xef,
+	0xb1, 0x7b, 0xd0, 0x51, 0xef, 0x5d, 0x42, 0xa1, 0x56, 0x11, 0x0f, 0x9a, 0x67, 0xd3, 0xc8, 0x9f,
+	0x4a, 0x76, 0x4c, 0x4a, 0x95, 0xcf, 0xee, 0x68, 0x2d, 0x54, 0x2a, 0xf2, 0x1d, 0x68, 0x9d, 0x4d,
+	0xa3, 0x11, 0x4f, 0x24, 0x51, 0x1d, 0xbd, 0x91, 0xba, 0x34, 0x47, 0x6b, 0xa1, 0x56, 0x92, 0xcf,
+	0x00, 0x16, 0xb1, 0x94, 0xc4, 0x75, 0x0e, 0x88, 0x9c, 0x5a, 0x09, 0xf1, 0xd1, 0x5a, 0x68, 0xcd,
+	0x23, 0x9f, 0xda, 0x74, 0xe9, 0xd4, 0x7b, 0xcb, 0x30, 0xa4, 0x61, 0x5c, 0xb2, 0x90, 0x9e, 0x6d,
+	0x43, 0x67, 0xcc, 0xc4, 0x9b, 0x2c, 0xbf, 0xf2, 0xf9, 0x45, 0xe6, 0xfd, 0xb6, 0x01, 0x3b, 0x21,
+	0x4b, 0x19, 0x2d, 0xd8, 0x87, 0x54, 0x3f, 0x17, 0xf4, 0xaf, 0xaf, 0xa6, 0xdf, 0x4e, 0x2f, 0xcd,
+	0x5a, 0x7a, 0xb1, 0xd2, 0x47, 0xab, 0x9a, 0x3e, 0x30, 0xf7, 0x33, 0x5a, 0x64, 0x5c, 0x3e, 0xe8,
+	0x76, 0xa8, 0x25, 0xef, 0x17, 0xd0, 0xb5, 0x88, 0xf8, 0xe6, 0xdb, 0x61, 0x5b, 0x6e, 0xd4, 0x2c,
+	0xd7, 0x93, 0x90, 0xbb, 0x9c, 0x84, 0xbc, 0xdf, 0x3b, 0xd0, 0x7d, 0xc1, 0x04, 0x46, 0xe0, 0x83,
+	0xe1, 0xdc, 0xfb, 0x83, 0x03, 0x5b, 0xe5, 0xa1, 0xd0, 0xff, 0x45, 0x10, 0x9c, 0xd5, 0x41, 0x78,
+	0xd7, 0x5c, 0x62, 0xe7, 0x05, 0xb7, 0x96, 0x17, 0xee, 0x03, 0x0c, 0x29, 0xbb, 0xce, 0xb8, 0x1f,
+	0x1c, 0x9e, 0xc8, 0x88, 0x6f, 0x86, 0x16, 0xe2, 0x8d, 0x61, 0xe7, 0x88, 0xf2, 0xb8, 0x78, 0x45,
+	0xaf, 0x98, 0xc5, 0xd7, 0x61, 0xe0, 0x9f, 0xb1, 0xbc, 0x48, 0x32, 0xae, 0xcb, 0xbc, 0x85, 0xa0,
+	0xbd, 0xe7, 0x8c, 0x8a, 0x59, 0xce, 0xb0, 0x67, 0x72, 0xd1, 0x9e, 0x91, 0xbd, 0x63, 0xe8, 0x5a,
+	0xfb, 0xa1, 0xab, 0xff, 0xcd, 0x6e, 0x7f, 0x77, 0xa0, 0x3b, 0xa0, 0x53, 0x14, 0xde, 0x7f, 0x30,
+	0xf7, 0xa0, 0xf5, 0x3c, 0x49, 0x05, 0x33, 0xa4, 0x69, 0x09, 0x77, 0x18, 0xce, 0x72, 0x2a, 0x92,
+	0x8c, 0x4f, 0x58, 0x94, 0xf1, 0x58, 0xb5, 0x9a, 0xcd, 0xb0, 0x0e, 0xe3, 0x59, 0x4e, 0xe8, 0xdb,
+	0x80, 0x46, 0x57, 0x4c, 0x14, 0xba, 0x24, 0x5a, 0x88, 0xbc, 0xe5, 0x9c, 0x4e, 0x8f, 0x19, 0x97,
+	0x2f, 0xa5, 0x19, 0x1a, 0xd1, 0xf3, 0x60, 0xab, 0xf4, 0x0b, 0x49, 0x22, 0xb0, 0x3e, 0xa4, 0x82,
+	0x4a, 0x7f, 0xb6, 0x42, 0x39, 0xf6, 0xfe, 0xea, 0x00, 0x09, 0xd9, 0x34, 0xcb, 0xc5, 0x84, 0x89,
+	0xd9, 0xf4, 0xc3, 0xc9, 0x20, 0xdf, 0x83, 0xdb, 0xf2, 0x44, 0x27, 0x49, 0x94, 0x67, 0x85, 0x45,
+	0x91, 0x1b, 0x2e, 0x2b, 0x3c, 0x02, 0x3b, 0x15, 0x2f, 0xa6, 0xe9, 0xdc, 0xfb, 0x19, 0xdc, 0xfd,
+	0x6a, 0x1a, 0x53, 0xc1, 0xfc, 0x60, 0xc8, 0xf8, 0xfc, 0x38, 0x29, 0x84, 0x71, 0x0f, 0x99, 0x60,
+	0x1c, 0xfb, 0x37, 0xbc, 0x0a, 0x72, 0x8c, 0x4d, 0x12, 0xd6, 0x96, 0x37, 0xfa, 0x7e, 0x28, 0xc1,
+	0xca, 0x36, 0x6e, 0x25, 0xdb, 0xec, 0xc1, 0x2e, 0xbe, 0xb5, 0xfa, 0xce, 0xde, 0x18, 0x36, 0x87,
+	0x8c, 0x27, 0x0c, 0x1b, 0xac, 0x2e, 0x34, 0xfc, 0x40, 0x93, 0xd7, 0xf0, 0x03, 0x6b, 0xaf, 0x86,
+	0xbd, 0x17, 0xe9, 0x9b, 0x35, 0x87, 0x42, 0x5a, 0x71, 0xc3, 0x52, 0xf6, 0x0e, 0xe0, 0x96, 0x6d,
+	0x04, 0xc3, 0xf8, 0x7f, 0xe0, 0xfa, 0x41, 0x21, 0xcf, 0xde, 0x39, 0xd8, 0x96, 0x6f, 0xd5, 0x98,
+	0x0c, 0x51, 0xe3, 0x7d, 0x0e, 0x9f, 0xbc, 0x60, 0x22, 0x64, 0x45, 0x36, 0xcb, 0x23, 0xe6, 0xf3,
+	0xd7, 0x8c, 0x8b, 0x2c, 0x9f, 0x1b, 0xe7, 0x17, 0x57, 0xd2, 0xb1, 0xaf, 0x24, 0x36, 0xe4, 0xdd,
+	0x20, 0xcb, 0x52, 0x16, 0x9b, 0xa5, 0xd2, 0x83, 0x61, 0xe9, 0xc1, 0x10, 0x79, 0x2b, 0x6b, 0x6a,
+	0x3b, 0x94, 0x63, 0xed, 0xa5, 0x5b, 0x7a, 0xb9, 0x0b, 0xcd, 0x89, 0xa0, 0x82, 0x99, 0xbf, 0x4e,
+	0x52, 0xc0, 0xac, 0x5a, 0xb9, 0x2d, 0x2a, 0xdf, 0x57, 0x30, 0xdd, 0xae, 0xa0, 0x6c, 0x72, 0xbe,
+	0x16, 0x51, 0x33, 0xc8, 0x19, 0x15, 0x2c, 0x96, 0x49, 0xdf, 0x0d, 0x8d, 0x88, 0xdc, 0x1d, 0xd3,
+	0x42, 0x7c, 0x55, 0xb0, 0xb8, 0xb7, 0xa9, 0xb8, 0x33, 0x32, 0x3a, 0x1a, 0x24, 0x9c, 0xb3, 0xb8,
+	0xd7, 0x96, 0x29, 0x49, 0x4b, 0xde, 0x4b, 0xd8, 0xbb, 0x81, 0x1c, 0xa4, 0xf6, 0x53, 0x68, 0x1b,
+	0x8d, 0x21, 0xf8, 0x8e, 0x4e, 0x86, 0x36, 0x2f, 0xe1, 0x62, 0x96, 0xf7, 0xff, 0xd0, 0x19, 0xe5,
+	0x79, 0x96, 0x0f, 0x99, 0xa0, 0x49, 0x8a, 0x0c, 0x0d, 0xb2, 0xd8, 0x3c, 0x19, 0x39, 0xf6, 0x9e,
+	0x01, 0xf9, 0x9a, 0x8a, 0xe8, 0xd5, 0x08, 0x6d, 0x15, 0x26, 0x0c, 0xbb, 0xd0, 0x44, 0xfe, 0x0a,
+	0x7d, 0x09, 0x95, 0x60, 0x05, 0xa7, 0x51, 0x09, 0xce, 0xbf, 0x1c, 0xd8, 0x36, 0x46, 0xe5, 0x3e,
+	0x65, 0x2c, 0x1c, 0x2b, 0x16, 0xf7, 0xa0, 0x7d, 0x9a, 0x5c, 0xb3, 0x42, 0xd0, 0xeb, 0xa9, 0xdc,
+	0xc0, 0x0d, 0x17, 0xc0, 0x52, 0x0c, 0xdc, 0x6f, 0x8e, 0xc1, 0x7a, 0x35, 0x06, 0xf7, 0xa0, 0x3d,
+	0xa1, 0x3c, 0x3e, 0xcf, 0xde, 0xfa, 0x43, 0x1d, 0xbe, 0x05, 0x80, 0x7b, 0x9b, 0xe3, 0xc9, 0x53,
+	0xa9, 0x00, 0x56, 0x30, 0x4c, 0x2a, 0x25, 0xef, 0x43, 0x5d, 0xbd, 0x2d, 0x04, 0x19, 0x91, 0x54,
+	0xca, 0x40, 0xb6, 0x43, 0x25, 0x78, 0xdf, 0x86, 0xad, 0x61, 0x4e, 0x13, 0x6e, 0xf1, 0xf6, 0x3c,
+	0xcb, 0x23, 0xa6, 0x6b, 0xba, 0x12, 0xbc, 0x3f, 0x3b, 0xf0, 0xf1, 0x24, 0xb9, 0x9e, 0xa5, 0x54,
+	0x30, 0xd9, 0x22, 0xca, 0x1c, 0xfa, 0xfe, 0xd3, 0xd9, 0x03, 0x7c, 0x23, 0xb1, 0xee, 0xc0, 0xa4,
+	0xa7, 0x8a, 0xc5, 0x1a, 0x8a, 0xe9, 0x2b, 0xc8, 0x93, 0x2c, 0x4f, 0xc4, 0x7c, 0x90, 0xd2, 0xa2,
+	0xb0, 0x18, 0x5d, 0x56, 0xe0, 0x1f, 0xae, 0xbb, 0x37, 0x9d, 0x1e, 0xef, 0xe4, 0x3e, 0x74, 0x34,
+	0x84, 0xff, 0x59, 0xb4, 0xd7, 0x36, 0xb4, 0x32, 0xaf, 0xbc, 0xeb, 0x59, 0xeb, 0xb1, 0x5b, 0xbf,
+	0x21, 0x76, 0x04, 0xd6, 0xfd, 0x38, 0x65, 0xba, 0xfe, 0xc8, 0x31, 0x62, 0xcf, 0x73, 0xc6, 0x74,
+	0xd9, 0x91, 0x63, 0x64, 0x7a, 0xcc, 0x58, 0xac, 0x9e, 0xa7, 0x8c, 0xf1, 0x66, 0x68, 0x21, 0xde,
+	0x6f, 0x60, 0x5b, 0x46, 0x33, 0xc8, 0x33, 0xf5, 0x05, 0x04, 0xff, 0x9b, 0xbe, 0xa2, 0x85, 0x89,
+	0x8a, 0x12, 0x64, 0xfb, 0xc7, 0x8a, 0x82, 0x5e, 0x9a, 0x40, 0x18, 0x51, 0xa6, 0xee, 0x8c, 0x33,
+	0xdd, 0x96, 0xc9, 0xb1, 0x7c, 0x4a, 0x99, 0xa0, 0xa9, 0x2e, 0xa1, 0x4a, 0x58, 0x5c, 0xa7, 0xa6,
+	0x75, 0x9d, 0xbe, 0xfb, 0xa5, 0x69, 0x8a, 0xc8, 0x36, 0xb4, 0xf1, 0x57, 0xf6, 0xfb, 0x3b, 0x6b,
+	0xa4, 0x0b, 0xa0, 0xc5, 0xd1, 0xd8, 0xdf, 0x71, 0x08, 0x81, 0x2e, 0xca, 0x8b, 0x6e, 0x7d, 0xa7,
+	0x61, 0xb0, 0x45, 0x3b, 0xbe, 0xe3, 0x1e, 0xfc, 0xb1, 0x05, 0xdb, 0xa7, 0x2c, 0x7f, 0x43, 0xe7,
+	0xcf, 0xb0, 0x22, 0xf3, 0x98, 0x3c, 0x86, 0x0d, 0xfd, 0x2f, 0x85, 0xa8, 0xec, 0x51, 0xfd, 0xbc,
+	0xd5, 0xbf, 0x5d, 0x05, 0xb1, 0x56, 0xad, 0x91, 0x1f, 0x41, 0xbb, 0x6c, 0x5f, 0x89, 0xfa, 0x6e,
+	0x53, 0xef, 0xeb, 0xfb, 0x77, 0xea, 0xb0, 0x5a, 0xfa, 0x39, 0xb4, 0x65, 0x2d, 0xc2, 0xce, 0x4f,
+	0x5b, 0xac, 0x36, 0xa7, 0xfd, 0xdb, 0x55, 0xb0, 0xb4, 0x58, 0x76, 0x51, 0xda, 0x62, 0xbd, 0x4b,
+	0xeb, 0xdf, 0xa9, 0xc3, 0x6a, 0xe9, 0x13, 0x00, 0xdd, 0x59, 0xe0, 0x67, 0x2f, 0x35, 0xa9, 0xda,
+	0x42, 0xf5, 0x6f, 0x57, 0x41, 0xb9, 0xee, 0x07, 0x0e, 0xf9, 0x09, 0x74, 0xac, 0x42, 0x4d, 0xee,
+	0x6a, 0x8f, 0xea, 0x0d, 0x48, 0xff, 0xa3, 0x65, 0x85, 0x32, 0x7d, 0x04, 0x3b, 0xf5, 0xaa, 0x4e,
+	0xee, 0xc9, 0xc9, 0x2b, 0x8a, 0x7d, 0x5f, 0x7f, 0xcb, 0xaa, 0x56, 0x51, 0x6f, 0x8d, 0x3c, 0x83,
+	0xed, 0x4a, 0x09, 0x27, 0x1f, 0x97, 0x2c, 0xbd, 0xf3, 0x1e, 0x5f, 0xcb, 0x36, 0x60, 0xa9, 0x9a,
+	0x90, 0x7d, 0xb3, 0xd5, 0xaa, 0x2a, 0xdc, 0xff, 0x44, 0x3b, 0x78, 0x53, 0x1d, 0xf2, 0xd6, 0xc8,
+	0x53, 0xe8, 0x58, 0x35, 0x43, 0xf3, 0xb4, 0x5c, 0x45, 0xfa, 0xa4, 0xb2, 0x8d, 0xd4, 0x49, 0x9e,
+	0x0f, 0xa0, 0x29, 0xdf, 0x19, 0x51, 0x71, 0xb0, 0x33, 0x68, 0x9f, 0x2c, 0x20, 0xf3, 0x0c, 0xe5,
+	0x9a, 0x53, 0x20, 0xcb, 0x49, 0x88, 0xdc, 0x97, 0xb3, 0x57, 0xe6, 0xd6, 0xfe, 0xbd, 0x95, 0x7a,
+	0xe9, 0xc9, 0x79, 0x4b, 0x7e, 0xeb, 0x7d, 0xfc, 0xef, 0x01, 0x00, 0xec, 0xd7, 0x13, 0x0d, 0xf8,
+	0x15, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// TerwayBackendClient is the client API for TerwayBackend service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type TerwayBackendClient interface {
+	AllocIP(ctx context.Context, in *AllocIPRequest, opts ...grpc.CallOption) (*AllocIPReply, error)
+	ReleaseIP(ctx context.Context, in *ReleaseIPRequest, opts ...grpc.CallOption) (*ReleaseIPReply, error)
+	GetIPInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoReply, error)
+	Handshake(ctx context.Context, in *HandshakeRequest, opts ...grpc.CallOption) (*HandshakeReply, error)
+	CapturePod(ctx context.Context, in *CaptureRequest, opts ...grpc.CallOption) (TerwayBackend_CapturePodClient, error)
+	ReportSetup(ctx context.Context, in *ReportSetupRequest, opts ...grpc.CallOption) (*ReportSetupReply, error)
+	UpdateIPDenyList(ctx context.Context, in *UpdateIPDenyListRequest, opts ...grpc.CallOption) (*IPDenyListReply, error)
+	GetIPDenyList(ctx context.Context, in *GetIPDenyListRequest, opts ...grpc.CallOption) (*IPDenyListReply, error)
+	GetResourceInventory(ctx context.Context, in *GetResourceInventoryRequest, opts ...grpc.CallOption) (*ResourceInventoryReply, error)
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (TerwayBackend_WatchEventsClient, error)
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (TerwayBackend_DrainClient, error)
+	SimulateAllocation(ctx context.Context, in *SimulateAllocationRequest, opts ...grpc.CallOption) (*SimulateAllocationReply, error)
+}
+
+type terwayBackendClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewTerwayBackendClient(cc *grpc.ClientConn) TerwayBackendClient {
+	return &terwayBackendClient{cc}
+}
+
+func (c *terwayBackendClient) AllocIP(ctx context.Context, in *AllocIPRequest, opts ...grpc.CallOption) (*AllocIPReply, error) {
+	out := new(AllocIPReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/AllocIP", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *terwayBackendClient) ReleaseIP(ctx context.Context, in *ReleaseIPRequest, opts ...grpc.CallOption) (*ReleaseIPReply, error) {
+	out := new(ReleaseIPReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/ReleaseIP", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *terwayBackendClient) GetIPInfo(ctx context.Context, in *GetInfoRequest, opts ...grpc.CallOption) (*GetInfoReply, error) {
+	out := new(GetInfoReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/GetIPInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *terwayBackendClient) Handshake(ctx context.Context, in *HandshakeRequest, opts ...grpc.CallOption) (*HandshakeReply, error) {
+	out := new(HandshakeReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/Handshake", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *terwayBackendClient) CapturePod(ctx context.Context, in *CaptureRequest, opts ...grpc.CallOption) (TerwayBackend_CapturePodClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_TerwayBackend_serviceDesc.Streams[0], "/rpc.TerwayBackend/CapturePod", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &terwayBackendCapturePodClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TerwayBackend_CapturePodClient interface {
+	Recv() (*CaptureReply, error)
+	grpc.ClientStream
+}
+
+type terwayBackendCapturePodClient struct {
+	grpc.ClientStream
+}
+
+func (x *terwayBackendCapturePodClient) Recv() (*CaptureReply, error) {
+	m := new(CaptureReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *terwayBackendClient) ReportSetup(ctx context.Context, in *ReportSetupRequest, opts ...grpc.CallOption) (*ReportSetupReply, error) {
+	out := new(ReportSetupReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/ReportSetup", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *terwayBackendClient) UpdateIPDenyList(ctx context.Context, in *UpdateIPDenyListRequest, opts ...grpc.CallOption) (*IPDenyListReply, error) {
+	out := new(IPDenyListReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/UpdateIPDenyList", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *terwayBackendClient) GetIPDenyList(ctx context.Context, in *GetIPDenyListRequest, opts ...grpc.CallOption) (*IPDenyListReply, error) {
+	out := new(IPDenyListReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/GetIPDenyList", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *terwayBackendClient) GetResourceInventory(ctx context.Context, in *GetResourceInventoryRequest, opts ...grpc.CallOption) (*ResourceInventoryReply, error) {
+	out := new(ResourceInventoryReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/GetResourceInventory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *terwayBackendClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (TerwayBackend_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_TerwayBackend_serviceDesc.Streams[1], "/rpc.TerwayBackend/WatchEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &terwayBackendWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TerwayBackend_WatchEventsClient interface {
+	Recv() (*ResourceEvent, error)
+	grpc.ClientStream
+}
+
+type terwayBackendWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *terwayBackendWatchEventsClient) Recv() (*ResourceEvent, error) {
+	m := new(ResourceEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *terwayBackendClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (TerwayBackend_DrainClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_TerwayBackend_serviceDesc.Streams[2], "/rpc.TerwayBackend/Drain", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &terwayBackendDrainClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TerwayBackend_DrainClient interface {
+	Recv() (*DrainProgress, error)
+	grpc.ClientStream
+}
+
+type terwayBackendDrainClient struct {
+	grpc.ClientStream
+}
+
+func (x *terwayBackendDrainClient) Recv() (*DrainProgress, error) {
+	m := new(DrainProgress)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *terwayBackendClient) SimulateAllocation(ctx context.Context, in *SimulateAllocationRequest, opts ...grpc.CallOption) (*SimulateAllocationReply, error) {
+	out := new(SimulateAllocationReply)
+	err := c.cc.Invoke(ctx, "/rpc.TerwayBackend/SimulateAllocation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TerwayBackendServer is the server API for TerwayBackend service.
+type TerwayBackendServer interface {
+	AllocIP(context.Context, *AllocIPRequest) (*AllocIPReply, error)
+	ReleaseIP(context.Context, *ReleaseIPRequest) (*ReleaseIPReply, error)
+	GetIPInfo(context.Context, *GetInfoRequest) (*GetInfoReply, error)
+	Handshake(context.Context, *HandshakeRequest) (*HandshakeReply, error)
+	CapturePod(*CaptureRequest, TerwayBackend_CapturePodServer) error
+	ReportSetup(context.Context, *ReportSetupRequest) (*ReportSetupReply, error)
+	UpdateIPDenyList(context.Context, *UpdateIPDenyListRequest) (*IPDenyListReply, error)
+	GetIPDenyList(context.Context, *GetIPDenyListRequest) (*IPDenyListReply, error)
+	GetResourceInventory(context.Context, *GetResourceInventoryRequest) (*ResourceInventoryReply, error)
+	WatchEvents(*WatchEventsRequest, TerwayBackend_WatchEventsServer) error
+	Drain(*DrainRequest, TerwayBackend_DrainServer) error
+	SimulateAllocation(context.Context, *SimulateAllocationRequest) (*SimulateAllocationReply, error)
+}
+
+func RegisterTerwayBackendServer(s *grpc.Server, srv TerwayBackendServer) {
+	s.RegisterService(&_TerwayBackend_serviceDesc, srv)
+}
+
+func _TerwayBackend_AllocIP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocIPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TerwayBackendServer).AllocIP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.TerwayBackend/AllocIP",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TerwayBackendServer).AllocIP(ctx, req.(*AllocIPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TerwayBackend_ReleaseIP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseIPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TerwayBackendServer).ReleaseIP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
 		FullMethod: "/rpc.TerwayBackend/ReleaseIP",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -1152,6 +2712,177 @@ func _TerwayBackend_GetIPInfo_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TerwayBackend_Handshake_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HandshakeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TerwayBackendServer).Handshake(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.TerwayBackend/Handshake",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TerwayBackendServer).Handshake(ctx, req.(*HandshakeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TerwayBackend_CapturePod_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CaptureRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TerwayBackendServer).CapturePod(m, &terwayBackendCapturePodServer{stream})
+}
+
+type TerwayBackend_CapturePodServer interface {
+	Send(*CaptureReply) error
+	grpc.ServerStream
+}
+
+type terwayBackendCapturePodServer struct {
+	grpc.ServerStream
+}
+
+func (x *terwayBackendCapturePodServer) Send(m *CaptureReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TerwayBackend_ReportSetup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportSetupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TerwayBackendServer).ReportSetup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.TerwayBackend/ReportSetup",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TerwayBackendServer).ReportSetup(ctx, req.(*ReportSetupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TerwayBackend_UpdateIPDenyList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateIPDenyListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TerwayBackendServer).UpdateIPDenyList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.TerwayBackend/UpdateIPDenyList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TerwayBackendServer).UpdateIPDenyList(ctx, req.(*UpdateIPDenyListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TerwayBackend_GetIPDenyList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetIPDenyListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TerwayBackendServer).GetIPDenyList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.TerwayBackend/GetIPDenyList",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TerwayBackendServer).GetIPDenyList(ctx, req.(*GetIPDenyListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TerwayBackend_GetResourceInventory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetResourceInventoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TerwayBackendServer).GetResourceInventory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.TerwayBackend/GetResourceInventory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TerwayBackendServer).GetResourceInventory(ctx, req.(*GetResourceInventoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TerwayBackend_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TerwayBackendServer).WatchEvents(m, &terwayBackendWatchEventsServer{stream})
+}
+
+type TerwayBackend_WatchEventsServer interface {
+	Send(*ResourceEvent) error
+	grpc.ServerStream
+}
+
+type terwayBackendWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *terwayBackendWatchEventsServer) Send(m *ResourceEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TerwayBackend_Drain_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DrainRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TerwayBackendServer).Drain(m, &terwayBackendDrainServer{stream})
+}
+
+type TerwayBackend_DrainServer interface {
+	Send(*DrainProgress) error
+	grpc.ServerStream
+}
+
+type terwayBackendDrainServer struct {
+	grpc.ServerStream
+}
+
+func (x *terwayBackendDrainServer) Send(m *DrainProgress) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _TerwayBackend_SimulateAllocation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimulateAllocationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TerwayBackendServer).SimulateAllocation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpc.TerwayBackend/SimulateAllocation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TerwayBackendServer).SimulateAllocation(ctx, req.(*SimulateAllocationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _TerwayBackend_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "rpc.TerwayBackend",
 	HandlerType: (*TerwayBackendServer)(nil),
@@ -1168,7 +2899,47 @@ var _TerwayBackend_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetIPInfo",
 			Handler:    _TerwayBackend_GetIPInfo_Handler,
 		},
+		{
+			MethodName: "Handshake",
+			Handler:    _TerwayBackend_Handshake_Handler,
+		},
+		{
+			MethodName: "ReportSetup",
+			Handler:    _TerwayBackend_ReportSetup_Handler,
+		},
+		{
+			MethodName: "UpdateIPDenyList",
+			Handler:    _TerwayBackend_UpdateIPDenyList_Handler,
+		},
+		{
+			MethodName: "GetIPDenyList",
+			Handler:    _TerwayBackend_GetIPDenyList_Handler,
+		},
+		{
+			MethodName: "GetResourceInventory",
+			Handler:    _TerwayBackend_GetResourceInventory_Handler,
+		},
+		{
+			MethodName: "SimulateAllocation",
+			Handler:    _TerwayBackend_SimulateAllocation_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CapturePod",
+			Handler:       _TerwayBackend_CapturePod_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _TerwayBackend_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Drain",
+			Handler:       _TerwayBackend_Drain_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "rpc.proto",
 }
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/daemon"
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/pkg/feature"
+	"github.com/AliyunContainerService/terway/types"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// annotationTrunking "true" for pod requiring node with Trunking feature gate enabled
+	annotationTrunking = "k8s.aliyun.com/trunking"
+
+	zoneLabel = "failure-domain.beta.kubernetes.io/zone"
+
+	// maxPriority max score of node in prioritize
+	maxPriority = 10
+
+	vSwitchCacheTTL = 5 * time.Minute
+)
+
+type vSwitchZone struct {
+	zone   string
+	expire time.Time
+}
+
+// extender filter and score nodes by network resources reported by daemons in NodeNetworkState
+type extender struct {
+	client      kubernetes.Interface
+	stateClient crd.Client
+	// ecs to get zone of vswitches, nil to skip the zone check
+	ecs aliyun.ECS
+	// stateTTL time node network states cached
+	stateTTL time.Duration
+
+	lock         sync.Mutex
+	states       map[string]*crd.NodeNetworkState
+	statesExpire time.Time
+	vSwitchZones map[string]*vSwitchZone
+}
+
+func newExtender(client kubernetes.Interface, stateClient crd.Client, ecs aliyun.ECS, stateTTL time.Duration) *extender {
+	return &extender{
+		client:       client,
+		stateClient:  stateClient,
+		ecs:          ecs,
+		stateTTL:     stateTTL,
+		vSwitchZones: make(map[string]*vSwitchZone),
+	}
+}
+
+// nodeStates network states of nodes by node name, listed again once expired
+func (e *extender) nodeStates() (map[string]*crd.NodeNetworkState, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.states != nil && time.Now().Before(e.statesExpire) {
+		return e.states, nil
+	}
+	list, err := e.stateClient.List()
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]*crd.NodeNetworkState, len(list))
+	for i := range list {
+		states[list[i].Name] = &list[i]
+	}
+	e.states = states
+	e.statesExpire = time.Now().Add(e.stateTTL)
+	return states, nil
+}
+
+// zonesOf zones of vswitches known, vswitches failed to describe are left out
+func (e *extender) zonesOf(vSwitches []string) map[string]string {
+	zones := make(map[string]string)
+	if e.ecs == nil {
+		return zones
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, vSwitch := range vSwitches {
+		if cached, ok := e.vSwitchZones[vSwitch]; ok && time.Now().Before(cached.expire) {
+			zones[vSwitch] = cached.zone
+			continue
+		}
+		zone, _, err := e.ecs.DescribeVSwitch(vSwitch)
+		if err != nil {
+			log.Warnf("error describe vswitch %s, skip its zone: %v", vSwitch, err)
+			continue
+		}
+		e.vSwitchZones[vSwitch] = &vSwitchZone{zone: zone, expire: time.Now().Add(vSwitchCacheTTL)}
+		zones[vSwitch] = zone
+	}
+	return zones
+}
+
+// nodeCandidate node with its network state and requirements of pod on it
+type nodeCandidate struct {
+	node  *corev1.Node
+	state *crd.NodeNetworkState
+	// placement nil if node not reported by daemon
+	placement *daemon.PodPlacement
+}
+
+// candidates resolve requirements of pod on nodes
+func (e *extender) candidates(pod *corev1.Pod, nodes []corev1.Node) ([]*nodeCandidate, error) {
+	states, err := e.nodeStates()
+	if err != nil {
+		return nil, fmt.Errorf("error list node network states: %v", err)
+	}
+	ns, err := e.client.CoreV1().Namespaces().Get(pod.Namespace, metav1.GetOptions{})
+	if err != nil {
+		log.Warnf("error get namespace %s for pod policy: %v", pod.Namespace, err)
+		ns = nil
+	}
+	var ret []*nodeCandidate
+	for i := range nodes {
+		c := &nodeCandidate{node: &nodes[i], state: states[nodes[i].Name]}
+		if c.state != nil {
+			c.placement = daemon.ResolvePodPlacement(c.state.Spec.DaemonMode, pod, ns, c.node)
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// freeOf free slots of pool, idle resources included
+func freeOf(stats crd.PoolStats) int {
+	return stats.Capacity - stats.Inuse - stats.Quarantine
+}
+
+// checkNode check node fits network requirements of pod, return the reason if not. nodes not reported by
+// daemon are not filtered, except for pods requiring trunking
+func checkNode(c *nodeCandidate, trunking bool, vSwitchZones map[string]string) string {
+	if trunking && (c.state == nil || c.state.Status.Node == nil || !containsString(c.state.Status.Node.FeatureGates, feature.Trunking)) {
+		return "feature gate Trunking not enabled on node"
+	}
+	if c.placement == nil {
+		return ""
+	}
+
+	if zone := c.node.Labels[zoneLabel]; zone != "" && len(c.placement.VSwitches) > 0 {
+		inZone := false
+		for _, vSwitch := range c.placement.VSwitches {
+			vSwitchZone, ok := vSwitchZones[vSwitch]
+			// zone unknown, leave it to the daemon
+			if !ok || vSwitchZone == zone {
+				inZone = true
+				break
+			}
+		}
+		if !inZone {
+			return fmt.Sprintf("none of vswitches %v in zone %s of node", c.placement.VSwitches, zone)
+		}
+	}
+
+	if c.placement.ResourceType == types.ResourceTypeVeth || c.state.Status.Summary == nil {
+		return ""
+	}
+	stats, ok := c.state.Status.Summary.Pools[c.placement.ResourceType]
+	if !ok {
+		return fmt.Sprintf("no %s pool on node for pod of network type %s", c.placement.ResourceType, c.placement.NetworkType)
+	}
+	if stats.Idle <= 0 && freeOf(stats) <= 0 {
+		return fmt.Sprintf("%s exhausted on node, inuse %d/%d", c.placement.ResourceType, stats.Inuse+stats.Quarantine, stats.Capacity)
+	}
+	return ""
+}
+
+// scoreNode score node by free slots of the pool serving pod in proportion to capacity, nodes without pooled
+// resources for pod scored in the middle
+func scoreNode(c *nodeCandidate) int {
+	if c.placement == nil || c.state.Status.Summary == nil {
+		return maxPriority / 2
+	}
+	if c.placement.ResourceType == types.ResourceTypeVeth {
+		return maxPriority
+	}
+	stats, ok := c.state.Status.Summary.Pools[c.placement.ResourceType]
+	if !ok || stats.Capacity <= 0 {
+		return 0
+	}
+	free := freeOf(stats)
+	if free <= 0 {
+		return 0
+	}
+	return free * maxPriority / stats.Capacity
+}
+
+// filter split nodes into fit ones and failed ones with reasons
+func (e *extender) filter(pod *corev1.Pod, nodes []corev1.Node) ([]corev1.Node, map[string]string, error) {
+	candidates, err := e.candidates(pod, nodes)
+	if err != nil {
+		return nil, nil, err
+	}
+	var vSwitches []string
+	for _, c := range candidates {
+		if c.placement == nil {
+			continue
+		}
+		for _, vSwitch := range c.placement.VSwitches {
+			if !containsString(vSwitches, vSwitch) {
+				vSwitches = append(vSwitches, vSwitch)
+			}
+		}
+	}
+	vSwitchZones := e.zonesOf(vSwitches)
+	trunking := pod.Annotations[annotationTrunking] == "true"
+
+	var fit []corev1.Node
+	failed := make(map[string]string)
+	for _, c := range candidates {
+		if reason := checkNode(c, trunking, vSwitchZones); reason != "" {
+			failed[c.node.Name] = reason
+			continue
+		}
+		fit = append(fit, *c.node)
+	}
+	if len(failed) > 0 {
+		log.Debugf("pod %s/%s filtered out of nodes: %v", pod.Namespace, pod.Name, failed)
+	}
+	return fit, failed, nil
+}
+
+// prioritize score nodes for pod
+func (e *extender) prioritize(pod *corev1.Pod, nodes []corev1.Node) ([]hostPriority, error) {
+	candidates, err := e.candidates(pod, nodes)
+	if err != nil {
+		return nil, err
+	}
+	var ret []hostPriority
+	for _, c := range candidates {
+		ret = append(ret, hostPriority{Host: c.node.Name, Score: scoreNode(c)})
+	}
+	return ret, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/AliyunContainerService/terway/daemon"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/pkg/feature"
+	"github.com/AliyunContainerService/terway/types"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func candidateOf(daemonMode string, pod *corev1.Pod, zone string, pools map[string]crd.PoolStats, gates ...string) *nodeCandidate {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: map[string]string{zoneLabel: zone}}}
+	state := &crd.NodeNetworkState{
+		Spec: crd.NodeNetworkStateSpec{DaemonMode: daemonMode},
+		Status: crd.NodeNetworkStateStatus{
+			Node:    &crd.NodeFacts{FeatureGates: gates},
+			Summary: &crd.NodeNetworkSummary{Pools: pools},
+		},
+	}
+	return &nodeCandidate{node: node, state: state, placement: daemon.ResolvePodPlacement(daemonMode, pod, nil, node)}
+}
+
+func TestCheckNode(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	full := map[string]crd.PoolStats{types.ResourceTypeENIIP: {Capacity: 10, Inuse: 8, Quarantine: 2}}
+	free := map[string]crd.PoolStats{types.ResourceTypeENIIP: {Capacity: 10, Inuse: 4, Idle: 2}}
+
+	assert.Contains(t, checkNode(candidateOf("ENIMultiIP", pod, "a", full), false, nil), "exhausted")
+	assert.Empty(t, checkNode(candidateOf("ENIMultiIP", pod, "a", free), false, nil))
+	// pods of vpc ip not pooled
+	assert.Empty(t, checkNode(candidateOf("VPC", pod, "a", nil), false, nil))
+	// not reported by daemon
+	assert.Empty(t, checkNode(&nodeCandidate{node: &corev1.Node{}}, false, nil))
+
+	assert.NotEmpty(t, checkNode(candidateOf("ENIMultiIP", pod, "a", free), true, nil))
+	assert.Empty(t, checkNode(candidateOf("ENIMultiIP", pod, "a", free, feature.Trunking), true, nil))
+
+	pod.Annotations = map[string]string{"k8s.aliyun.com/vswitch": "vsw-1, vsw-2"}
+	assert.Contains(t, checkNode(candidateOf("ENIMultiIP", pod, "a", free), false, map[string]string{"vsw-1": "b", "vsw-2": "c"}), "zone a")
+	assert.Empty(t, checkNode(candidateOf("ENIMultiIP", pod, "a", free), false, map[string]string{"vsw-1": "b", "vsw-2": "a"}))
+	// zone of vsw-2 unknown
+	assert.Empty(t, checkNode(candidateOf("ENIMultiIP", pod, "a", free), false, map[string]string{"vsw-1": "b"}))
+
+	// exclusive eni of pod in hybrid mode
+	pod.Annotations = map[string]string{"k8s.aliyun.com/network-mode": "exclusive"}
+	assert.Contains(t, checkNode(candidateOf("Hybrid", pod, "a", free), false, nil), "no eni pool")
+}
+
+func TestScoreNode(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	assert.Equal(t, 0, scoreNode(candidateOf("ENIMultiIP", pod, "a", map[string]crd.PoolStats{types.ResourceTypeENIIP: {Capacity: 10, Inuse: 10}})))
+	assert.Equal(t, 4, scoreNode(candidateOf("ENIMultiIP", pod, "a", map[string]crd.PoolStats{types.ResourceTypeENIIP: {Capacity: 10, Inuse: 5, Quarantine: 1}})))
+	assert.Equal(t, maxPriority, scoreNode(candidateOf("VPC", pod, "a", nil)))
+	assert.Equal(t, maxPriority/2, scoreNode(&nodeCandidate{node: &corev1.Node{}}))
+}
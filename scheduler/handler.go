@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// extenderArgs the arguments of scheduler extender call, nodeCacheCapable not supported so nodes always passed
+type extenderArgs struct {
+	Pod   *corev1.Pod      `json:"pod"`
+	Nodes *corev1.NodeList `json:"nodes,omitempty"`
+}
+
+// extenderFilterResult the result of filter call
+type extenderFilterResult struct {
+	Nodes       *corev1.NodeList  `json:"nodes,omitempty"`
+	FailedNodes map[string]string `json:"failedNodes,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// hostPriority the score of node in prioritize call
+type hostPriority struct {
+	Host  string `json:"host"`
+	Score int    `json:"score"`
+}
+
+func readArgs(r *http.Request) (*extenderArgs, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	args := &extenderArgs{}
+	if err = json.Unmarshal(body, args); err != nil {
+		return nil, err
+	}
+	if args.Pod == nil || args.Nodes == nil {
+		return nil, fmt.Errorf("pod and nodes required, nodeCacheCapable not supported")
+	}
+	return args, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warnf("error write extender response: %v", err)
+	}
+}
+
+// filterHandler serve filter call of scheduler
+func filterHandler(e *extender) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		args, err := readArgs(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid extender args: %v", err), http.StatusBadRequest)
+			return
+		}
+		result := &extenderFilterResult{}
+		fit, failed, err := e.filter(args.Pod, args.Nodes.Items)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Nodes = &corev1.NodeList{Items: fit}
+			result.FailedNodes = failed
+		}
+		writeJSON(w, result)
+	})
+}
+
+// prioritizeHandler serve prioritize call of scheduler
+func prioritizeHandler(e *extender) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		args, err := readArgs(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid extender args: %v", err), http.StatusBadRequest)
+			return
+		}
+		priorities, err := e.prioritize(args.Pod, args.Nodes.Items)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, priorities)
+	})
+}
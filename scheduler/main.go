@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/crd"
+	"github.com/AliyunContainerService/terway/types"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	gitVer     string
+	logLevel   string
+	kubeconfig string
+	master     string
+	configPath string
+	listen     string
+	stateTTL   time.Duration
+)
+
+func init() {
+	flag.StringVar(&logLevel, "log-level", "info", "terway scheduler extender log level")
+	flag.StringVar(&master, "master", "", "The address of the Kubernetes API server (overrides any value in kubeconfig).")
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
+	flag.StringVar(&configPath, "config", "", "terway config file with openapi credential for zones of vswitches, zone check skipped if empty")
+	flag.StringVar(&listen, "listen", ":10262", "address of scheduler extender server")
+	flag.DurationVar(&stateTTL, "state-ttl", 10*time.Second, "time node network states cached before listed again")
+}
+
+func main() {
+	flag.Parse()
+	level, err := log.ParseLevel(logLevel)
+	if err != nil {
+		log.Fatalf("error set log level: %s: %v", logLevel, err)
+	}
+	log.SetLevel(level)
+	log.Infof("Starting terway scheduler extender of version: %s", gitVer)
+
+	var ecs aliyun.ECS
+	if configPath != "" {
+		data, err := ioutil.ReadFile(configPath)
+		if err != nil {
+			log.Fatalf("failed read file %s: %v", configPath, err)
+		}
+		config := &types.Configure{}
+		if err = json.Unmarshal(data, config); err != nil {
+			log.Fatalf("failed parse config: %v", err)
+		}
+		ecs, err = aliyun.NewECS(config.AccessID, config.AccessSecret, "")
+		if err != nil {
+			log.Fatalf("error init ecs client: %v", err)
+		}
+	}
+
+	k8sRestConfig, err := clientcmd.BuildConfigFromFlags(master, kubeconfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	k8sClient, err := kubernetes.NewForConfig(k8sRestConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	e := newExtender(k8sClient, crd.NewClient(k8sClient.Discovery().RESTClient()), ecs, stateTTL)
+	mux := http.NewServeMux()
+	mux.Handle("/filter", filterHandler(e))
+	mux.Handle("/prioritize", prioritizeHandler(e))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	log.Fatal(http.ListenAndServe(listen, mux))
+}
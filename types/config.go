@@ -1,6 +1,11 @@
 package types
 
-import "github.com/denverdino/aliyungo/common"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/denverdino/aliyungo/common"
+)
 
 // Configure configuration of terway daemon
 type Configure struct {
@@ -16,6 +21,165 @@ type Configure struct {
 	HotPlug       string              `yaml:"hot_plug" json:"hot_plug"`
 	EniCapRatio   float64             `yaml:"eni_cap_ratio" json:"eni_cap_ratio"`
 	EniCapShift   int                 `yaml:"eni_cap_shift" json:"eni_cap_shift"`
+	// VSwitchSelectionPolicy how to select vswitch of zone for new eni: "ordered" (default) the order configured,
+	// or "most_free" the vswitch of most free ips. most_free is opt-in as it overrides the preference of order
+	// configured and describes vswitches by openapi
+	VSwitchSelectionPolicy string `yaml:"vswitch_selection_policy" json:"vswitch_selection_policy"`
+	// OverflowVSwitches zone to vswitches used once vswitches of zone run out of ips, eg: in secondary cidr blocks of vpc
+	OverflowVSwitches map[string][]string `yaml:"overflow_vswitches" json:"overflow_vswitches"`
+	// VSwitchAutoCreate create vswitches from cidr pool once vswitches of zone run out of ips, disabled if nil
+	VSwitchAutoCreate *VSwitchAutoCreate `yaml:"vswitch_auto_create" json:"vswitch_auto_create"`
+	// IPConflictDetection arp probe ip before assign to pod
+	IPConflictDetection bool `yaml:"ip_conflict_detection" json:"ip_conflict_detection"`
+	// LinkLocalAccess default access of pods to link-local services: "allow", "block" or "masquerade"
+	LinkLocalAccess string `yaml:"link_local_access" json:"link_local_access"`
+	// OpenAPIQPS global qps limit of openapi calls on node, shared by pod allocation and background tasks
+	OpenAPIQPS   float32 `yaml:"open_api_qps" json:"open_api_qps"`
+	OpenAPIBurst int     `yaml:"open_api_burst" json:"open_api_burst"`
+	// GCTimeout deadline of each garbage collection cycle, eg: "1m"
+	GCTimeout string `yaml:"gc_timeout" json:"gc_timeout"`
+	// GCProtectionWindow resources of sandboxes allocated within the window never reclaimed by garbage collection, eg: "1m"
+	GCProtectionWindow string `yaml:"gc_protection_window" json:"gc_protection_window"`
+	// VPCIPAM backend of ipam of pods with vpc ip: "host-local" (default) or "crd"
+	VPCIPAM string `yaml:"vpc_ipam" json:"vpc_ipam"`
+	// ReleasePolicy resource type to policy of releasing resource freed by pod: "pool", "cloud" or "quarantine"
+	ReleasePolicy map[string]string `yaml:"release_policy" json:"release_policy"`
+	// QuarantineSeconds cool-down of released resources before reused by other pods, 0 to disable
+	QuarantineSeconds int `yaml:"quarantine_seconds" json:"quarantine_seconds"`
+	// NamespaceResourceLimits resource type to max count each namespace can consume on node
+	NamespaceResourceLimits map[string]int `yaml:"namespace_resource_limits" json:"namespace_resource_limits"`
+	// IPChunkSize count of eni secondary ips assigned or unassigned by one openapi call, 0 or 1 to disable
+	IPChunkSize int `yaml:"ip_chunk_size" json:"ip_chunk_size"`
+	// ENIMaxIPs private ips of each eni used by terway at most, including the primary one, below the max of
+	// instance type, eg: headroom for other agents assigning ips to the enis, the max of instance type if 0
+	ENIMaxIPs int `yaml:"eni_max_ips" json:"eni_max_ips"`
+	// ENIPrefixDelegation assign ipv4 prefixes to eni and carve pod ips from them, fallback to secondary ips if not supported
+	ENIPrefixDelegation bool `yaml:"eni_prefix_delegation" json:"eni_prefix_delegation"`
+	// ConntrackCleanup pod network types flushing conntrack entries of pod ip on release: "VPCIP", "VPCENI" or "ENIMultiIP"
+	ConntrackCleanup []string `yaml:"conntrack_cleanup" json:"conntrack_cleanup"`
+	// ReleaseDelayMaxSeconds delay release of ips of deleted pods till termination grace period of pod elapsed and
+	// conntrack entries of ips drained, at most the seconds, 0 to disable
+	ReleaseDelayMaxSeconds int `yaml:"release_delay_max_seconds" json:"release_delay_max_seconds"`
+	// PrewarmPendingPods grow pools for pods scheduled to node ahead of their cni add
+	PrewarmPendingPods bool `yaml:"prewarm_pending_pods" json:"prewarm_pending_pods"`
+	// SlowAllocationThreshold allocations of pod network slower are logged with per-phase durations, eg: "5s"
+	SlowAllocationThreshold string `yaml:"slow_allocation_threshold" json:"slow_allocation_threshold"`
+	// AllocationBudget deadline of each allocation of pod network, less than the timeout of cni binary, eg: "100s"
+	AllocationBudget string `yaml:"allocation_budget" json:"allocation_budget"`
+	// ClusterID id of cluster tagged on enis created by terway for cost attribution
+	ClusterID string `yaml:"cluster_id" json:"cluster_id"`
+	// ENITags extra tags of enis created by terway
+	ENITags map[string]string `yaml:"eni_tags" json:"eni_tags"`
+	// TagPodNamespace tag exclusive enis with namespace of the pod using it
+	TagPodNamespace bool `yaml:"tag_pod_namespace" json:"tag_pod_namespace"`
+	// ENIAssumeRoleARN ram role of another account owning vswitches and enis, assumed with credential of node
+	ENIAssumeRoleARN string `yaml:"eni_assume_role_arn" json:"eni_assume_role_arn"`
+	// ENIResourceGroupID resource group of enis created by terway
+	ENIResourceGroupID string `yaml:"eni_resource_group_id" json:"eni_resource_group_id"`
+	// PoolDrainRate idle resources over max_pool_size disposed per minute at most, eg: max_pool_size lowered
+	PoolDrainRate int `yaml:"pool_drain_rate" json:"pool_drain_rate"`
+	// PoolParallelCreates concurrent creates of eni or eni ip resources by pool at most, 0 for one by one on refill
+	PoolParallelCreates int `yaml:"pool_parallel_creates" json:"pool_parallel_creates"`
+	// ThrottleCoordination share openapi throttling seen by daemons of cluster in OpenAPIThrottle of region,
+	// background refill of pools on all nodes slowed down until throttling ceased
+	ThrottleCoordination bool `yaml:"throttle_coordination" json:"throttle_coordination"`
+	// ReservedSlots slots of eni and eni ip pools reserved for pods of critical namespaces or priority classes
+	ReservedSlots int `yaml:"reserved_slots" json:"reserved_slots"`
+	// CriticalNamespaces namespaces of pods can take the slots reserved, eg: "kube-system"
+	CriticalNamespaces []string `yaml:"critical_namespaces" json:"critical_namespaces"`
+	// CriticalPriorityClasses priority classes of pods can take the slots reserved, eg: "system-node-critical"
+	CriticalPriorityClasses []string `yaml:"critical_priority_classes" json:"critical_priority_classes"`
+	// ENIHealthCheckSeconds period of checking link and cloud status of enis in eni multi ip mode, 0 to disable
+	ENIHealthCheckSeconds int `yaml:"eni_health_check_seconds" json:"eni_health_check_seconds"`
+	// CloudReconcileSeconds period of reconciling pools with enis and ips in cloud, 0 to disable
+	CloudReconcileSeconds int `yaml:"cloud_reconcile_seconds" json:"cloud_reconcile_seconds"`
+	// ENIDefragSeconds period of freeing enis of only idle ips by moving the ips to other enis, 0 to disable
+	ENIDefragSeconds int `yaml:"eni_defrag_seconds" json:"eni_defrag_seconds"`
+	// HybridExclusiveENIs enis of instance reserved for pods of exclusive network mode in Hybrid daemon mode
+	HybridExclusiveENIs int `yaml:"hybrid_exclusive_enis" json:"hybrid_exclusive_enis"`
+	// MaxInflightRequests concurrent requests of cni binary served by daemon, 0 for unlimited
+	MaxInflightRequests int `yaml:"max_inflight_requests" json:"max_inflight_requests"`
+	// MaxQueuedRequests requests of cni binary waiting over max_inflight_requests, others rejected as retryable
+	MaxQueuedRequests int `yaml:"max_queued_requests" json:"max_queued_requests"`
+	// TracingEndpoint OTLP/HTTP collector spans of allocations exported to, eg: "http://otel-collector:4318", disabled if empty
+	TracingEndpoint string `yaml:"tracing_endpoint" json:"tracing_endpoint"`
+	// ServiceLocalShortcut dnat connections of eni multi ip pods in veth datapath to services opted in by label
+	// to their backends on node ahead of kube-proxy
+	ServiceLocalShortcut bool `yaml:"service_local_shortcut" json:"service_local_shortcut"`
+	// NetfilterBackend backend of rules programmed by terway on node, "legacy" or "nft", detected by rules of
+	// kube-proxy and others on node if empty
+	NetfilterBackend string `yaml:"netfilter_backend" json:"netfilter_backend"`
+	// EgressNAT destinations of pod traffic exempted from snat or snat by terway, rules removed if empty
+	EgressNAT *EgressNAT `yaml:"egress_nat" json:"egress_nat"`
+	// NetnsLeakCleanup remove netns files without any process left by crashed runtimes and their host veths,
+	// only reported if disabled
+	NetnsLeakCleanup bool `yaml:"netns_leak_cleanup" json:"netns_leak_cleanup"`
+	// PodSysctls sysctls set in netns of pods, overridden by pod or namespace annotation, eg: {"net.core.somaxconn": "4096"}
+	PodSysctls map[string]string `yaml:"pod_sysctls" json:"pod_sysctls"`
+	// InterfaceTuning queues, ring sizes and offloads of interface of pods with exclusive eni, overridden by pod or
+	// namespace annotation, eg: {"combined_queues": 4, "gro": true}
+	InterfaceTuning *InterfaceTuning `yaml:"interface_tuning" json:"interface_tuning"`
+	// SelfCheck policy of self check on start, "warn" to refuse to serve on failures only, "strict" on warnings as well
+	SelfCheck string `yaml:"self_check" json:"self_check"`
+	// ExhaustionCondition type of condition set on node while network resources of pools exhausted, eg:
+	// "NetworkUnavailable" tainting node unschedulable, or a custom one, disabled if empty
+	ExhaustionCondition string `yaml:"exhaustion_condition" json:"exhaustion_condition"`
+	// FeatureGates experimental capabilities enabled or disabled, eg: {"Trunking": true}, per nodepool by profiles
+	FeatureGates map[string]bool `yaml:"feature_gates" json:"feature_gates"`
+	// CNIConf render cni config list of node from 10-terway.conf with chained plugins, installed and repaired on
+	// drift by daemon, 10-terway.conf copied as is if nil
+	CNIConf *CNIConf `yaml:"cni_conf" json:"cni_conf"`
+	// Profiles config of nodes selected by labels, eg: nodepools with different vswitches or pool sizes
+	Profiles []ConfigProfile `yaml:"profiles" json:"profiles"`
+}
+
+// ConfigProfile config of nodes selected by labels, overriding top-level fields of the global config
+type ConfigProfile struct {
+	Name string `yaml:"name" json:"name"`
+	// NodeSelector labels of nodes the profile applied to, the first profile matched applied
+	NodeSelector map[string]string `yaml:"node_selector" json:"node_selector"`
+	// DaemonMode daemon mode of nodes overriding the flag of daemon, eg: "ENIMultiIP"
+	DaemonMode string `yaml:"daemon_mode" json:"daemon_mode"`
+	// Config top-level fields of config replacing the global ones, eg: {"vswitches": {...}, "max_pool_size": 10}
+	Config json.RawMessage `yaml:"config" json:"config"`
+}
+
+// CNIConf cni config list rendered by daemon
+type CNIConf struct {
+	// CNIVersion cniVersion of config list, that of 10-terway.conf or 0.3.1 if empty
+	CNIVersion string `yaml:"cni_version" json:"cni_version"`
+	// MTU mtu of pod interfaces set in config of terway plugin, detected by cni binary if 0
+	MTU int `yaml:"mtu" json:"mtu"`
+	// ChainedPlugins config of plugins called after terway, eg: [{"type": "portmap", "capabilities": {"portMappings": true}}]
+	ChainedPlugins []json.RawMessage `yaml:"chained_plugins" json:"chained_plugins"`
+}
+
+// EgressNAT snat of pod traffic by destination, programmed in nat table and reconciled by daemon
+type EgressNAT struct {
+	// PodCIDRs source cidrs of pod traffic the rules applied to, any source if empty for exemptions
+	PodCIDRs []string `yaml:"pod_cidrs" json:"pod_cidrs"`
+	// NoSNAT destination cidrs of pod traffic never snat by rules after, eg: on-prem cidrs over CEN
+	NoSNAT []string `yaml:"no_snat" json:"no_snat"`
+	// SNAT rules of pod traffic to snat, checked after NoSNAT
+	SNAT []SNATRule `yaml:"snat" json:"snat"`
+}
+
+// SNATRule snat of pod traffic to destination
+type SNATRule struct {
+	// Destination cidr of pod traffic, eg: "0.0.0.0/0"
+	Destination string `yaml:"destination" json:"destination"`
+	// ToSource ip of node pod traffic snat to, masquerade to ip of outgoing interface if empty
+	ToSource string `yaml:"to_source" json:"to_source"`
+}
+
+// VSwitchAutoCreate config of vswitches created by terway on ip exhaustion
+type VSwitchAutoCreate struct {
+	// CIDRPool cidrs in blocks of vpc the vswitches carved from, eg: ["10.100.0.0/16"]
+	CIDRPool []string `yaml:"cidr_pool" json:"cidr_pool"`
+	// MaskSize prefix length of vswitches created, 24 by default
+	MaskSize int `yaml:"mask_size" json:"mask_size"`
+	// MaxVSwitches vswitches created by terway in each zone at most, 2 by default
+	MaxVSwitches int `yaml:"max_vswitches" json:"max_vswitches"`
 }
 
 // PoolConfig configuration of pool and resource factory
@@ -33,4 +197,33 @@ type PoolConfig struct {
 	HotPlug       bool
 	EniCapRatio   float64
 	EniCapShift   int
+	// VSwitchSelectionPolicy "ordered" or "most_free"
+	VSwitchSelectionPolicy string
+	// OverflowVSwitch vswitches used once VSwitch run out of ips
+	OverflowVSwitch []string
+	// VSwitchAutoCreate vswitches created once all vswitches run out of ips, nil to disable
+	VSwitchAutoCreate   *VSwitchAutoCreate
+	IPConflictDetection bool
+	// ReleasePolicy resource type to release policy
+	ReleasePolicy map[string]string
+	// QuarantinePeriod cool-down of released resources
+	QuarantinePeriod time.Duration
+	// IPChunkSize count of eni secondary ips allocated and freed in one chunk
+	IPChunkSize int
+	// ENIMaxIPs private ips of each eni used at most, the max of instance type if 0
+	ENIMaxIPs int
+	// PrefixDelegation carve eni secondary ips from ipv4 prefixes of eni
+	PrefixDelegation bool
+	// DeniedResource resources must not be allocated to pods, kept in quarantine of pool
+	DeniedResource func(res NetworkResource) bool
+	// ENITags tags of enis created
+	ENITags map[string]string
+	// DrainRate overfull idle resources disposed per minute
+	DrainRate int
+	// ParallelCreates concurrent creates of pool
+	ParallelCreates int
+	// Throttle level of openapi throttling of cluster, nil if not coordinated
+	Throttle func() int
+	// ReservedSlots slots of pool reserved for critical pods
+	ReservedSlots int
 }
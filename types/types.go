@@ -12,6 +12,23 @@ const (
 	ResourceTypeENIIP = "eniIp"
 )
 
+// access policy of pod to link-local services, eg: metadata 169.254.169.254
+const (
+	LinkLocalAccessAllow      = "allow"
+	LinkLocalAccessBlock      = "block"
+	LinkLocalAccessMasquerade = "masquerade"
+)
+
+// release policy of resources freed by pods
+const (
+	// ReleasePolicyPool put resource back to warm pool
+	ReleasePolicyPool = "pool"
+	// ReleasePolicyCloud return resource to cloud immediately
+	ReleasePolicyCloud = "cloud"
+	// ReleasePolicyQuarantine put resource back to pool after cool-down
+	ReleasePolicyQuarantine = "quarantine"
+)
+
 // ENI aliyun ENI resource
 type ENI struct {
 	ID           string
@@ -52,6 +69,9 @@ func (eniIP *ENIIP) GetType() string {
 // Veth veth pair resource on system
 type Veth struct {
 	HostVeth string
+	// PodIP ip of pod with mask of node cidr
+	PodIP   *net.IPNet
+	Gateway net.IP
 }
 
 // GetResourceID return host veth name of veth resource
@@ -69,3 +89,16 @@ type NetworkResource interface {
 	GetResourceID() string
 	GetType() string
 }
+
+// InterfaceTuning queues, ring sizes and offloads of interface of pod with exclusive eni, unset ones kept as is
+type InterfaceTuning struct {
+	// CombinedQueues combined channels of interface, at most the queues of eni by instance type
+	CombinedQueues int `yaml:"combined_queues" json:"combined_queues,omitempty"`
+	// RxRing descriptors of rx ring
+	RxRing int `yaml:"rx_ring" json:"rx_ring,omitempty"`
+	// TxRing descriptors of tx ring
+	TxRing int   `yaml:"tx_ring" json:"tx_ring,omitempty"`
+	GRO    *bool `yaml:"gro" json:"gro,omitempty"`
+	LRO    *bool `yaml:"lro" json:"lro,omitempty"`
+	TSO    *bool `yaml:"tso" json:"tso,omitempty"`
+}
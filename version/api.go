@@ -0,0 +1,48 @@
+package version
+
+import "fmt"
+
+// version of the grpc api between cni binary and daemon
+const (
+	// APIVersion the api version of this build, 2: handshake with feature negotiation
+	APIVersion = 2
+	// MinAPIVersion the oldest api version of peer compatible with this build, 1: before handshake
+	MinAPIVersion = 1
+)
+
+// features negotiated between cni binary and daemon by handshake
+const (
+	// FeatureLinkLocalAccess pod config carries the access of pod to link-local services
+	FeatureLinkLocalAccess = "link-local-access"
+	// FeatureSetupReport cni binary reports duration of pod network setup for allocation latency tracking
+	FeatureSetupReport = "setup-report"
+)
+
+// Features the features supported by this build
+var Features = []string{FeatureLinkLocalAccess, FeatureSetupReport}
+
+// LegacyAPIVersion the api version of peer not implementing handshake
+const LegacyAPIVersion = 1
+
+// CheckAPIVersion check the api version of peer compatible with this build
+func CheckAPIVersion(peer int32) error {
+	if peer < MinAPIVersion {
+		return fmt.Errorf("api version %d of peer too old, this build supports %d-%d", peer, MinAPIVersion, APIVersion)
+	}
+	return nil
+}
+
+// NegotiateFeatures return features supported by both local and peer
+func NegotiateFeatures(local, peer []string) []string {
+	peerSet := make(map[string]bool, len(peer))
+	for _, f := range peer {
+		peerSet[f] = true
+	}
+	var ret []string
+	for _, f := range local {
+		if peerSet[f] {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
@@ -1,19 +1,68 @@
 package version
 
-import "github.com/containernetworking/cni/pkg/version"
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/containernetworking/cni/pkg/version"
+)
 
 // specVersionSupported is the version of the CNI spec that's supported by the
-// ENI plugin. It's set to 0.3.0, which means that we support the following
+// ENI plugin. It's set to 0.3.0 and 0.3.1, which means that we support the following
 // commands:
 // * ADD
 // * DELETE
 // * VERSION
 // Refer to https://github.com/containernetworking/cni/blob/master/SPEC.md
 // for details
-var specVersionSupported = version.PluginSupports("0.3.0")
+var specVersionSupported = version.PluginSupports("0.3.0", "0.3.1")
+
+// datapath capabilities of terway cni binary
+const (
+	CapabilityVeth   = "veth"
+	CapabilityIPVlan = "ipvlan"
+	CapabilityRawNIC = "rawnic"
+)
+
+// version of the resource relation db schema of daemon
+const (
+	// DBSchemaVersion the schema written by daemon, 2: relations bound to pod sandbox
+	DBSchemaVersion = 2
+	// MinDBSchemaVersion the oldest schema daemon can read
+	MinDBSchemaVersion = 1
+)
+
+// LegacyCapabilities the capabilities of cni binary which not report it
+var LegacyCapabilities = []string{CapabilityVeth, CapabilityRawNIC}
+
+var capabilitiesSupported = []string{CapabilityVeth, CapabilityIPVlan, CapabilityRawNIC}
+
+// PluginInfo cni version info with datapath capabilities of terway binary
+type PluginInfo struct {
+	CNIVersion        string   `json:"cniVersion"`
+	SupportedVersions []string `json:"supportedVersions,omitempty"`
+	Capabilities      []string `json:"terwayCapabilities,omitempty"`
+}
+
+type terwayPluginInfo struct {
+	version.PluginInfo
+	capabilities []string
+}
+
+// Encode writes version information with capabilities as JSON to the given Writer
+func (p *terwayPluginInfo) Encode(w io.Writer) error {
+	return json.NewEncoder(w).Encode(&PluginInfo{
+		CNIVersion:        version.Current(),
+		SupportedVersions: p.SupportedVersions(),
+		Capabilities:      p.capabilities,
+	})
+}
 
 // GetSpecVersionSupported gets the version of the CNI spec that's supported
 // by the ENI plugin
 func GetSpecVersionSupported() version.PluginInfo {
-	return specVersionSupported
+	return &terwayPluginInfo{
+		PluginInfo:   specVersionSupported,
+		capabilities: capabilitiesSupported,
+	}
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/types"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const defaultConfigPath = "/etc/eni/eni.json"
+
+var (
+	gitVer      string
+	logLevel    string
+	kubeconfig  string
+	master      string
+	configPath  string
+	listen      string
+	tlsCertFile string
+	tlsKeyFile  string
+)
+
+func init() {
+	flag.StringVar(&logLevel, "log-level", "info", "terway webhook log level")
+	flag.StringVar(&master, "master", "", "The address of the Kubernetes API server (overrides any value in kubeconfig).")
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to kubeconfig file with authorization and master location information.")
+	flag.StringVar(&configPath, "config", defaultConfigPath, "terway config file with openapi credential")
+	flag.StringVar(&listen, "listen", ":8443", "address of webhook server")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "/etc/terway-webhook/tls.crt", "tls certificate of webhook server")
+	flag.StringVar(&tlsKeyFile, "tls-private-key-file", "/etc/terway-webhook/tls.key", "tls private key of webhook server")
+}
+
+func main() {
+	flag.Parse()
+	level, err := log.ParseLevel(logLevel)
+	if err != nil {
+		log.Fatalf("error set log level: %s: %v", logLevel, err)
+	}
+	log.SetLevel(level)
+	log.Infof("Starting terway webhook of version: %s", gitVer)
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		log.Fatalf("failed read file %s: %v", configPath, err)
+	}
+	config := &types.Configure{}
+	if err = json.Unmarshal(data, config); err != nil {
+		log.Fatalf("failed parse config: %v", err)
+	}
+
+	regionID, err := aliyun.GetLocalRegion()
+	if err != nil {
+		log.Fatalf("error get region-id: %v", err)
+	}
+	ecs, err := aliyun.NewECS(config.AccessID, config.AccessSecret, regionID)
+	if err != nil {
+		log.Fatalf("error init ecs client: %v", err)
+	}
+
+	k8sRestConfig, err := clientcmd.BuildConfigFromFlags(master, kubeconfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	k8sClient, err := kubernetes.NewForConfig(k8sRestConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/validate", reviewHandler(newValidator(ecs, k8sClient)))
+	log.Fatal(http.ListenAndServeTLS(listen, tlsCertFile, tlsKeyFile, mux))
+}
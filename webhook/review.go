@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// admissionReview the admission.k8s.io/v1beta1 AdmissionReview, only fields used by webhook
+type admissionReview struct {
+	APIVersion string             `json:"apiVersion,omitempty"`
+	Kind       string             `json:"kind,omitempty"`
+	Request    *admissionRequest  `json:"request,omitempty"`
+	Response   *admissionResponse `json:"response,omitempty"`
+}
+
+type admissionRequest struct {
+	UID       string                  `json:"uid"`
+	Kind      metav1.GroupVersionKind `json:"kind"`
+	Namespace string                  `json:"namespace,omitempty"`
+	Operation string                  `json:"operation"`
+	Object    json.RawMessage         `json:"object,omitempty"`
+}
+
+type admissionResponse struct {
+	UID     string         `json:"uid"`
+	Allowed bool           `json:"allowed"`
+	Result  *metav1.Status `json:"status,omitempty"`
+}
+
+// reviewHandler serve admission review of pods by validator
+func reviewHandler(v *validator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		review := &admissionReview{}
+		if err = json.Unmarshal(body, review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("invalid admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		response := &admissionResponse{UID: review.Request.UID, Allowed: true}
+		if review.Request.Kind.Kind == "Pod" {
+			pod := &corev1.Pod{}
+			if err = json.Unmarshal(review.Request.Object, pod); err != nil {
+				http.Error(w, fmt.Sprintf("invalid pod object: %v", err), http.StatusBadRequest)
+				return
+			}
+			if err = v.validatePod(pod); err != nil {
+				log.Infof("reject pod %s/%s: %v", review.Request.Namespace, pod.Name, err)
+				response.Allowed = false
+				response.Result = &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Reason:  metav1.StatusReasonInvalid,
+					Message: err.Error(),
+					Code:    http.StatusUnprocessableEntity,
+				}
+			}
+		}
+
+		review.Request = nil
+		review.Response = response
+		w.Header().Set("Content-Type", "application/json")
+		if err = json.NewEncoder(w).Encode(review); err != nil {
+			log.Errorf("error write admission review: %v", err)
+		}
+	})
+}
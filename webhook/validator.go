@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AliyunContainerService/terway/pkg/aliyun"
+	"github.com/AliyunContainerService/terway/pkg/annotation"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	zoneLabel = "failure-domain.beta.kubernetes.io/zone"
+
+	cacheTTL = 5 * time.Minute
+)
+
+type cacheEntry struct {
+	// done closed after fetched, value and err set before
+	done   chan struct{}
+	value  interface{}
+	err    error
+	expire time.Time
+}
+
+type vSwitchInfo struct {
+	zone         string
+	availIPCount int
+}
+
+// validator validate terway annotations of pod with cloud resources and cluster zones
+type validator struct {
+	ecs    aliyun.ECS
+	client kubernetes.Interface
+
+	lock  sync.Mutex
+	cache map[string]*cacheEntry
+}
+
+func newValidator(ecs aliyun.ECS, client kubernetes.Interface) *validator {
+	return &validator{
+		ecs:    ecs,
+		client: client,
+		cache:  make(map[string]*cacheEntry),
+	}
+}
+
+// cached get value by key from cache or fetch it out of lock, concurrent callers of key share one fetch,
+// errors are not cached
+func (v *validator) cached(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	v.lock.Lock()
+	if entry, ok := v.cache[key]; ok {
+		select {
+		case <-entry.done:
+			if time.Now().Before(entry.expire) {
+				v.lock.Unlock()
+				return entry.value, nil
+			}
+		default:
+			v.lock.Unlock()
+			<-entry.done
+			return entry.value, entry.err
+		}
+	}
+	entry := &cacheEntry{done: make(chan struct{})}
+	v.cache[key] = entry
+	v.lock.Unlock()
+
+	entry.value, entry.err = fetch()
+	entry.expire = time.Now().Add(cacheTTL)
+	if entry.err != nil {
+		v.lock.Lock()
+		if v.cache[key] == entry {
+			delete(v.cache, key)
+		}
+		v.lock.Unlock()
+	}
+	close(entry.done)
+	return entry.value, entry.err
+}
+
+func (v *validator) describeVSwitch(vSwitch string) (*vSwitchInfo, error) {
+	value, err := v.cached("vsw/"+vSwitch, func() (interface{}, error) {
+		zone, availIPCount, err := v.ecs.DescribeVSwitch(vSwitch)
+		if err != nil {
+			return nil, err
+		}
+		return &vSwitchInfo{zone: zone, availIPCount: availIPCount}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*vSwitchInfo), nil
+}
+
+// nodeZones zones of nodes in cluster
+func (v *validator) nodeZones() (map[string]bool, error) {
+	value, err := v.cached("zones", func() (interface{}, error) {
+		nodes, err := v.client.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		zones := make(map[string]bool)
+		for _, node := range nodes.Items {
+			if zone := node.Labels[zoneLabel]; zone != "" {
+				zones[zone] = true
+			}
+		}
+		return zones, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[string]bool), nil
+}
+
+// podZones zones the pod can be scheduled to
+func (v *validator) podZones(pod *corev1.Pod) (map[string]bool, error) {
+	if zone, ok := pod.Spec.NodeSelector[zoneLabel]; ok {
+		return map[string]bool{zone: true}, nil
+	}
+	return v.nodeZones()
+}
+
+func splitIDs(value string) []string {
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// validatePod reject annotations which would fail on CNI ADD, pods allowed if cloud resources or zones
+// failed to get
+func (v *validator) validatePod(pod *corev1.Pod) error {
+	annotations := pod.GetAnnotations()
+	var errs []error
+	if err := annotation.ValidatePod(annotations); err != nil {
+		errs = append(errs, err)
+	}
+
+	if value, ok := annotations[annotation.VSwitch]; ok {
+		if err := v.validateVSwitches(pod, splitIDs(value)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateVSwitches the vswitches are candidates, reject only if none of them usable
+func (v *validator) validateVSwitches(pod *corev1.Pod, vSwitches []string) error {
+	if len(vSwitches) == 0 {
+		return fmt.Errorf("empty %s annotation", annotation.VSwitch)
+	}
+	zones, err := v.podZones(pod)
+	if err != nil {
+		log.Warnf("error get zones of pod %s/%s, skip the check of vswitches: %v", pod.Namespace, pod.Name, err)
+		return nil
+	}
+
+	var errs []error
+	for _, vSwitch := range vSwitches {
+		info, err := v.describeVSwitch(vSwitch)
+		if err != nil {
+			log.Warnf("error describe vswitch %s of pod %s/%s, allowed as usable: %v", vSwitch, pod.Namespace, pod.Name, err)
+			return nil
+		}
+		if len(zones) != 0 && !zones[info.zone] {
+			errs = append(errs, fmt.Errorf("vswitch %s in zone %s, pod can not be scheduled to the zone", vSwitch, info.zone))
+			continue
+		}
+		if info.availIPCount == 0 {
+			errs = append(errs, fmt.Errorf("vswitch %s has no available ip", vSwitch))
+			continue
+		}
+		return nil
+	}
+	return utilerrors.NewAggregate(errs)
+}
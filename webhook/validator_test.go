@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatorCached(t *testing.T) {
+	v := newValidator(nil, nil)
+	fetched := 0
+	var lock sync.Mutex
+	release := make(chan struct{})
+	fetch := func() (interface{}, error) {
+		<-release
+		lock.Lock()
+		defer lock.Unlock()
+		fetched++
+		return fetched, nil
+	}
+
+	// concurrent callers share one fetch
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := v.cached("vsw/vsw-1", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, value)
+		}()
+	}
+	close(release)
+	wg.Wait()
+	value, _ := v.cached("vsw/vsw-1", fetch)
+	assert.Equal(t, 1, value)
+
+	// errors not cached
+	_, err := v.cached("vsw/vsw-2", func() (interface{}, error) {
+		return nil, errors.New("throttling")
+	})
+	assert.Error(t, err)
+	value, err = v.cached("vsw/vsw-2", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, value)
+}